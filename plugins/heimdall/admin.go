@@ -0,0 +1,560 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+)
+
+// AdminHandler returns the HTTP management surface for the plugin.
+// It is not started automatically; embed it in the host's admin server.
+func (p *Plugin) AdminHandler() http.Handler {
+	router := mux.NewRouter()
+
+	// Health stays open for load balancers; everything else requires a role
+	// when admin auth is enabled
+	router.HandleFunc("/health", p.handleHealth).Methods("GET")
+	router.HandleFunc("/metrics", p.requireRole(AdminRoleViewer, p.handleMetrics)).Methods("GET")
+
+	router.HandleFunc("/admin/drains", p.requireRole(AdminRoleViewer, p.handleListDrains)).Methods("GET")
+	router.HandleFunc("/admin/drains", p.requireRole(AdminRoleOperator, p.handleDrain)).Methods("POST")
+	router.HandleFunc("/admin/drains/{target:.+}", p.requireRole(AdminRoleOperator, p.handleUndrain)).Methods("DELETE")
+
+	router.HandleFunc("/admin/quarantine", p.requireRole(AdminRoleViewer, p.handleListQuarantine)).Methods("GET")
+	router.HandleFunc("/admin/quarantine/{model:.+}", p.requireRole(AdminRoleOperator, p.handleReleaseQuarantine)).Methods("DELETE")
+
+	router.HandleFunc("/admin/anomalies", p.requireRole(AdminRoleViewer, p.handleListAnomalies)).Methods("GET")
+	router.HandleFunc("/admin/anomalies/{tenant}", p.requireRole(AdminRoleOperator, p.handleAcknowledgeAnomaly)).Methods("DELETE")
+
+	router.HandleFunc("/admin/audit", p.requireRole(AdminRoleViewer, p.handleAudit)).Methods("GET")
+
+	router.HandleFunc("/admin/alpha", p.requireRole(AdminRoleViewer, p.handleAlphaStatus)).Methods("GET")
+	router.HandleFunc("/admin/alpha/kill", p.requireRole(AdminRoleOperator, p.handleKillAlpha)).Methods("POST")
+	router.HandleFunc("/admin/alpha/kill", p.requireRole(AdminRoleOperator, p.handleResumeAlpha)).Methods("DELETE")
+
+	router.HandleFunc("/admin/sessions/{id}", p.requireRole(AdminRoleViewer, p.handleSessionStatus)).Methods("GET")
+	router.HandleFunc("/admin/quality", p.requireRole(AdminRoleViewer, p.handleQuality)).Methods("GET")
+	router.HandleFunc("/admin/features", p.requireRole(AdminRoleViewer, p.handleFeatureAggregates)).Methods("GET")
+
+	router.HandleFunc("/admin/labeling/export", p.requireRole(AdminRoleAdmin, p.handleLabelExport)).Methods("GET")
+	router.HandleFunc("/admin/labeling/import", p.requireRole(AdminRoleAdmin, p.handleLabelImport)).Methods("POST")
+	router.HandleFunc("/admin/labeling/evalset", p.requireRole(AdminRoleAdmin, p.handleEvalSet)).Methods("GET")
+	router.HandleFunc("/admin/labeling/comparisons", p.requireRole(AdminRoleAdmin, p.handleComparisonExport)).Methods("GET")
+	router.HandleFunc("/admin/labeling/preferences", p.requireRole(AdminRoleAdmin, p.handlePreferenceImport)).Methods("POST")
+
+	router.HandleFunc("/admin/subjects/{tenant}", p.requireRole(AdminRoleAdmin, p.handleExportSubject)).Methods("GET")
+	router.HandleFunc("/admin/subjects/{tenant}", p.requireRole(AdminRoleAdmin, p.handleEraseSubject)).Methods("DELETE")
+
+	router.HandleFunc("/admin/schema/config", p.requireRole(AdminRoleViewer, p.handleConfigSchema)).Methods("GET")
+	router.HandleFunc("/admin/schema/config/validate", p.requireRole(AdminRoleViewer, p.handleValidateConfig)).Methods("POST")
+	router.HandleFunc("/admin/schema/artifact", p.requireRole(AdminRoleViewer, p.handleArtifactSchema)).Methods("GET")
+	router.HandleFunc("/admin/schema/decision", p.requireRole(AdminRoleViewer, p.handleDecisionSchema)).Methods("GET")
+
+	router.HandleFunc("/admin/dashboards/grafana", p.requireRole(AdminRoleViewer, p.handleGrafanaDashboard)).Methods("GET")
+	router.HandleFunc("/admin/dashboards/metrics", p.requireRole(AdminRoleViewer, p.handleMetricFamilies)).Methods("GET")
+
+	router.HandleFunc("/admin/decisions/replay", p.requireRole(AdminRoleOperator, p.handleReplayDecision)).Methods("POST")
+
+	router.HandleFunc("/admin/snapshot", p.requireRole(AdminRoleAdmin, p.handleCaptureSnapshot)).Methods("GET")
+	router.HandleFunc("/admin/snapshots", p.requireRole(AdminRoleAdmin, p.handleListSnapshots)).Methods("GET")
+	router.HandleFunc("/admin/snapshots", p.requireRole(AdminRoleAdmin, p.handleKeepSnapshot)).Methods("POST")
+	router.HandleFunc("/admin/snapshots/{id}", p.requireRole(AdminRoleAdmin, p.handleGetSnapshot)).Methods("GET")
+
+	router.HandleFunc("/admin/calibration", p.requireRole(AdminRoleViewer, p.handleCalibrationReport)).Methods("GET")
+	router.HandleFunc("/admin/calibration/outcomes", p.requireRole(AdminRoleAdmin, p.handleCalibrationOutcomes)).Methods("POST")
+
+	return router
+}
+
+// ConfigValidation is the result of validating a config against its schema
+type ConfigValidation struct {
+	Valid      bool               `json:"valid"`
+	Violations []schema.Violation `json:"violations,omitempty"`
+}
+
+// DrainRequest is the body of a drain admin request
+type DrainRequest struct {
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// KillRequest is the body of an α controller kill switch request
+type KillRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (p *Plugin) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := p.GetHealth()
+	status := http.StatusOK
+	if health.Status != "healthy" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+func (p *Plugin) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.Metrics())
+}
+
+func (p *Plugin) handleListDrains(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.drains.GetStatus())
+}
+
+func (p *Plugin) handleDrain(w http.ResponseWriter, r *http.Request) {
+	var req DrainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	before := p.drains.entry(req.Target)
+	if err := p.Drain(req.Target, req.Reason); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	p.auditAdmin(r, AuditDrain, "drained", req.Target,
+		diffFields("drains."+req.Target, before, p.drains.entry(req.Target)))
+
+	writeJSON(w, http.StatusOK, p.drains.GetStatus())
+}
+
+func (p *Plugin) handleUndrain(w http.ResponseWriter, r *http.Request) {
+	target := mux.Vars(r)["target"]
+
+	before := p.drains.entry(target)
+	if err := p.Undrain(target); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	p.auditAdmin(r, AuditDrain, "undrained", target, diffFields("drains."+target, before, nil))
+
+	writeJSON(w, http.StatusOK, p.drains.GetStatus())
+}
+
+func (p *Plugin) handleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	if p.quarantine == nil {
+		writeError(w, http.StatusNotFound, "quarantine is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.quarantine.GetStatus())
+}
+
+func (p *Plugin) handleReleaseQuarantine(w http.ResponseWriter, r *http.Request) {
+	if p.quarantine == nil {
+		writeError(w, http.StatusNotFound, "quarantine is disabled")
+		return
+	}
+	model := mux.Vars(r)["model"]
+	var before *QuarantineStatus
+	for _, status := range p.quarantine.GetStatus() {
+		if status.Model == model {
+			before = &status
+		}
+	}
+	if err := p.quarantine.Release(model); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	p.auditAdmin(r, AuditQuarantine, "released", model, diffFields("quarantine."+model, before, nil))
+	writeJSON(w, http.StatusOK, p.quarantine.GetStatus())
+}
+
+func (p *Plugin) handleListAnomalies(w http.ResponseWriter, r *http.Request) {
+	if p.anomalies == nil {
+		writeError(w, http.StatusNotFound, "cost anomaly detection is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.anomalies.GetStatus())
+}
+
+func (p *Plugin) handleAcknowledgeAnomaly(w http.ResponseWriter, r *http.Request) {
+	if p.anomalies == nil {
+		writeError(w, http.StatusNotFound, "cost anomaly detection is disabled")
+		return
+	}
+	tenant := mux.Vars(r)["tenant"]
+	var before []CostAnomaly
+	for _, anomaly := range p.anomalies.GetStatus() {
+		if anomaly.Tenant == tenant {
+			before = append(before, anomaly)
+		}
+	}
+	if err := p.anomalies.Acknowledge(tenant); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	p.auditAdmin(r, AuditCostAnomaly, "acknowledged", tenant, diffFields("cost_anomalies."+tenant, before, nil))
+	writeJSON(w, http.StatusOK, p.anomalies.GetStatus())
+}
+
+// handleExportSubject returns the data stored for a tenant, or for one
+// subject within it when the subject query parameter is set, for
+// data-subject access requests
+func (p *Plugin) handleExportSubject(w http.ResponseWriter, r *http.Request) {
+	export, err := p.ExportSubject(requestedSubject(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, export)
+}
+
+// handleEraseSubject deletes the data stored for a tenant, or for one
+// subject within it. Only the counts removed are audited, never the data
+// itself.
+func (p *Plugin) handleEraseSubject(w http.ResponseWriter, r *http.Request) {
+	subject := requestedSubject(r)
+	erasure, err := p.EraseSubject(subject)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	target := subject.Tenant
+	if subject.Subject != "" {
+		target += "/" + subject.Subject
+	}
+	p.auditAdmin(r, AuditDataSubject, "erased", target, diffFields("subjects."+target, *erasure, SubjectErasure{}))
+	writeJSON(w, http.StatusOK, erasure)
+}
+
+// requestedSubject reads the identity a data-subject request names
+func requestedSubject(r *http.Request) DataSubject {
+	return DataSubject{Tenant: mux.Vars(r)["tenant"], Subject: r.URL.Query().Get("subject")}
+}
+
+// handleCaptureSnapshot answers with a snapshot of the current routing
+// state without keeping it
+func (p *Plugin) handleCaptureSnapshot(w http.ResponseWriter, r *http.Request) {
+	signed, err := p.CaptureSnapshot("")
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, signed)
+}
+
+// SnapshotRequest asks for a snapshot to be captured and kept, e.g. from an
+// alerting webhook
+type SnapshotRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (p *Plugin) handleKeepSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req SnapshotRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "requested via admin API"
+	}
+
+	signed, err := p.CaptureSnapshot(req.Reason)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, signed)
+}
+
+func (p *Plugin) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if p.snapshots == nil {
+		writeError(w, http.StatusNotFound, "snapshots are disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.snapshots.List())
+}
+
+func (p *Plugin) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	if p.snapshots == nil {
+		writeError(w, http.StatusNotFound, "snapshots are disabled")
+		return
+	}
+	id := mux.Vars(r)["id"]
+	signed, ok := p.snapshots.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no snapshot "+id)
+		return
+	}
+	writeJSON(w, http.StatusOK, signed)
+}
+
+// handleReplayDecision reproduces a deterministic decision from its
+// decision log entry and reports whether it matches
+func (p *Plugin) handleReplayDecision(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	replayed, err := p.Replay(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrArtifactMismatch) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	recorded, err := DecodeRouterResponse(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matches, err := ReplayMatches(recorded, replayed)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"matches":  matches,
+		"decision": replayed,
+	})
+}
+
+func (p *Plugin) handleFeatureAggregates(w http.ResponseWriter, r *http.Request) {
+	if p.aggregates == nil {
+		writeError(w, http.StatusNotFound, "features are not logged as aggregates")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.aggregates.Releases())
+}
+
+func (p *Plugin) handleAlphaStatus(w http.ResponseWriter, r *http.Request) {
+	if p.alphaController == nil {
+		writeError(w, http.StatusNotFound, "alpha controller is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.alphaController.GetStatus())
+}
+
+// handleKillAlpha stops α adjustment and reverts to the artifact's α
+func (p *Plugin) handleKillAlpha(w http.ResponseWriter, r *http.Request) {
+	if p.alphaController == nil {
+		writeError(w, http.StatusNotFound, "alpha controller is disabled")
+		return
+	}
+	var req KillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	before := p.alphaController.GetStatus()
+	p.alphaController.Kill(req.Reason)
+	after := p.alphaController.GetStatus()
+	var changes []FieldChange
+	changes = append(changes, diffFields("alpha.offset", before.Offset, after.Offset)...)
+	changes = append(changes, diffFields("alpha.killed", before.Killed, after.Killed)...)
+	changes = append(changes, diffFields("alpha.kill_reason", before.KillReason, after.KillReason)...)
+	p.auditAdmin(r, AuditAlpha, "killed", "alpha_controller", changes)
+
+	writeJSON(w, http.StatusOK, after)
+}
+
+func (p *Plugin) handleResumeAlpha(w http.ResponseWriter, r *http.Request) {
+	if p.alphaController == nil {
+		writeError(w, http.StatusNotFound, "alpha controller is disabled")
+		return
+	}
+	if err := p.alphaController.Resume(); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	p.auditAdmin(r, AuditAlpha, "resumed", "alpha_controller", diffFields("alpha.killed", true, false))
+	writeJSON(w, http.StatusOK, p.alphaController.GetStatus())
+}
+
+// handleAudit lists audit entries oldest first; ?since=<id> returns only
+// later entries and ?limit=<n> caps the page
+func (p *Plugin) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if p.audit == nil {
+		writeError(w, http.StatusNotFound, "audit trail is disabled")
+		return
+	}
+	var since int64
+	var limit int
+	var err error
+	if value := r.URL.Query().Get("since"); value != "" {
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+value)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+value)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, p.audit.Entries(since, limit))
+}
+
+// auditAdmin records a change made through the admin API
+func (p *Plugin) auditAdmin(r *http.Request, kind, action, target string, changes []FieldChange) {
+	if p.audit == nil {
+		return
+	}
+	p.audit.Record(AuditEntry{
+		Actor:   adminActor(r),
+		Kind:    kind,
+		Action:  action,
+		Target:  target,
+		Changes: changes,
+	})
+}
+
+func (p *Plugin) handleSessionStatus(w http.ResponseWriter, r *http.Request) {
+	if p.sessions == nil {
+		writeError(w, http.StatusNotFound, "session tracking is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.sessions.GetStatus(mux.Vars(r)["id"]))
+}
+
+func (p *Plugin) handleQuality(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.quality.Snapshot())
+}
+
+// handleLabelExport streams pending labeling records as JSON lines
+func (p *Plugin) handleLabelExport(w http.ResponseWriter, r *http.Request) {
+	if p.labeling == nil {
+		writeError(w, http.StatusNotFound, "labeling is disabled")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := p.labeling.Export(w); err != nil {
+		p.logger.Printf("Label export failed: %v", err)
+	}
+}
+
+// handleLabelImport applies JSON-lines labels; ?target=quality|eval
+func (p *Plugin) handleLabelImport(w http.ResponseWriter, r *http.Request) {
+	if p.labeling == nil {
+		writeError(w, http.StatusNotFound, "labeling is disabled")
+		return
+	}
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = LabelTargetQuality
+	}
+
+	result, err := p.labeling.Import(r.Body, target)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleComparisonExport streams finished comparisons as JSON lines
+func (p *Plugin) handleComparisonExport(w http.ResponseWriter, r *http.Request) {
+	if p.comparer == nil {
+		writeError(w, http.StatusNotFound, "compare mode is disabled")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := p.comparer.Export(w); err != nil {
+		p.logger.Printf("Comparison export failed: %v", err)
+	}
+}
+
+// handlePreferenceImport applies JSON-lines preferences to the quality store
+func (p *Plugin) handlePreferenceImport(w http.ResponseWriter, r *http.Request) {
+	if p.comparer == nil {
+		writeError(w, http.StatusNotFound, "compare mode is disabled")
+		return
+	}
+	result, err := p.comparer.Import(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (p *Plugin) handleEvalSet(w http.ResponseWriter, r *http.Request) {
+	if p.labeling == nil {
+		writeError(w, http.StatusNotFound, "labeling is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.labeling.EvaluationSet())
+}
+
+func (p *Plugin) handleCalibrationReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.calibration.Report())
+}
+
+// handleCalibrationOutcomes records externally observed need-for-hard outcomes
+func (p *Plugin) handleCalibrationOutcomes(w http.ResponseWriter, r *http.Request) {
+	var outcomes []CalibrationOutcome
+	if err := json.NewDecoder(r.Body).Decode(&outcomes); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	for _, outcome := range outcomes {
+		if outcome.HardProbability < 0 || outcome.HardProbability > 1 {
+			writeError(w, http.StatusBadRequest, "hard_probability must be within [0, 1]")
+			return
+		}
+	}
+
+	for _, outcome := range outcomes {
+		p.calibration.Record(outcome)
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"recorded": len(outcomes)})
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (p *Plugin) handleConfigSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ConfigSchema())
+}
+
+func (p *Plugin) handleDecisionSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, DecisionSchema())
+}
+
+func (p *Plugin) handleGrafanaDashboard(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p.GrafanaDashboard())
+}
+
+func (p *Plugin) handleMetricFamilies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, MetricFamilies)
+}
+
+func (p *Plugin) handleArtifactSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, schema.GenerateArtifact())
+}
+
+// handleValidateConfig lints a proposed config without applying it
+func (p *Plugin) handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	violations, err := schema.Validate(ConfigSchema(), data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ConfigValidation{Valid: len(violations) == 0, Violations: violations})
+}
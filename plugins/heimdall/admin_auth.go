@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Admin roles. Each role may also do everything the roles before it may.
+const (
+	// AdminRoleViewer reads metrics, status listings, schemas and the audit
+	// trail
+	AdminRoleViewer = "viewer"
+	// AdminRoleOperator drains providers, releases quarantines and
+	// acknowledges cost anomalies
+	AdminRoleOperator = "operator"
+	// AdminRoleAdmin exports prompts for labeling and feeds labels and
+	// calibration outcomes back into routing
+	AdminRoleAdmin = "admin"
+)
+
+var adminRoleRank = map[string]int{
+	AdminRoleViewer:   1,
+	AdminRoleOperator: 2,
+	AdminRoleAdmin:    3,
+}
+
+// AdminAuthConfig protects the admin surface. While disabled every endpoint
+// is open and the host must restrict access to it.
+type AdminAuthConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Tokens are accepted as "Authorization: Bearer <token>"
+	Tokens []AdminToken `json:"tokens"`
+	// Certificates grant roles to mTLS clients. The host's TLS config must
+	// verify client certificates; unverified ones are ignored.
+	Certificates []AdminCertificate `json:"certificates"`
+}
+
+// AdminToken grants a role to a bearer token. The token is configured by
+// its SHA-256 digest so configs never hold the credential itself.
+type AdminToken struct {
+	// Name identifies the caller in the audit trail
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"` // hex
+	Role   string `json:"role"`
+}
+
+// AdminCertificate grants a role to clients whose verified certificate has
+// the given subject common name
+type AdminCertificate struct {
+	CommonName string `json:"common_name"`
+	Role       string `json:"role"`
+}
+
+// AdminPrincipal is an authenticated admin caller
+type AdminPrincipal struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// adminAuthenticator resolves admin requests to principals
+type adminAuthenticator struct {
+	tokens       map[[sha256.Size]byte]AdminPrincipal
+	certificates map[string]AdminPrincipal
+}
+
+func newAdminAuthenticator(config AdminAuthConfig) (*adminAuthenticator, error) {
+	if len(config.Tokens) == 0 && len(config.Certificates) == 0 {
+		return nil, fmt.Errorf("no tokens or certificates are configured")
+	}
+
+	aa := &adminAuthenticator{
+		tokens:       make(map[[sha256.Size]byte]AdminPrincipal),
+		certificates: make(map[string]AdminPrincipal),
+	}
+	for i, token := range config.Tokens {
+		if adminRoleRank[token.Role] == 0 {
+			return nil, fmt.Errorf("token %d: unknown role %q", i, token.Role)
+		}
+		digest, err := hex.DecodeString(token.SHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("token %d: sha256 must be a hex SHA-256 digest", i)
+		}
+		name := token.Name
+		if name == "" {
+			name = "token:" + strings.ToLower(token.SHA256[:16])
+		}
+		aa.tokens[[sha256.Size]byte(digest)] = AdminPrincipal{Name: name, Role: token.Role}
+	}
+	for i, cert := range config.Certificates {
+		if adminRoleRank[cert.Role] == 0 {
+			return nil, fmt.Errorf("certificate %d: unknown role %q", i, cert.Role)
+		}
+		if cert.CommonName == "" {
+			return nil, fmt.Errorf("certificate %d: common_name is required", i)
+		}
+		aa.certificates[cert.CommonName] = AdminPrincipal{Name: "cert:" + cert.CommonName, Role: cert.Role}
+	}
+	return aa, nil
+}
+
+// authenticate identifies the caller by bearer token, else by verified
+// client certificate
+func (aa *adminAuthenticator) authenticate(r *http.Request) (AdminPrincipal, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		principal, ok := aa.tokens[sha256.Sum256([]byte(strings.TrimSpace(token)))]
+		return principal, ok
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		principal, ok := aa.certificates[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+		return principal, ok
+	}
+	return AdminPrincipal{}, false
+}
+
+// requireRole restricts an admin handler to principals holding role. The
+// principal is passed on in the request context.
+func (p *Plugin) requireRole(role string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.adminAuth == nil {
+			handler(w, r)
+			return
+		}
+
+		principal, ok := p.adminAuth.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="heimdall-admin"`)
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if adminRoleRank[principal.Role] < adminRoleRank[role] {
+			p.logger.Printf("Denied %s %s to %s (%s role required)", r.Method, r.URL.Path, principal.Name, role)
+			writeError(w, http.StatusForbidden, role+" role required")
+			return
+		}
+		handler(w, r.WithContext(context.WithValue(r.Context(), "heimdall_admin_principal", principal)))
+	}
+}
+
+// adminActor identifies the caller of an admin request: the authenticated
+// principal, else the remote address
+func adminActor(r *http.Request) string {
+	if principal, ok := r.Context().Value("heimdall_admin_principal").(AdminPrincipal); ok {
+		return principal.Name
+	}
+	return r.RemoteAddr
+}
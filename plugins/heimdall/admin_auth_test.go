@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tokenDigest(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+func TestAdminAuth(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Audit = AuditConfig{Enabled: true}
+	config.AdminAuth = AdminAuthConfig{
+		Enabled: true,
+		Tokens: []AdminToken{
+			{Name: "dashboard", SHA256: tokenDigest("viewer-token"), Role: AdminRoleViewer},
+			{Name: "oncall", SHA256: tokenDigest("operator-token"), Role: AdminRoleOperator},
+		},
+		Certificates: []AdminCertificate{{CommonName: "labeling-job", Role: AdminRoleAdmin}},
+	}
+	plugin, err := New(config)
+	require.NoError(t, err)
+	handler := plugin.AdminHandler()
+
+	serve := func(method, path, token string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("should leave health open", func(t *testing.T) {
+		assert.NotEqual(t, http.StatusUnauthorized, serve("GET", "/health", "", "").Code)
+	})
+
+	t.Run("should require credentials", func(t *testing.T) {
+		recorder := serve("GET", "/metrics", "", "")
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), "Bearer")
+		assert.Equal(t, http.StatusUnauthorized, serve("GET", "/metrics", "wrong-token", "").Code)
+	})
+
+	t.Run("should let viewers read but not drain", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("GET", "/metrics", "viewer-token", "").Code)
+		assert.Equal(t, http.StatusOK, serve("GET", "/admin/drains", "viewer-token", "").Code)
+
+		recorder := serve("POST", "/admin/drains", "viewer-token", `{"target": "openai"}`)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "operator role required")
+	})
+
+	t.Run("should let operators drain, audited under their name", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("POST", "/admin/drains", "operator-token", `{"target": "openai"}`).Code)
+		assert.Equal(t, http.StatusForbidden, serve("GET", "/admin/labeling/evalset", "operator-token", "").Code)
+
+		entries := plugin.audit.Entries(0, 0)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "oncall", entries[0].Actor)
+	})
+
+	t.Run("should authenticate verified client certificates", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/admin/drains/openai", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+			{Subject: pkix.Name{CommonName: "labeling-job"}},
+		}}}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "cert:labeling-job", plugin.audit.Entries(1, 0)[0].Actor)
+
+		req = httptest.NewRequest("GET", "/metrics", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "labeling-job"}},
+		}}
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, "unverified certificates are ignored")
+	})
+
+	t.Run("should reject invalid configs", func(t *testing.T) {
+		for _, authConfig := range []AdminAuthConfig{
+			{Enabled: true},
+			{Enabled: true, Tokens: []AdminToken{{SHA256: tokenDigest("t"), Role: "root"}}},
+			{Enabled: true, Tokens: []AdminToken{{SHA256: "not-hex", Role: AdminRoleViewer}}},
+			{Enabled: true, Certificates: []AdminCertificate{{Role: AdminRoleViewer}}},
+		} {
+			config := createRouterTestConfig()
+			config.AdminAuth = authConfig
+			_, err := New(config)
+			assert.ErrorContains(t, err, "invalid admin auth config")
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Admission outcomes for hard-bucket requests
+const (
+	// AdmissionQueued: the request waited for hard capacity
+	AdmissionQueued = "queued"
+	// AdmissionDowngraded: the request was routed to mid for lack of hard
+	// capacity
+	AdmissionDowngraded = "downgraded"
+)
+
+// AdmissionConfig caps concurrent hard-bucket requests. At capacity,
+// borderline requests (hard probability within DowngradeMargin of the
+// threshold) are downgraded to mid; clearly hard requests wait in a bounded
+// queue and are downgraded only when it is full or the wait times out.
+type AdmissionConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// HardCapacity is the number of concurrent hard-bucket requests
+	HardCapacity int `json:"hard_capacity"`
+
+	// DowngradeMargin is how far above the hard threshold a request's hard
+	// probability may be and still be downgraded (default 0.1)
+	DowngradeMargin float64 `json:"downgrade_margin"`
+
+	// MaxQueue bounds requests waiting for capacity; QueueTimeout bounds
+	// each wait (default 100ms)
+	MaxQueue     int           `json:"max_queue"`
+	QueueTimeout time.Duration `json:"queue_timeout"`
+}
+
+// AdmissionStats counts admission outcomes
+type AdmissionStats struct {
+	InFlight      int   `json:"in_flight"`
+	Waiting       int   `json:"waiting"`
+	Admitted      int64 `json:"admitted"`
+	Queued        int64 `json:"queued"`
+	Downgraded    int64 `json:"downgraded"`
+	QueueTimeouts int64 `json:"queue_timeouts"`
+}
+
+// AdmissionController hands out hard-bucket capacity
+type AdmissionController struct {
+	config AdmissionConfig
+	slots  chan struct{}
+
+	stats AdmissionStats
+	mu    sync.Mutex
+}
+
+// NewAdmissionController creates a controller, validating its config
+func NewAdmissionController(config AdmissionConfig) (*AdmissionController, error) {
+	if config.HardCapacity <= 0 {
+		return nil, fmt.Errorf("hard_capacity must be positive")
+	}
+	if config.DowngradeMargin < 0 || config.MaxQueue < 0 || config.QueueTimeout < 0 {
+		return nil, fmt.Errorf("downgrade_margin, max_queue and queue_timeout must not be negative")
+	}
+	if config.DowngradeMargin == 0 {
+		config.DowngradeMargin = 0.1
+	}
+	if config.QueueTimeout == 0 {
+		config.QueueTimeout = 100 * time.Millisecond
+	}
+	return &AdmissionController{
+		config: config,
+		slots:  make(chan struct{}, config.HardCapacity),
+	}, nil
+}
+
+// Admit reserves hard capacity for a request whose hard probability exceeds
+// the threshold by margin. It returns the admission outcome ("" when
+// admitted immediately) and whether a slot was taken, which the caller must
+// Release. Requests forced to hard by guardrails are never downgraded; when
+// they cannot wait they proceed without a slot.
+func (ac *AdmissionController) Admit(margin float64, downgradable bool) (outcome string, slot bool) {
+	select {
+	case ac.slots <- struct{}{}:
+		ac.record(func(s *AdmissionStats) { s.Admitted++ })
+		return "", true
+	default:
+	}
+
+	// Borderline requests free capacity for clearly hard work
+	if downgradable && margin <= ac.config.DowngradeMargin {
+		return ac.downgrade(), false
+	}
+
+	ac.mu.Lock()
+	if ac.stats.Waiting >= ac.config.MaxQueue {
+		ac.mu.Unlock()
+		return ac.overflow(downgradable), false
+	}
+	ac.stats.Waiting++
+	ac.mu.Unlock()
+
+	timer := time.NewTimer(ac.config.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case ac.slots <- struct{}{}:
+		ac.record(func(s *AdmissionStats) {
+			s.Waiting--
+			s.Admitted++
+			s.Queued++
+		})
+		return AdmissionQueued, true
+	case <-timer.C:
+		ac.record(func(s *AdmissionStats) {
+			s.Waiting--
+			s.QueueTimeouts++
+		})
+		return ac.overflow(downgradable), false
+	}
+}
+
+// overflow handles a request that cannot wait for capacity
+func (ac *AdmissionController) overflow(downgradable bool) string {
+	if !downgradable {
+		return ""
+	}
+	return ac.downgrade()
+}
+
+func (ac *AdmissionController) downgrade() string {
+	ac.record(func(s *AdmissionStats) { s.Downgraded++ })
+	return AdmissionDowngraded
+}
+
+// Release returns a slot taken by Admit
+func (ac *AdmissionController) Release() {
+	select {
+	case <-ac.slots:
+	default:
+	}
+}
+
+func (ac *AdmissionController) record(update func(*AdmissionStats)) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	update(&ac.stats)
+}
+
+// GetStats returns the controller's counters
+func (ac *AdmissionController) GetStats() AdmissionStats {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	stats := ac.stats
+	stats.InFlight = len(ac.slots)
+	return stats
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionController(t *testing.T) {
+	config := AdmissionConfig{Enabled: true, HardCapacity: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond}
+
+	t.Run("should admit requests while capacity remains", func(t *testing.T) {
+		ac, err := NewAdmissionController(config)
+		require.NoError(t, err)
+		outcome, slot := ac.Admit(0.5, true)
+		assert.Equal(t, "", outcome)
+		assert.True(t, slot)
+		assert.Equal(t, 1, ac.GetStats().InFlight)
+	})
+
+	t.Run("should downgrade borderline requests at capacity", func(t *testing.T) {
+		ac, err := NewAdmissionController(config)
+		require.NoError(t, err)
+		ac.Admit(0.5, true)
+
+		outcome, slot := ac.Admit(0.05, true)
+		assert.Equal(t, AdmissionDowngraded, outcome)
+		assert.False(t, slot)
+		assert.Equal(t, int64(1), ac.GetStats().Downgraded)
+	})
+
+	t.Run("should queue clearly hard requests until a slot frees", func(t *testing.T) {
+		ac, err := NewAdmissionController(AdmissionConfig{HardCapacity: 1, MaxQueue: 1, QueueTimeout: time.Second})
+		require.NoError(t, err)
+		ac.Admit(0.5, true)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			ac.Release()
+		}()
+		outcome, slot := ac.Admit(0.5, true)
+		assert.Equal(t, AdmissionQueued, outcome)
+		assert.True(t, slot)
+		assert.Equal(t, int64(1), ac.GetStats().Queued)
+	})
+
+	t.Run("should downgrade when the wait times out", func(t *testing.T) {
+		ac, err := NewAdmissionController(config)
+		require.NoError(t, err)
+		ac.Admit(0.5, true)
+
+		outcome, slot := ac.Admit(0.5, true)
+		assert.Equal(t, AdmissionDowngraded, outcome)
+		assert.False(t, slot)
+		stats := ac.GetStats()
+		assert.Equal(t, int64(1), stats.QueueTimeouts)
+		assert.Equal(t, 0, stats.Waiting)
+	})
+
+	t.Run("should downgrade without waiting when the queue is full", func(t *testing.T) {
+		ac, err := NewAdmissionController(AdmissionConfig{HardCapacity: 1, QueueTimeout: time.Second})
+		require.NoError(t, err)
+		ac.Admit(0.5, true)
+
+		start := time.Now()
+		outcome, _ := ac.Admit(0.5, true)
+		assert.Equal(t, AdmissionDowngraded, outcome)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("should never downgrade requests that need hard capacity", func(t *testing.T) {
+		ac, err := NewAdmissionController(AdmissionConfig{HardCapacity: 1})
+		require.NoError(t, err)
+		ac.Admit(0.5, true)
+
+		outcome, slot := ac.Admit(0.01, false)
+		assert.Equal(t, "", outcome)
+		assert.False(t, slot)
+		assert.Equal(t, int64(0), ac.GetStats().Downgraded)
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		_, err := NewAdmissionController(AdmissionConfig{})
+		assert.ErrorContains(t, err, "hard_capacity must be positive")
+		_, err = NewAdmissionController(AdmissionConfig{HardCapacity: 1, MaxQueue: -1})
+		assert.Error(t, err)
+	})
+}
+
+func TestAdmissionRouting(t *testing.T) {
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}
+	newPlugin := func(t *testing.T, hard float64) *Plugin {
+		config := createRouterTestConfig()
+		config.Admission = AdmissionConfig{Enabled: true, HardCapacity: 1}
+		plugin, err := NewWithOptions(config,
+			WithFeatureExtractor(&stubExtractor{features: &RequestFeatures{TokenCount: 10}}),
+			WithTriageModel(&stubTriage{probs: &BucketProbabilities{Hard: hard}}))
+		require.NoError(t, err)
+		plugin.currentArtifact = &AvengersArtifact{Version: "stub"}
+		plugin.lastArtifactLoad = time.Now()
+		return plugin
+	}
+
+	t.Run("should hold a hard slot for admitted requests", func(t *testing.T) {
+		plugin := newPlugin(t, 0.9)
+		response, err := plugin.decide(req, map[string][]string{})
+		require.NoError(t, err)
+		assert.Equal(t, BucketHard, response.Bucket)
+		assert.True(t, response.hardSlot)
+		assert.Empty(t, response.Admission)
+		assert.Equal(t, 1, plugin.GetMetrics()["admission"].(AdmissionStats).InFlight)
+	})
+
+	t.Run("should not cache hard decisions, which must pass admission", func(t *testing.T) {
+		plugin := newPlugin(t, 0.9)
+		content := "hello"
+		ctx := context.Background()
+		_, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, BucketHard, ctx.Value("heimdall_bucket"))
+		assert.Zero(t, plugin.cache.Len())
+	})
+
+	t.Run("should route borderline requests to mid at capacity", func(t *testing.T) {
+		threshold := createRouterTestConfig().Router.Thresholds.Hard
+		plugin := newPlugin(t, threshold+0.05)
+		plugin.admission.Admit(1, true)
+
+		response, err := plugin.decide(req, map[string][]string{})
+		require.NoError(t, err)
+		assert.Equal(t, BucketMid, response.Bucket)
+		assert.Equal(t, AdmissionDowngraded, response.Admission)
+		assert.Contains(t, plugin.config.Router.MidCandidates, response.Decision.Model)
+		assert.False(t, response.hardSlot)
+	})
+}
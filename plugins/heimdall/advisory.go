@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+)
+
+// maxAdvisoryBody bounds the chat request accepted by the advisory endpoint
+const maxAdvisoryBody = 10 << 20
+
+// DecisionExplanation is why the decision's bucket ranked its model first
+type DecisionExplanation struct {
+	ArtifactVersion string  `json:"artifact_version"`
+	Alpha           float64 `json:"alpha"`
+	// Scores are the bucket's available candidates, best α-score first.
+	// Policy, BYOK and cascade choices made after scoring are not shown.
+	Scores []ModelScore `json:"scores"`
+}
+
+// RoutingHandler returns the advisory routing service: POST /v1/route takes
+// an OpenAI-style chat completion body and answers with the decision in the
+// stable RouterResponse encoding (see decision_codec.go) instead of
+// forwarding the request, so gateways other than Bifrost can route with
+// Heimdall. Add ?explain=true for the candidates' scores. Callers
+// authenticate with the same headers they would send upstream.
+func (p *Plugin) RoutingHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/route", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		p.handleRoute(w, r)
+	})
+	return mux
+}
+
+func (p *Plugin) handleRoute(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxAdvisoryBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var body RequestBody
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid chat completion body: "+err.Error())
+		return
+	}
+
+	response, err := p.Advise(r.Context(), &RouterRequest{
+		URL:     "/v1/chat/completions",
+		Method:  http.MethodPost,
+		Headers: r.Header,
+		Body:    &body,
+		RawBody: data,
+	}, r.URL.Query().Get("explain") == "true")
+	if err != nil {
+		var authErr *AuthenticationError
+		var rejection *RejectionError
+		switch {
+		case errors.As(err, &authErr):
+			p.writeRejection(w, authErr.rejection())
+		case errors.As(err, &rejection):
+			p.writeRejection(w, rejection)
+		case errors.Is(err, ErrArtifactUnavailable):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	encoded, err := EncodeRouterResponse(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+}
+
+// Advise makes a routing decision, through any injected middleware, without
+// dispatching the request. Hard capacity taken by admission control is
+// released at once, as the caller's request is not tracked to completion.
+func (p *Plugin) Advise(ctx context.Context, req *RouterRequest, explain bool) (*RouterResponse, error) {
+	p.metricsMu.Lock()
+	p.requestCount++
+	p.metricsMu.Unlock()
+
+	response, err := p.decideChain(ctx, req, req.Headers)
+	if err != nil {
+		p.metricsMu.Lock()
+		p.errorCount++
+		p.metricsMu.Unlock()
+		return nil, err
+	}
+	if response.hardSlot {
+		p.admission.Release()
+		response.hardSlot = false
+	}
+	if explain {
+		response.Explanation = p.explain(response)
+	}
+	return response, nil
+}
+
+// explain scores the decision bucket's available candidates
+func (p *Plugin) explain(response *RouterResponse) *DecisionExplanation {
+	artifact := p.scoringArtifact()
+	if artifact == nil {
+		return nil
+	}
+	candidates, err := p.bucketCandidates(string(response.Bucket))
+	if err != nil {
+		return nil
+	}
+	candidates = router.Constrain(p.availableCandidates(candidates, &response.Features), &response.Features, artifact)
+
+	explanation := &DecisionExplanation{ArtifactVersion: artifact.Version, Alpha: artifact.Alpha}
+	if len(candidates) == 0 {
+		return explanation
+	}
+	if _, scores, err := p.alphaScorer.SelectBestWithExplanation(candidates, &response.Features, artifact); err == nil {
+		explanation.Scores = scores
+	}
+	return explanation
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingHandler(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	server := httptest.NewServer(plugin.RoutingHandler())
+	defer server.Close()
+
+	route := func(t *testing.T, query, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest("POST", server.URL+"/v1/route"+query, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer sk-test-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	const chat = `{"model": "auto", "messages": [{"role": "user", "content": "What is 2+2?"}]}`
+
+	t.Run("should return the decision in the stable encoding", func(t *testing.T) {
+		resp, data := route(t, "", chat)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+
+		decoded, err := DecodeRouterResponse(data)
+		require.NoError(t, err)
+		assert.NotEmpty(t, decoded.Decision.Model)
+		assert.NotEmpty(t, decoded.Bucket)
+		assert.Nil(t, decoded.Explanation)
+		assert.NotContains(t, string(data), "sk-test-key", "tokens are fingerprinted")
+	})
+
+	t.Run("should explain on request", func(t *testing.T) {
+		resp, data := route(t, "?explain=true", chat)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+
+		decoded, err := DecodeRouterResponse(data)
+		require.NoError(t, err)
+		require.NotNil(t, decoded.Explanation)
+		require.NotEmpty(t, decoded.Explanation.Scores)
+		assert.Equal(t, plugin.currentArtifact.Version, decoded.Explanation.ArtifactVersion)
+		for i := 1; i < len(decoded.Explanation.Scores); i++ {
+			assert.GreaterOrEqual(t, decoded.Explanation.Scores[i-1].AlphaScore, decoded.Explanation.Scores[i].AlphaScore)
+		}
+	})
+
+	t.Run("should reject malformed bodies and methods", func(t *testing.T) {
+		resp, _ := route(t, "", `{"messages": "hi"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		get, err := http.Get(server.URL + "/v1/route")
+		require.NoError(t, err)
+		get.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, get.StatusCode)
+	})
+}
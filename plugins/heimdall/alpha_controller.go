@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+)
+
+// AlphaControllerConfig configures closed-loop adjustment of the artifact's
+// α against service level objectives. Missing the success rate target
+// raises α towards quality; missing the latency or cost target lowers it
+// towards cheaper, faster models.
+type AlphaControllerConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Interval is how often the controller evaluates the last window
+	// (default 5m)
+	Interval time.Duration `json:"interval"`
+	// MinSamples is the requests a window needs before it is acted on;
+	// smaller windows carry over to the next evaluation (default 100)
+	MinSamples int `json:"min_samples"`
+
+	// Targets; a zero target is not enforced. At least one is required.
+	TargetSuccessRate    float64       `json:"target_success_rate"`
+	TargetP95Latency     time.Duration `json:"target_p95_latency"`
+	TargetCostPerRequest float64       `json:"target_cost_per_request"` // USD
+
+	// Step is the α change per evaluation (default 0.02)
+	Step float64 `json:"step"`
+	// MaxOffset bounds the total adjustment from the artifact's α
+	// (default 0.2)
+	MaxOffset float64 `json:"max_offset"`
+	// MinAlpha and MaxAlpha bound the adjusted α (default 0.1 and 0.95)
+	MinAlpha float64 `json:"min_alpha"`
+	MaxAlpha float64 `json:"max_alpha"`
+
+	// Pricing is per-model USD pricing used to cost usage (defaults to the
+	// built-in pricing snapshot)
+	Pricing map[string]catalog.ModelPricing `json:"pricing"`
+
+	// History is how many recent adjustments are kept (default 100)
+	History int `json:"history"`
+}
+
+// SLOWindow summarizes the outcomes observed since the last evaluation
+type SLOWindow struct {
+	Requests       int           `json:"requests"`
+	SuccessRate    float64       `json:"success_rate"`
+	P95Latency     time.Duration `json:"p95_latency"`
+	CostPerRequest float64       `json:"cost_per_request"`
+}
+
+// AlphaAdjustment records a change to the α offset
+type AlphaAdjustment struct {
+	Timestamp time.Time  `json:"timestamp"`
+	OldOffset float64    `json:"old_offset"`
+	NewOffset float64    `json:"new_offset"`
+	Reason    string     `json:"reason"`
+	Window    *SLOWindow `json:"window,omitempty"`
+}
+
+// AlphaControllerStatus is the controller's state for the admin API
+type AlphaControllerStatus struct {
+	// Offset is added to the artifact's α (then bounded)
+	Offset     float64           `json:"offset"`
+	Killed     bool              `json:"killed"`
+	KillReason string            `json:"kill_reason,omitempty"`
+	Window     SLOWindow         `json:"window"`
+	History    []AlphaAdjustment `json:"history"`
+}
+
+// AlphaController adjusts α on a schedule to hold success rate, p95
+// latency and cost per request at their targets. Adjustments are bounded
+// per step and in total, and a kill switch reverts to the artifact's α.
+// It replaces the open-loop scoring.AlphaScorer.TuneAlphaParameter.
+type AlphaController struct {
+	config AlphaControllerConfig
+	now    func() time.Time
+
+	offset     float64
+	killed     bool
+	killReason string
+	history    []AlphaAdjustment
+
+	// The current window
+	requests  int
+	successes int
+	latencies []time.Duration
+	cost      float64
+
+	mu       sync.Mutex
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewAlphaController creates a controller, filling defaults
+func NewAlphaController(config AlphaControllerConfig, now func() time.Time) (*AlphaController, error) {
+	if config.Interval == 0 {
+		config.Interval = 5 * time.Minute
+	}
+	if config.MinSamples == 0 {
+		config.MinSamples = 100
+	}
+	if config.Step == 0 {
+		config.Step = 0.02
+	}
+	if config.MaxOffset == 0 {
+		config.MaxOffset = 0.2
+	}
+	if config.MinAlpha == 0 {
+		config.MinAlpha = 0.1
+	}
+	if config.MaxAlpha == 0 {
+		config.MaxAlpha = 0.95
+	}
+	if config.Pricing == nil {
+		config.Pricing = builtinDefault.Pricing
+	}
+	if config.History == 0 {
+		config.History = 100
+	}
+	if config.TargetSuccessRate == 0 && config.TargetP95Latency == 0 && config.TargetCostPerRequest == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	if config.TargetSuccessRate < 0 || config.TargetSuccessRate > 1 {
+		return nil, fmt.Errorf("target_success_rate must be within [0, 1], got %v", config.TargetSuccessRate)
+	}
+	if config.TargetP95Latency < 0 || config.TargetCostPerRequest < 0 {
+		return nil, fmt.Errorf("targets must not be negative")
+	}
+	if config.Interval < 0 || config.MinSamples < 0 || config.History < 0 {
+		return nil, fmt.Errorf("interval, min_samples and history must be positive")
+	}
+	if config.Step < 0 || config.MaxOffset < 0 || config.Step > config.MaxOffset {
+		return nil, fmt.Errorf("step must be positive and at most max_offset")
+	}
+	if config.MinAlpha < 0 || config.MaxAlpha > 1 || config.MinAlpha >= config.MaxAlpha {
+		return nil, fmt.Errorf("min_alpha and max_alpha must satisfy 0 <= min_alpha < max_alpha <= 1")
+	}
+
+	return &AlphaController{
+		config: config,
+		now:    now,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// Start evaluates on the configured interval in the background, passing
+// each adjustment to onAdjust
+func (ac *AlphaController) Start(onAdjust func(AlphaAdjustment)) {
+	go func() {
+		ticker := time.NewTicker(ac.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if adjustment := ac.Evaluate(); adjustment != nil && onAdjust != nil {
+					onAdjust(*adjustment)
+				}
+			case <-ac.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts background evaluation
+func (ac *AlphaController) Stop() {
+	ac.stopOnce.Do(func() {
+		close(ac.stopCh)
+	})
+}
+
+// Record adds a request outcome to the current window. Latency counts only
+// for successful requests; usage may be nil.
+func (ac *AlphaController) Record(model string, success bool, latency time.Duration, usage *schemas.LLMUsage) {
+	var cost float64
+	if usage != nil {
+		cost = usageCost(ac.config.Pricing[model], usage)
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.requests++
+	if success {
+		ac.successes++
+		ac.latencies = append(ac.latencies, latency)
+	}
+	ac.cost += cost
+}
+
+// window summarizes the current window; callers hold mu
+func (ac *AlphaController) window() SLOWindow {
+	window := SLOWindow{Requests: ac.requests}
+	if ac.requests == 0 {
+		return window
+	}
+	window.SuccessRate = float64(ac.successes) / float64(ac.requests)
+	window.CostPerRequest = ac.cost / float64(ac.requests)
+	if len(ac.latencies) > 0 {
+		sorted := append([]time.Duration(nil), ac.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		window.P95Latency = sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	}
+	return window
+}
+
+// resetWindow starts a new window; callers hold mu
+func (ac *AlphaController) resetWindow() {
+	ac.requests, ac.successes, ac.cost = 0, 0, 0
+	ac.latencies = ac.latencies[:0]
+}
+
+// Evaluate compares the current window with the targets and steps the
+// offset, returning the adjustment made if any. Success rate takes
+// precedence: α is not lowered for latency or cost while requests fail.
+func (ac *AlphaController) Evaluate() *AlphaAdjustment {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if ac.killed {
+		ac.resetWindow()
+		return nil
+	}
+	if ac.requests < ac.config.MinSamples {
+		return nil
+	}
+	window := ac.window()
+	ac.resetWindow()
+
+	var step float64
+	var reason string
+	switch {
+	case ac.config.TargetSuccessRate > 0 && window.SuccessRate < ac.config.TargetSuccessRate:
+		step, reason = ac.config.Step, fmt.Sprintf("success rate %.4f below target %.4f", window.SuccessRate, ac.config.TargetSuccessRate)
+	case ac.config.TargetP95Latency > 0 && window.P95Latency > ac.config.TargetP95Latency:
+		step, reason = -ac.config.Step, fmt.Sprintf("p95 latency %s above target %s", window.P95Latency, ac.config.TargetP95Latency)
+	case ac.config.TargetCostPerRequest > 0 && window.CostPerRequest > ac.config.TargetCostPerRequest:
+		step, reason = -ac.config.Step, fmt.Sprintf("cost per request %.6f above target %.6f", window.CostPerRequest, ac.config.TargetCostPerRequest)
+	default:
+		return nil
+	}
+
+	offset := math.Max(-ac.config.MaxOffset, math.Min(ac.config.MaxOffset, ac.offset+step))
+	if offset == ac.offset {
+		return nil
+	}
+	return ac.adjust(offset, reason, &window)
+}
+
+// adjust sets the offset and records the change; callers hold mu
+func (ac *AlphaController) adjust(offset float64, reason string, window *SLOWindow) *AlphaAdjustment {
+	adjustment := AlphaAdjustment{
+		Timestamp: ac.now(),
+		OldOffset: ac.offset,
+		NewOffset: offset,
+		Reason:    reason,
+		Window:    window,
+	}
+	ac.offset = offset
+	ac.history = append(ac.history, adjustment)
+	if len(ac.history) > ac.config.History {
+		ac.history = ac.history[len(ac.history)-ac.config.History:]
+	}
+	return &adjustment
+}
+
+// Alpha returns the α to score with given the artifact's
+func (ac *AlphaController) Alpha(base float64) float64 {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ac.offset == 0 {
+		return base
+	}
+	return math.Max(ac.config.MinAlpha, math.Min(ac.config.MaxAlpha, base+ac.offset))
+}
+
+// Kill stops adjustment and reverts to the artifact's α until Resume
+func (ac *AlphaController) Kill(reason string) *AlphaAdjustment {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.killed = true
+	ac.killReason = reason
+	ac.resetWindow()
+	if ac.offset == 0 {
+		return nil
+	}
+	return ac.adjust(0, "kill switch: "+reason, nil)
+}
+
+// Inherit copies a replaced controller's offset, kill switch and history
+func (ac *AlphaController) Inherit(old *AlphaController) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	ac.offset = math.Max(-ac.config.MaxOffset, math.Min(ac.config.MaxOffset, old.offset))
+	ac.killed, ac.killReason = old.killed, old.killReason
+	ac.history = append([]AlphaAdjustment{}, old.history...)
+	if len(ac.history) > ac.config.History {
+		ac.history = ac.history[len(ac.history)-ac.config.History:]
+	}
+}
+
+// Resume restarts adjustment from the artifact's α
+func (ac *AlphaController) Resume() error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if !ac.killed {
+		return fmt.Errorf("alpha controller is not killed")
+	}
+	ac.killed = false
+	ac.killReason = ""
+	return nil
+}
+
+// GetStatus returns the controller's state, history oldest first
+func (ac *AlphaController) GetStatus() AlphaControllerStatus {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return AlphaControllerStatus{
+		Offset:     ac.offset,
+		Killed:     ac.killed,
+		KillReason: ac.killReason,
+		Window:     ac.window(),
+		History:    append([]AlphaAdjustment{}, ac.history...),
+	}
+}
+
+// scoringArtifact is the current artifact with the controller's α applied
+func (p *Plugin) scoringArtifact() *AvengersArtifact {
+	if p.alphaController == nil || p.currentArtifact == nil {
+		return p.currentArtifact
+	}
+	alpha := p.alphaController.Alpha(p.currentArtifact.Alpha)
+	if alpha == p.currentArtifact.Alpha {
+		return p.currentArtifact
+	}
+	adjusted := *p.currentArtifact
+	adjusted.Alpha = alpha
+	return &adjusted
+}
+
+// auditAlpha records a controller adjustment in the audit trail
+func (p *Plugin) auditAlpha(adjustment AlphaAdjustment) {
+	p.logger.Printf("Alpha offset %+.3f -> %+.3f: %s", adjustment.OldOffset, adjustment.NewOffset, adjustment.Reason)
+	if p.audit == nil {
+		return
+	}
+	p.audit.Record(AuditEntry{
+		Actor:   "alpha_controller",
+		Kind:    AuditAlpha,
+		Action:  "adjusted",
+		Reason:  adjustment.Reason,
+		Changes: diffFields("alpha.offset", adjustment.OldOffset, adjustment.NewOffset),
+	})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlphaController(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newController := func(t *testing.T, config AlphaControllerConfig) *AlphaController {
+		config.MinSamples = 4
+		config.Step = 0.05
+		config.MaxOffset = 0.1
+		ac, err := NewAlphaController(config, func() time.Time { return now })
+		require.NoError(t, err)
+		return ac
+	}
+	record := func(ac *AlphaController, n int, success bool, latency time.Duration, usage *schemas.LLMUsage) {
+		for i := 0; i < n; i++ {
+			ac.Record("a", success, latency, usage)
+		}
+	}
+
+	t.Run("should raise alpha when the success rate misses its target", func(t *testing.T) {
+		ac := newController(t, AlphaControllerConfig{TargetSuccessRate: 0.9, TargetP95Latency: time.Second})
+		record(ac, 3, true, 2*time.Second, nil)
+		record(ac, 1, false, 0, nil)
+
+		adjustment := ac.Evaluate()
+		require.NotNil(t, adjustment)
+		assert.Equal(t, 0.05, adjustment.NewOffset)
+		assert.Contains(t, adjustment.Reason, "success rate 0.7500 below target")
+		assert.Equal(t, 0.75, ac.Alpha(0.7))
+		assert.Equal(t, now, adjustment.Timestamp)
+	})
+
+	t.Run("should lower alpha for latency and cost, within bounds", func(t *testing.T) {
+		ac := newController(t, AlphaControllerConfig{
+			TargetCostPerRequest: 0.001,
+			Pricing:              map[string]catalog.ModelPricing{"a": {InPerMillion: 10}},
+		})
+		for i := 0; i < 3; i++ {
+			record(ac, 4, true, time.Second, &schemas.LLMUsage{PromptTokens: 1000})
+			ac.Evaluate()
+		}
+
+		status := ac.GetStatus()
+		assert.InDelta(t, -0.1, status.Offset, 1e-9, "capped at max_offset")
+		require.Len(t, status.History, 2)
+		assert.Contains(t, status.History[0].Reason, "cost per request")
+		assert.Equal(t, 0.1, ac.Alpha(0.12), "bounded by min_alpha")
+	})
+
+	t.Run("should wait for enough samples", func(t *testing.T) {
+		ac := newController(t, AlphaControllerConfig{TargetSuccessRate: 0.9})
+		record(ac, 3, false, 0, nil)
+		assert.Nil(t, ac.Evaluate())
+		record(ac, 1, false, 0, nil)
+		assert.NotNil(t, ac.Evaluate(), "the short window carries over")
+	})
+
+	t.Run("should revert and hold while killed", func(t *testing.T) {
+		ac := newController(t, AlphaControllerConfig{TargetSuccessRate: 0.9})
+		record(ac, 4, false, 0, nil)
+		ac.Evaluate()
+
+		adjustment := ac.Kill("incident")
+		require.NotNil(t, adjustment)
+		assert.Equal(t, 0.0, adjustment.NewOffset)
+		assert.Equal(t, 0.7, ac.Alpha(0.7))
+
+		record(ac, 4, false, 0, nil)
+		assert.Nil(t, ac.Evaluate())
+		assert.True(t, ac.GetStatus().Killed)
+
+		require.NoError(t, ac.Resume())
+		assert.Error(t, ac.Resume())
+		record(ac, 4, false, 0, nil)
+		assert.NotNil(t, ac.Evaluate())
+	})
+
+	t.Run("should reject invalid configs", func(t *testing.T) {
+		for _, config := range []AlphaControllerConfig{
+			{},
+			{TargetSuccessRate: 1.5},
+			{TargetSuccessRate: 0.9, Step: 0.5, MaxOffset: 0.1},
+			{TargetSuccessRate: 0.9, MinAlpha: 0.8, MaxAlpha: 0.5},
+		} {
+			_, err := NewAlphaController(config, time.Now)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestAlphaControllerRouting(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Audit = AuditConfig{Enabled: true}
+	config.AlphaController = AlphaControllerConfig{Enabled: true, TargetSuccessRate: 0.9, MinSamples: 1}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	defer plugin.Cleanup()
+	base := plugin.currentArtifact.Alpha
+
+	t.Run("should score with the adjusted alpha", func(t *testing.T) {
+		assert.Same(t, plugin.currentArtifact, plugin.scoringArtifact())
+
+		plugin.alphaController.Record("a", false, 0, nil)
+		adjustment := plugin.alphaController.Evaluate()
+		require.NotNil(t, adjustment)
+		plugin.auditAlpha(*adjustment)
+
+		assert.InDelta(t, base+0.02, plugin.scoringArtifact().Alpha, 1e-9)
+		assert.Equal(t, base, plugin.currentArtifact.Alpha, "the artifact is not modified")
+		entries := plugin.audit.Entries(0, 0)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "alpha_controller", entries[0].Actor)
+		assert.Contains(t, entries[0].Reason, "success rate")
+	})
+
+	t.Run("should kill and resume through the admin API", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/admin/alpha/kill", "application/json", strings.NewReader(`{"reason": "incident"}`))
+		require.NoError(t, err)
+		var status AlphaControllerStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		resp.Body.Close()
+		assert.True(t, status.Killed)
+		assert.Equal(t, "incident", status.KillReason)
+		assert.Len(t, status.History, 2)
+		assert.Equal(t, base, plugin.scoringArtifact().Alpha)
+
+		req, _ := http.NewRequest("DELETE", server.URL+"/admin/alpha/kill", nil)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		entries := plugin.audit.Entries(1, 0)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "killed", entries[0].Action)
+		assert.Equal(t, "resumed", entries[1].Action)
+	})
+}
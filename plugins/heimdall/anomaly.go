@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+)
+
+// CostAnomalyConfig configures detection of runaway spend per tenant and model
+type CostAnomalyConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Window is the number of recent requests whose mean cost is compared
+	// with the baseline (default 20)
+	Window int `json:"window"`
+	// MinBaselineSamples is the requests a baseline needs before anomalies
+	// are reported (default 50)
+	MinBaselineSamples int `json:"min_baseline_samples"`
+	// BaselineWeight is the EWMA weight of each request in the baseline
+	// (default 0.05)
+	BaselineWeight float64 `json:"baseline_weight"`
+	// Multiplier is how many times the baseline the recent mean must reach
+	// to be anomalous (default 5)
+	Multiplier float64 `json:"multiplier"`
+
+	// ForceCheap caps an anomalous tenant to the cheap bucket until the
+	// anomaly is acknowledged
+	ForceCheap bool `json:"force_cheap"`
+
+	// Pricing is per-model USD pricing used to cost usage (defaults to the
+	// built-in pricing snapshot)
+	Pricing map[string]catalog.ModelPricing `json:"pricing"`
+
+	// WebhookURL receives anomaly and acknowledgement events (optional)
+	WebhookURL     string        `json:"webhook_url"`
+	WebhookTimeout time.Duration `json:"webhook_timeout"`
+}
+
+// CostAnomalyEvent is posted to the webhook on state changes
+type CostAnomalyEvent struct {
+	Event        string    `json:"event"` // "cost_anomaly" or "acknowledged"
+	Tenant       string    `json:"tenant"`
+	Model        string    `json:"model,omitempty"`
+	RecentCost   float64   `json:"recent_cost,omitempty"`
+	BaselineCost float64   `json:"baseline_cost,omitempty"`
+	ForcedCheap  bool      `json:"forced_cheap"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// CostAnomaly reports an unacknowledged anomaly
+type CostAnomaly struct {
+	Tenant       string    `json:"tenant"`
+	Model        string    `json:"model"`
+	RecentCost   float64   `json:"recent_cost"`   // mean USD per request over the window
+	BaselineCost float64   `json:"baseline_cost"` // baseline USD per request
+	DetectedAt   time.Time `json:"detected_at"`
+	ForcedCheap  bool      `json:"forced_cheap"`
+}
+
+// TenantCostSeries is a tenant's stored cost history for one model
+type TenantCostSeries struct {
+	Model           string       `json:"model"`
+	RecentCosts     []float64    `json:"recent_costs"` // oldest first
+	BaselineCost    float64      `json:"baseline_cost"`
+	BaselineSamples int          `json:"baseline_samples"`
+	Anomaly         *CostAnomaly `json:"anomaly,omitempty"`
+}
+
+// costSeries is a ring of recent request costs and the baseline they age into
+type costSeries struct {
+	recent []float64
+	next   int
+	filled bool
+
+	baseline float64
+	samples  int
+
+	anomaly *CostAnomaly
+}
+
+func (s *costSeries) recentMean() float64 {
+	if !s.filled {
+		return 0
+	}
+	total := 0.0
+	for _, cost := range s.recent {
+		total += cost
+	}
+	return total / float64(len(s.recent))
+}
+
+// CostAnomalyDetector watches rolling cost per request by tenant and model
+// and flags sharp deviations from baseline, such as runaway agent loops
+type CostAnomalyDetector struct {
+	config     CostAnomalyConfig
+	httpClient *http.Client
+	// onEvent also receives events, under the detector's lock (optional)
+	onEvent func(CostAnomalyEvent)
+
+	series map[string]*costSeries // keyed by tenant and model
+	mu     sync.Mutex
+}
+
+// NewCostAnomalyDetector creates a detector, filling defaults
+func NewCostAnomalyDetector(config CostAnomalyConfig) (*CostAnomalyDetector, error) {
+	if config.Window == 0 {
+		config.Window = 20
+	}
+	if config.MinBaselineSamples == 0 {
+		config.MinBaselineSamples = 50
+	}
+	if config.BaselineWeight == 0 {
+		config.BaselineWeight = 0.05
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = 5
+	}
+	if config.Pricing == nil {
+		config.Pricing = builtinDefault.Pricing
+	}
+	if config.WebhookTimeout == 0 {
+		config.WebhookTimeout = 5 * time.Second
+	}
+	if config.Window < 0 || config.MinBaselineSamples < 0 {
+		return nil, fmt.Errorf("window and min_baseline_samples must be positive")
+	}
+	if config.BaselineWeight < 0 || config.BaselineWeight > 1 {
+		return nil, fmt.Errorf("baseline_weight must be within [0, 1], got %v", config.BaselineWeight)
+	}
+	if config.Multiplier <= 1 {
+		return nil, fmt.Errorf("multiplier must exceed 1, got %v", config.Multiplier)
+	}
+
+	return &CostAnomalyDetector{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: config.WebhookTimeout,
+		},
+		series: make(map[string]*costSeries),
+	}, nil
+}
+
+// Record accounts a response's cost against its tenant and model. Costs
+// join the baseline only once they leave the recent window, so a sudden
+// spike cannot drag the baseline up before it is detected.
+func (cd *CostAnomalyDetector) Record(tenant, model string, usage *schemas.LLMUsage) {
+	if tenant == "" || usage == nil {
+		return
+	}
+	cost := usageCost(cd.config.Pricing[model], usage)
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	key := tenant + "\x00" + model
+	series, ok := cd.series[key]
+	if !ok {
+		series = &costSeries{recent: make([]float64, cd.config.Window)}
+		cd.series[key] = series
+	}
+
+	if series.filled && series.anomaly == nil {
+		aged := series.recent[series.next]
+		if series.samples == 0 {
+			series.baseline = aged
+		} else {
+			series.baseline += cd.config.BaselineWeight * (aged - series.baseline)
+		}
+		series.samples++
+	}
+	series.recent[series.next] = cost
+	series.next = (series.next + 1) % len(series.recent)
+	if series.next == 0 {
+		series.filled = true
+	}
+
+	if series.anomaly != nil || series.samples < cd.config.MinBaselineSamples || series.baseline <= 0 {
+		return
+	}
+	recent := series.recentMean()
+	if recent < cd.config.Multiplier*series.baseline {
+		return
+	}
+
+	series.anomaly = &CostAnomaly{
+		Tenant:       tenant,
+		Model:        model,
+		RecentCost:   recent,
+		BaselineCost: series.baseline,
+		DetectedAt:   time.Now(),
+		ForcedCheap:  cd.config.ForceCheap,
+	}
+	log.Printf("Cost anomaly for tenant %s on %s: $%.6f per request against a $%.6f baseline",
+		tenant, model, recent, series.baseline)
+	cd.notify(CostAnomalyEvent{
+		Event:        "cost_anomaly",
+		Tenant:       tenant,
+		Model:        model,
+		RecentCost:   recent,
+		BaselineCost: series.baseline,
+		ForcedCheap:  cd.config.ForceCheap,
+		Timestamp:    series.anomaly.DetectedAt,
+	})
+}
+
+// Forced reports whether a tenant is capped to the cheap bucket
+func (cd *CostAnomalyDetector) Forced(tenant string) bool {
+	if !cd.config.ForceCheap || tenant == "" {
+		return false
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	for _, series := range cd.series {
+		if series.anomaly != nil && series.anomaly.Tenant == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// Acknowledge clears a tenant's anomalies, lifting any cheap-bucket forcing.
+// The recent windows restart so a loop that is still running is detected
+// afresh rather than immediately.
+func (cd *CostAnomalyDetector) Acknowledge(tenant string) error {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	acknowledged := false
+	for _, series := range cd.series {
+		if series.anomaly == nil || series.anomaly.Tenant != tenant {
+			continue
+		}
+		series.anomaly = nil
+		series.next = 0
+		series.filled = false
+		acknowledged = true
+	}
+	if !acknowledged {
+		return fmt.Errorf("tenant %s has no cost anomaly", tenant)
+	}
+
+	log.Printf("Acknowledged cost anomaly for tenant %s", tenant)
+	cd.notify(CostAnomalyEvent{
+		Event:     "acknowledged",
+		Tenant:    tenant,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// GetStatus returns the unacknowledged anomalies
+func (cd *CostAnomalyDetector) GetStatus() []CostAnomaly {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	var anomalies []CostAnomaly
+	for _, series := range cd.series {
+		if series.anomaly != nil {
+			anomalies = append(anomalies, *series.anomaly)
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Tenant != anomalies[j].Tenant {
+			return anomalies[i].Tenant < anomalies[j].Tenant
+		}
+		return anomalies[i].Model < anomalies[j].Model
+	})
+	return anomalies
+}
+
+// TenantSeries returns the cost history stored for a tenant, by model
+func (cd *CostAnomalyDetector) TenantSeries(tenant string) []TenantCostSeries {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	var stored []TenantCostSeries
+	for key, series := range cd.series {
+		if !strings.HasPrefix(key, tenant+"\x00") {
+			continue
+		}
+		entry := TenantCostSeries{
+			Model:           strings.TrimPrefix(key, tenant+"\x00"),
+			BaselineCost:    series.baseline,
+			BaselineSamples: series.samples,
+		}
+		if series.filled {
+			entry.RecentCosts = append(append(entry.RecentCosts, series.recent[series.next:]...), series.recent[:series.next]...)
+		} else {
+			entry.RecentCosts = append(entry.RecentCosts, series.recent[:series.next]...)
+		}
+		if series.anomaly != nil {
+			anomaly := *series.anomaly
+			entry.Anomaly = &anomaly
+		}
+		stored = append(stored, entry)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Model < stored[j].Model })
+	return stored
+}
+
+// DeleteTenant drops a tenant's cost history, lifting any cheap-bucket
+// forcing without notifying the webhook, and returns how many series were
+// dropped
+func (cd *CostAnomalyDetector) DeleteTenant(tenant string) int {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	deleted := 0
+	for key := range cd.series {
+		if strings.HasPrefix(key, tenant+"\x00") {
+			delete(cd.series, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Inherit copies a replaced detector's baselines and open anomalies, so
+// acknowledged anomalies stay acknowledged and unacknowledged ones keep
+// their forcing. Recent windows carry over when the window is unchanged.
+func (cd *CostAnomalyDetector) Inherit(old *CostAnomalyDetector) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	for key, previous := range old.series {
+		series := &costSeries{recent: make([]float64, cd.config.Window), baseline: previous.baseline, samples: previous.samples}
+		if len(previous.recent) == len(series.recent) {
+			copy(series.recent, previous.recent)
+			series.next, series.filled = previous.next, previous.filled
+		}
+		if previous.anomaly != nil {
+			anomaly := *previous.anomaly
+			anomaly.ForcedCheap = cd.config.ForceCheap
+			series.anomaly = &anomaly
+		}
+		cd.series[key] = series
+	}
+}
+
+// notify posts an event to the webhook without blocking routing
+func (cd *CostAnomalyDetector) notify(event CostAnomalyEvent) {
+	if cd.onEvent != nil {
+		cd.onEvent(event)
+	}
+	if cd.config.WebhookURL == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		resp, err := cd.httpClient.Post(cd.config.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Cost anomaly webhook failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Cost anomaly webhook returned %d", resp.StatusCode)
+		}
+	}()
+}
+
+// usageCost prices a response's token usage in USD
+func usageCost(pricing catalog.ModelPricing, usage *schemas.LLMUsage) float64 {
+	return (float64(usage.PromptTokens)*pricing.InPerMillion +
+		float64(usage.CompletionTokens)*pricing.OutPerMillion) / 1e6
+}
+
+// tenantOf identifies the caller spend is attributed to: the organization
+// when known, otherwise the credential
+func tenantOf(authInfo *AuthInfo) string {
+	if authInfo == nil {
+		return ""
+	}
+	if authInfo.Org != "" {
+		return authInfo.Org
+	}
+	return authInfo.Token.Fingerprint()
+}
+
+// anomalyForced reports whether a cached decision must be remade because
+// its tenant is capped to the cheap bucket
+func (p *Plugin) anomalyForced(response *RouterResponse) bool {
+	return p.anomalies != nil && response.Bucket != BucketCheap && p.anomalies.Forced(tenantOf(response.AuthInfo))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostAnomalyDetector(t *testing.T) {
+	config := CostAnomalyConfig{
+		Window:             5,
+		MinBaselineSamples: 10,
+		ForceCheap:         true,
+		Pricing:            map[string]catalog.ModelPricing{"openai/gpt-4o": {InPerMillion: 1, OutPerMillion: 1}},
+	}
+	normal := &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000}
+	runaway := &schemas.LLMUsage{PromptTokens: 100000, CompletionTokens: 100000}
+
+	warmed := func(t *testing.T) *CostAnomalyDetector {
+		cd, err := NewCostAnomalyDetector(config)
+		require.NoError(t, err)
+		for i := 0; i < 15; i++ {
+			cd.Record("acme", "openai/gpt-4o", normal)
+		}
+		return cd
+	}
+
+	t.Run("should stay quiet at baseline spend", func(t *testing.T) {
+		cd := warmed(t)
+		assert.Empty(t, cd.GetStatus())
+		assert.False(t, cd.Forced("acme"))
+	})
+
+	t.Run("should flag a sharp rise and force the tenant to cheap", func(t *testing.T) {
+		cd := warmed(t)
+		for i := 0; i < 5; i++ {
+			cd.Record("acme", "openai/gpt-4o", runaway)
+		}
+
+		status := cd.GetStatus()
+		require.Len(t, status, 1)
+		assert.Equal(t, "acme", status[0].Tenant)
+		assert.InDelta(t, 0.002, status[0].BaselineCost, 1e-9)
+		assert.Greater(t, status[0].RecentCost, 5*status[0].BaselineCost)
+		assert.True(t, cd.Forced("acme"))
+		assert.False(t, cd.Forced("globex"))
+	})
+
+	t.Run("should lift forcing once acknowledged", func(t *testing.T) {
+		cd := warmed(t)
+		for i := 0; i < 5; i++ {
+			cd.Record("acme", "openai/gpt-4o", runaway)
+		}
+
+		require.NoError(t, cd.Acknowledge("acme"))
+		assert.False(t, cd.Forced("acme"))
+		assert.Empty(t, cd.GetStatus())
+		assert.Error(t, cd.Acknowledge("acme"))
+	})
+
+	t.Run("should need a baseline before flagging", func(t *testing.T) {
+		cd, err := NewCostAnomalyDetector(config)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			cd.Record("acme", "openai/gpt-4o", normal)
+		}
+		for i := 0; i < 5; i++ {
+			cd.Record("acme", "openai/gpt-4o", runaway)
+		}
+		assert.Empty(t, cd.GetStatus())
+	})
+
+	t.Run("should only alert when forcing is disabled", func(t *testing.T) {
+		alertOnly := config
+		alertOnly.ForceCheap = false
+		cd, err := NewCostAnomalyDetector(alertOnly)
+		require.NoError(t, err)
+		for i := 0; i < 15; i++ {
+			cd.Record("acme", "openai/gpt-4o", normal)
+		}
+		for i := 0; i < 5; i++ {
+			cd.Record("acme", "openai/gpt-4o", runaway)
+		}
+		assert.Len(t, cd.GetStatus(), 1)
+		assert.False(t, cd.Forced("acme"))
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		_, err := NewCostAnomalyDetector(CostAnomalyConfig{Multiplier: 0.5})
+		assert.ErrorContains(t, err, "multiplier must exceed 1")
+		_, err = NewCostAnomalyDetector(CostAnomalyConfig{BaselineWeight: 2})
+		assert.Error(t, err)
+	})
+}
+
+func TestCostAnomalyRouting(t *testing.T) {
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}
+	config := createRouterTestConfig()
+	config.CostAnomaly = CostAnomalyConfig{Enabled: true, ForceCheap: true}
+	config.Anonymous = AnonymousPolicyConfig{Mode: AnonymousModeHouse, DefaultTenant: "acme"}
+	plugin, err := NewWithOptions(config,
+		WithFeatureExtractor(&stubExtractor{features: &RequestFeatures{TokenCount: 10}}),
+		WithTriageModel(&stubTriage{probs: &BucketProbabilities{Hard: 0.9}}))
+	require.NoError(t, err)
+	plugin.currentArtifact = &AvengersArtifact{Version: "stub"}
+	plugin.lastArtifactLoad = time.Now()
+
+	plugin.anomalies.series["acme\x00openai/gpt-4o"] = &costSeries{
+		recent:  make([]float64, 1),
+		anomaly: &CostAnomaly{Tenant: "acme", Model: "openai/gpt-4o", ForcedCheap: true},
+	}
+	headers := map[string][]string{}
+
+	t.Run("should hold an anomalous tenant to the cheap bucket", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		assert.Equal(t, BucketCheap, response.Bucket)
+		assert.True(t, response.CostAnomaly)
+		assert.True(t, plugin.anomalyForced(&RouterResponse{Bucket: BucketHard, AuthInfo: response.AuthInfo}))
+	})
+
+	t.Run("should route normally once acknowledged via the admin API", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		httpReq, _ := http.NewRequest("DELETE", server.URL+"/admin/anomalies/acme", nil)
+		resp, err := http.DefaultClient.Do(httpReq)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		assert.Equal(t, BucketHard, response.Bucket)
+		assert.False(t, response.CostAnomaly)
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import "fmt"
+
+// Anonymous request policy modes, applied when no auth adapter matches
+const (
+	// AnonymousModeAllow keeps legacy behavior: full routing with house keys
+	AnonymousModeAllow = ""
+	// AnonymousModeReject rejects unauthenticated requests with 401
+	AnonymousModeReject = "reject"
+	// AnonymousModeHouse routes with house keys under the default tenant
+	AnonymousModeHouse = "house"
+	// AnonymousModeRestricted applies a tight policy (cheap bucket by default)
+	AnonymousModeRestricted = "restricted"
+)
+
+// AnonymousPolicyConfig controls routing of requests no auth adapter matched
+type AnonymousPolicyConfig struct {
+	Mode string `json:"mode"`
+
+	// DefaultTenant attributes anonymous traffic in "house" and "restricted" modes
+	DefaultTenant string `json:"default_tenant"`
+
+	// Policy applied in "restricted" mode; defaults to the cheap bucket with
+	// a low price cap
+	Policy *RoutingPolicy `json:"policy,omitempty"`
+}
+
+// defaultAnonymousPolicy is used in "restricted" mode when no policy is set
+var defaultAnonymousPolicy = RoutingPolicy{
+	MaxBucket: BucketCheap,
+	MaxPrice:  10,
+}
+
+// validate checks the mode and restricted policy
+func (c AnonymousPolicyConfig) validate() error {
+	switch c.Mode {
+	case AnonymousModeAllow, AnonymousModeReject, AnonymousModeHouse, AnonymousModeRestricted:
+	default:
+		return fmt.Errorf("unknown anonymous mode %q", c.Mode)
+	}
+	return c.Policy.Validate()
+}
+
+// anonymousAuth resolves auth for a request no adapter matched. It returns
+// nil auth in "allow" mode and an AuthenticationError in "reject" mode.
+func (p *Plugin) anonymousAuth() (*AuthInfo, error) {
+	config := p.config.Anonymous
+
+	switch config.Mode {
+	case AnonymousModeReject:
+		return nil, NewAuthenticationError("authentication required", nil)
+	case AnonymousModeHouse:
+		return &AuthInfo{Type: "anonymous", Org: config.DefaultTenant}, nil
+	case AnonymousModeRestricted:
+		policy := config.Policy
+		if policy == nil {
+			policy = &defaultAnonymousPolicy
+		}
+		return &AuthInfo{Type: "anonymous", Org: config.DefaultTenant, Policy: policy}, nil
+	default:
+		return nil, nil
+	}
+}
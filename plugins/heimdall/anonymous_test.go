@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymousPolicy(t *testing.T) {
+	newPlugin := func(t *testing.T, policy AnonymousPolicyConfig) *Plugin {
+		config := createRouterTestConfig()
+		config.Anonymous = policy
+		return createRouterTestPluginWithConfig(t, config)
+	}
+	hardRequest := &RouterRequest{
+		URL:    "/v1/chat/completions",
+		Method: "POST",
+		Body: &RequestBody{
+			Messages: []ChatMessage{
+				{Role: "user", Content: "Prove that there are infinitely many primes and analyze the complexity of the sieve of Eratosthenes step by step."},
+			},
+		},
+	}
+
+	t.Run("should keep full routing by default", func(t *testing.T) {
+		plugin := newPlugin(t, AnonymousPolicyConfig{})
+
+		response, err := plugin.decide(hardRequest, map[string][]string{})
+		require.NoError(t, err)
+		assert.Nil(t, response.AuthInfo)
+	})
+
+	t.Run("should reject unauthenticated requests with 401", func(t *testing.T) {
+		plugin := newPlugin(t, AnonymousPolicyConfig{Mode: AnonymousModeReject})
+
+		_, err := plugin.decide(hardRequest, map[string][]string{})
+		var authErr *AuthenticationError
+		assert.ErrorAs(t, err, &authErr)
+
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit)
+		assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+	})
+
+	t.Run("should attribute house-key routing to the default tenant", func(t *testing.T) {
+		plugin := newPlugin(t, AnonymousPolicyConfig{Mode: AnonymousModeHouse, DefaultTenant: "public"})
+
+		response, err := plugin.decide(hardRequest, map[string][]string{})
+		require.NoError(t, err)
+		require.NotNil(t, response.AuthInfo)
+		assert.Equal(t, "anonymous", response.AuthInfo.Type)
+		assert.Equal(t, "public", response.AuthInfo.Org)
+		assert.Equal(t, "env", response.Decision.Auth.Mode)
+	})
+
+	t.Run("should force the cheap bucket with a tight budget when restricted", func(t *testing.T) {
+		plugin := newPlugin(t, AnonymousPolicyConfig{Mode: AnonymousModeRestricted})
+
+		response, err := plugin.decide(hardRequest, map[string][]string{})
+		require.NoError(t, err)
+		assert.Equal(t, BucketCheap, response.Bucket)
+		assert.Contains(t, plugin.config.Router.CheapCandidates, response.Decision.Model)
+		assert.LessOrEqual(t, response.Decision.ProviderPrefs.MaxPrice, defaultAnonymousPolicy.MaxPrice)
+	})
+
+	t.Run("should not apply to authenticated requests", func(t *testing.T) {
+		plugin := newPlugin(t, AnonymousPolicyConfig{Mode: AnonymousModeReject})
+
+		_, err := plugin.decide(hardRequest, map[string][]string{"Authorization": {"Bearer sk-test"}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject unknown modes", func(t *testing.T) {
+		assert.Error(t, AnonymousPolicyConfig{Mode: "maybe"}.validate())
+	})
+}
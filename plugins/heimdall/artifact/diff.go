@@ -0,0 +1,304 @@
+package artifact
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Model change statuses in a Diff
+const (
+	ModelAdded   = "added"
+	ModelRemoved = "removed"
+	ModelChanged = "changed"
+)
+
+// changeEpsilon ignores floating-point noise between artifact builds
+const changeEpsilon = 1e-9
+
+// ValueChange is a scalar that differs between two artifacts
+type ValueChange struct {
+	Old float64 `json:"old"`
+	New float64 `json:"new"`
+}
+
+// ModelDiff reports how one model's estimates changed
+type ModelDiff struct {
+	Model  string `json:"model"`
+	Status string `json:"status"`
+
+	// MaxQhatDelta is the largest per-cluster quality change (signed);
+	// MeanQhatDelta averages the change over clusters both artifacts score
+	MaxQhatDelta  float64 `json:"max_qhat_delta,omitempty"`
+	MeanQhatDelta float64 `json:"mean_qhat_delta,omitempty"`
+
+	Chat *ValueChange `json:"chat,omitempty"`
+}
+
+// Diff reports the differences between two artifacts. Unchanged scalars
+// are nil and unchanged models are omitted.
+type Diff struct {
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+
+	Alpha          *ValueChange `json:"alpha,omitempty"`
+	CheapThreshold *ValueChange `json:"cheap_threshold,omitempty"`
+	HardThreshold  *ValueChange `json:"hard_threshold,omitempty"`
+	Normalization  []string     `json:"normalization,omitempty"` // [old, new]
+
+	Models []ModelDiff `json:"models,omitempty"`
+}
+
+// Load reads an artifact as the router would, converting pairwise
+// preferences to quality scores
+func Load(path string) (*core.AvengersArtifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var artifact core.AvengersArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("failed to decode artifact %s: %w", path, err)
+	}
+	if err := scoring.ApplyPairwise(&artifact); err != nil {
+		return nil, fmt.Errorf("invalid pairwise preferences in %s: %w", path, err)
+	}
+	return &artifact, nil
+}
+
+// Compare diffs the estimates of two artifacts
+func Compare(old, updated *core.AvengersArtifact) *Diff {
+	diff := &Diff{
+		OldVersion:     old.Version,
+		NewVersion:     updated.Version,
+		Alpha:          valueChange(old.Alpha, updated.Alpha),
+		CheapThreshold: valueChange(old.Thresholds.Cheap, updated.Thresholds.Cheap),
+		HardThreshold:  valueChange(old.Thresholds.Hard, updated.Thresholds.Hard),
+	}
+	if old.Normalization != updated.Normalization {
+		diff.Normalization = []string{old.Normalization, updated.Normalization}
+	}
+
+	models := make(map[string]bool)
+	for _, m := range []map[string][]float64{old.Qhat, updated.Qhat} {
+		for model := range m {
+			models[model] = true
+		}
+	}
+	for _, m := range []map[string]float64{old.Chat, updated.Chat} {
+		for model := range m {
+			models[model] = true
+		}
+	}
+
+	for _, model := range sortedKeys(models) {
+		if md := compareModel(model, old, updated); md != nil {
+			diff.Models = append(diff.Models, *md)
+		}
+	}
+	return diff
+}
+
+func compareModel(model string, old, updated *core.AvengersArtifact) *ModelDiff {
+	oldQ, inOld := old.Qhat[model]
+	newQ, inNew := updated.Qhat[model]
+	oldC, costInOld := old.Chat[model]
+	newC, costInNew := updated.Chat[model]
+
+	switch {
+	case !inOld && !costInOld:
+		return &ModelDiff{Model: model, Status: ModelAdded}
+	case !inNew && !costInNew:
+		return &ModelDiff{Model: model, Status: ModelRemoved}
+	}
+
+	md := &ModelDiff{Model: model, Status: ModelChanged}
+	changed := inOld != inNew || len(oldQ) != len(newQ)
+
+	sum, n := 0.0, 0
+	for c := 0; c < len(oldQ) && c < len(newQ); c++ {
+		delta := newQ[c] - oldQ[c]
+		if math.IsNaN(delta) {
+			continue
+		}
+		sum += delta
+		n++
+		if math.Abs(delta) > math.Abs(md.MaxQhatDelta) {
+			md.MaxQhatDelta = delta
+		}
+	}
+	if n > 0 {
+		md.MeanQhatDelta = sum / float64(n)
+	}
+	if math.Abs(md.MaxQhatDelta) > changeEpsilon {
+		changed = true
+	}
+
+	if costInOld != costInNew || math.Abs(newC-oldC) > changeEpsilon {
+		md.Chat = &ValueChange{Old: oldC, New: newC}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return md
+}
+
+func valueChange(old, updated float64) *ValueChange {
+	if math.Abs(updated-old) <= changeEpsilon {
+		return nil
+	}
+	return &ValueChange{Old: old, New: updated}
+}
+
+// Sample is a routed request from a decision log: a JSON line holding an
+// encoded router response, optionally with the time it was logged
+type Sample struct {
+	Timestamp time.Time            `json:"timestamp"`
+	Bucket    core.Bucket          `json:"bucket"`
+	Features  core.RequestFeatures `json:"features"`
+	Decision  struct {
+		Kind  string `json:"kind"`
+		Model string `json:"model"`
+	} `json:"decision"`
+}
+
+// ReadSamples reads a JSON-lines decision log
+func ReadSamples(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			return nil, fmt.Errorf("decision log line %d: %w", line, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// Impact estimates how replacing an artifact changes routing outcomes over a
+// sample of past decisions
+type Impact struct {
+	Samples int `json:"samples"`
+	// Skipped counts samples whose bucket has no configured candidates
+	Skipped int `json:"skipped"`
+
+	Changed         int            `json:"changed"`
+	ChangedFraction float64        `json:"changed_fraction"`
+	ChangedByBucket map[string]int `json:"changed_by_bucket,omitempty"`
+
+	// Transitions counts "old -> new" model switches
+	Transitions map[string]int `json:"transitions,omitempty"`
+
+	// QualityDelta and CostDelta are the mean per-request change in expected
+	// quality and normalized cost, both judged by the new artifact
+	QualityDelta float64 `json:"quality_delta"`
+	CostDelta    float64 `json:"cost_delta"`
+}
+
+// EstimateImpact replays each sample's selection under both artifacts. The
+// bucket is kept as logged, since bucket thresholds come from the router
+// config; per-caller policies and provider health are not replayed, so the
+// result is an estimate.
+func EstimateImpact(old, updated *core.AvengersArtifact, samples []Sample, candidates map[core.Bucket][]string) (*Impact, error) {
+	impact := &Impact{
+		Samples:         len(samples),
+		ChangedByBucket: make(map[string]int),
+		Transitions:     make(map[string]int),
+	}
+	oldScorer, updatedScorer := scoring.NewAlphaScorer(), scoring.NewAlphaScorer()
+
+	var qualitySum, costSum float64
+	var qualityN, costN int
+	for i := range samples {
+		sample := &samples[i]
+		bucketCandidates := candidates[sample.Bucket]
+		if len(bucketCandidates) == 0 {
+			impact.Skipped++
+			continue
+		}
+
+		before, err := oldScorer.SelectBest(bucketCandidates, &sample.Features, old)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d under %s: %w", i, old.Version, err)
+		}
+		after, err := updatedScorer.SelectBest(bucketCandidates, &sample.Features, updated)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d under %s: %w", i, updated.Version, err)
+		}
+		if before == after {
+			continue
+		}
+
+		impact.Changed++
+		impact.ChangedByBucket[string(sample.Bucket)]++
+		impact.Transitions[before+" -> "+after]++
+
+		qBefore, okBefore := quality(updated, before, sample.Features.ClusterID)
+		qAfter, okAfter := quality(updated, after, sample.Features.ClusterID)
+		if okBefore && okAfter {
+			qualitySum += qAfter - qBefore
+			qualityN++
+		}
+		cBefore, okBefore := updated.Chat[before]
+		cAfter, okAfter := updated.Chat[after]
+		if okBefore && okAfter {
+			costSum += cAfter - cBefore
+			costN++
+		}
+	}
+
+	// Unchanged requests contribute no delta, so average over every replayed sample
+	replayed := impact.Samples - impact.Skipped
+	if replayed > 0 {
+		impact.ChangedFraction = float64(impact.Changed) / float64(replayed)
+		if qualityN > 0 {
+			impact.QualityDelta = qualitySum / float64(replayed)
+		}
+		if costN > 0 {
+			impact.CostDelta = costSum / float64(replayed)
+		}
+	}
+	return impact, nil
+}
+
+// quality is a model's expected quality on a cluster, falling back to its
+// mean over clusters as selection does
+func quality(artifact *core.AvengersArtifact, model string, cluster int) (float64, bool) {
+	scores := artifact.Qhat[model]
+	if cluster >= 0 && cluster < len(scores) && !math.IsNaN(scores[cluster]) {
+		return scores[cluster], true
+	}
+	sum, n := 0.0, 0
+	for _, q := range scores {
+		if !math.IsNaN(q) {
+			sum += q
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// SortedTransitions returns transitions by descending count
+func (i *Impact) SortedTransitions() []string {
+	keys := sortedKeys(i.Transitions)
+	sort.SliceStable(keys, func(a, b int) bool { return i.Transitions[keys[a]] > i.Transitions[keys[b]] })
+	return keys
+}
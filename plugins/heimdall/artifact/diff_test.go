@@ -0,0 +1,81 @@
+package artifact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffArtifacts() (*core.AvengersArtifact, *core.AvengersArtifact) {
+	old := &core.AvengersArtifact{
+		Version:    "v1",
+		Alpha:      0.7,
+		Thresholds: core.BucketThresholds{Cheap: 0.3, Hard: 0.7},
+		Qhat:       map[string][]float64{"a": {0.8}, "b": {0.6}, "gone": {0.5}},
+		Chat:       map[string]float64{"a": 0.5, "b": 0.1, "gone": 0.2},
+	}
+	updated := &core.AvengersArtifact{
+		Version:    "v2",
+		Alpha:      0.7,
+		Thresholds: core.BucketThresholds{Cheap: 0.3, Hard: 0.8},
+		Qhat:       map[string][]float64{"a": {0.8}, "b": {0.75}, "added": {0.9}},
+		Chat:       map[string]float64{"a": 0.5, "b": 0.1, "added": 0.9},
+	}
+	return old, updated
+}
+
+func TestCompare(t *testing.T) {
+	old, updated := diffArtifacts()
+	diff := Compare(old, updated)
+
+	assert.Equal(t, "v1", diff.OldVersion)
+	assert.Nil(t, diff.Alpha)
+	assert.Nil(t, diff.CheapThreshold)
+	assert.Equal(t, &ValueChange{Old: 0.7, New: 0.8}, diff.HardThreshold)
+
+	require.Len(t, diff.Models, 3, "unchanged models are omitted")
+	assert.Equal(t, ModelDiff{Model: "added", Status: ModelAdded}, diff.Models[0])
+	assert.Equal(t, "b", diff.Models[1].Model)
+	assert.InDelta(t, 0.15, diff.Models[1].MaxQhatDelta, 1e-9)
+	assert.Nil(t, diff.Models[1].Chat)
+	assert.Equal(t, ModelDiff{Model: "gone", Status: ModelRemoved}, diff.Models[2])
+}
+
+func TestEstimateImpact(t *testing.T) {
+	old, updated := diffArtifacts()
+	samples, err := ReadSamples(strings.NewReader(
+		`{"bucket": "mid", "features": {"cluster_id": 0}, "decision": {"model": "a"}}
+{"bucket": "mid", "features": {"cluster_id": 0}, "decision": {"model": "a"}}
+
+{"bucket": "hard", "features": {"cluster_id": 0}, "decision": {"model": "x"}}
+`))
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, "a", samples[0].Decision.Model)
+
+	impact, err := EstimateImpact(old, updated, samples, map[core.Bucket][]string{core.BucketMid: {"a", "b"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, impact.Samples)
+	assert.Equal(t, 1, impact.Skipped)
+	assert.Equal(t, 2, impact.Changed)
+	assert.Equal(t, 1.0, impact.ChangedFraction)
+	assert.Equal(t, map[string]int{"a -> b": 2}, impact.Transitions)
+	assert.InDelta(t, -0.05, impact.QualityDelta, 1e-9)
+	assert.InDelta(t, -0.4, impact.CostDelta, 1e-9)
+
+	t.Run("should report no change for identical artifacts", func(t *testing.T) {
+		impact, err := EstimateImpact(old, old, samples, map[core.Bucket][]string{core.BucketMid: {"a", "b"}})
+		require.NoError(t, err)
+		assert.Zero(t, impact.Changed)
+		assert.Zero(t, impact.CostDelta)
+	})
+
+	t.Run("should reject malformed log lines", func(t *testing.T) {
+		_, err := ReadSamples(strings.NewReader("{\"bucket\": \"mid\"}\nnot json\n"))
+		assert.ErrorContains(t, err, "decision log line 2")
+	})
+}
@@ -0,0 +1,314 @@
+// Package artifact checks routing artifacts for conformance, so pipelines
+// that produce them can reject a bad artifact in CI rather than at load time
+// in the router.
+package artifact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Check names, reported with each issue
+const (
+	CheckSchema      = "schema"
+	CheckProbability = "probability"
+	CheckCoverage    = "coverage"
+	CheckFiles       = "files"
+	CheckConstraints = "constraints"
+)
+
+// Severity of an issue: errors fail validation, warnings do not
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a single conformance finding
+type Issue struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Report is the outcome of validating an artifact
+type Report struct {
+	Version string  `json:"version"`
+	Issues  []Issue `json:"issues"`
+}
+
+// OK reports whether the artifact passed every check
+func (r *Report) OK() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) errorf(check, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Check: check, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) warnf(check, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Check: check, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// Options configure validation
+type Options struct {
+	// Candidates are the models a router config may select, by bucket; every
+	// one must be covered by the artifact. Empty skips the coverage check.
+	Candidates map[core.Bucket][]string
+
+	// BaseDir resolves relative centroid and GBDT model paths. Empty skips
+	// the file checks.
+	BaseDir string
+}
+
+// ValidateFile reads and validates an artifact, resolving referenced files
+// next to it unless opts.BaseDir is set
+func ValidateFile(path string, opts Options) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BaseDir == "" {
+		opts.BaseDir = filepath.Dir(path)
+	}
+	return Validate(data, opts), nil
+}
+
+// Validate checks an encoded artifact against the schema, probability
+// sanity rules, candidate coverage and the files it references
+func Validate(data []byte, opts Options) *Report {
+	report := &Report{}
+
+	var artifact core.AvengersArtifact
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&artifact); err != nil {
+		report.errorf(CheckSchema, "invalid artifact: %v", err)
+		return report
+	}
+	report.Version = artifact.Version
+
+	validateSchema(&artifact, report)
+	validateProbabilities(&artifact, report)
+	validateConstraints(&artifact, opts.Candidates, report)
+	if len(opts.Candidates) > 0 {
+		validateCoverage(&artifact, opts.Candidates, report)
+	}
+	if opts.BaseDir != "" {
+		validateFiles(&artifact, opts.BaseDir, report)
+	}
+	return report
+}
+
+func validateSchema(artifact *core.AvengersArtifact, report *Report) {
+	if artifact.Version == "" {
+		report.errorf(CheckSchema, "version is required")
+	}
+	if len(artifact.Qhat) == 0 && artifact.Pairwise == nil {
+		report.errorf(CheckSchema, "qhat or pairwise is required")
+	}
+	if len(artifact.Chat) == 0 {
+		report.errorf(CheckSchema, "chat is required")
+	}
+	switch artifact.Normalization {
+	case scoring.NormalizationNone, scoring.NormalizationMinMax, scoring.NormalizationZScore:
+	default:
+		report.errorf(CheckSchema, "unknown normalization %q", artifact.Normalization)
+	}
+	if artifact.Penalties.LatencySD < 0 || artifact.Penalties.CtxOver80Pct < 0 {
+		report.errorf(CheckSchema, "penalties must not be negative")
+	}
+
+	// Every model needs one quality score per cluster
+	clusters := -1
+	for _, model := range sortedKeys(artifact.Qhat) {
+		scores := artifact.Qhat[model]
+		if len(scores) == 0 {
+			report.errorf(CheckSchema, "qhat for %s has no clusters", model)
+			continue
+		}
+		if clusters < 0 {
+			clusters = len(scores)
+		} else if len(scores) != clusters {
+			report.errorf(CheckSchema, "qhat for %s has %d clusters, expected %d", model, len(scores), clusters)
+		}
+	}
+	if artifact.Pairwise != nil && clusters >= 0 && len(artifact.Pairwise.WinRates) != clusters {
+		report.errorf(CheckSchema, "pairwise has %d clusters, qhat has %d", len(artifact.Pairwise.WinRates), clusters)
+	}
+
+	for _, model := range sortedKeys(artifact.Qhat) {
+		if _, ok := artifact.Chat[model]; !ok {
+			report.warnf(CheckSchema, "%s has quality scores but no cost", model)
+		}
+	}
+}
+
+func validateProbabilities(artifact *core.AvengersArtifact, report *Report) {
+	if !unit(artifact.Alpha) {
+		report.errorf(CheckProbability, "alpha must be within [0, 1], got %v", artifact.Alpha)
+	}
+	thresholds := artifact.Thresholds
+	if !unit(thresholds.Cheap) || !unit(thresholds.Hard) {
+		report.errorf(CheckProbability, "thresholds must be within [0, 1], got cheap %v and hard %v", thresholds.Cheap, thresholds.Hard)
+	} else if thresholds.Cheap > thresholds.Hard {
+		report.errorf(CheckProbability, "cheap threshold %v exceeds hard threshold %v", thresholds.Cheap, thresholds.Hard)
+	}
+
+	for _, model := range sortedKeys(artifact.Qhat) {
+		for cluster, q := range artifact.Qhat[model] {
+			if !unit(q) {
+				report.errorf(CheckProbability, "qhat for %s on cluster %d must be within [0, 1], got %v", model, cluster, q)
+			}
+		}
+	}
+	for _, model := range sortedKeys(artifact.Chat) {
+		cost := artifact.Chat[model]
+		if math.IsNaN(cost) || math.IsInf(cost, 0) || cost < 0 {
+			report.errorf(CheckProbability, "chat for %s must be a non-negative number, got %v", model, cost)
+		} else if artifact.Normalization == scoring.NormalizationNone && cost > 1 {
+			report.warnf(CheckProbability, "chat for %s is %v; costs are expected to be normalized to [0, 1]", model, cost)
+		}
+	}
+	if artifact.Pairwise != nil {
+		if err := scoring.ValidatePairwise(artifact.Pairwise); err != nil {
+			report.errorf(CheckProbability, "pairwise: %v", err)
+		}
+	}
+}
+
+func validateCoverage(artifact *core.AvengersArtifact, candidates map[core.Bucket][]string, report *Report) {
+	// Pairwise preferences become quality scores at load time
+	covered := make(map[string]bool, len(artifact.Qhat))
+	for model := range artifact.Qhat {
+		covered[model] = true
+	}
+	if artifact.Pairwise != nil {
+		for _, rates := range artifact.Pairwise.WinRates {
+			for a, opponents := range rates {
+				covered[a] = true
+				for b := range opponents {
+					covered[b] = true
+				}
+			}
+		}
+	}
+
+	for _, bucket := range []core.Bucket{core.BucketCheap, core.BucketMid, core.BucketHard} {
+		for _, model := range candidates[bucket] {
+			if !covered[model] {
+				report.errorf(CheckCoverage, "%s candidate %s has no quality scores", bucket, model)
+			}
+			if _, ok := artifact.Chat[model]; !ok {
+				report.errorf(CheckCoverage, "%s candidate %s has no cost", bucket, model)
+			}
+		}
+	}
+}
+
+// validateConstraints checks that constraints name real clusters and that
+// some model, and some candidate of each bucket, can meet them
+func validateConstraints(artifact *core.AvengersArtifact, candidates map[core.Bucket][]string, report *Report) {
+	for _, model := range sortedKeys(artifact.Models) {
+		profile := artifact.Models[model]
+		if profile.ContextWindow < 0 || profile.QualityTier < 0 {
+			report.errorf(CheckConstraints, "profile for %s must not be negative", model)
+		}
+	}
+
+	clusters := 0
+	for _, scores := range artifact.Qhat {
+		clusters = max(clusters, len(scores))
+	}
+	if artifact.Pairwise != nil {
+		clusters = max(clusters, len(artifact.Pairwise.WinRates))
+	}
+
+	ids := make([]int, 0, len(artifact.Constraints))
+	for id := range artifact.Constraints {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		constraint := artifact.Constraints[id]
+		if id < 0 || id >= clusters {
+			report.errorf(CheckConstraints, "constraint for cluster %d is outside the %d clusters", id, clusters)
+			continue
+		}
+
+		permitted := func(models []string) bool {
+			for _, model := range models {
+				if profile, ok := artifact.Models[model]; ok && constraint.Permits(profile) {
+					return true
+				}
+			}
+			return false
+		}
+		if !permitted(sortedKeys(artifact.Models)) {
+			report.errorf(CheckConstraints, "no model meets the constraint for cluster %d", id)
+			continue
+		}
+		for _, bucket := range []core.Bucket{core.BucketCheap, core.BucketMid, core.BucketHard} {
+			if len(candidates[bucket]) > 0 && !permitted(candidates[bucket]) {
+				report.warnf(CheckConstraints, "no %s candidate meets the constraint for cluster %d; such requests get the emergency fallback", bucket, id)
+			}
+		}
+	}
+}
+
+func validateFiles(artifact *core.AvengersArtifact, baseDir string, report *Report) {
+	if artifact.Centroids != "" {
+		checkFile("centroids", artifact.Centroids, baseDir, report)
+	}
+	if artifact.GBDT.ModelPath != "" {
+		if artifact.GBDT.Framework == "" {
+			report.errorf(CheckFiles, "gbdt model_path is set without a framework")
+		}
+		checkFile("gbdt model", artifact.GBDT.ModelPath, baseDir, report)
+	}
+}
+
+// checkFile requires a referenced file to exist, be non-empty and, when it
+// is JSON, to parse
+func checkFile(name, path, baseDir string, report *Report) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		report.errorf(CheckFiles, "%s file is not readable: %v", name, err)
+		return
+	}
+	if len(data) == 0 {
+		report.errorf(CheckFiles, "%s file %s is empty", name, path)
+		return
+	}
+	if filepath.Ext(path) == ".json" && !json.Valid(data) {
+		report.errorf(CheckFiles, "%s file %s is not valid JSON", name, path)
+	}
+}
+
+func unit(v float64) bool {
+	return !math.IsNaN(v) && v >= 0 && v <= 1
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
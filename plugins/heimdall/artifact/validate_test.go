@@ -0,0 +1,143 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validArtifact = `{
+  "version": "v1.0",
+  "alpha": 0.7,
+  "thresholds": {"cheap": 0.3, "hard": 0.7},
+  "penalties": {"latency_sd": 0.1, "ctx_over_80pct": 0.15},
+  "qhat": {"openai/gpt-4o": [0.8, 0.7], "qwen/qwen3-coder": [0.6, 0.5]},
+  "chat": {"openai/gpt-4o": 0.5, "qwen/qwen3-coder": 0.1},
+  "gbdt": {"framework": "lightgbm", "model_path": "gbdt.json"}
+}`
+
+func messages(report *Report) []string {
+	var messages []string
+	for _, issue := range report.Issues {
+		messages = append(messages, issue.Message)
+	}
+	return messages
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("should accept a conforming artifact", func(t *testing.T) {
+		report := Validate([]byte(validArtifact), Options{})
+		assert.True(t, report.OK(), messages(report))
+		assert.Empty(t, report.Issues)
+		assert.Equal(t, "v1.0", report.Version)
+	})
+
+	t.Run("should reject unknown fields and malformed JSON", func(t *testing.T) {
+		report := Validate([]byte(`{"version": "v1", "qhatt": {}}`), Options{})
+		assert.False(t, report.OK())
+		assert.Equal(t, CheckSchema, report.Issues[0].Check)
+		assert.Contains(t, report.Issues[0].Message, "qhatt")
+	})
+
+	t.Run("should require consistent cluster counts", func(t *testing.T) {
+		report := Validate([]byte(`{
+			"version": "v1", "thresholds": {"cheap": 0.3, "hard": 0.7},
+			"qhat": {"a": [0.5, 0.5], "b": [0.5]},
+			"chat": {"a": 0.1}
+		}`), Options{})
+		assert.False(t, report.OK())
+		assert.Contains(t, messages(report), "qhat for b has 1 clusters, expected 2")
+		assert.Contains(t, messages(report), "b has quality scores but no cost")
+	})
+
+	t.Run("should flag out-of-range probabilities", func(t *testing.T) {
+		report := Validate([]byte(`{
+			"version": "v1", "alpha": 1.5, "thresholds": {"cheap": 0.8, "hard": 0.7},
+			"qhat": {"a": [1.2]}, "chat": {"a": -1},
+			"pairwise": {"win_rates": [{"a": {"a": 0.5}}]}
+		}`), Options{})
+		assert.False(t, report.OK())
+		for _, issue := range report.Issues {
+			assert.Equal(t, CheckProbability, issue.Check, issue.Message)
+		}
+		assert.Len(t, report.Issues, 5)
+	})
+
+	t.Run("should require coverage of configured candidates", func(t *testing.T) {
+		report := Validate([]byte(validArtifact), Options{Candidates: map[core.Bucket][]string{
+			core.BucketCheap: {"qwen/qwen3-coder"},
+			core.BucketHard:  {"openai/gpt-5"},
+		}})
+		assert.False(t, report.OK())
+		assert.Equal(t, []string{
+			"hard candidate openai/gpt-5 has no quality scores",
+			"hard candidate openai/gpt-5 has no cost",
+		}, messages(report))
+	})
+
+	t.Run("should count pairwise models as covered", func(t *testing.T) {
+		report := Validate([]byte(`{
+			"version": "v1", "thresholds": {"cheap": 0.3, "hard": 0.7},
+			"chat": {"a": 0.2, "b": 0.4},
+			"pairwise": {"win_rates": [{"a": {"b": 0.6}}]}
+		}`), Options{Candidates: map[core.Bucket][]string{core.BucketMid: {"a", "b"}}})
+		assert.True(t, report.OK(), messages(report))
+	})
+
+	t.Run("should check cluster constraints", func(t *testing.T) {
+		report := Validate([]byte(`{
+			"version": "v1", "thresholds": {"cheap": 0.3, "hard": 0.7},
+			"qhat": {"a": [0.5, 0.5], "b": [0.6, 0.6]}, "chat": {"a": 0.1, "b": 0.5},
+			"models": {
+				"a": {"context_window": 32000, "quality_tier": 1},
+				"b": {"context_window": 200000, "capabilities": ["vision"], "quality_tier": 3}
+			},
+			"constraints": {
+				"0": {"min_context": 100000},
+				"1": {"required_capabilities": ["audio"]},
+				"2": {"min_quality_tier": 1}
+			}
+		}`), Options{Candidates: map[core.Bucket][]string{core.BucketCheap: {"a"}, core.BucketHard: {"b"}}})
+		assert.False(t, report.OK())
+		assert.Equal(t, []string{
+			"no cheap candidate meets the constraint for cluster 0; such requests get the emergency fallback",
+			"no model meets the constraint for cluster 1",
+			"constraint for cluster 2 is outside the 2 clusters",
+		}, messages(report))
+		for _, issue := range report.Issues {
+			assert.Equal(t, CheckConstraints, issue.Check)
+		}
+	})
+}
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "artifact.json")
+	require.NoError(t, os.WriteFile(path, []byte(validArtifact), 0o644))
+
+	t.Run("should require referenced files next to the artifact", func(t *testing.T) {
+		report, err := ValidateFile(path, Options{})
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, CheckFiles, report.Issues[0].Check)
+	})
+
+	t.Run("should require referenced JSON files to parse", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gbdt.json"), []byte("{trees"), 0o644))
+		report, err := ValidateFile(path, Options{})
+		require.NoError(t, err)
+		assert.Contains(t, messages(report)[0], "is not valid JSON")
+	})
+
+	t.Run("should pass once the model loads", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gbdt.json"), []byte(`{"trees": []}`), 0o644))
+		report, err := ValidateFile(path, Options{})
+		require.NoError(t, err)
+		assert.True(t, report.OK(), messages(report))
+	})
+}
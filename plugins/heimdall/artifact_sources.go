@@ -0,0 +1,143 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// artifactEndpointBackoff is how long a failed endpoint is demoted after
+	// its first failure, doubling with each consecutive failure
+	artifactEndpointBackoff    = 30 * time.Second
+	artifactEndpointMaxBackoff = 10 * time.Minute
+)
+
+// ArtifactSourceStatus reports the health of an artifact endpoint
+type ArtifactSourceStatus struct {
+	URL                 string    `json:"url"`
+	Active              bool      `json:"active"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	DemotedUntil        time.Time `json:"demoted_until,omitempty"`
+}
+
+// artifactSources remembers the health of each artifact endpoint. Endpoints
+// are tried in configured order, except that those which recently failed are
+// demoted until their backoff expires: skipped by refreshes, and tried behind
+// healthy ones when an artifact must load, promoting a working backup while
+// the primary is down.
+type artifactSources struct {
+	mu        sync.Mutex
+	endpoints []ArtifactSourceStatus
+	now       func() time.Time
+}
+
+// newArtifactSources tracks urls, skipping empty and duplicate entries
+func newArtifactSources(urls []string, now func() time.Time) *artifactSources {
+	as := &artifactSources{now: now}
+	seen := make(map[string]bool)
+	for _, url := range urls {
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		as.endpoints = append(as.endpoints, ArtifactSourceStatus{URL: url})
+	}
+	return as
+}
+
+// Order returns the endpoint URLs in the order they should be tried.
+// Endpoints inside their backoff are skipped unless includeDemoted is set.
+func (as *artifactSources) Order(includeDemoted bool) []string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	now := as.now()
+	var order []int
+	for i, ep := range as.endpoints {
+		if includeDemoted || !now.Before(ep.DemotedUntil) {
+			order = append(order, i)
+		}
+	}
+	// Demoted endpoints go last, soonest to recover first
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := as.endpoints[order[i]], as.endpoints[order[j]]
+		aDemoted, bDemoted := now.Before(a.DemotedUntil), now.Before(b.DemotedUntil)
+		if aDemoted != bDemoted {
+			return !aDemoted
+		}
+		return aDemoted && a.DemotedUntil.Before(b.DemotedUntil)
+	})
+
+	urls := make([]string, len(order))
+	for i, idx := range order {
+		urls[i] = as.endpoints[idx].URL
+	}
+	return urls
+}
+
+// RetryAt returns when the soonest endpoint backoff expires, zero when
+// there are no endpoints or one has never failed
+func (as *artifactSources) RetryAt() time.Time {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	var retryAt time.Time
+	for _, ep := range as.endpoints {
+		if retryAt.IsZero() || ep.DemotedUntil.Before(retryAt) {
+			retryAt = ep.DemotedUntil
+		}
+	}
+	return retryAt
+}
+
+// RecordSuccess marks url healthy and as the source of the current artifact
+func (as *artifactSources) RecordSuccess(url string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for i := range as.endpoints {
+		ep := &as.endpoints[i]
+		ep.Active = ep.URL == url
+		if ep.Active {
+			ep.ConsecutiveFailures = 0
+			ep.DemotedUntil = time.Time{}
+			ep.LastSuccess = as.now()
+		}
+	}
+}
+
+// RecordFailure demotes url with exponential backoff
+func (as *artifactSources) RecordFailure(url string, err error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	for i := range as.endpoints {
+		ep := &as.endpoints[i]
+		if ep.URL != url {
+			continue
+		}
+		ep.ConsecutiveFailures++
+		ep.LastError = err.Error()
+		ep.LastFailure = as.now()
+
+		backoff := artifactEndpointBackoff
+		for n := 1; n < ep.ConsecutiveFailures && backoff < artifactEndpointMaxBackoff; n++ {
+			backoff *= 2
+		}
+		if backoff > artifactEndpointMaxBackoff {
+			backoff = artifactEndpointMaxBackoff
+		}
+		ep.DemotedUntil = ep.LastFailure.Add(backoff)
+	}
+}
+
+// GetStatus returns the health of every endpoint in configured order
+func (as *artifactSources) GetStatus() []ArtifactSourceStatus {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return append([]ArtifactSourceStatus(nil), as.endpoints...)
+}
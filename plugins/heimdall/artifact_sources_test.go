@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactSources(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("should skip empty and duplicate endpoints", func(t *testing.T) {
+		sources := newArtifactSources([]string{"", "https://cdn", "https://backup", "https://cdn"}, clock)
+		assert.Equal(t, []string{"https://cdn", "https://backup"}, sources.Order(false))
+	})
+
+	t.Run("should demote failed endpoints until their backoff expires", func(t *testing.T) {
+		sources := newArtifactSources([]string{"https://cdn", "https://backup"}, clock)
+
+		sources.RecordFailure("https://cdn", assert.AnError)
+		sources.RecordSuccess("https://backup")
+		assert.Equal(t, []string{"https://backup", "https://cdn"}, sources.Order(true))
+		assert.Equal(t, []string{"https://backup"}, sources.Order(false), "refreshes skip demoted endpoints")
+
+		status := sources.GetStatus()
+		assert.False(t, status[0].Active)
+		assert.Equal(t, 1, status[0].ConsecutiveFailures)
+		assert.Equal(t, assert.AnError.Error(), status[0].LastError)
+		assert.True(t, status[1].Active)
+
+		now = now.Add(artifactEndpointBackoff)
+		assert.Equal(t, []string{"https://cdn", "https://backup"}, sources.Order(false))
+	})
+
+	t.Run("should double the backoff on consecutive failures", func(t *testing.T) {
+		sources := newArtifactSources([]string{"https://cdn"}, clock)
+		for i := 0; i < 3; i++ {
+			sources.RecordFailure("https://cdn", assert.AnError)
+		}
+		assert.Equal(t, now.Add(4*artifactEndpointBackoff), sources.GetStatus()[0].DemotedUntil)
+		assert.Equal(t, now.Add(4*artifactEndpointBackoff), sources.RetryAt())
+
+		for i := 0; i < 20; i++ {
+			sources.RecordFailure("https://cdn", assert.AnError)
+		}
+		assert.Equal(t, now.Add(artifactEndpointMaxBackoff), sources.GetStatus()[0].DemotedUntil)
+
+		sources.RecordSuccess("https://cdn")
+		assert.Zero(t, sources.GetStatus()[0].ConsecutiveFailures)
+		assert.True(t, sources.GetStatus()[0].DemotedUntil.IsZero())
+	})
+}
+
+func TestArtifactFailover(t *testing.T) {
+	primaryRequests := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryRequests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&AvengersArtifact{Version: "backup-1.0"})
+	}))
+	defer backup.Close()
+
+	config := createRouterTestConfig()
+	config.Tuning.ArtifactURL = primary.URL
+	config.Tuning.ArtifactURLs = []string{backup.URL}
+	plugin, err := New(config)
+	require.NoError(t, err)
+
+	t.Run("should load from the backup when the primary fails", func(t *testing.T) {
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, "backup-1.0", plugin.currentArtifact.Version)
+		assert.Equal(t, 1, primaryRequests)
+	})
+
+	t.Run("should try the promoted backup first on reload", func(t *testing.T) {
+		plugin.lastArtifactLoad = time.Time{}
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, 1, primaryRequests, "demoted primary should not be tried while the backup is healthy")
+
+		sources := plugin.GetHealth().ArtifactSources
+		require.Len(t, sources, 2)
+		assert.Equal(t, primary.URL, sources[0].URL)
+		assert.False(t, sources[0].Active)
+		assert.True(t, sources[1].Active)
+	})
+
+	t.Run("should report every endpoint when all fail", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = primary.URL
+		config.Tuning.ArtifactURLs = []string{"http://nonexistent-url"}
+		plugin, err := New(config)
+		require.NoError(t, err)
+
+		err = plugin.ensureCurrentArtifact()
+		assert.ErrorContains(t, err, "artifact fetch failed with status 503")
+		assert.ErrorContains(t, err, "failed to fetch artifact")
+	})
+
+	t.Run("should back off refreshes while every endpoint fails", func(t *testing.T) {
+		failing := false
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if failing {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(&AvengersArtifact{Version: "refreshed-1.0"})
+		}))
+		defer server.Close()
+
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = server.URL
+		config.Tuning.ReloadSeconds = 60
+		plugin, err := NewWithOptions(config, WithClock(func() time.Time { return now }))
+		require.NoError(t, err)
+		require.NoError(t, plugin.ensureCurrentArtifact())
+
+		failing = true
+		now = now.Add(2 * time.Minute)
+		require.NoError(t, plugin.ensureCurrentArtifact(), "the loaded artifact is kept")
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, 2, requests, "the failed endpoint is not retried inside its backoff")
+
+		now = now.Add(artifactEndpointBackoff)
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, 3, requests)
+
+		failing = false
+		require.NoError(t, plugin.awaitArtifact(time.Second), "forced loads ignore the backoff")
+		assert.Equal(t, 4, requests)
+		assert.Equal(t, now, plugin.lastArtifactLoad)
+	})
+
+	t.Run("should accept backup endpoints without a primary", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = ""
+		config.Tuning.ArtifactURLs = []string{backup.URL}
+		plugin, err := New(config)
+		require.NoError(t, err)
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, "backup-1.0", plugin.currentArtifact.Version)
+	})
+}
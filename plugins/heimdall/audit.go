@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audited change kinds
+const (
+	AuditArtifact    = "artifact"
+	AuditDrain       = "drain"
+	AuditQuarantine  = "quarantine"
+	AuditCostAnomaly = "cost_anomaly"
+	AuditAlpha       = "alpha"
+	AuditDataSubject = "data_subject"
+	AuditReload      = "config"
+)
+
+// AuditConfig configures the trail of changes made to a running plugin
+type AuditConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// MaxEntries is how many recent entries are kept for the admin API
+	// (default 1000)
+	MaxEntries int `json:"max_entries"`
+	// MaxChanges caps the field changes kept per entry (default 200); the
+	// remainder is counted in the entry's truncated_changes
+	MaxChanges int `json:"max_changes"`
+}
+
+// FieldChange is one changed value, addressed by its JSON path. A null Old
+// or New means the value was absent.
+type FieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// AuditEntry records who changed what and when
+type AuditEntry struct {
+	// Type is always "audit", telling entries apart in a shared sink
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Actor is the admin caller, the endpoint an artifact was loaded from,
+	// or the component that changed itself
+	Actor  string `json:"actor"`
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	Target string `json:"target,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	Changes          []FieldChange `json:"changes,omitempty"`
+	TruncatedChanges int           `json:"truncated_changes,omitempty"`
+}
+
+// AuditLog keeps recent change entries and copies each to an optional sink
+type AuditLog struct {
+	config AuditConfig
+	now    func() time.Time
+	sink   io.Writer
+
+	entries []AuditEntry // oldest first
+	nextID  int64
+	mu      sync.Mutex
+}
+
+// NewAuditLog creates an audit log, filling defaults. sink may be nil.
+func NewAuditLog(config AuditConfig, now func() time.Time, sink io.Writer) (*AuditLog, error) {
+	if config.MaxEntries == 0 {
+		config.MaxEntries = 1000
+	}
+	if config.MaxChanges == 0 {
+		config.MaxChanges = 200
+	}
+	if config.MaxEntries < 0 || config.MaxChanges < 0 {
+		return nil, fmt.Errorf("max_entries and max_changes must be positive")
+	}
+	return &AuditLog{config: config, now: now, sink: sink, nextID: 1}, nil
+}
+
+// Record stamps an entry with an ID and time, keeps it and writes it to the
+// sink as a JSON line
+func (al *AuditLog) Record(entry AuditEntry) AuditEntry {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	entry.Type = "audit"
+	entry.ID = al.nextID
+	al.nextID++
+	entry.Timestamp = al.now().UTC()
+	if len(entry.Changes) > al.config.MaxChanges {
+		entry.TruncatedChanges = len(entry.Changes) - al.config.MaxChanges
+		entry.Changes = entry.Changes[:al.config.MaxChanges]
+	}
+
+	al.entries = append(al.entries, entry)
+	if len(al.entries) > al.config.MaxEntries {
+		al.entries = al.entries[len(al.entries)-al.config.MaxEntries:]
+	}
+
+	if al.sink != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = al.sink.Write(append(line, '\n'))
+		}
+		if err != nil {
+			log.Printf("Failed to write audit entry %d to sink: %v", entry.ID, err)
+		}
+	}
+	return entry
+}
+
+// Inherit takes over a replaced log's entries, continuing its IDs after
+// them, and keeps the newest that fit
+func (al *AuditLog) Inherit(old *AuditLog) {
+	old.mu.Lock()
+	entries, offset := append([]AuditEntry{}, old.entries...), old.nextID-1
+	old.mu.Unlock()
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	for _, entry := range al.entries {
+		entry.ID += offset
+		entries = append(entries, entry)
+	}
+	al.nextID += offset
+	if len(entries) > al.config.MaxEntries {
+		entries = entries[len(entries)-al.config.MaxEntries:]
+	}
+	al.entries = entries
+}
+
+// Entries returns up to limit entries (0 = all) with IDs after since,
+// oldest first, so callers can page forward
+func (al *AuditLog) Entries(since int64, limit int) []AuditEntry {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	start := sort.Search(len(al.entries), func(i int) bool { return al.entries[i].ID > since })
+	entries := al.entries[start:]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return append([]AuditEntry{}, entries...)
+}
+
+// diffFields lists the values that differ between old and updated, walking
+// structs by their JSON field names. A value present on only one side is a
+// single change at its path.
+func diffFields(path string, old, updated interface{}) []FieldChange {
+	var changes []FieldChange
+	diffValues(path, reflect.ValueOf(old), reflect.ValueOf(updated), &changes)
+	return changes
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func diffValues(path string, old, updated reflect.Value, changes *[]FieldChange) {
+	old, updated = indirect(old), indirect(updated)
+	if (old.IsValid() && !old.CanInterface()) || (updated.IsValid() && !updated.CanInterface()) {
+		// Reached through an unexported embedded struct, which is not exposed
+		return
+	}
+	if !old.IsValid() || !updated.IsValid() || old.Type() != updated.Type() {
+		if old.IsValid() || updated.IsValid() {
+			*changes = append(*changes, FieldChange{Path: path, Old: leaf(old), New: leaf(updated)})
+		}
+		return
+	}
+
+	t := old.Type()
+	switch {
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		// Opaque values such as times and secrets compare whole
+	case t.Kind() == reflect.Struct:
+		diffStruct(path, old, updated, changes)
+		return
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		keys := make(map[string]bool)
+		for _, m := range []reflect.Value{old, updated} {
+			for _, key := range m.MapKeys() {
+				keys[key.String()] = true
+			}
+		}
+		for _, key := range sortedKeys(keys) {
+			k := reflect.ValueOf(key).Convert(t.Key())
+			diffValues(joinPath(path, key), old.MapIndex(k), updated.MapIndex(k), changes)
+		}
+		return
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && old.Len() == updated.Len():
+		for i := 0; i < old.Len(); i++ {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), old.Index(i), updated.Index(i), changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old.Interface(), updated.Interface()) {
+		*changes = append(*changes, FieldChange{Path: path, Old: leaf(old), New: leaf(updated)})
+	}
+}
+
+// diffStruct compares exported fields, flattening embedded structs as
+// encoding/json does
+func diffStruct(path string, old, updated reflect.Value, changes *[]FieldChange) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			diffStruct(path, old.Field(i), updated.Field(i), changes)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		diffValues(joinPath(path, name), old.Field(i), updated.Field(i), changes)
+	}
+}
+
+// indirect unwraps interfaces and pointers, returning the zero Value for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// leaf is the recorded form of a value, nil when it is absent
+func leaf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// auditArtifact records a newly loaded artifact's changes. Reloads of an
+// identical artifact are not recorded.
+func (p *Plugin) auditArtifact(source string, previous, loaded *AvengersArtifact) {
+	if p.audit == nil {
+		return
+	}
+	entry := AuditEntry{Actor: source, Kind: AuditArtifact, Target: loaded.Version}
+	if previous == nil {
+		entry.Action = "loaded"
+	} else {
+		entry.Action = "reloaded"
+		entry.Changes = diffFields("", previous, loaded)
+		if len(entry.Changes) == 0 {
+			return
+		}
+	}
+	p.audit.Record(entry)
+}
+
+// auditReload records the config changes of an instance that replaced one
+// built from previous. Credentials are redacted on both sides.
+func (p *Plugin) auditReload(source string, previous Config) {
+	if p.audit == nil {
+		return
+	}
+	old, err := redactConfig(previous)
+	if err != nil {
+		p.logger.Printf("Failed to audit config reload: %v", err)
+		return
+	}
+	updated, err := redactConfig(p.config)
+	if err != nil {
+		p.logger.Printf("Failed to audit config reload: %v", err)
+		return
+	}
+	p.audit.Record(AuditEntry{Actor: source, Kind: AuditReload, Action: "reloaded", Changes: diffFields("", old, updated)})
+}
+
+// redactedConfigFields hold credentials and are never recorded. Webhook
+// URLs are credentials in themselves (Slack and similar hooks carry their
+// token in the path).
+var redactedConfigFields = map[string]bool{
+	"key":         true,
+	"secret":      true,
+	"api_key":     true,
+	"webhook_url": true,
+}
+
+// redactConfig returns the config as JSON values, replacing credentials and
+// request-shaping header values (which may carry upstream API keys), and
+// stripping URLs of embedded credentials and query strings, which may hold
+// signed-URL tokens
+func redactConfig(config Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	redactValue("", values)
+	return values, nil
+}
+
+func redactValue(name string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, nested := range v {
+			if name == "headers" && !reservedOutboundHeaders[http.CanonicalHeaderKey(field)] {
+				if value, ok := nested.(string); ok && value != "" {
+					v[field] = "[redacted]"
+					continue
+				}
+			}
+			v[field] = redactValue(field, nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactValue(name, nested)
+		}
+	case string:
+		if v == "" {
+			return v
+		}
+		if redactedConfigFields[name] {
+			return "[redacted]"
+		}
+		if strings.HasSuffix(name, "url") || strings.HasSuffix(name, "urls") {
+			if u, err := url.Parse(v); err == nil && (u.User != nil || u.RawQuery != "") {
+				u.User, u.RawQuery = nil, ""
+				return u.String()
+			}
+		}
+	}
+	return value
+}
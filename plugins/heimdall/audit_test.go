@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffFields(t *testing.T) {
+	t.Run("should report changed fields by JSON path", func(t *testing.T) {
+		old := &AvengersArtifact{
+			Version:    "v1",
+			Alpha:      0.6,
+			Thresholds: BucketThresholds{Cheap: 0.3, Hard: 0.7},
+			Qhat:       map[string][]float64{"a": {0.5, 0.6}, "b": {0.4}},
+		}
+		updated := &AvengersArtifact{
+			Version:    "v2",
+			Alpha:      0.6,
+			Thresholds: BucketThresholds{Cheap: 0.25, Hard: 0.7},
+			Qhat:       map[string][]float64{"a": {0.5, 0.7}, "c": {0.9}},
+		}
+
+		changes := diffFields("", old, updated)
+		assert.Equal(t, []FieldChange{
+			{Path: "version", Old: "v1", New: "v2"},
+			{Path: "thresholds.cheap", Old: 0.3, New: 0.25},
+			{Path: "qhat.a[1]", Old: 0.6, New: 0.7},
+			{Path: "qhat.b", Old: []float64{0.4}, New: nil},
+			{Path: "qhat.c", Old: nil, New: []float64{0.9}},
+		}, changes)
+	})
+
+	t.Run("should record a value present on one side at its path", func(t *testing.T) {
+		entry := &DrainEntry{Target: "openai", Reason: "maintenance"}
+		assert.Equal(t, []FieldChange{{Path: "drains.openai", Old: nil, New: *entry}},
+			diffFields("drains.openai", (*DrainEntry)(nil), entry))
+		assert.Empty(t, diffFields("drains.openai", entry, entry))
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sink bytes.Buffer
+	audit, err := NewAuditLog(AuditConfig{MaxEntries: 3, MaxChanges: 2}, func() time.Time { return now }, &sink)
+	require.NoError(t, err)
+
+	t.Run("should stamp entries and write them to the sink", func(t *testing.T) {
+		entry := audit.Record(AuditEntry{Actor: "ops", Kind: AuditDrain, Action: "drained", Target: "openai"})
+		assert.Equal(t, int64(1), entry.ID)
+		assert.Equal(t, now, entry.Timestamp)
+
+		var written AuditEntry
+		require.NoError(t, json.Unmarshal(sink.Bytes(), &written))
+		assert.Equal(t, "audit", written.Type)
+		assert.Equal(t, "ops", written.Actor)
+	})
+
+	t.Run("should cap the changes kept per entry", func(t *testing.T) {
+		entry := audit.Record(AuditEntry{Kind: AuditArtifact, Changes: make([]FieldChange, 5)})
+		assert.Len(t, entry.Changes, 2)
+		assert.Equal(t, 3, entry.TruncatedChanges)
+	})
+
+	t.Run("should keep the most recent entries and page forward", func(t *testing.T) {
+		audit.Record(AuditEntry{Kind: AuditDrain})
+		audit.Record(AuditEntry{Kind: AuditDrain})
+
+		entries := audit.Entries(0, 0)
+		require.Len(t, entries, 3)
+		assert.Equal(t, int64(2), entries[0].ID)
+
+		entries = audit.Entries(2, 1)
+		require.Len(t, entries, 1)
+		assert.Equal(t, int64(3), entries[0].ID)
+		assert.Empty(t, audit.Entries(4, 0))
+	})
+}
+
+func TestAuditTrail(t *testing.T) {
+	version := "v1"
+	artifacts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&AvengersArtifact{Version: version, Alpha: 0.6})
+	}))
+	defer artifacts.Close()
+
+	var sink bytes.Buffer
+	config := createRouterTestConfig()
+	config.Tuning.ArtifactURL = artifacts.URL
+	config.Audit = AuditConfig{Enabled: true}
+	plugin, err := NewWithOptions(config, WithAuditSink(&sink))
+	require.NoError(t, err)
+
+	t.Run("should record artifact changes on reload", func(t *testing.T) {
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		plugin.lastArtifactLoad = time.Time{}
+		require.NoError(t, plugin.ensureCurrentArtifact())
+
+		version = "v2"
+		plugin.lastArtifactLoad = time.Time{}
+		require.NoError(t, plugin.ensureCurrentArtifact())
+
+		entries := plugin.audit.Entries(0, 0)
+		require.Len(t, entries, 2, "an unchanged reload is not recorded")
+		assert.Equal(t, "loaded", entries[0].Action)
+		assert.Equal(t, "reloaded", entries[1].Action)
+		assert.Equal(t, artifacts.URL, entries[1].Actor)
+		assert.Equal(t, []FieldChange{{Path: "version", Old: "v1", New: "v2"}}, entries[1].Changes)
+	})
+
+	t.Run("should record admin changes and serve the trail", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/admin/drains", "application/json",
+			strings.NewReader(`{"target": "openai", "reason": "maintenance"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		httpReq, _ := http.NewRequest("DELETE", server.URL+"/admin/drains/openai", nil)
+		resp, err = http.DefaultClient.Do(httpReq)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		resp, err = http.Get(server.URL + "/admin/audit?since=2")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var entries []AuditEntry
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+
+		require.Len(t, entries, 2)
+		assert.Equal(t, AuditDrain, entries[0].Kind)
+		assert.Equal(t, "drained", entries[0].Action)
+		assert.NotEmpty(t, entries[0].Actor)
+		require.Len(t, entries[0].Changes, 1)
+		assert.Equal(t, "drains.openai", entries[0].Changes[0].Path)
+		assert.Nil(t, entries[0].Changes[0].Old)
+		assert.Equal(t, "undrained", entries[1].Action)
+		assert.Nil(t, entries[1].Changes[0].New)
+
+		assert.Equal(t, 4, strings.Count(sink.String(), "\n"))
+	})
+
+	t.Run("should reject an invalid page", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/admin/audit?limit=-1")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,152 @@
+// Package auth detects and verifies caller credentials (API keys, OAuth
+// tokens, JWTs and HMAC-signed requests) and carries the resulting identity
+// and routing policy.
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// AuthInfo represents authentication information
+type AuthInfo struct {
+	Provider string `json:"provider"`
+	Type     string `json:"type"`
+	Token    Secret `json:"token"`
+
+	// AllowHouseKeys is set when the request opted in to mixing its own key
+	// with house-key providers (BYOK "mix" mode)
+	AllowHouseKeys bool `json:"allow_house_keys,omitempty"`
+
+	// Caller identity and routing policy from a verified JWT
+	Subject string              `json:"subject,omitempty"`
+	Tier    string              `json:"tier,omitempty"`
+	Org     string              `json:"org,omitempty"`
+	Policy  *core.RoutingPolicy `json:"policy,omitempty"`
+}
+
+// AuthAdapter represents an authentication adapter
+type AuthAdapter interface {
+	GetID() string
+	Matches(headers map[string][]string) bool
+	Extract(headers map[string][]string) *AuthInfo
+	Apply(outgoing *http.Request) *http.Request
+}
+
+// AuthAdapterRegistry manages authentication adapters
+type AuthAdapterRegistry struct {
+	adapters map[string]AuthAdapter
+	mu       sync.RWMutex
+}
+
+func NewAuthAdapterRegistry() *AuthAdapterRegistry {
+	return &AuthAdapterRegistry{
+		adapters: make(map[string]AuthAdapter),
+	}
+}
+
+func (r *AuthAdapterRegistry) Register(adapter AuthAdapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adapters[adapter.GetID()] = adapter
+}
+
+func (r *AuthAdapterRegistry) Get(id string) AuthAdapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.adapters[id]
+}
+
+func (r *AuthAdapterRegistry) GetEnabled(enabledIDs []string) []AuthAdapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var enabled []AuthAdapter
+	for _, id := range enabledIDs {
+		if adapter, exists := r.adapters[id]; exists {
+			enabled = append(enabled, adapter)
+		}
+	}
+	return enabled
+}
+
+func (r *AuthAdapterRegistry) FindMatch(headers map[string][]string) AuthAdapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, adapter := range r.adapters {
+		if adapter.Matches(headers) {
+			return adapter
+		}
+	}
+	return nil
+}
+
+// OpenAIKeyAdapter handles OpenAI API key authentication
+type OpenAIKeyAdapter struct{}
+
+func (a *OpenAIKeyAdapter) GetID() string { return "openai-key" }
+
+func (a *OpenAIKeyAdapter) Matches(headers map[string][]string) bool {
+	auth := HeaderValue(headers, "Authorization")
+	// Anthropic credentials share the "sk-" prefix ("sk-ant-...")
+	return strings.HasPrefix(auth, "Bearer sk-") && !strings.HasPrefix(auth, "Bearer sk-ant-")
+}
+
+func (a *OpenAIKeyAdapter) Extract(headers map[string][]string) *AuthInfo {
+	auth := HeaderValue(headers, "Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	return &AuthInfo{
+		Provider: "openai",
+		Type:     "bearer",
+		Token:    NewSecret(strings.TrimPrefix(auth, "Bearer ")),
+	}
+}
+
+func (a *OpenAIKeyAdapter) Apply(outgoing *http.Request) *http.Request {
+	return outgoing // No modification needed for API keys
+}
+
+// GeminiOAuthAdapter handles Google Gemini OAuth
+type GeminiOAuthAdapter struct{}
+
+func (a *GeminiOAuthAdapter) GetID() string { return "google-oauth" }
+
+func (a *GeminiOAuthAdapter) Matches(headers map[string][]string) bool {
+	auth := HeaderValue(headers, "Authorization")
+	return strings.HasPrefix(auth, "Bearer ya29.")
+}
+
+func (a *GeminiOAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
+	auth := HeaderValue(headers, "Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	return &AuthInfo{
+		Provider: "google",
+		Type:     "bearer",
+		Token:    NewSecret(strings.TrimPrefix(auth, "Bearer ")),
+	}
+}
+
+func (a *GeminiOAuthAdapter) Apply(outgoing *http.Request) *http.Request {
+	return outgoing
+}
+
+// HeaderValue returns the first value of a header, trying key as given and
+// then lowercased
+func HeaderValue(headers map[string][]string, key string) string {
+	if values, ok := headers[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	// Try lowercase key
+	if values, ok := headers[strings.ToLower(key)]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -1,4 +1,4 @@
-package main
+package auth
 
 import (
 	"net/http"
@@ -16,19 +16,19 @@ type MockOAuthAdapter struct{}
 func (a *MockOAuthAdapter) GetID() string { return "mock-oauth" }
 
 func (a *MockOAuthAdapter) Matches(headers map[string][]string) bool {
-	auth := getHeaderValue(headers, "authorization")
+	auth := HeaderValue(headers, "authorization")
 	return len(auth) > 7 && auth[:7] == "Bearer " && len(auth) > 13 && auth[7:13] == "oauth-"
 }
 
 func (a *MockOAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
-	auth := getHeaderValue(headers, "authorization")
+	auth := HeaderValue(headers, "authorization")
 	if !a.Matches(headers) {
 		return nil
 	}
 	return &AuthInfo{
 		Provider: "mock-oauth",
 		Type:     "oauth",
-		Token:    auth[7:], // Remove "Bearer " prefix
+		Token:    NewSecret(auth[7:]), // Remove "Bearer " prefix
 	}
 }
 
@@ -42,19 +42,19 @@ type MockKeyAdapter struct{}
 func (a *MockKeyAdapter) GetID() string { return "mock-key" }
 
 func (a *MockKeyAdapter) Matches(headers map[string][]string) bool {
-	auth := getHeaderValue(headers, "authorization")
+	auth := HeaderValue(headers, "authorization")
 	return len(auth) > 7 && auth[:7] == "Bearer " && len(auth) > 11 && auth[7:11] == "key-"
 }
 
 func (a *MockKeyAdapter) Extract(headers map[string][]string) *AuthInfo {
-	auth := getHeaderValue(headers, "authorization")
+	auth := HeaderValue(headers, "authorization")
 	if !a.Matches(headers) {
 		return nil
 	}
 	return &AuthInfo{
 		Provider: "mock-key",
 		Type:     "api-key", 
-		Token:    auth[7:], // Remove "Bearer " prefix
+		Token:    NewSecret(auth[7:]), // Remove "Bearer " prefix
 	}
 }
 
@@ -295,7 +295,7 @@ func testMockAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "mock-oauth", authInfo.Provider)
 			assert.Equal(t, "oauth", authInfo.Type)
-			assert.Equal(t, "oauth-token-123", authInfo.Token)
+			assert.Equal(t, "oauth-token-123", authInfo.Token.Reveal())
 		})
 
 		t.Run("should return nil for non-matching headers", func(t *testing.T) {
@@ -319,7 +319,7 @@ func testMockAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "mock-oauth", authInfo.Provider)
 			assert.Equal(t, "oauth", authInfo.Type)
-			assert.Equal(t, "oauth-token-123", authInfo.Token)
+			assert.Equal(t, "oauth-token-123", authInfo.Token.Reveal())
 		})
 	})
 
@@ -352,7 +352,7 @@ func testMockAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "mock-key", authInfo.Provider)
 			assert.Equal(t, "api-key", authInfo.Type)
-			assert.Equal(t, "key-abc123", authInfo.Token)
+			assert.Equal(t, "key-abc123", authInfo.Token.Reveal())
 		})
 
 		t.Run("should return nil for non-matching headers", func(t *testing.T) {
@@ -389,7 +389,7 @@ func testAuthIntegrationScenarios(t *testing.T) {
 		require.NotNil(t, oauthInfo)
 		assert.Equal(t, "mock-oauth", oauthInfo.Provider)
 		assert.Equal(t, "oauth", oauthInfo.Type)
-		assert.Equal(t, "oauth-my-token", oauthInfo.Token)
+		assert.Equal(t, "oauth-my-token", oauthInfo.Token.Reveal())
 
 		// Test API key flow
 		keyHeaders := map[string][]string{
@@ -404,7 +404,7 @@ func testAuthIntegrationScenarios(t *testing.T) {
 		require.NotNil(t, keyInfo)
 		assert.Equal(t, "mock-key", keyInfo.Provider)
 		assert.Equal(t, "api-key", keyInfo.Type)
-		assert.Equal(t, "key-my-key", keyInfo.Token)
+		assert.Equal(t, "key-my-key", keyInfo.Token.Reveal())
 	})
 
 	t.Run("should handle priority-based adapter selection", func(t *testing.T) {
@@ -467,7 +467,7 @@ func TestBuiltinAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "openai", authInfo.Provider)
 			assert.Equal(t, "bearer", authInfo.Type)
-			assert.Equal(t, "sk-test123", authInfo.Token)
+			assert.Equal(t, "sk-test123", authInfo.Token.Reveal())
 		})
 	})
 
@@ -492,7 +492,7 @@ func TestBuiltinAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "anthropic", authInfo.Provider)
 			assert.Equal(t, "bearer", authInfo.Type)
-			assert.Equal(t, "anthropic_test123", authInfo.Token)
+			assert.Equal(t, "anthropic_test123", authInfo.Token.Reveal())
 		})
 	})
 
@@ -517,7 +517,7 @@ func TestBuiltinAuthAdapters(t *testing.T) {
 			require.NotNil(t, authInfo)
 			assert.Equal(t, "google", authInfo.Provider)
 			assert.Equal(t, "bearer", authInfo.Type)
-			assert.Equal(t, "ya29.test123", authInfo.Token)
+			assert.Equal(t, "ya29.test123", authInfo.Token.Reveal())
 		})
 	})
 }
@@ -540,4 +540,34 @@ func TestAuthAdapterRegistryMethods(t *testing.T) {
 		require.NotNil(t, match)
 		assert.Equal(t, "mock-oauth", match.GetID())
 	})
-}
\ No newline at end of file
+}
+
+func TestAuthAdapters(t *testing.T) {
+	registry := NewAuthAdapterRegistry()
+	
+	// Test OpenAI adapter
+	openaiAdapter := &OpenAIKeyAdapter{}
+	registry.Register(openaiAdapter)
+	
+	headers := map[string][]string{
+		"Authorization": {"Bearer sk-test123"},
+	}
+	
+	adapter := registry.FindMatch(headers)
+	if adapter == nil {
+		t.Error("Expected to find OpenAI adapter")
+	}
+	
+	if adapter.GetID() != "openai-key" {
+		t.Errorf("Expected adapter ID 'openai-key', got '%s'", adapter.GetID())
+	}
+	
+	authInfo := adapter.Extract(headers)
+	if authInfo == nil {
+		t.Error("Expected auth info to be extracted")
+	}
+	
+	if authInfo.Provider != "openai" {
+		t.Errorf("Expected provider 'openai', got '%s'", authInfo.Provider)
+	}
+}
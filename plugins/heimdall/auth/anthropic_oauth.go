@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAnthropicTokenPatterns match Anthropic OAuth access tokens
+// ("sk-ant-oat01-...") and the legacy "anthropic_" prefix
+var defaultAnthropicTokenPatterns = []string{
+	`^sk-ant-oat\d*-`,
+	`^anthropic_`,
+}
+
+// AnthropicOAuthConfig configures Anthropic OAuth token detection and validation
+type AnthropicOAuthConfig struct {
+	// TokenPatterns are regexes matched against the bearer token;
+	// defaults to defaultAnthropicTokenPatterns
+	TokenPatterns []string `json:"token_patterns"`
+
+	// IntrospectionURL enables RFC 7662-style token validation when set
+	IntrospectionURL  string        `json:"introspection_url"`
+	ValidationTimeout time.Duration `json:"validation_timeout"`
+
+	// CacheTTL caps how long a positive validation is cached; tokens reporting
+	// an earlier expiry are cached only until they expire
+	CacheTTL         time.Duration `json:"cache_ttl"`
+	NegativeCacheTTL time.Duration `json:"negative_cache_ttl"`
+
+	// FailOpen accepts pattern-matched tokens when introspection is unavailable
+	FailOpen bool `json:"fail_open"`
+}
+
+// tokenValidation is a cached introspection result
+type tokenValidation struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// introspectionResponse is the subset of an RFC 7662 response we use
+type introspectionResponse struct {
+	Active bool  `json:"active"`
+	Exp    int64 `json:"exp,omitempty"`
+}
+
+// AnthropicOAuthAdapter handles Anthropic OAuth with configurable token
+// patterns and optional, cached token introspection.
+// The zero value matches the default token patterns without introspection.
+type AnthropicOAuthAdapter struct {
+	config     AnthropicOAuthConfig
+	httpClient *http.Client
+
+	patterns    []*regexp.Regexp
+	patternOnce sync.Once
+
+	validations map[string]tokenValidation // token fingerprint -> result
+	mu          sync.RWMutex
+}
+
+// NewAnthropicOAuthAdapter creates an adapter, validating the token patterns
+func NewAnthropicOAuthAdapter(config AnthropicOAuthConfig) (*AnthropicOAuthAdapter, error) {
+	if len(config.TokenPatterns) == 0 {
+		config.TokenPatterns = defaultAnthropicTokenPatterns
+	}
+	if config.ValidationTimeout == 0 {
+		config.ValidationTimeout = 2 * time.Second
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 10 * time.Minute
+	}
+	if config.NegativeCacheTTL == 0 {
+		config.NegativeCacheTTL = time.Minute
+	}
+
+	patterns, err := compileTokenPatterns(config.TokenPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &AnthropicOAuthAdapter{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: config.ValidationTimeout,
+		},
+		patterns:    patterns,
+		validations: make(map[string]tokenValidation),
+	}
+	adapter.patternOnce.Do(func() {}) // patterns already compiled
+
+	return adapter, nil
+}
+
+func compileTokenPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func (a *AnthropicOAuthAdapter) GetID() string { return "anthropic-oauth" }
+
+func (a *AnthropicOAuthAdapter) Matches(headers map[string][]string) bool {
+	token, ok := bearerToken(headers)
+	if !ok || !a.matchesPattern(token) {
+		return false
+	}
+
+	if a.config.IntrospectionURL == "" {
+		return true
+	}
+	return a.validate(token)
+}
+
+func (a *AnthropicOAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
+	token, ok := bearerToken(headers)
+	if !ok {
+		return nil
+	}
+	return &AuthInfo{
+		Provider: "anthropic",
+		Type:     "bearer",
+		Token:    NewSecret(token),
+	}
+}
+
+func (a *AnthropicOAuthAdapter) Apply(outgoing *http.Request) *http.Request {
+	return outgoing
+}
+
+// matchesPattern reports whether the token matches any configured pattern
+func (a *AnthropicOAuthAdapter) matchesPattern(token string) bool {
+	a.patternOnce.Do(func() {
+		// Zero-value adapter: fall back to the default patterns
+		a.patterns, _ = compileTokenPatterns(defaultAnthropicTokenPatterns)
+	})
+
+	for _, pattern := range a.patterns {
+		if pattern.MatchString(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the token against the introspection endpoint, caching
+// results by token fingerprint until the token (or cache TTL) expires
+func (a *AnthropicOAuthAdapter) validate(token string) bool {
+	key := TokenFingerprint(token)
+	now := time.Now()
+
+	a.mu.RLock()
+	cached, ok := a.validations[key]
+	a.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.active
+	}
+
+	result, err := a.introspect(token)
+	if err != nil {
+		log.Printf("Anthropic token introspection failed: %v", err)
+		// Don't cache transport failures so the next request retries
+		return a.config.FailOpen
+	}
+
+	validation := tokenValidation{active: result.Active}
+	if result.Active {
+		validation.expiresAt = now.Add(a.config.CacheTTL)
+		if result.Exp > 0 {
+			tokenExpiry := time.Unix(result.Exp, 0)
+			if tokenExpiry.Before(validation.expiresAt) {
+				validation.expiresAt = tokenExpiry
+			}
+			if !tokenExpiry.After(now) {
+				validation.active = false
+				validation.expiresAt = now.Add(a.config.NegativeCacheTTL)
+			}
+		}
+	} else {
+		validation.expiresAt = now.Add(a.config.NegativeCacheTTL)
+	}
+
+	a.mu.Lock()
+	a.validations[key] = validation
+	a.evictExpiredValidations(now)
+	a.mu.Unlock()
+
+	return validation.active
+}
+
+// introspect calls the introspection endpoint for a token
+func (a *AnthropicOAuthAdapter) introspect(token string) (*introspectionResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.ValidationTimeout)
+	defer cancel()
+
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.config.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection returned status %d", resp.StatusCode)
+	}
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	return &result, nil
+}
+
+// evictExpiredValidations drops expired cache entries (no lock - called from locked context)
+func (a *AnthropicOAuthAdapter) evictExpiredValidations(now time.Time) {
+	if len(a.validations) < 1000 {
+		return
+	}
+	for key, validation := range a.validations {
+		if now.After(validation.expiresAt) {
+			delete(a.validations, key)
+		}
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(headers map[string][]string) (string, bool) {
+	auth := HeaderValue(headers, "Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, "Bearer "), true
+}
+
+// TokenFingerprint returns a non-reversible identifier for a secret token
+func TokenFingerprint(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:8])
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bearerHeaders(token string) map[string][]string {
+	return map[string][]string{"Authorization": {"Bearer " + token}}
+}
+
+func TestAnthropicOAuthAdapterPatterns(t *testing.T) {
+	t.Run("should match real OAuth tokens and the legacy prefix by default", func(t *testing.T) {
+		adapter := &AnthropicOAuthAdapter{}
+
+		assert.True(t, adapter.Matches(bearerHeaders("sk-ant-oat01-abcdef")))
+		assert.True(t, adapter.Matches(bearerHeaders("anthropic_test123")))
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-api03-abcdef")))
+		assert.False(t, adapter.Matches(bearerHeaders("ya29.token")))
+	})
+
+	t.Run("should not let the OpenAI adapter claim Anthropic tokens", func(t *testing.T) {
+		adapter := &OpenAIKeyAdapter{}
+
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-oat01-abcdef")))
+		assert.True(t, adapter.Matches(bearerHeaders("sk-proj-abcdef")))
+	})
+
+	t.Run("should honor configured patterns", func(t *testing.T) {
+		adapter, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{
+			TokenPatterns: []string{`^corp-claude-`},
+		})
+		require.NoError(t, err)
+
+		assert.True(t, adapter.Matches(bearerHeaders("corp-claude-123")))
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-oat01-abcdef")))
+	})
+
+	t.Run("should reject invalid patterns", func(t *testing.T) {
+		_, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{TokenPatterns: []string{"("}})
+		assert.Error(t, err)
+	})
+}
+
+func TestAnthropicOAuthAdapterIntrospection(t *testing.T) {
+	newIntrospectionServer := func(calls *int32, responses map[string]introspectionResponse) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(calls, 1)
+			require.NoError(t, r.ParseForm())
+			json.NewEncoder(w).Encode(responses[r.PostForm.Get("token")])
+		}))
+	}
+
+	t.Run("should validate tokens and cache results", func(t *testing.T) {
+		var calls int32
+		server := newIntrospectionServer(&calls, map[string]introspectionResponse{
+			"sk-ant-oat01-good": {Active: true},
+			"sk-ant-oat01-bad":  {Active: false},
+		})
+		defer server.Close()
+
+		adapter, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: server.URL})
+		require.NoError(t, err)
+
+		assert.True(t, adapter.Matches(bearerHeaders("sk-ant-oat01-good")))
+		assert.True(t, adapter.Matches(bearerHeaders("sk-ant-oat01-good")))
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-oat01-bad")))
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-oat01-bad")))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should not cache past token expiry", func(t *testing.T) {
+		var calls int32
+		server := newIntrospectionServer(&calls, map[string]introspectionResponse{
+			"sk-ant-oat01-expiring": {Active: true, Exp: time.Now().Add(time.Second).Unix()},
+		})
+		defer server.Close()
+
+		adapter, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: server.URL})
+		require.NoError(t, err)
+
+		assert.True(t, adapter.Matches(bearerHeaders("sk-ant-oat01-expiring")))
+
+		cached := adapter.validations[TokenFingerprint("sk-ant-oat01-expiring")]
+		assert.True(t, cached.expiresAt.Before(time.Now().Add(2*time.Second)))
+	})
+
+	t.Run("should treat already expired tokens as inactive", func(t *testing.T) {
+		var calls int32
+		server := newIntrospectionServer(&calls, map[string]introspectionResponse{
+			"sk-ant-oat01-expired": {Active: true, Exp: time.Now().Add(-time.Minute).Unix()},
+		})
+		defer server.Close()
+
+		adapter, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: server.URL})
+		require.NoError(t, err)
+
+		assert.False(t, adapter.Matches(bearerHeaders("sk-ant-oat01-expired")))
+	})
+
+	t.Run("should fail closed unless fail-open is configured", func(t *testing.T) {
+		closed, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: "http://127.0.0.1:1"})
+		require.NoError(t, err)
+		assert.False(t, closed.Matches(bearerHeaders("sk-ant-oat01-good")))
+
+		open, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: "http://127.0.0.1:1", FailOpen: true})
+		require.NoError(t, err)
+		assert.True(t, open.Matches(bearerHeaders("sk-ant-oat01-good")))
+	})
+
+	t.Run("should never key the cache by the raw token", func(t *testing.T) {
+		var calls int32
+		server := newIntrospectionServer(&calls, map[string]introspectionResponse{
+			"sk-ant-oat01-good": {Active: true},
+		})
+		defer server.Close()
+
+		adapter, err := NewAnthropicOAuthAdapter(AnthropicOAuthConfig{IntrospectionURL: server.URL})
+		require.NoError(t, err)
+		adapter.Matches(bearerHeaders("sk-ant-oat01-good"))
+
+		for key := range adapter.validations {
+			assert.NotContains(t, key, "sk-ant")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedRequest(secret, service string, signedAt time.Time, body []byte) *core.RouterRequest {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	return &core.RouterRequest{
+		Headers: map[string][]string{
+			HMACServiceHeader:   {service},
+			HMACTimestampHeader: {timestamp},
+			HMACSignatureHeader: {hex.EncodeToString(SignRequest([]byte(secret), timestamp, body))},
+		},
+		RawBody: body,
+	}
+}
+
+func TestHMACAuthAdapter(t *testing.T) {
+	body := []byte(`{"messages":[{"role":"user","content":"hi"}]}`)
+	newAdapter := func(t *testing.T) *HMACAuthAdapter {
+		adapter, err := NewHMACAuthAdapter(HMACAuthConfig{
+			Services: map[string]HMACServiceConfig{
+				"billing": {
+					Secret: "s3cret",
+					Tenant: "finance",
+					Policy: &core.RoutingPolicy{MaxBucket: core.BucketMid},
+				},
+			},
+		})
+		require.NoError(t, err)
+		return adapter
+	}
+
+	t.Run("should verify signed requests and map the service to its tenant", func(t *testing.T) {
+		adapter := newAdapter(t)
+		req := signedRequest("s3cret", "billing", time.Now(), body)
+
+		require.True(t, adapter.Matches(req.Headers))
+		require.NoError(t, adapter.Verify(req))
+
+		authInfo := adapter.Extract(req.Headers)
+		require.NotNil(t, authInfo)
+		assert.Equal(t, "hmac", authInfo.Type)
+		assert.Equal(t, "billing", authInfo.Subject)
+		assert.Equal(t, "finance", authInfo.Org)
+		assert.Equal(t, core.BucketMid, authInfo.Policy.MaxBucket)
+	})
+
+	t.Run("should reject bad signatures, stale timestamps and replays", func(t *testing.T) {
+		adapter := newAdapter(t)
+
+		assert.Error(t, adapter.Verify(signedRequest("wrong", "billing", time.Now(), body)))
+		assert.Error(t, adapter.Verify(signedRequest("s3cret", "billing", time.Now().Add(-time.Hour), body)))
+
+		tampered := signedRequest("s3cret", "billing", time.Now(), body)
+		tampered.RawBody = []byte(`{"messages":[]}`)
+		assert.Error(t, adapter.Verify(tampered))
+
+		req := signedRequest("s3cret", "billing", time.Now(), body)
+		require.NoError(t, adapter.Verify(req))
+		assert.Error(t, adapter.Verify(req))
+	})
+
+	t.Run("should reject requests whose raw body is unavailable", func(t *testing.T) {
+		adapter := newAdapter(t)
+		req := signedRequest("s3cret", "billing", time.Now(), nil)
+		assert.ErrorContains(t, adapter.Verify(req), "body unavailable")
+	})
+
+	t.Run("should only match known services", func(t *testing.T) {
+		adapter := newAdapter(t)
+
+		assert.False(t, adapter.Matches(signedRequest("s3cret", "unknown", time.Now(), body).Headers))
+		assert.False(t, adapter.Matches(map[string][]string{"Authorization": {"Bearer sk-test"}}))
+	})
+
+	t.Run("should require a secret for every service", func(t *testing.T) {
+		_, err := NewHMACAuthAdapter(HMACAuthConfig{
+			Services: map[string]HMACServiceConfig{"billing": {SecretEnv: "HEIMDALL_TEST_UNSET_SECRET"}},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,472 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// JWTAuthConfig configures validation of inbound JWTs and the mapping of
+// their claims to routing policy
+type JWTAuthConfig struct {
+	JWKSURL  string `json:"jwks_url"`
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+
+	// Leeway tolerates clock skew when checking exp/nbf
+	Leeway time.Duration `json:"leeway"`
+	// JWKSRefresh is how often the key set is refetched
+	JWKSRefresh time.Duration `json:"jwks_refresh"`
+
+	// Claim names carrying the caller's tier and organization
+	TierClaim string `json:"tier_claim"`
+	OrgClaim  string `json:"org_claim"`
+
+	// Policies applied by tier, then overridden field-by-field by org
+	DefaultPolicy *core.RoutingPolicy           `json:"default_policy,omitempty"`
+	TierPolicies  map[string]core.RoutingPolicy `json:"tier_policies"`
+	OrgPolicies   map[string]core.RoutingPolicy `json:"org_policies"`
+}
+
+// jwtClaims are the registered claims we check plus all raw claims
+type jwtClaims struct {
+	Issuer    string
+	Audience  []string
+	Subject   string
+	ExpiresAt int64
+	NotBefore int64
+	Raw       map[string]interface{}
+}
+
+// jwk is a single key from a JWKS document
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWTAuthAdapter authenticates callers with JWTs signed by a key in a JWKS
+// and attaches the routing policy mapped from their claims
+type JWTAuthAdapter struct {
+	config     JWTAuthConfig
+	httpClient *http.Client
+
+	keys        map[string]crypto.PublicKey // kid -> key
+	keysFetched time.Time
+	keysMu      sync.RWMutex
+
+	verified map[string]*jwtClaims // token fingerprint -> claims
+	mu       sync.RWMutex
+}
+
+// NewJWTAuthAdapter creates a JWT adapter, validating its configuration
+func NewJWTAuthAdapter(config JWTAuthConfig) (*JWTAuthAdapter, error) {
+	if config.JWKSURL == "" {
+		return nil, fmt.Errorf("jwks_url is required")
+	}
+	if config.TierClaim == "" {
+		config.TierClaim = "tier"
+	}
+	if config.OrgClaim == "" {
+		config.OrgClaim = "org"
+	}
+	if config.Leeway == 0 {
+		config.Leeway = 30 * time.Second
+	}
+	if config.JWKSRefresh == 0 {
+		config.JWKSRefresh = 10 * time.Minute
+	}
+
+	if err := config.DefaultPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("default policy: %w", err)
+	}
+	for tier, policy := range config.TierPolicies {
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("tier %s policy: %w", tier, err)
+		}
+	}
+	for org, policy := range config.OrgPolicies {
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("org %s policy: %w", org, err)
+		}
+	}
+
+	return &JWTAuthAdapter{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		keys:     make(map[string]crypto.PublicKey),
+		verified: make(map[string]*jwtClaims),
+	}, nil
+}
+
+func (a *JWTAuthAdapter) GetID() string { return "jwt" }
+
+// Matches reports whether the request carries a valid JWT. Tokens that look
+// like JWTs but fail validation are not matched.
+func (a *JWTAuthAdapter) Matches(headers map[string][]string) bool {
+	token, ok := bearerToken(headers)
+	if !ok || strings.Count(token, ".") != 2 {
+		return false
+	}
+	_, err := a.verify(token)
+	if err != nil {
+		log.Printf("JWT rejected: %v", err)
+		return false
+	}
+	return true
+}
+
+func (a *JWTAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
+	token, ok := bearerToken(headers)
+	if !ok {
+		return nil
+	}
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil
+	}
+
+	tier := claimString(claims.Raw, a.config.TierClaim)
+	org := claimString(claims.Raw, a.config.OrgClaim)
+
+	// The identity token is not a provider credential, so it is not forwarded
+	return &AuthInfo{
+		Type:    "jwt",
+		Subject: claims.Subject,
+		Tier:    tier,
+		Org:     org,
+		Policy:  a.policyFor(tier, org),
+	}
+}
+
+func (a *JWTAuthAdapter) Apply(outgoing *http.Request) *http.Request {
+	return outgoing
+}
+
+// policyFor resolves the routing policy for a tier and organization
+func (a *JWTAuthAdapter) policyFor(tier, org string) *core.RoutingPolicy {
+	policy := a.config.DefaultPolicy
+	if tierPolicy, ok := a.config.TierPolicies[tier]; ok {
+		policy = policy.Merge(&tierPolicy)
+	}
+	if orgPolicy, ok := a.config.OrgPolicies[org]; ok {
+		policy = policy.Merge(&orgPolicy)
+	}
+	return policy
+}
+
+// verify validates the token's signature and claims, caching verified
+// claims until the token expires
+func (a *JWTAuthAdapter) verify(token string) (*jwtClaims, error) {
+	key := TokenFingerprint(token)
+	now := time.Now()
+
+	a.mu.RLock()
+	cached, ok := a.verified[key]
+	a.mu.RUnlock()
+	if ok {
+		if err := a.checkTimes(cached, now); err == nil {
+			return cached, nil
+		}
+	}
+
+	claims, err := a.parseAndVerify(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkClaims(claims, now); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	if len(a.verified) >= 1000 {
+		for k, c := range a.verified {
+			if a.checkTimes(c, now) != nil {
+				delete(a.verified, k)
+			}
+		}
+	}
+	a.verified[key] = claims
+	a.mu.Unlock()
+
+	return claims, nil
+}
+
+// parseAndVerify decodes the token and checks its signature
+func (a *JWTAuthAdapter) parseAndVerify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	publicKey, err := a.getKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, publicKey, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	claims := &jwtClaims{
+		Issuer:    claimString(raw, "iss"),
+		Subject:   claimString(raw, "sub"),
+		ExpiresAt: claimInt(raw, "exp"),
+		NotBefore: claimInt(raw, "nbf"),
+		Raw:       raw,
+	}
+	switch aud := raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				claims.Audience = append(claims.Audience, s)
+			}
+		}
+	}
+	return claims, nil
+}
+
+// checkClaims validates issuer, audience and validity window
+func (a *JWTAuthAdapter) checkClaims(claims *jwtClaims, now time.Time) error {
+	if a.config.Issuer != "" && claims.Issuer != a.config.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if a.config.Audience != "" && !slices.Contains(claims.Audience, a.config.Audience) {
+		return fmt.Errorf("token not issued for audience %q", a.config.Audience)
+	}
+	return a.checkTimes(claims, now)
+}
+
+// checkTimes validates exp and nbf with the configured leeway
+func (a *JWTAuthAdapter) checkTimes(claims *jwtClaims, now time.Time) error {
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(a.config.Leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-a.config.Leeway)) {
+		return fmt.Errorf("token not yet valid")
+	}
+	return nil
+}
+
+// getKey returns the public key for a kid, refreshing the JWKS when it is
+// stale or the kid is unknown (rate limited to once per minute)
+func (a *JWTAuthAdapter) getKey(kid string) (crypto.PublicKey, error) {
+	a.keysMu.RLock()
+	key, ok := a.keys[kid]
+	age := time.Since(a.keysFetched)
+	a.keysMu.RUnlock()
+
+	if ok && age < a.config.JWKSRefresh {
+		return key, nil
+	}
+	if !ok && age < time.Minute {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if err := a.refreshKeys(); err != nil {
+		if ok {
+			// Keep using the known key if the JWKS is temporarily unavailable
+			log.Printf("Failed to refresh JWKS, keeping existing keys: %v", err)
+			return key, nil
+		}
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refreshKeys fetches and parses the JWKS document
+func (a *JWTAuthAdapter) refreshKeys() error {
+	resp, err := a.httpClient.Get(a.config.JWKSURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS returned status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		publicKey, err := k.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = publicKey
+	}
+
+	a.keysMu.Lock()
+	a.keys = keys
+	a.keysFetched = time.Now()
+	a.keysMu.Unlock()
+	return nil
+}
+
+// publicKey converts a JWK into an RSA or EC public key
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// jwtHashes maps supported algorithms to their hash function
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifySignature checks an RS*/ES* signature over the signing input
+func verifySignature(alg string, publicKey crypto.PublicKey, signingInput string, signature []byte) error {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %s does not match EC key", alg)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type")
+	}
+	return nil
+}
+
+// decodeSegment base64url-decodes and unmarshals a JWT segment
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimString returns a string claim, or "" if absent
+func claimString(claims map[string]interface{}, name string) string {
+	if s, ok := claims[name].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// claimInt returns a numeric claim, or 0 if absent
+func claimInt(claims map[string]interface{}, name string) int64 {
+	if f, ok := claims[name].(float64); ok {
+		return int64(f)
+	}
+	return 0
+}
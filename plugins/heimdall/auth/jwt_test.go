@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwtTestIssuer signs tokens and serves the matching JWKS
+type jwtTestIssuer struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	server *httptest.Server
+}
+
+func newJWTTestIssuer(t *testing.T) *jwtTestIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issuer := &jwtTestIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kid": "rsa-1", "kty": "RSA",
+				"n": b64(rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kid": "ec-1", "kty": "EC", "crv": "P-256",
+				"x": b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *jwtTestIssuer) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signingInput))
+
+	var signature []byte
+	var err error
+	if alg == "ES256" {
+		r, s, signErr := ecdsa.Sign(rand.Reader, i.ecKey, digest.Sum(nil))
+		require.NoError(t, signErr)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest.Sum(nil))
+		require.NoError(t, err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":  "https://idp.example.com",
+		"aud":  "heimdall",
+		"sub":  "user-123",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+		"tier": "free",
+		"org":  "acme",
+	}
+}
+
+func TestJWTAuthAdapter(t *testing.T) {
+	issuer := newJWTTestIssuer(t)
+	newAdapter := func(t *testing.T) *JWTAuthAdapter {
+		adapter, err := NewJWTAuthAdapter(JWTAuthConfig{
+			JWKSURL:  issuer.server.URL,
+			Issuer:   "https://idp.example.com",
+			Audience: "heimdall",
+			TierPolicies: map[string]core.RoutingPolicy{
+				"free": {MaxBucket: core.BucketCheap, MaxPrice: 5},
+				"pro":  {MaxBucket: core.BucketHard},
+			},
+			OrgPolicies: map[string]core.RoutingPolicy{
+				"acme": {Candidates: []string{"deepseek/deepseek-r1"}},
+			},
+		})
+		require.NoError(t, err)
+		return adapter
+	}
+
+	t.Run("should accept valid RS256 and ES256 tokens", func(t *testing.T) {
+		adapter := newAdapter(t)
+
+		assert.True(t, adapter.Matches(bearerHeaders(issuer.sign(t, "RS256", "rsa-1", validClaims()))))
+		assert.True(t, adapter.Matches(bearerHeaders(issuer.sign(t, "ES256", "ec-1", validClaims()))))
+	})
+
+	t.Run("should reject invalid tokens", func(t *testing.T) {
+		adapter := newAdapter(t)
+
+		wrongIssuer := validClaims()
+		wrongIssuer["iss"] = "https://evil.example.com"
+		wrongAudience := validClaims()
+		wrongAudience["aud"] = []string{"other"}
+		expired := validClaims()
+		expired["exp"] = time.Now().Add(-time.Hour).Unix()
+		noExpiry := validClaims()
+		delete(noExpiry, "exp")
+
+		for name, claims := range map[string]map[string]interface{}{
+			"issuer": wrongIssuer, "audience": wrongAudience, "expired": expired, "no expiry": noExpiry,
+		} {
+			assert.False(t, adapter.Matches(bearerHeaders(issuer.sign(t, "RS256", "rsa-1", claims))), name)
+		}
+
+		tampered := issuer.sign(t, "RS256", "rsa-1", validClaims()) + "x"
+		assert.False(t, adapter.Matches(bearerHeaders(tampered)))
+		assert.False(t, adapter.Matches(bearerHeaders(issuer.sign(t, "RS256", "unknown", validClaims()))))
+		assert.False(t, adapter.Matches(bearerHeaders("sk-not-a-jwt")))
+	})
+
+	t.Run("should map claims to a merged routing policy", func(t *testing.T) {
+		adapter := newAdapter(t)
+
+		authInfo := adapter.Extract(bearerHeaders(issuer.sign(t, "RS256", "rsa-1", validClaims())))
+		require.NotNil(t, authInfo)
+		assert.Equal(t, "jwt", authInfo.Type)
+		assert.Equal(t, "user-123", authInfo.Subject)
+		assert.False(t, authInfo.Token.IsSet())
+		assert.Empty(t, authInfo.Provider)
+
+		require.NotNil(t, authInfo.Policy)
+		assert.Equal(t, core.BucketCheap, authInfo.Policy.MaxBucket)
+		assert.Equal(t, 5, authInfo.Policy.MaxPrice)
+		assert.Equal(t, []string{"deepseek/deepseek-r1"}, authInfo.Policy.Candidates)
+	})
+
+	t.Run("should reject invalid configuration", func(t *testing.T) {
+		_, err := NewJWTAuthAdapter(JWTAuthConfig{})
+		assert.Error(t, err)
+
+		_, err = NewJWTAuthAdapter(JWTAuthConfig{
+			JWKSURL:      issuer.server.URL,
+			TierPolicies: map[string]core.RoutingPolicy{"free": {MaxBucket: "tiny"}},
+		})
+		assert.Error(t, err)
+	})
+}
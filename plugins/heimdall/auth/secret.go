@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const redactedPrefix = "[redacted:"
+
+// Secret wraps a credential so it is never printed or serialized. Formatting
+// and JSON emit only a fingerprint; the value is available via Reveal.
+// Secrets decoded from JSON carry the fingerprint but no value.
+type Secret struct {
+	fingerprint string
+	value       func() string
+}
+
+// NewSecret wraps a credential value
+func NewSecret(value string) Secret {
+	if value == "" {
+		return Secret{}
+	}
+	return Secret{
+		fingerprint: TokenFingerprint(value),
+		value:       func() string { return value },
+	}
+}
+
+// Reveal returns the raw value, or "" when unset or decoded from a record
+func (s Secret) Reveal() string {
+	if s.value == nil {
+		return ""
+	}
+	return s.value()
+}
+
+// Fingerprint returns a non-reversible identifier for the value
+func (s Secret) Fingerprint() string {
+	return s.fingerprint
+}
+
+// Redacted returns the secret reduced to its fingerprint, as it is after a
+// JSON round trip
+func (s Secret) Redacted() Secret {
+	return Secret{fingerprint: s.fingerprint}
+}
+
+// IsSet reports whether the secret identifies a credential
+func (s Secret) IsSet() bool {
+	return s.fingerprint != ""
+}
+
+// String implements fmt.Stringer without exposing the value
+func (s Secret) String() string {
+	if !s.IsSet() {
+		return ""
+	}
+	return redactedPrefix + s.fingerprint + "]"
+}
+
+// GoString keeps %#v from exposing the value
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// MarshalJSON emits the redacted form only
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON accepts the redacted form. Raw values (e.g. from records
+// written before redaction) are reduced to their fingerprint.
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	switch {
+	case str == "":
+		*s = Secret{}
+	case strings.HasPrefix(str, redactedPrefix) && strings.HasSuffix(str, "]"):
+		*s = Secret{fingerprint: strings.TrimSuffix(strings.TrimPrefix(str, redactedPrefix), "]")}
+	default:
+		*s = Secret{fingerprint: TokenFingerprint(str)}
+	}
+	return nil
+}
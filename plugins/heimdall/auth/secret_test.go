@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret(t *testing.T) {
+	const raw = "sk-live-abcdef123456"
+
+	t.Run("should reveal the value only on request", func(t *testing.T) {
+		secret := NewSecret(raw)
+
+		assert.Equal(t, raw, secret.Reveal())
+		assert.Equal(t, TokenFingerprint(raw), secret.Fingerprint())
+		assert.True(t, secret.IsSet())
+		assert.False(t, NewSecret("").IsSet())
+	})
+
+	t.Run("should never format the raw value", func(t *testing.T) {
+		authInfo := &AuthInfo{Provider: "openai", Type: "bearer", Token: NewSecret(raw)}
+
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			formatted := fmt.Sprintf(verb, authInfo)
+			assert.NotContains(t, formatted, raw, verb)
+		}
+		assert.Contains(t, fmt.Sprintf("%+v", authInfo), TokenFingerprint(raw))
+	})
+
+	t.Run("should marshal only the redacted form", func(t *testing.T) {
+		data, err := json.Marshal(&AuthInfo{Token: NewSecret(raw)})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), raw)
+
+		var decoded AuthInfo
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, TokenFingerprint(raw), decoded.Token.Fingerprint())
+		assert.Empty(t, decoded.Token.Reveal())
+	})
+
+	t.Run("should redact to the fingerprint like a round trip", func(t *testing.T) {
+		redacted := NewSecret(raw).Redacted()
+		assert.Equal(t, TokenFingerprint(raw), redacted.Fingerprint())
+		assert.Empty(t, redacted.Reveal())
+	})
+
+	t.Run("should reduce raw values in legacy records to a fingerprint", func(t *testing.T) {
+		var decoded AuthInfo
+		require.NoError(t, json.Unmarshal([]byte(`{"token":"`+raw+`"}`), &decoded))
+		assert.Equal(t, TokenFingerprint(raw), decoded.Token.Fingerprint())
+		assert.Empty(t, decoded.Token.Reveal())
+
+		require.NoError(t, json.Unmarshal([]byte(`{"token":""}`), &decoded))
+		assert.False(t, decoded.Token.IsSet())
+	})
+}
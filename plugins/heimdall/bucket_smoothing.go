@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+)
+
+// BucketSmoothingConfig configures smoothing of bucket probabilities across
+// a user's recent requests, so one noisy triage does not flip an otherwise
+// consistent user between buckets. Users are told apart by JWT subject or
+// credential; anonymous requests are not smoothed.
+type BucketSmoothingConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Window is the number of recent requests the moving average spans
+	// (default 5); each request weighs 2/(Window+1)
+	Window int `json:"window"`
+	// IdleTTL forgets a user's history after this long without requests
+	// (default 30m)
+	IdleTTL time.Duration `json:"idle_ttl"`
+}
+
+// BucketSmoothingStats counts smoothed requests
+type BucketSmoothingStats struct {
+	Users    int   `json:"users"`
+	Smoothed int64 `json:"smoothed"`
+	// Overridden counts requests whose smoothed bucket differs from the
+	// bucket their own triage picked
+	Overridden int64 `json:"overridden"`
+}
+
+// SmoothedProbabilities is a user's moving average, exported for
+// data-subject requests
+type SmoothedProbabilities struct {
+	Probabilities BucketProbabilities `json:"probabilities"`
+	LastSeen      time.Time           `json:"last_seen"`
+}
+
+// smoothedUser is a user's moving average
+type smoothedUser struct {
+	probs    BucketProbabilities
+	lastSeen time.Time
+	// owner is the identity the user belongs to, for data-subject requests
+	owner DataSubject
+}
+
+// BucketSmoother keeps an exponentially weighted moving average of each
+// user's bucket probabilities
+type BucketSmoother struct {
+	config BucketSmoothingConfig
+	alpha  float64
+	now    func() time.Time
+
+	users map[string]*smoothedUser
+	stats BucketSmoothingStats
+	mu    sync.Mutex
+}
+
+// NewBucketSmoother creates a smoother, filling defaults
+func NewBucketSmoother(config BucketSmoothingConfig, now func() time.Time) (*BucketSmoother, error) {
+	if config.Window == 0 {
+		config.Window = 5
+	}
+	if config.IdleTTL == 0 {
+		config.IdleTTL = 30 * time.Minute
+	}
+	if config.Window < 1 {
+		return nil, fmt.Errorf("window must be at least 1, got %d", config.Window)
+	}
+	if config.IdleTTL < 0 {
+		return nil, fmt.Errorf("idle_ttl must not be negative")
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	return &BucketSmoother{
+		config: config,
+		alpha:  2 / float64(config.Window+1),
+		now:    now,
+		users:  make(map[string]*smoothedUser),
+	}, nil
+}
+
+// Smooth folds a request's probabilities into the user's average and
+// returns the average. A user's first request, or first after IdleTTL,
+// starts the average afresh.
+func (bs *BucketSmoother) Smooth(user string, owner DataSubject, probs BucketProbabilities) BucketProbabilities {
+	if user == "" {
+		return probs
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	now := bs.now()
+	state, ok := bs.users[user]
+	if !ok || now.Sub(state.lastSeen) >= bs.config.IdleTTL {
+		if !ok && len(bs.users) >= 10000 {
+			bs.evictIdle(now)
+		}
+		bs.users[user] = &smoothedUser{probs: probs, lastSeen: now, owner: owner}
+		return probs
+	}
+
+	mix := func(average, sample float64) float64 {
+		return average + bs.alpha*(sample-average)
+	}
+	state.probs = BucketProbabilities{
+		Cheap: mix(state.probs.Cheap, probs.Cheap),
+		Mid:   mix(state.probs.Mid, probs.Mid),
+		Hard:  mix(state.probs.Hard, probs.Hard),
+	}
+	state.lastSeen = now
+	bs.stats.Smoothed++
+	return state.probs
+}
+
+// GetStats returns the smoother's counters
+func (bs *BucketSmoother) GetStats() BucketSmoothingStats {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	stats := bs.stats
+	stats.Users = len(bs.users)
+	return stats
+}
+
+// SubjectHistory returns the live moving averages of an identity's users
+func (bs *BucketSmoother) SubjectHistory(subject DataSubject) []SmoothedProbabilities {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var history []SmoothedProbabilities
+	now := bs.now()
+	for _, state := range bs.users {
+		if subject.covers(state.owner) && now.Sub(state.lastSeen) < bs.config.IdleTTL {
+			history = append(history, SmoothedProbabilities{Probabilities: state.probs, LastSeen: state.lastSeen})
+		}
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].LastSeen.Before(history[j].LastSeen) })
+	return history
+}
+
+// DeleteSubject forgets the moving averages of an identity's users,
+// returning how many were forgotten
+func (bs *BucketSmoother) DeleteSubject(subject DataSubject) int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	deleted := 0
+	for user, state := range bs.users {
+		if subject.covers(state.owner) {
+			delete(bs.users, user)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// overridden counts a request whose bucket smoothing changed
+func (bs *BucketSmoother) overridden() {
+	bs.mu.Lock()
+	bs.stats.Overridden++
+	bs.mu.Unlock()
+}
+
+// evictIdle drops users idle past IdleTTL (no lock - called from locked context)
+func (bs *BucketSmoother) evictIdle(now time.Time) {
+	for user, state := range bs.users {
+		if now.Sub(state.lastSeen) >= bs.config.IdleTTL {
+			delete(bs.users, user)
+		}
+	}
+}
+
+// smoothingUser identifies the user whose requests are smoothed together,
+// or "" for anonymous requests
+func smoothingUser(authInfo *AuthInfo) string {
+	if authInfo == nil {
+		return ""
+	}
+	if authInfo.Subject != "" {
+		return "sub:" + authInfo.Subject
+	}
+	return authInfo.Token.Fingerprint()
+}
+
+// smoothTriage replaces a triage's probabilities with the user's moving
+// average and reselects its bucket from them, guardrails included
+func (p *Plugin) smoothTriage(triage *router.Triage, authInfo *AuthInfo) {
+	smoothed := p.smoother.Smooth(smoothingUser(authInfo), subjectOf(authInfo), *triage.Probabilities)
+	bucket := p.selectBucket(&smoothed, triage.Features)
+	if bucket != triage.Bucket {
+		p.smoother.overridden()
+	}
+	triage.Probabilities = &smoothed
+	triage.Bucket = bucket
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketSmoother(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	consistent := BucketProbabilities{Cheap: 0.9, Mid: 0.05, Hard: 0.05}
+	noisy := BucketProbabilities{Cheap: 0.2, Mid: 0.3, Hard: 0.5}
+	alice := DataSubject{Tenant: "acme", Subject: "alice"}
+	bob := DataSubject{Tenant: "acme", Subject: "bob"}
+
+	t.Run("should average a user's probabilities over the window", func(t *testing.T) {
+		bs, err := NewBucketSmoother(BucketSmoothingConfig{Window: 5}, clock)
+		require.NoError(t, err)
+
+		assert.Equal(t, consistent, bs.Smooth("alice", alice, consistent))
+		smoothed := bs.Smooth("alice", alice, noisy)
+		assert.InDelta(t, 0.9+(0.2-0.9)/3, smoothed.Cheap, 1e-9)
+		assert.InDelta(t, 0.05+(0.5-0.05)/3, smoothed.Hard, 1e-9)
+
+		assert.Equal(t, noisy, bs.Smooth("bob", bob, noisy), "users are smoothed apart")
+		assert.Equal(t, noisy, bs.Smooth("", DataSubject{}, noisy), "anonymous requests are not smoothed")
+		assert.Equal(t, BucketSmoothingStats{Users: 2, Smoothed: 1}, bs.GetStats())
+	})
+
+	t.Run("should start afresh after the idle TTL", func(t *testing.T) {
+		bs, err := NewBucketSmoother(BucketSmoothingConfig{IdleTTL: time.Minute}, clock)
+		require.NoError(t, err)
+
+		bs.Smooth("alice", alice, consistent)
+		now = now.Add(time.Minute)
+		assert.Equal(t, noisy, bs.Smooth("alice", alice, noisy))
+	})
+
+	t.Run("should export and forget an identity's history", func(t *testing.T) {
+		bs, err := NewBucketSmoother(BucketSmoothingConfig{}, clock)
+		require.NoError(t, err)
+		bs.Smooth("alice", alice, consistent)
+		bs.Smooth("bob", bob, noisy)
+
+		history := bs.SubjectHistory(alice)
+		require.Len(t, history, 1)
+		assert.Equal(t, consistent, history[0].Probabilities)
+		assert.Len(t, bs.SubjectHistory(DataSubject{Tenant: "acme"}), 2)
+
+		assert.Equal(t, 1, bs.DeleteSubject(alice))
+		assert.Empty(t, bs.SubjectHistory(alice))
+		assert.Equal(t, 1, bs.GetStats().Users)
+	})
+
+	t.Run("should reject invalid windows", func(t *testing.T) {
+		_, err := NewBucketSmoother(BucketSmoothingConfig{Window: -1}, clock)
+		assert.Error(t, err)
+	})
+}
+
+func TestBucketSmoothing(t *testing.T) {
+	config := createRouterTestConfig()
+	config.BucketSmoothing = BucketSmoothingConfig{Enabled: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	authInfo := &AuthInfo{Provider: "openai", Type: "bearer", Token: auth.NewSecret("sk-user")}
+
+	triage := func(probs BucketProbabilities) *router.Triage {
+		features := &RequestFeatures{TokenCount: 100}
+		return &router.Triage{
+			Features:      features,
+			Probabilities: &probs,
+			Bucket:        plugin.selectBucket(&probs, features),
+		}
+	}
+
+	t.Run("should keep a consistent user in their bucket through a noisy triage", func(t *testing.T) {
+		first := triage(BucketProbabilities{Cheap: 0.9, Mid: 0.05, Hard: 0.05})
+		plugin.smoothTriage(first, authInfo)
+		assert.Equal(t, BucketCheap, first.Bucket)
+
+		noisy := triage(BucketProbabilities{Cheap: 0.2, Mid: 0.3, Hard: 0.5})
+		require.Equal(t, BucketHard, noisy.Bucket)
+		plugin.smoothTriage(noisy, authInfo)
+		assert.Equal(t, BucketCheap, noisy.Bucket)
+		assert.Less(t, noisy.Probabilities.Hard, 0.3)
+
+		stats := plugin.GetMetrics()["bucket_smoothing"].(BucketSmoothingStats)
+		assert.Equal(t, int64(1), stats.Overridden)
+	})
+
+	t.Run("should be disabled by default", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		assert.Nil(t, plugin.smoother)
+		assert.NotContains(t, plugin.GetMetrics(), "bucket_smoothing")
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.BucketSmoothing = BucketSmoothingConfig{Enabled: true, Window: -2}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid bucket smoothing config")
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+)
+
+// BYOK policy modes
+const (
+	// BYOKModeOff keeps legacy behavior: client keys do not constrain routing
+	BYOKModeOff = ""
+	// BYOKModeRestrict routes only to the provider the client brought a key for
+	BYOKModeRestrict = "restrict"
+	// BYOKModeMix behaves like restrict unless the request explicitly opts in
+	// to mixing with house-key providers
+	BYOKModeMix = "mix"
+)
+
+// BYOKPolicyConfig controls routing when a client brings its own provider key
+type BYOKPolicyConfig struct {
+	Mode string `json:"mode"`
+
+	// AllowHouseFallbacks permits fallbacks to other providers (billed to
+	// house keys) even when candidates are restricted to the client's provider
+	AllowHouseFallbacks bool `json:"allow_house_fallbacks"`
+
+	// MixOptInHeader is the request header that opts in to mixing in "mix" mode
+	MixOptInHeader string `json:"mix_opt_in_header"`
+}
+
+// byokScope constrains model selection to a client-keyed provider
+type byokScope struct {
+	provider          string
+	restrictFallbacks bool
+}
+
+// mixOptInHeader returns the configured opt-in header name
+func (c BYOKPolicyConfig) mixOptInHeader() string {
+	if c.MixOptInHeader == "" {
+		return "X-Heimdall-Allow-House-Keys"
+	}
+	return c.MixOptInHeader
+}
+
+// applyBYOKOptIn records on the auth info whether the request opted in to
+// mixing client and house keys
+func (p *Plugin) applyBYOKOptIn(authInfo *AuthInfo, headers map[string][]string) {
+	if authInfo == nil || p.config.BYOK.Mode != BYOKModeMix {
+		return
+	}
+	optIn := auth.HeaderValue(headers, p.config.BYOK.mixOptInHeader())
+	authInfo.AllowHouseKeys = strings.EqualFold(optIn, "true")
+}
+
+// byokScopeFor returns the selection scope for the request, or nil when the
+// BYOK policy does not constrain it
+func (p *Plugin) byokScopeFor(authInfo *AuthInfo) *byokScope {
+	if authInfo == nil || authInfo.Provider == "" {
+		return nil
+	}
+
+	switch p.config.BYOK.Mode {
+	case BYOKModeRestrict:
+	case BYOKModeMix:
+		if authInfo.AllowHouseKeys {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	return &byokScope{
+		provider:          authInfo.Provider,
+		restrictFallbacks: !p.config.BYOK.AllowHouseFallbacks,
+	}
+}
+
+// filter keeps only candidates served by the scoped provider
+func (s *byokScope) filter(p *Plugin, candidates []string) []string {
+	var filtered []string
+	for _, c := range candidates {
+		if p.inferProviderKind(c) == s.provider {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// byokBucketSearchOrder lists the buckets searched for a client-keyed model
+// when the requested bucket has none, nearest bucket first
+var byokBucketSearchOrder = map[string][]string{
+	"cheap": {"cheap", "mid", "hard"},
+	"mid":   {"mid", "hard", "cheap"},
+	"hard":  {"hard", "mid", "cheap"},
+}
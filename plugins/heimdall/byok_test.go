@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBYOKPolicy(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 0, TokenCount: 500}
+	openaiKey := func() *AuthInfo {
+		return &AuthInfo{Provider: "openai", Type: "bearer", Token: auth.NewSecret("sk-client")}
+	}
+
+	newPlugin := func(t *testing.T, policy BYOKPolicyConfig) *Plugin {
+		config := createRouterTestConfig()
+		config.BYOK = policy
+		return createRouterTestPluginWithConfig(t, config)
+	}
+
+	t.Run("should not constrain routing when policy is off", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{})
+
+		assert.Nil(t, plugin.byokScopeFor(openaiKey()))
+
+		decision, err := plugin.selectModel(BucketCheap, features, openaiKey(), false)
+		require.NoError(t, err)
+		assert.Equal(t, "env", decision.Auth.Mode)
+	})
+
+	t.Run("should restrict candidates and fallbacks to the client's provider", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeRestrict})
+
+		decision, err := plugin.selectModel(BucketHard, features, openaiKey(), false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/o1", decision.Model)
+		assert.Equal(t, "passthrough", decision.Auth.Mode)
+		assert.Empty(t, decision.Fallbacks)
+	})
+
+	t.Run("should search neighbouring buckets when bucket has no client-keyed model", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeRestrict})
+
+		// The cheap bucket has no OpenAI models; mid is searched next
+		decision, err := plugin.selectModel(BucketCheap, features, openaiKey(), false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/gpt-4o", decision.Model)
+	})
+
+	t.Run("should search neighbouring buckets when the bucket has no available model", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeRestrict})
+		for _, model := range plugin.config.Router.CheapCandidates {
+			require.NoError(t, plugin.drains.Drain(model, "maintenance"))
+		}
+
+		decision, err := plugin.selectModel(BucketCheap, features, openaiKey(), false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/gpt-4o", decision.Model)
+	})
+
+	t.Run("should allow house-key fallbacks when configured", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeRestrict, AllowHouseFallbacks: true})
+
+		decision, err := plugin.selectModel(BucketHard, features, openaiKey(), false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/o1", decision.Model)
+		assert.Contains(t, decision.Fallbacks, "anthropic/claude-3-opus")
+		assert.NotContains(t, decision.Fallbacks, "openai/o1")
+	})
+
+	t.Run("should only mix providers with explicit opt-in", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeMix})
+
+		authInfo := openaiKey()
+		plugin.applyBYOKOptIn(authInfo, map[string][]string{})
+		assert.NotNil(t, plugin.byokScopeFor(authInfo))
+
+		authInfo = openaiKey()
+		plugin.applyBYOKOptIn(authInfo, map[string][]string{"X-Heimdall-Allow-House-Keys": {"true"}})
+		assert.True(t, authInfo.AllowHouseKeys)
+		assert.Nil(t, plugin.byokScopeFor(authInfo))
+	})
+
+	t.Run("should error when the client's provider has no candidates", func(t *testing.T) {
+		plugin := newPlugin(t, BYOKPolicyConfig{Mode: BYOKModeRestrict})
+
+		authInfo := &AuthInfo{Provider: "mistral", Type: "bearer", Token: auth.NewSecret("client")}
+		_, err := plugin.selectModel(BucketMid, features, authInfo, false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// envelopeVersion prefixes every sealed entry so the format can evolve
+const envelopeVersion byte = 1
+
+// EncryptionKeyConfig is one AES key, base64-encoded (16, 24 or 32 bytes)
+type EncryptionKeyConfig struct {
+	ID string `json:"id"`
+	// Key holds the base64 key; KeyEnv names an environment variable holding it instead
+	Key    string `json:"key,omitempty"`
+	KeyEnv string `json:"key_env,omitempty"`
+}
+
+// EncryptionConfig configures AES-GCM encryption of cached responses and
+// of decisions cached in a store supplied with WithCache.
+// New entries are sealed with ActiveKeyID (default: the first key); every
+// listed key can still open entries, so keys can be rotated without a flush.
+type EncryptionConfig struct {
+	Enabled     bool                  `json:"enabled"`
+	ActiveKeyID string                `json:"active_key_id"`
+	Keys        []EncryptionKeyConfig `json:"keys"`
+}
+
+// EntryCipher seals and opens cache entries with AES-GCM
+type EntryCipher struct {
+	activeKeyID string
+	aeads       map[string]cipher.AEAD
+}
+
+// NewEntryCipher creates a cipher from the configured keys
+func NewEntryCipher(config EncryptionConfig) (*EntryCipher, error) {
+	if len(config.Keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	ec := &EntryCipher{
+		activeKeyID: config.ActiveKeyID,
+		aeads:       make(map[string]cipher.AEAD, len(config.Keys)),
+	}
+	if ec.activeKeyID == "" {
+		ec.activeKeyID = config.Keys[0].ID
+	}
+
+	for _, keyConfig := range config.Keys {
+		if keyConfig.ID == "" || len(keyConfig.ID) > 255 {
+			return nil, fmt.Errorf("key id must be 1-255 bytes")
+		}
+		encoded := keyConfig.Key
+		if keyConfig.KeyEnv != "" {
+			encoded = os.Getenv(keyConfig.KeyEnv)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s is not valid base64: %w", keyConfig.ID, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyConfig.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyConfig.ID, err)
+		}
+		ec.aeads[keyConfig.ID] = aead
+	}
+
+	if _, ok := ec.aeads[ec.activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", ec.activeKeyID)
+	}
+	return ec, nil
+}
+
+// Seal encrypts plaintext with the active key. The additional data (e.g. the
+// cache key) is authenticated but not stored, binding the entry to its slot.
+// Layout: version | len(keyID) | keyID | nonce | ciphertext
+func (ec *EntryCipher) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	aead := ec.aeads[ec.activeKeyID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := make([]byte, 0, 2+len(ec.activeKeyID)+len(nonce)+len(plaintext)+aead.Overhead())
+	sealed = append(sealed, envelopeVersion, byte(len(ec.activeKeyID)))
+	sealed = append(sealed, ec.activeKeyID...)
+	sealed = append(sealed, nonce...)
+	return aead.Seal(sealed, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts an entry sealed with any configured key
+func (ec *EntryCipher) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < 2 || sealed[0] != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope")
+	}
+	idLen := int(sealed[1])
+	if len(sealed) < 2+idLen {
+		return nil, fmt.Errorf("truncated envelope")
+	}
+	keyID := string(sealed[2 : 2+idLen])
+
+	aead, ok := ec.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", keyID)
+	}
+
+	rest := sealed[2+idLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("truncated envelope")
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt entry with key %q: %w", keyID, err)
+	}
+	return plaintext, nil
+}
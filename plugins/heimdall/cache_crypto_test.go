@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(fill byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, 32))
+}
+
+func TestEntryCipher(t *testing.T) {
+	plaintext := []byte(`{"decision":{"model":"openai/gpt-4o"}}`)
+	aad := []byte("cache-key")
+
+	t.Run("should round-trip entries bound to their additional data", func(t *testing.T) {
+		ec, err := NewEntryCipher(EncryptionConfig{Keys: []EncryptionKeyConfig{{ID: "k1", Key: testKey(1)}}})
+		require.NoError(t, err)
+
+		sealed, err := ec.Seal(plaintext, aad)
+		require.NoError(t, err)
+		assert.NotContains(t, string(sealed), "gpt-4o")
+
+		opened, err := ec.Open(sealed, aad)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+
+		_, err = ec.Open(sealed, []byte("other-key"))
+		assert.Error(t, err)
+
+		sealed[len(sealed)-1] ^= 0xff
+		_, err = ec.Open(sealed, aad)
+		assert.Error(t, err)
+	})
+
+	t.Run("should open entries sealed with retired keys after rotation", func(t *testing.T) {
+		before, err := NewEntryCipher(EncryptionConfig{Keys: []EncryptionKeyConfig{{ID: "k1", Key: testKey(1)}}})
+		require.NoError(t, err)
+		sealed, err := before.Seal(plaintext, aad)
+		require.NoError(t, err)
+
+		after, err := NewEntryCipher(EncryptionConfig{
+			ActiveKeyID: "k2",
+			Keys:        []EncryptionKeyConfig{{ID: "k2", Key: testKey(2)}, {ID: "k1", Key: testKey(1)}},
+		})
+		require.NoError(t, err)
+
+		opened, err := after.Open(sealed, aad)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+
+		resealed, err := after.Seal(plaintext, aad)
+		require.NoError(t, err)
+		_, err = before.Open(resealed, aad)
+		assert.Error(t, err, "entries sealed with the new key need the new key")
+	})
+
+	t.Run("should reject invalid configuration", func(t *testing.T) {
+		_, err := NewEntryCipher(EncryptionConfig{})
+		assert.Error(t, err)
+
+		_, err = NewEntryCipher(EncryptionConfig{Keys: []EncryptionKeyConfig{{ID: "k1", Key: "short"}}})
+		assert.Error(t, err)
+
+		_, err = NewEntryCipher(EncryptionConfig{
+			ActiveKeyID: "missing",
+			Keys:        []EncryptionKeyConfig{{ID: "k1", Key: testKey(1)}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestEncryptedDecisionCache(t *testing.T) {
+	config := createRouterTestConfig()
+	config.CacheEncryption = EncryptionConfig{
+		Enabled: true,
+		Keys:    []EncryptionKeyConfig{{ID: "k1", Key: testKey(1)}},
+	}
+	// Only caches supplied with WithCache hold encoded entries
+	cache := &countingCache{memoryDecisionCache: newMemoryDecisionCache()}
+	plugin, err := NewWithOptions(config, WithCache(cache))
+	require.NoError(t, err)
+
+	req := &RouterRequest{Method: "POST", Body: &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "my secret prompt"}}}}
+	response := &RouterResponse{
+		Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+		AuthInfo: &AuthInfo{Provider: "openai", Type: "bearer", Token: auth.NewSecret("sk-client-secret")},
+	}
+
+	plugin.cacheResponse(req, response)
+
+	t.Run("should keep entries and keys free of plaintext", func(t *testing.T) {
+		require.Equal(t, 1, cache.sets)
+		for key, entry := range cache.entries {
+			assert.Nil(t, entry.Response)
+			assert.NotContains(t, key, "secret prompt")
+			assert.NotContains(t, string(entry.Data), "gpt-4o")
+			assert.NotContains(t, string(entry.Data), "sk-client-secret")
+		}
+	})
+
+	t.Run("should return decisions with a token fingerprint only", func(t *testing.T) {
+		cached := plugin.getCachedResponse(req)
+		require.NotNil(t, cached)
+		assert.Equal(t, "openai/gpt-4o", cached.Decision.Model)
+		assert.Empty(t, cached.AuthInfo.Token.Reveal())
+		assert.Equal(t, auth.TokenFingerprint("sk-client-secret"), cached.AuthInfo.Token.Fingerprint())
+		assert.Equal(t, "sk-client-secret", response.AuthInfo.Token.Reveal(), "caching should not mutate the live response")
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// calibrationBins is the number of predicted-probability bins per cluster
+const calibrationBins = 10
+
+// calibrationTolerance is the predicted-minus-observed gap beyond which a
+// cluster is reported as over- or under-escalating
+const calibrationTolerance = 0.1
+
+// Calibration verdicts
+const (
+	CalibrationOK              = "calibrated"
+	CalibrationOverEscalating  = "over_escalating"
+	CalibrationUnderEscalating = "under_escalating"
+	CalibrationInsufficient    = "insufficient_data"
+)
+
+// CalibrationOutcome is feedback on whether a request needed the hard bucket
+type CalibrationOutcome struct {
+	ClusterID       int     `json:"cluster_id"`
+	HardProbability float64 `json:"hard_probability"`
+	NeededHard      bool    `json:"needed_hard"`
+}
+
+// CalibrationBin is one cell of the reliability heatmap
+type CalibrationBin struct {
+	Lower         float64 `json:"lower"`
+	Upper         float64 `json:"upper"`
+	Samples       int     `json:"samples"`
+	MeanPredicted float64 `json:"mean_predicted"`
+	ObservedRate  float64 `json:"observed_rate"`
+}
+
+// ClusterCalibration reports calibration of the hard probability on one cluster
+type ClusterCalibration struct {
+	ClusterID     int              `json:"cluster_id"`
+	Samples       int              `json:"samples"`
+	MeanPredicted float64          `json:"mean_predicted"`
+	ObservedRate  float64          `json:"observed_rate"`
+	ECE           float64          `json:"ece"` // expected calibration error
+	Verdict       string           `json:"verdict"`
+	Bins          []CalibrationBin `json:"bins"`
+}
+
+// CalibrationReport is the per-cluster calibration heatmap
+type CalibrationReport struct {
+	Clusters []ClusterCalibration `json:"clusters"`
+	Overall  ClusterCalibration   `json:"overall"`
+}
+
+type calibrationCell struct {
+	samples   int
+	predicted float64
+	observed  float64
+}
+
+// CalibrationTracker accumulates predicted hard probability vs. observed
+// need-for-hard outcomes per cluster
+type CalibrationTracker struct {
+	// minSamples is the sample count below which no verdict is given
+	minSamples int
+
+	clusters map[int]*[calibrationBins]calibrationCell
+	mu       sync.Mutex
+}
+
+// NewCalibrationTracker creates an empty tracker
+func NewCalibrationTracker(minSamples int) *CalibrationTracker {
+	if minSamples <= 0 {
+		minSamples = 30
+	}
+	return &CalibrationTracker{
+		minSamples: minSamples,
+		clusters:   make(map[int]*[calibrationBins]calibrationCell),
+	}
+}
+
+// Record adds one outcome
+func (ct *CalibrationTracker) Record(outcome CalibrationOutcome) {
+	p := math.Max(0, math.Min(1, outcome.HardProbability))
+	bin := int(p * calibrationBins)
+	if bin == calibrationBins {
+		bin--
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	cells, ok := ct.clusters[outcome.ClusterID]
+	if !ok {
+		cells = &[calibrationBins]calibrationCell{}
+		ct.clusters[outcome.ClusterID] = cells
+	}
+	cells[bin].samples++
+	cells[bin].predicted += p
+	if outcome.NeededHard {
+		cells[bin].observed++
+	}
+}
+
+// Report builds the calibration report, clusters in ascending order
+func (ct *CalibrationTracker) Report() CalibrationReport {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	var overall [calibrationBins]calibrationCell
+	report := CalibrationReport{Clusters: make([]ClusterCalibration, 0, len(ct.clusters))}
+	for clusterID, cells := range ct.clusters {
+		report.Clusters = append(report.Clusters, ct.summarize(clusterID, cells))
+		for i, cell := range cells {
+			overall[i].samples += cell.samples
+			overall[i].predicted += cell.predicted
+			overall[i].observed += cell.observed
+		}
+	}
+	sort.Slice(report.Clusters, func(i, j int) bool {
+		return report.Clusters[i].ClusterID < report.Clusters[j].ClusterID
+	})
+	report.Overall = ct.summarize(-1, &overall)
+	return report
+}
+
+// summarize computes bins, ECE and a verdict for one set of cells
+func (ct *CalibrationTracker) summarize(clusterID int, cells *[calibrationBins]calibrationCell) ClusterCalibration {
+	result := ClusterCalibration{ClusterID: clusterID, Bins: make([]CalibrationBin, calibrationBins)}
+
+	var predicted, observed float64
+	for i, cell := range cells {
+		bin := CalibrationBin{
+			Lower:   float64(i) / calibrationBins,
+			Upper:   float64(i+1) / calibrationBins,
+			Samples: cell.samples,
+		}
+		if cell.samples > 0 {
+			bin.MeanPredicted = cell.predicted / float64(cell.samples)
+			bin.ObservedRate = cell.observed / float64(cell.samples)
+		}
+		result.Bins[i] = bin
+		result.Samples += cell.samples
+		predicted += cell.predicted
+		observed += cell.observed
+	}
+
+	if result.Samples == 0 {
+		result.Verdict = CalibrationInsufficient
+		return result
+	}
+
+	n := float64(result.Samples)
+	result.MeanPredicted = predicted / n
+	result.ObservedRate = observed / n
+	for _, bin := range result.Bins {
+		result.ECE += float64(bin.Samples) / n * math.Abs(bin.MeanPredicted-bin.ObservedRate)
+	}
+
+	switch gap := result.MeanPredicted - result.ObservedRate; {
+	case result.Samples < ct.minSamples:
+		result.Verdict = CalibrationInsufficient
+	case gap > calibrationTolerance:
+		result.Verdict = CalibrationOverEscalating
+	case gap < -calibrationTolerance:
+		result.Verdict = CalibrationUnderEscalating
+	default:
+		result.Verdict = CalibrationOK
+	}
+	return result
+}
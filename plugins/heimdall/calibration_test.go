@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalibrationTracker(t *testing.T) {
+	t.Run("should bin outcomes into a reliability heatmap", func(t *testing.T) {
+		tracker := NewCalibrationTracker(1)
+		tracker.Record(CalibrationOutcome{ClusterID: 1, HardProbability: 0.25, NeededHard: true})
+		tracker.Record(CalibrationOutcome{ClusterID: 1, HardProbability: 0.25})
+		tracker.Record(CalibrationOutcome{ClusterID: 1, HardProbability: 1.0, NeededHard: true})
+
+		report := tracker.Report()
+		require.Len(t, report.Clusters, 1)
+		cluster := report.Clusters[0]
+		require.Len(t, cluster.Bins, calibrationBins)
+
+		assert.Equal(t, 2, cluster.Bins[2].Samples)
+		assert.InDelta(t, 0.25, cluster.Bins[2].MeanPredicted, 1e-9)
+		assert.InDelta(t, 0.5, cluster.Bins[2].ObservedRate, 1e-9)
+		assert.Equal(t, 1, cluster.Bins[9].Samples, "p=1 falls in the last bin")
+
+		// ECE = 2/3*|0.25-0.5| + 1/3*|1-1|
+		assert.InDelta(t, 2.0/3*0.25, cluster.ECE, 1e-9)
+	})
+
+	t.Run("should flag systematic over and under escalation", func(t *testing.T) {
+		tracker := NewCalibrationTracker(10)
+		for i := 0; i < 10; i++ {
+			tracker.Record(CalibrationOutcome{ClusterID: 1, HardProbability: 0.8})
+			tracker.Record(CalibrationOutcome{ClusterID: 2, HardProbability: 0.1, NeededHard: true})
+			tracker.Record(CalibrationOutcome{ClusterID: 3, HardProbability: 0.5, NeededHard: i%2 == 0})
+		}
+		tracker.Record(CalibrationOutcome{ClusterID: 4, HardProbability: 0.9})
+
+		report := tracker.Report()
+		require.Len(t, report.Clusters, 4)
+		assert.Equal(t, CalibrationOverEscalating, report.Clusters[0].Verdict)
+		assert.Equal(t, CalibrationUnderEscalating, report.Clusters[1].Verdict)
+		assert.Equal(t, CalibrationOK, report.Clusters[2].Verdict)
+		assert.Equal(t, CalibrationInsufficient, report.Clusters[3].Verdict)
+		assert.Equal(t, 31, report.Overall.Samples)
+	})
+}
+
+func TestCascadeCalibrationFeedback(t *testing.T) {
+	plugin := createCascadeTestPlugin(t, CascadeConfig{Enabled: true})
+
+	run := func(bucket Bucket, answer string) {
+		ctx := context.Background()
+		response := &RouterResponse{
+			Features:            RequestFeatures{ClusterID: 5, TokenCount: 200},
+			BucketProbabilities: BucketProbabilities{Hard: 0.7},
+			Decision: *plugin.cascadeDecision(
+				&RouterDecision{Kind: "openrouter", Model: "deepseek/deepseek-r1"},
+				&RouterDecision{Kind: "openai", Model: "openai/o1"},
+				bucket,
+			),
+		}
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+		require.NoError(t, err)
+		_, _, err = plugin.PostHook(&ctx, textResponse(answer, "stop"), nil)
+		require.NoError(t, err)
+	}
+
+	good := "Here is a thorough explanation covering every step of the derivation in order."
+	run(BucketHard, good)
+	run(BucketHard, "")
+	run(BucketMid, "")   // escalating to mid says nothing about hard
+	run(BucketMid, good) // nor does an answer accepted instead of mid
+
+	report := plugin.calibration.Report()
+	require.Len(t, report.Clusters, 1)
+	assert.Equal(t, 5, report.Clusters[0].ClusterID)
+	assert.Equal(t, 2, report.Clusters[0].Samples)
+	assert.InDelta(t, 0.5, report.Clusters[0].ObservedRate, 1e-9)
+}
+
+func TestAdminCalibrationEndpoints(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	server := httptest.NewServer(plugin.AdminHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/admin/calibration/outcomes", "application/json",
+		strings.NewReader(`[{"cluster_id": 2, "hard_probability": 0.9, "needed_hard": false}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/admin/calibration/outcomes", "application/json",
+		strings.NewReader(`[{"cluster_id": 2, "hard_probability": 1.5}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/admin/calibration")
+	require.NoError(t, err)
+	var report CalibrationReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	require.Len(t, report.Clusters, 1)
+	assert.Equal(t, 1, report.Clusters[0].Samples)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// Task types used for cascade eligibility
+const (
+	TaskTypeCode    = "code"
+	TaskTypeMath    = "math"
+	TaskTypeGeneral = "general"
+)
+
+// CascadeConfig configures speculative cheap-first routing: eligible mid/hard
+// requests are sent to a cheap model first and escalate via Bifrost fallbacks
+// when the ResponseScorer rates the answer below MinQuality
+type CascadeConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Clusters and TaskTypes restrict eligibility; empty means all
+	Clusters  []int    `json:"clusters"`
+	TaskTypes []string `json:"task_types"`
+
+	// MinQuality is the score a cheap answer needs to be accepted
+	MinQuality float64 `json:"min_quality"`
+}
+
+// CascadeInfo marks a decision as a speculative cheap-first attempt
+type CascadeInfo struct {
+	EscalationBucket Bucket  `json:"escalation_bucket"`
+	EscalationModel  string  `json:"escalation_model"`
+	MinQuality       float64 `json:"min_quality"`
+}
+
+// CascadeStats counts cascade outcomes
+type CascadeStats struct {
+	Attempts  int64   `json:"attempts"`
+	Accepted  int64   `json:"accepted"`
+	Escalated int64   `json:"escalated"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// cascadeTracker records cascade outcomes overall and per task type
+type cascadeTracker struct {
+	overall CascadeStats
+	byTask  map[string]*CascadeStats
+	mu      sync.Mutex
+}
+
+func newCascadeTracker() *cascadeTracker {
+	return &cascadeTracker{byTask: make(map[string]*CascadeStats)}
+}
+
+// record counts one evaluated cascade attempt
+func (ct *cascadeTracker) record(taskType string, accepted bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	stats, ok := ct.byTask[taskType]
+	if !ok {
+		stats = &CascadeStats{}
+		ct.byTask[taskType] = stats
+	}
+	for _, s := range []*CascadeStats{&ct.overall, stats} {
+		s.Attempts++
+		if accepted {
+			s.Accepted++
+		} else {
+			s.Escalated++
+		}
+		s.HitRate = float64(s.Accepted) / float64(s.Attempts)
+	}
+}
+
+// snapshot returns overall and per-task stats
+func (ct *cascadeTracker) snapshot() map[string]CascadeStats {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	result := map[string]CascadeStats{"overall": ct.overall}
+	for taskType, stats := range ct.byTask {
+		result[taskType] = *stats
+	}
+	return result
+}
+
+// taskType classifies a request for cascade eligibility
+func taskType(features *RequestFeatures) string {
+	switch {
+	case features.HasCode:
+		return TaskTypeCode
+	case features.HasMath:
+		return TaskTypeMath
+	default:
+		return TaskTypeGeneral
+	}
+}
+
+// cascadeEligible reports whether a request should try a cheap model first
+func (p *Plugin) cascadeEligible(bucket Bucket, features *RequestFeatures) bool {
+	config := p.config.Cascade
+	if !config.Enabled || bucket == BucketCheap {
+		return false
+	}
+	if len(config.Clusters) > 0 {
+		eligible := false
+		for _, cluster := range config.Clusters {
+			if cluster == features.ClusterID {
+				eligible = true
+				break
+			}
+		}
+		if !eligible {
+			return false
+		}
+	}
+	if len(config.TaskTypes) > 0 && !contains(config.TaskTypes, taskType(features)) {
+		return false
+	}
+	return true
+}
+
+// cascadeDecision puts a cheap model in front of the escalation decision,
+// whose model and fallbacks become the cheap attempt's fallbacks
+func (p *Plugin) cascadeDecision(cheap, escalation *RouterDecision, bucket Bucket) *RouterDecision {
+	decision := *cheap
+
+	seen := map[string]bool{cheap.Model: true}
+	decision.Fallbacks = nil
+	for _, model := range append([]string{escalation.Model}, escalation.Fallbacks...) {
+		if !seen[model] {
+			seen[model] = true
+			decision.Fallbacks = append(decision.Fallbacks, model)
+		}
+	}
+
+	// The cheap provider fails fast; escalation providers keep their budgets
+	decision.ProviderHints = make(map[string]ProviderHint)
+	for provider, hint := range escalation.ProviderHints {
+		decision.ProviderHints[provider] = hint
+	}
+	if hint, ok := cheap.ProviderHints[cheap.Kind]; ok {
+		decision.ProviderHints[cheap.Kind] = hint
+	}
+
+	minQuality := p.config.Cascade.MinQuality
+	if minQuality == 0 {
+		minQuality = 0.6
+	}
+	decision.Escalation = escalation.Escalation
+	decision.Cascade = &CascadeInfo{
+		EscalationBucket: bucket,
+		EscalationModel:  escalation.Model,
+		MinQuality:       minQuality,
+	}
+	return &decision
+}
+
+// evaluateCascade scores a cheap-first response, converting a low-quality
+// answer into an error so Bifrost escalates to the decision's fallbacks
+func (p *Plugin) evaluateCascade(decision RouterDecision, features *RequestFeatures, res *schemas.BifrostResponse) (*schemas.BifrostResponse, *schemas.BifrostError) {
+	// Streamed chunks cannot be judged individually
+	if !scorable(res) {
+		return res, nil
+	}
+
+	score := p.responseScorer.Score(features, res)
+	accepted := score >= decision.Cascade.MinQuality
+	p.cascade.record(taskType(features), accepted)
+
+	if accepted {
+		return res, nil
+	}
+
+	errorType := "cascade_escalation"
+	return nil, &schemas.BifrostError{
+		Error: schemas.ErrorField{
+			Type: &errorType,
+			Message: fmt.Sprintf("cascade response from %s scored %.2f (< %.2f), escalating to %s",
+				decision.Model, score, decision.Cascade.MinQuality, decision.Cascade.EscalationModel),
+		},
+	}
+}
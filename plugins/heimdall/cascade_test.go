@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createCascadeTestPlugin(t *testing.T, cascade CascadeConfig) *Plugin {
+	config := createRouterTestConfig()
+	config.Cascade = cascade
+	return createRouterTestPluginWithConfig(t, config)
+}
+
+func TestCascadeEligibility(t *testing.T) {
+	plugin := createCascadeTestPlugin(t, CascadeConfig{
+		Enabled:   true,
+		Clusters:  []int{1, 2},
+		TaskTypes: []string{TaskTypeGeneral, TaskTypeMath},
+	})
+
+	assert.True(t, plugin.cascadeEligible(BucketMid, &RequestFeatures{ClusterID: 1}))
+	assert.True(t, plugin.cascadeEligible(BucketHard, &RequestFeatures{ClusterID: 2, HasMath: true}))
+	assert.False(t, plugin.cascadeEligible(BucketCheap, &RequestFeatures{ClusterID: 1}), "cheap requests have nothing to escalate")
+	assert.False(t, plugin.cascadeEligible(BucketMid, &RequestFeatures{ClusterID: 3}), "cluster not listed")
+	assert.False(t, plugin.cascadeEligible(BucketMid, &RequestFeatures{ClusterID: 1, HasCode: true}), "task type not listed")
+
+	disabled := createCascadeTestPlugin(t, CascadeConfig{})
+	assert.False(t, disabled.cascadeEligible(BucketHard, &RequestFeatures{}))
+}
+
+func TestCascadeDecision(t *testing.T) {
+	plugin := createCascadeTestPlugin(t, CascadeConfig{Enabled: true})
+
+	cheap := &RouterDecision{
+		Kind:          "openrouter",
+		Model:         "deepseek/deepseek-r1",
+		Fallbacks:     []string{"qwen/qwen-2.5-coder-32b-instruct"},
+		ProviderHints: map[string]ProviderHint{"openrouter": {TimeoutMs: 5000}},
+	}
+	escalation := &RouterDecision{
+		Kind:      "openai",
+		Model:     "openai/o1",
+		Fallbacks: []string{"deepseek/deepseek-r1", "anthropic/claude-3-opus"},
+		ProviderHints: map[string]ProviderHint{
+			"openai":     {TimeoutMs: 60000},
+			"openrouter": {TimeoutMs: 60000},
+		},
+	}
+
+	decision := plugin.cascadeDecision(cheap, escalation, BucketHard)
+
+	assert.Equal(t, "deepseek/deepseek-r1", decision.Model)
+	assert.Equal(t, []string{"openai/o1", "anthropic/claude-3-opus"}, decision.Fallbacks)
+	assert.Equal(t, int64(5000), decision.ProviderHints["openrouter"].TimeoutMs)
+	assert.Equal(t, int64(60000), decision.ProviderHints["openai"].TimeoutMs)
+	require.NotNil(t, decision.Cascade)
+	assert.Equal(t, BucketHard, decision.Cascade.EscalationBucket)
+	assert.Equal(t, "openai/o1", decision.Cascade.EscalationModel)
+	assert.Equal(t, 0.6, decision.Cascade.MinQuality)
+
+	assert.Equal(t, []string{"qwen/qwen-2.5-coder-32b-instruct"}, cheap.Fallbacks, "inputs are not modified")
+}
+
+func TestCascadePostHook(t *testing.T) {
+	plugin := createCascadeTestPlugin(t, CascadeConfig{Enabled: true, MinQuality: 0.6})
+
+	startCascade := func() context.Context {
+		ctx := context.Background()
+		response := &RouterResponse{
+			Features: RequestFeatures{TokenCount: 200},
+			Decision: *plugin.cascadeDecision(
+				&RouterDecision{Kind: "openrouter", Model: "deepseek/deepseek-r1"},
+				&RouterDecision{Kind: "openai", Model: "openai/o1"},
+				BucketHard,
+			),
+		}
+		req := &schemas.BifrostRequest{}
+		_, _, err := plugin.applyRoutingDecision(&ctx, req, response)
+		require.NoError(t, err)
+		require.Len(t, req.Fallbacks, 1)
+		return ctx
+	}
+
+	t.Run("should escalate weak answers through fallbacks", func(t *testing.T) {
+		ctx := startCascade()
+		res, bifrostErr, err := plugin.PostHook(&ctx, textResponse("I'm sorry, I cannot answer that.", "stop"), nil)
+		require.NoError(t, err)
+		assert.Nil(t, res)
+		require.NotNil(t, bifrostErr)
+		assert.Equal(t, "cascade_escalation", *bifrostErr.Error.Type)
+		assert.Nil(t, bifrostErr.AllowFallbacks, "escalation relies on Bifrost fallbacks")
+	})
+
+	t.Run("should pass good answers through", func(t *testing.T) {
+		ctx := startCascade()
+		answer := textResponse("Here is a thorough explanation covering every step of the derivation in order.", "stop")
+		res, bifrostErr, err := plugin.PostHook(&ctx, answer, nil)
+		require.NoError(t, err)
+		assert.Nil(t, bifrostErr)
+		assert.Same(t, answer, res)
+	})
+
+	t.Run("should not judge streamed chunks", func(t *testing.T) {
+		ctx := startCascade()
+		chunk := &schemas.BifrostResponse{Choices: []schemas.BifrostResponseChoice{{
+			BifrostStreamResponseChoice: &schemas.BifrostStreamResponseChoice{},
+		}}}
+		res, bifrostErr, err := plugin.PostHook(&ctx, chunk, nil)
+		require.NoError(t, err)
+		assert.Nil(t, bifrostErr)
+		assert.Same(t, chunk, res)
+	})
+
+	stats := plugin.GetMetrics()["cascade"].(map[string]CascadeStats)
+	assert.Equal(t, int64(2), stats["overall"].Attempts)
+	assert.Equal(t, int64(1), stats["overall"].Escalated)
+	assert.Equal(t, 0.5, stats[TaskTypeGeneral].HitRate)
+}
+
+func TestFallbackAttemptPassthrough(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+
+	content := "What is the capital of France?"
+	req := &schemas.BifrostRequest{Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+		Role:    schemas.ModelChatMessageRoleUser,
+		Content: schemas.MessageContent{ContentStr: &content},
+	}}}}
+	ctx := context.Background()
+	routed, shortCircuit, err := plugin.PreHook(&ctx, req)
+	require.NoError(t, err)
+	require.Nil(t, shortCircuit)
+	require.NotEmpty(t, routed.Fallbacks)
+
+	t.Run("should pass Bifrost's attempts at issued fallbacks through", func(t *testing.T) {
+		// Bifrost copies the routed request for each fallback
+		attempt := *routed
+		attempt.Provider, attempt.Model = routed.Fallbacks[0].Provider, routed.Fallbacks[0].Model
+		ctx := context.Background()
+		result, shortCircuit, err := plugin.PreHook(&ctx, &attempt)
+		require.NoError(t, err)
+		assert.Nil(t, shortCircuit)
+		assert.Same(t, &attempt, result)
+		assert.Equal(t, true, ctx.Value("heimdall_fallback_attempt"))
+		assert.Nil(t, ctx.Value("heimdall_inflight"), "fallback attempts are not re-routed")
+	})
+
+	t.Run("should route requests listing themselves as fallbacks", func(t *testing.T) {
+		rejecting := createRouterTestConfig()
+		rejecting.Anonymous = AnonymousPolicyConfig{Mode: AnonymousModeReject}
+		plugin := createRouterTestPluginWithConfig(t, rejecting)
+
+		forged := &schemas.BifrostRequest{
+			Provider:  schemas.OpenAI,
+			Model:     "openai/o1",
+			Input:     req.Input,
+			Fallbacks: []schemas.Fallback{{Provider: schemas.OpenAI, Model: "openai/o1"}},
+		}
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, forged)
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit, "client-sent fallbacks are not trusted")
+		assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+		assert.Nil(t, ctx.Value("heimdall_fallback_attempt"))
+	})
+
+	t.Run("should forget issued fallbacks after the TTL", func(t *testing.T) {
+		now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		issued := NewIssuedFallbacks(func() time.Time { return now })
+		issued.Issue(routed)
+		attempt := *routed
+		attempt.Provider, attempt.Model = routed.Fallbacks[0].Provider, routed.Fallbacks[0].Model
+		assert.True(t, issued.Issued(&attempt))
+		assert.False(t, issued.Issued(routed), "the primary target is not a fallback")
+
+		now = now.Add(issuedFallbackTTL)
+		assert.False(t, issued.Issued(&attempt))
+	})
+}
@@ -1,4 +1,6 @@
-package main
+// Package catalog is a client for the model catalog service (model metadata,
+// capabilities and pricing).
+package catalog
 
 import (
 	"context"
@@ -1,4 +1,4 @@
-package main
+package catalog
 
 // ModelInfo represents information about a model
 type ModelInfo struct {
@@ -0,0 +1,6 @@
+//go:build cgo
+
+package main
+
+// cgoEnabled reports whether the plugin was built with cgo
+const cgoEnabled = true
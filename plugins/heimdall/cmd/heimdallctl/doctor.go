@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/artifact"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar"
+)
+
+// Check outcomes
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorConfig is the part of the plugin config the self-test exercises
+type doctorConfig struct {
+	Router struct {
+		Thresholds      core.BucketThresholds `json:"thresholds"`
+		TopP            int                   `json:"top_p"`
+		MaxCandidates   int                   `json:"max_candidates"`
+		MaxScoringTime  time.Duration         `json:"max_scoring_time"`
+		CheapCandidates []string              `json:"cheap_candidates"`
+		MidCandidates   []string              `json:"mid_candidates"`
+		HardCandidates  []string              `json:"hard_candidates"`
+	} `json:"router"`
+	Tuning struct {
+		ArtifactURL  string   `json:"artifact_url"`
+		ArtifactURLs []string `json:"artifact_urls"`
+	} `json:"tuning"`
+	Catalog struct {
+		BaseURL string `json:"base_url"`
+	} `json:"catalog"`
+	Sidecar          sidecar.Config `json:"sidecar"`
+	EmbeddingTimeout time.Duration  `json:"embedding_timeout"`
+	FeatureTimeout   time.Duration  `json:"feature_timeout"`
+}
+
+// candidates returns the configured candidates by bucket
+func (c *doctorConfig) candidates() map[core.Bucket][]string {
+	return map[core.Bucket][]string{
+		core.BucketCheap: c.Router.CheapCandidates,
+		core.BucketMid:   c.Router.MidCandidates,
+		core.BucketHard:  c.Router.HardCandidates,
+	}
+}
+
+// doctorCheck is one step of the self-test
+type doctorCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Detail     string  `json:"detail"`
+	Hint       string  `json:"hint,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// doctorReport is the self-test outcome; it passes unless a check failed
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// syntheticPrompts are the self-test's requests: a short question, a code
+// review, a derivation and a document long enough for the context
+// guardrails, meant to reach every bucket
+var syntheticPrompts = []struct {
+	prompt string
+	// timed prompts count towards the timing budgets; the long document
+	// only checks the guardrails
+	timed bool
+}{
+	{"What is the capital of France?", true},
+	{"Review this code and suggest improvements:\n```go\n" + strings.Repeat("func parse(s string) int {\n\tn, err := strconv.Atoi(s)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\treturn n\n}\n\n", 60) + "```", true},
+	{"Prove that the integral of x^2 from 0 to 1 equals 1/3, then solve 3x^2 + 5x - 2 = 0. " + strings.Repeat("Show every step of the derivation and justify each equation. ", 80), true},
+	{strings.Repeat("The quarterly report covers revenue, churn and hiring across every region. ", 6000) + "\nSummarize the report above.", false},
+}
+
+// doctor runs the self-test against a config's live dependencies
+func doctor(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	decisions := flags.Int("n", 20, "synthetic decisions to make across the buckets")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout for each remote dependency")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *decisions < 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	report := runDoctor(flags.Arg(0), *decisions, *timeout)
+	if *asJSON {
+		writeJSON(stdout, report)
+	} else {
+		for _, check := range report.Checks {
+			fmt.Fprintf(stdout, "%-4s %-10s %s (%.0fms)\n", strings.ToUpper(check.Status), check.Name, check.Detail, check.DurationMs)
+			if check.Hint != "" {
+				fmt.Fprintf(stdout, "     hint: %s\n", check.Hint)
+			}
+		}
+		if report.OK {
+			fmt.Fprintln(stdout, "all checks passed")
+		} else {
+			fmt.Fprintln(stdout, "some checks failed")
+		}
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// runDoctor loads the config, fetches the artifact and catalog, checks the
+// sidecar and embeddings, then makes synthetic decisions. Checks that
+// depend on an earlier failure are skipped.
+func runDoctor(configPath string, decisions int, timeout time.Duration) *doctorReport {
+	report := &doctorReport{OK: true}
+	add := func(check doctorCheck, start time.Time) {
+		check.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		if check.Status == checkFail {
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	start := time.Now()
+	config, check := doctorLoadConfig(configPath)
+	add(check, start)
+	if config == nil {
+		return report
+	}
+
+	start = time.Now()
+	loaded, check := doctorFetchArtifact(config, timeout)
+	add(check, start)
+
+	start = time.Now()
+	add(doctorCatalog(config, timeout), start)
+
+	var client *sidecar.Client
+	start = time.Now()
+	client, check = doctorSidecar(config, loaded)
+	add(check, start)
+	if client != nil {
+		defer client.Close()
+	}
+
+	start = time.Now()
+	add(doctorEmbeddings(config, client), start)
+
+	if loaded == nil {
+		report.Checks = append(report.Checks, doctorCheck{Name: "decisions", Status: checkSkip, Detail: "no artifact to route with"})
+		return report
+	}
+	start = time.Now()
+	add(doctorDecisions(config, loaded, client, decisions), start)
+	return report
+}
+
+// doctorLoadConfig validates and parses the config
+func doctorLoadConfig(path string) (*doctorConfig, doctorCheck) {
+	check := doctorCheck{Name: "config"}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("failed to read config: %v", err)
+		check.Hint = "pass the path of the JSON config the plugin is deployed with"
+		return nil, check
+	}
+	violations, err := schema.ValidateConfig(data)
+	if err == nil && len(violations) > 0 {
+		err = fmt.Errorf("%s (%d violations)", violations[0], len(violations))
+	}
+	if err != nil {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("invalid config: %v", err)
+		check.Hint = "run heimdallctl config validate for every violation"
+		return nil, check
+	}
+
+	var config doctorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("failed to parse config: %v", err)
+		return nil, check
+	}
+	if config.FeatureTimeout == 0 {
+		config.FeatureTimeout = 25 * time.Millisecond
+	}
+	if config.EmbeddingTimeout == 0 {
+		config.EmbeddingTimeout = 15 * time.Second
+	}
+
+	check.Status, check.Detail = checkPass, path+" is valid"
+	candidates := config.candidates()
+	for _, bucket := range []core.Bucket{core.BucketCheap, core.BucketMid, core.BucketHard} {
+		if len(candidates[bucket]) == 0 {
+			check.Status = checkWarn
+			check.Detail = fmt.Sprintf("no %s candidates are configured", bucket)
+			check.Hint = "list candidates for every bucket under router"
+			break
+		}
+	}
+	return &config, check
+}
+
+// doctorFetchArtifact fetches the artifact from the first source that
+// serves it and validates it against the configured candidates
+func doctorFetchArtifact(config *doctorConfig, timeout time.Duration) (*core.AvengersArtifact, doctorCheck) {
+	check := doctorCheck{Name: "artifact"}
+	var urls []string
+	for _, url := range append([]string{config.Tuning.ArtifactURL}, config.Tuning.ArtifactURLs...) {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		check.Status, check.Detail = checkFail, "no artifact_url is configured"
+		check.Hint = "set tuning.artifact_url; until an artifact loads the plugin routes with the built-in default artifact"
+		return nil, check
+	}
+
+	var failures []string
+	for _, url := range urls {
+		data, baseDir, err := fetchArtifactData(url, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", url, err))
+			continue
+		}
+
+		validation := artifact.Validate(data, artifact.Options{Candidates: config.candidates(), BaseDir: baseDir})
+		var loaded core.AvengersArtifact
+		if err := json.Unmarshal(data, &loaded); err == nil {
+			err = scoring.ApplyPairwise(&loaded)
+			if err != nil {
+				validation.Issues = append(validation.Issues, artifact.Issue{Check: artifact.CheckSchema, Severity: artifact.SeverityError, Message: err.Error()})
+			}
+		}
+		if !validation.OK() {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("artifact %s from %s is invalid: %s", validation.Version, url, validation.Issues[0].Message)
+			check.Hint = "run heimdallctl artifact validate -config on it for every issue"
+			return nil, check
+		}
+
+		check.Status = checkPass
+		check.Detail = fmt.Sprintf("loaded artifact %s from %s", loaded.Version, url)
+		if len(failures) > 0 || len(validation.Issues) > 0 {
+			check.Status = checkWarn
+			for _, issue := range validation.Issues {
+				failures = append(failures, issue.Message)
+			}
+			check.Detail += "; " + strings.Join(failures, "; ")
+			check.Hint = "earlier sources failed or the artifact has warnings; fix them before relying on failover"
+		}
+		return &loaded, check
+	}
+
+	check.Status, check.Detail = checkFail, "no artifact source is reachable: "+strings.Join(failures, "; ")
+	check.Hint = "check the URLs are reachable from this host and serve the artifact with status 200"
+	return nil, check
+}
+
+// fetchArtifactData reads an artifact from a file:// or HTTP URL, returning
+// the directory its referenced files resolve against, if local
+func fetchArtifactData(url string, timeout time.Duration) ([]byte, string, error) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		data, err := os.ReadFile(path)
+		return data, filepath.Dir(path), err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, "", err
+}
+
+// doctorCatalog checks the catalog service serves models, including the
+// configured candidates
+func doctorCatalog(config *doctorConfig, timeout time.Duration) doctorCheck {
+	check := doctorCheck{Name: "catalog"}
+	if config.Catalog.BaseURL == "" {
+		check.Status, check.Detail = checkSkip, "no catalog is configured"
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	models, err := catalog.NewCatalogClient(config.Catalog.BaseURL).GetModels(ctx, nil)
+	if err != nil {
+		check.Status, check.Detail = checkWarn, err.Error()
+		check.Hint = "check catalog.base_url is reachable from this host; routing does not depend on the catalog"
+		return check
+	}
+
+	listed := make(map[string]bool, len(models))
+	for _, model := range models {
+		listed[model.Slug] = true
+	}
+	var missing []string
+	for _, candidates := range config.candidates() {
+		for _, candidate := range candidates {
+			if !listed[candidate] {
+				missing = append(missing, candidate)
+			}
+		}
+	}
+	sort.Strings(missing)
+
+	check.Status, check.Detail = checkPass, fmt.Sprintf("catalog lists %d models", len(models))
+	if len(missing) > 0 {
+		check.Status = checkWarn
+		check.Detail += fmt.Sprintf(", not %s", strings.Join(missing, ", "))
+		check.Hint = "candidates missing from the catalog may be misspelled or retired"
+	}
+	return check
+}
+
+// doctorSidecar connects to the sidecar and checks the stages delegated to
+// it other than embeddings
+func doctorSidecar(config *doctorConfig, loaded *core.AvengersArtifact) (*sidecar.Client, doctorCheck) {
+	check := doctorCheck{Name: "sidecar"}
+	if !config.Sidecar.Enabled() {
+		check.Status, check.Detail = checkSkip, "no stage is delegated to a sidecar"
+		return nil, check
+	}
+	client, err := sidecar.NewClient(config.Sidecar)
+	if err != nil {
+		check.Status, check.Detail = checkFail, err.Error()
+		check.Hint = "check sidecar.address and the sidecar settings"
+		return nil, check
+	}
+
+	check.Status, check.Detail = checkPass, "connected to "+config.Sidecar.Address
+	if config.Sidecar.Triage && loaded != nil {
+		if _, err := client.Predict(&core.RequestFeatures{TokenCount: 10}, loaded); err != nil {
+			check.Status = checkWarn
+			check.Detail = fmt.Sprintf("triage failed: %v", err)
+			check.Hint = "the plugin falls back to the built-in GBDT; check the sidecar is serving"
+		}
+	}
+	return client, check
+}
+
+// doctorEmbeddings embeds a prompt with the configured provider within the
+// embedding timeout
+func doctorEmbeddings(config *doctorConfig, client *sidecar.Client) doctorCheck {
+	check := doctorCheck{Name: "embeddings"}
+	if !config.Sidecar.Embed {
+		check.Status, check.Detail = checkPass, "built-in hash embeddings"
+		return check
+	}
+	if client == nil {
+		check.Status, check.Detail = checkSkip, "sidecar is unavailable"
+		return check
+	}
+
+	start := time.Now()
+	embedding, err := client.Embed(syntheticPrompts[0].prompt)
+	elapsed := time.Since(start)
+	switch {
+	case err != nil:
+		check.Status, check.Detail = checkFail, fmt.Sprintf("sidecar embedding failed: %v", err)
+		check.Hint = "the plugin falls back to hash embeddings, which cluster poorly; check the sidecar's embedding model"
+	case len(embedding) == 0:
+		check.Status, check.Detail = checkFail, "sidecar returned an empty embedding"
+		check.Hint = "check the sidecar's embedding model is loaded"
+	case elapsed > config.EmbeddingTimeout:
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%d dimensions in %s, over embedding_timeout %s", len(embedding), elapsed.Round(time.Millisecond), config.EmbeddingTimeout)
+		check.Hint = "raise embedding_timeout or move the sidecar closer to the plugin"
+	default:
+		check.Status, check.Detail = checkPass, fmt.Sprintf("%d dimensions in %s", len(embedding), elapsed.Round(time.Millisecond))
+	}
+	return check
+}
+
+// doctorDecisions makes synthetic decisions with the config's pipeline,
+// checking every bucket is reached and the timing budgets hold
+func doctorDecisions(config *doctorConfig, loaded *core.AvengersArtifact, client *sidecar.Client, n int) doctorCheck {
+	check := doctorCheck{Name: "decisions"}
+
+	extractor := features.NewFeatureExtractor()
+	var triage router.TriageModel = scoring.NewGBDTRuntime()
+	if client != nil {
+		if config.Sidecar.Embed {
+			extractor.SetEmbeddingProvider(client)
+		}
+		if config.Sidecar.Cluster {
+			extractor.SetClusterAssigner(client)
+		}
+		if config.Sidecar.Triage {
+			triage = router.TriageWithFallback(client, triage)
+		}
+	}
+	pipeline := router.New(router.Config{
+		Thresholds:     config.Router.Thresholds,
+		FeatureTimeout: config.FeatureTimeout,
+		TopP:           config.Router.TopP,
+		MaxCandidates:  config.Router.MaxCandidates,
+		MaxScoringTime: config.Router.MaxScoringTime,
+	}, extractor, triage, scoring.NewAlphaScorer())
+
+	reached := make(map[core.Bucket]int)
+	var extraction, total []time.Duration
+	var failures []string
+	decided, truncated := 0, 0
+	for i := 0; i < n; i++ {
+		synthetic := syntheticPrompts[i%len(syntheticPrompts)]
+		// A unique suffix keeps the embedding cache from hiding latency
+		req := &core.RouterRequest{Body: &core.RequestBody{Messages: []core.ChatMessage{
+			{Role: "user", Content: fmt.Sprintf("%s\n(self-test %d)", synthetic.prompt, i)},
+		}}}
+
+		start := time.Now()
+		features, err := extractor.Extract(req, loaded, int(config.FeatureTimeout.Milliseconds()))
+		if synthetic.timed {
+			extraction = append(extraction, time.Since(start))
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("feature extraction: %v", err))
+			continue
+		}
+		triaged, err := pipeline.Classify(features, loaded)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		reached[triaged.Bucket]++
+		if candidates := config.candidates()[triaged.Bucket]; len(candidates) > 0 {
+			_, cut, err := pipeline.SelectWithBudget(candidates, features, loaded)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s selection: %v", triaged.Bucket, err))
+				continue
+			}
+			if cut {
+				truncated++
+			}
+		}
+		decided++
+		if synthetic.timed {
+			total = append(total, time.Since(start))
+		}
+	}
+
+	extractionP95 := percentile(extraction, 0.95)
+	check.Detail = fmt.Sprintf("%d decisions (cheap %d, mid %d, hard %d), p50 %s, p95 %s, feature extraction p95 %s",
+		decided, reached[core.BucketCheap], reached[core.BucketMid], reached[core.BucketHard],
+		percentile(total, 0.5), percentile(total, 0.95), extractionP95)
+	check.Status = checkPass
+
+	var missed []string
+	for _, bucket := range []core.Bucket{core.BucketCheap, core.BucketMid, core.BucketHard} {
+		if reached[bucket] == 0 {
+			missed = append(missed, string(bucket))
+		}
+	}
+	switch {
+	case len(failures) > 0:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%d of %d decisions failed: %s", len(failures), n, failures[0])
+		check.Hint = "check every candidate has quality and cost estimates in the artifact"
+	case extractionP95 > config.FeatureTimeout:
+		check.Status = checkWarn
+		check.Hint = fmt.Sprintf("feature extraction exceeds feature_timeout %s; raise it or speed up the embedding provider", config.FeatureTimeout)
+	case truncated > 0:
+		check.Status = checkWarn
+		check.Hint = fmt.Sprintf("max_scoring_time or max_candidates cut scoring short in %d decisions; raise them or lower top_p", truncated)
+	case len(missed) > 0 && n >= len(syntheticPrompts):
+		check.Status = checkWarn
+		check.Hint = fmt.Sprintf("no decision reached %s; check router.thresholds against the artifact's", strings.Join(missed, ", "))
+	}
+	return check
+}
+
+// percentile returns the q-th percentile of durations, rounded for display
+func percentile(durations []time.Duration, q float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(q * float64(len(sorted)-1))
+	return sorted[index].Round(10 * time.Microsecond)
+}
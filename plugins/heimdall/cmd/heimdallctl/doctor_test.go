@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	artifactPath := write("artifact.json", `{
+		"version": "v7", "alpha": 0.7, "thresholds": {"cheap": 0.3, "hard": 0.7},
+		"qhat": {"cheap/a": [0.6], "mid/b": [0.75], "hard/c": [0.9]},
+		"chat": {"cheap/a": 0.1, "mid/b": 0.4, "hard/c": 0.9}
+	}`)
+	catalogServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models": [{"slug": "cheap/a"}, {"slug": "mid/b"}]}`))
+	}))
+	defer catalogServer.Close()
+
+	config := func(artifactURL string) string {
+		data, err := json.Marshal(map[string]interface{}{
+			"router": map[string]interface{}{
+				"thresholds":       map[string]float64{"cheap": 0.3, "hard": 0.7},
+				"cheap_candidates": []string{"cheap/a"},
+				"mid_candidates":   []string{"mid/b"},
+				"hard_candidates":  []string{"hard/c"},
+			},
+			"tuning":  map[string]interface{}{"artifact_url": artifactURL},
+			"catalog": map[string]interface{}{"base_url": catalogServer.URL},
+			// Generous, so slow test machines do not warn
+			"feature_timeout": 5_000_000_000,
+		})
+		require.NoError(t, err)
+		return write("config.json", string(data))
+	}
+
+	t.Run("should pass a working deployment", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"doctor", "-n", "8", "-json", config("file://" + artifactPath)}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stdout.String()+stderr.String())
+
+		var report doctorReport
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.True(t, report.OK)
+		statuses := make(map[string]string)
+		for _, check := range report.Checks {
+			statuses[check.Name] = check.Status
+		}
+		assert.Equal(t, map[string]string{
+			"config":     checkPass,
+			"artifact":   checkPass,
+			"catalog":    checkWarn,
+			"sidecar":    checkSkip,
+			"embeddings": checkPass,
+			"decisions":  checkPass,
+		}, statuses)
+		assert.Contains(t, report.Checks[2].Detail, "not hard/c")
+		assert.Contains(t, report.Checks[5].Detail, "8 decisions (cheap 2, mid 4, hard 2)")
+	})
+
+	t.Run("should fail with hints when the artifact is unreachable", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"doctor", "-n", "4", config("file://" + filepath.Join(dir, "missing.json"))}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout.String(), "FAIL artifact   no artifact source is reachable")
+		assert.Contains(t, stdout.String(), "hint: check the URLs are reachable")
+		assert.Contains(t, stdout.String(), "SKIP decisions  no artifact to route with")
+		assert.Contains(t, stdout.String(), "some checks failed")
+	})
+
+	t.Run("should fail an invalid config", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"doctor", write("bad.json", `{"enable_cachign": true}`)}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout.String(), "FAIL config")
+		assert.Contains(t, stdout.String(), "heimdallctl config validate")
+	})
+
+	t.Run("should exit 2 on usage errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run([]string{"doctor"}, &stdout, &stderr))
+		assert.Equal(t, 2, run([]string{"doctor", "-n", "0", "config.json"}, &stdout, &stderr))
+	})
+}
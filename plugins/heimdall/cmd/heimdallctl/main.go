@@ -0,0 +1,385 @@
+// Command heimdallctl is the operator CLI for Heimdall configuration and
+// routing artifacts.
+//
+//	heimdallctl artifact validate [-config router.json] [-base-dir dir] [-json] artifact.json
+//	heimdallctl artifact diff [-decisions log.jsonl -config router.json] [-json] old.json new.json
+//	heimdallctl artifact schema
+//	heimdallctl config validate [-json] config.json
+//	heimdallctl config schema
+//	heimdallctl forecast [-period 24h] [-confidence 0.9] [-pricing pricing.json] decisions.jsonl
+//	heimdallctl doctor [-n 20] [-timeout 10s] [-json] config.json
+//
+// validate exits 1 when the artifact or config fails any check, so it can
+// gate CI.
+// diff reports estimate changes and, given a decision log sample, how many
+// routing outcomes the new artifact would change and at what cost/quality.
+// forecast projects the next period's bucket mix, per-provider volume and
+// spend from a timestamped decision log, as JSON for capacity dashboards.
+// doctor self-tests a deployment's config against its live dependencies:
+// it fetches the artifact and catalog, checks the sidecar and embeddings,
+// makes synthetic decisions across the buckets and checks timing budgets,
+// printing a pass/fail report with remediation hints. It exits 1 when a
+// check fails.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/artifact"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/forecast"
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+)
+
+const usage = `usage: heimdallctl artifact validate [flags] artifact.json
+       heimdallctl artifact diff [flags] old.json new.json
+       heimdallctl artifact schema
+       heimdallctl config validate [flags] config.json
+       heimdallctl config schema
+       heimdallctl forecast [flags] decisions.jsonl
+       heimdallctl doctor [flags] config.json`
+
+func main() {
+	// The scoring library logs each selection; reports go to stdout
+	log.SetOutput(io.Discard)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "forecast" {
+		return projectForecast(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "doctor" {
+		return doctor(args[1:], stdout, stderr)
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	switch args[0] + " " + args[1] {
+	case "artifact validate":
+		return validate(args[2:], stdout, stderr)
+	case "artifact diff":
+		return diff(args[2:], stdout, stderr)
+	case "artifact schema":
+		stdout.Write(schema.ArtifactJSON)
+		return 0
+	case "config validate":
+		return validateConfig(args[2:], stdout, stderr)
+	case "config schema":
+		stdout.Write(schema.ConfigJSON)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s\n", args[0]+" "+args[1], usage)
+		return 2
+	}
+}
+
+// routerConfig is the part of the plugin config that names candidates
+type routerConfig struct {
+	Router struct {
+		CheapCandidates []string `json:"cheap_candidates"`
+		MidCandidates   []string `json:"mid_candidates"`
+		HardCandidates  []string `json:"hard_candidates"`
+	} `json:"router"`
+}
+
+func validate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "plugin config (JSON) whose candidates the artifact must cover")
+	baseDir := flags.String("base-dir", "", "directory for relative centroid and GBDT paths (default: the artifact's)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	opts := artifact.Options{BaseDir: *baseDir}
+	if *configPath != "" {
+		candidates, err := readCandidates(*configPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		opts.Candidates = candidates
+	}
+
+	report, err := artifact.ValidateFile(flags.Arg(0), opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read artifact: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		writeJSON(stdout, report)
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Fprintf(stdout, "%s [%s] %s\n", issue.Severity, issue.Check, issue.Message)
+		}
+		if report.OK() {
+			fmt.Fprintf(stdout, "artifact %s is valid\n", report.Version)
+		} else {
+			fmt.Fprintf(stdout, "artifact %s is invalid\n", report.Version)
+		}
+	}
+
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// validateConfig lints a plugin config against the config schema
+func validateConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print violations as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read config: %v\n", err)
+		return 2
+	}
+	violations, err := schema.ValidateConfig(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *asJSON {
+		writeJSON(stdout, violations)
+	} else {
+		for _, violation := range violations {
+			fmt.Fprintln(stdout, violation)
+		}
+		if len(violations) == 0 {
+			fmt.Fprintf(stdout, "%s is valid\n", flags.Arg(0))
+		}
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func diff(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	decisionsPath := flags.String("decisions", "", "decision log sample (JSON lines of router responses) to estimate impact on")
+	configPath := flags.String("config", "", "plugin config (JSON) naming the candidates per bucket; required with -decisions")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 || (*decisionsPath != "" && *configPath == "") {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	old, err := artifact.Load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	updated, err := artifact.Load(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	result := struct {
+		Diff   *artifact.Diff   `json:"diff"`
+		Impact *artifact.Impact `json:"impact,omitempty"`
+	}{Diff: artifact.Compare(old, updated)}
+
+	if *decisionsPath != "" {
+		candidates, err := readCandidates(*configPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		file, err := os.Open(*decisionsPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read decision log: %v\n", err)
+			return 2
+		}
+		samples, err := artifact.ReadSamples(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		result.Impact, err = artifact.EstimateImpact(old, updated, samples, candidates)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	if *asJSON {
+		writeJSON(stdout, result)
+		return 0
+	}
+	printDiff(stdout, result.Diff)
+	if result.Impact != nil {
+		printImpact(stdout, result.Impact)
+	}
+	return 0
+}
+
+// projectForecast forecasts the period after a decision log's history
+func projectForecast(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	period := flags.Duration("period", 24*time.Hour, "length of each history period and of the forecast")
+	until := flags.String("until", "", "end of the history (RFC 3339; default: the latest decision)")
+	confidence := flags.Float64("confidence", 0.9, "coverage of the forecast intervals")
+	pricingPath := flags.String("pricing", "", "model pricing (JSON: a model map, or a document with a pricing field such as default_artifact.json) to forecast spend")
+	outputTokens := flags.Int("output-tokens", 0, "assumed completion tokens per request when pricing spend")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	opts := forecast.Options{Period: *period, Confidence: *confidence, OutputTokens: *outputTokens}
+	if *until != "" {
+		var err error
+		if opts.Until, err = time.Parse(time.RFC3339, *until); err != nil {
+			fmt.Fprintf(stderr, "invalid -until: %v\n", err)
+			return 2
+		}
+	}
+	if *pricingPath != "" {
+		pricing, err := readPricing(*pricingPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		opts.Pricing = pricing
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read decision log: %v\n", err)
+		return 2
+	}
+	samples, err := artifact.ReadSamples(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	result, err := forecast.Project(samples, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	writeJSON(stdout, result)
+	return 0
+}
+
+func printDiff(w io.Writer, d *artifact.Diff) {
+	fmt.Fprintf(w, "artifact %s -> %s\n", d.OldVersion, d.NewVersion)
+	if d.Alpha != nil {
+		fmt.Fprintf(w, "  alpha: %.4g -> %.4g\n", d.Alpha.Old, d.Alpha.New)
+	}
+	if d.CheapThreshold != nil || d.HardThreshold != nil {
+		fmt.Fprintln(w, "  thresholds changed (routing uses the config's thresholds):")
+		if d.CheapThreshold != nil {
+			fmt.Fprintf(w, "    cheap: %.4g -> %.4g\n", d.CheapThreshold.Old, d.CheapThreshold.New)
+		}
+		if d.HardThreshold != nil {
+			fmt.Fprintf(w, "    hard: %.4g -> %.4g\n", d.HardThreshold.Old, d.HardThreshold.New)
+		}
+	}
+	if d.Normalization != nil {
+		fmt.Fprintf(w, "  normalization: %q -> %q\n", d.Normalization[0], d.Normalization[1])
+	}
+	for _, model := range d.Models {
+		switch model.Status {
+		case artifact.ModelAdded, artifact.ModelRemoved:
+			fmt.Fprintf(w, "  %s: %s\n", model.Model, model.Status)
+		default:
+			fmt.Fprintf(w, "  %s: qhat max %+.4f mean %+.4f", model.Model, model.MaxQhatDelta, model.MeanQhatDelta)
+			if model.Chat != nil {
+				fmt.Fprintf(w, ", chat %.4g -> %.4g", model.Chat.Old, model.Chat.New)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+func printImpact(w io.Writer, impact *artifact.Impact) {
+	fmt.Fprintf(w, "impact over %d decisions (%d skipped): %d changed (%.1f%%)\n",
+		impact.Samples, impact.Skipped, impact.Changed, 100*impact.ChangedFraction)
+	fmt.Fprintf(w, "  quality %+.4f, cost %+.4f per request\n", impact.QualityDelta, impact.CostDelta)
+	for _, transition := range impact.SortedTransitions() {
+		fmt.Fprintf(w, "  %s: %d\n", transition, impact.Transitions[transition])
+	}
+}
+
+// readCandidates loads the candidates per bucket from a plugin config
+func readCandidates(path string) (map[core.Bucket][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var config routerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return map[core.Bucket][]string{
+		core.BucketCheap: config.Router.CheapCandidates,
+		core.BucketMid:   config.Router.MidCandidates,
+		core.BucketHard:  config.Router.HardCandidates,
+	}, nil
+}
+
+// readPricing loads model pricing from a bare model map or from a document's
+// pricing field
+func readPricing(path string) (map[string]catalog.ModelPricing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing: %w", err)
+	}
+	var document struct {
+		Pricing map[string]catalog.ModelPricing `json:"pricing"`
+	}
+	if err := json.Unmarshal(data, &document); err == nil && document.Pricing != nil {
+		return document.Pricing, nil
+	}
+	var pricing map[string]catalog.ModelPricing
+	if err := json.Unmarshal(data, &pricing); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing: %w", err)
+	}
+	return pricing, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactValidate(t *testing.T) {
+	dir := t.TempDir()
+	artifactPath := filepath.Join(dir, "artifact.json")
+	require.NoError(t, os.WriteFile(artifactPath, []byte(`{
+		"version": "v2", "alpha": 0.7, "thresholds": {"cheap": 0.3, "hard": 0.7},
+		"qhat": {"openai/gpt-4o": [0.8]}, "chat": {"openai/gpt-4o": 0.5}
+	}`), 0o644))
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"router": {"hard_candidates": ["openai/gpt-5"]}}`), 0o644))
+
+	t.Run("should exit 0 for a valid artifact", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"artifact", "validate", artifactPath}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "artifact v2 is valid\n", stdout.String())
+	})
+
+	t.Run("should exit 1 when configured candidates are not covered", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"artifact", "validate", "-config", configPath, artifactPath}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout.String(), "error [coverage] hard candidate openai/gpt-5 has no quality scores")
+	})
+
+	t.Run("should print the report as JSON", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"artifact", "validate", "-json", artifactPath}, &stdout, &stderr)
+		assert.Equal(t, 0, code)
+		assert.JSONEq(t, `{"version": "v2", "issues": null}`, stdout.String())
+	})
+
+	t.Run("should exit 2 on usage errors", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run([]string{"artifact"}, &stdout, &stderr))
+		assert.Equal(t, 2, run([]string{"artifact", "publish"}, &stdout, &stderr))
+		assert.Equal(t, 2, run([]string{"artifact", "validate", filepath.Join(dir, "missing.json")}, &stdout, &stderr))
+	})
+}
+
+func TestArtifactDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	oldPath := write("old.json", `{"version": "v1", "alpha": 0.7,
+		"qhat": {"a": [0.8], "b": [0.6]}, "chat": {"a": 0.5, "b": 0.1}}`)
+	newPath := write("new.json", `{"version": "v2", "alpha": 0.7,
+		"qhat": {"a": [0.8], "b": [0.75]}, "chat": {"a": 0.5, "b": 0.1}}`)
+	configPath := write("config.json", `{"router": {"mid_candidates": ["a", "b"]}}`)
+	decisionsPath := write("decisions.jsonl", `{"bucket": "mid", "features": {"cluster_id": 0}, "decision": {"model": "a"}}`+"\n")
+
+	t.Run("should report estimate changes", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"artifact", "diff", oldPath, newPath}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "artifact v1 -> v2\n  b: qhat max +0.1500 mean +0.1500\n", stdout.String())
+	})
+
+	t.Run("should estimate impact over a decision log", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"artifact", "diff", "-decisions", decisionsPath, "-config", configPath, oldPath, newPath}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), "impact over 1 decisions (0 skipped): 1 changed (100.0%)")
+		assert.Contains(t, stdout.String(), "a -> b: 1")
+	})
+
+	t.Run("should require a config to estimate impact", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run([]string{"artifact", "diff", "-decisions", decisionsPath, oldPath, newPath}, &stdout, &stderr))
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"router": {"alpha": 0.7}, "enable_caching": true}`), 0o644))
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"router": {"alpha": "high"}, "timeout": "25ms"}`), 0o644))
+
+	t.Run("should exit 0 for a conforming config", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run([]string{"config", "validate", valid}, &stdout, &stderr), stderr.String())
+	})
+
+	t.Run("should list violations and exit 1", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, run([]string{"config", "validate", invalid}, &stdout, &stderr))
+		assert.Equal(t, "$.router.alpha: expected number, got string\n$.timeout: expected integer, got string\n", stdout.String())
+	})
+
+	t.Run("should print the schemas", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run([]string{"config", "schema"}, &stdout, &stderr))
+		assert.Contains(t, stdout.String(), `"title": "Heimdall plugin configuration"`)
+	})
+}
+
+func TestForecast(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	decisionsPath := write("decisions.jsonl", `{"timestamp": "2025-03-01T00:00:00Z", "bucket": "cheap", "features": {"token_count": 1000}, "decision": {"kind": "openai", "model": "openai/gpt-4o-mini"}}
+{"timestamp": "2025-03-02T00:00:00Z", "bucket": "cheap", "features": {"token_count": 1000}, "decision": {"kind": "openai", "model": "openai/gpt-4o-mini"}}
+{"timestamp": "2025-03-03T00:00:00Z", "bucket": "cheap", "features": {"token_count": 1000}, "decision": {"kind": "openai", "model": "openai/gpt-4o-mini"}}
+`)
+	pricingPath := write("artifact.json", `{"version": "v1", "pricing": {"openai/gpt-4o-mini": {"in_per_million": 1, "out_per_million": 2}}}`)
+
+	t.Run("should print the forecast as JSON", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"forecast", "-pricing", pricingPath, "-output-tokens", "500", decisionsPath}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Contains(t, stdout.String(), `"history_periods": 2`)
+		assert.Contains(t, stdout.String(), `"openai": {`)
+		assert.Contains(t, stdout.String(), `"spend": {`)
+	})
+
+	t.Run("should exit 1 without enough history", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, run([]string{"forecast", "-period", "72h", decisionsPath}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "need at least 2 complete")
+	})
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Preference outcomes of a comparison
+const (
+	PreferPrimary    = "primary"
+	PreferChallenger = "challenger"
+	PreferTie        = "tie"
+)
+
+// CompareConfig configures compare mode: a sampled or flagged request is
+// also sent to a challenger model in parallel, the caller gets the primary
+// response, and both outputs are kept for offline preference labeling.
+// Imported preferences feed the online quality store.
+type CompareConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// SampleRate is the fraction of requests compared
+	SampleRate float64 `json:"sample_rate"`
+	// Header set to "true" compares a request regardless of the sample rate
+	// (default X-Heimdall-Compare). It is honoured only for HeaderTenants,
+	// since a comparison sends the prompt to a second model.
+	Header        string   `json:"header"`
+	HeaderTenants []string `json:"header_tenants"`
+
+	// Model is the challenger; empty uses the decision's first fallback,
+	// the bucket's runner-up
+	Model string `json:"model"`
+
+	// Endpoint is an OpenAI-compatible chat completions URL serving models
+	// by name. It must not route through this plugin, or the challenger
+	// would be rerouted.
+	Endpoint string `json:"endpoint"`
+
+	// APIKey authenticates to the endpoint; APIKeyEnv names an environment
+	// variable to read it from instead
+	APIKey    string `json:"api_key,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+
+	Timeout   time.Duration `json:"timeout"`
+	QueueSize int           `json:"queue_size"`
+	Workers   int           `json:"workers"`
+
+	// MaxRecords bounds the comparisons awaiting a preference; the oldest
+	// are dropped
+	MaxRecords int `json:"max_records"`
+}
+
+// CompareOutput is one model's answer in a comparison
+type CompareOutput struct {
+	Model  string `json:"model"`
+	Output string `json:"output"`
+}
+
+// ComparisonRecord is a request answered by two models, awaiting a
+// preference. Unlike label records it holds the prompt and both outputs,
+// which labelers need side by side.
+type ComparisonRecord struct {
+	ID         string        `json:"id"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Bucket     Bucket        `json:"bucket"`
+	ClusterID  int           `json:"cluster_id"`
+	Prompt     string        `json:"prompt"`
+	Primary    CompareOutput `json:"primary"`
+	Challenger CompareOutput `json:"challenger"`
+
+	// Owner is the caller the request came from; it is not exported to
+	// labelers
+	Owner DataSubject `json:"-"`
+}
+
+// Preference is a human choice between a comparison's outputs
+type Preference struct {
+	ID        string `json:"id"`
+	Preferred string `json:"preferred"` // primary, challenger or tie
+	Note      string `json:"note,omitempty"`
+}
+
+// CompareStats counts compare mode activity
+type CompareStats struct {
+	Started   int64 `json:"started"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	Dropped   int64 `json:"dropped"`
+	Pending   int   `json:"pending"`
+}
+
+// ChallengerClient answers a chat request with a given model
+type ChallengerClient interface {
+	Complete(ctx context.Context, model string, messages []ChatMessage) (string, error)
+}
+
+// Comparison pairs the primary and challenger outputs of one request; the
+// record is kept once both sides have finished
+type Comparison struct {
+	record   ComparisonRecord
+	messages []ChatMessage
+	pending  int
+	failed   bool
+	erased   bool // the tenant's data was erased while it was in flight
+	mu       sync.Mutex
+}
+
+// Comparer runs challenger requests and buffers finished comparisons
+type Comparer struct {
+	config  CompareConfig
+	client  ChallengerClient
+	quality *scoring.QualityStore
+
+	queue    chan *Comparison
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	pending map[string]ComparisonRecord
+	order   []string
+	active  map[*Comparison]struct{} // begun, not yet finished
+	mu      sync.Mutex
+
+	started, completed, failed, dropped atomic.Int64
+}
+
+// withDefaults fills unset compare settings
+func (c CompareConfig) withDefaults() CompareConfig {
+	if c.Header == "" {
+		c.Header = "X-Heimdall-Compare"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 60 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 64
+	}
+	if c.Workers == 0 {
+		c.Workers = 2
+	}
+	if c.MaxRecords == 0 {
+		c.MaxRecords = 1000
+	}
+	return c
+}
+
+// NewComparer creates a comparer whose preferences feed quality
+func NewComparer(config CompareConfig, client ChallengerClient, quality *scoring.QualityStore) (*Comparer, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be within [0, 1], got %v", config.SampleRate)
+	}
+	config = config.withDefaults()
+
+	return &Comparer{
+		config:  config,
+		client:  client,
+		quality: quality,
+		queue:   make(chan *Comparison, config.QueueSize),
+		stopCh:  make(chan struct{}),
+		pending: make(map[string]ComparisonRecord),
+		active:  make(map[*Comparison]struct{}),
+	}, nil
+}
+
+// Start launches the challenger workers
+func (cp *Comparer) Start() {
+	for i := 0; i < cp.config.Workers; i++ {
+		cp.wg.Add(1)
+		go cp.worker()
+	}
+}
+
+// Stop halts the workers, abandoning queued challengers
+func (cp *Comparer) Stop() {
+	cp.stopOnce.Do(func() {
+		close(cp.stopCh)
+	})
+	cp.wg.Wait()
+}
+
+// ShouldCompare decides whether a tenant's request is compared
+func (cp *Comparer) ShouldCompare(headers map[string][]string, tenant string) bool {
+	if strings.EqualFold(auth.HeaderValue(headers, cp.config.Header), "true") && slices.Contains(cp.config.HeaderTenants, tenant) {
+		return true
+	}
+	return rand.Float64() < cp.config.SampleRate
+}
+
+// Challenger returns the model a decision is compared with, or "" when
+// there is none
+func (cp *Comparer) Challenger(decision RouterDecision) string {
+	challenger := cp.config.Model
+	if challenger == "" && len(decision.Fallbacks) > 0 {
+		challenger = decision.Fallbacks[0]
+	}
+	if challenger == decision.Model {
+		return ""
+	}
+	return challenger
+}
+
+// Begin queues challenger for a routed request and returns the comparison
+// the primary's output completes, or nil when there is no challenger or
+// the queue is full
+func (cp *Comparer) Begin(owner DataSubject, decision RouterDecision, challenger string, bucket Bucket, clusterID int, messages []ChatMessage, prompt string) *Comparison {
+	if challenger == "" {
+		return nil
+	}
+
+	c := &Comparison{
+		record: ComparisonRecord{
+			ID:         newLabelID(),
+			Timestamp:  time.Now(),
+			Bucket:     bucket,
+			ClusterID:  clusterID,
+			Prompt:     prompt,
+			Primary:    CompareOutput{Model: decision.Model},
+			Challenger: CompareOutput{Model: challenger},
+			Owner:      owner,
+		},
+		messages: messages,
+		pending:  2,
+	}
+	cp.mu.Lock()
+	cp.active[c] = struct{}{}
+	cp.mu.Unlock()
+	select {
+	case cp.queue <- c:
+		cp.started.Add(1)
+		return c
+	default:
+		cp.mu.Lock()
+		delete(cp.active, c)
+		cp.mu.Unlock()
+		cp.dropped.Add(1)
+		return nil
+	}
+}
+
+// Finish records the primary's output; ok is false when it failed or was
+// streamed
+func (cp *Comparer) Finish(c *Comparison, output string, ok bool) {
+	cp.finish(c, &c.record.Primary, output, ok)
+}
+
+// finish fills one side of a comparison, keeping the record once both
+// sides succeeded
+func (cp *Comparer) finish(c *Comparison, side *CompareOutput, output string, ok bool) {
+	c.mu.Lock()
+	side.Output = output
+	c.failed = c.failed || !ok
+	c.pending--
+	done, failed := c.pending == 0, c.failed
+	c.mu.Unlock()
+	if !done {
+		return
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	delete(cp.active, c)
+	c.mu.Lock()
+	erased := c.erased
+	c.mu.Unlock()
+	if erased {
+		return
+	}
+	if failed {
+		cp.failed.Add(1)
+		return
+	}
+	cp.completed.Add(1)
+
+	if len(cp.order) >= cp.config.MaxRecords {
+		delete(cp.pending, cp.order[0])
+		cp.order = cp.order[1:]
+	}
+	cp.pending[c.record.ID] = c.record
+	cp.order = append(cp.order, c.record.ID)
+}
+
+func (cp *Comparer) worker() {
+	defer cp.wg.Done()
+	for {
+		select {
+		case c := <-cp.queue:
+			cp.runChallenger(c)
+		case <-cp.stopCh:
+			return
+		}
+	}
+}
+
+// runChallenger answers a comparison's request with the challenger
+func (cp *Comparer) runChallenger(c *Comparison) {
+	ctx, cancel := context.WithTimeout(context.Background(), cp.config.Timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	erased := c.erased
+	c.mu.Unlock()
+	if erased {
+		cp.finish(c, &c.record.Challenger, "", false)
+		return
+	}
+
+	model := c.record.Challenger.Model
+	output, err := cp.client.Complete(ctx, model, c.messages)
+	if err != nil {
+		log.Printf("Challenger %s failed: %v", model, err)
+	}
+	cp.finish(c, &c.record.Challenger, output, err == nil)
+}
+
+// Export writes finished comparisons as JSON lines, oldest first
+func (cp *Comparer) Export(w io.Writer) error {
+	cp.mu.Lock()
+	records := make([]ComparisonRecord, 0, len(cp.order))
+	for _, id := range cp.order {
+		records = append(records, cp.pending[id])
+	}
+	cp.mu.Unlock()
+
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import reads preferences as JSON lines. The preferred model scores 1 and
+// the other 0 in the quality store; ties score 0.5 each. Compared records
+// leave the buffer.
+func (cp *Comparer) Import(r io.Reader) (*LabelImportResult, error) {
+	result := &LabelImportResult{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var preference Preference
+		if err := json.Unmarshal(scanner.Bytes(), &preference); err != nil {
+			result.Invalid++
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		var primary float64
+		switch preference.Preferred {
+		case PreferPrimary:
+			primary = 1
+		case PreferChallenger:
+			primary = 0
+		case PreferTie:
+			primary = 0.5
+		default:
+			result.Invalid++
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: unknown preference %q", line, preference.Preferred))
+			continue
+		}
+
+		record, ok := cp.take(preference.ID)
+		if !ok {
+			result.Unknown++
+			continue
+		}
+		cp.quality.Observe(record.Primary.Model, record.ClusterID, primary)
+		cp.quality.Observe(record.Challenger.Model, record.ClusterID, 1-primary)
+		result.Applied++
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// take removes and returns a finished comparison
+func (cp *Comparer) take(id string) (ComparisonRecord, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	record, ok := cp.pending[id]
+	if !ok {
+		return record, false
+	}
+	delete(cp.pending, id)
+	for i, pendingID := range cp.order {
+		if pendingID == id {
+			cp.order = append(cp.order[:i], cp.order[i+1:]...)
+			break
+		}
+	}
+	return record, true
+}
+
+// GetStats returns compare mode counters
+func (cp *Comparer) GetStats() CompareStats {
+	cp.mu.Lock()
+	pending := len(cp.order)
+	cp.mu.Unlock()
+	return CompareStats{
+		Started:   cp.started.Load(),
+		Completed: cp.completed.Load(),
+		Failed:    cp.failed.Load(),
+		Dropped:   cp.dropped.Load(),
+		Pending:   pending,
+	}
+}
+
+// SubjectRecords returns an identity's finished comparisons, oldest first
+func (cp *Comparer) SubjectRecords(subject DataSubject) []ComparisonRecord {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	var records []ComparisonRecord
+	for _, id := range cp.order {
+		if record := cp.pending[id]; subject.covers(record.Owner) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// DeleteSubject removes an identity's comparisons, finished or in flight,
+// returning how many were removed. Queued challengers of erased comparisons
+// are not sent.
+func (cp *Comparer) DeleteSubject(subject DataSubject) int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	deleted := 0
+	for c := range cp.active {
+		if !subject.covers(c.record.Owner) {
+			continue
+		}
+		c.mu.Lock()
+		c.erased = true
+		c.messages = nil
+		c.mu.Unlock()
+		delete(cp.active, c)
+		deleted++
+	}
+	order := cp.order[:0]
+	for _, id := range cp.order {
+		if subject.covers(cp.pending[id].Owner) {
+			delete(cp.pending, id)
+			deleted++
+			continue
+		}
+		order = append(order, id)
+	}
+	cp.order = order
+	return deleted
+}
+
+// challengerPermitted reports whether a routed request may also be sent to
+// challenger: like the routed candidates, it must pass the caller's policy
+// (tightened by session rules and cost anomalies), the cluster's
+// constraints and the BYOK provider scope
+func (p *Plugin) challengerPermitted(response *RouterResponse, challenger string) bool {
+	if challenger == "" {
+		return false
+	}
+
+	policy := policyFor(response.AuthInfo)
+	if p.sessions != nil && response.SessionID != "" {
+		policy = policy.Restrict(p.sessions.PolicyFor(response.SessionID))
+	}
+	if response.CostAnomaly {
+		policy = policy.Restrict(&RoutingPolicy{MaxBucket: BucketCheap})
+	}
+	if policy != nil && policy.MaxBucket != "" && !p.withinBucket(challenger, policy) {
+		return false
+	}
+
+	candidates := policy.FilterCandidates([]string{challenger})
+	candidates = router.Constrain(candidates, &response.Features, p.currentArtifact)
+	if policy != nil {
+		candidates = router.MeetQuality(candidates, &response.Features, p.currentArtifact, policy.MinQuality)
+	}
+	if scope := p.byokScopeFor(response.AuthInfo); scope != nil {
+		candidates = scope.filter(p, candidates)
+	}
+	return len(candidates) > 0
+}
+
+// withinBucket reports whether model is a candidate of a bucket the policy
+// does not cap
+func (p *Plugin) withinBucket(model string, policy *RoutingPolicy) bool {
+	for _, bucket := range []Bucket{BucketCheap, BucketMid, BucketHard} {
+		if policy.CapBucket(bucket) != bucket {
+			break
+		}
+		candidates, _ := p.bucketCandidates(string(bucket))
+		if slices.Contains(candidates, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPChallengerClient answers requests through an OpenAI-compatible
+// endpoint
+type HTTPChallengerClient struct {
+	endpoint   string
+	apiKey     auth.Secret
+	httpClient *http.Client
+}
+
+// NewHTTPChallengerClient creates a client from config
+func NewHTTPChallengerClient(config CompareConfig) (*HTTPChallengerClient, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("compare mode requires an endpoint")
+	}
+	config = config.withDefaults()
+	apiKey := config.APIKey
+	if config.APIKeyEnv != "" {
+		apiKey = os.Getenv(config.APIKeyEnv)
+	}
+
+	return &HTTPChallengerClient{
+		endpoint: config.Endpoint,
+		apiKey:   auth.NewSecret(apiKey),
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}, nil
+}
+
+// Complete sends messages to model and returns its answer
+func (hc *HTTPChallengerClient) Complete(ctx context.Context, model string, messages []ChatMessage) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hc.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hc.apiKey.IsSet() {
+		req.Header.Set("Authorization", "Bearer "+hc.apiKey.Reveal())
+	}
+
+	resp, err := hc.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("challenger returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("invalid challenger response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("challenger returned no choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChallenger answers every request with a fixed output
+type fakeChallenger struct {
+	output string
+	err    error
+	calls  atomic.Int64
+}
+
+func (c *fakeChallenger) Complete(ctx context.Context, model string, messages []ChatMessage) (string, error) {
+	c.calls.Add(1)
+	return c.output, c.err
+}
+
+func exportComparisons(t *testing.T, comparer *Comparer) []ComparisonRecord {
+	var buf bytes.Buffer
+	require.NoError(t, comparer.Export(&buf))
+	var records []ComparisonRecord
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record ComparisonRecord
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestComparer(t *testing.T) {
+	decision := RouterDecision{Model: "openai/gpt-4o", Fallbacks: []string{"anthropic/claude-3.5-sonnet"}}
+
+	t.Run("should pair the primary and challenger outputs", func(t *testing.T) {
+		comparer, err := NewComparer(CompareConfig{}, &fakeChallenger{output: "Paris."}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+		comparer.Start()
+		defer comparer.Stop()
+
+		c := comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 2, nil, "user: capital of France?")
+		require.NotNil(t, c)
+		comparer.Finish(c, "The capital is Paris.", true)
+		require.Eventually(t, func() bool { return comparer.GetStats().Completed == 1 }, time.Second, 5*time.Millisecond)
+
+		records := exportComparisons(t, comparer)
+		require.Len(t, records, 1)
+		assert.Equal(t, CompareOutput{Model: "openai/gpt-4o", Output: "The capital is Paris."}, records[0].Primary)
+		assert.Equal(t, CompareOutput{Model: "anthropic/claude-3.5-sonnet", Output: "Paris."}, records[0].Challenger)
+		assert.Equal(t, "user: capital of France?", records[0].Prompt)
+		assert.Empty(t, records[0].Owner, "owners are not exported")
+	})
+
+	t.Run("should drop comparisons when either side fails", func(t *testing.T) {
+		comparer, err := NewComparer(CompareConfig{}, &fakeChallenger{err: fmt.Errorf("boom")}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+		comparer.Start()
+		defer comparer.Stop()
+
+		comparer.Finish(comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 0, nil, ""), "ok", true)
+		require.Eventually(t, func() bool { return comparer.GetStats().Failed == 1 }, time.Second, 5*time.Millisecond)
+		assert.Empty(t, exportComparisons(t, comparer))
+	})
+
+	t.Run("should prefer the configured challenger and skip decisions without one", func(t *testing.T) {
+		comparer, err := NewComparer(CompareConfig{QueueSize: 1}, &fakeChallenger{}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+		assert.Empty(t, comparer.Challenger(RouterDecision{Model: "openai/gpt-4o"}))
+		assert.Nil(t, comparer.Begin(DataSubject{Tenant: "acme"}, RouterDecision{Model: "openai/gpt-4o"}, "", BucketMid, 0, nil, ""))
+
+		comparer, err = NewComparer(CompareConfig{Model: "openai/o1", QueueSize: 1}, &fakeChallenger{}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+		c := comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 0, nil, "")
+		require.NotNil(t, c)
+		assert.Equal(t, "openai/o1", c.record.Challenger.Model)
+		assert.Nil(t, comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 0, nil, ""), "queue is full")
+		assert.Equal(t, int64(1), comparer.GetStats().Dropped)
+	})
+
+	t.Run("should feed preferences into the quality store", func(t *testing.T) {
+		store := scoring.NewQualityStore(0)
+		comparer, err := NewComparer(CompareConfig{}, &fakeChallenger{output: "B"}, store)
+		require.NoError(t, err)
+		comparer.Start()
+		defer comparer.Stop()
+
+		comparer.Finish(comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 4, nil, ""), "A", true)
+		require.Eventually(t, func() bool { return comparer.GetStats().Pending == 1 }, time.Second, 5*time.Millisecond)
+		id := exportComparisons(t, comparer)[0].ID
+
+		result, err := comparer.Import(strings.NewReader(
+			`{"id":"` + id + `","preferred":"challenger"}` + "\n" +
+				`{"id":"missing","preferred":"tie"}` + "\n" +
+				`{"id":"` + id + `","preferred":"both"}` + "\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Applied)
+		assert.Equal(t, 1, result.Unknown)
+		assert.Equal(t, 1, result.Invalid)
+
+		estimate, ok := store.Estimate("anthropic/claude-3.5-sonnet", 4)
+		require.True(t, ok)
+		assert.Equal(t, 1.0, estimate.Mean)
+		estimate, ok = store.Estimate("openai/gpt-4o", 4)
+		require.True(t, ok)
+		assert.Equal(t, 0.0, estimate.Mean)
+		assert.Equal(t, 0, comparer.GetStats().Pending)
+	})
+
+	t.Run("should erase comparisons still in flight", func(t *testing.T) {
+		challenger := &fakeChallenger{output: "B"}
+		comparer, err := NewComparer(CompareConfig{}, challenger, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+
+		c := comparer.Begin(DataSubject{Tenant: "acme"}, decision, comparer.Challenger(decision), BucketMid, 0, nil, "user: secret plans")
+		require.NotNil(t, c)
+		other := comparer.Begin(DataSubject{Tenant: "globex"}, decision, comparer.Challenger(decision), BucketMid, 0, nil, "")
+		assert.Equal(t, 1, comparer.DeleteSubject(DataSubject{Tenant: "acme"}))
+
+		comparer.Start()
+		defer comparer.Stop()
+		comparer.Finish(c, "A", true)
+		comparer.Finish(other, "A", true)
+		require.Eventually(t, func() bool { return comparer.GetStats().Completed == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, int64(1), challenger.calls.Load(), "erased challengers are not sent")
+		assert.Empty(t, comparer.SubjectRecords(DataSubject{Tenant: "acme"}))
+	})
+
+	t.Run("should reject invalid sample rates", func(t *testing.T) {
+		_, err := NewComparer(CompareConfig{SampleRate: 2}, &fakeChallenger{}, scoring.NewQualityStore(0))
+		assert.Error(t, err)
+	})
+}
+
+func TestCompareMode(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Lyon."}}]}`)
+	}))
+	defer server.Close()
+
+	config := createRouterTestConfig()
+	config.Compare = CompareConfig{Enabled: true, Endpoint: server.URL, HeaderTenants: []string{"acme"}}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	content := "What is the capital of France?"
+	route := func(headers map[string][]string, authInfo *AuthInfo) context.Context {
+		ctx := context.WithValue(context.Background(), "http_headers", headers)
+		req := &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}}
+		_, _, err := plugin.applyRoutingDecision(&ctx, req, &RouterResponse{
+			Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o", Fallbacks: []string{"openai/gpt-4o-mini"}},
+			AuthInfo: authInfo,
+		})
+		require.NoError(t, err)
+		return ctx
+	}
+
+	acme := &AuthInfo{Provider: "openai", Org: "acme"}
+	flagged := map[string][]string{"X-Heimdall-Compare": {"true"}}
+
+	t.Run("should compare flagged requests only", func(t *testing.T) {
+		ctx := route(nil, acme)
+		assert.Nil(t, ctx.Value("heimdall_comparison"))
+
+		ctx = route(flagged, acme)
+		_, _, err := plugin.PostHook(&ctx, textResponse("Paris.", "stop"), nil)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return plugin.comparer.GetStats().Completed == 1 }, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, "openai/gpt-4o-mini", received["model"])
+		records := exportComparisons(t, plugin.comparer)
+		require.Len(t, records, 1)
+		assert.Equal(t, "Paris.", records[0].Primary.Output)
+		assert.Equal(t, "Lyon.", records[0].Challenger.Output)
+		assert.Equal(t, int64(1), plugin.GetMetrics()["compare"].(CompareStats).Started)
+	})
+
+	t.Run("should serve comparisons and take preferences via the admin API", func(t *testing.T) {
+		admin := httptest.NewServer(plugin.AdminHandler())
+		defer admin.Close()
+
+		resp, err := http.Get(admin.URL + "/admin/labeling/comparisons")
+		require.NoError(t, err)
+		var record ComparisonRecord
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&record))
+		resp.Body.Close()
+
+		resp, err = http.Post(admin.URL+"/admin/labeling/preferences", "application/x-ndjson",
+			strings.NewReader(`{"id":"`+record.ID+`","preferred":"primary"}`))
+		require.NoError(t, err)
+		var result LabelImportResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+		assert.Equal(t, 1, result.Applied)
+	})
+
+	t.Run("should only honour the header for trusted tenants", func(t *testing.T) {
+		ctx := route(flagged, &AuthInfo{Provider: "openai", Org: "globex"})
+		assert.Nil(t, ctx.Value("heimdall_comparison"))
+		ctx = route(flagged, nil)
+		assert.Nil(t, ctx.Value("heimdall_comparison"))
+	})
+
+	t.Run("should not compare with challengers the caller may not use", func(t *testing.T) {
+		restricted := &AuthInfo{Provider: "openai", Org: "acme", Policy: &RoutingPolicy{Candidates: []string{"openai/gpt-4o"}}}
+		ctx := route(flagged, restricted)
+		assert.Nil(t, ctx.Value("heimdall_comparison"), "the policy does not permit the challenger")
+
+		capped := &AuthInfo{Provider: "openai", Org: "acme", Policy: &RoutingPolicy{MaxBucket: BucketCheap}}
+		ctx = route(flagged, capped)
+		assert.Nil(t, ctx.Value("heimdall_comparison"), "the challenger is in no bucket within the caller's cap")
+
+		scoped := &AuthInfo{Provider: "anthropic", Org: "acme"}
+		plugin.config.BYOK.Mode = BYOKModeRestrict
+		defer func() { plugin.config.BYOK.Mode = "" }()
+		ctx = route(flagged, scoped)
+		assert.Nil(t, ctx.Value("heimdall_comparison"), "the challenger is outside the caller's BYOK provider")
+	})
+
+	t.Run("should export and erase comparisons per tenant", func(t *testing.T) {
+		ctx := route(flagged, acme)
+		_, _, err := plugin.PostHook(&ctx, textResponse("Paris.", "stop"), nil)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return plugin.comparer.GetStats().Pending == 1 }, time.Second, 5*time.Millisecond)
+
+		export, err := plugin.ExportSubject(DataSubject{Tenant: "acme"})
+		require.NoError(t, err)
+		assert.Len(t, export.Comparisons, 1)
+		erasure, err := plugin.EraseSubject(DataSubject{Tenant: "acme"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, erasure.Comparisons)
+	})
+
+	t.Run("should require an endpoint", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Compare = CompareConfig{Enabled: true}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid compare config")
+	})
+}
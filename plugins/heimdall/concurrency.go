@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ConcurrencyConfig caps concurrent requests per model. Selection skips a
+// model at its ceiling rather than queueing behind provider-side rate
+// limits. The limit is soft: requests selected concurrently may briefly
+// exceed it.
+type ConcurrencyConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Limits are per-model ceilings, taking precedence over tier limits
+	Limits map[string]int `json:"limits"`
+
+	// ProviderTiers maps provider kinds to their account tier, e.g.
+	// {"openai": "tier-3"}; TierLimits is the per-model ceiling for each tier
+	ProviderTiers map[string]string `json:"provider_tiers"`
+	TierLimits    map[string]int    `json:"tier_limits"`
+
+	// DefaultLimit applies to models without a specific or tier limit
+	// (0 = unlimited)
+	DefaultLimit int `json:"default_limit"`
+}
+
+// ConcurrencyStatus reports a model's concurrency against its ceiling
+type ConcurrencyStatus struct {
+	Model          string `json:"model"`
+	InFlight       int64  `json:"in_flight"`
+	Limit          int    `json:"limit"` // 0 = unlimited
+	Saturated      bool   `json:"saturated"`
+	SaturatedSkips int64  `json:"saturated_skips"`
+}
+
+// ConcurrencyLimiter tracks in-flight requests per model
+type ConcurrencyLimiter struct {
+	config   ConcurrencyConfig
+	inFlight map[string]int64
+	skips    map[string]int64
+	mu       sync.Mutex
+}
+
+// NewConcurrencyLimiter creates a limiter, validating its limits
+func NewConcurrencyLimiter(config ConcurrencyConfig) (*ConcurrencyLimiter, error) {
+	if config.DefaultLimit < 0 {
+		return nil, fmt.Errorf("default_limit must not be negative")
+	}
+	for model, limit := range config.Limits {
+		if limit < 0 {
+			return nil, fmt.Errorf("limit for %s must not be negative", model)
+		}
+	}
+	for tier, limit := range config.TierLimits {
+		if limit < 0 {
+			return nil, fmt.Errorf("limit for tier %s must not be negative", tier)
+		}
+	}
+	for provider, tier := range config.ProviderTiers {
+		if _, ok := config.TierLimits[tier]; !ok {
+			return nil, fmt.Errorf("provider %s has tier %s without a tier limit", provider, tier)
+		}
+	}
+
+	return &ConcurrencyLimiter{
+		config:   config,
+		inFlight: make(map[string]int64),
+		skips:    make(map[string]int64),
+	}, nil
+}
+
+// Limit returns a model's ceiling: its own limit, else its provider tier's,
+// else the default (0 = unlimited)
+func (cl *ConcurrencyLimiter) Limit(provider string, model string) int {
+	if limit, ok := cl.config.Limits[model]; ok {
+		return limit
+	}
+	if tier, ok := cl.config.ProviderTiers[provider]; ok {
+		return cl.config.TierLimits[tier]
+	}
+	return cl.config.DefaultLimit
+}
+
+// TryAdmit reports whether a model is below its ceiling, counting a
+// saturated skip when it is not
+func (cl *ConcurrencyLimiter) TryAdmit(provider string, model string) bool {
+	limit := cl.Limit(provider, model)
+	if limit == 0 {
+		return true
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.inFlight[model] < int64(limit) {
+		return true
+	}
+	cl.skips[model]++
+	return false
+}
+
+// Acquire records a request routed to the model
+func (cl *ConcurrencyLimiter) Acquire(model string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.inFlight[model]++
+}
+
+// Release records completion of a request routed to the model
+func (cl *ConcurrencyLimiter) Release(model string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.inFlight[model] > 0 {
+		cl.inFlight[model]--
+	}
+}
+
+// GetStatus reports every model with requests in flight or saturated skips
+func (cl *ConcurrencyLimiter) GetStatus(providerOf func(model string) string) []ConcurrencyStatus {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	models := make(map[string]bool)
+	for model := range cl.inFlight {
+		models[model] = true
+	}
+	for model := range cl.skips {
+		models[model] = true
+	}
+
+	status := make([]ConcurrencyStatus, 0, len(models))
+	for model := range models {
+		limit := cl.Limit(providerOf(model), model)
+		status = append(status, ConcurrencyStatus{
+			Model:          model,
+			InFlight:       cl.inFlight[model],
+			Limit:          limit,
+			Saturated:      limit > 0 && cl.inFlight[model] >= int64(limit),
+			SaturatedSkips: cl.skips[model],
+		})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Model < status[j].Model })
+	return status
+}
+
+// filterSaturatedCandidates removes models at their concurrency ceiling
+func (p *Plugin) filterSaturatedCandidates(candidates []string) []string {
+	if p.concurrency == nil {
+		return candidates
+	}
+
+	filtered := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if p.concurrency.TryAdmit(p.inferProviderKind(c), c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
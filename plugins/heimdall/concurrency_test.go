@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	config := ConcurrencyConfig{
+		Enabled:       true,
+		Limits:        map[string]int{"openai/gpt-4o": 1},
+		ProviderTiers: map[string]string{"anthropic": "tier-2"},
+		TierLimits:    map[string]int{"tier-2": 2},
+	}
+
+	t.Run("should resolve model, tier and default limits", func(t *testing.T) {
+		limiter, err := NewConcurrencyLimiter(config)
+		require.NoError(t, err)
+		assert.Equal(t, 1, limiter.Limit("openai", "openai/gpt-4o"))
+		assert.Equal(t, 2, limiter.Limit("anthropic", "anthropic/claude-3-opus"))
+		assert.Equal(t, 0, limiter.Limit("openrouter", "deepseek/deepseek-r1"))
+	})
+
+	t.Run("should refuse models at their ceiling until released", func(t *testing.T) {
+		limiter, err := NewConcurrencyLimiter(config)
+		require.NoError(t, err)
+
+		assert.True(t, limiter.TryAdmit("openai", "openai/gpt-4o"))
+		limiter.Acquire("openai/gpt-4o")
+		assert.False(t, limiter.TryAdmit("openai", "openai/gpt-4o"))
+
+		status := limiter.GetStatus(func(string) string { return "openai" })
+		require.Len(t, status, 1)
+		assert.Equal(t, ConcurrencyStatus{Model: "openai/gpt-4o", InFlight: 1, Limit: 1, Saturated: true, SaturatedSkips: 1}, status[0])
+
+		limiter.Release("openai/gpt-4o")
+		assert.True(t, limiter.TryAdmit("openai", "openai/gpt-4o"))
+	})
+
+	t.Run("should reject invalid limits", func(t *testing.T) {
+		_, err := NewConcurrencyLimiter(ConcurrencyConfig{DefaultLimit: -1})
+		assert.Error(t, err)
+		_, err = NewConcurrencyLimiter(ConcurrencyConfig{ProviderTiers: map[string]string{"openai": "tier-9"}})
+		assert.ErrorContains(t, err, "without a tier limit")
+	})
+}
+
+func TestConcurrencySelection(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Concurrency = ConcurrencyConfig{Enabled: true, DefaultLimit: 1}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	features := &RequestFeatures{ClusterID: 1, TokenCount: 500}
+
+	first, err := plugin.selectModelForBucket("hard", features)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, _, err = plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, &RouterResponse{Decision: *first})
+	require.NoError(t, err)
+
+	t.Run("should skip models at their ceiling", func(t *testing.T) {
+		second, err := plugin.selectModelForBucket("hard", features)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Model, second.Model)
+		assert.NotContains(t, second.Fallbacks, first.Model)
+	})
+
+	t.Run("should report saturation in metrics", func(t *testing.T) {
+		status := plugin.GetMetrics()["concurrency"].([]ConcurrencyStatus)
+		require.Len(t, status, 1)
+		assert.Equal(t, first.Model, status[0].Model)
+		assert.True(t, status[0].Saturated)
+		assert.Positive(t, status[0].SaturatedSkips)
+	})
+
+	t.Run("should admit the model again once its request completes", func(t *testing.T) {
+		_, _, err := plugin.PostHook(&ctx, textResponse("done", "stop"), nil)
+		require.NoError(t, err)
+
+		_, err = plugin.selectModelForBucket("hard", features)
+		require.NoError(t, err)
+		assert.False(t, plugin.GetMetrics()["concurrency"].([]ConcurrencyStatus)[0].Saturated)
+	})
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+)
+
+// ConfigSchema derives the JSON Schema of Config. The committed copy in
+// schema/config.schema.json, used by heimdallctl, must match it.
+func ConfigSchema() *schema.Schema {
+	return schema.Generate(reflect.TypeOf(Config{}), "Heimdall plugin configuration")
+}
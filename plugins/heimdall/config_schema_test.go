@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "rewrite the committed schema files")
+
+func TestConfigSchemaCurrent(t *testing.T) {
+	data, err := schema.Encode(ConfigSchema())
+	require.NoError(t, err)
+	if *updateSchema {
+		require.NoError(t, os.WriteFile("schema/config.schema.json", data, 0o644))
+		return
+	}
+	assert.Equal(t, string(data), string(schema.ConfigJSON), "run go test . -run SchemaCurrent -update-schema")
+}
+
+func TestConfigSchemaValidation(t *testing.T) {
+	t.Run("should accept an encoded config", func(t *testing.T) {
+		data, err := json.Marshal(createRouterTestConfig())
+		require.NoError(t, err)
+		violations, err := schema.ValidateConfig(data)
+		require.NoError(t, err)
+		assert.Empty(t, violations)
+	})
+
+	t.Run("should catch misspelled and mistyped settings", func(t *testing.T) {
+		violations, err := schema.ValidateConfig([]byte(`{"router": {"alpha": "high"}, "enable_cachign": true}`))
+		require.NoError(t, err)
+		assert.Equal(t, []schema.Violation{
+			{Path: "$.enable_cachign", Message: "unknown field"},
+			{Path: "$.router.alpha", Message: "expected number, got string"},
+		}, violations)
+	})
+
+	t.Run("should serve schemas and validation via the admin API", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/admin/schema/config")
+		require.NoError(t, err)
+		var served schema.Schema
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+		resp.Body.Close()
+		assert.Contains(t, served.Properties, "router")
+
+		resp, err = http.Post(server.URL+"/admin/schema/config/validate", "application/json",
+			strings.NewReader(`{"timeout": "25ms"}`))
+		require.NoError(t, err)
+		var result ConfigValidation
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.False(t, result.Valid)
+		assert.Equal(t, "$.timeout", result.Violations[0].Path)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterConstraints(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 1, TokenCount: 500}
+
+	newPlugin := func(t *testing.T) *Plugin {
+		plugin := createRouterTestPlugin(t)
+		plugin.currentArtifact.Models = map[string]ModelProfile{
+			"openai/gpt-4o":                        {ContextWindow: 128000, QualityTier: 2},
+			"anthropic/claude-3-5-sonnet-20241022": {ContextWindow: 200000, Capabilities: []string{"computer_use"}, QualityTier: 2},
+			"google/gemini-1.5-pro":                {ContextWindow: 2000000, QualityTier: 2},
+			"openai/o1":                            {ContextWindow: 200000, Capabilities: []string{"reasoning"}, QualityTier: 3},
+		}
+		plugin.currentArtifact.Constraints = map[int]ClusterConstraint{
+			1: {RequiredCapabilities: []string{"computer_use"}},
+		}
+		return plugin
+	}
+
+	t.Run("should only select and fall back to permitted models", func(t *testing.T) {
+		plugin := newPlugin(t)
+
+		decision, err := plugin.selectModelForBucket("mid", features)
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic/claude-3-5-sonnet-20241022", decision.Model)
+		assert.Empty(t, decision.Fallbacks)
+		assert.Nil(t, decision.Escalation, "no hard candidate meets the constraint")
+	})
+
+	t.Run("should not constrain other clusters", func(t *testing.T) {
+		plugin := newPlugin(t)
+
+		decision, err := plugin.selectModelForBucket("mid", &RequestFeatures{ClusterID: 0, TokenCount: 500})
+		require.NoError(t, err)
+		assert.NotEmpty(t, decision.Fallbacks)
+	})
+
+	t.Run("should fail selection when no candidate qualifies", func(t *testing.T) {
+		plugin := newPlugin(t)
+
+		_, err := plugin.selectModelForBucket("cheap", features)
+		assert.ErrorContains(t, err, "no candidates meet the constraints of cluster 1")
+		assert.Equal(t, FallbackError, fallbackReasonFor(err))
+	})
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RoutingPolicy constrains routing for a class of callers (e.g. a JWT tier or
+// organization). Zero-valued fields leave routing unconstrained.
+type RoutingPolicy struct {
+	// MaxBucket caps the bucket a request may be routed to ("cheap", "mid", "hard")
+	MaxBucket Bucket `json:"max_bucket,omitempty"`
+
+	// Candidates restricts selection (and fallbacks) to these models
+	Candidates []string `json:"candidates,omitempty"`
+
+	// MaxPrice caps the provider max price budget for the request
+	MaxPrice int `json:"max_price,omitempty"`
+
+	// NoSemanticCache keeps requests from being answered with cached
+	// responses to similar (not identical) prompts
+	NoSemanticCache bool `json:"no_semantic_cache,omitempty"`
+
+	// MinQuality restricts selection to models whose artifact quality on
+	// the request's cluster is at least this (0-1)
+	MinQuality float64 `json:"min_quality,omitempty"`
+}
+
+// bucketRank orders buckets from cheapest to most capable
+var bucketRank = map[Bucket]int{
+	BucketCheap: 0,
+	BucketMid:   1,
+	BucketHard:  2,
+}
+
+// Merge returns a copy of the policy with non-zero fields of override applied
+func (rp *RoutingPolicy) Merge(override *RoutingPolicy) *RoutingPolicy {
+	if rp == nil {
+		return override
+	}
+	if override == nil {
+		return rp
+	}
+
+	merged := *rp
+	if override.MaxBucket != "" {
+		merged.MaxBucket = override.MaxBucket
+	}
+	if len(override.Candidates) > 0 {
+		merged.Candidates = override.Candidates
+	}
+	if override.MaxPrice > 0 {
+		merged.MaxPrice = override.MaxPrice
+	}
+	if override.NoSemanticCache {
+		merged.NoSemanticCache = true
+	}
+	if override.MinQuality > 0 {
+		merged.MinQuality = override.MinQuality
+	}
+	return &merged
+}
+
+// Restrict returns a copy of the policy tightened by other: the lower
+// bucket and price caps, the higher quality floor and the candidates both
+// permit. Unlike Merge, other can never loosen the policy.
+func (rp *RoutingPolicy) Restrict(other *RoutingPolicy) *RoutingPolicy {
+	if rp == nil {
+		return other
+	}
+	if other == nil {
+		return rp
+	}
+
+	restricted := *rp
+	if other.MaxBucket != "" {
+		restricted.MaxBucket = rp.CapBucket(other.MaxBucket)
+	}
+	if len(other.Candidates) > 0 {
+		if len(rp.Candidates) == 0 {
+			restricted.Candidates = other.Candidates
+		} else {
+			restricted.Candidates = other.FilterCandidates(rp.Candidates)
+			if len(restricted.Candidates) == 0 {
+				// Nothing both permit; an empty list would permit everything
+				restricted.Candidates = []string{""}
+			}
+		}
+	}
+	if other.MaxPrice > 0 && (rp.MaxPrice == 0 || other.MaxPrice < rp.MaxPrice) {
+		restricted.MaxPrice = other.MaxPrice
+	}
+	restricted.NoSemanticCache = rp.NoSemanticCache || other.NoSemanticCache
+	restricted.MinQuality = max(rp.MinQuality, other.MinQuality)
+	return &restricted
+}
+
+// Validate checks the policy for unknown buckets and quality floors
+// outside 0-1
+func (rp *RoutingPolicy) Validate() error {
+	if rp == nil {
+		return nil
+	}
+	if rp.MinQuality < 0 || rp.MinQuality > 1 {
+		return fmt.Errorf("min_quality must be within [0, 1], got %v", rp.MinQuality)
+	}
+	if rp.MaxBucket == "" {
+		return nil
+	}
+	if _, ok := bucketRank[rp.MaxBucket]; !ok {
+		return fmt.Errorf("unknown max_bucket %q", rp.MaxBucket)
+	}
+	return nil
+}
+
+// CapBucket lowers the bucket to the policy's maximum
+func (rp *RoutingPolicy) CapBucket(bucket Bucket) Bucket {
+	if rp == nil || rp.MaxBucket == "" {
+		return bucket
+	}
+	if rank, ok := bucketRank[bucket]; ok && rank > bucketRank[rp.MaxBucket] {
+		return rp.MaxBucket
+	}
+	return bucket
+}
+
+// AllowsSemanticCache reports whether similar-prompt cache hits may be served
+func (rp *RoutingPolicy) AllowsSemanticCache() bool {
+	return rp == nil || !rp.NoSemanticCache
+}
+
+// Allows reports whether the policy permits a model
+func (rp *RoutingPolicy) Allows(model string) bool {
+	if rp == nil || len(rp.Candidates) == 0 {
+		return true
+	}
+	return slices.Contains(rp.Candidates, model)
+}
+
+// FilterCandidates keeps only models permitted by the policy
+func (rp *RoutingPolicy) FilterCandidates(candidates []string) []string {
+	if rp == nil || len(rp.Candidates) == 0 {
+		return candidates
+	}
+	var filtered []string
+	for _, c := range candidates {
+		if rp.Allows(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,241 @@
+// Package core holds the data model shared by Heimdall's routing stages:
+// requests, extracted features, bucket probabilities and routing artifacts.
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RouterRequest represents internal routing request
+type RouterRequest struct {
+	URL     string              `json:"url"`
+	Method  string              `json:"method"`
+	Headers map[string][]string `json:"headers"`
+	Body    *RequestBody        `json:"body,omitempty"`
+
+	// RawBody is the original request body, used to verify body signatures
+	RawBody []byte `json:"-"`
+
+	// FeatureOverrides are features computed upstream, if any
+	FeatureOverrides *FeatureOverrides `json:"-"`
+}
+
+type RequestBody struct {
+	Messages []ChatMessage          `json:"messages"`
+	Model    string                 `json:"model,omitempty"`
+	Stream   bool                   `json:"stream,omitempty"`
+	Params   map[string]interface{} `json:"-"` // Additional params
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// NonText marks messages carrying content other than text, such as
+	// images, tool calls or tool results
+	NonText bool `json:"-"`
+}
+
+// UnmarshalJSON accepts content as a string or, as OpenAI also allows, an
+// array of content parts, whose text parts are joined
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCalls  json.RawMessage `json:"tool_calls"`
+		ToolCallID string          `json:"tool_call_id"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Role, m.Content = raw.Role, ""
+	toolCalls := strings.TrimSpace(string(raw.ToolCalls))
+	m.NonText = raw.ToolCallID != "" || (toolCalls != "" && toolCalls != "null" && toolCalls != "[]")
+
+	content := strings.TrimSpace(string(raw.Content))
+	switch {
+	case content == "" || content == "null":
+	case content[0] == '"':
+		return json.Unmarshal(raw.Content, &m.Content)
+	case content[0] == '[':
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(raw.Content, &parts); err != nil {
+			return err
+		}
+		var texts []string
+		for _, part := range parts {
+			if part.Type == "text" {
+				texts = append(texts, part.Text)
+			} else {
+				m.NonText = true
+			}
+		}
+		m.Content = strings.Join(texts, "\n")
+	default:
+		return fmt.Errorf("message content must be a string or an array of parts")
+	}
+	return nil
+}
+
+// Bucket represents the bucket type
+type Bucket string
+
+const (
+	BucketCheap Bucket = "cheap"
+	BucketMid   Bucket = "mid"
+	BucketHard  Bucket = "hard"
+)
+
+type BucketThresholds struct {
+	Cheap float64 `json:"cheap"`
+	Hard  float64 `json:"hard"`
+}
+
+type PenaltyConfig struct {
+	LatencySD    float64 `json:"latency_sd"`
+	CtxOver80Pct float64 `json:"ctx_over_80pct"`
+}
+
+// NoCluster is the ClusterID of features extracted without cluster
+// assignment; per-cluster quality then falls back to the average
+const NoCluster = -1
+
+// RequestFeatures represents extracted request features
+type RequestFeatures struct {
+	Embedding       []float64 `json:"embedding"`
+	ClusterID       int       `json:"cluster_id"`
+	TopPDistances   []float64 `json:"top_p_distances"`
+	TokenCount      int       `json:"token_count"`
+	HasCode         bool      `json:"has_code"`
+	HasMath         bool      `json:"has_math"`
+	NgramEntropy    float64   `json:"ngram_entropy"`
+	ContextRatio    float64   `json:"context_ratio"`
+	UserSuccessRate *float64  `json:"user_success_rate,omitempty"`
+	AvgLatency      *float64  `json:"avg_latency,omitempty"`
+
+	// TemplateFingerprint identifies the prompt's template: its structure
+	// with variable spans masked
+	TemplateFingerprint string `json:"template_fingerprint,omitempty"`
+
+	// PromptPrefixes are the prompt's prefix hashes, when prompt-cache
+	// estimation is enabled
+	PromptPrefixes *PromptPrefixes `json:"-"`
+
+	// CacheablePrefixRatios is, by provider kind, the fraction of the
+	// prompt that repeats a prefix recently sent to that provider for the
+	// same tenant, and so is likely in its prompt cache
+	CacheablePrefixRatios map[string]float64 `json:"cacheable_prefix_ratios,omitempty"`
+}
+
+// PromptPrefixes are a running hash of a prompt sampled at fixed block
+// boundaries, so prompts sharing a prefix share its hashes
+type PromptPrefixes struct {
+	Hashes []uint64
+	Length int // prompt length in bytes
+}
+
+// FeatureOverrides pre-populates request features already computed
+// upstream, e.g. by a dedicated classification service. Nil fields are
+// extracted as usual.
+type FeatureOverrides struct {
+	// Embedding skips embedding computation
+	Embedding []float64 `json:"embedding,omitempty"`
+	// ClusterID skips the nearest-cluster search
+	ClusterID    *int     `json:"cluster_id,omitempty"`
+	TokenCount   *int     `json:"token_count,omitempty"`
+	HasCode      *bool    `json:"has_code,omitempty"`
+	HasMath      *bool    `json:"has_math,omitempty"`
+	NgramEntropy *float64 `json:"ngram_entropy,omitempty"`
+}
+
+// BucketProbabilities represents bucket classification probabilities
+type BucketProbabilities struct {
+	Cheap float64 `json:"cheap"`
+	Mid   float64 `json:"mid"`
+	Hard  float64 `json:"hard"`
+}
+
+// AvengersArtifact represents the ML artifact for routing decisions
+type AvengersArtifact struct {
+	Version    string               `json:"version"`
+	Centroids  string               `json:"centroids"` // path to FAISS index
+	Alpha      float64              `json:"alpha"`
+	Thresholds BucketThresholds     `json:"thresholds"`
+	Penalties  PenaltyConfig        `json:"penalties"`
+	Qhat       map[string][]float64 `json:"qhat"` // model -> cluster quality scores
+	Chat       map[string]float64   `json:"chat"` // model -> normalized cost
+	GBDT       GBDTConfig           `json:"gbdt"`
+
+	// Pairwise is an alternative to Qhat: per-cluster win rates, converted
+	// to Qhat when the artifact is loaded
+	Pairwise *PairwisePreferences `json:"pairwise,omitempty"`
+
+	// Normalization rescales quality and cost per request before they are
+	// combined: "" (none), "minmax" or "zscore"
+	Normalization string `json:"normalization,omitempty"`
+
+	// Models describes the attributes cluster constraints are checked against
+	Models map[string]ModelProfile `json:"models,omitempty"`
+	// Constraints are hard requirements on the models a cluster may be
+	// routed to, keyed by cluster
+	Constraints map[int]ClusterConstraint `json:"constraints,omitempty"`
+}
+
+// ModelProfile describes a model's fixed attributes
+type ModelProfile struct {
+	ContextWindow int `json:"context_window,omitempty"` // tokens
+	MaxOutput     int `json:"max_output,omitempty"`     // tokens generated per request
+	// Capabilities are names such as "reasoning", "vision" or "function_calling"
+	Capabilities []string `json:"capabilities,omitempty"`
+	// QualityTier ranks models by strength; higher is stronger
+	QualityTier int `json:"quality_tier,omitempty"`
+}
+
+// ClusterConstraint is a cluster's requirement on models. A model without a
+// profile cannot satisfy a constraint.
+type ClusterConstraint struct {
+	MinContext           int      `json:"min_context,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+	MinQualityTier       int      `json:"min_quality_tier,omitempty"`
+}
+
+// Permits reports whether a model with profile meets the constraint
+func (c ClusterConstraint) Permits(profile ModelProfile) bool {
+	if profile.ContextWindow < c.MinContext || profile.QualityTier < c.MinQualityTier {
+		return false
+	}
+	for _, required := range c.RequiredCapabilities {
+		if !slices.Contains(profile.Capabilities, required) {
+			return false
+		}
+	}
+	return true
+}
+
+// PairwisePreferences are head-to-head win rates per cluster, as produced by
+// pairwise offline evaluation
+type PairwisePreferences struct {
+	// WinRates[c][a][b] is the rate at which model a beat model b on
+	// cluster c. A missing b→a rate is taken as 1 - WinRates[c][a][b].
+	WinRates []map[string]map[string]float64 `json:"win_rates"`
+}
+
+type GBDTConfig struct {
+	Framework     string                 `json:"framework"`
+	ModelPath     string                 `json:"model_path"`
+	FeatureSchema map[string]interface{} `json:"feature_schema"`
+}
+
+// ModelScore represents a model's alpha score breakdown
+type ModelScore struct {
+	Model        string  `json:"model"`
+	QualityScore float64 `json:"quality_score"`
+	CostScore    float64 `json:"cost_score"`
+	PenaltyScore float64 `json:"penalty_score"`
+	AlphaScore   float64 `json:"alpha_score"`
+}
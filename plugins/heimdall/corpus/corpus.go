@@ -0,0 +1,372 @@
+// Package corpus generates labeled synthetic prompts for validating routing
+// changes without production traffic: benchmarks, fuzzing seeds and
+// golden-decision tests. Corpora are deterministic for a seed.
+package corpus
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// Kind is the shape of a synthetic prompt
+type Kind string
+
+const (
+	// KindCode prompts ask about fenced source code
+	KindCode Kind = "code"
+	// KindMath prompts pose problems in LaTeX notation
+	KindMath Kind = "math"
+	// KindLongContext prompts ask about a document of 110k-150k tokens,
+	// beyond the mid bucket's context capacity
+	KindLongContext Kind = "long_context"
+	// KindMultilingual prompts are short questions in languages other than
+	// English
+	KindMultilingual Kind = "multilingual"
+	// KindToolUse prompts are conversations continuing after a tool call,
+	// with the tool's result
+	KindToolUse Kind = "tool_use"
+)
+
+// Kinds lists every kind, in generation order
+var Kinds = []Kind{KindCode, KindMath, KindLongContext, KindMultilingual, KindToolUse}
+
+// expectedBuckets is the ground truth bucket of each kind
+var expectedBuckets = map[Kind]core.Bucket{
+	KindCode:         core.BucketMid,
+	KindMath:         core.BucketHard,
+	KindLongContext:  core.BucketHard,
+	KindMultilingual: core.BucketCheap,
+	KindToolUse:      core.BucketMid,
+}
+
+// ExpectedBucket returns the bucket prompts of a kind should be routed to
+func ExpectedBucket(kind Kind) core.Bucket {
+	return expectedBuckets[kind]
+}
+
+// Options configures a corpus
+type Options struct {
+	// Seed selects the corpus; the same seed always generates the same
+	// prompts
+	Seed int64
+	// PerKind is the number of prompts of each kind (default 10)
+	PerKind int
+	// Kinds limits the corpus to some kinds (default all)
+	Kinds []Kind
+}
+
+// Prompt is a synthetic request with its ground truth
+type Prompt struct {
+	ID   string `json:"id"`
+	Kind Kind   `json:"kind"`
+	// Language is the BCP 47 tag of the prompt's natural language
+	Language string `json:"language"`
+
+	Messages []Message `json:"messages"`
+	// Tools are the tools offered to the model, for tool use prompts
+	Tools []Tool `json:"tools,omitempty"`
+
+	// Expected is the bucket the prompt should be routed to
+	Expected core.Bucket `json:"expected"`
+}
+
+// Message is a chat message in OpenAI's shape. The package depends on
+// nothing beyond core, so it stays cheap to import from fuzz targets;
+// callers convert messages to their request types.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the calls an assistant message makes
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID pairs a tool message with the call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a function call made by the model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool is a function offered to the model
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Generate returns a corpus, grouped by kind in the order of Kinds
+func Generate(opts Options) ([]Prompt, error) {
+	if opts.PerKind == 0 {
+		opts.PerKind = 10
+	}
+	if opts.PerKind < 0 {
+		return nil, fmt.Errorf("per_kind must be positive, got %d", opts.PerKind)
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	prompts := make([]Prompt, 0, opts.PerKind*len(kinds))
+	for _, kind := range kinds {
+		generate, ok := generators[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt kind %q", kind)
+		}
+		for i := 0; i < opts.PerKind; i++ {
+			prompt := generate(rng)
+			prompt.ID = fmt.Sprintf("%s-%03d", kind, i)
+			prompt.Kind = kind
+			prompt.Expected = expectedBuckets[kind]
+			if prompt.Language == "" {
+				prompt.Language = "en"
+			}
+			prompts = append(prompts, prompt)
+		}
+	}
+	return prompts, nil
+}
+
+// Text returns the prompt's message contents, one message per line
+func (p Prompt) Text() string {
+	parts := make([]string, 0, len(p.Messages))
+	for _, msg := range p.Messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RouterRequest returns the prompt as a routing request
+func (p Prompt) RouterRequest() *core.RouterRequest {
+	body := &core.RequestBody{}
+	for _, msg := range p.Messages {
+		body.Messages = append(body.Messages, core.ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return &core.RouterRequest{URL: "/v1/chat/completions", Method: "POST", Body: body}
+}
+
+var generators = map[Kind]func(*rand.Rand) Prompt{
+	KindCode:         generateCode,
+	KindMath:         generateMath,
+	KindLongContext:  generateLongContext,
+	KindMultilingual: generateMultilingual,
+	KindToolUse:      generateToolUse,
+}
+
+func pick(rng *rand.Rand, options []string) string {
+	return options[rng.Intn(len(options))]
+}
+
+// Code prompts: a task over one to three snippets
+
+var codeTasks = []string{
+	"Find the bug in this code and explain the fix.",
+	"Refactor this code to be easier to read without changing its behavior.",
+	"Write unit tests covering the edge cases of this code.",
+	"Why does this code fail under concurrent use, and how would you fix it?",
+	"Port this code to idiomatic Rust.",
+	"Review this code for performance problems.",
+}
+
+var codeSnippets = []struct{ lang, source string }{
+	{"python", "def merge_intervals(intervals):\n    intervals.sort()\n    merged = [intervals[0]]\n    for start, end in intervals[1:]:\n        if start <= merged[-1][1]:\n            merged[-1][1] = end\n        else:\n            merged.append([start, end])\n    return merged\n"},
+	{"python", "class LRUCache:\n    def __init__(self, capacity):\n        self.capacity = capacity\n        self.items = {}\n\n    def get(self, key):\n        return self.items.get(key)\n\n    def put(self, key, value):\n        if len(self.items) >= self.capacity:\n            self.items.pop(next(iter(self.items)))\n        self.items[key] = value\n"},
+	{"javascript", "function debounce(fn, wait) {\n  let timer = null;\n  return function (...args) {\n    clearTimeout(timer);\n    timer = setTimeout(() => fn(args), wait);\n  };\n}\n"},
+	{"typescript", "import { readFile } from \"fs/promises\";\n\nconst loadConfig = async (path: string) => {\n  const raw = await readFile(path, \"utf8\");\n  return JSON.parse(raw);\n};\n"},
+	{"go", "func (s *Store) Get(key string) (string, bool) {\n\ts.mu.RLock()\n\tvalue, ok := s.items[key]\n\ts.mu.RUnlock()\n\tif !ok {\n\t\ts.misses++\n\t}\n\treturn value, ok\n}\n"},
+	{"go", "func Retry(attempts int, fn func() error) error {\n\tvar err error\n\tfor i := 0; i < attempts; i++ {\n\t\tif err = fn(); err == nil {\n\t\t\treturn nil\n\t\t}\n\t\ttime.Sleep(time.Duration(i) * time.Second)\n\t}\n\treturn err\n}\n"},
+	{"sql", "SELECT c.name, COUNT(o.id) AS orders\nFROM customers c\nLEFT JOIN orders o ON o.customer_id = c.id\nWHERE o.created_at > NOW() - INTERVAL '30 days'\nGROUP BY c.name\nORDER BY orders DESC;\n"},
+}
+
+func generateCode(rng *rand.Rand) Prompt {
+	var b strings.Builder
+	b.WriteString(pick(rng, codeTasks))
+	for i, n := 0, 1+rng.Intn(3); i < n; i++ {
+		snippet := codeSnippets[rng.Intn(len(codeSnippets))]
+		fmt.Fprintf(&b, "\n\n```%s\n%s```", snippet.lang, snippet.source)
+	}
+	return Prompt{Messages: []Message{
+		{Role: "user", Content: b.String()},
+	}}
+}
+
+// Math prompts: a problem in LaTeX with random coefficients
+
+var mathProblems = []string{
+	"Prove by induction that $\\sum_{k=1}^{n} k^2 = \\frac{n(n+1)(2n+1)}{6}$ for all $n \\geq %d$.",
+	"Evaluate the integral $\\int_0^{%d} x^2 e^{-x} \\, dx$ and justify each step.",
+	"Find every real root of $x^3 - %dx + 2 = 0$ and prove there are no others.",
+	"Show that the matrix $A = \\begin{pmatrix} %d & 1 \\\\ 1 & 2 \\end{pmatrix}$ is positive definite and compute its eigenvalues.",
+	"A fair die is rolled $n = %d$ times. What is the probability that the sum is divisible by 3? Give an exact answer.",
+	"Compute the derivative of $f(x) = \\ln(x^2 + %d) \\cdot \\sin x$ and find its critical points on $[0, \\pi]$.",
+}
+
+var mathFollowUps = []string{
+	"",
+	" Explain your reasoning step by step.",
+	" Then generalize the result.",
+	" Check your answer numerically.",
+}
+
+func generateMath(rng *rand.Rand) Prompt {
+	problem := fmt.Sprintf(pick(rng, mathProblems), 2+rng.Intn(9)) + pick(rng, mathFollowUps)
+	return Prompt{Messages: []Message{
+		{Role: "user", Content: problem},
+	}}
+}
+
+// Long context prompts: a question about a long generated document
+
+var documentKinds = []string{"quarterly operations report", "supplier agreement", "incident review", "meeting transcript", "research survey"}
+
+var documentSubjects = []string{"the warehouse team", "the regional office", "our largest supplier", "the support desk", "the field engineers", "the finance group", "the audit committee", "the onboarding program"}
+
+var documentVerbs = []string{"reported", "reviewed", "postponed", "approved", "escalated", "summarized", "questioned", "completed"}
+
+var documentObjects = []string{"the delivery schedule", "the revised budget", "the staffing plan", "the renewal terms", "the customer complaints", "the safety checklist", "the migration timeline", "the travel policy"}
+
+var documentClauses = []string{"after a long discussion", "despite earlier concerns", "ahead of the deadline", "with minor changes", "pending further review", "at the request of management", "for the third time this year", "without objection"}
+
+var documentQuestions = []string{
+	"Summarize the main decisions in this %s.",
+	"List every commitment made in this %s, with who made it.",
+	"Which risks does this %s raise, and which are left unresolved?",
+	"Write a one page brief of this %s for a new team member.",
+}
+
+func generateLongContext(rng *rand.Rand) Prompt {
+	kind := pick(rng, documentKinds)
+	// Token counts are estimated at four characters a token
+	size := 4 * (110000 + rng.Intn(40000))
+
+	var b strings.Builder
+	b.Grow(size + 200)
+	fmt.Fprintf(&b, "Below is a %s.\n\n", kind)
+	for section := 1; b.Len() < size; section++ {
+		fmt.Fprintf(&b, "Section %d\n", section)
+		for i := 0; i < 12 && b.Len() < size; i++ {
+			fmt.Fprintf(&b, "On day %d, %s %s %s %s. ",
+				1+rng.Intn(90), pick(rng, documentSubjects), pick(rng, documentVerbs),
+				pick(rng, documentObjects), pick(rng, documentClauses))
+		}
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, pick(rng, documentQuestions), kind)
+	return Prompt{Messages: []Message{
+		{Role: "user", Content: b.String()},
+	}}
+}
+
+// Multilingual prompts: short everyday questions
+
+var multilingualPrompts = map[string][]string{
+	"es": {"¿Cuál es la capital de Australia?", "¿Me recomiendas un libro para las vacaciones?", "¿Cómo se dice gracias en japonés?", "Escribe un saludo corto para un cumpleaños."},
+	"fr": {"Quelle est la meilleure saison pour visiter Lisbonne ?", "Peux-tu traduire bonjour en allemand ?", "Donne-moi une idée de dîner rapide.", "Combien de jours y a-t-il en février ?"},
+	"de": {"Wie spät ist es jetzt in Tokio?", "Kannst du mir ein kurzes Gedicht über den Herbst schreiben?", "Was ist das höchste Gebirge Europas?", "Wie sagt man Entschuldigung auf Spanisch?"},
+	"pt": {"Qual é o maior rio do Brasil?", "Sugira um nome para um gato laranja.", "Como se faz um café coado?", "Traduza bom dia para o francês."},
+	"ja": {"東京でおすすめの公園はどこですか？", "簡単な朝ごはんのアイデアを教えてください。", "「ありがとう」を英語で何と言いますか？", "週末に読む本を一冊すすめてください。"},
+	"zh": {"北京今天的天气怎么样？", "请推荐一部适合全家看的电影。", "怎么用英语说“早上好”？", "给我一个周末放松的建议。"},
+	"hi": {"भारत की राजधानी क्या है?", "मुझे एक आसान मिठाई की विधि बताइए।", "धन्यवाद को अंग्रेज़ी में क्या कहते हैं?", "सुबह की सैर के क्या फायदे हैं?"},
+	"ar": {"ما هي عاصمة المغرب؟", "اقترح علي اسما لمقهى صغير.", "كيف أقول شكرا بالفرنسية؟", "ما هو أطول نهر في العالم؟"},
+}
+
+// multilingualLanguages orders the languages, since map order is random
+var multilingualLanguages = []string{"es", "fr", "de", "pt", "ja", "zh", "hi", "ar"}
+
+func generateMultilingual(rng *rand.Rand) Prompt {
+	language := pick(rng, multilingualLanguages)
+	return Prompt{
+		Language: language,
+		Messages: []Message{
+			{Role: "user", Content: pick(rng, multilingualPrompts[language])},
+		},
+	}
+}
+
+// Tool use prompts: a question, the model's tool call and a sizable result
+
+var toolScenarios = []struct {
+	tool, description, question, arguments string
+	result                                 func(*rand.Rand) string
+}{
+	{
+		tool:        "search_web",
+		description: "Search the web and return the top results",
+		question:    "What changed in the latest release of the city's recycling rules?",
+		arguments:   `{"query": "city recycling rules latest changes"}`,
+		result:      searchResults,
+	},
+	{
+		tool:        "query_orders",
+		description: "Look up a customer's recent orders",
+		question:    "Which of my orders from the last two months have not shipped yet?",
+		arguments:   `{"customer_id": "C-20931", "days": 60}`,
+		result:      orderRecords,
+	},
+	{
+		tool:        "read_inbox",
+		description: "Read the user's unread email",
+		question:    "Summarize my unread email and tell me what needs a reply today.",
+		arguments:   `{"folder": "inbox", "unread": true}`,
+		result:      emailDigest,
+	},
+}
+
+var searchTopics = []string{"collection days", "glass containers", "electronics drop-off", "composting", "fines for contamination", "apartment buildings", "holiday schedule"}
+
+func searchResults(rng *rand.Rand) string {
+	var results []string
+	for i, n := 0, 15+rng.Intn(15); i < n; i++ {
+		topic := pick(rng, searchTopics)
+		results = append(results, fmt.Sprintf(
+			`{"title": "Update on %s (part %d)", "url": "https://news.example.org/recycling/%d", "snippet": "The council confirmed new guidance on %s, effective from month %d. Residents should check the updated guide and contact the service desk with questions about %s."}`,
+			topic, i+1, rng.Intn(100000), topic, 1+rng.Intn(12), topic))
+	}
+	return "[" + strings.Join(results, ", ") + "]"
+}
+
+var orderStatuses = []string{"shipped", "processing", "awaiting stock", "delivered", "cancelled"}
+
+var orderItems = []string{"desk lamp", "running shoes", "coffee grinder", "backpack", "wireless headphones", "plant pot", "wool blanket"}
+
+func orderRecords(rng *rand.Rand) string {
+	var records []string
+	for i, n := 0, 30+rng.Intn(30); i < n; i++ {
+		records = append(records, fmt.Sprintf(
+			`{"order_id": "O-%05d", "item": "%s", "quantity": %d, "status": "%s", "placed_days_ago": %d, "carrier_note": "No tracking events recorded yet for this parcel."}`,
+			rng.Intn(100000), pick(rng, orderItems), 1+rng.Intn(4), pick(rng, orderStatuses), rng.Intn(60)))
+	}
+	return "[" + strings.Join(records, ", ") + "]"
+}
+
+var emailSenders = []string{"Priya from accounting", "the building manager", "a recruiter", "your team lead", "the travel desk", "a customer"}
+
+var emailSubjects = []string{"invoice approval", "fire drill on Thursday", "interview availability", "sprint planning notes", "flight change", "late delivery"}
+
+func emailDigest(rng *rand.Rand) string {
+	var emails []string
+	for i, n := 0, 16+rng.Intn(10); i < n; i++ {
+		emails = append(emails, fmt.Sprintf(
+			`{"from": "%s", "subject": "%s", "received_hours_ago": %d, "body": "Hi, following up on the %s. Could you take a look when you have a moment and let me know how you would like to proceed? Thanks in advance."}`,
+			pick(rng, emailSenders), pick(rng, emailSubjects), rng.Intn(48), pick(rng, emailSubjects)))
+	}
+	return "[" + strings.Join(emails, ", ") + "]"
+}
+
+func generateToolUse(rng *rand.Rand) Prompt {
+	scenario := toolScenarios[rng.Intn(len(toolScenarios))]
+	callID := fmt.Sprintf("call_%06d", rng.Intn(1000000))
+	return Prompt{
+		Messages: []Message{
+			{Role: "user", Content: scenario.question},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: callID, Name: scenario.tool, Arguments: scenario.arguments}}},
+			{Role: "tool", Content: scenario.result(rng), ToolCallID: callID},
+		},
+		Tools: []Tool{{Name: scenario.tool, Description: scenario.description}},
+	}
+}
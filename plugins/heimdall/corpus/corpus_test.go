@@ -0,0 +1,67 @@
+package corpus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("should generate the same corpus for a seed", func(t *testing.T) {
+		first, err := Generate(Options{Seed: 7, PerKind: 3})
+		require.NoError(t, err)
+		second, err := Generate(Options{Seed: 7, PerKind: 3})
+		require.NoError(t, err)
+		other, err := Generate(Options{Seed: 8, PerKind: 3})
+		require.NoError(t, err)
+
+		require.Len(t, first, 3*len(Kinds))
+		for i := range first {
+			assert.Equal(t, first[i].ID, second[i].ID)
+			assert.Equal(t, first[i].Text(), second[i].Text())
+		}
+		assert.NotEqual(t, first[0].Text()+first[3].Text(), other[0].Text()+other[3].Text())
+	})
+
+	t.Run("should label prompts with their kind's bucket", func(t *testing.T) {
+		prompts, err := Generate(Options{PerKind: 2, Kinds: []Kind{KindMultilingual, KindToolUse}})
+		require.NoError(t, err)
+		require.Len(t, prompts, 4)
+
+		assert.Equal(t, "multilingual-000", prompts[0].ID)
+		assert.Equal(t, core.BucketCheap, prompts[0].Expected)
+		assert.NotEqual(t, "en", prompts[0].Language)
+
+		toolUse := prompts[2]
+		assert.Equal(t, core.BucketMid, toolUse.Expected)
+		assert.Equal(t, "en", toolUse.Language)
+		assert.Len(t, toolUse.Tools, 1)
+		require.Len(t, toolUse.Messages, 3)
+		require.Len(t, toolUse.Messages[1].ToolCalls, 1)
+		assert.Equal(t, toolUse.Tools[0].Name, toolUse.Messages[1].ToolCalls[0].Name)
+		assert.Equal(t, toolUse.Messages[1].ToolCalls[0].ID, toolUse.Messages[2].ToolCallID, "results are paired with their calls")
+	})
+
+	t.Run("should generate prompts whose lexical features match their label", func(t *testing.T) {
+		prompts, err := Generate(Options{Seed: 42, PerKind: 5})
+		require.NoError(t, err)
+
+		extractor := features.NewFeatureExtractor()
+		for _, prompt := range prompts {
+			extracted, err := extractor.ExtractLight(prompt.RouterRequest())
+			require.NoError(t, err)
+			probs := router.HeuristicProbabilities(extracted)
+			assert.Equal(t, prompt.Expected, router.SelectBucket(probs, extracted, core.BucketThresholds{Cheap: 0.5, Hard: 0.5}, nil), prompt.ID)
+		}
+	})
+
+	t.Run("should reject unknown kinds", func(t *testing.T) {
+		_, err := Generate(Options{Kinds: []Kind{"poetry"}})
+		assert.ErrorContains(t, err, `unknown prompt kind "poetry"`)
+	})
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DashboardConfig configures the Grafana dashboard served at
+// /admin/dashboards/grafana. The dashboard queries the metric names in
+// MetricFamilies, so it works as soon as a Prometheus exporter publishes
+// them.
+type DashboardConfig struct {
+	// Title of the dashboard (default "Heimdall Router")
+	Title string `json:"title"`
+	// Datasource is the Prometheus datasource UID selected on import; empty
+	// leaves the choice to the dashboard's datasource variable
+	Datasource string `json:"datasource"`
+}
+
+// MetricFamily names one Prometheus metric and its labels
+type MetricFamily struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"` // counter, gauge or histogram
+	Help   string   `json:"help"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Metric names shared by exporters and the generated dashboard
+const (
+	MetricRequests        = "heimdall_requests_total"
+	MetricErrors          = "heimdall_errors_total"
+	MetricRequestDuration = "heimdall_request_duration_seconds"
+	MetricInFlight        = "heimdall_in_flight_requests"
+	MetricSpend           = "heimdall_spend_usd_total"
+	MetricFallbacks       = "heimdall_fallbacks_total"
+	MetricStreams         = "heimdall_streams_total"
+	MetricCacheHits       = "heimdall_cache_hits_total"
+	MetricLoadShedding    = "heimdall_load_shedding"
+)
+
+// MetricFamilies is the naming scheme exporters should publish. Bucket and
+// model labels take the values in routing decisions; provider is the
+// provider kind.
+var MetricFamilies = []MetricFamily{
+	{MetricRequests, "counter", "Routed requests", []string{"bucket", "model", "provider"}},
+	{MetricErrors, "counter", "Requests that failed at the provider", []string{"bucket", "model", "provider"}},
+	{MetricRequestDuration, "histogram", "Provider latency of routed requests", []string{"bucket", "model"}},
+	{MetricInFlight, "gauge", "Requests awaiting a response", []string{"bucket", "provider"}},
+	{MetricSpend, "counter", "Estimated spend in US dollars", []string{"bucket", "model"}},
+	{MetricFallbacks, "counter", "Decisions that fell back, by reason", []string{"reason"}},
+	{MetricStreams, "counter", "Streamed responses by how they ended", []string{"model", "outcome"}},
+	{MetricCacheHits, "counter", "Requests served from the decision cache", nil},
+	{MetricLoadShedding, "gauge", "1 while decisions take the heuristic triage path", nil},
+}
+
+// GrafanaDashboard is a dashboard definition in Grafana's JSON model, ready
+// to import
+type GrafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          grafanaTimeRange  `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []GrafanaPanel    `json:"panels"`
+}
+
+// GrafanaPanel is a row or time series panel
+type GrafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	GridPos     grafanaGridPos      `json:"gridPos"`
+	Datasource  *grafanaDatasource  `json:"datasource,omitempty"`
+	FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+	Targets     []GrafanaTarget     `json:"targets,omitempty"`
+}
+
+// GrafanaTarget is one PromQL query of a panel
+type GrafanaTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name    string          `json:"name"`
+	Label   string          `json:"label"`
+	Type    string          `json:"type"`
+	Query   string          `json:"query"`
+	Current *grafanaCurrent `json:"current,omitempty"`
+}
+
+type grafanaCurrent struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults grafanaFieldDefaults `json:"defaults"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+// GrafanaDashboard builds a dashboard for the active config: an overview
+// row across buckets, then a row per bucket with request rate, spend and
+// latency per configured candidate and concurrency per provider
+func (p *Plugin) GrafanaDashboard() GrafanaDashboard {
+	return buildGrafanaDashboard(p.config.Dashboards, map[Bucket][]string{
+		BucketCheap: p.config.Router.CheapCandidates,
+		BucketMid:   p.config.Router.MidCandidates,
+		BucketHard:  p.config.Router.HardCandidates,
+	})
+}
+
+func buildGrafanaDashboard(config DashboardConfig, candidates map[Bucket][]string) GrafanaDashboard {
+	title := config.Title
+	if title == "" {
+		title = "Heimdall Router"
+	}
+	datasource := &grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+	variable := grafanaVariable{Name: "datasource", Label: "Datasource", Type: "datasource", Query: "prometheus"}
+	if config.Datasource != "" {
+		variable.Current = &grafanaCurrent{Text: config.Datasource, Value: config.Datasource}
+	}
+
+	layout := &dashboardLayout{datasource: datasource}
+	layout.row("Overview")
+	layout.panel("Requests by bucket", "reqps",
+		target(fmt.Sprintf("sum by (bucket) (rate(%s[$__rate_interval]))", MetricRequests), "{{bucket}}"))
+	layout.panel("Spend by bucket", "currencyUSD",
+		target(fmt.Sprintf("sum by (bucket) (increase(%s[$__range]))", MetricSpend), "{{bucket}}"))
+	layout.panel("Fallbacks by reason", "reqps",
+		target(fmt.Sprintf("sum by (reason) (rate(%s[$__rate_interval]))", MetricFallbacks), "{{reason}}"))
+
+	for _, bucket := range []Bucket{BucketCheap, BucketMid, BucketHard} {
+		selector := fmt.Sprintf("bucket=%q", bucket)
+		layout.row(fmt.Sprintf("Bucket: %s", bucket))
+
+		var requests, spend, latency []GrafanaTarget
+		for _, model := range candidates[bucket] {
+			labels := selector + ",model=" + strconv.Quote(model)
+			requests = append(requests, target(fmt.Sprintf(
+				"sum(rate(%s{%s}[$__rate_interval]))", MetricRequests, labels), model))
+			spend = append(spend, target(fmt.Sprintf(
+				"sum(increase(%s{%s}[$__range]))", MetricSpend, labels), model))
+			latency = append(latency, target(fmt.Sprintf(
+				"histogram_quantile(0.95, sum by (le) (rate(%s_bucket{%s}[$__rate_interval])))", MetricRequestDuration, labels), model))
+		}
+		layout.panel("Requests by candidate", "reqps", requests...)
+		layout.panel("Spend by candidate", "currencyUSD", spend...)
+		layout.panel("p95 latency by candidate", "s", latency...)
+		layout.panel("In-flight by provider", "short",
+			target(fmt.Sprintf("sum by (provider) (%s{%s})", MetricInFlight, selector), "{{provider}}"))
+	}
+	layout.flush()
+
+	return GrafanaDashboard{
+		UID:           "heimdall-router",
+		Title:         title,
+		Tags:          []string{"heimdall", "bifrost"},
+		SchemaVersion: 39,
+		Refresh:       "30s",
+		Time:          grafanaTimeRange{From: "now-6h", To: "now"},
+		Templating:    grafanaTemplating{List: []grafanaVariable{variable}},
+		Panels:        layout.panels,
+	}
+}
+
+// dashboardLayout places panels left to right under their row, with refIDs
+// A, B, ... per panel
+type dashboardLayout struct {
+	datasource *grafanaDatasource
+	panels     []GrafanaPanel
+	pending    []int // indexes of the current row's panels
+	y          int
+}
+
+const (
+	dashboardWidth = 24
+	panelHeight    = 8
+)
+
+func (l *dashboardLayout) row(title string) {
+	l.flush()
+	l.panels = append(l.panels, GrafanaPanel{
+		ID:      len(l.panels) + 1,
+		Type:    "row",
+		Title:   title,
+		GridPos: grafanaGridPos{H: 1, W: dashboardWidth, Y: l.y},
+	})
+	l.y++
+}
+
+func (l *dashboardLayout) panel(title, unit string, targets ...GrafanaTarget) {
+	for i := range targets {
+		targets[i].RefID = string(rune('A' + i))
+	}
+	l.pending = append(l.pending, len(l.panels))
+	l.panels = append(l.panels, GrafanaPanel{
+		ID:          len(l.panels) + 1,
+		Type:        "timeseries",
+		Title:       title,
+		Datasource:  l.datasource,
+		FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: unit}},
+		Targets:     targets,
+	})
+}
+
+// flush splits the width evenly between the current row's panels
+func (l *dashboardLayout) flush() {
+	if len(l.pending) == 0 {
+		return
+	}
+	width := dashboardWidth / len(l.pending)
+	for i, index := range l.pending {
+		l.panels[index].GridPos = grafanaGridPos{H: panelHeight, W: width, X: i * width, Y: l.y}
+	}
+	l.y += panelHeight
+	l.pending = nil
+}
+
+func target(expr, legend string) GrafanaTarget {
+	return GrafanaTarget{Expr: expr, LegendFormat: legend}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrafanaDashboard(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Router.CheapCandidates = []string{"qwen/qwen3-coder"}
+	config.Router.MidCandidates = []string{"openai/gpt-4o", "anthropic/claude-3.5-sonnet"}
+	config.Router.HardCandidates = []string{"openai/gpt-5"}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	panel := func(dashboard GrafanaDashboard, row, title string) GrafanaPanel {
+		inRow := false
+		for _, p := range dashboard.Panels {
+			if p.Type == "row" {
+				inRow = p.Title == row
+			} else if inRow && p.Title == title {
+				return p
+			}
+		}
+		t.Fatalf("no panel %q in row %q", title, row)
+		return GrafanaPanel{}
+	}
+
+	t.Run("should add a row per bucket with a query per candidate", func(t *testing.T) {
+		dashboard := plugin.GrafanaDashboard()
+		assert.Equal(t, "Heimdall Router", dashboard.Title)
+
+		requests := panel(dashboard, "Bucket: mid", "Requests by candidate")
+		require.Len(t, requests.Targets, 2)
+		assert.Equal(t, "A", requests.Targets[0].RefID)
+		assert.Equal(t, `sum(rate(heimdall_requests_total{bucket="mid",model="openai/gpt-4o"}[$__rate_interval]))`, requests.Targets[0].Expr)
+		assert.Equal(t, "anthropic/claude-3.5-sonnet", requests.Targets[1].LegendFormat)
+
+		spend := panel(dashboard, "Bucket: hard", "Spend by candidate")
+		assert.Equal(t, "currencyUSD", spend.FieldConfig.Defaults.Unit)
+		assert.Contains(t, spend.Targets[0].Expr, `heimdall_spend_usd_total{bucket="hard",model="openai/gpt-5"}`)
+
+		inFlight := panel(dashboard, "Bucket: cheap", "In-flight by provider")
+		assert.Equal(t, `sum by (provider) (heimdall_in_flight_requests{bucket="cheap"})`, inFlight.Targets[0].Expr)
+	})
+
+	t.Run("should lay panels out without overlap", func(t *testing.T) {
+		dashboard := plugin.GrafanaDashboard()
+		ids := map[int]bool{}
+		bottom := 0
+		for _, p := range dashboard.Panels {
+			assert.False(t, ids[p.ID], "panel ids are unique")
+			ids[p.ID] = true
+			assert.LessOrEqual(t, p.GridPos.X+p.GridPos.W, 24)
+			if p.GridPos.X == 0 {
+				assert.GreaterOrEqual(t, p.GridPos.Y, bottom, p.Title)
+				bottom = p.GridPos.Y + p.GridPos.H
+			}
+		}
+	})
+
+	t.Run("should only query metrics in the naming scheme", func(t *testing.T) {
+		names := make([]string, 0, len(MetricFamilies))
+		for _, family := range MetricFamilies {
+			names = append(names, family.Name)
+		}
+		for _, p := range plugin.GrafanaDashboard().Panels {
+			for _, target := range p.Targets {
+				known := false
+				for _, name := range names {
+					known = known || strings.Contains(target.Expr, name)
+				}
+				assert.True(t, known, target.Expr)
+			}
+		}
+	})
+
+	t.Run("should preselect the configured datasource", func(t *testing.T) {
+		dashboard := buildGrafanaDashboard(DashboardConfig{Title: "Prod", Datasource: "prom-prod"}, nil)
+		assert.Equal(t, "Prod", dashboard.Title)
+		require.Len(t, dashboard.Templating.List, 1)
+		assert.Equal(t, "prom-prod", dashboard.Templating.List[0].Current.Value)
+		assert.Equal(t, "${datasource}", panel(dashboard, "Overview", "Requests by bucket").Datasource.UID)
+	})
+
+	t.Run("should serve the dashboard and naming scheme via the admin API", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/admin/dashboards/grafana")
+		require.NoError(t, err)
+		var served GrafanaDashboard
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+		resp.Body.Close()
+		assert.Equal(t, "heimdall-router", served.UID)
+
+		resp, err = http.Get(server.URL + "/admin/dashboards/metrics")
+		require.NoError(t, err)
+		var families []MetricFamily
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&families))
+		resp.Body.Close()
+		assert.Equal(t, MetricFamilies, families)
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// DataSubject names the auth identity a data-subject request concerns: the
+// tenant spend is attributed to (the organization when known, otherwise the
+// credential fingerprint) and, optionally, one authenticated subject within
+// it, such as a JWT sub claim
+type DataSubject struct {
+	Tenant  string `json:"tenant"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// subjectOf returns the identity a request's stored data belongs to
+func subjectOf(authInfo *AuthInfo) DataSubject {
+	if authInfo == nil {
+		return DataSubject{}
+	}
+	return DataSubject{Tenant: tenantOf(authInfo), Subject: authInfo.Subject}
+}
+
+// covers reports whether data stored for owner falls under a request for
+// ds: the tenants match and, when ds names a subject, so do the subjects
+func (ds DataSubject) covers(owner DataSubject) bool {
+	return owner.Tenant == ds.Tenant && (ds.Subject == "" || owner.Subject == ds.Subject)
+}
+
+// SubjectExport is everything the plugin stores about one auth identity,
+// for data-subject access requests
+type SubjectExport struct {
+	DataSubject
+	ExportedAt time.Time `json:"exported_at"`
+
+	Sessions []SessionStatus `json:"sessions"`
+	// CostSeries are per-tenant aggregates, so they are exported only for
+	// requests that name no subject
+	CostSeries      []TenantCostSeries      `json:"cost_series"`
+	CachedResponses []CachedResponse        `json:"cached_responses"`
+	CachedDecisions []*RouterResponse       `json:"cached_decisions"`
+	BucketSmoothing []SmoothedProbabilities `json:"bucket_smoothing"`
+	LabelRecords    []LabelRecord           `json:"label_records"`
+	LabeledRecords  []LabeledRecord         `json:"labeled_records"`
+	Comparisons     []ComparisonRecord      `json:"comparisons"`
+
+	// Unattributed lists stored data that may concern the identity but
+	// cannot be found by it
+	Unattributed []string `json:"unattributed"`
+}
+
+// SubjectErasure counts the entries removed for one auth identity
+type SubjectErasure struct {
+	Sessions        int `json:"sessions"`
+	CostSeries      int `json:"cost_series"`
+	CachedResponses int `json:"cached_responses"`
+	CachedDecisions int `json:"cached_decisions"`
+	BucketSmoothing int `json:"bucket_smoothing"`
+	LabelRecords    int `json:"label_records"`
+	Comparisons     int `json:"comparisons"`
+}
+
+// unattributedData describes stores that are keyed by request rather than
+// identity and expire on their own
+var unattributedData = []string{
+	"loop detection state, keyed by credential and prompt hashes, expires after the loop window",
+}
+
+// ExportSubject collects the data stored for an identity. Disabled stores
+// contribute nothing.
+func (p *Plugin) ExportSubject(subject DataSubject) (*SubjectExport, error) {
+	if subject.Tenant == "" {
+		return nil, fmt.Errorf("tenant is required")
+	}
+
+	export := &SubjectExport{
+		DataSubject:  subject,
+		ExportedAt:   time.Now(),
+		Unattributed: unattributedData,
+	}
+	if p.sessions != nil {
+		export.Sessions = p.sessions.SubjectSessions(subject)
+	}
+	if p.anomalies != nil && subject.Subject == "" {
+		export.CostSeries = p.anomalies.TenantSeries(subject.Tenant)
+	}
+	if p.responses != nil {
+		export.CachedResponses = p.responses.SubjectEntries(subject)
+	}
+	export.CachedDecisions = p.subjectDecisions(subject, false)
+	if p.smoother != nil {
+		export.BucketSmoothing = p.smoother.SubjectHistory(subject)
+	}
+	if p.labeling != nil {
+		export.LabelRecords, export.LabeledRecords = p.labeling.SubjectRecords(subject)
+	}
+	if p.comparer != nil {
+		export.Comparisons = p.comparer.SubjectRecords(subject)
+	}
+	return export, nil
+}
+
+// EraseSubject deletes the data stored for an identity. Aggregates learned
+// from it, such as quality adjustments and calibration, are kept, as are a
+// tenant's cost series when only one of its subjects is erased.
+func (p *Plugin) EraseSubject(subject DataSubject) (*SubjectErasure, error) {
+	if subject.Tenant == "" {
+		return nil, fmt.Errorf("tenant is required")
+	}
+
+	erasure := &SubjectErasure{}
+	if p.sessions != nil {
+		erasure.Sessions = p.sessions.DeleteSubject(subject)
+	}
+	if p.anomalies != nil && subject.Subject == "" {
+		erasure.CostSeries = p.anomalies.DeleteTenant(subject.Tenant)
+	}
+	if p.responses != nil {
+		erasure.CachedResponses = p.responses.DeleteSubject(subject)
+	}
+	erasure.CachedDecisions = len(p.subjectDecisions(subject, true))
+	if p.smoother != nil {
+		erasure.BucketSmoothing = p.smoother.DeleteSubject(subject)
+	}
+	if p.labeling != nil {
+		erasure.LabelRecords = p.labeling.DeleteSubject(subject)
+	}
+	if p.comparer != nil {
+		erasure.Comparisons = p.comparer.DeleteSubject(subject)
+	}
+	p.logger.Printf("Erased data for tenant %s subject %q: %d sessions, %d cost series, %d cached responses, %d cached decisions, %d bucket smoothing histories, %d label records, %d comparisons",
+		subject.Tenant, subject.Subject, erasure.Sessions, erasure.CostSeries, erasure.CachedResponses, erasure.CachedDecisions,
+		erasure.BucketSmoothing, erasure.LabelRecords, erasure.Comparisons)
+	return erasure, nil
+}
+
+// subjectDecisions returns the cached routing decisions made for an
+// identity, deleting them when erase is set. Caches that cannot enumerate
+// their entries are cleared on erasure instead, as their entries cannot be
+// told apart.
+func (p *Plugin) subjectDecisions(subject DataSubject, erase bool) []*RouterResponse {
+	if p.cache == nil {
+		return nil
+	}
+	cache, ok := p.cache.(EnumerableDecisionCache)
+	if !ok {
+		if erase {
+			p.cache.Clear()
+		}
+		return nil
+	}
+
+	var keys []string
+	var decisions []*RouterResponse
+	cache.Range(func(key string, entry CacheEntry) {
+		response, err := p.openCacheEntry(key, entry)
+		if err != nil || response.AuthInfo == nil || !subject.covers(subjectOf(response.AuthInfo)) {
+			return
+		}
+		keys = append(keys, key)
+		decisions = append(decisions, response)
+	})
+	if erase {
+		for _, key := range keys {
+			cache.Delete(key)
+		}
+	}
+	return decisions
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSubjectRequests(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Audit = AuditConfig{Enabled: true}
+	config.Sessions = testSessionConfig()
+	config.CostAnomaly = CostAnomalyConfig{Enabled: true}
+	config.ResponseCache = ResponseCacheConfig{Enabled: true}
+	config.Labeling = LabelingConfig{Enabled: true, SampleRate: 1}
+	config.BucketSmoothing = BucketSmoothingConfig{Enabled: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	server := httptest.NewServer(plugin.AdminHandler())
+	defer server.Close()
+
+	usage := &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000}
+	owners := []DataSubject{{Tenant: "acme", Subject: "alice"}, {Tenant: "acme", Subject: "bob"}, {Tenant: "globex"}}
+	for _, owner := range owners {
+		id := owner.Tenant + owner.Subject
+		plugin.sessions.Record("conv-"+id, owner, "openai/o1", usage)
+		require.NoError(t, plugin.responses.Set("key-"+id, "", owner, nil, textResponse("pong", "stop")))
+		plugin.labeling.Record(owner, "openai/gpt-4o", BucketMid, RequestFeatures{}, "answer")
+		plugin.smoother.Smooth(id, owner, BucketProbabilities{Cheap: 1})
+		req := &RouterRequest{Body: &RequestBody{Messages: []ChatMessage{{Role: "user", Content: id}}}}
+		plugin.cacheResponse(req, &RouterResponse{
+			Decision: RouterDecision{Model: "openai/gpt-4o"},
+			AuthInfo: &AuthInfo{Provider: "openai", Org: owner.Tenant, Subject: owner.Subject},
+		})
+	}
+	plugin.anomalies.Record("acme", "openai/gpt-4o", usage)
+	plugin.anomalies.Record("globex", "openai/gpt-4o", usage)
+
+	t.Run("should export and erase one subject within a tenant", func(t *testing.T) {
+		alice := DataSubject{Tenant: "acme", Subject: "alice"}
+		export, err := plugin.ExportSubject(alice)
+		require.NoError(t, err)
+		require.Len(t, export.Sessions, 1)
+		assert.Equal(t, "conv-acmealice", export.Sessions[0].SessionID)
+		assert.Len(t, export.CachedResponses, 1)
+		require.Len(t, export.CachedDecisions, 1)
+		assert.Equal(t, "alice", export.CachedDecisions[0].AuthInfo.Subject)
+		assert.Len(t, export.LabelRecords, 1)
+		assert.Empty(t, export.CostSeries, "cost series are tenant aggregates")
+
+		req, _ := http.NewRequest("DELETE", server.URL+"/admin/subjects/acme?subject=alice", nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		var erasure SubjectErasure
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&erasure))
+		resp.Body.Close()
+		assert.Equal(t, SubjectErasure{Sessions: 1, CachedResponses: 1, CachedDecisions: 1, BucketSmoothing: 1, LabelRecords: 1}, erasure)
+
+		export, err = plugin.ExportSubject(DataSubject{Tenant: "acme"})
+		require.NoError(t, err)
+		require.Len(t, export.Sessions, 1, "bob's data is kept")
+		assert.Equal(t, "conv-acmebob", export.Sessions[0].SessionID)
+		assert.Len(t, export.CachedDecisions, 1)
+		assert.Equal(t, 2, plugin.cache.Len())
+
+		entries := plugin.audit.Entries(0, 0)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "acme/alice", entries[0].Target)
+	})
+
+	t.Run("should export only the tenant's data", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/admin/subjects/acme")
+		require.NoError(t, err)
+		var export SubjectExport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&export))
+		resp.Body.Close()
+
+		assert.Equal(t, "acme", export.Tenant)
+		require.Len(t, export.Sessions, 1)
+		assert.Equal(t, "conv-acmebob", export.Sessions[0].SessionID)
+		require.Len(t, export.CostSeries, 1)
+		assert.Equal(t, "openai/gpt-4o", export.CostSeries[0].Model)
+		assert.Len(t, export.CostSeries[0].RecentCosts, 1)
+		require.Len(t, export.CachedResponses, 1)
+		assert.Equal(t, "pong", messageText(export.CachedResponses[0].Response.Choices[0].Message))
+		assert.Len(t, export.CachedDecisions, 1)
+		assert.Len(t, export.BucketSmoothing, 1)
+		assert.Len(t, export.LabelRecords, 1)
+		assert.NotEmpty(t, export.Unattributed)
+	})
+
+	t.Run("should erase the tenant's data and audit the counts", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", server.URL+"/admin/subjects/acme", nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		var erasure SubjectErasure
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&erasure))
+		resp.Body.Close()
+		assert.Equal(t, SubjectErasure{Sessions: 1, CostSeries: 1, CachedResponses: 1, CachedDecisions: 1, BucketSmoothing: 1, LabelRecords: 1}, erasure)
+
+		export, err := plugin.ExportSubject(DataSubject{Tenant: "acme"})
+		require.NoError(t, err)
+		assert.Empty(t, export.Sessions)
+		assert.Empty(t, export.CostSeries)
+		assert.Empty(t, export.CachedResponses)
+		assert.Empty(t, export.CachedDecisions)
+		assert.Empty(t, export.LabelRecords)
+
+		other, err := plugin.ExportSubject(DataSubject{Tenant: "globex"})
+		require.NoError(t, err)
+		assert.Len(t, other.Sessions, 1)
+		assert.Len(t, other.CachedResponses, 1)
+		assert.Len(t, other.CachedDecisions, 1)
+		assert.Equal(t, 1, plugin.labeling.Pending())
+
+		entries := plugin.audit.Entries(0, 0)
+		require.Len(t, entries, 2)
+		assert.Equal(t, AuditDataSubject, entries[len(entries)-1].Kind)
+	})
+
+	t.Run("should clear caches that cannot be enumerated", func(t *testing.T) {
+		cache := struct{ DecisionCache }{newMemoryDecisionCache()}
+		plugin, err := NewWithOptions(createRouterTestConfig(), WithCache(cache))
+		require.NoError(t, err)
+		plugin.cacheResponse(&RouterRequest{Body: &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}},
+			&RouterResponse{Decision: RouterDecision{Model: "openai/gpt-4o"}, AuthInfo: &AuthInfo{Org: "acme"}})
+
+		_, err = plugin.EraseSubject(DataSubject{Tenant: "acme"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("should require a tenant", func(t *testing.T) {
+		_, err := plugin.EraseSubject(DataSubject{Subject: "alice"})
+		assert.Error(t, err)
+	})
+}
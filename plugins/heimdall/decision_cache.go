@@ -0,0 +1,70 @@
+package main
+
+import "sync"
+
+// DecisionCache stores encoded routing decisions by request cache key.
+// Entries carry their own expiry; the plugin skips expired entries on read.
+type DecisionCache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+	Len() int
+	Clear()
+}
+
+// EnumerableDecisionCache is a DecisionCache that can visit and delete its
+// entries, so data-subject requests can find the decisions made for an
+// identity. Caches that are not enumerable are cleared on erasure.
+type EnumerableDecisionCache interface {
+	DecisionCache
+	Range(visit func(key string, entry CacheEntry))
+	Delete(key string)
+}
+
+// memoryDecisionCache is the default in-process DecisionCache
+type memoryDecisionCache struct {
+	entries map[string]CacheEntry
+	mu      sync.RWMutex
+}
+
+func newMemoryDecisionCache() *memoryDecisionCache {
+	return &memoryDecisionCache{entries: make(map[string]CacheEntry)}
+}
+
+func (c *memoryDecisionCache) Get(key string) (CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *memoryDecisionCache) Set(key string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+}
+
+func (c *memoryDecisionCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+func (c *memoryDecisionCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]CacheEntry)
+}
+
+func (c *memoryDecisionCache) Range(visit func(key string, entry CacheEntry)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key, entry := range c.entries {
+		visit(key, entry)
+	}
+}
+
+func (c *memoryDecisionCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
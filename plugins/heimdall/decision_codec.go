@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+)
+
+// Router response schema versions:
+//
+//	1 - unversioned records written before schema_version existed
+//	2 - adds schema_version; empty fallbacks/params are always arrays/objects
+const RouterSchemaVersion = 2
+
+// routerSchemaMigrations upgrade a raw record from version N to N+1
+var routerSchemaMigrations = map[int]func(record map[string]interface{}){
+	1: migrateRouterResponseV1,
+}
+
+// DecisionSchema derives the JSON Schema of encoded router responses. The
+// committed copy in schema/decision.schema.json is the documented contract
+// for SDKs and must match it.
+func DecisionSchema() *schema.Schema {
+	return schema.Generate(reflect.TypeOf(RouterResponse{}), "Heimdall routing decision")
+}
+
+// EncodeRouterResponse serializes a response stamped with the current schema
+// version. Auth tokens are Secrets, so records carry only their fingerprint.
+func EncodeRouterResponse(response *RouterResponse) ([]byte, error) {
+	stamped := *response
+	stamped.SchemaVersion = RouterSchemaVersion
+	return json.Marshal(&stamped)
+}
+
+// DecodeRouterResponse deserializes a response written by any schema version.
+// Older records are migrated forward; newer records are decoded leniently,
+// ignoring fields this version does not know.
+func DecodeRouterResponse(data []byte) (*RouterResponse, error) {
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode router response: %w", err)
+	}
+
+	version := 1
+	if v, ok := record["schema_version"].(float64); ok {
+		version = int(v)
+	}
+
+	if version > RouterSchemaVersion {
+		log.Printf("Decoding router response with newer schema version %d (current %d)", version, RouterSchemaVersion)
+	}
+	for ; version < RouterSchemaVersion; version++ {
+		if migrate, ok := routerSchemaMigrations[version]; ok {
+			migrate(record)
+		}
+	}
+
+	migrated, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode migrated response: %w", err)
+	}
+
+	var response RouterResponse
+	if err := json.Unmarshal(migrated, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode router response: %w", err)
+	}
+	response.SchemaVersion = RouterSchemaVersion
+	return &response, nil
+}
+
+// migrateRouterResponseV1 normalizes null fallbacks and params that
+// unversioned records could contain
+func migrateRouterResponseV1(record map[string]interface{}) {
+	decision, ok := record["decision"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if decision["fallbacks"] == nil {
+		decision["fallbacks"] = []interface{}{}
+	}
+	if decision["params"] == nil {
+		decision["params"] = map[string]interface{}{}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterResponseCodec(t *testing.T) {
+	response := &RouterResponse{
+		Decision: RouterDecision{
+			Kind:      "openai",
+			Model:     "openai/gpt-4o",
+			Params:    map[string]interface{}{"reasoning_effort": "medium"},
+			Fallbacks: []string{"anthropic/claude-3-opus"},
+			ProviderHints: map[string]ProviderHint{
+				"openai": {TimeoutMs: 60000, MaxRetries: 1},
+			},
+		},
+		Bucket:         BucketMid,
+		FallbackReason: "error_fallback",
+	}
+
+	t.Run("should stamp and round-trip the current version", func(t *testing.T) {
+		data, err := EncodeRouterResponse(response)
+		require.NoError(t, err)
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &raw))
+		assert.Equal(t, float64(RouterSchemaVersion), raw["schema_version"])
+
+		decoded, err := DecodeRouterResponse(data)
+		require.NoError(t, err)
+		assert.Equal(t, response.Decision, decoded.Decision)
+		assert.Equal(t, response.Bucket, decoded.Bucket)
+		assert.Equal(t, RouterSchemaVersion, decoded.SchemaVersion)
+		assert.Zero(t, response.SchemaVersion, "encoding should not mutate the input")
+	})
+
+	t.Run("should migrate unversioned records", func(t *testing.T) {
+		legacy := `{"decision":{"kind":"openai","model":"openai/gpt-4o","params":null,"fallbacks":null},"bucket":"cheap"}`
+
+		decoded, err := DecodeRouterResponse([]byte(legacy))
+		require.NoError(t, err)
+		assert.Equal(t, "openai/gpt-4o", decoded.Decision.Model)
+		assert.NotNil(t, decoded.Decision.Fallbacks)
+		assert.NotNil(t, decoded.Decision.Params)
+		assert.Equal(t, BucketCheap, decoded.Bucket)
+	})
+
+	t.Run("should decode newer records leniently", func(t *testing.T) {
+		newer := `{"schema_version":99,"decision":{"kind":"google","model":"google/gemini-1.5-pro","future_field":true},"bucket":"hard","future":{"x":1}}`
+
+		decoded, err := DecodeRouterResponse([]byte(newer))
+		require.NoError(t, err)
+		assert.Equal(t, "google/gemini-1.5-pro", decoded.Decision.Model)
+		assert.Equal(t, BucketHard, decoded.Bucket)
+	})
+
+	t.Run("should reject malformed data", func(t *testing.T) {
+		_, err := DecodeRouterResponse([]byte("not json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("should round-trip decisions through the cache", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		req := &RouterRequest{Method: "POST", Body: &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}}
+
+		plugin.cacheResponse(req, response)
+		cached := plugin.getCachedResponse(req)
+		require.NotNil(t, cached)
+		assert.Equal(t, response.Decision, cached.Decision)
+	})
+
+	t.Run("should keep in-process entries typed and unshared", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		req := &RouterRequest{Method: "POST", Body: &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "typed"}}}}
+		live := *response
+		live.AuthInfo = &AuthInfo{Provider: "openai", Token: auth.NewSecret("sk-client-secret")}
+
+		plugin.cacheResponse(req, &live)
+		entry, ok := plugin.cache.Get(plugin.getCacheKey(req))
+		require.True(t, ok)
+		assert.Nil(t, entry.Data)
+		require.NotNil(t, entry.Response)
+		assert.Empty(t, entry.Response.AuthInfo.Token.Reveal(), "only the token fingerprint is cached")
+		assert.Equal(t, "sk-client-secret", live.AuthInfo.Token.Reveal())
+
+		cached := plugin.getCachedResponse(req)
+		require.NotNil(t, cached)
+		cached.Decision.Params["reasoning_effort"] = "high"
+		cached.Decision.Fallbacks[0] = "openai/gpt-4o-mini"
+		again := plugin.getCachedResponse(req)
+		assert.Equal(t, response.Decision, again.Decision)
+	})
+}
+
+func TestDecisionSchemaCurrent(t *testing.T) {
+	data, err := schema.Encode(DecisionSchema())
+	require.NoError(t, err)
+	if *updateSchema {
+		require.NoError(t, os.WriteFile("schema/decision.schema.json", data, 0o644))
+		return
+	}
+	assert.Equal(t, string(data), string(schema.DecisionJSON), "run go test . -run SchemaCurrent -update-schema")
+}
+
+func TestDecisionSchemaValidation(t *testing.T) {
+	data, err := EncodeRouterResponse(&RouterResponse{
+		Decision:    RouterDecision{Kind: "openai", Model: "openai/gpt-4o", Params: map[string]interface{}{}, Fallbacks: []string{}},
+		Bucket:      BucketMid,
+		AuthInfo:    &AuthInfo{Provider: "openai", Type: "bearer"},
+		Explanation: &DecisionExplanation{ArtifactVersion: "v1", Alpha: 0.7, Scores: []ModelScore{{Model: "openai/gpt-4o", AlphaScore: 0.8}}},
+	})
+	require.NoError(t, err)
+	violations, err := schema.Validate(schema.Decision(), data)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
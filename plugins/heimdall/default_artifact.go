@@ -0,0 +1,79 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+)
+
+// defaultArtifactVersion identifies the built-in default artifact
+const defaultArtifactVersion = "builtin-default"
+
+// unpricedModelCost is the normalized cost of candidates missing from the
+// built-in pricing snapshot, so priced models are preferred over them
+const unpricedModelCost = 1.0
+
+//go:embed default_artifact.json
+var defaultArtifactJSON []byte
+
+// builtinArtifact is the embedded last-resort artifact: uniform quality,
+// costs derived from a catalog pricing snapshot and permissive thresholds
+type builtinArtifact struct {
+	AvengersArtifact
+
+	// Quality is assigned to every model
+	Quality float64 `json:"quality"`
+
+	// Pricing is a catalog snapshot from which normalized costs are derived
+	Pricing map[string]catalog.ModelPricing `json:"pricing"`
+}
+
+var builtinDefault = mustParseBuiltinArtifact(defaultArtifactJSON)
+
+func mustParseBuiltinArtifact(data []byte) builtinArtifact {
+	var artifact builtinArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		panic(fmt.Sprintf("invalid embedded default artifact: %v", err))
+	}
+	return artifact
+}
+
+// defaultArtifact builds the built-in artifact for the configured
+// candidates. It routes with degraded but sensible quality until a real
+// artifact loads: with uniform quality, the cheapest candidate of each
+// bucket wins.
+func defaultArtifact(config Config) *AvengersArtifact {
+	artifact := builtinDefault.AvengersArtifact
+	artifact.Qhat = make(map[string][]float64)
+	artifact.Chat = make(map[string]float64)
+
+	maxPrice := 0.0
+	for _, pricing := range builtinDefault.Pricing {
+		maxPrice = max(maxPrice, blendedPrice(pricing))
+	}
+
+	models := make([]string, 0, len(builtinDefault.Pricing))
+	for model := range builtinDefault.Pricing {
+		models = append(models, model)
+	}
+	models = append(models, config.Router.CheapCandidates...)
+	models = append(models, config.Router.MidCandidates...)
+	models = append(models, config.Router.HardCandidates...)
+
+	for _, model := range models {
+		artifact.Qhat[model] = []float64{builtinDefault.Quality}
+		if pricing, ok := builtinDefault.Pricing[model]; ok && maxPrice > 0 {
+			artifact.Chat[model] = blendedPrice(pricing) / maxPrice
+		} else {
+			artifact.Chat[model] = unpricedModelCost
+		}
+	}
+	return &artifact
+}
+
+// blendedPrice averages input and output prices per million tokens
+func blendedPrice(pricing catalog.ModelPricing) float64 {
+	return (pricing.InPerMillion + pricing.OutPerMillion) / 2
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+)
+
+// ErrArtifactMismatch is returned when replaying a decision made with an
+// artifact version other than the one loaded
+var ErrArtifactMismatch = errors.New("decision was made with another artifact version")
+
+// DeterministicConfig configures strict-deterministic decisions for
+// compliance replays. A deterministic decision depends only on the request
+// features, its caller, the config, the artifact version and the live
+// inputs recorded with it, so Replay can reproduce it from its decision log
+// entry. Such decisions ignore the alpha controller, online quality
+// estimates, self-hosted saturation penalties, learned token factors and
+// the scoring time budget, break α-score ties by model name and bypass the
+// decision cache.
+type DeterministicConfig struct {
+	// Enabled lets requests opt in with Header
+	Enabled bool `json:"enabled"`
+	// Header set to "true" makes a request's decision deterministic
+	// (default X-Heimdall-Deterministic)
+	Header string `json:"header"`
+	// All makes every decision deterministic
+	All bool `json:"all"`
+}
+
+// ReplayInputs are the live inputs a deterministic decision was made with,
+// recorded in its decision log entry
+type ReplayInputs struct {
+	// At is when the decision was made; windows are not re-evaluated on
+	// replay, their outcomes below are used instead
+	At              time.Time `json:"at"`
+	ArtifactVersion string    `json:"artifact_version"`
+	// Unavailable are the candidates excluded by drains, quarantine,
+	// saturation or self-hosted health at the time
+	Unavailable []string `json:"unavailable,omitempty"`
+	// SessionPolicy is the session spend rule in force, if any
+	SessionPolicy *RoutingPolicy `json:"session_policy,omitempty"`
+}
+
+// replayState carries a deterministic decision's live inputs: collected as
+// the decision is made, or the recorded ones when replaying. Methods on a
+// nil state use live inputs without recording them.
+type replayState struct {
+	inputs      ReplayInputs
+	replaying   bool
+	costAnomaly bool   // recorded cost anomaly hold, when replaying
+	admission   string // recorded admission outcome, when replaying
+	unavailable map[string]bool
+}
+
+// newReplayState starts recording a deterministic decision
+func (p *Plugin) newReplayState() *replayState {
+	return &replayState{
+		inputs:      ReplayInputs{At: p.now(), ArtifactVersion: p.currentArtifact.Version},
+		unavailable: make(map[string]bool),
+	}
+}
+
+// deterministic reports whether a request's decision must be deterministic
+func (p *Plugin) deterministic(headers map[string][]string) bool {
+	config := p.config.Deterministic
+	if config.All {
+		return true
+	}
+	if !config.Enabled {
+		return false
+	}
+	header := config.Header
+	if header == "" {
+		header = "X-Heimdall-Deterministic"
+	}
+	return strings.EqualFold(auth.HeaderValue(headers, header), "true")
+}
+
+// available filters candidates like availableCandidates, recording the
+// exclusions or, when replaying, applying the recorded ones
+func (rs *replayState) available(p *Plugin, candidates []string, features *RequestFeatures) []string {
+	if rs == nil {
+		return p.availableCandidates(candidates, features)
+	}
+	if rs.replaying {
+		var available []string
+		for _, c := range candidates {
+			if !rs.unavailable[c] {
+				available = append(available, c)
+			}
+		}
+		return available
+	}
+
+	available := p.availableCandidates(candidates, features)
+	kept := make(map[string]bool, len(available))
+	for _, c := range available {
+		kept[c] = true
+	}
+	for _, c := range candidates {
+		if !kept[c] {
+			rs.unavailable[c] = true
+		}
+	}
+	return available
+}
+
+// drained reports whether model is drained, like available
+func (rs *replayState) drained(p *Plugin, kind, model string) bool {
+	if rs == nil {
+		return p.drains.IsDrained(kind, model)
+	}
+	if rs.replaying {
+		return rs.unavailable[model]
+	}
+	drained := p.drains.IsDrained(kind, model)
+	if drained {
+		rs.unavailable[model] = true
+	}
+	return drained
+}
+
+// finish returns the inputs to record with the decision
+func (rs *replayState) finish() *ReplayInputs {
+	inputs := rs.inputs
+	inputs.Unavailable = nil
+	for model := range rs.unavailable {
+		inputs.Unavailable = append(inputs.Unavailable, model)
+	}
+	sort.Strings(inputs.Unavailable)
+	return &inputs
+}
+
+// Replay reproduces a deterministic decision from its decision log entry,
+// as written by EncodeRouterResponse or EncodeDecisionLog with full
+// features. The artifact version the decision was made with must be
+// loaded, e.g. from an archived copy via a file:// artifact URL.
+func (p *Plugin) Replay(record []byte) (*RouterResponse, error) {
+	recorded, err := DecodeRouterResponse(record)
+	if err != nil {
+		return nil, err
+	}
+	if recorded.Replay == nil {
+		return nil, fmt.Errorf("decision was not made in deterministic mode")
+	}
+	if recorded.FeaturePrivacy != "" {
+		return nil, fmt.Errorf("decision log entry has %s features, replay needs them in full", recorded.FeaturePrivacy)
+	}
+
+	p.artifactMu.RLock()
+	artifact := p.currentArtifact
+	p.artifactMu.RUnlock()
+	if artifact == nil || artifact.Version != recorded.Replay.ArtifactVersion {
+		loaded := ""
+		if artifact != nil {
+			loaded = artifact.Version
+		}
+		return nil, fmt.Errorf("%w: recorded %q, loaded %q", ErrArtifactMismatch, recorded.Replay.ArtifactVersion, loaded)
+	}
+
+	rs := &replayState{
+		inputs:      *recorded.Replay,
+		replaying:   true,
+		costAnomaly: recorded.CostAnomaly,
+		admission:   recorded.Admission,
+		unavailable: make(map[string]bool),
+	}
+	for _, model := range recorded.Replay.Unavailable {
+		rs.unavailable[model] = true
+	}
+
+	triage, err := p.router.Classify(&recorded.Features, artifact)
+	if err != nil {
+		return nil, err
+	}
+	return p.route(triage, recorded.AuthInfo, recorded.SessionID, rs)
+}
+
+// ReplayMatches reports whether a replayed decision reproduces the recorded
+// one. Provider timeout hints follow live latency and are not compared.
+func ReplayMatches(recorded, replayed *RouterResponse) (bool, error) {
+	encode := func(response *RouterResponse) ([]byte, error) {
+		stripped := *response
+		stripped.Decision.ProviderHints = nil
+		return EncodeRouterResponse(&stripped)
+	}
+	a, err := encode(recorded)
+	if err != nil {
+		return false, err
+	}
+	b, err := encode(replayed)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicDecisions(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Deterministic = DeterministicConfig{Enabled: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	plugin.now = func() time.Time { return now }
+
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "Summarize this paragraph for me."}}},
+	}
+	headers := map[string][]string{
+		"Authorization":            {"Bearer sk-test-key"},
+		"X-Heimdall-Deterministic": {"true"},
+	}
+
+	t.Run("should record replay inputs only when requested", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		require.NotNil(t, response.Replay)
+		assert.Equal(t, now, response.Replay.At)
+		assert.Equal(t, "test-1.0.0", response.Replay.ArtifactVersion)
+
+		live, err := plugin.decide(req, map[string][]string{"Authorization": {"Bearer sk-test-key"}})
+		require.NoError(t, err)
+		assert.Nil(t, live.Replay)
+	})
+
+	t.Run("should replay with the recorded live inputs", func(t *testing.T) {
+		first, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		drained := first.Decision.Model
+
+		require.NoError(t, plugin.drains.Drain(drained, "test"))
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, plugin.drains.Undrain(drained))
+		require.NoError(t, err)
+		assert.NotEqual(t, drained, response.Decision.Model)
+		assert.Contains(t, response.Replay.Unavailable, drained)
+
+		record, err := EncodeRouterResponse(response)
+		require.NoError(t, err)
+		replayed, err := plugin.Replay(record)
+		require.NoError(t, err)
+		assert.Equal(t, response.Decision.Model, replayed.Decision.Model, "the drain is replayed though lifted since")
+		assert.Equal(t, response.Bucket, replayed.Bucket)
+
+		recorded, err := DecodeRouterResponse(record)
+		require.NoError(t, err)
+		matches, err := ReplayMatches(recorded, replayed)
+		require.NoError(t, err)
+		assert.True(t, matches)
+	})
+
+	t.Run("should refuse records that cannot be replayed", func(t *testing.T) {
+		live, err := plugin.decide(req, map[string][]string{"Authorization": {"Bearer sk-test-key"}})
+		require.NoError(t, err)
+		record, err := EncodeRouterResponse(live)
+		require.NoError(t, err)
+		_, err = plugin.Replay(record)
+		assert.ErrorContains(t, err, "deterministic")
+
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		response.Replay.ArtifactVersion = "older"
+		record, err = EncodeRouterResponse(response)
+		require.NoError(t, err)
+		_, err = plugin.Replay(record)
+		assert.ErrorIs(t, err, ErrArtifactMismatch)
+
+		response.Replay.ArtifactVersion = "test-1.0.0"
+		response.FeaturePrivacy = FeatureLogCoarse
+		record, err = EncodeRouterResponse(response)
+		require.NoError(t, err)
+		_, err = plugin.Replay(record)
+		assert.ErrorContains(t, err, "coarse")
+	})
+
+	t.Run("should serve replays on the admin surface", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		record, err := EncodeRouterResponse(response)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		plugin.AdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/decisions/replay", strings.NewReader(string(record))))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"matches":true`)
+	})
+}
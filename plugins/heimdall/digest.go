@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+)
+
+// DigestConfig configures a periodic routing health digest posted to a
+// webhook, so teams can follow the router without a dashboard
+type DigestConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// WebhookURL receives each digest as JSON (required)
+	WebhookURL     string        `json:"webhook_url"`
+	WebhookTimeout time.Duration `json:"webhook_timeout"`
+
+	// Interval is the period each digest covers (default 24h)
+	Interval time.Duration `json:"interval"`
+	// TopModels is how many of the busiest models are listed (default 5)
+	TopModels int `json:"top_models"`
+	// Budget is the USD spend expected per interval; zero reports spend
+	// without a budget
+	Budget float64 `json:"budget"`
+
+	// Pricing is per-model USD pricing used to cost usage (defaults to the
+	// built-in pricing snapshot)
+	Pricing map[string]catalog.ModelPricing `json:"pricing"`
+}
+
+// Digest summarizes routing health over one interval
+type Digest struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	Requests  int64            `json:"requests"`
+	Buckets   map[Bucket]int64 `json:"buckets"`
+	Errors    int64            `json:"errors"`
+	ErrorRate float64          `json:"error_rate"`
+	TopModels []DigestModel    `json:"top_models"`
+
+	// Spend is in USD; BudgetUsed is spend as a fraction of the budget,
+	// when one is set
+	Spend      float64 `json:"spend"`
+	Budget     float64 `json:"budget,omitempty"`
+	BudgetUsed float64 `json:"budget_used,omitempty"`
+
+	// Anomalies are the conditions active when the digest was built
+	Anomalies []DigestAnomaly `json:"anomalies"`
+}
+
+// DigestModel is one model's share of the interval
+type DigestModel struct {
+	Model     string  `json:"model"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	Spend     float64 `json:"spend"` // USD
+}
+
+// DigestAnomaly is a notable condition, such as a quarantined model
+type DigestAnomaly struct {
+	Kind    string `json:"kind"` // "cost_anomaly", "quarantined", "drained" or "alpha_killed"
+	Subject string `json:"subject"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// DigestStats reports digest delivery
+type DigestStats struct {
+	Sent       int64     `json:"sent"`
+	Failed     int64     `json:"failed"`
+	LastSentAt time.Time `json:"last_sent_at"`
+	// PeriodStart is when the interval being accumulated began
+	PeriodStart time.Time `json:"period_start"`
+}
+
+// digestModel accumulates a model's outcomes
+type digestModel struct {
+	requests int64
+	errors   int64
+	spend    float64
+}
+
+// Digester accumulates routing outcomes over an interval and posts a
+// digest of them to a webhook when the interval ends
+type Digester struct {
+	config     DigestConfig
+	now        func() time.Time
+	httpClient *http.Client
+
+	periodStart time.Time
+	buckets     map[Bucket]int64
+	models      map[string]*digestModel
+
+	sent       int64
+	failed     int64
+	lastSentAt time.Time
+
+	mu       sync.Mutex
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDigester creates a digester, filling defaults
+func NewDigester(config DigestConfig, now func() time.Time) (*Digester, error) {
+	if config.Interval == 0 {
+		config.Interval = 24 * time.Hour
+	}
+	if config.TopModels == 0 {
+		config.TopModels = 5
+	}
+	if config.WebhookTimeout == 0 {
+		config.WebhookTimeout = 5 * time.Second
+	}
+	if config.Pricing == nil {
+		config.Pricing = builtinDefault.Pricing
+	}
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook_url is required")
+	}
+	if config.Interval < 0 || config.TopModels < 0 {
+		return nil, fmt.Errorf("interval and top_models must be positive")
+	}
+	if config.Budget < 0 {
+		return nil, fmt.Errorf("budget must not be negative, got %v", config.Budget)
+	}
+
+	return &Digester{
+		config: config,
+		now:    now,
+		httpClient: &http.Client{
+			Timeout: config.WebhookTimeout,
+		},
+		periodStart: now(),
+		buckets:     make(map[Bucket]int64),
+		models:      make(map[string]*digestModel),
+		stopCh:      make(chan struct{}),
+	}, nil
+}
+
+// Start posts a digest every interval in the background; anomalies
+// supplies the conditions active at the time
+func (d *Digester) Start(anomalies func() []DigestAnomaly) {
+	go func() {
+		ticker := time.NewTicker(d.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.Send(d.Flush(anomalies()))
+			case <-d.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts background digests
+func (d *Digester) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+	})
+}
+
+// RecordDecision counts a routed request in its bucket
+func (d *Digester) RecordDecision(bucket Bucket) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.buckets[bucket]++
+}
+
+// RecordOutcome accounts a request's outcome and cost against its model;
+// usage may be nil
+func (d *Digester) RecordOutcome(model string, success bool, usage *schemas.LLMUsage) {
+	var cost float64
+	if usage != nil {
+		cost = usageCost(d.config.Pricing[model], usage)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats, ok := d.models[model]
+	if !ok {
+		stats = &digestModel{}
+		d.models[model] = stats
+	}
+	stats.requests++
+	if !success {
+		stats.errors++
+	}
+	stats.spend += cost
+}
+
+// RecordSpend adds usage reported apart from its request's outcome, e.g. in
+// a stream's trailing usage chunk
+func (d *Digester) RecordSpend(model string, usage *schemas.LLMUsage) {
+	cost := usageCost(d.config.Pricing[model], usage)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats, ok := d.models[model]
+	if !ok {
+		stats = &digestModel{}
+		d.models[model] = stats
+	}
+	stats.spend += cost
+}
+
+// Flush builds the digest of the current interval and starts the next
+func (d *Digester) Flush(anomalies []DigestAnomaly) Digest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	digest := Digest{
+		PeriodStart: d.periodStart,
+		PeriodEnd:   now,
+		Buckets:     d.buckets,
+		TopModels:   []DigestModel{},
+		Budget:      d.config.Budget,
+		Anomalies:   append([]DigestAnomaly{}, anomalies...),
+	}
+	for _, count := range d.buckets {
+		digest.Requests += count
+	}
+
+	var outcomes int64
+	for model, stats := range d.models {
+		outcomes += stats.requests
+		digest.Errors += stats.errors
+		digest.Spend += stats.spend
+		digest.TopModels = append(digest.TopModels, DigestModel{
+			Model:     model,
+			Requests:  stats.requests,
+			Errors:    stats.errors,
+			ErrorRate: float64(stats.errors) / float64(stats.requests),
+			Spend:     stats.spend,
+		})
+	}
+	if outcomes > 0 {
+		digest.ErrorRate = float64(digest.Errors) / float64(outcomes)
+	}
+	if d.config.Budget > 0 {
+		digest.BudgetUsed = digest.Spend / d.config.Budget
+	}
+	sort.Slice(digest.TopModels, func(i, j int) bool {
+		if digest.TopModels[i].Requests != digest.TopModels[j].Requests {
+			return digest.TopModels[i].Requests > digest.TopModels[j].Requests
+		}
+		return digest.TopModels[i].Model < digest.TopModels[j].Model
+	})
+	if len(digest.TopModels) > d.config.TopModels {
+		digest.TopModels = digest.TopModels[:d.config.TopModels]
+	}
+
+	d.periodStart = now
+	d.buckets = make(map[Bucket]int64)
+	d.models = make(map[string]*digestModel)
+	return digest
+}
+
+// Send posts a digest to the webhook
+func (d *Digester) Send(digest Digest) error {
+	err := d.post(digest)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.failed++
+		log.Printf("Routing digest webhook failed: %v", err)
+		return err
+	}
+	d.sent++
+	d.lastSentAt = d.now()
+	return nil
+}
+
+func (d *Digester) post(digest Digest) error {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	resp, err := d.httpClient.Post(d.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetStats reports digest delivery
+func (d *Digester) GetStats() DigestStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DigestStats{
+		Sent:        d.sent,
+		Failed:      d.failed,
+		LastSentAt:  d.lastSentAt,
+		PeriodStart: d.periodStart,
+	}
+}
+
+// digestAnomalies lists the notable conditions for a digest: cost
+// anomalies, quarantined models, drains and a killed α controller
+func (p *Plugin) digestAnomalies() []DigestAnomaly {
+	anomalies := []DigestAnomaly{}
+	if p.anomalies != nil {
+		for _, anomaly := range p.anomalies.GetStatus() {
+			anomalies = append(anomalies, DigestAnomaly{
+				Kind:    "cost_anomaly",
+				Subject: anomaly.Tenant,
+				Detail: fmt.Sprintf("%s at $%.6f per request against a $%.6f baseline",
+					anomaly.Model, anomaly.RecentCost, anomaly.BaselineCost),
+			})
+		}
+	}
+	if p.quarantine != nil {
+		for _, status := range p.quarantine.GetStatus() {
+			anomalies = append(anomalies, DigestAnomaly{
+				Kind:    "quarantined",
+				Subject: status.Model,
+				Detail:  fmt.Sprintf("success rate %.2f", status.SuccessRate),
+			})
+		}
+	}
+	for _, status := range p.drains.GetStatus() {
+		anomalies = append(anomalies, DigestAnomaly{
+			Kind:    "drained",
+			Subject: status.Target,
+			Detail:  status.Reason,
+		})
+	}
+	if p.alphaController != nil {
+		if status := p.alphaController.GetStatus(); status.Killed {
+			anomalies = append(anomalies, DigestAnomaly{
+				Kind:    "alpha_killed",
+				Subject: "alpha_controller",
+				Detail:  status.KillReason,
+			})
+		}
+	}
+	return anomalies
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigester(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	config := DigestConfig{
+		WebhookURL: "http://digest.invalid",
+		TopModels:  2,
+		Budget:     0.01,
+		Pricing:    map[string]catalog.ModelPricing{"openai/gpt-4o": {InPerMillion: 1, OutPerMillion: 1}},
+	}
+	usage := &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000}
+
+	t.Run("should summarize the interval", func(t *testing.T) {
+		d, err := NewDigester(config, clock)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			d.RecordDecision(BucketCheap)
+		}
+		d.RecordDecision(BucketHard)
+		d.RecordOutcome("openai/gpt-4o", true, usage)
+		d.RecordOutcome("openai/gpt-4o", false, nil)
+		d.RecordOutcome("deepseek/deepseek-r1", true, nil)
+		d.RecordOutcome("qwen/qwen-2.5-coder-32b-instruct", true, nil)
+
+		now = now.Add(24 * time.Hour)
+		digest := d.Flush([]DigestAnomaly{{Kind: "drained", Subject: "anthropic"}})
+		assert.Equal(t, now.Add(-24*time.Hour), digest.PeriodStart)
+		assert.Equal(t, int64(4), digest.Requests)
+		assert.Equal(t, map[Bucket]int64{BucketCheap: 3, BucketHard: 1}, digest.Buckets)
+		assert.Equal(t, int64(1), digest.Errors)
+		assert.InDelta(t, 0.25, digest.ErrorRate, 1e-9)
+		assert.InDelta(t, 0.002, digest.Spend, 1e-9)
+		assert.InDelta(t, 0.2, digest.BudgetUsed, 1e-9)
+		assert.Len(t, digest.Anomalies, 1)
+
+		require.Len(t, digest.TopModels, 2, "only the busiest models are listed")
+		assert.Equal(t, "openai/gpt-4o", digest.TopModels[0].Model)
+		assert.InDelta(t, 0.5, digest.TopModels[0].ErrorRate, 1e-9)
+		assert.Equal(t, "deepseek/deepseek-r1", digest.TopModels[1].Model)
+	})
+
+	t.Run("should add spend reported apart from its request", func(t *testing.T) {
+		d, err := NewDigester(config, clock)
+		require.NoError(t, err)
+		d.RecordOutcome("openai/gpt-4o", true, nil)
+		d.RecordSpend("openai/gpt-4o", usage)
+
+		digest := d.Flush(nil)
+		require.Len(t, digest.TopModels, 1)
+		assert.Equal(t, int64(1), digest.TopModels[0].Requests)
+		assert.InDelta(t, 0.002, digest.TopModels[0].Spend, 1e-9)
+	})
+
+	t.Run("should start a fresh interval after each digest", func(t *testing.T) {
+		d, err := NewDigester(config, clock)
+		require.NoError(t, err)
+		d.RecordDecision(BucketMid)
+		d.RecordOutcome("openai/gpt-4o", true, usage)
+		d.Flush(nil)
+
+		digest := d.Flush(nil)
+		assert.Zero(t, digest.Requests)
+		assert.Empty(t, digest.TopModels)
+		assert.NotNil(t, digest.Anomalies)
+		assert.Equal(t, now, d.GetStats().PeriodStart)
+	})
+
+	t.Run("should post digests and count failures", func(t *testing.T) {
+		var received Digest
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		webhooked := config
+		webhooked.WebhookURL = server.URL
+		d, err := NewDigester(webhooked, clock)
+		require.NoError(t, err)
+		d.RecordDecision(BucketCheap)
+
+		require.NoError(t, d.Send(d.Flush(nil)))
+		assert.Equal(t, int64(1), received.Requests)
+
+		status = http.StatusBadGateway
+		assert.ErrorContains(t, d.Send(d.Flush(nil)), "webhook returned 502")
+		stats := d.GetStats()
+		assert.Equal(t, int64(1), stats.Sent)
+		assert.Equal(t, int64(1), stats.Failed)
+		assert.Equal(t, now, stats.LastSentAt)
+	})
+
+	t.Run("should reject invalid configs", func(t *testing.T) {
+		_, err := NewDigester(DigestConfig{}, clock)
+		assert.ErrorContains(t, err, "webhook_url is required")
+		_, err = NewDigester(DigestConfig{WebhookURL: "http://digest.invalid", Budget: -1}, clock)
+		assert.ErrorContains(t, err, "budget must not be negative")
+	})
+}
+
+func TestDigestRouting(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Digest = DigestConfig{Enabled: true, WebhookURL: "http://digest.invalid"}
+	config.Quarantine = testQuarantineConfig()
+	config.Synthetic = SyntheticConfig{Enabled: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	require.NoError(t, plugin.Drain("anthropic", "maintenance"))
+
+	route := func(synthetic bool) context.Context {
+		ctx := context.Background()
+		if synthetic {
+			ctx = context.WithValue(ctx, "heimdall_synthetic", "health")
+		}
+		content := "What is the capital of France?"
+		req := &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}}
+		_, _, err := plugin.applyRoutingDecision(&ctx, req, &RouterResponse{
+			Bucket:   BucketMid,
+			Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+		})
+		require.NoError(t, err)
+		return ctx
+	}
+
+	for _, synthetic := range []bool{false, true} {
+		ctx := route(synthetic)
+		_, _, err := plugin.PostHook(&ctx, textResponse("Paris.", "stop"), nil)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 5; i++ {
+		plugin.quarantine.Record("openai/gpt-4o-mini", false)
+	}
+
+	digest := plugin.digester.Flush(plugin.digestAnomalies())
+	assert.Equal(t, map[Bucket]int64{BucketMid: 1}, digest.Buckets, "synthetic traffic is not counted")
+	require.Len(t, digest.TopModels, 1)
+	assert.Equal(t, int64(1), digest.TopModels[0].Requests)
+
+	kinds := map[string]string{}
+	for _, anomaly := range digest.Anomalies {
+		kinds[anomaly.Kind] = anomaly.Subject
+	}
+	assert.Equal(t, map[string]string{"drained": "anthropic", "quarantined": "openai/gpt-4o-mini"}, kinds)
+	assert.NotNil(t, plugin.Metrics().Digest)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DrainConfig configures drain/maintenance mode
+type DrainConfig struct {
+	// StateFile persists drain state across restarts (optional)
+	StateFile string `json:"state_file"`
+}
+
+// DrainEntry records a drained provider or model
+type DrainEntry struct {
+	Target    string    `json:"target"` // provider kind ("anthropic") or model ("openai/gpt-4o")
+	Reason    string    `json:"reason,omitempty"`
+	DrainedAt time.Time `json:"drained_at"`
+}
+
+// DrainStatus is a drain entry plus its remaining in-flight requests
+type DrainStatus struct {
+	DrainEntry
+	InFlight int64 `json:"in_flight"`
+}
+
+// DrainManager tracks drained providers/models. Drained targets are not
+// selected for new requests; requests already routed to them finish normally.
+type DrainManager struct {
+	stateFile string
+
+	entries  map[string]DrainEntry
+	inFlight map[string]int64
+	mu       sync.RWMutex
+}
+
+// NewDrainManager creates a drain manager, restoring persisted state if present
+func NewDrainManager(config DrainConfig) *DrainManager {
+	dm := &DrainManager{
+		stateFile: config.StateFile,
+		entries:   make(map[string]DrainEntry),
+		inFlight:  make(map[string]int64),
+	}
+
+	if err := dm.load(); err != nil {
+		log.Printf("Failed to load drain state from %s: %v", dm.stateFile, err)
+	}
+
+	return dm
+}
+
+// Drain stops selection of a provider or model for new requests
+func (dm *DrainManager) Drain(target string, reason string) error {
+	if target == "" {
+		return fmt.Errorf("drain target is required")
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	dm.entries[target] = DrainEntry{
+		Target:    target,
+		Reason:    reason,
+		DrainedAt: time.Now().UTC(),
+	}
+	log.Printf("Drained %s (reason: %s)", target, reason)
+
+	return dm.persist()
+}
+
+// Undrain returns a provider or model to service
+func (dm *DrainManager) Undrain(target string) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if _, ok := dm.entries[target]; !ok {
+		return fmt.Errorf("%s is not drained", target)
+	}
+	delete(dm.entries, target)
+	log.Printf("Undrained %s", target)
+
+	return dm.persist()
+}
+
+// entry returns target's drain entry, or nil when it is not drained
+func (dm *DrainManager) entry(target string) *DrainEntry {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	entry, ok := dm.entries[target]
+	if !ok {
+		return nil
+	}
+	return &entry
+}
+
+// IsDrained reports whether the model or its provider is drained
+func (dm *DrainManager) IsDrained(provider string, model string) bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	if len(dm.entries) == 0 {
+		return false
+	}
+	_, modelDrained := dm.entries[model]
+	_, providerDrained := dm.entries[provider]
+	return modelDrained || providerDrained
+}
+
+// Acquire records a request routed to the model
+func (dm *DrainManager) Acquire(provider string, model string) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.inFlight[provider]++
+	dm.inFlight[model]++
+}
+
+// Release records completion of a request routed to the model
+func (dm *DrainManager) Release(provider string, model string) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	for _, key := range []string{provider, model} {
+		if dm.inFlight[key] > 0 {
+			dm.inFlight[key]--
+		}
+	}
+}
+
+// GetStatus returns all drained targets with their in-flight counts
+func (dm *DrainManager) GetStatus() []DrainStatus {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	statuses := make([]DrainStatus, 0, len(dm.entries))
+	for target, entry := range dm.entries {
+		statuses = append(statuses, DrainStatus{
+			DrainEntry: entry,
+			InFlight:   dm.inFlight[target],
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Target < statuses[j].Target
+	})
+	return statuses
+}
+
+// Inherit takes over a replaced manager's drains, persisting them to this
+// manager's state file. In-flight counts stay with the old manager, which
+// its requests are released on.
+func (dm *DrainManager) Inherit(old *DrainManager) {
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	for target, entry := range old.entries {
+		dm.entries[target] = entry
+	}
+	if err := dm.persist(); err != nil {
+		log.Printf("Failed to persist inherited drain state: %v", err)
+	}
+}
+
+// persist writes drain state to the state file (no lock - called from locked context)
+func (dm *DrainManager) persist() error {
+	if dm.stateFile == "" {
+		return nil
+	}
+
+	entries := make([]DrainEntry, 0, len(dm.entries))
+	for _, entry := range dm.entries {
+		entries = append(entries, entry)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal drain state: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated state file
+	tmpFile := dm.stateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write drain state: %w", err)
+	}
+	if err := os.Rename(tmpFile, dm.stateFile); err != nil {
+		return fmt.Errorf("failed to write drain state: %w", err)
+	}
+	return nil
+}
+
+// load restores drain state from the state file
+func (dm *DrainManager) load() error {
+	if dm.stateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(dm.stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var entries []DrainEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		dm.entries[entry.Target] = entry
+	}
+	if len(entries) > 0 {
+		log.Printf("Restored %d drained targets from %s", len(entries), dm.stateFile)
+	}
+	return nil
+}
+
+// filterDrainedCandidates removes drained providers/models from candidates
+func (p *Plugin) filterDrainedCandidates(candidates []string) []string {
+	if p.drains == nil {
+		return candidates
+	}
+
+	filtered := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if !p.drains.IsDrained(p.inferProviderKind(c), c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Drain stops selecting a provider kind or model for new requests
+func (p *Plugin) Drain(target string, reason string) error {
+	return p.drains.Drain(target, reason)
+}
+
+// Undrain returns a drained provider kind or model to service
+func (p *Plugin) Undrain(target string) error {
+	return p.drains.Undrain(target)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainManager(t *testing.T) {
+	t.Run("should drain models and providers", func(t *testing.T) {
+		dm := NewDrainManager(DrainConfig{})
+
+		require.NoError(t, dm.Drain("openai/gpt-4o", "key rotation"))
+		require.NoError(t, dm.Drain("anthropic", "incident"))
+
+		assert.True(t, dm.IsDrained("openai", "openai/gpt-4o"))
+		assert.False(t, dm.IsDrained("openai", "openai/o1"))
+		assert.True(t, dm.IsDrained("anthropic", "anthropic/claude-3-opus"))
+
+		require.NoError(t, dm.Undrain("anthropic"))
+		assert.False(t, dm.IsDrained("anthropic", "anthropic/claude-3-opus"))
+		assert.Error(t, dm.Undrain("anthropic"))
+		assert.Error(t, dm.Drain("", "no target"))
+	})
+
+	t.Run("should persist drain state across restarts", func(t *testing.T) {
+		stateFile := filepath.Join(t.TempDir(), "drains.json")
+
+		dm := NewDrainManager(DrainConfig{StateFile: stateFile})
+		require.NoError(t, dm.Drain("google", "quota exhausted"))
+
+		restored := NewDrainManager(DrainConfig{StateFile: stateFile})
+		assert.True(t, restored.IsDrained("google", "google/gemini-1.5-pro"))
+		require.Len(t, restored.GetStatus(), 1)
+		assert.Equal(t, "quota exhausted", restored.GetStatus()[0].Reason)
+	})
+
+	t.Run("should track in-flight requests for drained targets", func(t *testing.T) {
+		dm := NewDrainManager(DrainConfig{})
+		dm.Acquire("openai", "openai/gpt-4o")
+		dm.Acquire("openai", "openai/gpt-4o")
+		require.NoError(t, dm.Drain("openai", ""))
+
+		assert.Equal(t, int64(2), dm.GetStatus()[0].InFlight)
+
+		dm.Release("openai", "openai/gpt-4o")
+		dm.Release("openai", "openai/gpt-4o")
+		dm.Release("openai", "openai/gpt-4o")
+		assert.Equal(t, int64(0), dm.GetStatus()[0].InFlight)
+	})
+}
+
+func TestDrainRouting(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 0, TokenCount: 500}
+
+	t.Run("should not select drained models", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		before, err := plugin.selectModelForBucket("mid", features)
+		require.NoError(t, err)
+
+		require.NoError(t, plugin.Drain(before.Model, "maintenance"))
+
+		after, err := plugin.selectModelForBucket("mid", features)
+		require.NoError(t, err)
+		assert.NotEqual(t, before.Model, after.Model)
+		assert.NotContains(t, after.Fallbacks, before.Model)
+	})
+
+	t.Run("should error when every candidate is drained", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		require.NoError(t, plugin.Drain("qwen/qwen-2.5-coder-32b-instruct", ""))
+		require.NoError(t, plugin.Drain("deepseek/deepseek-r1", ""))
+
+		_, err := plugin.selectModelForBucket("cheap", features)
+		assert.Error(t, err)
+	})
+
+	t.Run("should skip the Anthropic shortcut when anthropic is drained", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		authInfo := &AuthInfo{Provider: "anthropic", Type: "bearer", Token: auth.NewSecret("test")}
+
+		decision, err := plugin.selectModel(BucketMid, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", decision.Kind)
+
+		require.NoError(t, plugin.Drain("anthropic", "incident"))
+		decision, err = plugin.selectModel(BucketMid, features, authInfo, false)
+		require.NoError(t, err)
+		assert.NotEqual(t, "anthropic", decision.Kind)
+	})
+
+	t.Run("should re-route cached decisions for drained models", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EnableCaching = true
+		plugin := createRouterTestPluginWithConfig(t, config)
+		route := func() *schemas.BifrostRequest {
+			content := "Summarize the plot of Hamlet"
+			ctx := context.Background()
+			req, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+				ChatCompletionInput: &[]schemas.BifrostMessage{{
+					Role:    schemas.ModelChatMessageRoleUser,
+					Content: schemas.MessageContent{ContentStr: &content},
+				}},
+			}})
+			require.NoError(t, err)
+			return req
+		}
+
+		before := route()
+		require.NoError(t, plugin.Drain(before.Model, "maintenance"))
+		after := route()
+		assert.NotEqual(t, before.Model, after.Model)
+		for _, fallback := range after.Fallbacks {
+			assert.NotEqual(t, before.Model, fallback.Model)
+		}
+	})
+
+	t.Run("should release in-flight requests in PostHook", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		require.NoError(t, plugin.Drain("openai", ""))
+
+		ctx := context.Background()
+		req := &schemas.BifrostRequest{}
+		response := &RouterResponse{Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"}}
+
+		_, _, err := plugin.applyRoutingDecision(&ctx, req, response)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), plugin.GetHealth().Drained[0].InFlight)
+
+		_, _, err = plugin.PostHook(&ctx, &schemas.BifrostResponse{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), plugin.GetHealth().Drained[0].InFlight)
+	})
+}
+
+func TestAdminDrainEndpoints(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	server := httptest.NewServer(plugin.AdminHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/admin/drains", "application/json",
+		strings.NewReader(`{"target": "openai/gpt-4o", "reason": "incident"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, plugin.drains.IsDrained("openai", "openai/gpt-4o"))
+
+	resp, err = http.Get(server.URL + "/health")
+	require.NoError(t, err)
+	var health HealthStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	resp.Body.Close()
+	require.Len(t, health.Drained, 1)
+	assert.Equal(t, "openai/gpt-4o", health.Drained[0].Target)
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/admin/drains/openai/gpt-4o", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, plugin.drains.IsDrained("openai", "openai/gpt-4o"))
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
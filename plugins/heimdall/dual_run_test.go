@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualRunSelection(t *testing.T) {
+	config := createRouterTestConfig()
+	config.DualRun = scoring.DualRunConfig{Enabled: true, SampleRate: 1}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	_, err := plugin.selectModelForBucket("mid", &RequestFeatures{ClusterID: 0})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return plugin.dualRun.GetStats().Comparisons == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Zero(t, plugin.dualRun.GetStats().Mismatches)
+	assert.Contains(t, plugin.GetMetrics(), "dual_run")
+}
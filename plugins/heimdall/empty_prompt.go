@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// Empty prompt actions
+const (
+	// EmptyPromptRoute routes empty prompts to a default model unclassified
+	EmptyPromptRoute = "route"
+	// EmptyPromptReject rejects empty prompts with 400
+	EmptyPromptReject = "reject"
+)
+
+// EmptyPromptConfig handles requests whose messages hold no text. They
+// carry nothing to classify, so they skip feature extraction and triage.
+type EmptyPromptConfig struct {
+	// Action is "route" (default) or "reject"
+	Action string `json:"action"`
+	// Model answers routed empty prompts (default the first available cheap
+	// candidate, which also stands in when Model is unavailable)
+	Model string `json:"model"`
+}
+
+// validate checks the action and fills its default
+func (c *EmptyPromptConfig) validate() error {
+	switch c.Action {
+	case "":
+		c.Action = EmptyPromptRoute
+	case EmptyPromptRoute, EmptyPromptReject:
+	default:
+		return fmt.Errorf("unknown empty prompt action %q", c.Action)
+	}
+	return nil
+}
+
+// emptyPrompt reports whether a request has no message text and nothing
+// else, such as an image or tool result, to answer
+func emptyPrompt(body *RequestBody) bool {
+	if body == nil {
+		return true
+	}
+	for _, msg := range body.Messages {
+		if msg.NonText || strings.TrimSpace(msg.Content) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nonTextContent reports whether a message carries a non-text content
+// block such as an image, tool calls or a tool result
+func nonTextContent(msg schemas.BifrostMessage) bool {
+	if msg.ToolMessage != nil && msg.ToolCallID != nil {
+		return true
+	}
+	if msg.AssistantMessage != nil && msg.ToolCalls != nil && len(*msg.ToolCalls) > 0 {
+		return true
+	}
+	if msg.Content.ContentBlocks != nil {
+		for _, block := range *msg.Content.ContentBlocks {
+			if block.Type != schemas.ContentBlockTypeText {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// routeEmptyPrompt answers an empty prompt per the empty prompt config,
+// within the caller's policy and BYOK scope
+func (p *Plugin) routeEmptyPrompt(authInfo *AuthInfo, sessionID string) (*RouterResponse, error) {
+	p.metricsMu.Lock()
+	p.emptyPromptCount++
+	p.metricsMu.Unlock()
+
+	if p.config.EmptyPrompt.Action == EmptyPromptReject {
+		return nil, NewInvalidRequestError("empty_prompt", "request has no prompt text")
+	}
+
+	candidates := p.config.Router.CheapCandidates
+	if model := p.config.EmptyPrompt.Model; model != "" {
+		candidates = append([]string{model}, slices.DeleteFunc(slices.Clone(candidates), func(c string) bool { return c == model })...)
+	}
+	features := &RequestFeatures{}
+	policy := policyFor(authInfo)
+	candidates = policy.FilterCandidates(p.availableCandidates(candidates, features))
+
+	authMode := "env"
+	if scope := p.byokScopeFor(authInfo); scope != nil {
+		scoped := scope.filter(p, candidates)
+		if len(scoped) == 0 {
+			return nil, fmt.Errorf("no candidates for BYOK provider %s", scope.provider)
+		}
+		if !scope.restrictFallbacks {
+			// Scoped models are selected first; other providers remain as fallbacks
+			scoped = append(scoped, slices.DeleteFunc(candidates, func(c string) bool { return slices.Contains(scoped, c) })...)
+		}
+		candidates = scoped
+		authMode = "passthrough"
+	}
+	if len(candidates) == 0 {
+		return nil, NewPolicyError("no_permitted_candidates", "no candidates permitted by routing policy for empty prompts")
+	}
+
+	decision := &RouterDecision{
+		Kind:          p.inferProviderKind(candidates[0]),
+		Model:         candidates[0],
+		Params:        map[string]interface{}{},
+		ProviderPrefs: p.getProviderPreferencesForBucket(string(BucketCheap)),
+		Auth:          AuthConfig{Mode: authMode},
+		Fallbacks:     candidates[1:],
+	}
+	applyPolicy(policy, decision)
+	response := &RouterResponse{
+		Decision:  *decision,
+		Features:  *features,
+		Bucket:    BucketCheap,
+		AuthInfo:  authInfo,
+		SessionID: sessionID,
+	}
+	if p.currentArtifact.Version == defaultArtifactVersion {
+		response.FallbackReason = FallbackArtifactMissing
+	}
+	return response, nil
+}
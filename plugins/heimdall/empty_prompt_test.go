@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmptyPrompt(t *testing.T) {
+	blank := func(content string) *schemas.BifrostRequest {
+		return &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}}
+	}
+
+	t.Run("should detect prompts without text", func(t *testing.T) {
+		assert.True(t, emptyPrompt(nil))
+		assert.True(t, emptyPrompt(&RequestBody{}))
+		assert.True(t, emptyPrompt(&RequestBody{Messages: []ChatMessage{{Role: "system", Content: " \n\t"}}}))
+		assert.False(t, emptyPrompt(&RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}))
+
+		text := "Once upon a time"
+		messages := chatMessages(&schemas.BifrostRequest{Input: schemas.RequestInput{TextCompletionInput: &text}})
+		assert.False(t, emptyPrompt(&RequestBody{Messages: messages}), "text completions have a prompt")
+	})
+
+	t.Run("should count images, tool calls and tool results as a prompt", func(t *testing.T) {
+		image := schemas.BifrostMessage{Role: schemas.ModelChatMessageRoleUser, Content: schemas.MessageContent{
+			ContentBlocks: &[]schemas.ContentBlock{{Type: schemas.ContentBlockTypeImage, ImageURL: &schemas.ImageURLStruct{URL: "https://example.com/cat.png"}}},
+		}}
+		name, id := "lookup", "call_1"
+		toolCall := schemas.BifrostMessage{Role: schemas.ModelChatMessageRoleAssistant, AssistantMessage: &schemas.AssistantMessage{
+			ToolCalls: &[]schemas.ToolCall{{ID: &id, Function: schemas.FunctionCall{Name: &name}}},
+		}}
+		toolResult := schemas.BifrostMessage{Role: schemas.ModelChatMessageRoleTool, ToolMessage: &schemas.ToolMessage{ToolCallID: &id}}
+
+		for _, msg := range []schemas.BifrostMessage{image, toolCall, toolResult} {
+			req := &schemas.BifrostRequest{Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{msg}}}
+			assert.False(t, emptyPrompt(&RequestBody{Messages: chatMessages(req)}), msg.Role)
+		}
+
+		var body RequestBody
+		require.NoError(t, json.Unmarshal([]byte(`{"messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]}]}`), &body))
+		assert.False(t, emptyPrompt(&body))
+		require.NoError(t, json.Unmarshal([]byte(`{"messages": [{"role": "tool", "content": "", "tool_call_id": "call_1"}]}`), &body))
+		assert.False(t, emptyPrompt(&body))
+		require.NoError(t, json.Unmarshal([]byte(`{"messages": [{"role": "user", "content": [{"type": "text", "text": " "}]}]}`), &body))
+		assert.True(t, emptyPrompt(&body))
+	})
+
+	t.Run("should route empty prompts to the configured model unclassified", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EmptyPrompt = EmptyPromptConfig{Model: "openai/gpt-4o-mini"}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		ctx := context.Background()
+		result, shortCircuit, err := plugin.PreHook(&ctx, blank("   "))
+		require.NoError(t, err)
+		assert.Nil(t, shortCircuit)
+		assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+
+		decision := ctx.Value("heimdall_decision").(RouterDecision)
+		assert.Equal(t, "openai/gpt-4o-mini", decision.Model)
+		assert.Equal(t, config.Router.CheapCandidates, decision.Fallbacks)
+		assert.Equal(t, BucketCheap, ctx.Value("heimdall_bucket"))
+		assert.Equal(t, RequestFeatures{}, ctx.Value("heimdall_features"))
+		assert.Equal(t, int64(1), plugin.GetMetrics()["empty_prompts"])
+	})
+
+	t.Run("should default to the first permitted cheap candidate", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EmptyPrompt = EmptyPromptConfig{Model: "openai/gpt-4o-mini"}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{Candidates: config.Router.CheapCandidates[1:]}}
+		response, err := plugin.routeEmptyPrompt(authInfo, "")
+		require.NoError(t, err)
+		assert.Equal(t, config.Router.CheapCandidates[1], response.Decision.Model)
+	})
+
+	t.Run("should reject empty prompts when configured to", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EmptyPrompt = EmptyPromptConfig{Action: EmptyPromptReject}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit)
+		assert.Equal(t, http.StatusBadRequest, *shortCircuit.Error.StatusCode)
+		assert.Equal(t, ErrorTypeInvalidRequest, *shortCircuit.Error.Error.Type)
+		assert.Equal(t, "empty_prompt", *shortCircuit.Error.Error.Code)
+		assert.Equal(t, int64(1), plugin.GetMetrics()["empty_prompts"])
+	})
+
+	t.Run("should reject unknown actions", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EmptyPrompt = EmptyPromptConfig{Action: "ignore"}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid empty prompt config")
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EnvoyConfig configures the Envoy external authorization adapter (see
+// ExtAuthzHandler). Envoy must list the headers below in the ext_authz
+// filter's allowed_upstream_headers to forward them.
+type EnvoyConfig struct {
+	// ClusterHeader carries the upstream cluster for routes using
+	// cluster_header (default x-heimdall-cluster)
+	ClusterHeader string `json:"cluster_header"`
+	// ModelHeader carries the selected model (default x-heimdall-model).
+	// The request body is forwarded unchanged, so the upstream must apply it.
+	ModelHeader string `json:"model_header"`
+	// BucketHeader carries the triage bucket (default x-heimdall-bucket)
+	BucketHeader string `json:"bucket_header"`
+	// FallbacksHeader carries the fallback models, comma separated
+	// (default x-heimdall-fallbacks)
+	FallbacksHeader string `json:"fallbacks_header"`
+
+	// Clusters maps provider kinds to Envoy cluster names; unmapped kinds
+	// use the kind itself
+	Clusters map[string]string `json:"clusters"`
+
+	// FailOpen allows authenticated requests Heimdall cannot route (bodies
+	// that are not chat completions, routing failures) unchanged, leaving
+	// Envoy's default route in place. By default they are denied.
+	FailOpen bool `json:"fail_open"`
+}
+
+// withDefaults fills unset header names
+func (c EnvoyConfig) withDefaults() EnvoyConfig {
+	if c.ClusterHeader == "" {
+		c.ClusterHeader = "x-heimdall-cluster"
+	}
+	if c.ModelHeader == "" {
+		c.ModelHeader = "x-heimdall-model"
+	}
+	if c.BucketHeader == "" {
+		c.BucketHeader = "x-heimdall-bucket"
+	}
+	if c.FallbacksHeader == "" {
+		c.FallbacksHeader = "x-heimdall-fallbacks"
+	}
+	return c
+}
+
+// ExtAuthzHandler returns an Envoy HTTP external authorization service that
+// routes each request. Envoy sends it the original request's headers and
+// body (with_request_body); an allowed request carries the decision back in
+// headers that Envoy adds upstream, so route configuration can pick the
+// cluster. ext_authz cannot change the body, so the upstream must rewrite
+// the model from the model header itself. The selected provider's
+// outbound headers (see RequestShapingConfig) are added the same way.
+// Callers are authenticated before the body is parsed; authentication
+// failures and rejections deny the request with their error body. Requests
+// that cannot be routed are denied too, unless FailOpen is set.
+func (p *Plugin) ExtAuthzHandler() http.Handler {
+	config := p.config.Envoy.withDefaults()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxAdvisoryBody+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(data) > maxAdvisoryBody {
+			// A truncated body would be routed, and signatures checked, on
+			// part of the request
+			rejection := NewInvalidRequestError("request_too_large", "request body exceeds 10MB")
+			rejection.StatusCode = http.StatusRequestEntityTooLarge
+			p.writeRejection(w, rejection)
+			return
+		}
+
+		req := &RouterRequest{
+			URL:     r.URL.Path,
+			Method:  r.Method,
+			Headers: r.Header,
+			RawBody: data,
+		}
+		authInfo, err := p.authenticate(req, r.Header)
+		if err != nil {
+			p.denyExtAuthz(w, err)
+			return
+		}
+
+		var body RequestBody
+		if len(data) == 0 || json.Unmarshal(data, &body) != nil || len(body.Messages) == 0 {
+			// Not a chat completion (or the body was not forwarded)
+			p.unroutedExtAuthz(w, config, NewInvalidRequestError("unroutable_request", "request is not a chat completion"))
+			return
+		}
+		req.Body = &body
+
+		ctx := context.WithValue(r.Context(), authenticatedContextKey{}, &authenticated{authInfo: authInfo})
+		response, err := p.Advise(ctx, req, false)
+		if err != nil {
+			var authErr *AuthenticationError
+			var rejection *RejectionError
+			if errors.As(err, &authErr) || errors.As(err, &rejection) {
+				p.denyExtAuthz(w, err)
+				return
+			}
+			p.logger.Printf("Envoy request left unrouted: %v", err)
+			p.unroutedExtAuthz(w, config, NewUnavailableError("routing_unavailable", "request could not be routed"))
+			return
+		}
+
+		decision := response.Decision
+		cluster, ok := config.Clusters[decision.Kind]
+		if !ok {
+			cluster = decision.Kind
+		}
+		w.Header().Set(config.ClusterHeader, cluster)
+		w.Header().Set(config.ModelHeader, decision.Model)
+		w.Header().Set(config.BucketHeader, string(response.Bucket))
+		if len(decision.Fallbacks) > 0 {
+			w.Header().Set(config.FallbacksHeader, strings.Join(decision.Fallbacks, ","))
+		}
+		for name, value := range decision.Outbound[decision.Kind].Headers {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// denyExtAuthz denies a request with the error body of an authentication
+// failure or rejection
+func (p *Plugin) denyExtAuthz(w http.ResponseWriter, err error) {
+	var authErr *AuthenticationError
+	if errors.As(err, &authErr) {
+		p.writeRejection(w, authErr.rejection())
+		return
+	}
+	var rejection *RejectionError
+	if errors.As(err, &rejection) {
+		p.writeRejection(w, rejection)
+		return
+	}
+	p.writeRejection(w, NewUnavailableError("routing_unavailable", err.Error()))
+}
+
+// unroutedExtAuthz allows an authenticated request Heimdall cannot route
+// unchanged when failing open, and denies it otherwise
+func (p *Plugin) unroutedExtAuthz(w http.ResponseWriter, config EnvoyConfig, rejection *RejectionError) {
+	if config.FailOpen {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	p.writeRejection(w, rejection)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtAuthzHandler(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Envoy = EnvoyConfig{Clusters: map[string]string{"openai": "openai_upstream"}}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	server := httptest.NewServer(plugin.ExtAuthzHandler())
+	defer server.Close()
+
+	check := func(t *testing.T, body string) *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/authz/v1/chat/completions", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer sk-test-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("should allow with the decision in upstream headers", func(t *testing.T) {
+		resp := check(t, `{"model": "auto", "messages": [{"role": "user", "content": "What is 2+2?"}]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		model := resp.Header.Get("X-Heimdall-Model")
+		require.NotEmpty(t, model)
+		assert.NotEmpty(t, resp.Header.Get("X-Heimdall-Bucket"))
+		cluster := resp.Header.Get("X-Heimdall-Cluster")
+		if plugin.inferProviderKind(model) == "openai" {
+			assert.Equal(t, "openai_upstream", cluster)
+		} else {
+			assert.Equal(t, plugin.inferProviderKind(model), cluster)
+		}
+	})
+
+	t.Run("should route array-of-parts content", func(t *testing.T) {
+		resp := check(t, `{"model": "auto", "messages": [{"role": "user", "content": [{"type": "text", "text": "What is 2+2?"}]}]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("X-Heimdall-Model"))
+	})
+
+	t.Run("should deny requests it cannot route", func(t *testing.T) {
+		resp := check(t, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Heimdall-Model"))
+
+		resp = check(t, `{"input": "embed me"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Heimdall-Model"))
+	})
+
+	t.Run("should deny bodies over the limit instead of truncating them", func(t *testing.T) {
+		resp := check(t, `{"messages": [{"role": "user", "content": "`+strings.Repeat("a", maxAdvisoryBody)+`"}]}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("should allow requests it cannot route unchanged when failing open", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Envoy = EnvoyConfig{FailOpen: true}
+		server := httptest.NewServer(createRouterTestPluginWithConfig(t, config).ExtAuthzHandler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"input": "embed me"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Heimdall-Model"))
+	})
+
+	t.Run("should authenticate before reading array content", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Anonymous.Mode = AnonymousModeReject
+		server := httptest.NewServer(createRouterTestPluginWithConfig(t, config).ExtAuthzHandler())
+		defer server.Close()
+
+		body := `{"messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "https://example.com/a.png"}}]}]}`
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, err = http.Post(server.URL, "application/json", strings.NewReader(""))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "unauthenticated callers learn nothing about routing")
+	})
+}
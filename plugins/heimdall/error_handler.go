@@ -379,6 +379,31 @@ func NewArtifactLoadError(message string, cause error) *ArtifactLoadError {
 	}
 }
 
+// AuthenticationError rejects a request whose credentials failed verification;
+// it short-circuits with 401 instead of falling back
+type AuthenticationError struct {
+	Message string
+	Cause   error
+}
+
+func (e AuthenticationError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("AuthenticationError: %s (caused by: %v)", e.Message, e.Cause)
+	}
+	return fmt.Sprintf("AuthenticationError: %s", e.Message)
+}
+
+func (e AuthenticationError) Unwrap() error {
+	return e.Cause
+}
+
+func NewAuthenticationError(message string, cause error) *AuthenticationError {
+	return &AuthenticationError{
+		Message: message,
+		Cause:   cause,
+	}
+}
+
 // ErrorUtils provides utility functions for common error scenarios
 type ErrorUtils struct{}
 
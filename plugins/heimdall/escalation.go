@@ -0,0 +1,45 @@
+package main
+
+import "github.com/nathanrice/heimdall-bifrost-plugin/router"
+
+// nextBucket is the bucket a request escalates to once every candidate of
+// its own bucket has failed
+var nextBucket = map[string]Bucket{
+	"cheap": BucketMid,
+	"mid":   BucketHard,
+}
+
+// EscalationInfo is a fallback from the next bucket up, appended after the
+// bucket's own candidates so a request cannot exhaust a single bucket
+type EscalationInfo struct {
+	Bucket Bucket                 `json:"bucket"`
+	Model  string                 `json:"model"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// escalationFor picks the first available candidate of the next bucket that
+// is not already in the fallback list, within the caller's policy and BYOK
+// scope; nil when there is none
+func (p *Plugin) escalationFor(bucketType string, features *RequestFeatures, scope *byokScope, policy *RoutingPolicy, seen map[string]bool, rs *replayState) *EscalationInfo {
+	next, ok := nextBucket[bucketType]
+	if !ok || policy.CapBucket(next) != next {
+		return nil
+	}
+
+	candidates, _ := p.bucketCandidates(string(next))
+	candidates = policy.FilterCandidates(rs.available(p, candidates, features))
+	candidates = router.Constrain(candidates, features, p.currentArtifact)
+	if scope != nil && scope.restrictFallbacks {
+		candidates = scope.filter(p, candidates)
+	}
+	for _, model := range candidates {
+		if !seen[model] {
+			return &EscalationInfo{
+				Bucket: next,
+				Model:  model,
+				Params: p.bucketParams(string(next), model),
+			}
+		}
+	}
+	return nil
+}
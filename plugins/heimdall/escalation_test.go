@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalationLadder(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 1, TokenCount: 500}
+
+	t.Run("should end cheap fallbacks with a mid escalation and its params", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		decision, err := plugin.selectModelForBucket("cheap", features)
+		require.NoError(t, err)
+		require.NotNil(t, decision.Escalation)
+		assert.Equal(t, BucketMid, decision.Escalation.Bucket)
+		assert.Equal(t, "openai/gpt-4o", decision.Escalation.Model)
+		assert.Equal(t, "medium", decision.Escalation.Params["reasoning_effort"])
+		assert.Equal(t, "openai/gpt-4o", decision.Fallbacks[len(decision.Fallbacks)-1])
+	})
+
+	t.Run("should not escalate past the hard bucket", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		decision, err := plugin.selectModelForBucket("hard", features)
+		require.NoError(t, err)
+		assert.Nil(t, decision.Escalation)
+	})
+
+	t.Run("should skip unavailable escalation candidates", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		require.NoError(t, plugin.Drain("openai/gpt-4o", "maintenance"))
+
+		decision, err := plugin.selectModelForBucket("cheap", features)
+		require.NoError(t, err)
+		require.NotNil(t, decision.Escalation)
+		assert.Equal(t, "anthropic/claude-3-5-sonnet-20241022", decision.Escalation.Model)
+	})
+
+	t.Run("should respect the caller's bucket cap", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		decision, err := plugin.selectModelForBucketScoped("cheap", features, nil, &RoutingPolicy{MaxBucket: BucketCheap}, nil)
+		require.NoError(t, err)
+		assert.Nil(t, decision.Escalation)
+		for _, fallback := range decision.Fallbacks {
+			assert.Contains(t, plugin.config.Router.CheapCandidates, fallback)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// FallbackReason explains why a request was not routed by a complete
+// scoring pass over a loaded artifact
+type FallbackReason string
+
+const (
+	// FallbackArtifactMissing: no artifact loaded, so the request was routed
+	// with the built-in artifact or passed through
+	FallbackArtifactMissing FallbackReason = "artifact_missing"
+	// FallbackScoringFailed: α-score selection failed
+	FallbackScoringFailed FallbackReason = "scoring_failed"
+	// FallbackBudgetExceeded: the scoring budget cut selection short
+	FallbackBudgetExceeded FallbackReason = "budget_exceeded"
+	// FallbackProviderCooldown: every candidate was drained, quarantined or
+	// saturated
+	FallbackProviderCooldown FallbackReason = "provider_cooldown"
+	// FallbackLoadShed: the request was triaged by heuristics, without
+	// embeddings or GBDT, while shedding load
+	FallbackLoadShed FallbackReason = "load_shed"
+	// FallbackDeadline: a stage ran out of time
+	FallbackDeadline FallbackReason = "deadline"
+	// FallbackError: any other error, answered with the emergency decision
+	FallbackError FallbackReason = "error_fallback"
+)
+
+var (
+	// ErrScoringFailed wraps α-score selection failures
+	ErrScoringFailed = errors.New("α-score selection failed")
+
+	// ErrNoHealthyCandidates is returned when every candidate of a bucket is
+	// unavailable
+	ErrNoHealthyCandidates = errors.New("no healthy candidates")
+)
+
+// fallbackReasonFor classifies the error behind an emergency fallback
+func fallbackReasonFor(err error) FallbackReason {
+	switch {
+	case errors.Is(err, ErrArtifactUnavailable):
+		return FallbackArtifactMissing
+	case errors.Is(err, context.DeadlineExceeded):
+		return FallbackDeadline
+	case errors.Is(err, ErrNoHealthyCandidates):
+		return FallbackProviderCooldown
+	case errors.Is(err, ErrScoringFailed):
+		return FallbackScoringFailed
+	default:
+		return FallbackError
+	}
+}
+
+// recordFallback counts a request answered for reason
+func (p *Plugin) recordFallback(reason FallbackReason) {
+	p.metricsMu.Lock()
+	defer p.metricsMu.Unlock()
+	if p.fallbackCounts == nil {
+		p.fallbackCounts = make(map[FallbackReason]int64)
+	}
+	p.fallbackCounts[reason]++
+}
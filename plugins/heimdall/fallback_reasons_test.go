@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackReasonFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want FallbackReason
+	}{
+		{fmt.Errorf("routing decision failed: %w", ErrArtifactUnavailable), FallbackArtifactMissing},
+		{fmt.Errorf("feature extraction failed: %w", context.DeadlineExceeded), FallbackDeadline},
+		{fmt.Errorf("model selection failed: %w for bucket mid", ErrNoHealthyCandidates), FallbackProviderCooldown},
+		{fmt.Errorf("%w: %w", ErrScoringFailed, errors.New("no scorable candidates")), FallbackScoringFailed},
+		{errors.New("failed to convert request"), FallbackError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, fallbackReasonFor(tt.err), tt.err.Error())
+	}
+}
+
+func TestFallbackReasonMetrics(t *testing.T) {
+	plugin := createTestPluginWithoutArtifact(t)
+
+	t.Run("should count emergency fallbacks by reason", func(t *testing.T) {
+		ctx := context.Background()
+		plugin.handleError(&ctx, &schemas.BifrostRequest{}, fmt.Errorf("routing decision failed: %w", ErrNoHealthyCandidates))
+		assert.Equal(t, string(FallbackProviderCooldown), ctx.Value("heimdall_fallback_reason"))
+	})
+
+	t.Run("should flag decisions made with the built-in artifact", func(t *testing.T) {
+		ctx := context.Background()
+		_, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, string(FallbackArtifactMissing), ctx.Value("heimdall_fallback_reason"))
+	})
+
+	fallbacks := plugin.GetMetrics()["fallback_reasons"].(map[FallbackReason]int64)
+	assert.Equal(t, map[FallbackReason]int64{
+		FallbackProviderCooldown: 1,
+		FallbackArtifactMissing:  1,
+	}, fallbacks)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// issuedFallbackTTL is how long after a decision Bifrost's attempts at its
+// fallbacks are recognised; they start as soon as the earlier targets fail
+const issuedFallbackTTL = 5 * time.Minute
+
+// fallbackChain returns fallbacks without the selected model or repeats,
+// which Bifrost would retry in a loop, cut to maxDepth (0 = unbounded)
+func fallbackChain(model string, fallbacks []string, maxDepth int) []string {
+	seen := map[string]bool{model: true}
+	chain := make([]string, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		if maxDepth > 0 && len(chain) == maxDepth {
+			break
+		}
+		if fallback == "" || seen[fallback] {
+			continue
+		}
+		seen[fallback] = true
+		chain = append(chain, fallback)
+	}
+	return chain
+}
+
+// boundFallbacks applies fallbackChain to a decision, dropping its
+// escalation if the escalation model was cut
+func (p *Plugin) boundFallbacks(decision *RouterDecision) {
+	decision.Fallbacks = fallbackChain(decision.Model, decision.Fallbacks, p.config.Router.MaxFallbacks)
+	if decision.Escalation != nil && !slices.Contains(decision.Fallbacks, decision.Escalation.Model) {
+		decision.Escalation = nil
+	}
+}
+
+// bifrostFallbacks converts fallbacks to Bifrost targets. Provider rewrites
+// can map distinct fallbacks onto the primary target or onto each other, so
+// targets are compared once rewritten.
+func (p *Plugin) bifrostFallbacks(primary schemas.Fallback, fallbacks []string) []schemas.Fallback {
+	seen := map[schemas.Fallback]bool{primary: true}
+	var targets []schemas.Fallback
+	for _, fallback := range fallbacks {
+		target := schemas.Fallback{
+			Provider: p.bifrostProvider(p.inferProviderKind(fallback), fallback),
+			Model:    fallback,
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// IssuedFallbacks records the fallback lists the plugin issued, keyed by a
+// hash of the request input and the list. Bifrost retries fallbacks by
+// re-running plugins on a copy of the routed request, so an attempt is
+// recognised by matching an issued list; a Fallbacks list the client sent
+// itself never matches, and such requests are routed like any other.
+type IssuedFallbacks struct {
+	now       func() time.Time
+	expiries  map[string]time.Time
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewIssuedFallbacks creates an empty record
+func NewIssuedFallbacks(now func() time.Time) *IssuedFallbacks {
+	return &IssuedFallbacks{now: now, expiries: make(map[string]time.Time), lastPrune: now()}
+}
+
+// Issue records the fallbacks of a routed request
+func (f *IssuedFallbacks) Issue(req *schemas.BifrostRequest) {
+	if len(req.Fallbacks) == 0 {
+		return
+	}
+	key, ok := issuedFallbackKey(req)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	f.expiries[key] = now.Add(issuedFallbackTTL)
+	if now.Sub(f.lastPrune) >= issuedFallbackTTL {
+		for k, expiry := range f.expiries {
+			if !now.Before(expiry) {
+				delete(f.expiries, k)
+			}
+		}
+		f.lastPrune = now
+	}
+}
+
+// Issued reports whether req is an attempt at one of the fallbacks the
+// plugin issued
+func (f *IssuedFallbacks) Issued(req *schemas.BifrostRequest) bool {
+	target := schemas.Fallback{Provider: req.Provider, Model: req.Model}
+	if !slices.Contains(req.Fallbacks, target) {
+		return false
+	}
+	key, ok := issuedFallbackKey(req)
+	if !ok {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	expiry, ok := f.expiries[key]
+	return ok && f.now().Before(expiry)
+}
+
+// issuedFallbackKey hashes what a fallback attempt shares with the routed
+// request: its input and fallbacks
+func issuedFallbackKey(req *schemas.BifrostRequest) (string, bool) {
+	data, err := json.Marshal(struct {
+		Input     schemas.RequestInput `json:"input"`
+		Fallbacks []schemas.Fallback   `json:"fallbacks"`
+	}{req.Input, req.Fallbacks})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertFallbackChain checks the invariants of a request's fallback chain
+func assertFallbackChain(t *testing.T, req *schemas.BifrostRequest, maxDepth int) {
+	t.Helper()
+	seen := map[schemas.Fallback]bool{{Provider: req.Provider, Model: req.Model}: true}
+	for _, fallback := range req.Fallbacks {
+		assert.False(t, seen[fallback], "fallback %v repeats the model or an earlier fallback", fallback)
+		seen[fallback] = true
+	}
+	if maxDepth > 0 {
+		assert.LessOrEqual(t, len(req.Fallbacks), maxDepth)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	t.Run("should drop the selected model, repeats and blanks", func(t *testing.T) {
+		chain := fallbackChain("a", []string{"b", "a", "c", "b", "", "d"}, 0)
+		assert.Equal(t, []string{"b", "c", "d"}, chain)
+	})
+
+	t.Run("should cut the chain to the maximum depth", func(t *testing.T) {
+		assert.Equal(t, []string{"b", "c"}, fallbackChain("a", []string{"a", "b", "b", "c", "d"}, 2))
+		assert.Empty(t, fallbackChain("a", nil, 2))
+	})
+
+	t.Run("should drop an escalation that was cut", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Router.MaxFallbacks = 1
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		decision := &RouterDecision{Model: "a", Fallbacks: []string{"b", "c"}, Escalation: &EscalationInfo{Model: "c"}}
+		plugin.boundFallbacks(decision)
+		assert.Equal(t, []string{"b"}, decision.Fallbacks)
+		assert.Nil(t, decision.Escalation)
+	})
+
+	t.Run("should compare Bifrost targets once rewritten", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		primary := schemas.Fallback{Provider: "openai", Model: "openai/gpt-4o"}
+		targets := plugin.bifrostFallbacks(primary, []string{"openai/gpt-4o", "openai/gpt-4o-mini", "openai/gpt-4o-mini"})
+		assert.Equal(t, []schemas.Fallback{{Provider: "openai", Model: "openai/gpt-4o-mini"}}, targets)
+	})
+}
+
+func TestFallbackChainInvariants(t *testing.T) {
+	prompts := []string{
+		"What is 2+2?",
+		"Write a Python function that parses RFC 3339 timestamps.",
+		"Prove that there are infinitely many primes, then generalize the argument.",
+	}
+
+	for _, maxDepth := range []int{0, 1, 3} {
+		t.Run(fmt.Sprintf("should hold with max depth %d", maxDepth), func(t *testing.T) {
+			config := createRouterTestConfig()
+			config.Router.MaxFallbacks = maxDepth
+			config.Cascade = CascadeConfig{Enabled: true}
+			plugin := createRouterTestPluginWithConfig(t, config)
+
+			for _, prompt := range prompts {
+				ctx := context.Background()
+				content := prompt
+				req := &schemas.BifrostRequest{Input: schemas.RequestInput{
+					ChatCompletionInput: &[]schemas.BifrostMessage{{
+						Role:    schemas.ModelChatMessageRoleUser,
+						Content: schemas.MessageContent{ContentStr: &content},
+					}},
+				}}
+				result, _, err := plugin.PreHook(&ctx, req)
+				require.NoError(t, err)
+				assertFallbackChain(t, result, maxDepth)
+			}
+		})
+	}
+
+	t.Run("should hold for fallbacks rewritten by middleware", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Router.MaxFallbacks = 2
+		rewrite := func(next DecideFunc) DecideFunc {
+			return func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+				response, err := next(ctx, req, headers)
+				if err == nil {
+					decision := &response.Decision
+					decision.Fallbacks = append([]string{decision.Model, "openai/gpt-4o-mini"}, decision.Fallbacks...)
+					decision.Fallbacks = append(decision.Fallbacks, "openai/gpt-4o-mini", decision.Model)
+				}
+				return response, err
+			}
+		}
+		plugin, err := NewWithOptions(config, WithMiddleware(rewrite))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		result, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		assertFallbackChain(t, result, 2)
+		assert.Equal(t, "openai/gpt-4o-mini", result.Fallbacks[0].Model)
+	})
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Feature logging modes
+const (
+	// FeatureLogRaw logs features as extracted
+	FeatureLogRaw = "raw"
+	// FeatureLogCoarse drops embeddings, cluster distances, template
+	// fingerprints, per-user statistics and the caller's identity, and
+	// rounds token counts down to their token bucket
+	FeatureLogCoarse = "coarse"
+	// FeatureLogAggregate logs no features or caller identity; features
+	// are only counted into per-period aggregates released with
+	// differential privacy noise
+	FeatureLogAggregate = "aggregate"
+)
+
+// aggregateSensitivity is how many counts one request adds to: requests,
+// token bucket, code flag, math flag and cluster
+const aggregateSensitivity = 5
+
+// tokenBuckets are the token count ranges features are coarsened to,
+// by lower bound
+var tokenBuckets = []struct {
+	min   int
+	label string
+}{
+	{0, "<256"},
+	{256, "256-1k"},
+	{1024, "1k-4k"},
+	{4096, "4k-16k"},
+	{16384, "16k-64k"},
+	{65536, "64k-256k"},
+	{262144, ">=256k"},
+}
+
+// FeatureLoggingConfig controls what decision logs reveal about requests,
+// for privacy review of decision logging
+type FeatureLoggingConfig struct {
+	// Mode is "raw" (default), "coarse" or "aggregate"
+	Mode string `json:"mode"`
+
+	// Period is the length of each aggregate release (default 1h)
+	Period time.Duration `json:"period"`
+	// Epsilon is the differential privacy budget spent on each release.
+	// Every released count gets Laplace noise of scale 5/ε, as one request
+	// adds to five counts (default 1).
+	Epsilon float64 `json:"epsilon"`
+	// MinCount suppresses released counts below it after noise (default 10)
+	MinCount int `json:"min_count"`
+	// Retain is how many releases are kept (default 24)
+	Retain int `json:"retain"`
+}
+
+// BucketAggregate counts one routing bucket's requests by coarse feature
+type BucketAggregate struct {
+	Requests     int            `json:"requests"`
+	TokenBuckets map[string]int `json:"token_buckets"`
+	HasCode      int            `json:"has_code"`
+	HasMath      int            `json:"has_math"`
+	Clusters     map[int]int    `json:"clusters"`
+}
+
+// FeatureAggregate is one period's released feature statistics
+type FeatureAggregate struct {
+	Start   time.Time                   `json:"start"`
+	End     time.Time                   `json:"end"`
+	Epsilon float64                     `json:"epsilon"`
+	Buckets map[Bucket]*BucketAggregate `json:"buckets"`
+}
+
+// FeatureAggregator counts features per period and releases each closed
+// period once, noised, so repeated reads spend no further privacy budget
+type FeatureAggregator struct {
+	config FeatureLoggingConfig
+	now    func() time.Time
+
+	start    time.Time
+	counts   map[Bucket]*BucketAggregate
+	releases []FeatureAggregate // oldest first
+	mu       sync.Mutex
+}
+
+// NewFeatureAggregator creates an aggregator, filling defaults
+func NewFeatureAggregator(config FeatureLoggingConfig, now func() time.Time) (*FeatureAggregator, error) {
+	if config.Period == 0 {
+		config.Period = time.Hour
+	}
+	if config.Epsilon == 0 {
+		config.Epsilon = 1
+	}
+	if config.MinCount == 0 {
+		config.MinCount = 10
+	}
+	if config.Retain == 0 {
+		config.Retain = 24
+	}
+	if config.Period < 0 || config.Epsilon < 0 || config.MinCount < 0 || config.Retain < 0 {
+		return nil, fmt.Errorf("period, epsilon, min_count and retain must be positive")
+	}
+
+	return &FeatureAggregator{
+		config: config,
+		now:    now,
+		start:  now().Truncate(config.Period),
+		counts: make(map[Bucket]*BucketAggregate),
+	}, nil
+}
+
+// Record counts a request's coarse features
+func (fa *FeatureAggregator) Record(bucket Bucket, features RequestFeatures) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+	fa.rotate()
+
+	counts, ok := fa.counts[bucket]
+	if !ok {
+		counts = &BucketAggregate{TokenBuckets: make(map[string]int), Clusters: make(map[int]int)}
+		fa.counts[bucket] = counts
+	}
+	counts.Requests++
+	_, label := tokenBucket(features.TokenCount)
+	counts.TokenBuckets[label]++
+	if features.HasCode {
+		counts.HasCode++
+	}
+	if features.HasMath {
+		counts.HasMath++
+	}
+	counts.Clusters[features.ClusterID]++
+}
+
+// Releases returns the noised aggregates of closed periods, oldest first
+func (fa *FeatureAggregator) Releases() []FeatureAggregate {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+	fa.rotate()
+	return append([]FeatureAggregate{}, fa.releases...)
+}
+
+// rotate releases the current period once it has ended; callers hold mu
+func (fa *FeatureAggregator) rotate() {
+	end := fa.start.Add(fa.config.Period)
+	now := fa.now()
+	if now.Before(end) {
+		return
+	}
+
+	release := FeatureAggregate{
+		Start:   fa.start,
+		End:     end,
+		Epsilon: fa.config.Epsilon,
+		Buckets: make(map[Bucket]*BucketAggregate),
+	}
+	for _, bucket := range []Bucket{BucketCheap, BucketMid, BucketHard} {
+		counts := fa.counts[bucket]
+		if counts == nil {
+			counts = &BucketAggregate{}
+		}
+		// Every bucket and token label is released, observed or not. Only
+		// observed clusters are; min_count makes a rare one unlikely to
+		// show, so clusters are (ε, δ) rather than ε private.
+		noised := &BucketAggregate{
+			Requests:     fa.noise(counts.Requests),
+			TokenBuckets: make(map[string]int),
+			HasCode:      fa.noise(counts.HasCode),
+			HasMath:      fa.noise(counts.HasMath),
+			Clusters:     make(map[int]int),
+		}
+		for _, tokens := range tokenBuckets {
+			if count := fa.noise(counts.TokenBuckets[tokens.label]); count > 0 {
+				noised.TokenBuckets[tokens.label] = count
+			}
+		}
+		for cluster, count := range counts.Clusters {
+			if count := fa.noise(count); count > 0 {
+				noised.Clusters[cluster] = count
+			}
+		}
+		release.Buckets[bucket] = noised
+	}
+
+	fa.releases = append(fa.releases, release)
+	if len(fa.releases) > fa.config.Retain {
+		fa.releases = fa.releases[len(fa.releases)-fa.config.Retain:]
+	}
+	fa.counts = make(map[Bucket]*BucketAggregate)
+	fa.start = now.Truncate(fa.config.Period)
+}
+
+// noise adds Laplace noise to a count, rounding and suppressing counts
+// below the minimum to 0
+func (fa *FeatureAggregator) noise(count int) int {
+	noised := math.Round(float64(count) + laplace(aggregateSensitivity/fa.config.Epsilon))
+	if noised < float64(fa.config.MinCount) {
+		return 0
+	}
+	return int(noised)
+}
+
+// laplace draws from a zero-mean Laplace distribution with the given scale,
+// using the system's secure random source so noise cannot be predicted
+func laplace(scale float64) float64 {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(fmt.Sprintf("failed to read random noise: %v", err))
+	}
+	// Uniform in (-0.5, 0.5)
+	u := (float64(binary.LittleEndian.Uint64(buf[:])>>11)+0.5)/(1<<53) - 0.5
+	return -scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+}
+
+// tokenBucket returns the lower bound and label of a token count's range
+func tokenBucket(tokens int) (int, string) {
+	bucket := tokenBuckets[0]
+	for _, candidate := range tokenBuckets {
+		if tokens >= candidate.min {
+			bucket = candidate
+		}
+	}
+	return bucket.min, bucket.label
+}
+
+// coarsenFeatures keeps only features that do not identify a request or user
+func coarsenFeatures(features RequestFeatures) RequestFeatures {
+	tokens, _ := tokenBucket(features.TokenCount)
+	return RequestFeatures{
+		ClusterID:    features.ClusterID,
+		TokenCount:   tokens,
+		HasCode:      features.HasCode,
+		HasMath:      features.HasMath,
+		NgramEntropy: math.Round(features.NgramEntropy),
+		ContextRatio: math.Round(features.ContextRatio*10) / 10,
+	}
+}
+
+// EncodeDecisionLog serializes a response for the decision log, revealing
+// only what the feature logging mode allows. Use EncodeRouterResponse where
+// full features are needed, e.g. to replay decisions.
+func (p *Plugin) EncodeDecisionLog(response *RouterResponse) ([]byte, error) {
+	return p.encodeDecisionLog(response, true)
+}
+
+// encodeDecisionLog encodes like EncodeDecisionLog, counting the features
+// into aggregates only when aggregate is set, e.g. not for debug captures.
+// Modes other than raw also drop the caller's auth info and session ID.
+func (p *Plugin) encodeDecisionLog(response *RouterResponse, aggregate bool) ([]byte, error) {
+	withheld := *response
+	withheld.AuthInfo = nil
+	withheld.SessionID = ""
+
+	switch p.config.FeatureLogging.Mode {
+	case FeatureLogCoarse:
+		withheld.Features = coarsenFeatures(response.Features)
+		withheld.FeaturePrivacy = FeatureLogCoarse
+		return EncodeRouterResponse(&withheld)
+	case FeatureLogAggregate:
+		if aggregate {
+			p.aggregates.Record(response.Bucket, response.Features)
+		}
+		withheld.FeaturePrivacy = FeatureLogAggregate
+		data, err := EncodeRouterResponse(&withheld)
+		if err != nil {
+			return nil, err
+		}
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		delete(record, "features")
+		return json.Marshal(record)
+	default:
+		return EncodeRouterResponse(response)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecisionLog(t *testing.T) {
+	successRate := 0.9
+	response := &RouterResponse{
+		Decision:  RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+		Bucket:    BucketMid,
+		AuthInfo:  &AuthInfo{Provider: "openai", Subject: "alice", Org: "acme", Tier: "pro"},
+		SessionID: "conv-1",
+		Features: RequestFeatures{
+			Embedding:           []float64{0.1, 0.2},
+			ClusterID:           3,
+			TopPDistances:       []float64{0.4},
+			TokenCount:          3000,
+			HasCode:             true,
+			NgramEntropy:        4.37,
+			ContextRatio:        0.123,
+			UserSuccessRate:     &successRate,
+			TemplateFingerprint: "abc",
+		},
+	}
+	newPlugin := func(t *testing.T, logging FeatureLoggingConfig) *Plugin {
+		config := createRouterTestConfig()
+		config.FeatureLogging = logging
+		return createRouterTestPluginWithConfig(t, config)
+	}
+
+	t.Run("should log raw features by default", func(t *testing.T) {
+		data, err := newPlugin(t, FeatureLoggingConfig{}).EncodeDecisionLog(response)
+		require.NoError(t, err)
+		decoded, err := DecodeRouterResponse(data)
+		require.NoError(t, err)
+		assert.Equal(t, response.Features, decoded.Features)
+		assert.Equal(t, "alice", decoded.AuthInfo.Subject)
+		assert.Equal(t, "conv-1", decoded.SessionID)
+		assert.Empty(t, decoded.FeaturePrivacy)
+	})
+
+	t.Run("should coarsen features", func(t *testing.T) {
+		data, err := newPlugin(t, FeatureLoggingConfig{Mode: FeatureLogCoarse}).EncodeDecisionLog(response)
+		require.NoError(t, err)
+		decoded, err := DecodeRouterResponse(data)
+		require.NoError(t, err)
+		assert.Equal(t, RequestFeatures{
+			ClusterID:    3,
+			TokenCount:   1024,
+			HasCode:      true,
+			NgramEntropy: 4,
+			ContextRatio: 0.1,
+		}, decoded.Features)
+		assert.Equal(t, FeatureLogCoarse, decoded.FeaturePrivacy)
+		assert.Equal(t, "openai/gpt-4o", decoded.Decision.Model)
+		assert.Nil(t, decoded.AuthInfo)
+		assert.Empty(t, decoded.SessionID)
+		assert.NotEmpty(t, response.Features.Embedding, "the response is not modified")
+		assert.NotNil(t, response.AuthInfo, "the response is not modified")
+	})
+
+	t.Run("should withhold features and aggregate them", func(t *testing.T) {
+		plugin := newPlugin(t, FeatureLoggingConfig{Mode: FeatureLogAggregate})
+		data, err := plugin.EncodeDecisionLog(response)
+		require.NoError(t, err)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &record))
+		assert.NotContains(t, record, "features")
+		assert.Nil(t, record["auth_info"])
+		assert.NotContains(t, record, "session_id")
+		assert.Equal(t, FeatureLogAggregate, record["feature_privacy"])
+	})
+
+	t.Run("should keep the decision timestamp in every mode", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+		for _, mode := range []string{FeatureLogRaw, FeatureLogCoarse, FeatureLogAggregate} {
+			plugin := newPlugin(t, FeatureLoggingConfig{Mode: mode})
+			plugin.now = func() time.Time { return now }
+			decision, err := plugin.decide(&RouterRequest{
+				Method: "POST",
+				Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "Hello"}}},
+			}, map[string][]string{"Authorization": {"Bearer sk-test-key"}})
+			require.NoError(t, err)
+			data, err := plugin.EncodeDecisionLog(decision)
+			require.NoError(t, err)
+
+			var record struct {
+				Timestamp time.Time `json:"timestamp"`
+			}
+			require.NoError(t, json.Unmarshal(data, &record))
+			assert.Equal(t, now, record.Timestamp, mode)
+		}
+	})
+
+	t.Run("should reject unknown modes", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.FeatureLogging = FeatureLoggingConfig{Mode: "hashed"}
+		_, err := New(config)
+		assert.ErrorContains(t, err, "invalid feature logging config")
+	})
+}
+
+func TestFeatureAggregator(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 30, 0, 0, time.UTC)
+	aggregator, err := NewFeatureAggregator(FeatureLoggingConfig{Epsilon: 50, MinCount: 5}, func() time.Time { return now })
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		aggregator.Record(BucketCheap, RequestFeatures{ClusterID: 1, TokenCount: 100, HasCode: i%2 == 0})
+	}
+	aggregator.Record(BucketHard, RequestFeatures{ClusterID: 9, TokenCount: 100000})
+
+	t.Run("should hold the open period back", func(t *testing.T) {
+		assert.Empty(t, aggregator.Releases())
+	})
+
+	t.Run("should release noised counts once the period closes", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		releases := aggregator.Releases()
+		require.Len(t, releases, 1)
+		release := releases[0]
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), release.Start)
+		assert.Equal(t, 50.0, release.Epsilon)
+
+		cheap := release.Buckets[BucketCheap]
+		// Laplace noise of scale 0.1 rarely moves a count by 2
+		assert.InDelta(t, 200, cheap.Requests, 2)
+		assert.InDelta(t, 100, cheap.HasCode, 2)
+		assert.InDelta(t, 200, cheap.TokenBuckets["<256"], 2)
+		assert.InDelta(t, 200, cheap.Clusters[1], 2)
+
+		hard := release.Buckets[BucketHard]
+		assert.Zero(t, hard.Requests, "counts below min_count are suppressed")
+		assert.NotContains(t, hard.Clusters, 9)
+		assert.Contains(t, release.Buckets, BucketMid)
+	})
+
+	t.Run("should return the same release on every read", func(t *testing.T) {
+		assert.Equal(t, aggregator.Releases(), aggregator.Releases())
+	})
+
+	t.Run("should serve releases over the admin API", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.FeatureLogging = FeatureLoggingConfig{Mode: FeatureLogAggregate}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		recorder := httptest.NewRecorder()
+		plugin.AdminHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/features", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `[]`, recorder.Body.String())
+	})
+}
+
+func TestLaplace(t *testing.T) {
+	const n = 20000
+	var sum, absSum float64
+	for i := 0; i < n; i++ {
+		x := laplace(2)
+		sum += x
+		absSum += math.Abs(x)
+	}
+	// Mean 0 and mean absolute deviation equal to the scale
+	assert.InDelta(t, 0, sum/n, 0.1)
+	assert.InDelta(t, 2, absSum/n, 0.1)
+}
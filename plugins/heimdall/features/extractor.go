@@ -0,0 +1,402 @@
+// Package features extracts routing features (embeddings, cluster
+// assignment and lexical signals) from requests.
+package features
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// EmbeddingProvider computes a text embedding for cluster assignment
+type EmbeddingProvider interface {
+	Embed(text string) ([]float64, error)
+}
+
+// ClusterAssigner assigns an embedding to its nearest routing clusters,
+// returning the nearest cluster and the distances to the k nearest
+type ClusterAssigner interface {
+	Cluster(embedding []float64, k int) (clusterID int, distances []float64, err error)
+}
+
+// ContextEmbeddingProvider is an EmbeddingProvider whose calls take a
+// context. Extract calls it with the feature budget's deadline.
+type ContextEmbeddingProvider interface {
+	EmbeddingProvider
+	EmbedContext(ctx context.Context, text string) ([]float64, error)
+}
+
+// ContextClusterAssigner is a ClusterAssigner whose calls take a context.
+// Extract calls it with what remains of the feature budget.
+type ContextClusterAssigner interface {
+	ClusterAssigner
+	ClusterContext(ctx context.Context, embedding []float64, k int) (clusterID int, distances []float64, err error)
+}
+
+// FeatureExtractor implements native feature extraction (port of features.ts)
+type FeatureExtractor struct {
+	embedder       EmbeddingProvider // nil uses the hash embedding
+	clusterer      ClusterAssigner   // nil uses the built-in cluster search
+	hashPrefixes   bool              // set PromptPrefixes for prompt-cache estimation
+	embeddingCache sync.Map          // string -> []float64
+	mu             sync.RWMutex
+}
+
+func NewFeatureExtractor() *FeatureExtractor {
+	return &FeatureExtractor{}
+}
+
+// SetEmbeddingProvider replaces the hash embedding with provider. Texts the
+// provider fails to embed fall back to the hash embedding. Embeddings are
+// cached per text, so set the provider before the extractor is used.
+func (fe *FeatureExtractor) SetEmbeddingProvider(provider EmbeddingProvider) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.embedder = provider
+}
+
+// SetClusterAssigner replaces the built-in cluster search with assigner.
+// Requests the assigner fails on fall back to the built-in search.
+func (fe *FeatureExtractor) SetClusterAssigner(assigner ClusterAssigner) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.clusterer = assigner
+}
+
+// SetPrefixHashing hashes each prompt's prefixes into PromptPrefixes, to
+// be matched against a PrefixIndex once the caller is known
+func (fe *FeatureExtractor) SetPrefixHashing(enabled bool) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.hashPrefixes = enabled
+}
+
+// Extract computes routing features for req. Fields pre-populated in
+// req.FeatureOverrides are used as given and their computation is skipped.
+// Context-aware embedding providers and cluster assigners are called under
+// the deadline timeoutMs sets, falling back to the built-in stages once it
+// passes.
+func (fe *FeatureExtractor) Extract(req *core.RouterRequest, artifact *core.AvengersArtifact, timeoutMs int) (*core.RequestFeatures, error) {
+	startTime := time.Now()
+	ctx := context.Background()
+	if timeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, startTime.Add(time.Duration(timeoutMs)*time.Millisecond))
+		defer cancel()
+	}
+	overrides := req.FeatureOverrides
+	if overrides == nil {
+		overrides = &core.FeatureOverrides{}
+	}
+
+	// Extract prompt text from messages
+	promptText := fe.extractPromptText(req)
+
+	features := &core.RequestFeatures{}
+
+	// Get embedding (with caching) and find nearest clusters (simplified -
+	// in production would use FAISS)
+	if overrides.ClusterID != nil {
+		features.Embedding = overrides.Embedding
+		features.ClusterID = *overrides.ClusterID
+	} else {
+		embedding := overrides.Embedding
+		if embedding == nil {
+			embedding = fe.getEmbedding(ctx, promptText)
+		}
+		features.Embedding = embedding
+		features.ClusterID, features.TopPDistances = fe.assignCluster(ctx, embedding, 5)
+	}
+
+	fe.extractText(req, promptText, overrides, features)
+
+	elapsed := time.Since(startTime)
+	if elapsed.Milliseconds() > int64(timeoutMs) {
+		log.Printf("Feature extraction took %dms (budget: %dms)", elapsed.Milliseconds(), timeoutMs)
+	}
+
+	return features, nil
+}
+
+// ExtractLight computes the lexical and context features of req alone,
+// skipping the embedding and cluster assignment, for when the full
+// extraction costs too much. ClusterID is core.NoCluster unless overridden.
+func (fe *FeatureExtractor) ExtractLight(req *core.RouterRequest) (*core.RequestFeatures, error) {
+	overrides := req.FeatureOverrides
+	if overrides == nil {
+		overrides = &core.FeatureOverrides{}
+	}
+	features := &core.RequestFeatures{ClusterID: core.NoCluster}
+	if overrides.ClusterID != nil {
+		features.Embedding = overrides.Embedding
+		features.ClusterID = *overrides.ClusterID
+	}
+	fe.extractText(req, fe.extractPromptText(req), overrides, features)
+	return features, nil
+}
+
+// extractText computes the lexical, context and template features
+func (fe *FeatureExtractor) extractText(req *core.RouterRequest, promptText string, overrides *core.FeatureOverrides, features *core.RequestFeatures) {
+	// Extract lexical features
+	if overrides.HasCode == nil || overrides.HasMath == nil || overrides.NgramEntropy == nil {
+		lexFeatures := fe.extractLexicalFeatures(promptText)
+		features.HasCode = lexFeatures.hasCode
+		features.HasMath = lexFeatures.hasMath
+		features.NgramEntropy = lexFeatures.ngramEntropy
+	}
+	if overrides.HasCode != nil {
+		features.HasCode = *overrides.HasCode
+	}
+	if overrides.HasMath != nil {
+		features.HasMath = *overrides.HasMath
+	}
+	if overrides.NgramEntropy != nil {
+		features.NgramEntropy = *overrides.NgramEntropy
+	}
+
+	// Context analysis
+	if overrides.TokenCount != nil {
+		features.TokenCount = *overrides.TokenCount
+	} else {
+		features.TokenCount = fe.estimateTokens(promptText)
+	}
+	features.ContextRatio = fe.calculateContextRatio(features.TokenCount)
+
+	if req.Body != nil {
+		features.TemplateFingerprint = TemplateFingerprint(req.Body.Messages)
+	}
+
+	fe.mu.RLock()
+	hashPrefixes := fe.hashPrefixes
+	fe.mu.RUnlock()
+	if hashPrefixes {
+		features.PromptPrefixes = HashPrefixes(promptText)
+	}
+}
+
+// assignCluster finds the nearest cluster to embedding and the distances to
+// the k nearest, preferring the configured ClusterAssigner
+func (fe *FeatureExtractor) assignCluster(ctx context.Context, embedding []float64, k int) (int, []float64) {
+	fe.mu.RLock()
+	clusterer := fe.clusterer
+	fe.mu.RUnlock()
+
+	if clusterer != nil {
+		var clusterID int
+		var distances []float64
+		var err error
+		if withContext, ok := clusterer.(ContextClusterAssigner); ok {
+			clusterID, distances, err = withContext.ClusterContext(ctx, embedding, k)
+		} else {
+			clusterID, distances, err = clusterer.Cluster(embedding, k)
+		}
+		if err == nil {
+			return clusterID, distances
+		}
+		log.Printf("Cluster assignment failed, using built-in search: %v", err)
+	}
+
+	nearestClusters := fe.findNearestClusters(embedding, k)
+	return fe.getTopCluster(nearestClusters), fe.getTopDistances(nearestClusters)
+}
+
+type lexicalFeatures struct {
+	hasCode      bool
+	hasMath      bool
+	ngramEntropy float64
+}
+
+func (fe *FeatureExtractor) extractPromptText(req *core.RouterRequest) string {
+	if req.Body == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, msg := range req.Body.Messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (fe *FeatureExtractor) getEmbedding(ctx context.Context, text string) []float64 {
+	// Check cache first
+	if cached, ok := fe.embeddingCache.Load(text); ok {
+		return cached.([]float64)
+	}
+
+	fe.mu.RLock()
+	embedder := fe.embedder
+	fe.mu.RUnlock()
+
+	if embedder != nil {
+		var embedding []float64
+		var err error
+		if withContext, ok := embedder.(ContextEmbeddingProvider); ok {
+			embedding, err = withContext.EmbedContext(ctx, text)
+		} else {
+			embedding, err = embedder.Embed(text)
+		}
+		if err == nil && len(embedding) == 0 {
+			err = fmt.Errorf("empty embedding")
+		}
+		if err == nil {
+			fe.embeddingCache.Store(text, embedding)
+			return embedding
+		}
+		// Not cached, so the provider is asked again next time
+		log.Printf("Embedding provider failed, using fallback embedding: %v", err)
+		return fe.generateFallbackEmbedding(text)
+	}
+
+	// Generate fallback embedding using deterministic hash
+	embedding := fe.generateFallbackEmbedding(text)
+	fe.embeddingCache.Store(text, embedding)
+	return embedding
+}
+
+func (fe *FeatureExtractor) generateFallbackEmbedding(text string) []float64 {
+	// Create deterministic embedding from text hash (similar to TS fallback)
+	hash := sha256.Sum256([]byte(text))
+	embedding := make([]float64, 384) // Standard sentence-transformer dimension
+
+	for i := 0; i < 384; i++ {
+		byteIndex := i % len(hash)
+		rawValue := float64(hash[byteIndex]) / 255.0
+		embedding[i] = (rawValue - 0.5) * 2 // Normalize to [-1, 1]
+	}
+
+	return embedding
+}
+
+type clusterMatch struct {
+	id       int
+	distance float64
+}
+
+func (fe *FeatureExtractor) findNearestClusters(embedding []float64, k int) []clusterMatch {
+	// Simplified cluster matching - in production would use FAISS index
+	// For now, return mock clusters with deterministic distances
+	var clusters []clusterMatch
+
+	for i := 0; i < k; i++ {
+		// Generate deterministic distance based on embedding
+		dist := math.Mod(float64(i)+embedding[i%len(embedding)], 1.0)
+		clusters = append(clusters, clusterMatch{id: i, distance: dist})
+	}
+
+	// Sort by distance
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].distance < clusters[j].distance
+	})
+
+	return clusters
+}
+
+func (fe *FeatureExtractor) getTopCluster(clusters []clusterMatch) int {
+	if len(clusters) == 0 {
+		return 0
+	}
+	return clusters[0].id
+}
+
+func (fe *FeatureExtractor) getTopDistances(clusters []clusterMatch) []float64 {
+	var distances []float64
+	for _, cluster := range clusters {
+		distances = append(distances, cluster.distance)
+	}
+	return distances
+}
+
+func (fe *FeatureExtractor) extractLexicalFeatures(text string) lexicalFeatures {
+	// Code detection patterns (port of TypeScript regexes)
+	codePatterns := []*regexp.Regexp{
+		regexp.MustCompile("```[\\s\\S]*?```"),        // Code blocks
+		regexp.MustCompile("`[^`]+`"),                 // Inline code
+		regexp.MustCompile("function\\s+\\w+\\s*\\("), // Function definitions
+		regexp.MustCompile("class\\s+\\w+"),           // Class definitions
+		regexp.MustCompile("\\bimport\\s+.*?from"),    // Import statements
+		regexp.MustCompile("\\bdef\\s+\\w+\\s*\\("),   // Python functions
+		regexp.MustCompile("\\bconst\\s+\\w+\\s*="),   // JS const declarations
+		regexp.MustCompile("\\blet\\s+\\w+\\s*="),     // JS let declarations
+	}
+
+	hasCode := false
+	for _, pattern := range codePatterns {
+		if pattern.MatchString(text) {
+			hasCode = true
+			break
+		}
+	}
+
+	// Math detection patterns
+	mathPatterns := []*regexp.Regexp{
+		regexp.MustCompile("\\$[^$]+\\$"),                           // LaTeX math
+		regexp.MustCompile("\\\\\\([^)]+\\\\\\)"),                   // LaTeX inline math
+		regexp.MustCompile("\\\\\\[[^\\]]+\\\\\\]"),                 // LaTeX display math
+		regexp.MustCompile("[∫∑∏√∞≤≥≠±×÷]"),                         // Math symbols
+		regexp.MustCompile("\\b\\d+\\.\\d*[eE][+-]?\\d+"),           // Scientific notation
+		regexp.MustCompile("(?i)matrix|vector|derivative|integral"), // Math terms
+	}
+
+	hasMath := false
+	for _, pattern := range mathPatterns {
+		if pattern.MatchString(text) {
+			hasMath = true
+			break
+		}
+	}
+
+	// N-gram entropy calculation (simplified)
+	ngramEntropy := fe.calculateNgramEntropy(text, 3)
+
+	return lexicalFeatures{
+		hasCode:      hasCode,
+		hasMath:      hasMath,
+		ngramEntropy: ngramEntropy,
+	}
+}
+
+func (fe *FeatureExtractor) calculateNgramEntropy(text string, n int) float64 {
+	ngrams := make(map[string]int)
+	cleanText := strings.ToLower(regexp.MustCompile("[^a-z\\s]").ReplaceAllString(text, ""))
+
+	// Generate n-grams
+	total := 0
+	for i := 0; i <= len(cleanText)-n; i++ {
+		ngram := cleanText[i : i+n]
+		ngrams[ngram]++
+		total++
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	// Calculate entropy
+	entropy := 0.0
+	for _, count := range ngrams {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+func (fe *FeatureExtractor) estimateTokens(text string) int {
+	// Rough token estimation: ~4 characters per token
+	return int(math.Ceil(float64(len(text)) / 4.0))
+}
+
+func (fe *FeatureExtractor) calculateContextRatio(tokenCount int) float64 {
+	maxContext := 128000.0 // Default context window
+	return math.Min(float64(tokenCount)/maxContext, 1.0)
+}
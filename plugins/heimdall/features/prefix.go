@@ -0,0 +1,152 @@
+package features
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// prefixBlock is the granularity prefixes are matched at, in bytes. Provider
+// prompt caches also work in blocks (e.g. 128 or 256 tokens), so a partial
+// block is never counted as cached.
+const prefixBlock = 512
+
+// PrefixStats counts prefix index lookups
+type PrefixStats struct {
+	Lookups  int64 `json:"lookups"`
+	Hits     int64 `json:"hits"`
+	Prefixes int   `json:"prefixes"`
+}
+
+type prefixKey struct {
+	scope string
+	hash  uint64
+}
+
+type prefixEntry struct {
+	key  prefixKey
+	seen time.Time
+}
+
+// PrefixIndex remembers hashes of recent prompts' prefixes to estimate how
+// much of a new prompt a provider's prompt cache is likely to hold. Caches
+// are not shared between providers or accounts, so prefixes are held per
+// scope, e.g. a provider kind and tenant.
+type PrefixIndex struct {
+	mu         sync.Mutex
+	seen       map[prefixKey]time.Time // scoped prefix hash -> last seen
+	order      []prefixEntry           // insertion order, for eviction
+	ttl        time.Duration
+	maxEntries int
+	minBytes   int
+	now        func() time.Time
+	stats      PrefixStats
+}
+
+// NewPrefixIndex creates an index of prefixes seen within ttl, holding at
+// most maxEntries. Matches shorter than minTokens (at ~4 bytes per token)
+// are not counted, as providers do not cache short prompts.
+func NewPrefixIndex(ttl time.Duration, maxEntries, minTokens int, now func() time.Time) *PrefixIndex {
+	if now == nil {
+		now = time.Now
+	}
+	return &PrefixIndex{
+		seen:       make(map[prefixKey]time.Time),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		minBytes:   minTokens * 4,
+		now:        now,
+	}
+}
+
+// HashPrefixes hashes text's prefixes at each block boundary, or returns
+// nil for text shorter than a block
+func HashPrefixes(text string) *core.PromptPrefixes {
+	if len(text) < prefixBlock {
+		return nil
+	}
+
+	// One running hash, sampled at each block boundary
+	hashes := make([]uint64, 0, len(text)/prefixBlock)
+	h := fnv.New64a()
+	for end := prefixBlock; end <= len(text); end += prefixBlock {
+		h.Write([]byte(text[end-prefixBlock : end]))
+		hashes = append(hashes, h.Sum64())
+	}
+	return &core.PromptPrefixes{Hashes: hashes, Length: len(text)}
+}
+
+// Match returns the fraction of the prompt covered by the longest of its
+// prefixes recorded in scope within the TTL
+func (pi *PrefixIndex) Match(scope string, prefixes *core.PromptPrefixes) float64 {
+	if prefixes == nil || prefixes.Length == 0 {
+		return 0
+	}
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	now := pi.now()
+	matched := 0
+	for i := len(prefixes.Hashes) - 1; i >= 0; i-- {
+		if seen, ok := pi.seen[prefixKey{scope, prefixes.Hashes[i]}]; ok && now.Sub(seen) <= pi.ttl {
+			matched = (i + 1) * prefixBlock
+			break
+		}
+	}
+
+	pi.stats.Lookups++
+	if matched == 0 || matched < pi.minBytes {
+		return 0
+	}
+	pi.stats.Hits++
+	return float64(matched) / float64(prefixes.Length)
+}
+
+// Record notes that the prompt was sent in scope, e.g. to the selected
+// model's provider, so its prefixes match later prompts there
+func (pi *PrefixIndex) Record(scope string, prefixes *core.PromptPrefixes) {
+	if prefixes == nil {
+		return
+	}
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	now := pi.now()
+	for _, hash := range prefixes.Hashes {
+		key := prefixKey{scope, hash}
+		pi.seen[key] = now
+		pi.order = append(pi.order, prefixEntry{key: key, seen: now})
+	}
+	pi.evict(now)
+}
+
+// evict drops expired prefixes, then the oldest beyond maxEntries (or
+// sightings beyond twice that); callers hold mu
+func (pi *PrefixIndex) evict(now time.Time) {
+	drop := 0
+	for drop < len(pi.order) {
+		entry := pi.order[drop]
+		if now.Sub(entry.seen) <= pi.ttl && len(pi.seen) <= pi.maxEntries && len(pi.order)-drop <= 2*pi.maxEntries {
+			break
+		}
+		// Only the latest sighting of a prefix owns its map entry
+		if pi.seen[entry.key].Equal(entry.seen) {
+			delete(pi.seen, entry.key)
+		}
+		drop++
+	}
+	pi.order = pi.order[drop:]
+}
+
+// GetStats returns lookup counts and the number of prefixes held
+func (pi *PrefixIndex) GetStats() PrefixStats {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	stats := pi.stats
+	stats.Prefixes = len(pi.seen)
+	return stats
+}
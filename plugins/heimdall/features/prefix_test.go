@@ -0,0 +1,70 @@
+package features
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrefixIndex(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	system := strings.Repeat("You are a meticulous assistant. ", 200) // 6400 bytes
+
+	t.Run("should estimate the share of a prompt seen recently", func(t *testing.T) {
+		index := NewPrefixIndex(5*time.Minute, 1000, 1024, clock)
+		first := HashPrefixes(system + "First question")
+		if ratio := index.Match("openai", first); ratio != 0 {
+			t.Fatalf("first sighting ratio = %v, want 0", ratio)
+		}
+		index.Record("openai", first)
+		prompt := system + strings.Repeat("Second question. ", 100)
+		ratio := index.Match("openai", HashPrefixes(prompt))
+		want := float64(len(system)/prefixBlock*prefixBlock) / float64(len(prompt))
+		if ratio != want {
+			t.Errorf("ratio = %v, want %v", ratio, want)
+		}
+		if stats := index.GetStats(); stats.Lookups != 2 || stats.Hits != 1 {
+			t.Errorf("stats = %+v, want 2 lookups and 1 hit", stats)
+		}
+	})
+
+	t.Run("should keep scopes apart", func(t *testing.T) {
+		index := NewPrefixIndex(5*time.Minute, 1000, 1024, clock)
+		index.Record("openai", HashPrefixes(system+"a"))
+		if ratio := index.Match("anthropic", HashPrefixes(system+"b")); ratio != 0 {
+			t.Errorf("ratio in another scope = %v, want 0", ratio)
+		}
+		if ratio := index.Match("openai", HashPrefixes(system+"b")); ratio == 0 {
+			t.Error("ratio in the recorded scope = 0, want a match")
+		}
+	})
+
+	t.Run("should ignore short and expired prefixes", func(t *testing.T) {
+		index := NewPrefixIndex(5*time.Minute, 1000, 1024, clock)
+		short := strings.Repeat("x", 2*prefixBlock)
+		index.Record("openai", HashPrefixes(short+"a"))
+		if ratio := index.Match("openai", HashPrefixes(short+"b")); ratio != 0 {
+			t.Errorf("short prefix ratio = %v, want 0", ratio)
+		}
+		if prefixes := HashPrefixes("tiny"); prefixes != nil {
+			t.Errorf("prefixes of a prompt under a block = %+v, want nil", prefixes)
+		}
+
+		index.Record("openai", HashPrefixes(system))
+		now = now.Add(6 * time.Minute)
+		if ratio := index.Match("openai", HashPrefixes(system)); ratio != 0 {
+			t.Errorf("expired prefix ratio = %v, want 0", ratio)
+		}
+	})
+
+	t.Run("should stay within its bound", func(t *testing.T) {
+		index := NewPrefixIndex(time.Hour, 20, 0, clock)
+		for i := 0; i < 50; i++ {
+			index.Record("openai", HashPrefixes(strings.Repeat(string(rune('a'+i%26)), 4*prefixBlock)+strings.Repeat("y", i)))
+		}
+		if stats := index.GetStats(); stats.Prefixes > 20 {
+			t.Errorf("held %d prefixes, want at most 20", stats.Prefixes)
+		}
+	})
+}
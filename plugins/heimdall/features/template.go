@@ -0,0 +1,48 @@
+package features
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// templateSlots match the spans templated prompts typically vary in, most
+// specific first so e.g. a UUID is not split into numbers
+var templateSlots = []*regexp.Regexp{
+	regexp.MustCompile(`https?://\S+`),                                                         // URLs
+	regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.-]+`),                                             // Emails
+	regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), // UUIDs
+	regexp.MustCompile(`(?i)\b[0-9a-f]{16,}\b`),                                                // Hashes and hex IDs
+	regexp.MustCompile(`"[^"\n]*"|'[^'\n]*'`),                                                  // Quoted strings
+	regexp.MustCompile(`(?:\.{0,2}/)?(?:[\w.-]+/)+[\w.-]+`),                                    // Paths
+	regexp.MustCompile(`[-+]?\d+(?:[.,:/-]\d+)*`),                                              // Numbers, dates and times
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// TemplateFingerprint hashes the structure of a conversation: each message's
+// role and content with variable spans (numbers, quoted strings, IDs, URLs,
+// paths) masked. Prompts rendered from the same template with different slot
+// values share a fingerprint.
+func TemplateFingerprint(messages []core.ChatMessage) string {
+	if len(messages) == 0 {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(hash, "%s\x00%s\x00", msg.Role, templateSkeleton(msg.Content))
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)[:8])
+}
+
+// templateSkeleton masks a text's variable spans and normalizes whitespace
+func templateSkeleton(text string) string {
+	for _, slot := range templateSlots {
+		text = slot.ReplaceAllString(text, "\x01")
+	}
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
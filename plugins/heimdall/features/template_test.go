@@ -0,0 +1,65 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+func TestTemplateFingerprint(t *testing.T) {
+	conversation := func(system, user string) []core.ChatMessage {
+		return []core.ChatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		}
+	}
+	system := "You are a coding agent. Work in the repository at /srv/repos/app."
+
+	same := []struct {
+		name string
+		a, b string
+	}{
+		{"numbers", "Summarize issue #4211 opened 2025-01-03", "Summarize issue #97 opened 2024-12-30"},
+		{"quoted strings", `Rename "fooBar" to "foo_bar"`, `Rename "x" to "renamedVariable"`},
+		{"ids and urls", "Fetch https://api.example.com/v1/items/9 for 3f2b9c1e-8a7d-4c2e-9b1a-0e5d6c7f8a9b",
+			"Fetch http://localhost:8080/items for 00000000-0000-0000-0000-000000000000"},
+		{"paths", "Open src/handlers/user.go and fix the bug", "Open internal/db/conn.go and fix the bug"},
+		{"whitespace", "Explain  the\n\nerror", "Explain the error"},
+	}
+	for _, tt := range same {
+		t.Run("should ignore "+tt.name, func(t *testing.T) {
+			a := TemplateFingerprint(conversation(system, tt.a))
+			b := TemplateFingerprint(conversation(system, tt.b))
+			if a != b {
+				t.Errorf("fingerprints differ: %s vs %s", a, b)
+			}
+		})
+	}
+
+	t.Run("should distinguish template structure", func(t *testing.T) {
+		a := TemplateFingerprint(conversation(system, "Summarize issue 42"))
+		if b := TemplateFingerprint(conversation(system, "Close issue 42")); a == b {
+			t.Error("different instructions should not share a fingerprint")
+		}
+		if b := TemplateFingerprint([]core.ChatMessage{{Role: "user", Content: "Summarize issue 42"}}); a == b {
+			t.Error("different message structure should not share a fingerprint")
+		}
+	})
+
+	t.Run("should be empty without messages", func(t *testing.T) {
+		if fp := TemplateFingerprint(nil); fp != "" {
+			t.Errorf("expected empty fingerprint, got %q", fp)
+		}
+	})
+
+	t.Run("should be extracted as a feature", func(t *testing.T) {
+		req := &core.RouterRequest{Body: &core.RequestBody{Messages: conversation(system, "Summarize issue 42")}}
+		features, err := NewFeatureExtractor().Extract(req, &core.AvengersArtifact{}, 25)
+		if err != nil {
+			t.Fatalf("Feature extraction failed: %v", err)
+		}
+		if features.TemplateFingerprint != TemplateFingerprint(req.Body.Messages) {
+			t.Errorf("unexpected fingerprint %q", features.TemplateFingerprint)
+		}
+	})
+}
@@ -0,0 +1,252 @@
+// Package forecast projects next-period routing volume and spend from a
+// decision log, for capacity planning.
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/artifact"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// Options configures a forecast
+type Options struct {
+	// Period is the length of each history period and of the forecast
+	// (default 24h)
+	Period time.Duration
+	// Until ends the history; periods are counted back from it. Zero uses
+	// the latest sample's timestamp.
+	Until time.Time
+	// Confidence is the coverage of the reported intervals (default 0.9)
+	Confidence float64
+
+	// Pricing prices models for the spend forecast; without it spend is
+	// not forecast
+	Pricing map[string]catalog.ModelPricing
+	// OutputTokens is the assumed completion length per request, since
+	// decision logs only record prompt tokens
+	OutputTokens int
+}
+
+// Interval is a projected value with its prediction interval
+type Interval struct {
+	Expected float64 `json:"expected"`
+	Low      float64 `json:"low"`
+	High     float64 `json:"high"`
+}
+
+// BucketForecast projects one bucket's share of traffic
+type BucketForecast struct {
+	// Share is the bucket's fraction of the expected requests
+	Share    float64  `json:"share"`
+	Requests Interval `json:"requests"`
+}
+
+// Forecast projects the period after the history
+type Forecast struct {
+	Period         string    `json:"period"`
+	HistoryPeriods int       `json:"history_periods"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Confidence     float64   `json:"confidence"`
+
+	Requests  Interval                       `json:"requests"`
+	Buckets   map[core.Bucket]BucketForecast `json:"buckets"`
+	Providers map[string]Interval            `json:"providers"`
+
+	// Spend is in USD; nil without pricing
+	Spend *Interval `json:"spend,omitempty"`
+	// UnpricedModels were routed to but have no pricing, so their spend is
+	// not counted
+	UnpricedModels []string `json:"unpriced_models,omitempty"`
+
+	// Skipped counts samples without a timestamp or before the first
+	// complete period
+	Skipped int `json:"skipped"`
+}
+
+// history holds per-period totals, oldest first
+type history struct {
+	requests  []float64
+	buckets   map[core.Bucket][]float64
+	providers map[string][]float64
+	spend     []float64
+}
+
+// Project forecasts the period following the samples' history
+func Project(samples []artifact.Sample, opts Options) (*Forecast, error) {
+	if opts.Period == 0 {
+		opts.Period = 24 * time.Hour
+	}
+	if opts.Period < 0 {
+		return nil, fmt.Errorf("period must be positive")
+	}
+	if opts.Confidence == 0 {
+		opts.Confidence = 0.9
+	}
+	if opts.Confidence <= 0 || opts.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	if opts.OutputTokens < 0 {
+		return nil, fmt.Errorf("output tokens must not be negative")
+	}
+
+	until := opts.Until
+	var earliest time.Time
+	for _, sample := range samples {
+		if sample.Timestamp.IsZero() {
+			continue
+		}
+		if opts.Until.IsZero() && sample.Timestamp.After(until) {
+			until = sample.Timestamp
+		}
+		if earliest.IsZero() || sample.Timestamp.Before(earliest) {
+			earliest = sample.Timestamp
+		}
+	}
+	periods := 0
+	if !earliest.IsZero() {
+		periods = int(until.Sub(earliest) / opts.Period)
+	}
+	if periods < 2 {
+		return nil, fmt.Errorf("need at least 2 complete %s periods of timestamped decisions, have %d", opts.Period, max(periods, 0))
+	}
+
+	forecast := &Forecast{
+		Period:         opts.Period.String(),
+		HistoryPeriods: periods,
+		Start:          until,
+		End:            until.Add(opts.Period),
+		Confidence:     opts.Confidence,
+		Buckets:        make(map[core.Bucket]BucketForecast),
+		Providers:      make(map[string]Interval),
+	}
+	h := history{
+		requests:  make([]float64, periods),
+		buckets:   make(map[core.Bucket][]float64),
+		providers: make(map[string][]float64),
+		spend:     make([]float64, periods),
+	}
+	unpriced := make(map[string]bool)
+
+	start := until.Add(-time.Duration(periods) * opts.Period)
+	for _, sample := range samples {
+		if sample.Timestamp.IsZero() || sample.Timestamp.Before(start) || sample.Timestamp.After(until) {
+			forecast.Skipped++
+			continue
+		}
+		// The last period also holds samples logged exactly at until
+		i := min(int(sample.Timestamp.Sub(start)/opts.Period), periods-1)
+
+		h.requests[i]++
+		series(h.buckets, sample.Bucket, periods)[i]++
+		series(h.providers, provider(sample), periods)[i]++
+
+		if opts.Pricing != nil {
+			pricing, ok := opts.Pricing[sample.Decision.Model]
+			if !ok {
+				unpriced[sample.Decision.Model] = true
+				continue
+			}
+			h.spend[i] += (float64(sample.Features.TokenCount)*pricing.InPerMillion +
+				float64(opts.OutputTokens)*pricing.OutPerMillion) / 1e6
+		}
+	}
+
+	z := math.Sqrt2 * math.Erfinv(opts.Confidence)
+	forecast.Requests = project(h.requests, z)
+	for bucket, counts := range h.buckets {
+		requests := project(counts, z)
+		share := 0.0
+		if forecast.Requests.Expected > 0 {
+			share = requests.Expected / forecast.Requests.Expected
+		}
+		forecast.Buckets[bucket] = BucketForecast{Share: share, Requests: requests}
+	}
+	for name, counts := range h.providers {
+		forecast.Providers[name] = project(counts, z)
+	}
+	if opts.Pricing != nil {
+		spend := project(h.spend, z)
+		forecast.Spend = &spend
+		for model := range unpriced {
+			forecast.UnpricedModels = append(forecast.UnpricedModels, model)
+		}
+		sort.Strings(forecast.UnpricedModels)
+	}
+	return forecast, nil
+}
+
+// series returns the per-period counts under key, creating them
+func series[K comparable](m map[K][]float64, key K, periods int) []float64 {
+	counts, ok := m[key]
+	if !ok {
+		counts = make([]float64, periods)
+		m[key] = counts
+	}
+	return counts
+}
+
+// provider names the provider a sample was routed to: the decision's kind,
+// else the model's provider prefix
+func provider(sample artifact.Sample) string {
+	if sample.Decision.Kind != "" {
+		return sample.Decision.Kind
+	}
+	if prefix, _, ok := strings.Cut(sample.Decision.Model, "/"); ok {
+		return prefix
+	}
+	return "unknown"
+}
+
+// project extrapolates a series one period ahead with a least-squares
+// trend, bounding it by a normal prediction interval of z standard errors.
+// Two points cannot fit a trend and its error, so their mean and spread
+// are used instead. Volumes cannot be negative, so bounds are clipped at 0.
+func project(values []float64, z float64) Interval {
+	n := float64(len(values))
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= n
+
+	var expected, stderr float64
+	if len(values) < 3 {
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		expected = mean
+		stderr = math.Sqrt(variance / (n - 1) * (1 + 1/n))
+	} else {
+		xMean := (n - 1) / 2
+		var sxx, sxy float64
+		for i, v := range values {
+			dx := float64(i) - xMean
+			sxx += dx * dx
+			sxy += dx * (v - mean)
+		}
+		slope := sxy / sxx
+		intercept := mean - slope*xMean
+
+		sse := 0.0
+		for i, v := range values {
+			residual := v - (intercept + slope*float64(i))
+			sse += residual * residual
+		}
+		expected = intercept + slope*n
+		stderr = math.Sqrt(sse / (n - 2) * (1 + 1/n + (n-xMean)*(n-xMean)/sxx))
+	}
+
+	return Interval{
+		Expected: math.Max(expected, 0),
+		Low:      math.Max(expected-z*stderr, 0),
+		High:     math.Max(expected+z*stderr, 0),
+	}
+}
@@ -0,0 +1,94 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/artifact"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+func sample(at time.Time, bucket core.Bucket, model string, tokens int) artifact.Sample {
+	var s artifact.Sample
+	s.Timestamp = at
+	s.Bucket = bucket
+	s.Features.TokenCount = tokens
+	s.Decision.Model = model
+	return s
+}
+
+func TestProject(t *testing.T) {
+	until := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// Cheap traffic grows by 2 requests a day (2, 4, 6), hard traffic is flat
+	var samples []artifact.Sample
+	for d := 0; d < 3; d++ {
+		dayStart := until.Add(-time.Duration(3-d) * day)
+		samples = append(samples, sample(dayStart, core.BucketHard, "anthropic/claude-3-opus", 2000))
+		for i := 0; i < 2*(d+1); i++ {
+			samples = append(samples, sample(dayStart.Add(time.Hour), core.BucketCheap, "openai/gpt-4o-mini", 1000))
+		}
+	}
+
+	t.Run("should extrapolate trends per bucket and provider", func(t *testing.T) {
+		forecast, err := Project(samples, Options{Until: until})
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, forecast.HistoryPeriods)
+		assert.Equal(t, until, forecast.Start)
+		assert.Equal(t, until.Add(day), forecast.End)
+		assert.InDelta(t, 9, forecast.Requests.Expected, 1e-9)
+		assert.InDelta(t, 8, forecast.Buckets[core.BucketCheap].Requests.Expected, 1e-9)
+		assert.InDelta(t, 8.0/9, forecast.Buckets[core.BucketCheap].Share, 1e-9)
+		assert.InDelta(t, 1, forecast.Buckets[core.BucketHard].Requests.Expected, 1e-9)
+		assert.InDelta(t, 8, forecast.Providers["openai"].Expected, 1e-9)
+		assert.Nil(t, forecast.Spend)
+	})
+
+	t.Run("should widen intervals with noise", func(t *testing.T) {
+		noisy := append([]artifact.Sample{}, samples...)
+		noisy = append(noisy, sample(until.Add(-day), core.BucketHard, "anthropic/claude-3-opus", 2000))
+
+		forecast, err := Project(noisy, Options{Until: until})
+		require.NoError(t, err)
+		hard := forecast.Buckets[core.BucketHard].Requests
+		assert.Less(t, hard.Low, hard.Expected)
+		assert.Greater(t, hard.High, hard.Expected)
+		assert.GreaterOrEqual(t, hard.Low, 0.0)
+	})
+
+	t.Run("should forecast spend for priced models", func(t *testing.T) {
+		forecast, err := Project(samples, Options{
+			Until:        until,
+			Pricing:      map[string]catalog.ModelPricing{"openai/gpt-4o-mini": {InPerMillion: 1, OutPerMillion: 2}},
+			OutputTokens: 500,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, forecast.Spend)
+		// 8 requests at 1000 prompt and 500 completion tokens
+		assert.InDelta(t, 8*0.002, forecast.Spend.Expected, 1e-12)
+		assert.Equal(t, []string{"anthropic/claude-3-opus"}, forecast.UnpricedModels)
+	})
+
+	t.Run("should skip samples outside the history", func(t *testing.T) {
+		extra := append([]artifact.Sample{}, samples...)
+		extra = append(extra, sample(time.Time{}, core.BucketMid, "a", 0), sample(until.Add(time.Hour), core.BucketMid, "a", 0))
+
+		forecast, err := Project(extra, Options{Until: until})
+		require.NoError(t, err)
+		assert.Equal(t, 2, forecast.Skipped)
+		assert.NotContains(t, forecast.Buckets, core.BucketMid)
+	})
+
+	t.Run("should require two complete periods", func(t *testing.T) {
+		_, err := Project(samples[8:], Options{Until: until})
+		assert.ErrorContains(t, err, "need at least 2 complete")
+		_, err = Project(samples, Options{Confidence: 1})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/corpus"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden decisions")
+
+const goldenDecisionsPath = "testdata/golden_decisions.json"
+
+// goldenMinAgreement is the fewest prompts of each kind, out of 4, that
+// must be routed to their expected bucket
+const goldenMinAgreement = 3
+
+// goldenDecision is the routing of one synthetic prompt
+type goldenDecision struct {
+	ID       string `json:"id"`
+	Expected Bucket `json:"expected"`
+	Bucket   Bucket `json:"bucket"`
+	Model    string `json:"model"`
+}
+
+// corpusRequest converts a synthetic prompt to a Bifrost request
+func corpusRequest(prompt corpus.Prompt) *schemas.BifrostRequest {
+	messages := make([]schemas.BifrostMessage, 0, len(prompt.Messages))
+	for _, msg := range prompt.Messages {
+		content := msg.Content
+		message := schemas.BifrostMessage{
+			Role:    schemas.ModelChatMessageRole(msg.Role),
+			Content: schemas.MessageContent{ContentStr: &content},
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]schemas.ToolCall, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				id, name := call.ID, call.Name
+				calls = append(calls, schemas.ToolCall{ID: &id, Function: schemas.FunctionCall{Name: &name, Arguments: call.Arguments}})
+			}
+			message.AssistantMessage = &schemas.AssistantMessage{ToolCalls: &calls}
+		}
+		if msg.ToolCallID != "" {
+			id := msg.ToolCallID
+			message.ToolMessage = &schemas.ToolMessage{ToolCallID: &id}
+		}
+		messages = append(messages, message)
+	}
+
+	req := &schemas.BifrostRequest{Input: schemas.RequestInput{ChatCompletionInput: &messages}}
+	if len(prompt.Tools) > 0 {
+		tools := make([]schemas.Tool, 0, len(prompt.Tools))
+		for _, tool := range prompt.Tools {
+			tools = append(tools, schemas.Tool{Type: "function", Function: schemas.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  schemas.FunctionParameters{Type: "object"},
+			}})
+		}
+		req.Params = &schemas.ModelParameters{Tools: &tools}
+	}
+	return req
+}
+
+// TestGoldenDecisions routes a synthetic corpus and compares the decisions
+// with the committed ones, so routing changes show up as a reviewable diff
+func TestGoldenDecisions(t *testing.T) {
+	prompts, err := corpus.Generate(corpus.Options{Seed: 1, PerKind: 4})
+	require.NoError(t, err)
+	// Thresholds that separate the built-in triage's probabilities for
+	// short, code, mid-length and math prompts
+	config := createRouterTestConfig()
+	config.Router.Thresholds = BucketThresholds{Cheap: 0.4, Hard: 0.4}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	decisions := make([]goldenDecision, 0, len(prompts))
+	agreed := make(map[corpus.Kind]int)
+	for _, prompt := range prompts {
+		ctx := context.Background()
+		req, shortCircuit, err := plugin.PreHook(&ctx, corpusRequest(prompt))
+		require.NoError(t, err, prompt.ID)
+		require.Nil(t, shortCircuit, prompt.ID)
+
+		bucket, _ := ctx.Value("heimdall_bucket").(Bucket)
+		decisions = append(decisions, goldenDecision{ID: prompt.ID, Expected: prompt.Expected, Bucket: bucket, Model: req.Model})
+		if bucket == prompt.Expected {
+			agreed[prompt.Kind]++
+		}
+	}
+	for _, kind := range corpus.Kinds {
+		assert.GreaterOrEqual(t, agreed[kind], goldenMinAgreement, "%s: %d/4 routed to the expected bucket", kind, agreed[kind])
+	}
+
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	require.NoError(t, err)
+	data = append(data, '\n')
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(goldenDecisionsPath, data, 0o644))
+		return
+	}
+	golden, err := os.ReadFile(goldenDecisionsPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(data), "review the changes, then run go test . -run GoldenDecisions -update-golden")
+}
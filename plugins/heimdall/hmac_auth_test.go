@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedRequest(secret, service string, signedAt time.Time, body []byte) *RouterRequest {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	return &RouterRequest{
+		Headers: map[string][]string{
+			auth.HMACServiceHeader:   {service},
+			auth.HMACTimestampHeader: {timestamp},
+			auth.HMACSignatureHeader: {hex.EncodeToString(auth.SignRequest([]byte(secret), timestamp, body))},
+		},
+		RawBody: body,
+	}
+}
+
+func TestHMACAuthRejection(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	adapter, err := auth.NewHMACAuthAdapter(auth.HMACAuthConfig{
+		Services: map[string]auth.HMACServiceConfig{"billing": {Secret: "s3cret"}},
+	})
+	require.NoError(t, err)
+	plugin.authRegistry = auth.NewAuthAdapterRegistry()
+	plugin.authRegistry.Register(adapter)
+
+	forged := signedRequest("wrong", "billing", time.Now(), []byte("{}"))
+	ctx := context.WithValue(context.Background(), "http_headers", forged.Headers)
+	ctx = context.WithValue(ctx, RawBodyContextKey, forged.RawBody)
+
+	_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, shortCircuit)
+	require.NotNil(t, shortCircuit.Error)
+	assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+}
+
+func TestHMACAuthReplay(t *testing.T) {
+	config := createRouterTestConfig()
+	config.EnableCaching = true
+	plugin := createRouterTestPluginWithConfig(t, config)
+	adapter, err := auth.NewHMACAuthAdapter(auth.HMACAuthConfig{
+		Services: map[string]auth.HMACServiceConfig{"billing": {Secret: "s3cret"}},
+	})
+	require.NoError(t, err)
+	plugin.authRegistry = auth.NewAuthAdapterRegistry()
+	plugin.authRegistry.Register(adapter)
+
+	content := "Summarize the quarterly report"
+	body := []byte(`{"messages":[{"role":"user","content":"` + content + `"}]}`)
+	send := func(headers map[string][]string, rawBody []byte) *schemas.PluginShortCircuit {
+		ctx := context.WithValue(context.Background(), "http_headers", headers)
+		if rawBody != nil {
+			ctx = context.WithValue(ctx, RawBodyContextKey, rawBody)
+		}
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		})
+		require.NoError(t, err)
+		return shortCircuit
+	}
+
+	t.Run("should verify replays instead of answering them from the decision cache", func(t *testing.T) {
+		signed := signedRequest("s3cret", "billing", time.Now(), body)
+		require.Nil(t, send(signed.Headers, body))
+
+		shortCircuit := send(signed.Headers, body)
+		require.NotNil(t, shortCircuit)
+		require.NotNil(t, shortCircuit.Error)
+		assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+	})
+
+	t.Run("should reject signed requests when the host does not provide the raw body", func(t *testing.T) {
+		signed := signedRequest("s3cret", "billing", time.Now().Add(time.Second), body)
+		shortCircuit := send(signed.Headers, nil)
+		require.NotNil(t, shortCircuit)
+		require.NotNil(t, shortCircuit.Error)
+		assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Implementations reports which implementation backs each routing stage.
+// Every built-in stage is pure Go, so the plugin builds with CGO_ENABLED=0
+// on any platform Go supports; native backends (a FAISS index, an ONNX
+// runtime) plug in through the sidecar or the With* options instead of
+// being linked in.
+type Implementations struct {
+	Platform string `json:"platform"`
+	CGO      bool   `json:"cgo"`
+	// Embedding is "hash", "provider", "sidecar" or "custom"
+	Embedding string `json:"embedding"`
+	// ClusterSearch is "builtin", "sidecar" or "custom"
+	ClusterSearch string `json:"cluster_search"`
+	// Triage is "gbdt", "sidecar" (with GBDT fallback) or "custom"
+	Triage string `json:"triage"`
+	// Scorer is "alpha", "wasm" (with α-score fallback) or "custom"
+	Scorer string `json:"scorer"`
+}
+
+// resolveImplementations reports the implementations NewWithOptions selects
+// for config and o
+func resolveImplementations(config Config, o options) Implementations {
+	impl := Implementations{
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		CGO:           cgoEnabled,
+		Embedding:     "hash",
+		ClusterSearch: "builtin",
+		Triage:        "gbdt",
+		Scorer:        "alpha",
+	}
+
+	switch {
+	case o.featureExtractor != nil:
+		impl.Embedding = "custom"
+		impl.ClusterSearch = "custom"
+	default:
+		if o.embedder != nil {
+			impl.Embedding = "provider"
+		} else if config.Sidecar.Embed {
+			impl.Embedding = "sidecar"
+		}
+		if config.Sidecar.Cluster {
+			impl.ClusterSearch = "sidecar"
+		}
+	}
+
+	if o.triageModel != nil {
+		impl.Triage = "custom"
+	} else if config.Sidecar.Triage {
+		impl.Triage = "sidecar"
+	}
+
+	if o.scorer != nil {
+		impl.Scorer = "custom"
+	} else if config.WASMScoring.Enabled() {
+		impl.Scorer = "wasm"
+	}
+	return impl
+}
+
+// String formats the report for the startup log
+func (i Implementations) String() string {
+	return fmt.Sprintf("platform=%s cgo=%v embedding=%s cluster_search=%s triage=%s scorer=%s",
+		i.Platform, i.CGO, i.Embedding, i.ClusterSearch, i.Triage, i.Scorer)
+}
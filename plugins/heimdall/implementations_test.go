@@ -0,0 +1,51 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveImplementations(t *testing.T) {
+	t.Run("should report the built-in pure-Go stages by default", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		impl := plugin.implementations
+		assert.Equal(t, runtime.GOOS+"/"+runtime.GOARCH, impl.Platform)
+		assert.Equal(t, cgoEnabled, impl.CGO)
+		assert.Equal(t, "hash", impl.Embedding)
+		assert.Equal(t, "builtin", impl.ClusterSearch)
+		assert.Equal(t, "gbdt", impl.Triage)
+		assert.Equal(t, "alpha", impl.Scorer)
+		assert.Equal(t, impl, plugin.GetMetrics()["implementations"])
+	})
+
+	t.Run("should report the sidecar and WASM stages", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Sidecar = sidecar.Config{Embed: true, Cluster: true, Triage: true}
+		config.WASMScoring = scoring.WASMConfig{ModulePath: "select.wasm"}
+		impl := resolveImplementations(config, options{})
+		assert.Equal(t, "sidecar", impl.Embedding)
+		assert.Equal(t, "sidecar", impl.ClusterSearch)
+		assert.Equal(t, "sidecar", impl.Triage)
+		assert.Equal(t, "wasm", impl.Scorer)
+	})
+
+	t.Run("should report injected stages as custom", func(t *testing.T) {
+		impl := resolveImplementations(createRouterTestConfig(), options{
+			featureExtractor: &stubExtractor{},
+			triageModel:      &stubTriage{},
+			scorer:           &recordingScorer{},
+		})
+		assert.Equal(t, "custom", impl.Embedding)
+		assert.Equal(t, "custom", impl.ClusterSearch)
+		assert.Equal(t, "custom", impl.Triage)
+		assert.Equal(t, "custom", impl.Scorer)
+
+		impl = resolveImplementations(createRouterTestConfig(), options{embedder: &fixedEmbedder{}})
+		assert.Equal(t, "provider", impl.Embedding)
+		assert.Equal(t, "builtin", impl.ClusterSearch)
+	})
+}
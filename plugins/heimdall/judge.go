@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// JudgeConfig configures LLM-as-judge quality sampling
+type JudgeConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// SampleRate is the fraction of completed requests sent to the judge
+	SampleRate float64 `json:"sample_rate"`
+
+	// Endpoint is an OpenAI-compatible chat completions URL for the judge model
+	Endpoint string `json:"endpoint"`
+	Model    string `json:"model"`
+
+	// APIKey authenticates to the endpoint; APIKeyEnv names an environment
+	// variable to read it from instead
+	APIKey    string `json:"api_key,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+
+	Timeout   time.Duration `json:"timeout"`
+	QueueSize int           `json:"queue_size"`
+	Workers   int           `json:"workers"`
+
+	// PriorWeight is how many judged samples the artifact's Q̂ is worth
+	PriorWeight float64 `json:"prior_weight"`
+}
+
+// JudgeSample is a completed request queued for judging
+type JudgeSample struct {
+	Model     string
+	ClusterID int
+	Prompt    string
+	Response  string
+}
+
+// Judge scores a response's quality in [0, 1]
+type Judge interface {
+	Score(ctx context.Context, sample JudgeSample) (float64, error)
+}
+
+// JudgeStats counts judge pipeline activity
+type JudgeStats struct {
+	Sampled int64 `json:"sampled"`
+	Judged  int64 `json:"judged"`
+	Dropped int64 `json:"dropped"`
+	Errors  int64 `json:"errors"`
+}
+
+// JudgePipeline asynchronously judges a sample of completed requests and
+// writes the scores into the online quality store
+type JudgePipeline struct {
+	config JudgeConfig
+	judge  Judge
+	store  *scoring.QualityStore
+
+	queue    chan JudgeSample
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	sampled, judged, dropped, errors atomic.Int64
+}
+
+// withDefaults fills unset pipeline settings
+func (c JudgeConfig) withDefaults() JudgeConfig {
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 256
+	}
+	if c.Workers == 0 {
+		c.Workers = 2
+	}
+	return c
+}
+
+// NewJudgePipeline creates a pipeline writing into store
+func NewJudgePipeline(config JudgeConfig, judge Judge, store *scoring.QualityStore) (*JudgePipeline, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be within [0, 1], got %v", config.SampleRate)
+	}
+	config = config.withDefaults()
+
+	return &JudgePipeline{
+		config: config,
+		judge:  judge,
+		store:  store,
+		queue:  make(chan JudgeSample, config.QueueSize),
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// Start launches the judge workers
+func (jp *JudgePipeline) Start() {
+	for i := 0; i < jp.config.Workers; i++ {
+		jp.wg.Add(1)
+		go jp.worker()
+	}
+}
+
+// Stop halts the workers, abandoning queued samples
+func (jp *JudgePipeline) Stop() {
+	jp.stopOnce.Do(func() {
+		close(jp.stopCh)
+	})
+	jp.wg.Wait()
+}
+
+// ShouldSample decides whether a request is judged
+func (jp *JudgePipeline) ShouldSample() bool {
+	return rand.Float64() < jp.config.SampleRate
+}
+
+// Submit queues a sample without blocking; samples are dropped when full
+func (jp *JudgePipeline) Submit(sample JudgeSample) {
+	jp.sampled.Add(1)
+	select {
+	case jp.queue <- sample:
+	default:
+		jp.dropped.Add(1)
+	}
+}
+
+// GetStats returns pipeline counters
+func (jp *JudgePipeline) GetStats() JudgeStats {
+	return JudgeStats{
+		Sampled: jp.sampled.Load(),
+		Judged:  jp.judged.Load(),
+		Dropped: jp.dropped.Load(),
+		Errors:  jp.errors.Load(),
+	}
+}
+
+func (jp *JudgePipeline) worker() {
+	defer jp.wg.Done()
+	for {
+		select {
+		case sample := <-jp.queue:
+			jp.process(sample)
+		case <-jp.stopCh:
+			return
+		}
+	}
+}
+
+// process judges one sample and records the score
+func (jp *JudgePipeline) process(sample JudgeSample) {
+	ctx, cancel := context.WithTimeout(context.Background(), jp.config.Timeout)
+	defer cancel()
+
+	score, err := jp.judge.Score(ctx, sample)
+	if err != nil {
+		jp.errors.Add(1)
+		log.Printf("Judge failed for %s: %v", sample.Model, err)
+		return
+	}
+	jp.judged.Add(1)
+	jp.store.Observe(sample.Model, sample.ClusterID, score)
+}
+
+// judgeScorePattern finds the rating in a judge's reply
+var judgeScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+const judgeSystemPrompt = "You are grading an AI assistant's answer. Rate how well the answer " +
+	"addresses the user's request for correctness, completeness and clarity on a scale " +
+	"from 0 to 10. Reply with the number only."
+
+// HTTPJudge scores responses with a model behind an OpenAI-compatible endpoint
+type HTTPJudge struct {
+	endpoint   string
+	model      string
+	apiKey     auth.Secret
+	httpClient *http.Client
+}
+
+// NewHTTPJudge creates a judge from config
+func NewHTTPJudge(config JudgeConfig) (*HTTPJudge, error) {
+	if config.Endpoint == "" || config.Model == "" {
+		return nil, fmt.Errorf("judge requires an endpoint and model")
+	}
+	config = config.withDefaults()
+	apiKey := config.APIKey
+	if config.APIKeyEnv != "" {
+		apiKey = os.Getenv(config.APIKeyEnv)
+	}
+
+	return &HTTPJudge{
+		endpoint: config.Endpoint,
+		model:    config.Model,
+		apiKey:   auth.NewSecret(apiKey),
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}, nil
+}
+
+// Score asks the judge model to rate a response
+func (j *HTTPJudge) Score(ctx context.Context, sample JudgeSample) (float64, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":       j.model,
+		"temperature": 0,
+		"messages": []ChatMessage{
+			{Role: "system", Content: judgeSystemPrompt},
+			{Role: "user", Content: fmt.Sprintf("Request:\n%s\n\nAnswer:\n%s", sample.Prompt, sample.Response)},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if j.apiKey.IsSet() {
+		req.Header.Set("Authorization", "Bearer "+j.apiKey.Reveal())
+	}
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("judge returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return 0, fmt.Errorf("invalid judge response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return 0, fmt.Errorf("judge returned no choices")
+	}
+
+	return parseJudgeScore(completion.Choices[0].Message.Content)
+}
+
+// parseJudgeScore converts a 0-10 rating into a [0, 1] quality score
+func parseJudgeScore(reply string) (float64, error) {
+	match := judgeScorePattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no rating in judge reply %q", reply)
+	}
+	rating, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rating > 10 {
+		return 0, fmt.Errorf("rating %v out of range", rating)
+	}
+	return rating / 10, nil
+}
+
+// promptText flattens a chat request into judge-readable text
+func promptText(req *schemas.BifrostRequest) string {
+	if req.Input.ChatCompletionInput == nil {
+		if req.Input.TextCompletionInput != nil {
+			return *req.Input.TextCompletionInput
+		}
+		return ""
+	}
+	var parts []string
+	for _, msg := range *req.Input.ChatCompletionInput {
+		parts = append(parts, fmt.Sprintf("%s: %s", msg.Role, messageText(msg)))
+	}
+	return strings.Join(parts, "\n")
+}
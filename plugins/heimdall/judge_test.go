@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJudge scores every sample with a fixed value
+type fakeJudge struct {
+	score float64
+	err   error
+}
+
+func (j *fakeJudge) Score(ctx context.Context, sample JudgeSample) (float64, error) {
+	return j.score, j.err
+}
+
+func TestParseJudgeScore(t *testing.T) {
+	score, err := parseJudgeScore("8")
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, score)
+
+	score, err = parseJudgeScore("Rating: 7.5/10")
+	require.NoError(t, err)
+	assert.Equal(t, 0.75, score)
+
+	_, err = parseJudgeScore("excellent")
+	assert.Error(t, err)
+	_, err = parseJudgeScore("42")
+	assert.Error(t, err)
+}
+
+func TestHTTPJudge(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer judge-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"9"}}]}`)
+	}))
+	defer server.Close()
+
+	judge, err := NewHTTPJudge(JudgeConfig{Endpoint: server.URL, Model: "openai/gpt-4o-mini", APIKey: "judge-key"})
+	require.NoError(t, err)
+
+	score, err := judge.Score(context.Background(), JudgeSample{Prompt: "user: hi", Response: "Hello!"})
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, score)
+	assert.Equal(t, "openai/gpt-4o-mini", received["model"])
+
+	_, err = NewHTTPJudge(JudgeConfig{Model: "openai/gpt-4o-mini"})
+	assert.Error(t, err)
+}
+
+func TestJudgePipeline(t *testing.T) {
+	t.Run("should write judged scores into the quality store", func(t *testing.T) {
+		store := scoring.NewQualityStore(0)
+		pipeline, err := NewJudgePipeline(JudgeConfig{SampleRate: 1}, &fakeJudge{score: 0.4}, store)
+		require.NoError(t, err)
+		pipeline.Start()
+		defer pipeline.Stop()
+
+		pipeline.Submit(JudgeSample{Model: "openai/gpt-4o", ClusterID: 3})
+		require.Eventually(t, func() bool { return pipeline.GetStats().Judged == 1 }, time.Second, 5*time.Millisecond)
+
+		estimate, ok := store.Estimate("openai/gpt-4o", 3)
+		require.True(t, ok)
+		assert.Equal(t, 0.4, estimate.Mean)
+	})
+
+	t.Run("should count judge failures", func(t *testing.T) {
+		pipeline, err := NewJudgePipeline(JudgeConfig{}, &fakeJudge{err: fmt.Errorf("boom")}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+		pipeline.Start()
+		defer pipeline.Stop()
+
+		pipeline.Submit(JudgeSample{Model: "openai/gpt-4o"})
+		require.Eventually(t, func() bool { return pipeline.GetStats().Errors == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should drop samples when the queue is full", func(t *testing.T) {
+		pipeline, err := NewJudgePipeline(JudgeConfig{QueueSize: 1}, &fakeJudge{}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+
+		pipeline.Submit(JudgeSample{})
+		pipeline.Submit(JudgeSample{})
+		assert.Equal(t, JudgeStats{Sampled: 2, Dropped: 1}, pipeline.GetStats())
+	})
+
+	t.Run("should reject invalid sample rates", func(t *testing.T) {
+		_, err := NewJudgePipeline(JudgeConfig{SampleRate: 1.5}, &fakeJudge{}, scoring.NewQualityStore(0))
+		assert.Error(t, err)
+	})
+}
+
+func TestJudgeSampling(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	pipeline, err := NewJudgePipeline(JudgeConfig{SampleRate: 1}, &fakeJudge{score: 0.3}, plugin.quality)
+	require.NoError(t, err)
+	plugin.judge = pipeline
+
+	content := "What is the capital of France?"
+	req := &schemas.BifrostRequest{Input: schemas.RequestInput{
+		ChatCompletionInput: &[]schemas.BifrostMessage{{
+			Role:    schemas.ModelChatMessageRoleUser,
+			Content: schemas.MessageContent{ContentStr: &content},
+		}},
+	}}
+	ctx := context.Background()
+	_, _, err = plugin.applyRoutingDecision(&ctx, req, &RouterResponse{
+		Features: RequestFeatures{ClusterID: 2},
+		Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user: What is the capital of France?", ctx.Value("heimdall_judge_prompt"))
+
+	_, _, err = plugin.PostHook(&ctx, textResponse("Paris.", "stop"), nil)
+	require.NoError(t, err)
+
+	sample := <-pipeline.queue
+	assert.Equal(t, JudgeSample{
+		Model:     "openai/gpt-4o",
+		ClusterID: 2,
+		Prompt:    "user: What is the capital of France?",
+		Response:  "Paris.",
+	}, sample)
+}
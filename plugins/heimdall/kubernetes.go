@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/schema"
+)
+
+// reloadingPluginKey carries the plugin instance that handled a request's
+// PreHook, so its PostHook runs on the same instance across reloads
+type reloadingPluginKey struct{}
+
+// KubernetesConfig configures hot reload from files Kubernetes mounts,
+// without an artifact server. Mounted ConfigMaps and Secrets are updated in
+// place by the kubelet; the files are polled for changes.
+type KubernetesConfig struct {
+	// ConfigPath is the JSON plugin config, e.g. a mounted ConfigMap key
+	ConfigPath string `json:"config_path"`
+	// SecretsDir is a mounted Secret. Each file is exported as the
+	// environment variable it is named after before the config is loaded,
+	// so key_env, secret_env and api_key_env settings read rotated keys.
+	SecretsDir string `json:"secrets_dir"`
+	// ArtifactPath is a mounted artifact file, tried before any artifact
+	// URLs in the config (optional)
+	ArtifactPath string `json:"artifact_path"`
+
+	// PollInterval is how often the files are checked (default 10s)
+	PollInterval time.Duration `json:"poll_interval"`
+	// DrainGrace is how long a replaced plugin instance keeps serving
+	// in-flight requests before it is cleaned up (default 1m)
+	DrainGrace time.Duration `json:"drain_grace"`
+}
+
+// ReloadingPlugin is a Bifrost plugin that rebuilds itself when its mounted
+// config or secrets change and reloads the artifact when its file changes.
+// A config that fails to load or validate is logged and ignored, leaving
+// the running instance in place.
+type ReloadingPlugin struct {
+	config  KubernetesConfig
+	opts    []Option
+	logger  *log.Logger
+	current atomic.Pointer[Plugin]
+
+	configSum   [32]byte
+	failedSum   [32]byte // last config that failed to build, not retried
+	artifactSum [32]byte
+	mu          sync.Mutex // serializes reloads
+
+	retired  sync.WaitGroup
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewKubernetes loads the plugin from mounted files and starts watching
+// them. Options apply to every instance built.
+func NewKubernetes(config KubernetesConfig, opts ...Option) (*ReloadingPlugin, error) {
+	if config.ConfigPath == "" {
+		return nil, fmt.Errorf("config_path is required")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.DrainGrace == 0 {
+		config.DrainGrace = time.Minute
+	}
+	if config.PollInterval < 0 || config.DrainGrace < 0 {
+		return nil, fmt.Errorf("poll_interval and drain_grace must be positive")
+	}
+
+	o := options{logger: log.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	rp := &ReloadingPlugin{
+		config: config,
+		opts:   opts,
+		logger: o.logger,
+		stopCh: make(chan struct{}),
+	}
+
+	data, sum, err := rp.read()
+	if err != nil {
+		return nil, err
+	}
+	plugin, err := rp.build(data)
+	if err != nil {
+		return nil, err
+	}
+	rp.current.Store(plugin)
+	rp.configSum = sum
+	rp.artifactSum = rp.artifactChecksum()
+
+	go rp.watch()
+	return rp, nil
+}
+
+// read exports the mounted secrets and reads the config, returning it with
+// the checksum of both
+func (rp *ReloadingPlugin) read() ([]byte, [32]byte, error) {
+	var sum [32]byte
+	h := sha256.New()
+	if rp.config.SecretsDir != "" {
+		if err := exportSecrets(rp.config.SecretsDir, h); err != nil {
+			return nil, sum, err
+		}
+	}
+
+	data, err := os.ReadFile(rp.config.ConfigPath)
+	if err != nil {
+		return nil, sum, fmt.Errorf("failed to read config: %w", err)
+	}
+	h.Write(data)
+	copy(sum[:], h.Sum(nil))
+	return data, sum, nil
+}
+
+// build validates a config and creates a plugin from it
+func (rp *ReloadingPlugin) build(data []byte) (*Plugin, error) {
+	violations, err := schema.Validate(ConfigSchema(), data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("invalid config: %s (%d violations)", violations[0], len(violations))
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if rp.config.ArtifactPath != "" {
+		if config.Tuning.ArtifactURL != "" {
+			config.Tuning.ArtifactURLs = append([]string{config.Tuning.ArtifactURL}, config.Tuning.ArtifactURLs...)
+		}
+		config.Tuning.ArtifactURL = "file://" + rp.config.ArtifactPath
+	}
+
+	return NewWithOptions(config, rp.opts...)
+}
+
+// exportSecrets sets an environment variable per file in dir, hashing the
+// contents into h. Hidden entries, such as the kubelet's ..data links, are
+// skipped.
+func exportSecrets(dir string, h hash.Hash) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			// Directories and dangling links are not secrets
+			continue
+		}
+		value = []byte(strings.TrimRight(string(value), "\r\n"))
+		if err := os.Setenv(name, string(value)); err != nil {
+			return fmt.Errorf("failed to export secret %s: %w", name, err)
+		}
+		h.Write([]byte(name + "\x00"))
+		h.Write(value)
+		h.Write([]byte{0})
+	}
+	return nil
+}
+
+// artifactChecksum hashes the artifact file, or returns zero without one
+func (rp *ReloadingPlugin) artifactChecksum() [32]byte {
+	if rp.config.ArtifactPath == "" {
+		return [32]byte{}
+	}
+	data, err := os.ReadFile(rp.config.ArtifactPath)
+	if err != nil {
+		return [32]byte{}
+	}
+	return sha256.Sum256(data)
+}
+
+// watch polls the mounted files until Cleanup
+func (rp *ReloadingPlugin) watch() {
+	ticker := time.NewTicker(rp.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := rp.Reload(); err != nil {
+				rp.logger.Printf("Reload from mounted files failed, keeping current plugin: %v", err)
+			}
+		case <-rp.stopCh:
+			return
+		}
+	}
+}
+
+// Reload checks the mounted files now. A changed config or secret replaces
+// the plugin instance; a changed artifact is reloaded into it. A config
+// that fails to build is not retried until the files change again.
+func (rp *ReloadingPlugin) Reload() error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	data, sum, err := rp.read()
+	if err != nil {
+		return err
+	}
+	if sum == rp.configSum || sum == rp.failedSum {
+		return rp.reloadArtifact()
+	}
+	plugin, err := rp.build(data)
+	if err != nil {
+		rp.failedSum = sum
+		return err
+	}
+
+	old := rp.current.Load()
+	plugin.inherit(old)
+	plugin.auditReload(rp.config.ConfigPath, old.config)
+	rp.current.Store(plugin)
+	rp.configSum = sum
+	rp.artifactSum = rp.artifactChecksum()
+	rp.logger.Printf("Reloaded plugin from %s", rp.config.ConfigPath)
+
+	rp.retired.Add(1)
+	go func() {
+		defer rp.retired.Done()
+		select {
+		case <-time.After(rp.config.DrainGrace):
+		case <-rp.stopCh:
+		}
+		old.Cleanup()
+	}()
+	return nil
+}
+
+// inherit carries runtime state that is not config over from the instance
+// being replaced: the audit trail, drains, sessions, cost anomalies and
+// their acknowledgements, quarantines and the α controller's kill switch.
+// Bucket smoothing history and pending labeling records start afresh, and
+// in-flight drain, concurrency and admission counts stay with the old
+// instance, which releases the requests it routed.
+func (p *Plugin) inherit(old *Plugin) {
+	if p.audit != nil && old.audit != nil {
+		p.audit.Inherit(old.audit)
+	}
+	if p.drains != nil && old.drains != nil {
+		p.drains.Inherit(old.drains)
+	}
+	if p.sessions != nil && old.sessions != nil {
+		p.sessions.Inherit(old.sessions)
+	}
+	if p.anomalies != nil && old.anomalies != nil {
+		p.anomalies.Inherit(old.anomalies)
+	}
+	if p.quarantine != nil && old.quarantine != nil {
+		p.quarantine.Inherit(old.quarantine)
+	}
+	if p.alphaController != nil && old.alphaController != nil {
+		p.alphaController.Inherit(old.alphaController)
+	}
+}
+
+// reloadArtifact refreshes the current instance's artifact when its file
+// changed; callers hold mu
+func (rp *ReloadingPlugin) reloadArtifact() error {
+	sum := rp.artifactChecksum()
+	if sum == rp.artifactSum {
+		return nil
+	}
+	rp.artifactSum = sum
+
+	plugin := rp.current.Load()
+	plugin.artifactMu.Lock()
+	plugin.lastArtifactLoad = time.Time{}
+	plugin.artifactMu.Unlock()
+	return plugin.loadArtifact(true)
+}
+
+// Current returns the plugin instance new requests are routed by
+func (rp *ReloadingPlugin) Current() *Plugin {
+	return rp.current.Load()
+}
+
+// GetName implements schemas.Plugin
+func (rp *ReloadingPlugin) GetName() string {
+	return rp.current.Load().GetName()
+}
+
+// PreHook routes with the current instance, remembering it for PostHook
+func (rp *ReloadingPlugin) PreHook(ctx *context.Context, req *schemas.BifrostRequest) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
+	plugin := rp.current.Load()
+	*ctx = context.WithValue(*ctx, reloadingPluginKey{}, plugin)
+	return plugin.PreHook(ctx, req)
+}
+
+// PostHook completes a request on the instance that routed it
+func (rp *ReloadingPlugin) PostHook(ctx *context.Context, res *schemas.BifrostResponse, err *schemas.BifrostError) (*schemas.BifrostResponse, *schemas.BifrostError, error) {
+	plugin, ok := (*ctx).Value(reloadingPluginKey{}).(*Plugin)
+	if !ok {
+		plugin = rp.current.Load()
+	}
+	return plugin.PostHook(ctx, res, err)
+}
+
+// AdminHandler serves the current instance's admin surface
+func (rp *ReloadingPlugin) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rp.current.Load().AdminHandler().ServeHTTP(w, r)
+	})
+}
+
+// Cleanup stops watching and cleans up every instance
+func (rp *ReloadingPlugin) Cleanup() error {
+	rp.stopOnce.Do(func() {
+		close(rp.stopCh)
+	})
+	rp.retired.Wait()
+	return rp.current.Load().Cleanup()
+}
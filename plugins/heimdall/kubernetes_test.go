@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadingPlugin(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	artifactPath := filepath.Join(dir, "artifact.json")
+	secretsDir := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secretsDir, 0o755))
+
+	writeJSON := func(path string, value interface{}) {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	config := createRouterTestConfig()
+	config.Startup.Policy = StartupPolicyBlock
+	config.Tuning.ArtifactURL = ""
+	writeJSON(configPath, config)
+	artifact := defaultArtifact(config)
+	artifact.Version = "k8s-1"
+	writeJSON(artifactPath, artifact)
+	require.NoError(t, os.WriteFile(filepath.Join(secretsDir, "HEIMDALL_TEST_K8S_KEY"), []byte("first\n"), 0o600))
+	t.Setenv("HEIMDALL_TEST_K8S_KEY", "")
+
+	rp, err := NewKubernetes(KubernetesConfig{
+		ConfigPath:   configPath,
+		SecretsDir:   secretsDir,
+		ArtifactPath: artifactPath,
+		PollInterval: time.Hour,
+		DrainGrace:   time.Millisecond,
+	}, WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+	defer rp.Cleanup()
+	first := rp.Current()
+
+	t.Run("should load the mounted config, secrets and artifact", func(t *testing.T) {
+		assert.Equal(t, "k8s-1", first.currentArtifact.Version)
+		assert.Equal(t, "first", os.Getenv("HEIMDALL_TEST_K8S_KEY"))
+		assert.NoError(t, rp.Reload(), "unchanged files are a no-op")
+		assert.Same(t, first, rp.Current())
+	})
+
+	t.Run("should reload a changed artifact in place", func(t *testing.T) {
+		artifact.Version = "k8s-2"
+		writeJSON(artifactPath, artifact)
+		require.NoError(t, rp.Reload())
+		assert.Same(t, first, rp.Current())
+		assert.Equal(t, "k8s-2", first.currentArtifact.Version)
+	})
+
+	t.Run("should replace the plugin when config or secrets change", func(t *testing.T) {
+		config.MaxCacheSize = 42
+		writeJSON(configPath, config)
+		require.NoError(t, rp.Reload())
+		second := rp.Current()
+		assert.NotSame(t, first, second)
+		assert.Equal(t, 42, second.config.MaxCacheSize)
+		assert.Equal(t, "k8s-2", second.currentArtifact.Version)
+
+		require.NoError(t, os.WriteFile(filepath.Join(secretsDir, "HEIMDALL_TEST_K8S_KEY"), []byte("second"), 0o600))
+		require.NoError(t, rp.Reload())
+		assert.NotSame(t, second, rp.Current())
+		assert.Equal(t, "second", os.Getenv("HEIMDALL_TEST_K8S_KEY"))
+	})
+
+	t.Run("should keep the running plugin when the config is invalid", func(t *testing.T) {
+		current := rp.Current()
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"enable_cachign": true}`), 0o644))
+		assert.ErrorContains(t, rp.Reload(), "unknown field")
+		assert.NoError(t, rp.Reload(), "a failed config is not retried")
+		assert.Same(t, current, rp.Current())
+	})
+
+	t.Run("should carry runtime state over and audit the reload", func(t *testing.T) {
+		config.Audit = AuditConfig{Enabled: true}
+		config.Sessions = testSessionConfig()
+		config.Quarantine = QuarantineConfig{Enabled: true}
+		config.CostAnomaly = CostAnomalyConfig{Enabled: true, ForceCheap: true}
+		config.AlphaController = AlphaControllerConfig{Enabled: true, TargetSuccessRate: 0.9}
+		writeJSON(configPath, config)
+		require.NoError(t, rp.Reload())
+		before := rp.Current()
+
+		before.sessions.Record("session-1", DataSubject{Tenant: "tenant-a"}, "openai/o1", &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000})
+		for i := 0; i < 20; i++ {
+			before.quarantine.Record("openai/o1", false)
+		}
+		before.anomalies.series["tenant-a\x00openai/o1"] = &costSeries{anomaly: &CostAnomaly{Tenant: "tenant-a", Model: "openai/o1"}}
+		before.anomalies.series["tenant-b\x00openai/o1"] = &costSeries{baseline: 0.01, samples: 60}
+		before.alphaController.Kill("incident")
+		require.NoError(t, before.drains.Drain("anthropic", "maintenance"))
+
+		config.MaxCacheSize = 43
+		config.Quarantine.Window = 40
+		writeJSON(configPath, config)
+		require.NoError(t, rp.Reload())
+		after := rp.Current()
+		require.NotSame(t, before, after)
+
+		assert.InDelta(t, before.sessions.Spend("session-1"), after.sessions.Spend("session-1"), 1e-9)
+		assert.True(t, after.quarantine.IsQuarantined("openai/o1"))
+		assert.True(t, after.anomalies.Forced("tenant-a"))
+		assert.False(t, after.anomalies.Forced("tenant-b"), "acknowledged anomalies stay acknowledged")
+		assert.Equal(t, 60, after.anomalies.series["tenant-b\x00openai/o1"].samples)
+		assert.True(t, after.alphaController.GetStatus().Killed)
+		assert.True(t, after.drains.IsDrained("anthropic", "anthropic/claude-3.5-sonnet"))
+
+		entries := after.audit.Entries(0, 0)
+		require.NotEmpty(t, entries)
+		reload := entries[len(entries)-1]
+		assert.Equal(t, AuditReload, reload.Kind)
+		assert.Equal(t, configPath, reload.Actor)
+		assert.Contains(t, reload.Changes, FieldChange{Path: "max_cache_size", Old: float64(42), New: float64(43)})
+		assert.Contains(t, reload.Changes, FieldChange{Path: "quarantine.window", Old: float64(0), New: float64(40)})
+		previous := before.audit.Entries(0, 0)
+		assert.Equal(t, previous, entries[:len(previous)], "the trail continues across instances")
+	})
+
+	t.Run("should not prepend an empty artifact URL", func(t *testing.T) {
+		assert.Empty(t, rp.Current().config.Tuning.ArtifactURLs)
+		assert.Equal(t, "file://"+artifactPath, rp.Current().config.Tuning.ArtifactURL)
+	})
+
+	t.Run("should require a config path", func(t *testing.T) {
+		_, err := NewKubernetes(KubernetesConfig{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Label import targets
+const (
+	LabelTargetQuality = "quality" // fold labels into the online quality store
+	LabelTargetEval    = "eval"    // keep labels as an evaluation set only
+)
+
+// LabelingConfig configures sampling of completed requests for human labeling
+type LabelingConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// SampleRate is the fraction of completed requests exported for labeling
+	SampleRate float64 `json:"sample_rate"`
+
+	// MaxRecords bounds the pending export buffer; the oldest are dropped
+	MaxRecords int `json:"max_records"`
+}
+
+// LabelRecord is one sampled request awaiting a human label. Only features
+// and a response reference are kept; raw text stays with the host's logs.
+type LabelRecord struct {
+	ID          string          `json:"id"`
+	Timestamp   time.Time       `json:"timestamp"`
+	Model       string          `json:"model"`
+	Bucket      Bucket          `json:"bucket"`
+	ClusterID   int             `json:"cluster_id"`
+	Features    RequestFeatures `json:"features"`
+	ResponseRef string          `json:"response_ref"`
+
+	// Owner is the caller the request came from; it is not exported to
+	// labelers
+	Owner DataSubject `json:"-"`
+}
+
+// Label is a human judgement of a record's response quality in [0, 1]
+type Label struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+	Note  string  `json:"note,omitempty"`
+}
+
+// LabeledRecord pairs a record with its label for evaluation sets
+type LabeledRecord struct {
+	LabelRecord
+	Score float64 `json:"score"`
+	Note  string  `json:"note,omitempty"`
+}
+
+// LabelImportResult summarizes an import
+type LabelImportResult struct {
+	Applied int      `json:"applied"`
+	Unknown int      `json:"unknown"`
+	Invalid int      `json:"invalid"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// LabelingStore buffers sampled records for export and collects imported
+// labels into quality adjustments or an evaluation set
+type LabelingStore struct {
+	config  LabelingConfig
+	quality *scoring.QualityStore
+
+	pending map[string]LabelRecord
+	order   []string
+	evalSet []LabeledRecord
+	mu      sync.Mutex
+}
+
+// NewLabelingStore creates a store whose quality labels feed quality
+func NewLabelingStore(config LabelingConfig, quality *scoring.QualityStore) (*LabelingStore, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be within [0, 1], got %v", config.SampleRate)
+	}
+	if config.MaxRecords == 0 {
+		config.MaxRecords = 10000
+	}
+	return &LabelingStore{
+		config:  config,
+		quality: quality,
+		pending: make(map[string]LabelRecord),
+	}, nil
+}
+
+// ShouldSample decides whether a completed request is exported
+func (ls *LabelingStore) ShouldSample() bool {
+	return mathrand.Float64() < ls.config.SampleRate
+}
+
+// Record buffers a completed request from owner for labeling
+func (ls *LabelingStore) Record(owner DataSubject, model string, bucket Bucket, features RequestFeatures, response string) LabelRecord {
+	record := LabelRecord{
+		ID:          newLabelID(),
+		Timestamp:   time.Now(),
+		Model:       model,
+		Bucket:      bucket,
+		ClusterID:   features.ClusterID,
+		Features:    features,
+		ResponseRef: responseRef(response),
+		Owner:       owner,
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if len(ls.order) >= ls.config.MaxRecords {
+		delete(ls.pending, ls.order[0])
+		ls.order = ls.order[1:]
+	}
+	ls.pending[record.ID] = record
+	ls.order = append(ls.order, record.ID)
+	return record
+}
+
+// Export writes pending records as JSON lines, oldest first
+func (ls *LabelingStore) Export(w io.Writer) error {
+	ls.mu.Lock()
+	records := make([]LabelRecord, 0, len(ls.order))
+	for _, id := range ls.order {
+		records = append(records, ls.pending[id])
+	}
+	ls.mu.Unlock()
+
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import reads labels as JSON lines and applies them to target. Labeled
+// records leave the export buffer and always join the evaluation set.
+func (ls *LabelingStore) Import(r io.Reader, target string) (*LabelImportResult, error) {
+	if target != LabelTargetQuality && target != LabelTargetEval {
+		return nil, fmt.Errorf("unknown label target %q", target)
+	}
+
+	result := &LabelImportResult{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var label Label
+		if err := json.Unmarshal(scanner.Bytes(), &label); err != nil {
+			result.Invalid++
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if label.Score < 0 || label.Score > 1 {
+			result.Invalid++
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: score %v outside [0, 1]", line, label.Score))
+			continue
+		}
+
+		record, ok := ls.take(label.ID)
+		if !ok {
+			result.Unknown++
+			continue
+		}
+
+		if target == LabelTargetQuality {
+			ls.quality.Observe(record.Model, record.ClusterID, label.Score)
+		}
+		ls.mu.Lock()
+		ls.evalSet = append(ls.evalSet, LabeledRecord{LabelRecord: record, Score: label.Score, Note: label.Note})
+		ls.mu.Unlock()
+		result.Applied++
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// EvaluationSet returns all labeled records
+func (ls *LabelingStore) EvaluationSet() []LabeledRecord {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return append([]LabeledRecord(nil), ls.evalSet...)
+}
+
+// Pending returns the number of records awaiting labels
+func (ls *LabelingStore) Pending() int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return len(ls.order)
+}
+
+// SubjectRecords returns an identity's pending and labeled records, oldest
+// first
+func (ls *LabelingStore) SubjectRecords(subject DataSubject) ([]LabelRecord, []LabeledRecord) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	var pending []LabelRecord
+	for _, id := range ls.order {
+		if record := ls.pending[id]; subject.covers(record.Owner) {
+			pending = append(pending, record)
+		}
+	}
+	var labeled []LabeledRecord
+	for _, record := range ls.evalSet {
+		if subject.covers(record.Owner) {
+			labeled = append(labeled, record)
+		}
+	}
+	return pending, labeled
+}
+
+// DeleteSubject removes an identity's pending and labeled records,
+// returning how many were removed. Quality adjustments already learned from their
+// labels are aggregates and are kept.
+func (ls *LabelingStore) DeleteSubject(subject DataSubject) int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	deleted := 0
+	order := ls.order[:0]
+	for _, id := range ls.order {
+		if subject.covers(ls.pending[id].Owner) {
+			delete(ls.pending, id)
+			deleted++
+			continue
+		}
+		order = append(order, id)
+	}
+	ls.order = order
+
+	evalSet := ls.evalSet[:0]
+	for _, record := range ls.evalSet {
+		if subject.covers(record.Owner) {
+			deleted++
+			continue
+		}
+		evalSet = append(evalSet, record)
+	}
+	ls.evalSet = evalSet
+	return deleted
+}
+
+// take removes a pending record by ID
+func (ls *LabelingStore) take(id string) (LabelRecord, bool) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	record, ok := ls.pending[id]
+	if !ok {
+		return LabelRecord{}, false
+	}
+	delete(ls.pending, id)
+	for i, pendingID := range ls.order {
+		if pendingID == id {
+			ls.order = append(ls.order[:i], ls.order[i+1:]...)
+			break
+		}
+	}
+	return record, true
+}
+
+// responseRef fingerprints a response so labelers can join it to host logs
+func responseRef(response string) string {
+	sum := sha256.Sum256([]byte(response))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func newLabelID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
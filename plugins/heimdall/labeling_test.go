@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelingStore(t *testing.T) {
+	t.Run("should export records without raw response text", func(t *testing.T) {
+		store, err := NewLabelingStore(LabelingConfig{}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+
+		record := store.Record(DataSubject{}, "openai/gpt-4o", BucketMid, RequestFeatures{ClusterID: 4, TokenCount: 120}, "secret answer")
+
+		var buf bytes.Buffer
+		require.NoError(t, store.Export(&buf))
+		assert.NotContains(t, buf.String(), "secret answer")
+
+		var exported LabelRecord
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+		assert.Equal(t, record.ID, exported.ID)
+		assert.Equal(t, 4, exported.ClusterID)
+		assert.Equal(t, BucketMid, exported.Bucket)
+		assert.Equal(t, responseRef("secret answer"), exported.ResponseRef)
+	})
+
+	t.Run("should convert quality labels into Q̂ adjustments", func(t *testing.T) {
+		quality := scoring.NewQualityStore(0)
+		store, err := NewLabelingStore(LabelingConfig{}, quality)
+		require.NoError(t, err)
+
+		first := store.Record(DataSubject{}, "openai/gpt-4o", BucketMid, RequestFeatures{ClusterID: 1}, "a")
+		second := store.Record(DataSubject{}, "openai/gpt-4o", BucketMid, RequestFeatures{ClusterID: 1}, "b")
+
+		labels := fmt.Sprintf("{\"id\":%q,\"score\":0.2}\n{\"id\":%q,\"score\":0.6,\"note\":\"ok\"}\n{\"id\":\"missing\",\"score\":1}\n{\"id\":%q,\"score\":7}\nnot json\n",
+			first.ID, second.ID, first.ID)
+		result, err := store.Import(strings.NewReader(labels), LabelTargetQuality)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Applied)
+		assert.Equal(t, 1, result.Unknown)
+		assert.Equal(t, 2, result.Invalid)
+
+		estimate, ok := quality.Estimate("openai/gpt-4o", 1)
+		require.True(t, ok)
+		assert.InDelta(t, 0.4, estimate.Mean, 1e-9)
+		assert.Zero(t, store.Pending())
+		require.Len(t, store.EvaluationSet(), 2)
+		assert.Equal(t, "ok", store.EvaluationSet()[1].Note)
+	})
+
+	t.Run("should keep eval labels out of the quality store", func(t *testing.T) {
+		quality := scoring.NewQualityStore(0)
+		store, err := NewLabelingStore(LabelingConfig{}, quality)
+		require.NoError(t, err)
+
+		record := store.Record(DataSubject{}, "openai/gpt-4o", BucketMid, RequestFeatures{}, "a")
+		result, err := store.Import(strings.NewReader(fmt.Sprintf(`{"id":%q,"score":1}`, record.ID)), LabelTargetEval)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Applied)
+		assert.Empty(t, quality.Snapshot())
+		assert.Len(t, store.EvaluationSet(), 1)
+
+		_, err = store.Import(strings.NewReader(""), "bogus")
+		assert.Error(t, err)
+	})
+
+	t.Run("should drop the oldest records when full", func(t *testing.T) {
+		store, err := NewLabelingStore(LabelingConfig{MaxRecords: 2}, scoring.NewQualityStore(0))
+		require.NoError(t, err)
+
+		first := store.Record(DataSubject{}, "m", BucketCheap, RequestFeatures{}, "1")
+		store.Record(DataSubject{}, "m", BucketCheap, RequestFeatures{}, "2")
+		store.Record(DataSubject{}, "m", BucketCheap, RequestFeatures{}, "3")
+		assert.Equal(t, 2, store.Pending())
+
+		result, err := store.Import(strings.NewReader(fmt.Sprintf(`{"id":%q,"score":1}`, first.ID)), LabelTargetEval)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Unknown)
+	})
+}
+
+func TestAdminLabelingEndpoints(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Labeling = LabelingConfig{Enabled: true, SampleRate: 1}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	server := httptest.NewServer(plugin.AdminHandler())
+	defer server.Close()
+
+	plugin.labeling.Record(DataSubject{}, "openai/gpt-4o", BucketMid, RequestFeatures{ClusterID: 2}, "answer")
+
+	resp, err := http.Get(server.URL + "/admin/labeling/export")
+	require.NoError(t, err)
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	var record LabelRecord
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/admin/labeling/import?target=quality", "application/x-ndjson",
+		strings.NewReader(fmt.Sprintf(`{"id":%q,"score":0.9}`, record.ID)))
+	require.NoError(t, err)
+	var result LabelImportResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Equal(t, 1, result.Applied)
+
+	estimate, ok := plugin.quality.Estimate("openai/gpt-4o", 2)
+	require.True(t, ok)
+	assert.Equal(t, 0.9, estimate.Mean)
+
+	resp, err = http.Get(server.URL + "/admin/labeling/evalset")
+	require.NoError(t, err)
+	var evalSet []LabeledRecord
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&evalSet))
+	resp.Body.Close()
+	assert.Len(t, evalSet, 1)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LadderConfig configures size ladders: model families offered in several
+// sizes whose quality and cost rise together. The α-score picks the family;
+// the ladder then routes to the smallest size predicted to be good enough.
+type LadderConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Ladders lists each family's models, smallest first
+	Ladders [][]string `json:"ladders"`
+
+	// MinQuality is the predicted quality a size must reach (default 0.7)
+	MinQuality float64 `json:"min_quality"`
+	// ClusterMinQuality overrides MinQuality for specific clusters
+	ClusterMinQuality map[int]float64 `json:"cluster_min_quality"`
+}
+
+// LadderInfo records a decision moved along a size ladder
+type LadderInfo struct {
+	// ScoredModel is the size the α-score picked
+	ScoredModel      string  `json:"scored_model"`
+	RequiredQuality  float64 `json:"required_quality"`
+	PredictedQuality float64 `json:"predicted_quality"`
+}
+
+// LadderStats counts ladder outcomes
+type LadderStats struct {
+	Considered  int64 `json:"considered"`
+	SteppedDown int64 `json:"stepped_down"`
+	SteppedUp   int64 `json:"stepped_up"`
+}
+
+// ladderRung locates a model on a ladder
+type ladderRung struct {
+	ladder int
+	size   int
+}
+
+// LadderSelector moves selections along configured size ladders
+type LadderSelector struct {
+	config LadderConfig
+	rungs  map[string]ladderRung
+
+	stats LadderStats
+	mu    sync.Mutex
+}
+
+// NewLadderSelector creates a selector, filling defaults
+func NewLadderSelector(config LadderConfig) (*LadderSelector, error) {
+	if config.MinQuality == 0 {
+		config.MinQuality = 0.7
+	}
+	if config.MinQuality < 0 {
+		return nil, fmt.Errorf("min_quality must be positive")
+	}
+
+	ls := &LadderSelector{config: config, rungs: make(map[string]ladderRung)}
+	for i, ladder := range config.Ladders {
+		if len(ladder) < 2 {
+			return nil, fmt.Errorf("ladder %d needs at least two sizes", i)
+		}
+		for size, model := range ladder {
+			if _, ok := ls.rungs[model]; ok {
+				return nil, fmt.Errorf("model %s is on more than one ladder", model)
+			}
+			ls.rungs[model] = ladderRung{ladder: i, size: size}
+		}
+	}
+	return ls, nil
+}
+
+// requiredQuality is the quality a size must reach on a cluster
+func (ls *LadderSelector) requiredQuality(clusterID int) float64 {
+	if required, ok := ls.config.ClusterMinQuality[clusterID]; ok {
+		return required
+	}
+	return ls.config.MinQuality
+}
+
+// Select returns the size of model's family to route to among candidates,
+// and a LadderInfo when that differs from model. quality predicts a model's
+// quality on the request's cluster.
+//
+// Predictions are made monotone in size, capping each size at the quality of
+// the sizes above it, so noisy estimates cannot favor a smaller model. When
+// no size clears the requirement the largest is used.
+func (ls *LadderSelector) Select(model string, candidates []string, clusterID int, quality func(model string) (float64, bool)) (string, *LadderInfo) {
+	rung, ok := ls.rungs[model]
+	if !ok {
+		return model, nil
+	}
+
+	available := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		available[candidate] = true
+	}
+	var sizes []string
+	var predicted []float64
+	for _, size := range ls.config.Ladders[rung.ladder] {
+		if !available[size] {
+			continue
+		}
+		if q, ok := quality(size); ok {
+			sizes = append(sizes, size)
+			predicted = append(predicted, q)
+		}
+	}
+	if len(sizes) == 0 {
+		return model, nil
+	}
+	for i := len(predicted) - 2; i >= 0; i-- {
+		predicted[i] = min(predicted[i], predicted[i+1])
+	}
+
+	required := ls.requiredQuality(clusterID)
+	chosen := len(sizes) - 1
+	for i, q := range predicted {
+		if q >= required {
+			chosen = i
+			break
+		}
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.stats.Considered++
+	if sizes[chosen] == model {
+		return model, nil
+	}
+	if ls.rungs[sizes[chosen]].size < rung.size {
+		ls.stats.SteppedDown++
+	} else {
+		ls.stats.SteppedUp++
+	}
+	return sizes[chosen], &LadderInfo{
+		ScoredModel:      model,
+		RequiredQuality:  required,
+		PredictedQuality: predicted[chosen],
+	}
+}
+
+// GetStats returns ladder outcome counts
+func (ls *LadderSelector) GetStats() LadderStats {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.stats
+}
+
+// predictedQuality is a model's artifact quality on a cluster blended with
+// online estimates, as the α-score sees it
+func (p *Plugin) predictedQuality(clusterID int) func(model string) (float64, bool) {
+	return func(model string) (float64, bool) {
+		qhat := p.alphaScorer.QualityScore(model, clusterID, p.currentArtifact)
+		if qhat == nil {
+			return 0, false
+		}
+		return p.quality.Blend(model, clusterID, *qhat), true
+	}
+}
+
+// artifactQuality is a model's artifact quality on a cluster alone, for
+// deterministic decisions
+func (p *Plugin) artifactQuality(clusterID int) func(model string) (float64, bool) {
+	return func(model string) (float64, bool) {
+		qhat := p.alphaScorer.QualityScore(model, clusterID, p.currentArtifact)
+		if qhat == nil {
+			return 0, false
+		}
+		return *qhat, true
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLadderSelector(t *testing.T) {
+	ladder, err := NewLadderSelector(LadderConfig{
+		Ladders:           [][]string{{"small", "medium", "large"}},
+		MinQuality:        0.7,
+		ClusterMinQuality: map[int]float64{2: 0.9},
+	})
+	require.NoError(t, err)
+
+	qualities := map[string]float64{"small": 0.72, "medium": 0.85, "large": 0.93}
+	quality := func(model string) (float64, bool) {
+		q, ok := qualities[model]
+		return q, ok
+	}
+	all := []string{"small", "medium", "large", "other"}
+
+	t.Run("should step down to the smallest sufficient size", func(t *testing.T) {
+		model, info := ladder.Select("large", all, 0, quality)
+		assert.Equal(t, "small", model)
+		require.NotNil(t, info)
+		assert.Equal(t, "large", info.ScoredModel)
+		assert.Equal(t, 0.7, info.RequiredQuality)
+		assert.Equal(t, 0.72, info.PredictedQuality)
+	})
+
+	t.Run("should apply cluster requirements and step up when needed", func(t *testing.T) {
+		model, info := ladder.Select("small", all, 2, quality)
+		assert.Equal(t, "large", model)
+		require.NotNil(t, info)
+		assert.Equal(t, 0.9, info.RequiredQuality)
+	})
+
+	t.Run("should only consider available sizes", func(t *testing.T) {
+		model, _ := ladder.Select("large", []string{"medium", "large"}, 0, quality)
+		assert.Equal(t, "medium", model)
+	})
+
+	t.Run("should cap smaller sizes at the quality of larger ones", func(t *testing.T) {
+		noisy := func(model string) (float64, bool) {
+			return map[string]float64{"small": 0.95, "medium": 0.6, "large": 0.8}[model], true
+		}
+		model, info := ladder.Select("large", all, 0, noisy)
+		assert.Equal(t, "large", model, "small is capped at medium's 0.6")
+		assert.Nil(t, info)
+	})
+
+	t.Run("should leave models off any ladder alone", func(t *testing.T) {
+		model, info := ladder.Select("other", all, 0, quality)
+		assert.Equal(t, "other", model)
+		assert.Nil(t, info)
+	})
+
+	t.Run("should count outcomes", func(t *testing.T) {
+		stats := ladder.GetStats()
+		assert.Equal(t, int64(4), stats.Considered)
+		assert.Equal(t, int64(2), stats.SteppedDown)
+		assert.Equal(t, int64(1), stats.SteppedUp)
+	})
+
+	t.Run("should reject invalid ladders", func(t *testing.T) {
+		_, err := NewLadderSelector(LadderConfig{Ladders: [][]string{{"solo"}}})
+		assert.Error(t, err)
+		_, err = NewLadderSelector(LadderConfig{Ladders: [][]string{{"a", "b"}, {"b", "c"}}})
+		assert.ErrorContains(t, err, "more than one ladder")
+	})
+}
+
+func TestLadderRouting(t *testing.T) {
+	sonnet, opus := "anthropic/claude-3-5-sonnet-20241022", "anthropic/claude-3-opus"
+	features := &RequestFeatures{ClusterID: 1, TokenCount: 500}
+
+	newPlugin := func(t *testing.T, ladder LadderConfig) *Plugin {
+		config := createRouterTestConfig()
+		config.Router.HardCandidates = append(config.Router.HardCandidates, sonnet)
+		ladder.Enabled = true
+		ladder.Ladders = [][]string{{sonnet, opus}}
+		config.SizeLadder = ladder
+		plugin := createRouterTestPluginWithConfig(t, config)
+		// Make the largest size the α-score's pick
+		plugin.currentArtifact.Qhat[opus] = []float64{0.95, 0.95, 0.95}
+		plugin.currentArtifact.Chat[opus] = 0.1
+		return plugin
+	}
+
+	t.Run("should route to the smaller size and keep the scored one as a fallback", func(t *testing.T) {
+		plugin := newPlugin(t, LadderConfig{})
+
+		decision, err := plugin.selectModelForBucket("hard", features)
+		require.NoError(t, err)
+		assert.Equal(t, sonnet, decision.Model)
+		require.NotNil(t, decision.Ladder)
+		assert.Equal(t, opus, decision.Ladder.ScoredModel)
+		assert.Contains(t, decision.Fallbacks, opus)
+		assert.Equal(t, int64(1), plugin.GetMetrics()["size_ladder"].(LadderStats).SteppedDown)
+	})
+
+	t.Run("should keep the scored size when smaller ones fall short", func(t *testing.T) {
+		plugin := newPlugin(t, LadderConfig{ClusterMinQuality: map[int]float64{1: 0.92}})
+
+		decision, err := plugin.selectModelForBucket("hard", features)
+		require.NoError(t, err)
+		assert.Equal(t, opus, decision.Model)
+		assert.Nil(t, decision.Ladder)
+	})
+}
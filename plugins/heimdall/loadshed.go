@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadShedConfig configures shedding load onto the lightweight heuristic
+// triage path (no embeddings, cluster search or GBDT) while decisions run
+// slow or the host is busy, restoring the full pipeline once pressure
+// subsides
+type LoadShedConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// MaxP95 is the p95 decision time above which load is shed
+	// (default 50ms)
+	MaxP95 time.Duration `json:"max_p95"`
+	// MaxCPU is the host CPU utilization within (0, 1] above which load is
+	// shed; zero ignores CPU. Read from /proc/stat, so Linux only.
+	MaxCPU float64 `json:"max_cpu"`
+
+	// Window is the number of recent full decisions p95 is taken over; it
+	// must fill before p95 is acted on (default 200)
+	Window int `json:"window"`
+	// Interval is how often pressure is evaluated (default 5s)
+	Interval time.Duration `json:"interval"`
+	// MinDuration is how long shedding lasts before the full pipeline is
+	// restored, provided CPU is back under MaxCPU (default 30s)
+	MinDuration time.Duration `json:"min_duration"`
+}
+
+// LoadShedStats reports load shedding
+type LoadShedStats struct {
+	Shedding bool      `json:"shedding"`
+	Since    time.Time `json:"since,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+
+	// P95 and CPU are as of the last evaluation; CPU is zero when ignored
+	P95 time.Duration `json:"p95"`
+	CPU float64       `json:"cpu"`
+
+	// Shed counts decisions made on the lightweight path, Episodes the
+	// times shedding started
+	Shed     int64 `json:"shed"`
+	Episodes int64 `json:"episodes"`
+}
+
+// LoadShedder watches decision time and host CPU and reports when
+// decisions should take the lightweight path. Pressure is evaluated
+// lazily as decisions arrive, at most once per interval.
+type LoadShedder struct {
+	config LoadShedConfig
+	now    func() time.Time
+	cpu    func() (float64, error) // nil when CPU is ignored
+
+	latencies []time.Duration
+	next      int
+	filled    bool
+
+	shedding bool
+	since    time.Time
+	reason   string
+	lastEval time.Time
+	p95      time.Duration
+	lastCPU  float64
+	shed     int64
+	episodes int64
+
+	mu sync.Mutex
+}
+
+// NewLoadShedder creates a shedder, filling defaults. cpu samples host CPU
+// utilization; nil reads /proc/stat.
+func NewLoadShedder(config LoadShedConfig, now func() time.Time, cpu func() (float64, error)) (*LoadShedder, error) {
+	if config.MaxP95 == 0 {
+		config.MaxP95 = 50 * time.Millisecond
+	}
+	if config.Window == 0 {
+		config.Window = 200
+	}
+	if config.Interval == 0 {
+		config.Interval = 5 * time.Second
+	}
+	if config.MinDuration == 0 {
+		config.MinDuration = 30 * time.Second
+	}
+	if config.MaxP95 < 0 || config.Window < 0 || config.Interval < 0 || config.MinDuration < 0 {
+		return nil, fmt.Errorf("max_p95, window, interval and min_duration must be positive")
+	}
+	if config.MaxCPU < 0 || config.MaxCPU > 1 {
+		return nil, fmt.Errorf("max_cpu must be within [0, 1], got %v", config.MaxCPU)
+	}
+
+	ls := &LoadShedder{
+		config:    config,
+		now:       now,
+		latencies: make([]time.Duration, config.Window),
+		lastEval:  now(),
+	}
+	if config.MaxCPU > 0 {
+		if cpu == nil {
+			cpu = (&procCPU{}).Sample
+		}
+		// The first sample also sets the baseline of the next
+		if _, err := cpu(); err != nil {
+			return nil, fmt.Errorf("max_cpu needs host CPU: %w", err)
+		}
+		ls.cpu = cpu
+	}
+	return ls, nil
+}
+
+// Shedding reports whether decisions should take the lightweight path,
+// evaluating pressure when the interval has passed
+func (ls *LoadShedder) Shedding() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if now := ls.now(); now.Sub(ls.lastEval) >= ls.config.Interval {
+		ls.lastEval = now
+		ls.evaluate(now)
+	}
+	if ls.shedding {
+		ls.shed++
+	}
+	return ls.shedding
+}
+
+// Record adds the time of a decision made on the full path
+func (ls *LoadShedder) Record(latency time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.latencies[ls.next] = latency
+	ls.next = (ls.next + 1) % len(ls.latencies)
+	if ls.next == 0 {
+		ls.filled = true
+	}
+}
+
+// evaluate starts or stops shedding (no lock - called from locked context)
+func (ls *LoadShedder) evaluate(now time.Time) {
+	var cpuErr error
+	if ls.cpu != nil {
+		var cpu float64
+		if cpu, cpuErr = ls.cpu(); cpuErr == nil {
+			ls.lastCPU = cpu
+		} else {
+			log.Printf("Load shedding could not read host CPU: %v", cpuErr)
+		}
+	}
+	if ls.filled {
+		ls.p95 = percentile95(ls.latencies)
+	}
+
+	if !ls.shedding {
+		var reason string
+		switch {
+		case ls.filled && ls.p95 > ls.config.MaxP95:
+			reason = fmt.Sprintf("p95 decision time %v over %v", ls.p95, ls.config.MaxP95)
+		case ls.cpu != nil && cpuErr == nil && ls.lastCPU > ls.config.MaxCPU:
+			reason = fmt.Sprintf("host CPU %.0f%% over %.0f%%", 100*ls.lastCPU, 100*ls.config.MaxCPU)
+		default:
+			return
+		}
+		ls.shedding, ls.since, ls.reason = true, now, reason
+		ls.episodes++
+		log.Printf("Shedding load onto heuristic triage: %s", reason)
+		return
+	}
+
+	if now.Sub(ls.since) < ls.config.MinDuration {
+		return
+	}
+	if ls.cpu != nil && cpuErr == nil && ls.lastCPU > ls.config.MaxCPU {
+		return
+	}
+	// Decision times restart from the full pipeline, so the window refills
+	// before p95 can shed load again
+	ls.shedding, ls.since, ls.reason = false, time.Time{}, ""
+	ls.next, ls.filled = 0, false
+	log.Printf("Restored the full routing pipeline after shedding load")
+}
+
+// percentile95 returns the 95th percentile of latencies
+func percentile95(latencies []time.Duration) time.Duration {
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+// GetStats reports load shedding
+func (ls *LoadShedder) GetStats() LoadShedStats {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return LoadShedStats{
+		Shedding: ls.shedding,
+		Since:    ls.since,
+		Reason:   ls.reason,
+		P95:      ls.p95,
+		CPU:      ls.lastCPU,
+		Shed:     ls.shed,
+		Episodes: ls.episodes,
+	}
+}
+
+// procCPU samples host CPU utilization from /proc/stat as the busy share
+// of the time since the previous sample
+type procCPU struct {
+	idle, total uint64
+}
+
+func (pc *procCPU) Sample() (float64, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return 0, fmt.Errorf("empty /proc/stat")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, fmt.Errorf("unexpected /proc/stat line %q", scanner.Text())
+	}
+
+	var idle, total uint64
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected /proc/stat field %q", field)
+		}
+		total += value
+		// idle and iowait
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+
+	deltaIdle, deltaTotal := idle-pc.idle, total-pc.total
+	pc.idle, pc.total = idle, total
+	if deltaTotal == 0 {
+		return 0, nil
+	}
+	return 1 - float64(deltaIdle)/float64(deltaTotal), nil
+}
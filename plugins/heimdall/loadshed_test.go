@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShedder(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	config := LoadShedConfig{MaxP95: 20 * time.Millisecond, Window: 10, Interval: time.Second, MinDuration: time.Minute}
+
+	record := func(ls *LoadShedder, n int, latency time.Duration) {
+		for i := 0; i < n; i++ {
+			ls.Record(latency)
+		}
+	}
+
+	t.Run("should shed once p95 decision time exceeds the limit", func(t *testing.T) {
+		ls, err := NewLoadShedder(config, clock, nil)
+		require.NoError(t, err)
+		record(ls, 9, time.Millisecond)
+		now = now.Add(time.Second)
+		assert.False(t, ls.Shedding(), "the window must fill first")
+
+		record(ls, 1, 50*time.Millisecond)
+		assert.False(t, ls.Shedding(), "pressure is evaluated once per interval")
+		now = now.Add(time.Second)
+		assert.True(t, ls.Shedding())
+
+		stats := ls.GetStats()
+		assert.Equal(t, 50*time.Millisecond, stats.P95)
+		assert.Contains(t, stats.Reason, "p95 decision time")
+		assert.Equal(t, int64(1), stats.Episodes)
+		assert.Equal(t, int64(1), stats.Shed)
+	})
+
+	t.Run("should restore the full pipeline after the minimum duration", func(t *testing.T) {
+		ls, err := NewLoadShedder(config, clock, nil)
+		require.NoError(t, err)
+		record(ls, 10, 50*time.Millisecond)
+		now = now.Add(time.Second)
+		require.True(t, ls.Shedding())
+
+		now = now.Add(30 * time.Second)
+		assert.True(t, ls.Shedding())
+		now = now.Add(30 * time.Second)
+		assert.False(t, ls.Shedding())
+
+		now = now.Add(time.Second)
+		assert.False(t, ls.Shedding(), "decision times refill before shedding again")
+	})
+
+	t.Run("should shed while the host is busy", func(t *testing.T) {
+		cpu := 0.5
+		cpuConfig := config
+		cpuConfig.MaxCPU = 0.8
+		ls, err := NewLoadShedder(cpuConfig, clock, func() (float64, error) { return cpu, nil })
+		require.NoError(t, err)
+
+		now = now.Add(time.Second)
+		assert.False(t, ls.Shedding())
+		cpu = 0.95
+		now = now.Add(time.Second)
+		assert.True(t, ls.Shedding())
+		assert.Equal(t, "host CPU 95% over 80%", ls.GetStats().Reason)
+
+		now = now.Add(time.Minute)
+		assert.True(t, ls.Shedding(), "shedding holds while CPU stays high")
+		cpu = 0.3
+		now = now.Add(time.Second)
+		assert.False(t, ls.Shedding())
+	})
+
+	t.Run("should read host CPU from /proc/stat", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("/proc/stat is Linux only")
+		}
+		var pc procCPU
+		_, err := pc.Sample()
+		require.NoError(t, err)
+		cpu, err := pc.Sample()
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, cpu, 0.0)
+		assert.LessOrEqual(t, cpu, 1.0)
+	})
+
+	t.Run("should reject invalid configs", func(t *testing.T) {
+		_, err := NewLoadShedder(LoadShedConfig{MaxCPU: 1.5}, clock, nil)
+		assert.ErrorContains(t, err, "max_cpu must be within [0, 1]")
+	})
+}
+
+func TestLoadShedRouting(t *testing.T) {
+	config := createRouterTestConfig()
+	config.LoadShed = LoadShedConfig{Enabled: true, Window: 1, Interval: time.Nanosecond}
+	config.EnableCaching = true
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	route := func(content string) context.Context {
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}})
+		require.NoError(t, err)
+		require.Nil(t, shortCircuit)
+		return ctx
+	}
+
+	plugin.shedder.Record(time.Second)
+	ctx := route("What is the capital of France?")
+	assert.Equal(t, string(FallbackLoadShed), ctx.Value("heimdall_fallback_reason"))
+	assert.Equal(t, BucketCheap, ctx.Value("heimdall_bucket"))
+	assert.Equal(t, -1, ctx.Value("heimdall_features").(RequestFeatures).ClusterID)
+
+	metrics := plugin.Metrics()
+	require.NotNil(t, metrics.LoadShed)
+	assert.True(t, metrics.LoadShed.Shedding)
+	assert.Equal(t, int64(1), metrics.FallbackReasons[FallbackLoadShed])
+	assert.Zero(t, plugin.cache.Len(), "shed decisions are not cached")
+
+	t.Run("should keep an earlier fallback reason", func(t *testing.T) {
+		plugin.currentArtifact.Version = defaultArtifactVersion
+		plugin.shedder.Record(time.Second)
+		ctx := route("What is the capital of Spain?")
+		assert.Equal(t, string(FallbackArtifactMissing), ctx.Value("heimdall_fallback_reason"))
+		assert.Zero(t, plugin.cache.Len(), "shed decisions are not cached")
+	})
+}
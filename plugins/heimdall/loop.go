@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+)
+
+// Loop detection actions
+const (
+	// LoopActionReject rejects looping requests until the cooldown ends
+	LoopActionReject = "reject"
+	// LoopActionObserve only counts loops
+	LoopActionObserve = "observe"
+)
+
+// LoopConfig configures detection of callers stuck resending the same prompt
+type LoopConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Threshold is the number of repeats within Window that make a loop
+	// (default 10 within 10s)
+	Threshold int           `json:"threshold"`
+	Window    time.Duration `json:"window"`
+	// Cooldown is how long a detected loop is rejected (default 30s)
+	Cooldown time.Duration `json:"cooldown"`
+
+	// Action is "reject" (default) or "observe"
+	Action string `json:"action"`
+
+	// MatchTemplates treats prompts that differ only in slot values such as
+	// numbers, IDs and paths as repeats
+	MatchTemplates bool `json:"match_templates"`
+}
+
+// LoopStats counts loop detection outcomes
+type LoopStats struct {
+	Detected int64 `json:"detected"`
+	Rejected int64 `json:"rejected"`
+	Active   int   `json:"active"`
+}
+
+// loopState tracks recent repeats of one prompt from one caller
+type loopState struct {
+	hits         []time.Time
+	blockedUntil time.Time
+}
+
+// LoopDetector spots tight loops of identical prompts from one caller
+type LoopDetector struct {
+	config LoopConfig
+	now    func() time.Time
+
+	states map[string]*loopState
+	stats  LoopStats
+	mu     sync.Mutex
+}
+
+// NewLoopDetector creates a detector, filling defaults
+func NewLoopDetector(config LoopConfig, now func() time.Time) (*LoopDetector, error) {
+	if config.Threshold == 0 {
+		config.Threshold = 10
+	}
+	if config.Window == 0 {
+		config.Window = 10 * time.Second
+	}
+	if config.Cooldown == 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.Action == "" {
+		config.Action = LoopActionReject
+	}
+	if config.Threshold < 2 {
+		return nil, fmt.Errorf("threshold must be at least 2, got %d", config.Threshold)
+	}
+	if config.Window < 0 || config.Cooldown < 0 {
+		return nil, fmt.Errorf("window and cooldown must not be negative")
+	}
+	if config.Action != LoopActionReject && config.Action != LoopActionObserve {
+		return nil, fmt.Errorf("unknown loop action %q", config.Action)
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	return &LoopDetector{
+		config: config,
+		now:    now,
+		states: make(map[string]*loopState),
+	}, nil
+}
+
+// Key identifies a request's prompt and caller, or "" for anonymous
+// requests, which cannot be told apart
+func (ld *LoopDetector) Key(req *RouterRequest) string {
+	credentials := credentialsOf(req.Headers)
+	if credentials == "||" || req.Body == nil {
+		return ""
+	}
+
+	var prompt string
+	if ld.config.MatchTemplates {
+		prompt = features.TemplateFingerprint(req.Body.Messages)
+	} else {
+		h := sha256.New()
+		for _, msg := range req.Body.Messages {
+			h.Write([]byte(msg.Role + "\x00" + msg.Content + "\x00"))
+		}
+		prompt = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return auth.TokenFingerprint(credentials) + ":" + req.Body.Model + ":" + prompt
+}
+
+// Check records a request and returns how long it must be held off when
+// it is part of a loop, or 0 to let it through
+func (ld *LoopDetector) Check(key string) time.Duration {
+	if key == "" {
+		return 0
+	}
+
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	now := ld.now()
+	state, ok := ld.states[key]
+	if !ok {
+		if len(ld.states) >= 10000 {
+			ld.evictIdle(now)
+		}
+		state = &loopState{}
+		ld.states[key] = state
+	}
+
+	if now.Before(state.blockedUntil) {
+		if ld.config.Action == LoopActionObserve {
+			return 0
+		}
+		ld.stats.Rejected++
+		return state.blockedUntil.Sub(now)
+	}
+
+	// Keep only the repeats still inside the window
+	recent := state.hits[:0]
+	for _, hit := range state.hits {
+		if now.Sub(hit) < ld.config.Window {
+			recent = append(recent, hit)
+		}
+	}
+	state.hits = append(recent, now)
+	if len(state.hits) < ld.config.Threshold {
+		return 0
+	}
+
+	state.hits = state.hits[:0]
+	state.blockedUntil = now.Add(ld.config.Cooldown)
+	ld.stats.Detected++
+	if ld.config.Action == LoopActionObserve {
+		return 0
+	}
+	ld.stats.Rejected++
+	return ld.config.Cooldown
+}
+
+// GetStats returns the detector's counters
+func (ld *LoopDetector) GetStats() LoopStats {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+
+	stats := ld.stats
+	now := ld.now()
+	for _, state := range ld.states {
+		if now.Before(state.blockedUntil) {
+			stats.Active++
+		}
+	}
+	return stats
+}
+
+// evictIdle drops states with no recent repeats (no lock - called from locked context)
+func (ld *LoopDetector) evictIdle(now time.Time) {
+	for key, state := range ld.states {
+		idle := len(state.hits) == 0 || now.Sub(state.hits[len(state.hits)-1]) >= ld.config.Window
+		if idle && !now.Before(state.blockedUntil) {
+			delete(ld.states, key)
+		}
+	}
+}
+
+// rejectLoop answers a looping request with a structured rate-limit error
+func (p *Plugin) rejectLoop(ctx *context.Context, req *schemas.BifrostRequest, retryAfter time.Duration) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
+	*ctx = context.WithValue(*ctx, "heimdall_loop_detected", true)
+	return p.reject(ctx, req, NewRateLimitError("repeated_request", "identical request repeated too often", retryAfter))
+}
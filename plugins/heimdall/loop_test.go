@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopDetector(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	config := LoopConfig{Threshold: 3, Window: 10 * time.Second, Cooldown: 30 * time.Second}
+	request := func(content string) *RouterRequest {
+		return &RouterRequest{
+			Headers: map[string][]string{"Authorization": {"Bearer sk-agent"}},
+			Body:    &RequestBody{Model: "gpt-4o", Messages: []ChatMessage{{Role: "user", Content: content}}},
+		}
+	}
+
+	t.Run("should reject repeats past the threshold until the cooldown ends", func(t *testing.T) {
+		ld, err := NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		key := ld.Key(request("retry this"))
+
+		assert.Zero(t, ld.Check(key))
+		assert.Zero(t, ld.Check(key))
+		assert.Equal(t, 30*time.Second, ld.Check(key))
+
+		now = now.Add(10 * time.Second)
+		assert.Equal(t, 20*time.Second, ld.Check(key))
+		assert.Equal(t, LoopStats{Detected: 1, Rejected: 2, Active: 1}, ld.GetStats())
+
+		now = now.Add(20 * time.Second)
+		assert.Zero(t, ld.Check(key))
+	})
+
+	t.Run("should forget repeats outside the window", func(t *testing.T) {
+		ld, err := NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		key := ld.Key(request("slow poll"))
+
+		for i := 0; i < 5; i++ {
+			assert.Zero(t, ld.Check(key))
+			now = now.Add(6 * time.Second)
+		}
+	})
+
+	t.Run("should match near-identical prompts by template", func(t *testing.T) {
+		ld, err := NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		assert.NotEqual(t, ld.Key(request("fetch order 1001")), ld.Key(request("fetch order 1002")))
+
+		config := config
+		config.MatchTemplates = true
+		ld, err = NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		assert.Equal(t, ld.Key(request("fetch order 1001")), ld.Key(request("fetch order 1002")))
+	})
+
+	t.Run("should tell callers apart and skip anonymous requests", func(t *testing.T) {
+		ld, err := NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		other := request("retry this")
+		other.Headers = map[string][]string{"Authorization": {"Bearer sk-other"}}
+		assert.NotEqual(t, ld.Key(request("retry this")), ld.Key(other))
+
+		other.Headers = map[string][]string{}
+		assert.Empty(t, ld.Key(other))
+		assert.Zero(t, ld.Check(""))
+	})
+
+	t.Run("should only count loops when observing", func(t *testing.T) {
+		config := config
+		config.Action = LoopActionObserve
+		ld, err := NewLoopDetector(config, clock)
+		require.NoError(t, err)
+		key := ld.Key(request("observe me"))
+
+		for i := 0; i < 4; i++ {
+			assert.Zero(t, ld.Check(key))
+		}
+		assert.Equal(t, LoopStats{Detected: 1, Active: 1}, ld.GetStats())
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		_, err := NewLoopDetector(LoopConfig{Threshold: 1}, clock)
+		assert.ErrorContains(t, err, "threshold must be at least 2")
+		_, err = NewLoopDetector(LoopConfig{Action: "block"}, clock)
+		assert.ErrorContains(t, err, "unknown loop action")
+	})
+}
+
+func TestLoopRejection(t *testing.T) {
+	config := createRouterTestConfig()
+	config.LoopDetection = LoopConfig{Enabled: true, Threshold: 2}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	content := "are we there yet"
+	ask := func() (context.Context, *schemas.PluginShortCircuit) {
+		ctx := context.WithValue(context.Background(), "http_headers",
+			map[string][]string{"Authorization": {"Bearer sk-agent"}})
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		})
+		require.NoError(t, err)
+		return ctx, shortCircuit
+	}
+
+	_, shortCircuit := ask()
+	assert.Nil(t, shortCircuit)
+
+	ctx, shortCircuit := ask()
+	require.NotNil(t, shortCircuit)
+	require.NotNil(t, shortCircuit.Error)
+	assert.Equal(t, http.StatusTooManyRequests, *shortCircuit.Error.StatusCode)
+	assert.Equal(t, ErrorTypeRateLimit, *shortCircuit.Error.Error.Type)
+	assert.Equal(t, "repeated_request", *shortCircuit.Error.Error.Code)
+	assert.Contains(t, shortCircuit.Error.Error.Message, "retry after 30s")
+	assert.Equal(t, true, ctx.Value("heimdall_loop_detected"))
+	assert.Equal(t, int64(1), plugin.GetMetrics()["loop_detection"].(LoopStats).Detected)
+}
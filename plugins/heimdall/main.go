@@ -7,17 +7,27 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"math"
+	"math/bits"
 	"net/http"
-	"regexp"
-	"sort"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar"
 )
 
 // Config holds the native configuration for the Heimdall plugin
@@ -33,13 +43,147 @@ type Config struct {
 	
 	// Tuning/artifact configuration
 	Tuning TuningConfig `json:"tuning"`
-	
+
+	// Self-hosted (vLLM/Ollama) endpoint configuration
+	SelfHosted SelfHostedConfig `json:"self_hosted"`
+
+	// Drain/maintenance mode configuration
+	Drain DrainConfig `json:"drain"`
+
+	// Bring-your-own-key routing policy
+	BYOK BYOKPolicyConfig `json:"byok"`
+
+	// Handling of requests no auth adapter matches
+	Anonymous AnonymousPolicyConfig `json:"anonymous"`
+
+	// Outbound timeout/retry hints attached to decisions
+	Timeouts TimeoutPolicyConfig `json:"timeouts"`
+
+	// Per-session cost accumulation and spend-based policies
+	Sessions SessionConfig `json:"sessions"`
+
+	// Speculative cheap-first cascade routing
+	Cascade CascadeConfig `json:"cascade"`
+
+	// LLM-as-judge sampling feeding online quality estimates
+	Judge JudgeConfig `json:"judge"`
+
+	// Sampling for human labeling and label import
+	Labeling LabelingConfig `json:"labeling"`
+
+	// Compare mode: sampled requests answered by a challenger for preference labeling
+	Compare CompareConfig `json:"compare"`
+
+	// Automatic quarantine of models whose success rate collapses
+	Quarantine QuarantineConfig `json:"quarantine"`
+
+	// Per-model concurrent request ceilings
+	Concurrency ConcurrencyConfig `json:"concurrency"`
+
+	// Hard-bucket capacity and surge handling
+	Admission AdmissionConfig `json:"admission"`
+
+	// Heuristic triage while decisions run slow or the host is busy
+	LoadShed LoadShedConfig `json:"load_shed"`
+
+	// Detection of runaway spend per tenant and model
+	CostAnomaly CostAnomalyConfig `json:"cost_anomaly"`
+
+	// Detection of callers stuck resending the same prompt
+	LoopDetection LoopConfig `json:"loop_detection"`
+
+	// Moving average of each user's bucket probabilities
+	BucketSmoothing BucketSmoothingConfig `json:"bucket_smoothing"`
+
+	// Smallest-sufficient size selection within model families
+	SizeLadder LadderConfig `json:"size_ladder"`
+
+	// Closed-loop α adjustment against success, latency and cost targets
+	AlphaController AlphaControllerConfig `json:"alpha_controller"`
+
+	// Trail of artifact reloads and admin changes
+	Audit AuditConfig `json:"audit"`
+
+	// What decision logs reveal about request features
+	FeatureLogging FeatureLoggingConfig `json:"feature_logging"`
+
+	// Tagging of health-check and canary traffic kept out of learning
+	Synthetic SyntheticConfig `json:"synthetic"`
+
+	// Strict-deterministic decisions for compliance replays
+	Deterministic DeterministicConfig `json:"deterministic"`
+
+	// max_tokens derived from the selected models' remaining context
+	OutputCap OutputCapConfig `json:"output_cap"`
+
+	// Prompt-prefix cache estimation and cost discounts
+	PromptCache PromptCacheConfig `json:"prompt_cache"`
+
+	// Per-provider token-count calibration for cost and context estimates
+	TokenCalibration TokenCalibrationConfig `json:"token_calibration"`
+
+	// Outcome accounting for streamed responses
+	StreamAccounting StreamAccountingConfig `json:"stream_accounting"`
+
+	// Routing or rejection of requests with no prompt text
+	EmptyPrompt EmptyPromptConfig `json:"empty_prompt"`
+
+	// Named routing hint sets callers select with model "router:<profile>"
+	RouterProfiles map[string]string `json:"router_profiles"`
+
+	// Per-signal observability sampling, with per-tenant overrides
+	Observability ObservabilityConfig `json:"observability"`
+
+	// Grafana dashboard generated from the configured buckets and candidates
+	Dashboards DashboardConfig `json:"dashboards"`
+
+	// Headers set by the Envoy external authorization adapter
+	Envoy EnvoyConfig `json:"envoy"`
+
+	// Outbound headers and query parameters per provider kind
+	RequestShaping RequestShapingConfig `json:"request_shaping"`
+
+	// Rewrites of fallback attempts for their provider
+	FallbackNormalization FallbackNormalizationConfig `json:"fallback_normalization"`
+
+	// Signed routing policy snapshots for incident forensics
+	Snapshots SnapshotConfig `json:"snapshots"`
+
+	// Periodic routing health digest posted to a webhook
+	Digest DigestConfig `json:"digest"`
+
+	// Authentication and roles for the admin surface
+	AdminAuth AdminAuthConfig `json:"admin_auth"`
+
+	// Shadow comparison of a second scoring implementation
+	DualRun scoring.DualRunConfig `json:"dual_run"`
+
+	// External feature/embedding sidecar
+	Sidecar sidecar.Config `json:"sidecar"`
+
+	// Custom scoring function hosted as a WASM module
+	WASMScoring scoring.WASMConfig `json:"wasm_scoring"`
+
+	// Behavior until the first routing artifact loads
+	Startup StartupConfig `json:"startup"`
+
 	// Performance and caching settings
 	Timeout              time.Duration `json:"timeout"`
 	CacheTTL            time.Duration `json:"cache_ttl"`
 	MaxCacheSize        int           `json:"max_cache_size"`
 	EmbeddingTimeout    time.Duration `json:"embedding_timeout"`
 	FeatureTimeout      time.Duration `json:"feature_timeout"`
+
+	// Optional AES-GCM encryption of cached responses and of decisions
+	// cached in a WithCache store
+	CacheEncryption EncryptionConfig `json:"cache_encryption"`
+
+	// Exact-match cache of provider responses
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+
+	// TemplateCacheKeys keys cached decisions by prompt template rather than
+	// exact content, so templated (e.g. agent) prompts share decisions
+	TemplateCacheKeys bool `json:"template_cache_keys"`
 	
 	// Feature flags
 	EnableCaching      bool `json:"enable_caching"`
@@ -54,6 +198,11 @@ type RouterConfig struct {
 	Alpha      float64                  `json:"alpha"`
 	Thresholds BucketThresholds         `json:"thresholds"`
 	TopP       int                      `json:"top_p"`
+	// MaxCandidates and MaxScoringTime bound scoring per request (0 = unbounded)
+	MaxCandidates  int           `json:"max_candidates"`
+	MaxScoringTime time.Duration `json:"max_scoring_time"`
+	// MaxFallbacks caps each decision's fallback chain (0 = unbounded)
+	MaxFallbacks int `json:"max_fallbacks"`
 	Penalties  PenaltyConfig           `json:"penalties"`
 	BucketDefaults BucketDefaults       `json:"bucket_defaults"`
 	CheapCandidates []string            `json:"cheap_candidates"`
@@ -62,15 +211,9 @@ type RouterConfig struct {
 	OpenRouter     OpenRouterConfig     `json:"openrouter"`
 }
 
-type BucketThresholds struct {
-	Cheap float64 `json:"cheap"`
-	Hard  float64 `json:"hard"`
-}
+type BucketThresholds = core.BucketThresholds
 
-type PenaltyConfig struct {
-	LatencySD     float64 `json:"latency_sd"`
-	CtxOver80Pct  float64 `json:"ctx_over_80pct"`
-}
+type PenaltyConfig = core.PenaltyConfig
 
 type BucketDefaults struct {
 	Mid  BucketParams `json:"mid"`
@@ -89,6 +232,11 @@ type OpenRouterConfig struct {
 
 type AuthAdaptersConfig struct {
 	Enabled []string `json:"enabled"`
+
+	// Per-adapter settings
+	AnthropicOAuth auth.AnthropicOAuthConfig `json:"anthropic_oauth"`
+	JWT            auth.JWTAuthConfig `json:"jwt"`
+	HMAC           auth.HMACAuthConfig `json:"hmac"`
 }
 
 type CatalogConfig struct {
@@ -98,28 +246,17 @@ type CatalogConfig struct {
 
 type TuningConfig struct {
 	ArtifactURL   string        `json:"artifact_url"`
+	// ArtifactURLs are backup endpoints tried in order after ArtifactURL
+	ArtifactURLs  []string      `json:"artifact_urls"`
 	ReloadSeconds time.Duration `json:"reload_seconds"`
 }
 
 // RouterRequest represents internal routing request
-type RouterRequest struct {
-	URL     string                    `json:"url"`
-	Method  string                    `json:"method"`
-	Headers map[string][]string       `json:"headers"`
-	Body    *RequestBody              `json:"body,omitempty"`
-}
+type RouterRequest = core.RouterRequest
 
-type RequestBody struct {
-	Messages []ChatMessage `json:"messages"`
-	Model    string        `json:"model,omitempty"`
-	Stream   bool          `json:"stream,omitempty"`
-	Params   map[string]interface{} `json:"-"` // Additional params
-}
+type RequestBody = core.RequestBody
 
-type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+type ChatMessage = core.ChatMessage
 
 // RouterResponse represents the native routing response
 type RouterResponse struct {
@@ -128,16 +265,53 @@ type RouterResponse struct {
 	Bucket              Bucket              `json:"bucket"`
 	BucketProbabilities BucketProbabilities `json:"bucket_probabilities"`
 	AuthInfo            *AuthInfo           `json:"auth_info"`
-	FallbackReason      string              `json:"fallback_reason,omitempty"`
+	FallbackReason      FallbackReason      `json:"fallback_reason,omitempty"`
+
+	// Timestamp is when the decision was served, including from the cache;
+	// capacity forecasts bucket decision log records by it
+	Timestamp time.Time `json:"timestamp,omitzero"`
+
+	// SessionID is the conversation the request belongs to, if tracked
+	SessionID string `json:"session_id,omitempty"`
+
+	// FeaturePrivacy is set on decision log records whose features were
+	// coarsened or withheld (see feature_privacy.go)
+	FeaturePrivacy string `json:"feature_privacy,omitempty"`
+
+	// Admission is set when hard-bucket admission control queued or
+	// downgraded the request
+	Admission string `json:"admission,omitempty"`
+
+	// CostAnomaly is set when the tenant was held to the cheap bucket for
+	// an unacknowledged cost anomaly
+	CostAnomaly bool `json:"cost_anomaly,omitempty"`
+
+	// Explanation is set on advisory decisions that asked for one (see
+	// advisory.go)
+	Explanation *DecisionExplanation `json:"explanation,omitempty"`
+
+	// Replay is set on deterministic decisions: the live inputs needed to
+	// reproduce them (see deterministic.go)
+	Replay *ReplayInputs `json:"replay,omitempty"`
+
+	// hardSlot is set while the request holds hard-bucket capacity; it is
+	// never cached
+	hardSlot bool
+
+	// cacheHit is set on decisions served from the decision cache
+	cacheHit bool
+
+	// SchemaVersion is set when the response is serialized (see decision_codec.go)
+	SchemaVersion int `json:"schema_version,omitempty"`
 }
 
 // Bucket represents the bucket type
-type Bucket string
+type Bucket = core.Bucket
 
 const (
-	BucketCheap Bucket = "cheap"
-	BucketMid   Bucket = "mid" 
-	BucketHard  Bucket = "hard"
+	BucketCheap = core.BucketCheap
+	BucketMid   = core.BucketMid
+	BucketHard  = core.BucketHard
 )
 
 // RouterDecision represents the routing decision 
@@ -148,6 +322,26 @@ type RouterDecision struct {
 	ProviderPrefs ProviderPrefs          `json:"provider_prefs"`
 	Auth          AuthConfig             `json:"auth"`
 	Fallbacks     []string               `json:"fallbacks"`
+
+	// ProviderHints are outbound timeout/retry hints keyed by provider kind
+	ProviderHints map[string]ProviderHint `json:"provider_hints,omitempty"`
+
+	// Outbound are the headers and query parameters requests must carry,
+	// keyed by provider kind (see request_shaping.go)
+	Outbound map[string]OutboundShaping `json:"outbound,omitempty"`
+
+	// Cascade is set when this is a speculative cheap-first attempt
+	Cascade *CascadeInfo `json:"cascade,omitempty"`
+
+	// Ladder is set when a size ladder replaced the α-score's pick
+	Ladder *LadderInfo `json:"ladder,omitempty"`
+
+	// Escalation is the final fallback, taken from the next bucket up
+	Escalation *EscalationInfo `json:"escalation,omitempty"`
+
+	// BudgetTruncated is set when the scoring budget stopped selection
+	// before every candidate was scored
+	BudgetTruncated bool `json:"budget_truncated,omitempty"`
 }
 
 // ProviderPrefs represents provider preferences
@@ -164,63 +358,37 @@ type AuthConfig struct {
 }
 
 // RequestFeatures represents extracted request features
-type RequestFeatures struct {
-	Embedding         []float64 `json:"embedding"`
-	ClusterID         int       `json:"cluster_id"`
-	TopPDistances     []float64 `json:"top_p_distances"`
-	TokenCount        int       `json:"token_count"`
-	HasCode          bool      `json:"has_code"`
-	HasMath          bool      `json:"has_math"`
-	NgramEntropy     float64   `json:"ngram_entropy"`
-	ContextRatio     float64   `json:"context_ratio"`
-	UserSuccessRate  *float64  `json:"user_success_rate,omitempty"`
-	AvgLatency       *float64  `json:"avg_latency,omitempty"`
-}
+type RequestFeatures = core.RequestFeatures
+
+// FeatureOverrides pre-populates request features computed upstream
+type FeatureOverrides = core.FeatureOverrides
 
 // BucketProbabilities represents bucket classification probabilities
-type BucketProbabilities struct {
-	Cheap float64 `json:"cheap"`
-	Mid   float64 `json:"mid"`
-	Hard  float64 `json:"hard"`
-}
+type BucketProbabilities = core.BucketProbabilities
 
 // AuthInfo represents authentication information
-type AuthInfo struct {
-	Provider string `json:"provider"`
-	Type     string `json:"type"`
-	Token    string `json:"token"`
-}
+type AuthInfo = auth.AuthInfo
 
 // AvengersArtifact represents the ML artifact for routing decisions
-type AvengersArtifact struct {
-	Version    string                     `json:"version"`
-	Centroids  string                    `json:"centroids"`  // path to FAISS index
-	Alpha      float64                   `json:"alpha"`
-	Thresholds BucketThresholds          `json:"thresholds"`
-	Penalties  PenaltyConfig             `json:"penalties"`
-	Qhat       map[string][]float64      `json:"qhat"`  // model -> cluster quality scores
-	Chat       map[string]float64        `json:"chat"`  // model -> normalized cost
-	GBDT       GBDTConfig                `json:"gbdt"`
-}
+type AvengersArtifact = core.AvengersArtifact
 
-type GBDTConfig struct {
-	Framework     string                 `json:"framework"`
-	ModelPath     string                 `json:"model_path"`
-	FeatureSchema map[string]interface{} `json:"feature_schema"`
-}
+type GBDTConfig = core.GBDTConfig
+
+// ModelProfile and ClusterConstraint are the artifact's per-cluster hard filters
+type ModelProfile = core.ModelProfile
+
+type ClusterConstraint = core.ClusterConstraint
 
 // ModelScore represents a model's alpha score breakdown
-type ModelScore struct {
-	Model        string  `json:"model"`
-	QualityScore float64 `json:"quality_score"`
-	CostScore    float64 `json:"cost_score"`
-	PenaltyScore float64 `json:"penalty_score"`
-	AlphaScore   float64 `json:"alpha_score"`
-}
+type ModelScore = core.ModelScore
 
-// CacheEntry represents a cached routing decision
+// CacheEntry represents a cached routing decision. The in-process cache
+// keeps decisions as typed values in Response; caches supplied with
+// WithCache, which may persist entries, get the versioned, optionally
+// encrypted RouterResponse encoding in Data.
 type CacheEntry struct {
-	Response  RouterResponse
+	Data      []byte
+	Response  *RouterResponse
 	ExpiresAt time.Time
 }
 
@@ -229,789 +397,633 @@ type CacheEntry struct {
 // Direct ports of TypeScript logic for GBDT triage and α-score routing
 // ============================================================================
 
-// AuthAdapter represents an authentication adapter
-type AuthAdapter interface {
-	GetID() string
-	Matches(headers map[string][]string) bool
-	Extract(headers map[string][]string) *AuthInfo
-	Apply(outgoing *http.Request) *http.Request
-}
-
-// AuthAdapterRegistry manages authentication adapters
-type AuthAdapterRegistry struct {
-	adapters map[string]AuthAdapter
-	mu       sync.RWMutex
-}
-
-func NewAuthAdapterRegistry() *AuthAdapterRegistry {
-	return &AuthAdapterRegistry{
-		adapters: make(map[string]AuthAdapter),
-	}
-}
-
-func (r *AuthAdapterRegistry) Register(adapter AuthAdapter) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.adapters[adapter.GetID()] = adapter
-}
-
-func (r *AuthAdapterRegistry) Get(id string) AuthAdapter {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.adapters[id]
-}
-
-func (r *AuthAdapterRegistry) GetEnabled(enabledIDs []string) []AuthAdapter {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// Plugin implements the schemas.Plugin interface for native Heimdall routing
+type Plugin struct {
+	name   string
+	config Config
 	
-	var enabled []AuthAdapter
-	for _, id := range enabledIDs {
-		if adapter, exists := r.adapters[id]; exists {
-			enabled = append(enabled, adapter)
-		}
-	}
-	return enabled
-}
+	// Core routing components (native Go implementations)
+	authRegistry     *auth.AuthAdapterRegistry
+	router           *router.Router
+	strictRouter     *router.Router // deterministic decisions: artifact-only scoring, no time budget
+	featureExtractor router.FeatureExtractor
+	alphaScorer      *scoring.AlphaScorer // built-in scorer carrying quality and self-hosted hooks
+	selfHosted       *SelfHostedRegistry
+	drains           *DrainManager
+	fallbacks        *IssuedFallbacks // fallback lists issued, to recognise Bifrost's attempts
+	latency          *LatencyTracker
+	sessions         *SessionTracker // nil when session tracking is disabled
+	responseScorer   *ResponseScorer
+	cascade          *cascadeTracker
+	quality          *scoring.QualityStore
+	judge            *JudgePipeline // nil when judging is disabled
+	labeling         *LabelingStore // nil when labeling is disabled
+	comparer         *Comparer      // nil when compare mode is disabled
+	calibration      *CalibrationTracker
+	streams          *StreamTracker
+	sampler          *ObservabilitySampler
+	shaper           *RequestShaper
+	normalizer       *FallbackNormalizer
+	quarantine       *QuarantineManager // nil when quarantine is disabled
+	concurrency      *ConcurrencyLimiter // nil when concurrency limits are disabled
+	admission        *AdmissionController // nil when admission control is disabled
+	shedder          *LoadShedder         // nil when load shedding is disabled
+	anomalies        *CostAnomalyDetector // nil when cost anomaly detection is disabled
+	loops            *LoopDetector        // nil when loop detection is disabled
+	smoother         *BucketSmoother      // nil when bucket smoothing is disabled
+	ladder           *LadderSelector      // nil when size ladders are disabled
+	alphaController  *AlphaController     // nil when α adjustment is disabled
+	audit            *AuditLog            // nil when the audit trail is disabled
+	snapshots        *SnapshotStore       // nil when snapshots are disabled
+	digester         *Digester            // nil when the digest is disabled
+	profiles         map[string]*RoutingPolicy
+	aggregates       *FeatureAggregator   // nil unless features are logged as aggregates
+	synthetic        *SyntheticDetector   // nil when synthetic traffic tagging is disabled
+	adminAuth        *adminAuthenticator  // nil when the admin surface is open
+	dualRun          *scoring.DualRunner // nil when dual-run is disabled
+	sidecar          *sidecar.Client     // nil when no sidecar is configured
+	wasmScorer       *scoring.WASMScorer // nil when WASM scoring is disabled
+	decideChain      DecideFunc          // decide wrapped in injected middleware
+	prefixIndex      *features.PrefixIndex // nil when prompt-cache estimation is disabled
+	tokens           *TokenCalibrator    // nil when token calibration is disabled
+	implementations  Implementations     // active stage implementations
 
-func (r *AuthAdapterRegistry) FindMatch(headers map[string][]string) AuthAdapter {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	// Current routing artifact
+	currentArtifact *AvengersArtifact
+	lastArtifactLoad time.Time
+	artifactMu      sync.RWMutex
+	artifactSources *artifactSources
 	
-	for _, adapter := range r.adapters {
-		if adapter.Matches(headers) {
-			return adapter
-		}
-	}
-	return nil
-}
-
-// OpenAIKeyAdapter handles OpenAI API key authentication
-type OpenAIKeyAdapter struct{}
-
-func (a *OpenAIKeyAdapter) GetID() string { return "openai-key" }
-
-func (a *OpenAIKeyAdapter) Matches(headers map[string][]string) bool {
-	auth := getHeaderValue(headers, "Authorization")
-	return strings.HasPrefix(auth, "Bearer sk-")
-}
-
-func (a *OpenAIKeyAdapter) Extract(headers map[string][]string) *AuthInfo {
-	auth := getHeaderValue(headers, "Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		return nil
-	}
-	return &AuthInfo{
-		Provider: "openai",
-		Type:     "bearer",
-		Token:    strings.TrimPrefix(auth, "Bearer "),
-	}
-}
-
-func (a *OpenAIKeyAdapter) Apply(outgoing *http.Request) *http.Request {
-	return outgoing // No modification needed for API keys
-}
-
-// AnthropicOAuthAdapter handles Anthropic OAuth
-type AnthropicOAuthAdapter struct{}
-
-func (a *AnthropicOAuthAdapter) GetID() string { return "anthropic-oauth" }
-
-func (a *AnthropicOAuthAdapter) Matches(headers map[string][]string) bool {
-	auth := getHeaderValue(headers, "Authorization")
-	return strings.HasPrefix(auth, "Bearer anthropic_")
-}
-
-func (a *AnthropicOAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
-	auth := getHeaderValue(headers, "Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		return nil
-	}
-	return &AuthInfo{
-		Provider: "anthropic",
-		Type:     "bearer",
-		Token:    strings.TrimPrefix(auth, "Bearer "),
-	}
-}
-
-func (a *AnthropicOAuthAdapter) Apply(outgoing *http.Request) *http.Request {
-	return outgoing
-}
-
-// GeminiOAuthAdapter handles Google Gemini OAuth
-type GeminiOAuthAdapter struct{}
-
-func (a *GeminiOAuthAdapter) GetID() string { return "google-oauth" }
-
-func (a *GeminiOAuthAdapter) Matches(headers map[string][]string) bool {
-	auth := getHeaderValue(headers, "Authorization")
-	return strings.HasPrefix(auth, "Bearer ya29.")
-}
-
-func (a *GeminiOAuthAdapter) Extract(headers map[string][]string) *AuthInfo {
-	auth := getHeaderValue(headers, "Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		return nil
-	}
-	return &AuthInfo{
-		Provider: "google",
-		Type:     "bearer",
-		Token:    strings.TrimPrefix(auth, "Bearer "),
-	}
-}
-
-func (a *GeminiOAuthAdapter) Apply(outgoing *http.Request) *http.Request {
-	return outgoing
-}
+	// Cache for routing decisions
+	cache       DecisionCache
+	cacheCipher *EntryCipher // nil when cache encryption is disabled
 
-// FeatureExtractor implements native feature extraction (port of features.ts)
-type FeatureExtractor struct {
-	embeddingCache sync.Map // string -> []float64
-	mu             sync.RWMutex
-}
+	// Cache of provider responses, nil when disabled
+	responses *ResponseCache
+	
+	// HTTP client for artifact fetching
+	httpClient *http.Client
 
-func NewFeatureExtractor() *FeatureExtractor {
-	return &FeatureExtractor{}
+	// Injected dependencies (see options.go)
+	logger *log.Logger
+	now    func() time.Time
+	
+	// Metrics and monitoring
+	requestCount     int64
+	errorCount       int64
+	cacheHitCount    int64
+	emptyPromptCount int64
+	fallbackCounts   map[FallbackReason]int64
+	metricsMu        sync.RWMutex
 }
 
-func (fe *FeatureExtractor) Extract(req *RouterRequest, artifact *AvengersArtifact, timeoutMs int) (*RequestFeatures, error) {
-	startTime := time.Now()
-	
-	// Extract prompt text from messages
-	promptText := fe.extractPromptText(req)
-	
-	// Get embedding (with caching)
-	embedding := fe.getEmbedding(promptText)
-	
-	// Find nearest clusters (simplified - in production would use FAISS)
-	nearestClusters := fe.findNearestClusters(embedding, 5)
-	
-	// Extract lexical features
-	lexFeatures := fe.extractLexicalFeatures(promptText)
-	
-	// Context analysis
-	tokenCount := fe.estimateTokens(promptText)
-	contextRatio := fe.calculateContextRatio(tokenCount)
-	
-	features := &RequestFeatures{
-		Embedding:     embedding,
-		ClusterID:     fe.getTopCluster(nearestClusters),
-		TopPDistances: fe.getTopDistances(nearestClusters),
-		TokenCount:    tokenCount,
-		HasCode:       lexFeatures.hasCode,
-		HasMath:       lexFeatures.hasMath,
-		NgramEntropy:  lexFeatures.ngramEntropy,
-		ContextRatio:  contextRatio,
+// New creates a new native Heimdall plugin instance
+func New(cfg interface{}) (*Plugin, error) {
+	// Parse configuration
+	configData, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
 	
-	elapsed := time.Since(startTime)
-	if elapsed.Milliseconds() > int64(timeoutMs) {
-		log.Printf("Feature extraction took %dms (budget: %dms)", elapsed.Milliseconds(), timeoutMs)
+	var config Config
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 	
-	return features, nil
+	return NewWithOptions(config)
 }
 
-type lexicalFeatures struct {
-	hasCode      bool
-	hasMath      bool
-	ngramEntropy float64
-}
-
-func (fe *FeatureExtractor) extractPromptText(req *RouterRequest) string {
-	if req.Body == nil {
-		return ""
+// NewWithOptions creates a plugin from a typed config, with dependencies
+// injected through options
+func NewWithOptions(config Config, opts ...Option) (*Plugin, error) {
+	o := options{
+		logger: log.Default(),
+		now:    time.Now,
 	}
-	
-	var parts []string
-	for _, msg := range req.Body.Messages {
-		parts = append(parts, msg.Content)
+	for _, opt := range opts {
+		opt(&o)
 	}
-	return strings.Join(parts, "\n")
-}
 
-func (fe *FeatureExtractor) getEmbedding(text string) []float64 {
-	// Check cache first
-	if cached, ok := fe.embeddingCache.Load(text); ok {
-		return cached.([]float64)
+	// Set defaults
+	if config.Timeout == 0 {
+		config.Timeout = 25 * time.Millisecond // Fast PreHook requirement
 	}
-	
-	// Generate fallback embedding using deterministic hash
-	embedding := fe.generateFallbackEmbedding(text)
-	fe.embeddingCache.Store(text, embedding)
-	return embedding
-}
-
-func (fe *FeatureExtractor) generateFallbackEmbedding(text string) []float64 {
-	// Create deterministic embedding from text hash (similar to TS fallback)
-	hash := sha256.Sum256([]byte(text))
-	embedding := make([]float64, 384) // Standard sentence-transformer dimension
-	
-	for i := 0; i < 384; i++ {
-		byteIndex := i % len(hash)
-		rawValue := float64(hash[byteIndex]) / 255.0
-		embedding[i] = (rawValue - 0.5) * 2 // Normalize to [-1, 1]
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 5 * time.Minute
 	}
-	
-	return embedding
-}
-
-type clusterMatch struct {
-	id       int
-	distance float64
-}
-
-func (fe *FeatureExtractor) findNearestClusters(embedding []float64, k int) []clusterMatch {
-	// Simplified cluster matching - in production would use FAISS index
-	// For now, return mock clusters with deterministic distances
-	var clusters []clusterMatch
-	
-	for i := 0; i < k; i++ {
-		// Generate deterministic distance based on embedding
-		dist := math.Mod(float64(i)+embedding[i%len(embedding)], 1.0)
-		clusters = append(clusters, clusterMatch{id: i, distance: dist})
+	if config.MaxCacheSize == 0 {
+		config.MaxCacheSize = 10000
 	}
-	
-	// Sort by distance
-	sort.Slice(clusters, func(i, j int) bool {
-		return clusters[i].distance < clusters[j].distance
-	})
-	
-	return clusters
-}
-
-func (fe *FeatureExtractor) getTopCluster(clusters []clusterMatch) int {
-	if len(clusters) == 0 {
-		return 0
+	if config.EmbeddingTimeout == 0 {
+		config.EmbeddingTimeout = 15 * time.Second
 	}
-	return clusters[0].id
-}
-
-func (fe *FeatureExtractor) getTopDistances(clusters []clusterMatch) []float64 {
-	var distances []float64
-	for _, cluster := range clusters {
-		distances = append(distances, cluster.distance)
+	if config.FeatureTimeout == 0 {
+		config.FeatureTimeout = 25 * time.Millisecond
 	}
-	return distances
-}
-
-func (fe *FeatureExtractor) extractLexicalFeatures(text string) lexicalFeatures {
-	// Code detection patterns (port of TypeScript regexes)
-	codePatterns := []*regexp.Regexp{
-		regexp.MustCompile("```[\\s\\S]*?```"),          // Code blocks
-		regexp.MustCompile("`[^`]+`"),                   // Inline code
-		regexp.MustCompile("function\\s+\\w+\\s*\\("),     // Function definitions
-		regexp.MustCompile("class\\s+\\w+"),               // Class definitions
-		regexp.MustCompile("\\bimport\\s+.*?from"),        // Import statements
-		regexp.MustCompile("\\bdef\\s+\\w+\\s*\\("),        // Python functions
-		regexp.MustCompile("\\bconst\\s+\\w+\\s*="),        // JS const declarations
-		regexp.MustCompile("\\blet\\s+\\w+\\s*="),          // JS let declarations
+	if config.Startup.Timeout == 0 {
+		config.Startup.Timeout = defaultStartupTimeout
 	}
 	
-	hasCode := false
-	for _, pattern := range codePatterns {
-		if pattern.MatchString(text) {
-			hasCode = true
-			break
-		}
+	// Validate configuration
+	if config.Tuning.ArtifactURL == "" && len(config.Tuning.ArtifactURLs) == 0 {
+		return nil, fmt.Errorf("tuning.artifact_url is required")
 	}
-	
-	// Math detection patterns
-	mathPatterns := []*regexp.Regexp{
-		regexp.MustCompile("\\$[^$]+\\$"),                 // LaTeX math
-		regexp.MustCompile("\\\\\\([^)]+\\\\\\)"),             // LaTeX inline math
-		regexp.MustCompile("\\\\\\[[^\\]]+\\\\\\]"),         // LaTeX display math
-		regexp.MustCompile("[∫∑∏√∞≤≥≠±×÷]"),              // Math symbols
-		regexp.MustCompile("\\b\\d+\\.\\d*[eE][+-]?\\d+"), // Scientific notation
-		regexp.MustCompile("(?i)matrix|vector|derivative|integral"), // Math terms
+	if err := config.Anonymous.validate(); err != nil {
+		return nil, fmt.Errorf("invalid anonymous policy: %w", err)
 	}
-	
-	hasMath := false
-	for _, pattern := range mathPatterns {
-		if pattern.MatchString(text) {
-			hasMath = true
-			break
+	if err := config.Startup.validate(); err != nil {
+		return nil, fmt.Errorf("invalid startup config: %w", err)
+	}
+	if config.PromptCache.Enabled {
+		if err := config.PromptCache.validate(); err != nil {
+			return nil, fmt.Errorf("invalid prompt cache config: %w", err)
 		}
 	}
-	
-	// N-gram entropy calculation (simplified)
-	ngramEntropy := fe.calculateNgramEntropy(text, 3)
-	
-	return lexicalFeatures{
-		hasCode:      hasCode,
-		hasMath:      hasMath,
-		ngramEntropy: ngramEntropy,
+	if err := config.EmptyPrompt.validate(); err != nil {
+		return nil, fmt.Errorf("invalid empty prompt config: %w", err)
 	}
-}
-
-func (fe *FeatureExtractor) calculateNgramEntropy(text string, n int) float64 {
-	ngrams := make(map[string]int)
-	cleanText := strings.ToLower(regexp.MustCompile("[^a-z\\s]").ReplaceAllString(text, ""))
-	
-	// Generate n-grams
-	total := 0
-	for i := 0; i <= len(cleanText)-n; i++ {
-		ngram := cleanText[i : i+n]
-		ngrams[ngram]++
-		total++
+	sampler, err := NewObservabilitySampler(config.Observability, config.EnableObservability)
+	if err != nil {
+		return nil, fmt.Errorf("invalid observability config: %w", err)
 	}
-	
-	if total == 0 {
-		return 0
+	shaper, err := NewRequestShaper(config.RequestShaping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request shaping config: %w", err)
 	}
-	
-	// Calculate entropy
-	entropy := 0.0
-	for _, count := range ngrams {
-		p := float64(count) / float64(total)
-		entropy -= p * math.Log2(p)
+	normalizer, err := NewFallbackNormalizer(config.FallbackNormalization)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fallback normalization config: %w", err)
 	}
-	
-	return entropy
-}
-
-func (fe *FeatureExtractor) estimateTokens(text string) int {
-	// Rough token estimation: ~4 characters per token
-	return int(math.Ceil(float64(len(text)) / 4.0))
-}
-
-func (fe *FeatureExtractor) calculateContextRatio(tokenCount int) float64 {
-	maxContext := 128000.0 // Default context window
-	return math.Min(float64(tokenCount)/maxContext, 1.0)
-}
 
-// GBDTRuntime implements GBDT prediction (port of gbdt_runtime.ts)
-type GBDTRuntime struct {
-	mu sync.RWMutex
-}
+	var sessions *SessionTracker
+	if config.Sessions.Enabled {
+		var err error
+		sessions, err = NewSessionTracker(config.Sessions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session config: %w", err)
+		}
+	}
 
-func NewGBDTRuntime() *GBDTRuntime {
-	return &GBDTRuntime{}
-}
+	var cacheCipher *EntryCipher
+	if config.CacheEncryption.Enabled {
+		var err error
+		cacheCipher, err = NewEntryCipher(config.CacheEncryption)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache encryption config: %w", err)
+		}
+	}
 
-func (gbdt *GBDTRuntime) Predict(features *RequestFeatures, artifact *AvengersArtifact) (*BucketProbabilities, error) {
-	gbdt.mu.RLock()
-	defer gbdt.mu.RUnlock()
-	
-	// Simplified GBDT prediction - in production would load actual model
-	// For now, use heuristics based on features
-	
-	cheapProb := 0.33
-	midProb := 0.33
-	hardProb := 0.34
-	
-	// Adjust probabilities based on features
-	if features.HasCode {
-		// Code tasks tend to be mid-tier
-		midProb += 0.2
-		cheapProb -= 0.1
-		hardProb -= 0.1
+	var responses *ResponseCache
+	if config.ResponseCache.Enabled {
+		var err error
+		responses, err = NewResponseCache(config.ResponseCache, cacheCipher, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response cache config: %w", err)
+		}
 	}
 	
-	if features.HasMath {
-		// Math tasks tend to be hard
-		hardProb += 0.2
-		cheapProb -= 0.1
-		midProb -= 0.1
+	var sidecarClient *sidecar.Client
+	initialized := false
+	if config.Sidecar.Enabled() {
+		var err error
+		sidecarClient, err = sidecar.NewClient(config.Sidecar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sidecar config: %w", err)
+		}
+		defer func() {
+			if !initialized {
+				sidecarClient.Close()
+			}
+		}()
 	}
 	
-	if features.TokenCount > 50000 {
-		// Long context tasks tend to be hard
-		hardProb += 0.15
-		cheapProb -= 0.075
-		midProb -= 0.075
-	} else if features.TokenCount < 1000 {
-		// Short tasks can be cheap
-		cheapProb += 0.15
-		midProb -= 0.075
-		hardProb -= 0.075
+	// Initialize core components; injected stages take precedence over the
+	// sidecar, which falls back to the built-in implementations
+	authRegistry := auth.NewAuthAdapterRegistry()
+	var prefixIndex *features.PrefixIndex
+	var featureExtractor router.FeatureExtractor = o.featureExtractor
+	if featureExtractor == nil {
+		builtin := features.NewFeatureExtractor()
+		if config.PromptCache.Enabled {
+			prefixIndex = features.NewPrefixIndex(config.PromptCache.TTL, config.PromptCache.MaxPrefixes, config.PromptCache.MinTokens, o.now)
+			builtin.SetPrefixHashing(true)
+		}
+		if o.embedder != nil {
+			builtin.SetEmbeddingProvider(o.embedder)
+		} else if config.Sidecar.Embed {
+			builtin.SetEmbeddingProvider(sidecarClient)
+		}
+		if config.Sidecar.Cluster {
+			builtin.SetClusterAssigner(sidecarClient)
+		}
+		featureExtractor = builtin
 	}
-	
-	// Normalize probabilities
-	total := cheapProb + midProb + hardProb
-	cheapProb /= total
-	midProb /= total
-	hardProb /= total
-	
-	return &BucketProbabilities{
-		Cheap: cheapProb,
-		Mid:   midProb,
-		Hard:  hardProb,
-	}, nil
-}
-
-// AlphaScorer implements α-score model selection with advanced features
-// Includes caching, batch optimization, and historical performance tracking
-type AlphaScorer struct {
-	mu                sync.RWMutex
-	scoreCache        sync.Map // string -> *ModelScore
-	performanceHist   sync.Map // string -> *PerformanceHistory
-	cacheTTL          time.Duration
-	lastCacheClean    time.Time
-}
-
-// PerformanceHistory tracks model performance over time for alpha tuning
-type PerformanceHistory struct {
-	ModelName        string    `json:"model_name"`
-	SuccessRate      float64   `json:"success_rate"`
-	AvgLatency       float64   `json:"avg_latency"`
-	TotalRequests    int64     `json:"total_requests"`
-	LastUpdated      time.Time `json:"last_updated"`
-	AlphaOptimal     float64   `json:"alpha_optimal"` // Learned optimal alpha
-}
-
-// ScoreCacheEntry represents a cached score with expiration
-type ScoreCacheEntry struct {
-	Score     *ModelScore
-	ExpiresAt time.Time
-}
-
-func NewAlphaScorer() *AlphaScorer {
-	return &AlphaScorer{
-		cacheTTL:       5 * time.Minute,
-		lastCacheClean: time.Now(),
+	var triageModel router.TriageModel = o.triageModel
+	if triageModel == nil {
+		triageModel = scoring.NewGBDTRuntime()
+		if config.Sidecar.Triage {
+			triageModel = router.TriageWithFallback(sidecarClient, triageModel)
+		}
 	}
-}
-
-// NewAlphaScorerWithCache creates scorer with custom cache settings
-func NewAlphaScorerWithCache(cacheTTL time.Duration) *AlphaScorer {
-	return &AlphaScorer{
-		cacheTTL:       cacheTTL,
-		lastCacheClean: time.Now(),
+	alphaScorer := scoring.NewAlphaScorer()
+	var wasmScorer *scoring.WASMScorer
+	var scorer router.Scorer = o.scorer
+	if scorer == nil {
+		scorer = alphaScorer
+		if config.WASMScoring.Enabled() {
+			var err error
+			wasmScorer, err = scoring.NewWASMScorer(config.WASMScoring, alphaScorer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid wasm scoring config: %w", err)
+			}
+			defer func() {
+				if !initialized {
+					wasmScorer.Close()
+				}
+			}()
+			scorer = router.ScorerWithFallback(wasmScorer, alphaScorer)
+		}
 	}
-}
-
-func (as *AlphaScorer) SelectBest(candidates []string, features *RequestFeatures, artifact *AvengersArtifact) (string, error) {
-	if len(candidates) == 0 {
-		return "", fmt.Errorf("no candidates provided")
+	// Deterministic decisions score without the live penalties and quality
+	// estimates the built-in scorer is given; other scorers are used as is
+	deterministicScorer := scoring.NewAlphaScorer()
+	var strictScorer router.Scorer = deterministicScorer
+	if scorer != router.Scorer(alphaScorer) {
+		strictScorer = scorer
 	}
 	
-	// Clean expired cache entries periodically
-	if time.Since(as.lastCacheClean) > 10*time.Minute {
-		as.cleanExpiredCache()
+	// Setup auth adapters based on configuration
+	if contains(config.AuthAdapters.Enabled, "openai-key") {
+		authRegistry.Register(&auth.OpenAIKeyAdapter{})
 	}
-	
-	scores, err := as.scoreModelsBatched(candidates, features, artifact)
-	if err != nil {
-		return "", err
+	if contains(config.AuthAdapters.Enabled, "anthropic-oauth") {
+		anthropicAdapter, err := auth.NewAnthropicOAuthAdapter(config.AuthAdapters.AnthropicOAuth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid anthropic-oauth adapter config: %w", err)
+		}
+		authRegistry.Register(anthropicAdapter)
 	}
-	
-	if len(scores) == 0 {
-		return candidates[0], nil // Fallback to first candidate
+	if contains(config.AuthAdapters.Enabled, "google-oauth") {
+		authRegistry.Register(&auth.GeminiOAuthAdapter{})
 	}
-	
-	// Sort by α-score (descending) with tie-breaking
-	sort.Slice(scores, func(i, j int) bool {
-		if math.Abs(scores[i].AlphaScore-scores[j].AlphaScore) < 0.001 {
-			// Tie-breaking: prefer lower cost for equal quality
-			return scores[i].CostScore < scores[j].CostScore
+	if contains(config.AuthAdapters.Enabled, "jwt") {
+		jwtAdapter, err := auth.NewJWTAuthAdapter(config.AuthAdapters.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt adapter config: %w", err)
 		}
-		return scores[i].AlphaScore > scores[j].AlphaScore
-	})
-	
-	best := scores[0]
-	
-	// Update performance history (async)
-	go as.updatePerformanceHistory(best.Model, features)
-	
-	log.Printf("Selected model: %s (α-score: %.3f, quality: %.3f, cost: %.3f, penalty: %.3f)", 
-		best.Model, best.AlphaScore, best.QualityScore, best.CostScore, best.PenaltyScore)
-	
-	return best.Model, nil
-}
-
-// SelectBestWithExplanation returns the best model with detailed scoring breakdown
-func (as *AlphaScorer) SelectBestWithExplanation(candidates []string, features *RequestFeatures, artifact *AvengersArtifact) (string, []ModelScore, error) {
-	if len(candidates) == 0 {
-		return "", nil, fmt.Errorf("no candidates provided")
+		authRegistry.Register(jwtAdapter)
 	}
-	
-	scores, err := as.scoreModelsBatched(candidates, features, artifact)
-	if err != nil {
-		return "", nil, err
+	if contains(config.AuthAdapters.Enabled, "hmac") {
+		hmacAdapter, err := auth.NewHMACAuthAdapter(config.AuthAdapters.HMAC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hmac adapter config: %w", err)
+		}
+		authRegistry.Register(hmacAdapter)
 	}
-	
-	if len(scores) == 0 {
-		return candidates[0], nil, nil
+
+	// Self-hosted endpoints: zero-cost models score as free capacity
+	selfHosted := NewSelfHostedRegistry(config.SelfHosted)
+	for _, model := range selfHosted.ZeroCostModels() {
+		alphaScorer.SetCostOverride(model, 0)
+		deterministicScorer.SetCostOverride(model, 0)
 	}
-	
-	// Sort by α-score (descending)
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].AlphaScore > scores[j].AlphaScore
-	})
-	
-	return scores[0].Model, scores, nil
-}
+	alphaScorer.AddPenaltyHook(selfHosted.SaturationPenalty)
 
-// scoreModelsBatched implements optimized batch scoring with caching
-func (as *AlphaScorer) scoreModelsBatched(candidates []string, features *RequestFeatures, artifact *AvengersArtifact) ([]ModelScore, error) {
-	var scores []ModelScore
-	
-	// Pre-allocate slice for efficiency
-	scores = make([]ModelScore, 0, len(candidates))
-	
-	for _, model := range candidates {
-		// Try cache first
-		if cachedScore := as.getCachedScore(model, features, artifact); cachedScore != nil {
-			scores = append(scores, *cachedScore)
-			continue
+	// Online quality estimates are blended with the artifact's Q̂
+	quality := scoring.NewQualityStore(config.Judge.PriorWeight)
+	alphaScorer.SetQualityStore(quality)
+
+	var judge *JudgePipeline
+	if config.Judge.Enabled {
+		httpJudge, err := NewHTTPJudge(config.Judge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid judge config: %w", err)
 		}
-		
-		// Calculate fresh score
-		score := as.scoreModel(model, features, artifact)
-		if score != nil {
-			// Cache the result
-			as.cacheScore(model, features, artifact, score)
-			scores = append(scores, *score)
+		judge, err = NewJudgePipeline(config.Judge, httpJudge, quality)
+		if err != nil {
+			return nil, fmt.Errorf("invalid judge config: %w", err)
 		}
 	}
-	
-	return scores, nil
-}
 
-// scoreModels maintains backward compatibility
-func (as *AlphaScorer) scoreModels(candidates []string, features *RequestFeatures, artifact *AvengersArtifact) ([]ModelScore, error) {
-	return as.scoreModelsBatched(candidates, features, artifact)
-}
-
-func (as *AlphaScorer) scoreModel(model string, features *RequestFeatures, artifact *AvengersArtifact) *ModelScore {
-	// Get quality score for this model and cluster
-	qualityScore := as.getQualityScore(model, features.ClusterID, artifact)
-	if qualityScore == nil {
-		return nil
-	}
-	
-	// Get cost score for this model
-	costScore := as.getCostScore(model, artifact)
-	if costScore == nil {
-		return nil
-	}
-	
-	// Calculate penalties
-	penaltyScore := as.calculatePenalties(model, features, artifact)
-	
-	// Calculate α-score: α * Q̂[m,c] - (1-α) * Ĉ[m] - penalties
-	alpha := artifact.Alpha
-	alphaScore := (alpha * *qualityScore) - ((1 - alpha) * *costScore) - penaltyScore
-	
-	return &ModelScore{
-		Model:        model,
-		QualityScore: *qualityScore,
-		CostScore:    *costScore,
-		PenaltyScore: penaltyScore,
-		AlphaScore:   alphaScore,
+	var labeling *LabelingStore
+	if config.Labeling.Enabled {
+		var err error
+		labeling, err = NewLabelingStore(config.Labeling, quality)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labeling config: %w", err)
+		}
 	}
-}
 
-func (as *AlphaScorer) getQualityScore(model string, clusterID int, artifact *AvengersArtifact) *float64 {
-	modelQuality, ok := artifact.Qhat[model]
-	if !ok || len(modelQuality) == 0 {
-		return nil
-	}
-	
-	// Use cluster-specific quality score, fallback to average
-	if clusterID < len(modelQuality) {
-		score := modelQuality[clusterID]
-		return &score
-	}
-	
-	// Fallback to average quality across all clusters
-	avg := 0.0
-	for _, score := range modelQuality {
-		avg += score
+	var comparer *Comparer
+	if config.Compare.Enabled {
+		client, err := NewHTTPChallengerClient(config.Compare)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compare config: %w", err)
+		}
+		comparer, err = NewComparer(config.Compare, client, quality)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compare config: %w", err)
+		}
 	}
-	avg /= float64(len(modelQuality))
-	return &avg
-}
 
-func (as *AlphaScorer) getCostScore(model string, artifact *AvengersArtifact) *float64 {
-	if cost, ok := artifact.Chat[model]; ok {
-		return &cost
+	var quarantine *QuarantineManager
+	if config.Quarantine.Enabled {
+		var err error
+		quarantine, err = NewQuarantineManager(config.Quarantine)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quarantine config: %w", err)
+		}
 	}
-	return nil
-}
 
-func (as *AlphaScorer) calculatePenalties(model string, features *RequestFeatures, artifact *AvengersArtifact) float64 {
-	penalty := 0.0
-	
-	// Context over-utilization penalty
-	if features.ContextRatio > 0.8 {
-		penalty += artifact.Penalties.CtxOver80Pct
-	}
-	
-	// Latency variance penalty (simplified)
-	expectedLatency := as.estimateLatency(model, features)
-	if features.AvgLatency != nil {
-		latencyVariance := math.Abs(expectedLatency - *features.AvgLatency) / *features.AvgLatency
-		if latencyVariance > 0.2 {
-			penalty += artifact.Penalties.LatencySD * latencyVariance
+	var concurrency *ConcurrencyLimiter
+	if config.Concurrency.Enabled {
+		var err error
+		concurrency, err = NewConcurrencyLimiter(config.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid concurrency config: %w", err)
 		}
 	}
-	
-	// Model-specific penalties
-	penalty += as.getModelSpecificPenalties(model, features)
-	
-	return penalty
-}
 
-func (as *AlphaScorer) estimateLatency(model string, features *RequestFeatures) float64 {
-	// Base latency estimates (in seconds)
-	baseLatencies := map[string]float64{
-		"deepseek/deepseek-r1":     3.0,
-		"qwen/qwen3-coder":         2.5,
-		"openai/gpt-5":             8.0,
-		"google/gemini-2.5-pro":    6.0,
-	}
-	
-	latency := baseLatencies[model]
-	if latency == 0 {
-		latency = 5.0 // Default
-	}
-	
-	// Scale with token count for large contexts
-	if features.TokenCount > 5000 {
-		tokenMultiplier := math.Min(float64(features.TokenCount)/10000, 3.0)
-		latency *= (1 + tokenMultiplier*0.5)
-	}
-	
-	// Reasoning models take longer for complex tasks
-	if (strings.Contains(model, "gpt-5") || strings.Contains(model, "gemini")) &&
-		(features.HasCode || features.HasMath) {
-		latency *= 1.5
+	var admission *AdmissionController
+	if config.Admission.Enabled {
+		var err error
+		admission, err = NewAdmissionController(config.Admission)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admission config: %w", err)
+		}
 	}
-	
-	return latency
-}
 
-func (as *AlphaScorer) getModelSpecificPenalties(model string, features *RequestFeatures) float64 {
-	penalty := 0.0
-	
-	// DeepSeek is good for code, give bonus
-	if features.HasCode && strings.Contains(model, "deepseek") {
-		penalty -= 0.05
-	}
-	
-	// Math tasks benefit from reasoning models
-	if features.HasMath && !strings.Contains(model, "gpt-5") && !strings.Contains(model, "gemini") {
-		penalty += 0.1
-	}
-	
-	// Very long context penalty for models without good long-context support
-	if features.TokenCount > 100000 && !strings.Contains(model, "gemini") {
-		penalty += 0.15
+	var shedder *LoadShedder
+	if config.LoadShed.Enabled {
+		var err error
+		shedder, err = NewLoadShedder(config.LoadShed, o.now, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid load shed config: %w", err)
+		}
 	}
-	
-	return penalty
-}
 
-// Utility functions
-func getHeaderValue(headers map[string][]string, key string) string {
-	if values, ok := headers[key]; ok && len(values) > 0 {
-		return values[0]
-	}
-	// Try lowercase key
-	if values, ok := headers[strings.ToLower(key)]; ok && len(values) > 0 {
-		return values[0]
+	var anomalies *CostAnomalyDetector
+	if config.CostAnomaly.Enabled {
+		var err error
+		anomalies, err = NewCostAnomalyDetector(config.CostAnomaly)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost anomaly config: %w", err)
+		}
 	}
-	return ""
-}
 
-// Plugin implements the schemas.Plugin interface for native Heimdall routing
-type Plugin struct {
-	name   string
-	config Config
-	
-	// Core routing components (native Go implementations)
-	authRegistry     *AuthAdapterRegistry
-	featureExtractor *FeatureExtractor
-	gbdtRuntime      *GBDTRuntime
-	alphaScorer      *AlphaScorer
-	
-	// Current routing artifact
-	currentArtifact *AvengersArtifact
-	lastArtifactLoad time.Time
-	artifactMu      sync.RWMutex
-	
-	// Cache for routing decisions
-	cache   map[string]CacheEntry
-	cacheMu sync.RWMutex
-	
-	// HTTP client for artifact fetching
-	httpClient *http.Client
-	
-	// Metrics and monitoring
-	requestCount   int64
-	errorCount     int64
-	cacheHitCount  int64
-	metricsMu      sync.RWMutex
-}
+	var loops *LoopDetector
+	if config.LoopDetection.Enabled {
+		var err error
+		loops, err = NewLoopDetector(config.LoopDetection, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loop detection config: %w", err)
+		}
+	}
 
-// New creates a new native Heimdall plugin instance
-func New(cfg interface{}) (*Plugin, error) {
-	// Parse configuration
-	configData, err := json.Marshal(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	var smoother *BucketSmoother
+	if config.BucketSmoothing.Enabled {
+		var err error
+		smoother, err = NewBucketSmoother(config.BucketSmoothing, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket smoothing config: %w", err)
+		}
 	}
-	
-	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+
+	var ladder *LadderSelector
+	if config.SizeLadder.Enabled {
+		var err error
+		ladder, err = NewLadderSelector(config.SizeLadder)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size ladder config: %w", err)
+		}
 	}
-	
-	// Set defaults
-	if config.Timeout == 0 {
-		config.Timeout = 25 * time.Millisecond // Fast PreHook requirement
+
+	var alphaController *AlphaController
+	if config.AlphaController.Enabled {
+		var err error
+		alphaController, err = NewAlphaController(config.AlphaController, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alpha controller config: %w", err)
+		}
 	}
-	if config.CacheTTL == 0 {
-		config.CacheTTL = 5 * time.Minute
+
+	var audit *AuditLog
+	if config.Audit.Enabled {
+		var err error
+		audit, err = NewAuditLog(config.Audit, o.now, o.auditSink)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit config: %w", err)
+		}
 	}
-	if config.MaxCacheSize == 0 {
-		config.MaxCacheSize = 10000
+
+	profiles, err := parseRouterProfiles(config.RouterProfiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid router profiles: %w", err)
 	}
-	if config.EmbeddingTimeout == 0 {
-		config.EmbeddingTimeout = 15 * time.Second
+
+	var snapshots *SnapshotStore
+	if config.Snapshots.Enabled {
+		var err error
+		snapshots, err = NewSnapshotStore(config.Snapshots)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshots config: %w", err)
+		}
 	}
-	if config.FeatureTimeout == 0 {
-		config.FeatureTimeout = 25 * time.Millisecond
+
+	var digester *Digester
+	if config.Digest.Enabled {
+		var err error
+		digester, err = NewDigester(config.Digest, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest config: %w", err)
+		}
 	}
-	
-	// Validate configuration
-	if config.Tuning.ArtifactURL == "" {
-		return nil, fmt.Errorf("tuning.artifact_url is required")
+
+	var aggregates *FeatureAggregator
+	switch config.FeatureLogging.Mode {
+	case "", FeatureLogRaw, FeatureLogCoarse:
+	case FeatureLogAggregate:
+		var err error
+		aggregates, err = NewFeatureAggregator(config.FeatureLogging, o.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature logging config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid feature logging config: unknown mode %q", config.FeatureLogging.Mode)
 	}
-	
-	// Initialize core components
-	authRegistry := NewAuthAdapterRegistry()
-	featureExtractor := NewFeatureExtractor()
-	gbdtRuntime := NewGBDTRuntime()
-	alphaScorer := NewAlphaScorer()
-	
-	// Setup auth adapters based on configuration
-	if contains(config.AuthAdapters.Enabled, "openai-key") {
-		authRegistry.Register(&OpenAIKeyAdapter{})
+
+	var synthetic *SyntheticDetector
+	if config.Synthetic.Enabled {
+		var err error
+		synthetic, err = NewSyntheticDetector(config.Synthetic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid synthetic traffic config: %w", err)
+		}
 	}
-	if contains(config.AuthAdapters.Enabled, "anthropic-oauth") {
-		authRegistry.Register(&AnthropicOAuthAdapter{})
+
+	var adminAuth *adminAuthenticator
+	if config.AdminAuth.Enabled {
+		var err error
+		adminAuth, err = newAdminAuthenticator(config.AdminAuth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin auth config: %w", err)
+		}
 	}
-	if contains(config.AuthAdapters.Enabled, "google-oauth") {
-		authRegistry.Register(&GeminiOAuthAdapter{})
+
+	var dualRun *scoring.DualRunner
+	if config.DualRun.Enabled {
+		var err error
+		dualRun, err = scoring.NewDualRunner(config.DualRun, alphaScorer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dual-run config: %w", err)
+		}
 	}
-	
+
 	plugin := &Plugin{
 		name:             "heimdall",
 		config:           config,
 		authRegistry:     authRegistry,
+		router: router.New(router.Config{
+			Thresholds:     config.Router.Thresholds,
+			FeatureTimeout: config.FeatureTimeout,
+			TopP:           config.Router.TopP,
+			MaxCandidates:  config.Router.MaxCandidates,
+			MaxScoringTime: config.Router.MaxScoringTime,
+		}, featureExtractor, triageModel, scorer),
+		strictRouter: router.New(router.Config{
+			Thresholds:     config.Router.Thresholds,
+			FeatureTimeout: config.FeatureTimeout,
+			TopP:           config.Router.TopP,
+			MaxCandidates:  config.Router.MaxCandidates,
+		}, featureExtractor, triageModel, strictScorer),
 		featureExtractor: featureExtractor,
-		gbdtRuntime:      gbdtRuntime,
 		alphaScorer:      alphaScorer,
-		httpClient: &http.Client{
+		selfHosted:       selfHosted,
+		drains:           NewDrainManager(config.Drain),
+		fallbacks:        NewIssuedFallbacks(time.Now),
+		latency:          NewLatencyTracker(),
+		sessions:         sessions,
+		responseScorer:   NewResponseScorer(),
+		cascade:          newCascadeTracker(),
+		quality:          quality,
+		judge:            judge,
+		labeling:         labeling,
+		comparer:         comparer,
+		calibration:      NewCalibrationTracker(0),
+		streams:          NewStreamTracker(config.StreamAccounting),
+		sampler:          sampler,
+		shaper:           shaper,
+		normalizer:       normalizer,
+		quarantine:       quarantine,
+		concurrency:      concurrency,
+		admission:        admission,
+		shedder:          shedder,
+		anomalies:        anomalies,
+		loops:            loops,
+		smoother:         smoother,
+		ladder:           ladder,
+		alphaController:  alphaController,
+		audit:            audit,
+		snapshots:        snapshots,
+		digester:         digester,
+		profiles:         profiles,
+		aggregates:       aggregates,
+		synthetic:        synthetic,
+		adminAuth:        adminAuth,
+		dualRun:          dualRun,
+		sidecar:          sidecarClient,
+		wasmScorer:       wasmScorer,
+		implementations:  resolveImplementations(config, o),
+		prefixIndex:      prefixIndex,
+		artifactSources:  newArtifactSources(append([]string{config.Tuning.ArtifactURL}, config.Tuning.ArtifactURLs...), o.now),
+		httpClient:  o.httpClient,
+		cache:       o.cache,
+		cacheCipher: cacheCipher,
+		responses:   responses,
+		logger:      o.logger,
+		now:         o.now,
+	}
+	if plugin.httpClient == nil {
+		plugin.httpClient = &http.Client{
 			Timeout: config.Timeout,
-		},
-		cache: make(map[string]CacheEntry),
+		}
 	}
-	
-	log.Printf("Initialized native Heimdall plugin with %d auth adapters", len(config.AuthAdapters.Enabled))
+	if plugin.cache == nil {
+		plugin.cache = newMemoryDecisionCache()
+	}
+	if config.PromptCache.Enabled {
+		alphaScorer.SetCostDiscount(plugin.promptCacheDiscount)
+		deterministicScorer.SetCostDiscount(plugin.promptCacheDiscount)
+	}
+	if config.TokenCalibration.Enabled {
+		tokens, err := NewTokenCalibrator(config.TokenCalibration, plugin.inferProviderKind)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token calibration config: %w", err)
+		}
+		plugin.tokens = tokens
+		alphaScorer.SetCostScale(func(model string, _ *RequestFeatures) float64 {
+			return tokens.Factor(model, true)
+		})
+		deterministicScorer.SetCostScale(func(model string, _ *RequestFeatures) float64 {
+			return tokens.Factor(model, false)
+		})
+		plugin.router.SetTokenFactor(plugin.learnedBucketTokenFactor)
+		plugin.strictRouter.SetTokenFactor(func(bucket Bucket) float64 {
+			return plugin.bucketTokenFactor(bucket, false)
+		})
+	}
+	if snapshots != nil && config.Snapshots.OnAlert {
+		if quarantine != nil {
+			quarantine.onEvent = func(event QuarantineEvent) {
+				if event.Event == "quarantined" {
+					plugin.snapshotOnAlert("quarantined " + event.Model)
+				}
+			}
+		}
+		if anomalies != nil {
+			anomalies.onEvent = func(event CostAnomalyEvent) {
+				if event.Event == "cost_anomaly" {
+					plugin.snapshotOnAlert("cost anomaly for tenant " + event.Tenant + " on " + event.Model)
+				}
+			}
+		}
+	}
+	decide := chainMiddleware(plugin.decideCached, o.middleware)
+	// Decisions are finished again once middleware, which may edit them, ran
+	plugin.decideChain = func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+		response, err := decide(ctx, req, headers)
+		if err == nil && response != nil {
+			plugin.finishDecision(&response.Decision)
+		}
+		return response, err
+	}
+
+	switch config.Startup.Policy {
+	case StartupPolicyBlock:
+		if err := plugin.awaitArtifact(config.Startup.Timeout); err != nil {
+			return nil, fmt.Errorf("startup: %w", err)
+		}
+	case StartupPolicyDefault:
+		plugin.installDefaultArtifact(ErrArtifactUnavailable)
+	}
+
+	selfHosted.Start()
+	if judge != nil {
+		judge.Start()
+	}
+	if comparer != nil {
+		comparer.Start()
+	}
+	if alphaController != nil {
+		alphaController.Start(plugin.auditAlpha)
+	}
+	if digester != nil {
+		digester.Start(plugin.digestAnomalies)
+	}
+
+	initialized = true
+	plugin.logger.Printf("Initialized native Heimdall plugin with %d auth adapters", len(config.AuthAdapters.Enabled))
+	plugin.logger.Printf("Active implementations: %s", plugin.implementations)
 	return plugin, nil
 }
 
@@ -1024,6 +1036,14 @@ func (p *Plugin) GetName() string {
 func (p *Plugin) PreHook(ctx *context.Context, req *schemas.BifrostRequest) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
 	startTime := time.Now()
 	
+	// Bifrost re-runs plugins for each fallback; honor the fallbacks we chose,
+	// rewritten for their provider
+	if p.fallbacks.Issued(req) {
+		*ctx = context.WithValue(*ctx, "heimdall_fallback_attempt", true)
+		p.normalizeFallback(req)
+		return req, nil, nil
+	}
+	
 	// Increment request counter
 	p.metricsMu.Lock()
 	p.requestCount++
@@ -1035,26 +1055,88 @@ func (p *Plugin) PreHook(ctx *context.Context, req *schemas.BifrostRequest) (*sc
 		return p.handleError(ctx, req, fmt.Errorf("failed to convert request: %w", err))
 	}
 	
-	// Check cache if enabled (using deterministic key)
-	if p.config.EnableCaching {
-		if cached := p.getCachedResponse(routerReq); cached != nil {
-			p.metricsMu.Lock()
-			p.cacheHitCount++
-			p.metricsMu.Unlock()
-			
-			return p.applyCachedDecision(ctx, req, cached)
+	// Health checks and canaries are tagged so they do not train the router
+	var synthetic string
+	var probe bool
+	if p.synthetic != nil {
+		if synthetic, probe = p.synthetic.Detect(routerReq); synthetic != "" {
+			*ctx = context.WithValue(*ctx, "heimdall_synthetic", synthetic)
+		}
+	}
+	
+	// Callers stuck resending the same prompt are held off before any work;
+	// probes repeat by design, but only those with the secret are trusted
+	if p.loops != nil && !probe {
+		if retryAfter := p.loops.Check(p.loops.Key(routerReq)); retryAfter > 0 {
+			return p.rejectLoop(ctx, req, retryAfter)
+		}
+	}
+	
+	// Identical prompts may be answered without calling a provider, but
+	// only to authenticated callers; the decision below reuses the result
+	decideCtx := *ctx
+	if p.responses != nil {
+		authInfo, err := p.authenticate(routerReq, headers)
+		if err != nil {
+			var authErr *AuthenticationError
+			if errors.As(err, &authErr) {
+				return p.reject(ctx, req, authErr.rejection())
+			}
+			return p.handleError(ctx, req, fmt.Errorf("authentication failed: %w", err))
 		}
+		decideCtx = context.WithValue(decideCtx, authenticatedContextKey{}, &authenticated{authInfo: authInfo})
+
+		key := p.responses.Key(req, headers)
+		if cached, ok := p.responses.Get(key); ok {
+			*ctx = context.WithValue(*ctx, "heimdall_response_cache_hit", true)
+			return req, &schemas.PluginShortCircuit{Response: cached}, nil
+		}
+		*ctx = context.WithValue(*ctx, "heimdall_response_cache_key", key)
+		if p.responses.Semantic() {
+			*ctx = context.WithValue(*ctx, "heimdall_response_cache_scope", p.responses.Scope(req, headers))
+		}
+	}
+	
+	// Decisions are cached unless deterministic, as cached decisions carry
+	// no replay inputs, or signed, as signed requests are verified every
+	// time (see decideCached)
+	if p.config.EnableCaching && !p.deterministic(headers) && auth.HeaderValue(headers, auth.HMACSignatureHeader) == "" {
+		decideCtx = context.WithValue(decideCtx, decisionCacheContextKey{}, true)
 	}
 	
-	// Make native routing decision (port of RouterPreHook.decide())
-	response, err := p.decide(routerReq, headers)
+	// Make native routing decision (port of RouterPreHook.decide()),
+	// through any injected middleware
+	response, err := p.decideChain(decideCtx, routerReq, headers)
 	if err != nil {
+		var authErr *AuthenticationError
+		if errors.As(err, &authErr) {
+			return p.reject(ctx, req, authErr.rejection())
+		}
+		var rejection *RejectionError
+		if errors.As(err, &rejection) {
+			return p.reject(ctx, req, rejection)
+		}
+		if errors.Is(err, ErrArtifactUnavailable) && p.config.Startup.Policy == StartupPolicyPassThrough {
+			return p.passThrough(ctx, req, err)
+		}
 		return p.handleError(ctx, req, fmt.Errorf("routing decision failed: %w", err))
 	}
+	if response.cacheHit {
+		p.metricsMu.Lock()
+		p.cacheHitCount++
+		p.metricsMu.Unlock()
+		*ctx = context.WithValue(*ctx, "heimdall_cache_hit", true)
+	}
 	
-	// Cache the response if enabled
-	if p.config.EnableCaching {
-		p.cacheResponse(routerReq, response)
+	// Template-keyed decisions carry the features, embedding included, of
+	// the first prompt rendered from the template
+	if !response.cacheHit || !p.config.TemplateCacheKeys {
+		if shortCircuit := p.similarResponse(ctx, response); shortCircuit != nil {
+			if response.hardSlot {
+				p.admission.Release()
+			}
+			return req, shortCircuit, nil
+		}
 	}
 	
 	// Apply routing decision to the request
@@ -1062,7 +1144,7 @@ func (p *Plugin) PreHook(ctx *context.Context, req *schemas.BifrostRequest) (*sc
 	
 	elapsed := time.Since(startTime)
 	if elapsed.Microseconds() > 10000 { // 10ms warning threshold
-		log.Printf("PreHook took %dus (>10ms threshold)", elapsed.Microseconds())
+		p.logger.Printf("PreHook took %dus (>10ms threshold)", elapsed.Microseconds())
 	}
 	
 	return result, shortCircuit, err
@@ -1070,42 +1152,204 @@ func (p *Plugin) PreHook(ctx *context.Context, req *schemas.BifrostRequest) (*sc
 
 // PostHook implements 429 fallback and observability
 func (p *Plugin) PostHook(ctx *context.Context, res *schemas.BifrostResponse, err *schemas.BifrostError) (*schemas.BifrostResponse, *schemas.BifrostError, error) {
+	authInfo, _ := (*ctx).Value("heimdall_auth_info").(*AuthInfo)
+	owner := subjectOf(authInfo)
+	synthetic, _ := (*ctx).Value("heimdall_synthetic").(string)
+
+	inFlight, ok := (*ctx).Value("heimdall_inflight").(RouterDecision)
+	// Streams may report usage in a chunk of its own after the finish chunk;
+	// it is accounted without ending the stream again
+	if ok && streamUsage(*ctx, res) {
+		p.accountUsage(*ctx, owner, inFlight.Model, res.Usage)
+		if p.digester != nil && synthetic == "" {
+			p.digester.RecordSpend(inFlight.Model, res.Usage)
+		}
+		return res, err, nil
+	}
+
+	// Streams are accounted once, when they end
+	var outcome StreamOutcome
+	if ok {
+		var ongoing bool
+		outcome, ongoing = p.observeStream(ctx, inFlight.Model, res, err)
+		ok = !ongoing
+	}
+	failed := isProviderFailure(err) || streamFailed(outcome, err)
+	if streamFailed(outcome, err) {
+		p.revalidateSession(*ctx, inFlight.Model, "stream failed")
+	}
+
+	if ok {
+		p.drains.Release(inFlight.Kind, inFlight.Model)
+		if p.concurrency != nil {
+			p.concurrency.Release(inFlight.Model)
+		}
+		if held, _ := (*ctx).Value("heimdall_hard_slot").(bool); held {
+			p.admission.Release()
+		}
+
+		// Successful calls feed the latency estimates behind timeout hints
+		if startTime, ok := (*ctx).Value("heimdall_start_time").(time.Time); ok && err == nil && !failed && synthetic == "" {
+			p.latency.Record(inFlight.Kind, time.Since(startTime))
+		}
+
+		// Synthetic traffic is counted apart from the learning below
+		if synthetic != "" && (res != nil || err != nil) {
+			var latency time.Duration
+			if startTime, ok := (*ctx).Value("heimdall_start_time").(time.Time); ok {
+				latency = time.Since(startTime)
+			}
+			p.synthetic.Record(synthetic, !failed, latency)
+		}
+
+		if res != nil {
+			p.accountUsage(*ctx, owner, inFlight.Model, res.Usage)
+		}
+
+		// Provider outcomes drive quarantine of misbehaving models
+		if p.quarantine != nil && (res != nil || err != nil) {
+			p.quarantine.Record(inFlight.Model, !failed)
+		}
+
+		// Outcomes feed the α controller's service level window
+		if p.alphaController != nil && synthetic == "" && (res != nil || err != nil) {
+			var latency time.Duration
+			if startTime, ok := (*ctx).Value("heimdall_start_time").(time.Time); ok {
+				latency = time.Since(startTime)
+			}
+			var usage *schemas.LLMUsage
+			if res != nil {
+				usage = res.Usage
+			}
+			p.alphaController.Record(inFlight.Model, !failed, latency, usage)
+		}
+
+		// Outcomes and spend feed the periodic digest
+		if p.digester != nil && synthetic == "" && (res != nil || err != nil) {
+			var usage *schemas.LLMUsage
+			if res != nil {
+				usage = res.Usage
+			}
+			p.digester.RecordOutcome(inFlight.Model, !failed, usage)
+		}
+
+		// Outcomes, including streams that ended early, feed the scorer's
+		// performance history
+		if synthetic == "" && (res != nil || err != nil) {
+			partial := outcome == StreamPartial || outcome == StreamAborted
+			p.alphaScorer.RecordOutcome(inFlight.Model, !failed && !partial, partial)
+		}
+
+		// Cheap-first attempts escalate via fallbacks when the answer is weak
+		if inFlight.Cascade != nil && err == nil && scorable(res) {
+			features, _ := (*ctx).Value("heimdall_features").(RequestFeatures)
+			res, err = p.evaluateCascade(inFlight, &features, res)
+
+			// Only cascades that would escalate to hard say whether hard was
+			// needed: an accepted cheap answer shows it was not, an escalation
+			// that it was
+			if probs, ok := (*ctx).Value("heimdall_bucket_probs").(BucketProbabilities); ok && synthetic == "" &&
+				inFlight.Cascade.EscalationBucket == BucketHard {
+				p.calibration.Record(CalibrationOutcome{
+					ClusterID:       features.ClusterID,
+					HardProbability: probs.Hard,
+					NeededHard:      err != nil,
+				})
+			}
+		}
+
+		// Sampled requests are judged asynchronously once complete
+		if prompt, ok := (*ctx).Value("heimdall_judge_prompt").(string); ok && p.judge != nil && synthetic == "" && err == nil && scorable(res) {
+			features, _ := (*ctx).Value("heimdall_features").(RequestFeatures)
+			p.judge.Submit(JudgeSample{
+				Model:     inFlight.Model,
+				ClusterID: features.ClusterID,
+				Prompt:    prompt,
+				Response:  messageText(res.Choices[0].Message),
+			})
+		}
+
+		// Compared requests are kept once the challenger also answers
+		if comparison, ok := (*ctx).Value("heimdall_comparison").(*Comparison); ok && p.comparer != nil {
+			var output string
+			if scorable(res) {
+				output = messageText(res.Choices[0].Message)
+			}
+			p.comparer.Finish(comparison, output, err == nil && scorable(res))
+		}
+
+		// Sampled requests are exported for human labeling
+		if p.labeling != nil && synthetic == "" && err == nil && scorable(res) && p.labeling.ShouldSample() {
+			features, _ := (*ctx).Value("heimdall_features").(RequestFeatures)
+			bucket, _ := (*ctx).Value("heimdall_bucket").(Bucket)
+			p.labeling.Record(owner, inFlight.Model, bucket, features, messageText(res.Choices[0].Message))
+		}
+	}
+
+	// Complete responses answer later identical prompts
+	if key, ok := (*ctx).Value("heimdall_response_cache_key").(string); ok && p.responses != nil && err == nil && scorable(res) {
+		scope, _ := (*ctx).Value("heimdall_response_cache_scope").(string)
+		embedding, _ := (*ctx).Value("heimdall_response_cache_embedding").([]float64)
+		if cacheErr := p.responses.Set(key, scope, owner, embedding, res); cacheErr != nil {
+			p.logger.Printf("Failed to cache response: %v", cacheErr)
+		}
+	}
+
 	// Handle 429 rate limiting with native fallback routing
 	if err != nil && err.StatusCode != nil && *err.StatusCode == 429 && p.config.EnableFallbacks {
 		// Check if this was an Anthropic 429
 		if provider, ok := (*ctx).Value("heimdall_decision").(RouterDecision); ok {
 			if provider.Kind == "anthropic" {
-				log.Printf("Received 429 from Anthropic, fallback logic could be implemented here")
+				p.logger.Printf("Received 429 from Anthropic, fallback logic could be implemented here")
 				// In a full implementation, we could trigger a re-routing with excludeAnthropic=true
 			}
 		}
 	}
 	
-	// Add observability metrics if enabled
-	if p.config.EnableObservability && res != nil {
+	// Add observability metrics for sampled requests
+	if sampled, _ := (*ctx).Value("heimdall_sampled").(Sampled); sampled.Logs && res != nil {
 		// Note: ExtraFields is a struct, not a map. In a full implementation,
 		// we would need to extend the BifrostResponseExtraFields struct or use
 		// the RawResponse field to store additional metrics.
 		// For now, we'll use the existing fields where possible.
 		
 		if bucket, ok := (*ctx).Value("heimdall_bucket").(Bucket); ok {
-			log.Printf("Request routed to bucket: %s", string(bucket))
+			p.logger.Printf("Request routed to bucket: %s", string(bucket))
 		}
 		if features, ok := (*ctx).Value("heimdall_features").(RequestFeatures); ok {
-			log.Printf("Request features - tokens: %d, has_code: %v, has_math: %v", 
+			p.logger.Printf("Request features - tokens: %d, has_code: %v, has_math: %v", 
 				features.TokenCount, features.HasCode, features.HasMath)
 		}
 		if fallbackReason, ok := (*ctx).Value("heimdall_fallback_reason").(string); ok {
-			log.Printf("Fallback reason: %s", fallbackReason)
+			p.logger.Printf("Fallback reason: %s", fallbackReason)
 		}
 		if cacheHit, ok := (*ctx).Value("heimdall_cache_hit").(bool); ok && cacheHit {
-			log.Printf("Cache hit for request")
+			p.logger.Printf("Cache hit for request")
 		}
 	}
 	
 	return res, err, nil
 }
 
+// accountUsage records a response's reported usage against the session's
+// running spend, the tenant's cost anomaly series and the provider's token
+// factor
+func (p *Plugin) accountUsage(ctx context.Context, owner DataSubject, model string, usage *schemas.LLMUsage) {
+	if sessionID, ok := ctx.Value("heimdall_session_id").(string); ok && p.sessions != nil {
+		p.sessions.Record(sessionID, owner, model, usage)
+	}
+
+	if p.anomalies != nil {
+		p.anomalies.Record(owner.Tenant, model, usage)
+	}
+
+	if p.tokens != nil && usage != nil {
+		if features, ok := ctx.Value("heimdall_features").(RequestFeatures); ok {
+			p.tokens.Observe(model, features.TokenCount, usage)
+		}
+	}
+}
+
 // Utility functions for plugin operation
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -1166,155 +1410,432 @@ func main() {
 
 // decide implements the core routing decision logic (port of RouterPreHook.decide())
 func (p *Plugin) decide(req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+	return p.decideAs(req, headers, nil)
+}
+
+// authenticated is a caller already authenticated by authenticate, so the
+// decision does not verify the request again (HMAC verification would see
+// the second check as a replay)
+type authenticated struct {
+	authInfo *AuthInfo
+}
+
+// authenticatedContextKey carries an *authenticated through the decide
+// chain
+type authenticatedContextKey struct{}
+
+// decisionCacheContextKey marks a decide chain call whose decision may be
+// served from and stored in the decision cache
+type decisionCacheContextKey struct{}
+
+// decideCached is the innermost step of the decide chain, so middleware
+// also runs for cached decisions. Calls marked for caching reuse a cached
+// decision while its model is available, and cache fresh ones.
+func (p *Plugin) decideCached(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+	pre, _ := ctx.Value(authenticatedContextKey{}).(*authenticated)
+	if cacheable, _ := ctx.Value(decisionCacheContextKey{}).(bool); !cacheable {
+		return p.decideAs(req, headers, pre)
+	}
+	if cached := p.getCachedResponse(req); cached != nil && !p.anomalyForced(cached) && p.cachedDecisionAvailable(cached) {
+		cached.cacheHit = true
+		cached.Timestamp = p.now()
+		return cached, nil
+	}
+
+	response, err := p.decideAs(req, headers, pre)
+	if err == nil && p.cacheableDecision(response) {
+		p.cacheResponse(req, response)
+	}
+	return response, err
+}
+
+// cacheableDecision reports whether a decision may be reused. Admission
+// outcomes, anomaly forcing and fallbacks (load shedding, the default
+// artifact, budget truncation) are transient, and hard decisions must pass
+// admission control every time.
+func (p *Plugin) cacheableDecision(response *RouterResponse) bool {
+	return response.Admission == "" && !(p.admission != nil && response.Bucket == BucketHard) &&
+		!response.CostAnomaly && response.FallbackReason == ""
+}
+
+// authenticate verifies the request's credentials and identifies the
+// caller; anonymous callers are identified by the anonymous policy
+func (p *Plugin) authenticate(req *RouterRequest, headers map[string][]string) (*AuthInfo, error) {
+	authAdapter := p.authRegistry.FindMatch(headers)
+	if authAdapter == nil {
+		return p.anonymousAuth()
+	}
+	if verifier, ok := authAdapter.(auth.RequestVerifier); ok {
+		if err := verifier.Verify(req); err != nil {
+			return nil, NewAuthenticationError(authAdapter.GetID()+" verification failed", err)
+		}
+	}
+	return authAdapter.Extract(headers), nil
+}
+
+// decideAs decides for a caller, authenticating it unless pre is set
+func (p *Plugin) decideAs(req *RouterRequest, headers map[string][]string, pre *authenticated) (*RouterResponse, error) {
 	// Step 1: Ensure we have current artifacts
 	if err := p.ensureCurrentArtifact(); err != nil {
-		return nil, fmt.Errorf("failed to ensure artifact: %w", err)
+		if p.config.Startup.Policy == StartupPolicyPassThrough {
+			return nil, fmt.Errorf("%w: %v", ErrArtifactUnavailable, err)
+		}
+		// Last resort: degrade to the embedded artifact rather than the
+		// single-model emergency fallback
+		p.installDefaultArtifact(err)
 	}
 	
 	if p.currentArtifact == nil {
-		return nil, fmt.Errorf("no routing artifact available")
+		return nil, ErrArtifactUnavailable
 	}
 	
 	// Step 2: Auth detection
-	authAdapter := p.authRegistry.FindMatch(headers)
 	var authInfo *AuthInfo
-	if authAdapter != nil {
-		authInfo = authAdapter.Extract(headers)
+	if pre != nil {
+		authInfo = pre.authInfo
+	} else {
+		var err error
+		if authInfo, err = p.authenticate(req, headers); err != nil {
+			return nil, err
+		}
 	}
-	
-	// Step 3: Feature extraction (≤25ms budget)
-	features, err := p.featureExtractor.Extract(req, p.currentArtifact, int(p.config.FeatureTimeout.Milliseconds()))
+	p.applyBYOKOptIn(authInfo, headers)
+	authInfo, err := p.applyModelHints(req.Body, authInfo)
 	if err != nil {
-		return nil, fmt.Errorf("feature extraction failed: %w", err)
+		return nil, err
+	}
+	
+	var sessionID string
+	if p.sessions != nil {
+		sessionID = p.sessions.SessionID(headers)
 	}
 	
-	// Step 4: GBDT triage
-	bucketProbs, err := p.gbdtRuntime.Predict(features, p.currentArtifact)
+	// Empty prompts have nothing to classify
+	if emptyPrompt(req.Body) {
+		return p.routeEmptyPrompt(authInfo, sessionID)
+	}
+	
+	// Deterministic decisions record their live inputs for replay
+	var rs *replayState
+	if p.deterministic(headers) {
+		rs = p.newReplayState()
+	}
+	
+	// Steps 3-5: Feature extraction (≤25ms budget), GBDT triage and
+	// bucket selection with guardrails. Under load, heuristics on lexical
+	// features stand in, except for deterministic decisions.
+	start := time.Now()
+	shed := p.shedder != nil && rs == nil && p.shedder.Shedding()
+	var triage *router.Triage
+	if shed {
+		triage, err = p.router.TriageLight(req, p.currentArtifact)
+	} else {
+		triage, err = p.router.Triage(req, p.currentArtifact)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("GBDT prediction failed: %w", err)
+		return nil, err
+	}
+	
+	// Smoothing depends on the user's history, so deterministic decisions
+	// neither use nor feed it
+	if p.smoother != nil && rs == nil {
+		p.smoothTriage(triage, authInfo)
+	}
+	response, err := p.route(triage, authInfo, sessionID, rs)
+	if err != nil || p.shedder == nil {
+		return response, err
+	}
+	if shed {
+		if response.FallbackReason == "" {
+			response.FallbackReason = FallbackLoadShed
+		}
+	} else {
+		p.shedder.Record(time.Since(start))
+	}
+	return response, nil
+}
+
+// route selects the bucket and model for a triaged request. rs is set for
+// deterministic decisions, made or replayed with its live inputs.
+func (p *Plugin) route(triage *router.Triage, authInfo *AuthInfo, sessionID string, rs *replayState) (*RouterResponse, error) {
+	features, bucketProbs := triage.Features, triage.Probabilities
+	
+	// Caller policy, tightened by session spend rules
+	policy := policyFor(authInfo)
+	var sessionPolicy *RoutingPolicy
+	switch {
+	case rs != nil && rs.replaying:
+		sessionPolicy = rs.inputs.SessionPolicy
+	case p.sessions != nil:
+		sessionPolicy = p.sessions.PolicyFor(sessionID)
+		if rs != nil {
+			rs.inputs.SessionPolicy = sessionPolicy
+		}
+	}
+	policy = policy.Restrict(sessionPolicy)
+	
+	// Tenants with runaway spend are held to cheap until acknowledged
+	var costAnomaly bool
+	if rs != nil && rs.replaying {
+		costAnomaly = rs.costAnomaly
+	} else {
+		costAnomaly = p.anomalies != nil && p.anomalies.Forced(tenantOf(authInfo))
+	}
+	if costAnomaly {
+		policy = policy.Restrict(&RoutingPolicy{MaxBucket: BucketCheap})
+	}
+	
+	bucket := policy.CapBucket(triage.Bucket)
+	
+	// Hard capacity is reserved for clearly hard work during surges
+	var admission string
+	var hardSlot bool
+	if rs != nil && rs.replaying {
+		admission = rs.admission
+		if bucket == BucketHard && admission == AdmissionDowngraded {
+			bucket = BucketMid
+		}
+	} else if bucket == BucketHard && p.admission != nil {
+		downgradable := !p.contextExceedsCapacity(features, BucketMid)
+		admission, hardSlot = p.admission.Admit(bucketProbs.Hard-p.config.Router.Thresholds.Hard, downgradable)
+		if admission == AdmissionDowngraded {
+			bucket = BucketMid
+		}
 	}
 	
-	// Step 5: Bucket selection with guardrails
-	bucket := p.selectBucket(bucketProbs, features)
+	// Prompt-cache estimates are kept in the features, so replays reuse them
+	replaying := rs != nil && rs.replaying
+	if !replaying {
+		p.estimatePromptCache(features, tenantOf(authInfo))
+	}
 	
 	// Step 6: In-bucket α-score selection
-	decision, err := p.selectModel(bucket, features, authInfo, false)
+	decision, err := p.selectModelWithPolicy(bucket, features, authInfo, policy, false, rs)
 	if err != nil {
+		if hardSlot {
+			p.admission.Release()
+		}
 		return nil, fmt.Errorf("model selection failed: %w", err)
 	}
+
+	// Sessions stick to their model; deterministic decisions do not
+	if p.sessions != nil && rs == nil {
+		decision = p.stickToSession(decision, p.sessions.Pinned(sessionID), bucket, features, authInfo, policy)
+		p.sessions.Pin(sessionID, subjectOf(authInfo), decision.Model)
+	}
 	
-	return &RouterResponse{
+	// Optional cascade: try a cheap model first, escalating on low quality
+	if p.cascadeEligible(bucket, features) {
+		cheap, err := p.selectModelWithPolicy(BucketCheap, features, authInfo, policy, false, rs)
+		if err != nil {
+			p.logger.Printf("Cascade skipped, no cheap candidate: %v", err)
+		} else {
+			decision = p.cascadeDecision(cheap, decision, bucket)
+		}
+	}
+	p.finishDecision(decision)
+	if !replaying {
+		p.recordPromptPrefixes(decision.Model, features, tenantOf(authInfo))
+	}
+	
+	response := &RouterResponse{
 		Decision:            *decision,
 		Features:            *features,
 		Bucket:              bucket,
 		BucketProbabilities: *bucketProbs,
 		AuthInfo:            authInfo,
-	}, nil
+		SessionID:           sessionID,
+		Admission:           admission,
+		CostAnomaly:         costAnomaly,
+		Timestamp:           p.now(),
+		hardSlot:            hardSlot,
+	}
+	if rs != nil {
+		response.Replay = rs.finish()
+	}
+	switch {
+	case p.currentArtifact.Version == defaultArtifactVersion:
+		response.FallbackReason = FallbackArtifactMissing
+	case decision.BudgetTruncated:
+		response.FallbackReason = FallbackBudgetExceeded
+	}
+	return response, nil
+}
+
+// finishDecision bounds a decision's fallback chain and shapes its requests
+// for their providers
+func (p *Plugin) finishDecision(decision *RouterDecision) {
+	p.boundFallbacks(decision)
+	p.shapeRequests(decision)
 }
 
 // ensureCurrentArtifact ensures we have a current routing artifact
 func (p *Plugin) ensureCurrentArtifact() error {
+	return p.loadArtifact(false)
+}
+
+// loadArtifact loads the artifact when none is loaded or the current one is
+// due for a refresh. Endpoints inside their backoff are skipped, so a refresh
+// while every endpoint is failing waits for the first backoff to expire
+// rather than retrying on each request; force tries them too, for callers
+// that retry on their own schedule or know an endpoint changed.
+func (p *Plugin) loadArtifact(force bool) error {
 	p.artifactMu.Lock()
 	defer p.artifactMu.Unlock()
 	
-	now := time.Now()
+	now := p.now()
 	reloadInterval := p.config.Tuning.ReloadSeconds * time.Second
 	
 	if p.currentArtifact == nil || now.Sub(p.lastArtifactLoad) > reloadInterval {
-		log.Printf("Loading/refreshing routing artifact from %s", p.config.Tuning.ArtifactURL)
-		
-		// Fetch artifact from URL
-		resp, err := p.httpClient.Get(p.config.Tuning.ArtifactURL)
-		if err != nil {
+		urls := p.artifactSources.Order(force)
+		if retryAt := p.artifactSources.RetryAt(); len(urls) == 0 && !retryAt.IsZero() {
 			if p.currentArtifact != nil {
-				// Keep existing artifact on fetch failure
-				log.Printf("Failed to fetch artifact, keeping existing: %v", err)
 				return nil
 			}
-			return fmt.Errorf("failed to fetch artifact: %w", err)
+			return fmt.Errorf("every artifact endpoint is backing off until %s", retryAt.Format(time.RFC3339))
 		}
-		defer resp.Body.Close()
-		
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("artifact fetch failed with status %d", resp.StatusCode)
+
+		// Try each endpoint until one serves a valid artifact
+		var errs []error
+		for _, url := range urls {
+			p.logger.Printf("Loading/refreshing routing artifact from %s", url)
+			
+			artifact, err := p.fetchArtifact(url)
+			if err != nil {
+				p.logger.Printf("Artifact endpoint %s failed: %v", url, err)
+				p.artifactSources.RecordFailure(url, err)
+				errs = append(errs, err)
+				continue
+			}
+			p.artifactSources.RecordSuccess(url)
+			
+			p.auditArtifact(url, p.currentArtifact, artifact)
+			p.currentArtifact = artifact
+			p.lastArtifactLoad = now
+			p.logger.Printf("Loaded artifact version: %s", artifact.Version)
+			return nil
 		}
 		
-		var artifact AvengersArtifact
-		if err := json.NewDecoder(resp.Body).Decode(&artifact); err != nil {
-			return fmt.Errorf("failed to decode artifact: %w", err)
+		err := errors.Join(errs...)
+		if p.currentArtifact != nil {
+			// Keep existing artifact when every endpoint fails
+			p.logger.Printf("Failed to fetch artifact, keeping existing: %v", err)
+			return nil
 		}
-		
-		p.currentArtifact = &artifact
-		p.lastArtifactLoad = now
-		log.Printf("Loaded artifact version: %s", artifact.Version)
+		return err
 	}
 	
 	return nil
 }
 
-// selectBucket implements bucket selection with guardrails (port of RouterPreHook.selectBucket())
-func (p *Plugin) selectBucket(probs *BucketProbabilities, features *RequestFeatures) Bucket {
-	thresholds := p.config.Router.Thresholds
-	
-	// Guardrails for context overflow
-	if p.contextExceedsCapacity(features, BucketCheap) {
-		if p.contextExceedsCapacity(features, BucketMid) {
-			return BucketHard
+// fetchArtifact fetches and prepares the artifact served at url. file://
+// URLs read a local file, such as a mounted ConfigMap or volume.
+func (p *Plugin) fetchArtifact(url string) (*AvengersArtifact, error) {
+	var body io.Reader
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact: %w", err)
+		}
+		defer file.Close()
+		body = file
+	} else {
+		resp, err := p.httpClient.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch artifact: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("artifact fetch failed with status %d", resp.StatusCode)
 		}
-		return BucketMid
+		body = resp.Body
 	}
 	
-	// Threshold-based bucket selection
-	if probs.Hard > thresholds.Hard {
-		return BucketHard
+	var artifact AvengersArtifact
+	if err := json.NewDecoder(body).Decode(&artifact); err != nil {
+		return nil, fmt.Errorf("failed to decode artifact: %w", err)
 	}
-	
-	if probs.Cheap > thresholds.Cheap {
-		return BucketCheap
+	if err := scoring.ApplyPairwise(&artifact); err != nil {
+		return nil, fmt.Errorf("invalid pairwise preferences: %w", err)
 	}
-	
-	return BucketMid
+	return &artifact, nil
 }
 
-// contextExceedsCapacity checks if context exceeds bucket capacity
-func (p *Plugin) contextExceedsCapacity(features *RequestFeatures, bucket Bucket) bool {
-	// Rough context capacity estimates
-	capacities := map[Bucket]int{
-		BucketCheap: 16000,   // DeepSeek R1, Qwen3-Coder
-		BucketMid:   128000,  // GPT-5 medium, Gemini medium
-		BucketHard:  1048576, // Gemini 2.5 Pro with high thinking
-	}
-	
-	capacity, ok := capacities[bucket]
-	if !ok {
-		return false
-	}
-	
-	return features.TokenCount > int(float64(capacity)*0.8) // 80% threshold
+// selectBucket implements bucket selection with guardrails (port of RouterPreHook.selectBucket())
+func (p *Plugin) selectBucket(probs *BucketProbabilities, features *RequestFeatures) Bucket {
+	return router.SelectBucket(probs, features, p.config.Router.Thresholds, p.learnedBucketTokenFactor)
+}
+
+// contextExceedsCapacity checks if context exceeds bucket capacity
+func (p *Plugin) contextExceedsCapacity(features *RequestFeatures, bucket Bucket) bool {
+	return router.ContextExceedsCapacity(features, bucket, p.bucketTokenFactor(bucket, true))
 }
 
 // selectModel implements in-bucket model selection (port of RouterPreHook.selectModel())
 func (p *Plugin) selectModel(bucket Bucket, features *RequestFeatures, authInfo *AuthInfo, excludeAnthropic bool) (*RouterDecision, error) {
+	return p.selectModelWithPolicy(bucket, features, authInfo, policyFor(authInfo), excludeAnthropic, nil)
+}
+
+// selectModelWithPolicy selects a model under an explicit routing policy,
+// deterministically when rs is set
+func (p *Plugin) selectModelWithPolicy(bucket Bucket, features *RequestFeatures, authInfo *AuthInfo, policy *RoutingPolicy, excludeAnthropic bool, rs *replayState) (*RouterDecision, error) {
 	if p.currentArtifact == nil {
 		return nil, fmt.Errorf("no artifact available for model selection")
 	}
 	
+	// BYOK policy may restrict candidates to the client's provider
+	scope := p.byokScopeFor(authInfo)
+
+	// Caller policy (e.g. from JWT claims) may cap the bucket and candidates
+	bucket = policy.CapBucket(bucket)
+
+	var decision *RouterDecision
+	var err error
 	switch bucket {
 	case BucketCheap:
-		return p.selectModelForBucket("cheap", features)
+		decision, err = p.selectModelForBucketScoped("cheap", features, scope, policy, rs)
 		
 	case BucketMid:
-		if !excludeAnthropic && authInfo != nil && authInfo.Provider == "anthropic" {
-			return p.selectAnthropicModel(), nil
+		if !excludeAnthropic && authInfo != nil && authInfo.Provider == "anthropic" &&
+			p.anthropicShortcutPermitted(features, policy, rs) {
+			decision = p.selectAnthropicModel()
+		} else {
+			decision, err = p.selectModelForBucketScoped("mid", features, scope, policy, rs)
 		}
-		return p.selectModelForBucket("mid", features)
 		
 	case BucketHard:
-		return p.selectModelForBucket("hard", features)
+		decision, err = p.selectModelForBucketScoped("hard", features, scope, policy, rs)
 		
 	default:
 		return nil, fmt.Errorf("unknown bucket: %s", bucket)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	applyPolicy(policy, decision)
+	p.capOutputTokens(decision, features, rs == nil)
+	decision.ProviderHints = p.providerHints(bucket, decision)
+	return decision, nil
+}
+
+// anthropicShortcutPermitted reports whether Anthropic OAuth callers may be
+// sent to the default Anthropic model without scoring: it must not be
+// drained and must pass the caller policy's candidates and quality floor
+// as scored candidates do. The price cap is applied to it with the rest of
+// the policy.
+func (p *Plugin) anthropicShortcutPermitted(features *RequestFeatures, policy *RoutingPolicy, rs *replayState) bool {
+	model := p.selectAnthropicModel().Model
+	if rs.drained(p, "anthropic", model) || !policy.Allows(model) {
+		return false
+	}
+	if policy == nil || policy.MinQuality <= 0 {
+		return true
+	}
+	// The artifact knows the model by its qualified name
+	qualified := []string{"anthropic/" + model}
+	return len(router.MeetQuality(qualified, features, p.currentArtifact, policy.MinQuality)) > 0
 }
 
 // selectAnthropicModel returns a default Anthropic model decision
@@ -1337,26 +1858,107 @@ func (p *Plugin) selectAnthropicModel() *RouterDecision {
 
 // selectModelForBucket implements consolidated model selection (port of RouterPreHook.selectModelForBucket())
 func (p *Plugin) selectModelForBucket(bucketType string, features *RequestFeatures) (*RouterDecision, error) {
-	var candidates []string
-	
+	return p.selectModelForBucketScoped(bucketType, features, nil, nil, nil)
+}
+
+// bucketCandidates returns the configured candidates for a bucket
+func (p *Plugin) bucketCandidates(bucketType string) ([]string, error) {
 	switch bucketType {
 	case "cheap":
-		candidates = p.config.Router.CheapCandidates
+		return p.config.Router.CheapCandidates, nil
 	case "mid":
-		candidates = p.config.Router.MidCandidates
+		return p.config.Router.MidCandidates, nil
 	case "hard":
-		candidates = p.config.Router.HardCandidates
+		return p.config.Router.HardCandidates, nil
 	default:
 		return nil, fmt.Errorf("unknown bucket type: %s", bucketType)
 	}
+}
+
+// availableCandidates filters out drained, quarantined and saturated models
+// and unhealthy or insufficient self-hosted endpoints
+func (p *Plugin) availableCandidates(candidates []string, features *RequestFeatures) []string {
+	candidates = p.filterDrainedCandidates(candidates)
+	candidates = p.filterQuarantinedCandidates(candidates)
+	candidates = p.filterSaturatedCandidates(candidates)
+	return p.filterSelfHostedCandidates(candidates, features)
+}
+
+// eligibleCandidates returns a bucket's candidates that are available,
+// permitted by the caller's policy and meet the cluster's constraints and
+// minQuality, failing with the reason when none are
+func (p *Plugin) eligibleCandidates(bucketType string, features *RequestFeatures, policy *RoutingPolicy, minQuality float64, rs *replayState) ([]string, error) {
+	candidates, err := p.bucketCandidates(bucketType)
+	if err != nil {
+		return nil, err
+	}
 	
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no candidates for bucket %s", bucketType)
 	}
-	
+
+	candidates = rs.available(p, candidates, features)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w for bucket %s", ErrNoHealthyCandidates, bucketType)
+	}
+
+	candidates = policy.FilterCandidates(candidates)
+	if len(candidates) == 0 {
+		return nil, NewPolicyError("no_permitted_candidates", fmt.Sprintf("no candidates permitted by routing policy for bucket %s", bucketType))
+	}
+
+	candidates = router.Constrain(candidates, features, p.currentArtifact)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidates meet the constraints of cluster %d for bucket %s", features.ClusterID, bucketType)
+	}
+
+	candidates = router.MeetQuality(candidates, features, p.currentArtifact, minQuality)
+	if len(candidates) == 0 {
+		return nil, NewPolicyError("no_permitted_candidates", fmt.Sprintf("no candidates meet quality %.2f for bucket %s", minQuality, bucketType))
+	}
+	return candidates, nil
+}
+
+// selectModelForBucketScoped selects a model for a bucket, optionally
+// restricted to a BYOK provider scope and a caller routing policy, and
+// deterministically when rs is set
+func (p *Plugin) selectModelForBucketScoped(bucketType string, features *RequestFeatures, scope *byokScope, policy *RoutingPolicy, rs *replayState) (*RouterDecision, error) {
+	minQuality := 0.0
+	if policy != nil {
+		minQuality = policy.MinQuality
+	}
+	candidates, err := p.eligibleCandidates(bucketType, features, policy, minQuality, rs)
+
+	// Restrict to the client's provider, searching neighbouring buckets if
+	// needed, also when none of the bucket's own candidates are eligible
+	if scope != nil {
+		var scoped []string
+		for _, searchBucket := range byokBucketSearchOrder[bucketType] {
+			searchCandidates, _ := p.eligibleCandidates(searchBucket, features, policy, minQuality, rs)
+			scoped = scope.filter(p, searchCandidates)
+			if len(scoped) > 0 {
+				break
+			}
+		}
+		if len(scoped) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("no candidates for BYOK provider %s", scope.provider)
+		}
+		if scope.restrictFallbacks {
+			candidates = scoped
+		} else {
+			// Scoped models are selected first; other providers remain as fallbacks
+			candidates = append(scoped, candidates...)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
 	// Special logic for hard models with long context
 	finalCandidates := candidates
-	if bucketType == "hard" && features.TokenCount > 200000 {
+	if bucketType == "hard" && float64(features.TokenCount)*p.bucketTokenFactor(BucketHard, rs == nil) > 200000 {
 		// For very long context, bias towards Gemini
 		var geminiModels, otherModels []string
 		for _, c := range candidates {
@@ -1370,29 +1972,40 @@ func (p *Plugin) selectModelForBucket(bucketType string, features *RequestFeatur
 	}
 	
 	// Use α-score to pick best model
-	bestModel, err := p.alphaScorer.SelectBest(finalCandidates, features, p.currentArtifact)
+	selectionCandidates := finalCandidates
+	if scope != nil {
+		selectionCandidates = scope.filter(p, finalCandidates)
+	}
+	// Shortlisted here so dual-run compares the same candidates
+	eligible := selectionCandidates
+	// Deterministic decisions score with the artifact alone
+	artifact, selector, quality := p.scoringArtifact(), p.router, p.predictedQuality(features.ClusterID)
+	if rs != nil {
+		artifact, selector, quality = p.currentArtifact, p.strictRouter, p.artifactQuality(features.ClusterID)
+	}
+	selectionCandidates = selector.Shortlist(selectionCandidates, features, artifact)
+	selectStart := time.Now()
+	bestModel, budgetTruncated, err := selector.SelectWithBudget(selectionCandidates, features, artifact)
 	if err != nil {
-		return nil, fmt.Errorf("α-score selection failed: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrScoringFailed, err)
 	}
 	
-	// Build model-specific parameters
-	params := make(map[string]interface{})
-	if bucketType != "cheap" {
-		// Add bucket-specific parameters
-		if bucketType == "mid" || bucketType == "hard" {
-			bucketParams := p.config.Router.BucketDefaults.Mid
-			if bucketType == "hard" {
-				bucketParams = p.config.Router.BucketDefaults.Hard
-			}
-			
-			if strings.Contains(bestModel, "gpt") {
-				params["reasoning_effort"] = bucketParams.GPT5ReasoningEffort
-			} else if strings.Contains(bestModel, "gemini") {
-				params["thinkingBudget"] = bucketParams.GeminiThinkingBudget
-			}
-		}
+	// Shadow-compare a sample of selections off the request path
+	if p.dualRun != nil && rs == nil && p.dualRun.ShouldSample() {
+		featuresCopy := *features
+		go p.dualRun.Compare(bestModel, time.Since(selectStart),
+			append([]string(nil), selectionCandidates...), &featuresCopy, artifact)
+	}
+
+	// Within a model family, move to the smallest sufficient size
+	var ladder *LadderInfo
+	if p.ladder != nil {
+		bestModel, ladder = p.ladder.Select(bestModel, eligible, features.ClusterID, quality)
 	}
 	
+	// Build model-specific parameters
+	params := p.bucketParams(bucketType, bestModel)
+	
 	// Infer provider kind from model name
 	providerKind := p.inferProviderKind(bestModel)
 	
@@ -1401,11 +2014,25 @@ func (p *Plugin) selectModelForBucket(bucketType string, features *RequestFeatur
 	
 	// Build fallbacks list (exclude the selected model)
 	var fallbacks []string
+	seen := map[string]bool{bestModel: true}
 	for _, c := range finalCandidates {
-		if c != bestModel {
+		if !seen[c] {
+			seen[c] = true
 			fallbacks = append(fallbacks, c)
 		}
 	}
+
+	// Last resort once the bucket is exhausted: the next bucket up
+	escalation := p.escalationFor(bucketType, features, scope, policy, seen, rs)
+	if escalation != nil {
+		fallbacks = append(fallbacks, escalation.Model)
+	}
+
+	// Client-keyed requests authenticate with the client's own credentials
+	authMode := "env"
+	if scope != nil {
+		authMode = "passthrough"
+	}
 	
 	return &RouterDecision{
 		Kind:          providerKind,
@@ -1413,14 +2040,38 @@ func (p *Plugin) selectModelForBucket(bucketType string, features *RequestFeatur
 		Params:        params,
 		ProviderPrefs: providerPrefs,
 		Auth: AuthConfig{
-			Mode: "env",
+			Mode: authMode,
 		},
-		Fallbacks: fallbacks,
+		Fallbacks:       fallbacks,
+		Ladder:          ladder,
+		Escalation:      escalation,
+		BudgetTruncated: budgetTruncated,
 	}, nil
 }
 
+// bucketParams builds the bucket-specific parameters for a model
+func (p *Plugin) bucketParams(bucketType string, model string) map[string]interface{} {
+	params := make(map[string]interface{})
+	if bucketType == "mid" || bucketType == "hard" {
+		bucketParams := p.config.Router.BucketDefaults.Mid
+		if bucketType == "hard" {
+			bucketParams = p.config.Router.BucketDefaults.Hard
+		}
+		
+		if strings.Contains(model, "gpt") {
+			params["reasoning_effort"] = bucketParams.GPT5ReasoningEffort
+		} else if strings.Contains(model, "gemini") {
+			params["thinkingBudget"] = bucketParams.GeminiThinkingBudget
+		}
+	}
+	return params
+}
+
 // inferProviderKind infers provider from model name
 func (p *Plugin) inferProviderKind(model string) string {
+	if p.selfHosted != nil && p.selfHosted.IsSelfHosted(model) {
+		return ProviderKindSelfHosted
+	}
 	if strings.Contains(model, "openai") || strings.Contains(model, "gpt") {
 		return "openai"
 	}
@@ -1433,6 +2084,16 @@ func (p *Plugin) inferProviderKind(model string) string {
 	return "openrouter" // Default for other models
 }
 
+// bifrostProvider maps a provider kind to the Bifrost provider that serves it
+func (p *Plugin) bifrostProvider(kind string, model string) schemas.ModelProvider {
+	if kind == ProviderKindSelfHosted && p.selfHosted != nil {
+		if endpoint, ok := p.selfHosted.Endpoint(model); ok && endpoint.BifrostProvider != "" {
+			return schemas.ModelProvider(endpoint.BifrostProvider)
+		}
+	}
+	return schemas.ModelProvider(kind)
+}
+
 // getProviderPreferencesForBucket returns provider preferences for bucket
 func (p *Plugin) getProviderPreferencesForBucket(bucketType string) ProviderPrefs {
 	switch bucketType {
@@ -1459,6 +2120,16 @@ func (p *Plugin) getProviderPreferencesForBucket(bucketType string) ProviderPref
 	}
 }
 
+// FeatureOverridesContextKey is the context key under which upstream plugins
+// can store a *FeatureOverrides with features they already computed (e.g. a
+// classification service's task type); extraction skips those features
+const FeatureOverridesContextKey = "heimdall_feature_overrides"
+
+// RawBodyContextKey is the context key under which hosts store the request
+// body as received ([]byte). Bifrost does not set it; hosts serving signed
+// (HMAC) callers must, or their requests fail verification.
+const RawBodyContextKey = "http_body"
+
 // convertToRouterRequest converts BifrostRequest to internal RouterRequest
 func (p *Plugin) convertToRouterRequest(ctx *context.Context, req *schemas.BifrostRequest) (*RouterRequest, map[string][]string, error) {
 	headers := make(map[string][]string)
@@ -1468,23 +2139,8 @@ func (p *Plugin) convertToRouterRequest(ctx *context.Context, req *schemas.Bifro
 		headers = httpHeaders
 	}
 	
-	// Convert ChatCompletionInput to messages
-	var messages []ChatMessage
-	if req.Input.ChatCompletionInput != nil {
-		for _, msg := range *req.Input.ChatCompletionInput {
-			content := ""
-			if msg.Content.ContentStr != nil {
-				content = *msg.Content.ContentStr
-			}
-			messages = append(messages, ChatMessage{
-				Role:    string(msg.Role),
-				Content: content,
-			})
-		}
-	}
-	
 	body := &RequestBody{
-		Messages: messages,
+		Messages: chatMessages(req),
 		Model:    req.Model,
 	}
 	
@@ -1494,40 +2150,123 @@ func (p *Plugin) convertToRouterRequest(ctx *context.Context, req *schemas.Bifro
 		Headers: headers,
 		Body:    body,
 	}
+
+	// Raw body from the HTTP transport, if available (for signed requests)
+	if rawBody, ok := (*ctx).Value(RawBodyContextKey).([]byte); ok {
+		routerReq.RawBody = rawBody
+	}
+
+	if overrides, ok := (*ctx).Value(FeatureOverridesContextKey).(*FeatureOverrides); ok {
+		routerReq.FeatureOverrides = overrides
+	}
 	
 	return routerReq, headers, nil
 }
 
+// chatMessages converts a request's chat input, or its text completion
+// prompt as a user message, to router messages
+func chatMessages(req *schemas.BifrostRequest) []ChatMessage {
+	var messages []ChatMessage
+	if req.Input.ChatCompletionInput != nil {
+		for _, msg := range *req.Input.ChatCompletionInput {
+			messages = append(messages, ChatMessage{
+				Role:    string(msg.Role),
+				Content: messageText(msg),
+				NonText: nonTextContent(msg),
+			})
+		}
+	} else if req.Input.TextCompletionInput != nil {
+		messages = append(messages, ChatMessage{
+			Role:    string(schemas.ModelChatMessageRoleUser),
+			Content: *req.Input.TextCompletionInput,
+		})
+	}
+	return messages
+}
+
 // applyRoutingDecision applies the routing decision to the BifrostRequest
 func (p *Plugin) applyRoutingDecision(ctx *context.Context, req *schemas.BifrostRequest, response *RouterResponse) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
 	// Update request with routing decision
-	req.Provider = schemas.ModelProvider(response.Decision.Kind)
+	req.Provider = p.bifrostProvider(response.Decision.Kind, response.Decision.Model)
 	req.Model = response.Decision.Model
 	
 	// Set fallbacks - convert string slice to Fallback slice
-	var fallbacks []schemas.Fallback
-	for _, fallback := range response.Decision.Fallbacks {
-		// Extract provider from model name (simplified)
-		provider := p.inferProviderKind(fallback)
-		fallbacks = append(fallbacks, schemas.Fallback{
-			Provider: schemas.ModelProvider(provider),
-			Model:    fallback,
-		})
-	}
-	req.Fallbacks = fallbacks
+	req.Fallbacks = p.bifrostFallbacks(schemas.Fallback{Provider: req.Provider, Model: req.Model}, response.Decision.Fallbacks)
+	p.fallbacks.Issue(req)
+	applyOutputCap(req, &response.Decision)
 	
 	// Enrich context with routing information
 	*ctx = context.WithValue(*ctx, "heimdall_bucket", response.Bucket)
+	*ctx = context.WithValue(*ctx, "heimdall_bucket_probs", response.BucketProbabilities)
 	*ctx = context.WithValue(*ctx, "heimdall_features", response.Features)
 	*ctx = context.WithValue(*ctx, "heimdall_decision", response.Decision)
 	*ctx = context.WithValue(*ctx, "heimdall_alpha_scores", "enabled") // Flag for observability
+
+	// Track in-flight requests so drains can report when they are complete
+	p.drains.Acquire(response.Decision.Kind, response.Decision.Model)
+	if p.concurrency != nil {
+		p.concurrency.Acquire(response.Decision.Model)
+	}
+	*ctx = context.WithValue(*ctx, "heimdall_inflight", response.Decision)
+	*ctx = context.WithValue(*ctx, "heimdall_start_time", time.Now())
+	*ctx = context.WithValue(*ctx, "heimdall_stream", &streamState{})
+	if p.digester != nil && (*ctx).Value("heimdall_synthetic") == nil {
+		p.digester.RecordDecision(response.Bucket)
+	}
+	if response.hardSlot {
+		*ctx = context.WithValue(*ctx, "heimdall_hard_slot", true)
+	}
+	if response.Admission != "" {
+		*ctx = context.WithValue(*ctx, "heimdall_admission", response.Admission)
+	}
+
+	if response.SessionID != "" {
+		*ctx = context.WithValue(*ctx, "heimdall_session_id", response.SessionID)
+	}
+
+	if p.judge != nil && p.judge.ShouldSample() {
+		*ctx = context.WithValue(*ctx, "heimdall_judge_prompt", promptText(req))
+	}
+
+	// Compared requests are answered by a challenger alongside the primary
+	if p.comparer != nil && (*ctx).Value("heimdall_synthetic") == nil {
+		headers, _ := (*ctx).Value("http_headers").(map[string][]string)
+		tenant := tenantOf(response.AuthInfo)
+		if p.comparer.ShouldCompare(headers, tenant) {
+			// Requests whose caller may not use the challenger are not compared
+			if challenger := p.comparer.Challenger(response.Decision); p.challengerPermitted(response, challenger) {
+				comparison := p.comparer.Begin(subjectOf(response.AuthInfo), response.Decision, challenger, response.Bucket,
+					response.Features.ClusterID, chatMessages(req), promptText(req))
+				if comparison != nil {
+					*ctx = context.WithValue(*ctx, "heimdall_comparison", comparison)
+				}
+			}
+		}
+	}
+
+	if len(response.Decision.ProviderHints) > 0 {
+		*ctx = context.WithValue(*ctx, "heimdall_provider_hints", response.Decision.ProviderHints)
+	}
+	if len(response.Decision.Outbound) > 0 {
+		*ctx = context.WithValue(*ctx, "heimdall_outbound", response.Decision.Outbound)
+	}
 	
 	if response.AuthInfo != nil {
 		*ctx = context.WithValue(*ctx, "heimdall_auth_info", response.AuthInfo)
 	}
 	
 	if response.FallbackReason != "" {
-		*ctx = context.WithValue(*ctx, "heimdall_fallback_reason", response.FallbackReason)
+		p.recordFallback(response.FallbackReason)
+		*ctx = context.WithValue(*ctx, "heimdall_fallback_reason", string(response.FallbackReason))
+	}
+
+	// Observability signals are sampled once per request
+	sampled := p.sampler.Sample(tenantOf(response.AuthInfo))
+	*ctx = context.WithValue(*ctx, "heimdall_sampled", sampled)
+	if sampled.Debug {
+		if record, err := p.encodeDecisionLog(response, false); err == nil {
+			p.logger.Printf("Debug capture: %s", record)
+		}
 	}
 	
 	return req, nil, nil
@@ -1539,7 +2278,7 @@ func (p *Plugin) handleError(ctx *context.Context, req *schemas.BifrostRequest,
 	p.errorCount++
 	p.metricsMu.Unlock()
 	
-	log.Printf("Heimdall plugin error: %v", err)
+	p.logger.Printf("Heimdall plugin error: %v", err)
 	
 	// Create fallback decision
 	fallbackResponse := p.getFallbackDecision(req, err)
@@ -1549,18 +2288,16 @@ func (p *Plugin) handleError(ctx *context.Context, req *schemas.BifrostRequest,
 	req.Model = fallbackResponse.Decision.Model
 	
 	// Convert fallbacks
-	var fallbacks []schemas.Fallback
-	for _, fallback := range fallbackResponse.Decision.Fallbacks {
-		provider := p.inferProviderKind(fallback)
-		fallbacks = append(fallbacks, schemas.Fallback{
-			Provider: schemas.ModelProvider(provider),
-			Model:    fallback,
-		})
+	p.finishDecision(&fallbackResponse.Decision)
+	req.Fallbacks = p.bifrostFallbacks(schemas.Fallback{Provider: req.Provider, Model: req.Model}, fallbackResponse.Decision.Fallbacks)
+	p.fallbacks.Issue(req)
+	if len(fallbackResponse.Decision.Outbound) > 0 {
+		*ctx = context.WithValue(*ctx, "heimdall_outbound", fallbackResponse.Decision.Outbound)
 	}
-	req.Fallbacks = fallbacks
 	
 	// Set fallback context
-	*ctx = context.WithValue(*ctx, "heimdall_fallback_reason", fallbackResponse.FallbackReason)
+	p.recordFallback(fallbackResponse.FallbackReason)
+	*ctx = context.WithValue(*ctx, "heimdall_fallback_reason", string(fallbackResponse.FallbackReason))
 	*ctx = context.WithValue(*ctx, "heimdall_error", err.Error())
 	*ctx = context.WithValue(*ctx, "heimdall_bucket", fallbackResponse.Bucket)
 	
@@ -1570,10 +2307,31 @@ func (p *Plugin) handleError(ctx *context.Context, req *schemas.BifrostRequest,
 // Cleanup releases resources and performs cleanup
 func (p *Plugin) Cleanup() error {
 	// Clear cache
-	p.cacheMu.Lock()
-	p.cache = make(map[string]CacheEntry)
-	p.cacheMu.Unlock()
+	p.cache.Clear()
 	
+	// Stop self-hosted health checks
+	if p.selfHosted != nil {
+		p.selfHosted.Stop()
+	}
+	if p.judge != nil {
+		p.judge.Stop()
+	}
+	if p.comparer != nil {
+		p.comparer.Stop()
+	}
+	if p.alphaController != nil {
+		p.alphaController.Stop()
+	}
+	if p.digester != nil {
+		p.digester.Stop()
+	}
+	if p.sidecar != nil {
+		p.sidecar.Close()
+	}
+	if p.wasmScorer != nil {
+		p.wasmScorer.Close()
+	}
+
 	// Close HTTP client
 	if p.httpClient != nil {
 		p.httpClient.CloseIdleConnections()
@@ -1584,36 +2342,61 @@ func (p *Plugin) Cleanup() error {
 	p.currentArtifact = nil
 	p.artifactMu.Unlock()
 	
-	log.Printf("Native Heimdall plugin cleanup completed")
+	p.logger.Printf("Native Heimdall plugin cleanup completed")
 	return nil
 }
 
-// GetMetrics returns plugin metrics for monitoring
+// GetMetrics returns Metrics as a map, for existing callers
 func (p *Plugin) GetMetrics() map[string]interface{} {
-	p.metricsMu.RLock()
-	defer p.metricsMu.RUnlock()
-	
-	metrics := map[string]interface{}{
-		"request_count":    p.requestCount,
-		"error_count":      p.errorCount,
-		"cache_hit_count":  p.cacheHitCount,
-		"cache_entries":    len(p.cache),
-	}
-	
-	// Add artifact info if available
+	return p.Metrics().Map()
+}
+
+// HealthStatus represents the plugin health report
+type HealthStatus struct {
+	Status             string             `json:"status"`
+	ArtifactVersion    string             `json:"artifact_version,omitempty"`
+	ArtifactAgeSeconds float64            `json:"artifact_age_seconds,omitempty"`
+	SelfHosted         []SelfHostedStatus `json:"self_hosted,omitempty"`
+	Drained            []DrainStatus      `json:"drained,omitempty"`
+	Quarantined        []QuarantineStatus `json:"quarantined,omitempty"`
+	ArtifactSources    []ArtifactSourceStatus `json:"artifact_sources,omitempty"`
+}
+
+// GetHealth returns the plugin health, including self-hosted endpoint status
+func (p *Plugin) GetHealth() HealthStatus {
+	health := HealthStatus{Status: "healthy"}
+
 	p.artifactMu.RLock()
 	if p.currentArtifact != nil {
-		metrics["artifact_version"] = p.currentArtifact.Version
-		metrics["artifact_age_seconds"] = time.Since(p.lastArtifactLoad).Seconds()
+		health.ArtifactVersion = p.currentArtifact.Version
+		health.ArtifactAgeSeconds = time.Since(p.lastArtifactLoad).Seconds()
+		if p.currentArtifact.Version == defaultArtifactVersion {
+			health.Status = "degraded"
+		}
+	} else {
+		health.Status = "degraded"
 	}
 	p.artifactMu.RUnlock()
-	
-	return metrics
+
+	if p.selfHosted != nil {
+		health.SelfHosted = p.selfHosted.GetStatus()
+	}
+	if p.drains != nil {
+		health.Drained = p.drains.GetStatus()
+	}
+	if p.quarantine != nil {
+		health.Quarantined = p.quarantine.GetStatus()
+	}
+	if p.artifactSources != nil {
+		health.ArtifactSources = p.artifactSources.GetStatus()
+	}
+
+	return health
 }
 
 // getFallbackDecision creates a safe fallback decision on errors
 func (p *Plugin) getFallbackDecision(req *schemas.BifrostRequest, err error) *RouterResponse {
-	log.Printf("Creating fallback decision due to error: %v", err)
+	p.logger.Printf("Creating fallback decision due to error: %v", err)
 	
 	// Emergency fallback to cheapest reliable option
 	decision := RouterDecision{
@@ -1654,7 +2437,8 @@ func (p *Plugin) getFallbackDecision(req *schemas.BifrostRequest, err error) *Ro
 			Hard:  0.0,
 		},
 		AuthInfo:       nil,
-		FallbackReason: "error_fallback",
+		FallbackReason: fallbackReasonFor(err),
+		Timestamp:      p.now(),
 	}
 }
 
@@ -1679,327 +2463,146 @@ func (p *Plugin) estimateTokens(req *schemas.BifrostRequest) int {
 func (p *Plugin) getCachedResponse(req *RouterRequest) *RouterResponse {
 	key := p.getCacheKey(req)
 	
-	p.cacheMu.RLock()
-	defer p.cacheMu.RUnlock()
-	
-	entry, exists := p.cache[key]
-	if !exists || time.Now().After(entry.ExpiresAt) {
+	entry, exists := p.cache.Get(key)
+	if !exists || p.now().After(entry.ExpiresAt) {
 		return nil
 	}
 	
-	return &entry.Response
-}
-
-// cacheResponse stores a routing decision in cache
-func (p *Plugin) cacheResponse(req *RouterRequest, response *RouterResponse) {
-	key := p.getCacheKey(req)
-	
-	p.cacheMu.Lock()
-	defer p.cacheMu.Unlock()
-	
-	p.cache[key] = CacheEntry{
-		Response:  *response,
-		ExpiresAt: time.Now().Add(p.config.CacheTTL),
+	response, err := p.openCacheEntry(key, entry)
+	if err != nil {
+		p.logger.Printf("Discarding cache entry: %v", err)
+		return nil
 	}
+	return response
 }
 
-// getCacheKey generates a cache key for the request
-func (p *Plugin) getCacheKey(req *RouterRequest) string {
-	// Generate a cache key based on request content
-	// This is a simplified implementation - in production you'd want a more sophisticated key
-	data, _ := json.Marshal(req.Body)
-	return fmt.Sprintf("%s:%s", req.Method, string(data))
-}
-
-// applyCachedDecision applies a cached routing decision
-func (p *Plugin) applyCachedDecision(ctx *context.Context, req *schemas.BifrostRequest, response *RouterResponse) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
-	*ctx = context.WithValue(*ctx, "heimdall_cache_hit", true)
-	return p.applyRoutingDecision(ctx, req, response)
-}
-
-// ============================================================================
-// ADVANCED ALPHA SCORING METHODS - Phase 3 Implementation
-// Caching, performance tracking, A/B testing, and optimization features
-// ============================================================================
-
-// getCachedScore retrieves a cached alpha score if available and not expired
-func (as *AlphaScorer) getCachedScore(model string, features *RequestFeatures, artifact *AvengersArtifact) *ModelScore {
-	cacheKey := as.generateCacheKey(model, features, artifact)
-	
-	if cached, ok := as.scoreCache.Load(cacheKey); ok {
-		entry := cached.(*ScoreCacheEntry)
-		if time.Now().Before(entry.ExpiresAt) {
-			return entry.Score
+// openCacheEntry returns a copy of a typed decision cache entry, or
+// decrypts and decodes an encoded one
+func (p *Plugin) openCacheEntry(key string, entry CacheEntry) (*RouterResponse, error) {
+	if entry.Response != nil {
+		return cloneCachedResponse(entry.Response), nil
+	}
+	data := entry.Data
+	if p.cacheCipher != nil {
+		var err error
+		if data, err = p.cacheCipher.Open(data, []byte(key)); err != nil {
+			return nil, fmt.Errorf("unreadable entry: %w", err)
 		}
-		// Expired - remove from cache
-		as.scoreCache.Delete(cacheKey)
 	}
-	
-	return nil
-}
-
-// cacheScore stores a calculated score in the cache with expiration
-func (as *AlphaScorer) cacheScore(model string, features *RequestFeatures, artifact *AvengersArtifact, score *ModelScore) {
-	cacheKey := as.generateCacheKey(model, features, artifact)
-	
-	entry := &ScoreCacheEntry{
-		Score:     score,
-		ExpiresAt: time.Now().Add(as.cacheTTL),
+	response, err := DecodeRouterResponse(data)
+	if err != nil {
+		return nil, fmt.Errorf("undecodable entry: %w", err)
 	}
-	
-	as.scoreCache.Store(cacheKey, entry)
-}
-
-// generateCacheKey creates a deterministic cache key from inputs
-func (as *AlphaScorer) generateCacheKey(model string, features *RequestFeatures, artifact *AvengersArtifact) string {
-	// Create deterministic key based on relevant inputs
-	keyData := fmt.Sprintf("%s:%d:%d:%.2f:%.2f:%t:%t", 
-		model, 
-		features.ClusterID,
-		features.TokenCount,
-		artifact.Alpha,
-		features.ContextRatio,
-		features.HasCode,
-		features.HasMath,
-	)
-	
-	// Hash to fixed-length key
-	hash := sha256.Sum256([]byte(keyData))
-	return fmt.Sprintf("score:%x", hash[:8]) // Use first 8 bytes for efficiency
+	return response, nil
 }
 
-// cleanExpiredCache removes expired entries from the score cache
-func (as *AlphaScorer) cleanExpiredCache() {
-	as.mu.Lock()
-	defer as.mu.Unlock()
-	
-	now := time.Now()
-	as.lastCacheClean = now
-	
-	// Iterate through cache and remove expired entries
-	as.scoreCache.Range(func(key, value interface{}) bool {
-		entry := value.(*ScoreCacheEntry)
-		if now.After(entry.ExpiresAt) {
-			as.scoreCache.Delete(key)
-		}
-		return true
-	})
+// cachedDecisionAvailable reports whether a cached decision's model is
+// still available (not drained, quarantined, saturated or unhealthy), and
+// drops fallbacks that no longer are
+func (p *Plugin) cachedDecisionAvailable(response *RouterResponse) bool {
+	models := append([]string{response.Decision.Model}, response.Decision.Fallbacks...)
+	available := p.availableCandidates(models, &response.Features)
+	if len(available) == 0 || available[0] != response.Decision.Model {
+		return false
+	}
+	response.Decision.Fallbacks = available[1:]
+	return true
 }
 
-// updatePerformanceHistory tracks model performance for alpha optimization
-func (as *AlphaScorer) updatePerformanceHistory(model string, features *RequestFeatures) {
-	histKey := fmt.Sprintf("perf:%s", model)
+// cacheResponse stores a routing decision in cache
+func (p *Plugin) cacheResponse(req *RouterRequest, response *RouterResponse) {
+	key := p.getCacheKey(req)
+	expiresAt := p.now().Add(p.config.CacheTTL)
 	
-	now := time.Now()
+	// In-process entries are never persisted, so they skip the encoding
+	if _, ok := p.cache.(*memoryDecisionCache); ok {
+		p.cache.Set(key, CacheEntry{Response: cloneCachedResponse(response), ExpiresAt: expiresAt})
+		return
+	}
 	
-	if existing, ok := as.performanceHist.Load(histKey); ok {
-		// Update existing history
-		hist := existing.(*PerformanceHistory)
-		as.mu.Lock()
-		hist.TotalRequests++
-		hist.LastUpdated = now
-		// Update average latency if available
-		if features.AvgLatency != nil {
-			hist.AvgLatency = (hist.AvgLatency + *features.AvgLatency) / 2.0
-		}
-		as.mu.Unlock()
-	} else {
-		// Create new history entry
-		hist := &PerformanceHistory{
-			ModelName:     model,
-			SuccessRate:   1.0, // Assume success initially
-			AvgLatency:    5.0, // Default latency
-			TotalRequests: 1,
-			LastUpdated:   now,
-			AlphaOptimal:  0.7, // Default alpha
-		}
-		
-		if features.AvgLatency != nil {
-			hist.AvgLatency = *features.AvgLatency
+	data, err := EncodeRouterResponse(response)
+	if err != nil {
+		p.logger.Printf("Failed to encode response for cache: %v", err)
+		return
+	}
+	if p.cacheCipher != nil {
+		data, err = p.cacheCipher.Seal(data, []byte(key))
+		if err != nil {
+			p.logger.Printf("Failed to encrypt cache entry: %v", err)
+			return
 		}
-		
-		as.performanceHist.Store(histKey, hist)
 	}
-}
-
-// GetPerformanceMetrics returns performance history for observability
-func (as *AlphaScorer) GetPerformanceMetrics() map[string]*PerformanceHistory {
-	metrics := make(map[string]*PerformanceHistory)
 	
-	as.performanceHist.Range(func(key, value interface{}) bool {
-		keyStr := key.(string)
-		hist := value.(*PerformanceHistory)
-		metrics[keyStr] = hist
-		return true
+	p.cache.Set(key, CacheEntry{
+		Data:      data,
+		ExpiresAt: expiresAt,
 	})
-	
-	return metrics
 }
 
-// TuneAlphaParameter implements adaptive alpha tuning based on historical performance
-func (as *AlphaScorer) TuneAlphaParameter(currentAlpha float64, successRate float64, avgLatency float64) float64 {
-	// Simple adaptive tuning algorithm
-	// If success rate is low, favor quality (increase alpha)
-	// If latency is high, favor speed/cost (decrease alpha)
-	
-	newAlpha := currentAlpha
-	
-	if successRate < 0.8 {
-		// Low success rate - increase quality weight
-		newAlpha = math.Min(currentAlpha+0.05, 0.95)
-	} else if successRate > 0.95 && avgLatency > 10.0 {
-		// High success but slow - can reduce quality weight for speed
-		newAlpha = math.Max(currentAlpha-0.05, 0.1)
+// cloneCachedResponse copies a decision into or out of the in-process
+// cache, so callers cannot change cached entries through the decision's
+// slices and maps. Like encoded entries, copies keep only the caller's
+// token fingerprint.
+func cloneCachedResponse(response *RouterResponse) *RouterResponse {
+	clone := *response
+	clone.Decision.Params = maps.Clone(response.Decision.Params)
+	clone.Decision.Fallbacks = slices.Clone(response.Decision.Fallbacks)
+	clone.Decision.ProviderHints = maps.Clone(response.Decision.ProviderHints)
+	clone.Decision.Outbound = maps.Clone(response.Decision.Outbound)
+	if response.AuthInfo != nil {
+		authInfo := *response.AuthInfo
+		authInfo.Token = authInfo.Token.Redacted()
+		clone.AuthInfo = &authInfo
 	}
-	
-	return newAlpha
+	clone.hardSlot, clone.cacheHit = false, false
+	return &clone
 }
 
-// ScoreModelsWithAlphaTuning implements A/B testing for alpha parameter optimization
-func (as *AlphaScorer) ScoreModelsWithAlphaTuning(candidates []string, features *RequestFeatures, artifact *AvengersArtifact, explorationRate float64) ([]ModelScore, float64, error) {
-	// A/B test: Use different alpha values for exploration
-	originalAlpha := artifact.Alpha
-	testAlpha := originalAlpha
-	
-	// With probability explorationRate, try a different alpha
-	if math.Mod(float64(time.Now().UnixNano()), 1.0) < explorationRate {
-		// Explore different alpha values
-		alphaVariants := []float64{0.3, 0.5, 0.7, 0.9}
-		variantIndex := int(time.Now().UnixNano()) % len(alphaVariants)
-		testAlpha = alphaVariants[variantIndex]
-		
-		// Temporarily modify artifact
-		testArtifact := *artifact
-		testArtifact.Alpha = testAlpha
-		artifact = &testArtifact
+// getCacheKey generates a cache key for the request
+func (p *Plugin) getCacheKey(req *RouterRequest) string {
+	// Generate a cache key based on request content
+	// This is a simplified implementation - in production you'd want a more sophisticated key
+	data, _ := json.Marshal(req.Body)
+	if p.config.TemplateCacheKeys && req.Body != nil {
+		// Prompts from one template differ only in slot values, but slots
+		// can hold whole documents, so the size class is part of the key
+		data = []byte(fmt.Sprintf("%s\x00%s\x00%d", req.Body.Model, features.TemplateFingerprint(req.Body.Messages), templateSizeClass(req.Body.Messages)))
 	}
-	
-	scores, err := as.scoreModelsBatched(candidates, features, artifact)
-	if err != nil {
-		return nil, originalAlpha, err
+	if req.FeatureOverrides != nil {
+		// Upstream features change decisions for the same prompt
+		overrides, _ := json.Marshal(req.FeatureOverrides)
+		data = append(data, overrides...)
 	}
-	
-	return scores, testAlpha, nil
-}
 
-// GetCacheMetrics returns cache performance metrics
-func (as *AlphaScorer) GetCacheMetrics() map[string]interface{} {
-	cacheSize := 0
-	expiredCount := 0
-	now := time.Now()
-	
-	as.scoreCache.Range(func(key, value interface{}) bool {
-		cacheSize++
-		entry := value.(*ScoreCacheEntry)
-		if now.After(entry.ExpiresAt) {
-			expiredCount++
-		}
-		return true
-	})
-	
-	return map[string]interface{}{
-		"cache_size":      cacheSize,
-		"expired_entries": expiredCount,
-		"cache_ttl_minutes": int(as.cacheTTL.Minutes()),
-		"last_cleanup":    as.lastCacheClean.Format(time.RFC3339),
+	// Decisions depend on the caller's credentials (BYOK scope, policies)
+	credentials := credentialsOf(req.Headers)
+	// Session spend rules and affinity change decisions, so the reached rule
+	// and the session's model are part of the key
+	sessionRule, pinned := -1, ""
+	if p.sessions != nil {
+		sessionID := p.sessions.SessionID(req.Headers)
+		sessionRule, pinned = p.sessions.ruleIndex(sessionID), p.sessions.Pinned(sessionID)
 	}
-}
 
-// InvalidateCache clears all cached scores (useful for testing or after artifact updates)
-func (as *AlphaScorer) InvalidateCache() {
-	as.scoreCache.Range(func(key, value interface{}) bool {
-		as.scoreCache.Delete(key)
-		return true
-	})
+	// Hash the prompt so keys never hold request content in the clear
+	bodyHash := sha256.Sum256(data)
+	return fmt.Sprintf("%s:%s:%d:%s:%x", req.Method, auth.TokenFingerprint(credentials), sessionRule, pinned, bodyHash)
 }
 
-// ScoreModelsConcurrent implements concurrent scoring for improved performance
-func (as *AlphaScorer) ScoreModelsConcurrent(candidates []string, features *RequestFeatures, artifact *AvengersArtifact, maxWorkers int) ([]ModelScore, error) {
-	if len(candidates) == 0 {
-		return nil, nil
-	}
-	
-	// Limit workers to avoid over-subscription
-	workers := maxWorkers
-	if workers <= 0 || workers > len(candidates) {
-		workers = len(candidates)
-	}
-	
-	type scoreJob struct {
-		model string
-		index int
-	}
-	
-	type scoreResult struct {
-		score *ModelScore
-		index int
-	}
-	
-	jobs := make(chan scoreJob, len(candidates))
-	results := make(chan scoreResult, len(candidates))
-	
-	// Start workers
-	for i := 0; i < workers; i++ {
-		go func() {
-			for job := range jobs {
-				score := as.scoreModel(job.model, features, artifact)
-				results <- scoreResult{score: score, index: job.index}
-			}
-		}()
-	}
-	
-	// Send jobs
-	for i, model := range candidates {
-		jobs <- scoreJob{model: model, index: i}
+// templateSizeClass buckets a conversation's estimated token count by powers
+// of two, so template-keyed decisions are only shared by prompts of similar
+// size
+func templateSizeClass(messages []ChatMessage) int {
+	chars := 0
+	for _, msg := range messages {
+		chars += len(msg.Content)
 	}
-	close(jobs)
-	
-	// Collect results
-	scores := make([]*ModelScore, len(candidates))
-	for i := 0; i < len(candidates); i++ {
-		result := <-results
-		scores[result.index] = result.score
-	}
-	
-	// Filter out nil scores and convert to slice
-	var validScores []ModelScore
-	for _, score := range scores {
-		if score != nil {
-			validScores = append(validScores, *score)
-		}
-	}
-	
-	return validScores, nil
+	return bits.Len(uint(chars / 4))
 }
 
-// EstimateOptimalAlpha suggests an optimal alpha value based on task characteristics
-func (as *AlphaScorer) EstimateOptimalAlpha(features *RequestFeatures) float64 {
-	baseAlpha := 0.7 // Default
-	
-	// Adjust based on task characteristics
-	if features.HasCode {
-		// Code tasks benefit from specialized models (favor quality)
-		baseAlpha += 0.1
-	}
-	
-	if features.HasMath {
-		// Math tasks need reasoning capabilities (strongly favor quality)
-		baseAlpha += 0.15
-	}
-	
-	if features.TokenCount > 50000 {
-		// Long context tasks need capable models (favor quality)
-		baseAlpha += 0.05
-	} else if features.TokenCount < 1000 {
-		// Short tasks can use cheaper models (favor cost)
-		baseAlpha -= 0.1
-	}
-	
-	if features.ContextRatio > 0.8 {
-		// High context utilization needs capable models
-		baseAlpha += 0.05
-	}
-	
-	// Clamp to reasonable range
-	return math.Max(0.1, math.Min(0.95, baseAlpha))
-}
\ No newline at end of file
+// credentialsOf joins the request headers that identify the caller
+func credentialsOf(headers map[string][]string) string {
+	return auth.HeaderValue(headers, "Authorization") + "|" +
+		auth.HeaderValue(headers, auth.HMACServiceHeader) + "|" +
+		auth.HeaderValue(headers, auth.HMACSignatureHeader)
+}
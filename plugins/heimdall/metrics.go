@@ -0,0 +1,253 @@
+package main
+
+import (
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Metrics is a point-in-time view of the plugin's counters and subsystem
+// stats, as returned by Plugin.Metrics and served at /metrics.
+//
+// Stability: within a major version fields are only added. A field is
+// never renamed, retyped or given a different meaning, and its JSON name
+// is kept. Fields of optional subsystems are nil, and omitted from JSON,
+// while the subsystem is disabled; enabled subsystems report empty values
+// rather than nil, so their slices and maps are pointers too. The nested
+// stats types follow the same rules.
+type Metrics struct {
+	RequestCount  int64 `json:"request_count"`
+	ErrorCount    int64 `json:"error_count"`
+	CacheHitCount int64 `json:"cache_hit_count"`
+	// EmptyPrompts counts requests with no prompt text
+	EmptyPrompts int64 `json:"empty_prompts"`
+	// CacheEntries is the number of cached decisions
+	CacheEntries int `json:"cache_entries"`
+
+	Implementations Implementations          `json:"implementations"`
+	ScoreSanity     scoring.ScoreSanityStats `json:"score_sanity"`
+	ScoreCache      scoring.ScoreCacheStats  `json:"score_cache"`
+	// Streams reports streamed responses per model
+	Streams               map[string]StreamStats   `json:"streams"`
+	ObservabilitySampling SamplingStats            `json:"observability_sampling"`
+	FallbackReasons       map[FallbackReason]int64 `json:"fallback_reasons"`
+	// FallbackNormalization counts fallback attempts rewritten for their
+	// provider, by provider kind
+	FallbackNormalization map[string]int64 `json:"fallback_normalization"`
+
+	// The artifact in use; empty before one is loaded
+	ArtifactVersion    string  `json:"artifact_version,omitempty"`
+	ArtifactAgeSeconds float64 `json:"artifact_age_seconds,omitempty"`
+
+	// Optional subsystems
+	PromptCache      *features.PrefixStats        `json:"prompt_cache,omitempty"`
+	TokenCalibration *map[string]TokenFactorStats `json:"token_calibration,omitempty"`
+	Cascade          *map[string]CascadeStats     `json:"cascade,omitempty"`
+	Judge            *JudgeStats                  `json:"judge,omitempty"`
+	Compare          *CompareStats                `json:"compare,omitempty"`
+	DualRun          *scoring.DualRunStats        `json:"dual_run,omitempty"`
+	ResponseCache    *ResponseCacheStats          `json:"response_cache,omitempty"`
+	Concurrency      *[]ConcurrencyStatus         `json:"concurrency,omitempty"`
+	Admission        *AdmissionStats              `json:"admission,omitempty"`
+	LoadShed         *LoadShedStats               `json:"load_shed,omitempty"`
+	CostAnomalies    *[]CostAnomaly               `json:"cost_anomalies,omitempty"`
+	LoopDetection    *LoopStats                   `json:"loop_detection,omitempty"`
+	BucketSmoothing  *BucketSmoothingStats        `json:"bucket_smoothing,omitempty"`
+	SizeLadder       *LadderStats                 `json:"size_ladder,omitempty"`
+	AlphaController  *AlphaControllerStatus       `json:"alpha_controller,omitempty"`
+	Synthetic        *map[string]SyntheticStats   `json:"synthetic,omitempty"`
+	Sessions         *SessionStats                `json:"sessions,omitempty"`
+	Digest           *DigestStats                 `json:"digest,omitempty"`
+}
+
+// Metrics returns the plugin's counters and subsystem stats
+func (p *Plugin) Metrics() Metrics {
+	p.metricsMu.RLock()
+	defer p.metricsMu.RUnlock()
+
+	metrics := Metrics{
+		RequestCount:          p.requestCount,
+		ErrorCount:            p.errorCount,
+		CacheHitCount:         p.cacheHitCount,
+		EmptyPrompts:          p.emptyPromptCount,
+		CacheEntries:          p.cache.Len(),
+		Implementations:       p.implementations,
+		ScoreSanity:           p.alphaScorer.SanityStats(),
+		ScoreCache:            p.alphaScorer.CacheStats(),
+		Streams:               p.streams.GetStats(),
+		ObservabilitySampling: p.sampler.GetStats(),
+		FallbackReasons:       make(map[FallbackReason]int64, len(p.fallbackCounts)),
+		FallbackNormalization: p.normalizer.GetStats(),
+	}
+	for reason, count := range p.fallbackCounts {
+		metrics.FallbackReasons[reason] = count
+	}
+
+	p.artifactMu.RLock()
+	if p.currentArtifact != nil {
+		metrics.ArtifactVersion = p.currentArtifact.Version
+		metrics.ArtifactAgeSeconds = p.now().Sub(p.lastArtifactLoad).Seconds()
+	}
+	p.artifactMu.RUnlock()
+
+	if p.prefixIndex != nil {
+		stats := p.prefixIndex.GetStats()
+		metrics.PromptCache = &stats
+	}
+	if p.tokens != nil {
+		stats := p.tokens.GetStats()
+		if stats == nil {
+			stats = map[string]TokenFactorStats{}
+		}
+		metrics.TokenCalibration = &stats
+	}
+	if p.config.Cascade.Enabled {
+		stats := p.cascade.snapshot()
+		if stats == nil {
+			stats = map[string]CascadeStats{}
+		}
+		metrics.Cascade = &stats
+	}
+	if p.judge != nil {
+		stats := p.judge.GetStats()
+		metrics.Judge = &stats
+	}
+	if p.comparer != nil {
+		stats := p.comparer.GetStats()
+		metrics.Compare = &stats
+	}
+	if p.dualRun != nil {
+		stats := p.dualRun.GetStats()
+		metrics.DualRun = &stats
+	}
+	if p.responses != nil {
+		stats := p.responses.GetStats()
+		metrics.ResponseCache = &stats
+	}
+	if p.concurrency != nil {
+		status := append([]ConcurrencyStatus{}, p.concurrency.GetStatus(p.inferProviderKind)...)
+		metrics.Concurrency = &status
+	}
+	if p.admission != nil {
+		stats := p.admission.GetStats()
+		metrics.Admission = &stats
+	}
+	if p.shedder != nil {
+		stats := p.shedder.GetStats()
+		metrics.LoadShed = &stats
+	}
+	if p.anomalies != nil {
+		anomalies := append([]CostAnomaly{}, p.anomalies.GetStatus()...)
+		metrics.CostAnomalies = &anomalies
+	}
+	if p.loops != nil {
+		stats := p.loops.GetStats()
+		metrics.LoopDetection = &stats
+	}
+	if p.smoother != nil {
+		stats := p.smoother.GetStats()
+		metrics.BucketSmoothing = &stats
+	}
+	if p.ladder != nil {
+		stats := p.ladder.GetStats()
+		metrics.SizeLadder = &stats
+	}
+	if p.alphaController != nil {
+		status := p.alphaController.GetStatus()
+		metrics.AlphaController = &status
+	}
+	if p.synthetic != nil {
+		stats := p.synthetic.GetStats()
+		if stats == nil {
+			stats = map[string]SyntheticStats{}
+		}
+		metrics.Synthetic = &stats
+	}
+	if p.sessions != nil {
+		stats := p.sessions.GetStats()
+		metrics.Sessions = &stats
+	}
+	if p.digester != nil {
+		stats := p.digester.GetStats()
+		metrics.Digest = &stats
+	}
+	return metrics
+}
+
+// Map returns the metrics keyed by their JSON names, with stats by value.
+// Disabled subsystems have no key.
+func (m Metrics) Map() map[string]interface{} {
+	metrics := map[string]interface{}{
+		"request_count":          m.RequestCount,
+		"error_count":            m.ErrorCount,
+		"cache_hit_count":        m.CacheHitCount,
+		"empty_prompts":          m.EmptyPrompts,
+		"cache_entries":          m.CacheEntries,
+		"implementations":        m.Implementations,
+		"score_sanity":           m.ScoreSanity,
+		"score_cache":            m.ScoreCache,
+		"streams":                m.Streams,
+		"observability_sampling": m.ObservabilitySampling,
+		"fallback_reasons":       m.FallbackReasons,
+		"fallback_normalization": m.FallbackNormalization,
+	}
+	if m.ArtifactVersion != "" {
+		metrics["artifact_version"] = m.ArtifactVersion
+		metrics["artifact_age_seconds"] = m.ArtifactAgeSeconds
+	}
+
+	if m.PromptCache != nil {
+		metrics["prompt_cache"] = *m.PromptCache
+	}
+	if m.TokenCalibration != nil {
+		metrics["token_calibration"] = *m.TokenCalibration
+	}
+	if m.Cascade != nil {
+		metrics["cascade"] = *m.Cascade
+	}
+	if m.Judge != nil {
+		metrics["judge"] = *m.Judge
+	}
+	if m.Compare != nil {
+		metrics["compare"] = *m.Compare
+	}
+	if m.DualRun != nil {
+		metrics["dual_run"] = *m.DualRun
+	}
+	if m.ResponseCache != nil {
+		metrics["response_cache"] = *m.ResponseCache
+	}
+	if m.Concurrency != nil {
+		metrics["concurrency"] = *m.Concurrency
+	}
+	if m.Admission != nil {
+		metrics["admission"] = *m.Admission
+	}
+	if m.LoadShed != nil {
+		metrics["load_shed"] = *m.LoadShed
+	}
+	if m.CostAnomalies != nil {
+		metrics["cost_anomalies"] = *m.CostAnomalies
+	}
+	if m.LoopDetection != nil {
+		metrics["loop_detection"] = *m.LoopDetection
+	}
+	if m.BucketSmoothing != nil {
+		metrics["bucket_smoothing"] = *m.BucketSmoothing
+	}
+	if m.SizeLadder != nil {
+		metrics["size_ladder"] = *m.SizeLadder
+	}
+	if m.AlphaController != nil {
+		metrics["alpha_controller"] = *m.AlphaController
+	}
+	if m.Synthetic != nil {
+		metrics["synthetic"] = *m.Synthetic
+	}
+	if m.Sessions != nil {
+		metrics["sessions"] = *m.Sessions
+	}
+	if m.Digest != nil {
+		metrics["digest"] = *m.Digest
+	}
+	return metrics
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("should report optional subsystems only while enabled", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		metrics := plugin.Metrics()
+		assert.Nil(t, metrics.LoopDetection)
+		assert.Nil(t, metrics.CostAnomalies)
+		assert.NotNil(t, metrics.FallbackReasons)
+
+		config := createRouterTestConfig()
+		config.LoopDetection = LoopConfig{Enabled: true}
+		config.CostAnomaly = CostAnomalyConfig{Enabled: true}
+		config.Sessions = SessionConfig{Enabled: true}
+		plugin = createRouterTestPluginWithConfig(t, config)
+		metrics = plugin.Metrics()
+		require.NotNil(t, metrics.LoopDetection)
+		require.NotNil(t, metrics.Sessions)
+		assert.NotNil(t, metrics.CostAnomalies, "enabled subsystems report empty values")
+	})
+
+	t.Run("should match the map form", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.LoopDetection = LoopConfig{Enabled: true}
+		config.CostAnomaly = CostAnomalyConfig{Enabled: true}
+		config.Sessions = SessionConfig{Enabled: true}
+		plugin := createRouterTestPluginWithConfig(t, config)
+		plugin.emptyPromptCount = 3
+
+		metrics := plugin.Metrics()
+		mapped := metrics.Map()
+		assert.Equal(t, int64(3), mapped["empty_prompts"])
+		assert.Equal(t, *metrics.LoopDetection, mapped["loop_detection"].(LoopStats), "stats are mapped by value")
+		assert.NotContains(t, mapped, "size_ladder")
+
+		typed, err := json.Marshal(metrics)
+		require.NoError(t, err)
+		untyped, err := json.Marshal(mapped)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(untyped), string(typed))
+		assert.Contains(t, string(typed), `"cost_anomalies":[]`, "enabled subsystems are serialized while empty")
+	})
+}
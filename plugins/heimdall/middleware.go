@@ -0,0 +1,22 @@
+package main
+
+import "context"
+
+// DecideFunc makes a routing decision for a request. ctx is the Bifrost
+// request context seen by PreHook.
+type DecideFunc func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error)
+
+// Middleware wraps a DecideFunc with a cross-cutting concern such as rate
+// limiting, policy checks, logging or experiments. A middleware may
+// short-circuit by returning without calling next, or adjust the request
+// before and the response after it. Returning a RejectionError rejects the
+// request with that error; other errors fall back to a default route.
+type Middleware func(next DecideFunc) DecideFunc
+
+// chainMiddleware wraps decide so the first middleware is outermost
+func chainMiddleware(decide DecideFunc, middleware []Middleware) DecideFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		decide = middleware[i](decide)
+	}
+	return decide
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecideMiddleware(t *testing.T) {
+	newPlugin := func(t *testing.T, middleware ...Middleware) *Plugin {
+		plugin, err := NewWithOptions(createRouterTestConfig(), WithMiddleware(middleware...))
+		require.NoError(t, err)
+		plugin.currentArtifact = &AvengersArtifact{Version: "test-1.0.0", Alpha: 0.7}
+		plugin.lastArtifactLoad = time.Now()
+		return plugin
+	}
+	// tracing records the order middleware run in
+	tracing := func(name string, trace *[]string) Middleware {
+		return func(next DecideFunc) DecideFunc {
+			return func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+				*trace = append(*trace, name+" before")
+				response, err := next(ctx, req, headers)
+				*trace = append(*trace, name+" after")
+				return response, err
+			}
+		}
+	}
+
+	t.Run("should run middleware outermost first", func(t *testing.T) {
+		var trace []string
+		plugin := newPlugin(t, tracing("outer", &trace), tracing("inner", &trace))
+
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		assert.Nil(t, shortCircuit)
+		assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, trace)
+	})
+
+	t.Run("should pass the request context and adjust the decision", func(t *testing.T) {
+		var tenant interface{}
+		plugin := newPlugin(t, func(next DecideFunc) DecideFunc {
+			return func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+				tenant = ctx.Value("tenant")
+				response, err := next(ctx, req, headers)
+				if err == nil {
+					response.Decision.Model = "openai/gpt-4o-mini"
+				}
+				return response, err
+			}
+		})
+
+		ctx := context.WithValue(context.Background(), "tenant", "acme")
+		result, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "acme", tenant)
+		assert.Equal(t, "openai/gpt-4o-mini", result.Model)
+	})
+
+	t.Run("should short-circuit without calling the rest of the chain", func(t *testing.T) {
+		var trace []string
+		limiter := func(next DecideFunc) DecideFunc {
+			return func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+				return nil, fmt.Errorf("rate limited")
+			}
+		}
+		plugin := newPlugin(t, limiter, tracing("inner", &trace))
+
+		ctx := context.Background()
+		_, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, trace)
+		assert.Contains(t, ctx.Value("heimdall_error"), "rate limited")
+	})
+
+	t.Run("should run for cached decisions too", func(t *testing.T) {
+		var trace []string
+		plugin := newPlugin(t, tracing("outer", &trace))
+		route := func() context.Context {
+			content := "What is the capital of France?"
+			ctx := context.Background()
+			_, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+				ChatCompletionInput: &[]schemas.BifrostMessage{{
+					Role:    schemas.ModelChatMessageRoleUser,
+					Content: schemas.MessageContent{ContentStr: &content},
+				}},
+			}})
+			require.NoError(t, err)
+			return ctx
+		}
+
+		route()
+		ctx := route()
+		assert.Equal(t, true, ctx.Value("heimdall_cache_hit"))
+		assert.Equal(t, []string{"outer before", "outer after", "outer before", "outer after"}, trace)
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RouterModelPrefix marks a model string carrying routing hints, so any
+// OpenAI SDK can express routing preferences without custom headers, e.g.
+// model="router:quality>=0.8,cost<=mid" or model="router:fast"
+const RouterModelPrefix = "router:"
+
+// profileNamePattern is what a bare hint term must look like to name a
+// profile
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// parseModelHints parses the comma-separated terms of a hint string into a
+// policy. A term is a constraint:
+//
+//	quality>=<0-1>             minimum artifact quality on the request's cluster
+//	cost<=cheap|mid|hard       highest bucket
+//	price<=<n>                 provider max price budget
+//
+// or, when profiles is set, the name of a profile. Constraints may appear
+// once each; a profile and the constraints next to it all apply.
+func parseModelHints(hints string, profiles map[string]*RoutingPolicy) (*RoutingPolicy, error) {
+	if strings.TrimSpace(hints) == "" {
+		return nil, fmt.Errorf("no routing hints")
+	}
+
+	policy := &RoutingPolicy{}
+	var profile *RoutingPolicy
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(hints, ",") {
+		term = strings.TrimSpace(term)
+		key, value, op := cutConstraint(term)
+		if op == "" {
+			if profiles == nil {
+				return nil, fmt.Errorf("%q is not a constraint", term)
+			}
+			if !profileNamePattern.MatchString(term) {
+				return nil, fmt.Errorf("%q is neither a constraint nor a profile name", term)
+			}
+			if profile != nil {
+				return nil, fmt.Errorf("only one profile may be named, got %q after another", term)
+			}
+			if profile = profiles[term]; profile == nil {
+				return nil, fmt.Errorf("unknown profile %q", term)
+			}
+			continue
+		}
+
+		if seen[key] {
+			return nil, fmt.Errorf("%s is constrained more than once", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "quality":
+			if op != ">=" {
+				return nil, fmt.Errorf("quality takes >=, got %s", op)
+			}
+			quality, err := strconv.ParseFloat(value, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				return nil, fmt.Errorf("quality must be a number within [0, 1], got %q", value)
+			}
+			policy.MinQuality = quality
+		case "cost":
+			if op != "<=" {
+				return nil, fmt.Errorf("cost takes <=, got %s", op)
+			}
+			bucket := Bucket(value)
+			if bucket != BucketCheap && bucket != BucketMid && bucket != BucketHard {
+				return nil, fmt.Errorf("cost must be cheap, mid or hard, got %q", value)
+			}
+			policy.MaxBucket = bucket
+		case "price":
+			if op != "<=" {
+				return nil, fmt.Errorf("price takes <=, got %s", op)
+			}
+			price, err := strconv.Atoi(value)
+			if err != nil || price <= 0 {
+				return nil, fmt.Errorf("price must be a positive integer, got %q", value)
+			}
+			policy.MaxPrice = price
+		default:
+			return nil, fmt.Errorf("unknown constraint %q", key)
+		}
+	}
+	return profile.Restrict(policy), nil
+}
+
+// cutConstraint splits a term at its comparison operator; op is empty when
+// the term has none
+func cutConstraint(term string) (key, value, op string) {
+	for _, candidate := range []string{">=", "<=", "=", ">", "<"} {
+		if i := strings.Index(term, candidate); i >= 0 {
+			return strings.TrimSpace(term[:i]), strings.TrimSpace(term[i+len(candidate):]), candidate
+		}
+	}
+	return "", "", ""
+}
+
+// parseRouterProfiles parses the configured profiles, each a hint string
+// of constraints
+func parseRouterProfiles(profiles map[string]string) (map[string]*RoutingPolicy, error) {
+	parsed := make(map[string]*RoutingPolicy, len(profiles))
+	for name, hints := range profiles {
+		if !profileNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid profile name %q", name)
+		}
+		policy, err := parseModelHints(hints, nil)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		parsed[name] = policy
+	}
+	return parsed, nil
+}
+
+// applyModelHints tightens the caller's policy by the hints in a
+// "router:" model string. Hints can only restrict routing, never loosen a
+// caller's policy. Malformed hints are rejected with 400.
+func (p *Plugin) applyModelHints(body *RequestBody, authInfo *AuthInfo) (*AuthInfo, error) {
+	if body == nil || !strings.HasPrefix(body.Model, RouterModelPrefix) {
+		return authInfo, nil
+	}
+	hints, err := parseModelHints(strings.TrimPrefix(body.Model, RouterModelPrefix), p.profiles)
+	if err != nil {
+		rejection := NewInvalidRequestError("invalid_routing_hints", "invalid model "+strconv.Quote(body.Model)+": "+err.Error())
+		rejection.Param = "model"
+		return nil, rejection
+	}
+
+	hinted := &AuthInfo{Type: "anonymous"}
+	if authInfo != nil {
+		copied := *authInfo
+		hinted = &copied
+	}
+	hinted.Policy = hinted.Policy.Restrict(hints)
+	return hinted, nil
+}
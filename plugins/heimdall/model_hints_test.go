@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModelHints(t *testing.T) {
+	profiles := map[string]*RoutingPolicy{"fast": {MaxBucket: BucketCheap}}
+
+	t.Run("should parse constraints", func(t *testing.T) {
+		policy, err := parseModelHints("quality>=0.8, cost<=mid,price<=20", nil)
+		require.NoError(t, err)
+		assert.Equal(t, &RoutingPolicy{MinQuality: 0.8, MaxBucket: BucketMid, MaxPrice: 20}, policy)
+	})
+
+	t.Run("should apply profiles with the constraints next to them", func(t *testing.T) {
+		policy, err := parseModelHints("fast", profiles)
+		require.NoError(t, err)
+		assert.Equal(t, &RoutingPolicy{MaxBucket: BucketCheap}, policy)
+
+		policy, err = parseModelHints("fast,quality>=0.7,cost<=hard", profiles)
+		require.NoError(t, err)
+		assert.Equal(t, &RoutingPolicy{MaxBucket: BucketCheap, MinQuality: 0.7}, policy, "constraints cannot loosen a profile")
+	})
+
+	t.Run("should reject malformed hints", func(t *testing.T) {
+		for hints, message := range map[string]string{
+			"":                      "no routing hints",
+			"quality>=1.5":          "quality must be a number within [0, 1]",
+			"quality<=0.5":          "quality takes >=",
+			"cost<=premium":         "cost must be cheap, mid or hard",
+			"price<=-1":             "price must be a positive integer",
+			"latency<=200":          `unknown constraint "latency"`,
+			"cost<=mid,cost<=cheap": "cost is constrained more than once",
+			"slow":                  `unknown profile "slow"`,
+			"fast,fast":             "only one profile may be named",
+			"Fast Lane":             "neither a constraint nor a profile name",
+		} {
+			_, err := parseModelHints(hints, profiles)
+			assert.ErrorContains(t, err, message, hints)
+		}
+	})
+
+	t.Run("should reject invalid profiles at startup", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.RouterProfiles = map[string]string{"fast": "cost<=cheap", "nested": "fast"}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid router profiles")
+	})
+}
+
+func TestModelHints(t *testing.T) {
+	config := createRouterTestConfig()
+	config.RouterProfiles = map[string]string{"fast": "cost<=cheap"}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	request := func(model string) *schemas.BifrostRequest {
+		content := "Prove that there are infinitely many primes and analyse the complexity of the sieve."
+		return &schemas.BifrostRequest{Model: model, Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}}
+	}
+
+	t.Run("should route within the hints", func(t *testing.T) {
+		for _, model := range []string{"router:cost<=cheap", "router:fast"} {
+			ctx := context.Background()
+			result, shortCircuit, err := plugin.PreHook(&ctx, request(model))
+			require.NoError(t, err)
+			require.Nil(t, shortCircuit)
+			assert.Equal(t, BucketCheap, ctx.Value("heimdall_bucket"), model)
+			assert.Contains(t, config.Router.CheapCandidates, result.Model)
+		}
+	})
+
+	t.Run("should answer malformed hints with a structured 400", func(t *testing.T) {
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, request("router:cost<=premium"))
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit)
+		assert.Equal(t, http.StatusBadRequest, *shortCircuit.Error.StatusCode)
+		assert.Equal(t, "invalid_routing_hints", *shortCircuit.Error.Error.Code)
+		assert.Equal(t, "model", shortCircuit.Error.Error.Param)
+
+		rejection := ctx.Value("heimdall_rejection").(*RejectionError)
+		require.NotNil(t, rejection.Body().Error.Param)
+		assert.Equal(t, "model", *rejection.Body().Error.Param)
+	})
+
+	t.Run("should only tighten the caller's policy", func(t *testing.T) {
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{MaxBucket: BucketMid, MaxPrice: 5}}
+		hinted, err := plugin.applyModelHints(&RequestBody{Model: "router:cost<=hard,price<=50,quality>=0.5"}, authInfo)
+		require.NoError(t, err)
+		assert.Equal(t, &RoutingPolicy{MaxBucket: BucketMid, MaxPrice: 5, MinQuality: 0.5}, hinted.Policy)
+		assert.Equal(t, &RoutingPolicy{MaxBucket: BucketMid, MaxPrice: 5}, authInfo.Policy, "the caller's policy is not modified")
+
+		unhinted, err := plugin.applyModelHints(&RequestBody{Model: "gpt-4o"}, authInfo)
+		require.NoError(t, err)
+		assert.Same(t, authInfo, unhinted)
+	})
+
+	t.Run("should select only models meeting the quality floor", func(t *testing.T) {
+		features := &RequestFeatures{ClusterID: 0}
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{MinQuality: 0.78}}
+		decision, err := plugin.selectModel(BucketCheap, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Equal(t, "deepseek/deepseek-r1", decision.Model)
+		assert.NotContains(t, decision.Fallbacks, "qwen/qwen-2.5-coder-32b-instruct")
+
+		authInfo.Policy.MinQuality = 0.9
+		_, err = plugin.selectModel(BucketCheap, features, authInfo, false)
+		var rejection *RejectionError
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, ErrorTypePermission, rejection.Type)
+	})
+
+	t.Run("should hold the Anthropic shortcut to the quality floor and price cap", func(t *testing.T) {
+		features := &RequestFeatures{ClusterID: 0}
+		authInfo := &AuthInfo{Provider: "anthropic", Type: "bearer", Policy: &RoutingPolicy{MinQuality: 0.8, MaxPrice: 20}}
+		decision, err := plugin.selectModel(BucketMid, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic", decision.Kind)
+		assert.Equal(t, 20, decision.ProviderPrefs.MaxPrice)
+
+		authInfo.Policy.MinQuality = 0.88
+		decision, err = plugin.selectModel(BucketMid, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/gpt-4o", decision.Model, "sonnet's 0.85 is below the floor")
+		assert.Equal(t, 20, decision.ProviderPrefs.MaxPrice)
+	})
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// FallbackNormalizationConfig extends the rewrites applied to fallback
+// attempts. Bifrost passes parameters through to providers as they are,
+// so a request the primary's provider accepted can be rejected by a
+// fallback on another provider, e.g. top_k by OpenAI or presence_penalty
+// by Anthropic.
+type FallbackNormalizationConfig struct {
+	// Drop lists extra parameters each provider kind rejects; they are
+	// added to the built-in lists
+	Drop map[string][]string `json:"drop"`
+}
+
+// defaultDroppedParams are extra parameters, specific to one provider,
+// that the others reject
+var defaultDroppedParams = map[string][]string{
+	"openai":    {"top_k", "thinking", "anthropic_beta", "safety_settings", "generation_config"},
+	"anthropic": {"reasoning_effort", "response_format", "seed", "logit_bias", "logprobs", "top_logprobs", "n", "service_tier", "store", "prediction", "modalities", "safety_settings", "generation_config"},
+	"google":    {"logit_bias", "service_tier", "store", "prediction", "thinking", "anthropic_beta"},
+}
+
+// anthropicToolIDPattern is what Anthropic accepts as a tool use ID
+var anthropicToolIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// FallbackNormalizer rewrites the parameters and messages of fallback
+// attempts into the form their provider accepts
+type FallbackNormalizer struct {
+	drop map[string]map[string]bool
+
+	normalized map[string]int64 // fallback attempts rewritten, by provider kind
+	mu         sync.Mutex
+}
+
+// NewFallbackNormalizer merges the configured drop lists over the defaults
+func NewFallbackNormalizer(config FallbackNormalizationConfig) (*FallbackNormalizer, error) {
+	drop := make(map[string]map[string]bool)
+	for _, lists := range []map[string][]string{defaultDroppedParams, config.Drop} {
+		for kind, params := range lists {
+			if kind == "" {
+				return nil, fmt.Errorf("drop list without a provider kind")
+			}
+			if drop[kind] == nil {
+				drop[kind] = make(map[string]bool)
+			}
+			for _, param := range params {
+				if param == "" {
+					return nil, fmt.Errorf("empty parameter name in drop list for %s", kind)
+				}
+				drop[kind][param] = true
+			}
+		}
+	}
+	return &FallbackNormalizer{
+		drop:       drop,
+		normalized: make(map[string]int64),
+	}, nil
+}
+
+// Normalize rewrites req for a provider kind and describes each change.
+// The params and messages are copied before they are changed, since
+// Bifrost shares them between the attempts of a request.
+func (fn *FallbackNormalizer) Normalize(kind string, req *schemas.BifrostRequest) []string {
+	var changes []string
+	if req.Params != nil {
+		params := *req.Params
+		params.ExtraParams = maps.Clone(req.Params.ExtraParams)
+		if changes = fn.normalizeParams(kind, &params); len(changes) > 0 {
+			req.Params = &params
+		}
+	}
+	if req.Input.ChatCompletionInput != nil {
+		messages, messageChanges := normalizeMessages(kind, *req.Input.ChatCompletionInput)
+		if len(messageChanges) > 0 {
+			req.Input.ChatCompletionInput = &messages
+			changes = append(changes, messageChanges...)
+		}
+	}
+
+	if len(changes) > 0 {
+		fn.mu.Lock()
+		fn.normalized[kind]++
+		fn.mu.Unlock()
+	}
+	return changes
+}
+
+// normalizeParams rewrites params, which the caller owns, for a provider
+// kind
+func (fn *FallbackNormalizer) normalizeParams(kind string, params *schemas.ModelParameters) []string {
+	var changes []string
+	switch kind {
+	case "openai":
+		if params.TopK != nil {
+			params.TopK = nil
+			changes = append(changes, "dropped top_k")
+		}
+		// OpenAI names stop sequences "stop"
+		if params.StopSequences != nil {
+			if _, ok := params.ExtraParams["stop"]; !ok {
+				params.ExtraParams = setParam(params.ExtraParams, "stop", *params.StopSequences)
+			}
+			params.StopSequences = nil
+			changes = append(changes, "renamed stop_sequences to stop")
+		}
+	case "anthropic":
+		if params.PresencePenalty != nil || params.FrequencyPenalty != nil {
+			params.PresencePenalty, params.FrequencyPenalty = nil, nil
+			changes = append(changes, "dropped penalties")
+		}
+		if params.ParallelToolCalls != nil {
+			params.ParallelToolCalls = nil
+			changes = append(changes, "dropped parallel_tool_calls")
+		}
+		if params.Temperature != nil && *params.Temperature > 1 {
+			temperature := 1.0
+			params.Temperature = &temperature
+			changes = append(changes, "capped temperature at 1")
+		}
+		if value, ok := params.ExtraParams["max_completion_tokens"]; ok {
+			if tokens, ok := intParam(value); ok && params.MaxTokens == nil {
+				params.MaxTokens = &tokens
+			}
+			delete(params.ExtraParams, "max_completion_tokens")
+			changes = append(changes, "renamed max_completion_tokens to max_tokens")
+		}
+		if value, ok := params.ExtraParams["stop"]; ok {
+			if stops, ok := stringsParam(value); ok && params.StopSequences == nil {
+				params.StopSequences = &stops
+			}
+			delete(params.ExtraParams, "stop")
+			changes = append(changes, "renamed stop to stop_sequences")
+		}
+	}
+
+	var dropped []string
+	for param := range params.ExtraParams {
+		if fn.drop[kind][param] {
+			delete(params.ExtraParams, param)
+			dropped = append(dropped, param)
+		}
+	}
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		changes = append(changes, "dropped "+strings.Join(dropped, ", "))
+	}
+	return changes
+}
+
+// normalizeMessages returns the messages rewritten for a provider kind,
+// sharing those left unchanged
+func normalizeMessages(kind string, messages []schemas.BifrostMessage) ([]schemas.BifrostMessage, []string) {
+	var changes []string
+	switch kind {
+	case "openai":
+		// Reasoning traces from other providers are not OpenAI message fields
+		var cleared bool
+		normalized := make([]schemas.BifrostMessage, len(messages))
+		for i, message := range messages {
+			if message.AssistantMessage != nil && message.Thought != nil {
+				assistant := *message.AssistantMessage
+				assistant.Thought = nil
+				message.AssistantMessage = &assistant
+				cleared = true
+			}
+			normalized[i] = message
+		}
+		if cleared {
+			changes = append(changes, "dropped assistant thoughts")
+		}
+		return normalized, changes
+
+	case "anthropic":
+		var emptied, renamed bool
+		normalized := make([]schemas.BifrostMessage, 0, len(messages))
+		for i, message := range messages {
+			// Anthropic rejects empty messages other than a final assistant turn
+			if i < len(messages)-1 && emptyMessage(message) {
+				emptied = true
+				continue
+			}
+			if message.AssistantMessage != nil && message.ToolCalls != nil {
+				calls := append([]schemas.ToolCall{}, *message.ToolCalls...)
+				for j := range calls {
+					if id, ok := anthropicToolID(calls[j].ID); ok {
+						calls[j].ID = &id
+						renamed = true
+					}
+				}
+				assistant := *message.AssistantMessage
+				assistant.ToolCalls = &calls
+				message.AssistantMessage = &assistant
+			}
+			if message.ToolMessage != nil {
+				if id, ok := anthropicToolID(message.ToolCallID); ok {
+					message.ToolMessage = &schemas.ToolMessage{ToolCallID: &id}
+					renamed = true
+				}
+			}
+			normalized = append(normalized, message)
+		}
+		if emptied {
+			changes = append(changes, "dropped empty messages")
+		}
+		if renamed {
+			changes = append(changes, "rewrote tool call IDs")
+		}
+		return normalized, changes
+	}
+	return messages, nil
+}
+
+// emptyMessage reports whether a message carries neither text, content
+// blocks nor tool calls. Tool results are kept, empty or not, to stay
+// paired with their calls.
+func emptyMessage(message schemas.BifrostMessage) bool {
+	if message.ToolMessage != nil {
+		return false
+	}
+	if message.Content.ContentStr != nil && strings.TrimSpace(*message.Content.ContentStr) != "" {
+		return false
+	}
+	if message.Content.ContentBlocks != nil && len(*message.Content.ContentBlocks) > 0 {
+		return false
+	}
+	return message.AssistantMessage == nil || message.ToolCalls == nil || len(*message.ToolCalls) == 0
+}
+
+// anthropicToolID returns a tool call ID Anthropic accepts, and whether it
+// differs from id. The same ID always maps to the same replacement, so
+// calls and their results stay paired.
+func anthropicToolID(id *string) (string, bool) {
+	if id == nil || !anthropicToolIDPattern.MatchString(*id) {
+		return "", false
+	}
+	return anthropicToolIDPattern.ReplaceAllString(*id, "_"), true
+}
+
+// stringsParam reads OpenAI's stop parameter, a string or list of strings
+func stringsParam(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		stops := make([]string, 0, len(v))
+		for _, item := range v {
+			stop, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			stops = append(stops, stop)
+		}
+		return stops, true
+	}
+	return nil, false
+}
+
+// GetStats returns the fallback attempts rewritten, by provider kind
+func (fn *FallbackNormalizer) GetStats() map[string]int64 {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	return maps.Clone(fn.normalized)
+}
+
+// normalizeFallback rewrites a fallback attempt for the provider it is
+// sent to
+func (p *Plugin) normalizeFallback(req *schemas.BifrostRequest) {
+	kind := normalizationKind(req.Provider)
+	if changes := p.normalizer.Normalize(kind, req); len(changes) > 0 {
+		p.logger.Printf("Normalized fallback to %s: %s", req.Model, strings.Join(changes, "; "))
+	}
+}
+
+// normalizationKind returns the API format a Bifrost provider takes. It
+// follows the provider rather than the model's name, since OpenRouter and
+// self-hosted endpoints serve every family in OpenAI's format.
+func normalizationKind(provider schemas.ModelProvider) string {
+	switch provider {
+	case schemas.Anthropic:
+		return "anthropic"
+	case "google", schemas.Vertex:
+		return "google"
+	default:
+		return "openai"
+	}
+}
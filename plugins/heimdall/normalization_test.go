@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackNormalizer(t *testing.T) {
+	fn, err := NewFallbackNormalizer(FallbackNormalizationConfig{Drop: map[string][]string{"anthropic": {"user_tier"}}})
+	require.NoError(t, err)
+
+	float := func(v float64) *float64 { return &v }
+	text := func(v string) schemas.MessageContent { return schemas.MessageContent{ContentStr: &v} }
+	id := func(v string) *string { return &v }
+
+	t.Run("should rewrite OpenAI parameters for Anthropic", func(t *testing.T) {
+		params := &schemas.ModelParameters{
+			Temperature:       float(1.4),
+			PresencePenalty:   float(0.5),
+			ParallelToolCalls: new(bool),
+			ExtraParams: map[string]interface{}{
+				"max_completion_tokens": float64(512),
+				"stop":                  []interface{}{"END"},
+				"seed":                  float64(7),
+				"user_tier":             "gold",
+				"metadata":              map[string]interface{}{"user_id": "u-1"},
+			},
+		}
+		req := &schemas.BifrostRequest{Model: "anthropic/claude-3.5-sonnet", Params: params}
+
+		changes := fn.Normalize("anthropic", req)
+		assert.Contains(t, changes, "dropped seed, user_tier")
+		assert.Nil(t, req.Params.PresencePenalty)
+		assert.Nil(t, req.Params.ParallelToolCalls)
+		assert.Equal(t, 1.0, *req.Params.Temperature)
+		assert.Equal(t, 512, *req.Params.MaxTokens)
+		assert.Equal(t, []string{"END"}, *req.Params.StopSequences)
+		assert.Equal(t, map[string]interface{}{"metadata": map[string]interface{}{"user_id": "u-1"}}, req.Params.ExtraParams)
+
+		assert.Equal(t, 1.4, *params.Temperature, "the primary's params are not modified")
+		assert.Contains(t, params.ExtraParams, "seed")
+	})
+
+	t.Run("should rewrite Anthropic parameters for OpenAI", func(t *testing.T) {
+		topK := 40
+		req := &schemas.BifrostRequest{Params: &schemas.ModelParameters{
+			TopK:          &topK,
+			StopSequences: &[]string{"\n\nHuman:"},
+			ExtraParams:   map[string]interface{}{"thinking": map[string]interface{}{"type": "enabled"}},
+		}}
+
+		fn.Normalize("openai", req)
+		assert.Nil(t, req.Params.TopK)
+		assert.Nil(t, req.Params.StopSequences)
+		assert.Equal(t, map[string]interface{}{"stop": []string{"\n\nHuman:"}}, req.Params.ExtraParams)
+	})
+
+	t.Run("should rewrite messages for Anthropic", func(t *testing.T) {
+		messages := []schemas.BifrostMessage{
+			{Role: schemas.ModelChatMessageRoleUser, Content: text("What is the weather in Paris?")},
+			{Role: schemas.ModelChatMessageRoleAssistant, Content: text(""), AssistantMessage: &schemas.AssistantMessage{
+				ToolCalls: &[]schemas.ToolCall{{ID: id("call:weather.1"), Function: schemas.FunctionCall{Name: id("weather")}}},
+			}},
+			{Role: schemas.ModelChatMessageRoleTool, Content: text("18C"), ToolMessage: &schemas.ToolMessage{ToolCallID: id("call:weather.1")}},
+			{Role: schemas.ModelChatMessageRoleAssistant, Content: text(" ")},
+			{Role: schemas.ModelChatMessageRoleUser, Content: text("And tomorrow?")},
+		}
+		req := &schemas.BifrostRequest{Input: schemas.RequestInput{ChatCompletionInput: &messages}}
+
+		changes := fn.Normalize("anthropic", req)
+		assert.Equal(t, []string{"dropped empty messages", "rewrote tool call IDs"}, changes)
+		normalized := *req.Input.ChatCompletionInput
+		require.Len(t, normalized, 4)
+		assert.Equal(t, "call_weather_1", *(*normalized[1].ToolCalls)[0].ID)
+		assert.Equal(t, "call_weather_1", *normalized[2].ToolCallID)
+		assert.Equal(t, "call:weather.1", *(*messages[1].ToolCalls)[0].ID, "the primary's messages are not modified")
+	})
+
+	t.Run("should drop reasoning traces for OpenAI", func(t *testing.T) {
+		messages := []schemas.BifrostMessage{
+			{Role: schemas.ModelChatMessageRoleAssistant, Content: text("42"), AssistantMessage: &schemas.AssistantMessage{Thought: id("6 times 7")}},
+		}
+		req := &schemas.BifrostRequest{Input: schemas.RequestInput{ChatCompletionInput: &messages}}
+		fn.Normalize("openai", req)
+		assert.Nil(t, (*req.Input.ChatCompletionInput)[0].Thought)
+		assert.NotNil(t, messages[0].Thought)
+	})
+
+	t.Run("should leave requests the provider accepts alone", func(t *testing.T) {
+		params := &schemas.ModelParameters{Temperature: float(0.2)}
+		req := &schemas.BifrostRequest{Params: params}
+		assert.Empty(t, fn.Normalize("openrouter", req))
+		assert.Empty(t, fn.Normalize("anthropic", req))
+		assert.Same(t, params, req.Params)
+	})
+
+	t.Run("should reject malformed drop lists", func(t *testing.T) {
+		_, err := NewFallbackNormalizer(FallbackNormalizationConfig{Drop: map[string][]string{"openai": {""}}})
+		assert.ErrorContains(t, err, "empty parameter name")
+	})
+}
+
+func TestFallbackNormalization(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+
+	content := "What is the capital of France?"
+	penalty := 0.5
+	req := &schemas.BifrostRequest{
+		Params: &schemas.ModelParameters{PresencePenalty: &penalty},
+		Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+			Role:    schemas.ModelChatMessageRoleUser,
+			Content: schemas.MessageContent{ContentStr: &content},
+		}}},
+	}
+	ctx := context.Background()
+	routed, _, err := plugin.PreHook(&ctx, req)
+	require.NoError(t, err)
+
+	var attempt *schemas.BifrostRequest
+	for _, fallback := range routed.Fallbacks {
+		if plugin.inferProviderKind(fallback.Model) == "anthropic" {
+			copied := *routed
+			copied.Provider, copied.Model = fallback.Provider, fallback.Model
+			attempt = &copied
+		}
+	}
+	require.NotNil(t, attempt, "the decision falls back to an Anthropic model")
+
+	ctx = context.Background()
+	result, shortCircuit, err := plugin.PreHook(&ctx, attempt)
+	require.NoError(t, err)
+	require.Nil(t, shortCircuit)
+	assert.Nil(t, result.Params.PresencePenalty)
+	assert.NotNil(t, routed.Params.PresencePenalty, "the routed request is not modified")
+	assert.Equal(t, int64(1), plugin.Metrics().FallbackNormalization["anthropic"])
+}
+
+func TestFallbackNormalizationByProvider(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Router.MidCandidates = append(config.Router.MidCandidates, "mistralai/mistral-large")
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	content := "What is the capital of France?"
+	topK := 40
+	req := &schemas.BifrostRequest{
+		Params: &schemas.ModelParameters{TopK: &topK},
+		Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+			Role:    schemas.ModelChatMessageRoleUser,
+			Content: schemas.MessageContent{ContentStr: &content},
+		}}},
+	}
+	ctx := context.Background()
+	routed, _, err := plugin.PreHook(&ctx, req)
+	require.NoError(t, err)
+
+	var attempt *schemas.BifrostRequest
+	for _, fallback := range routed.Fallbacks {
+		if fallback.Provider == "openrouter" {
+			copied := *routed
+			copied.Provider, copied.Model = fallback.Provider, fallback.Model
+			attempt = &copied
+		}
+	}
+	require.NotNil(t, attempt, "the decision falls back through OpenRouter")
+
+	ctx = context.Background()
+	result, shortCircuit, err := plugin.PreHook(&ctx, attempt)
+	require.NoError(t, err)
+	require.Nil(t, shortCircuit)
+	assert.Nil(t, result.Params.TopK, "OpenRouter takes OpenAI's parameters")
+	assert.Equal(t, int64(1), plugin.Metrics().FallbackNormalization["openai"])
+
+	assert.Equal(t, "openai", normalizationKind("groq"))
+	assert.Equal(t, "anthropic", normalizationKind(schemas.Anthropic))
+	assert.Equal(t, "google", normalizationKind(schemas.Vertex))
+}
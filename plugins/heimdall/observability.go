@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+)
+
+// ObservabilityConfig sets how often each observability signal is kept, so
+// high-volume deployments can control its cost. Each routed request draws
+// once; a signal is kept when the draw falls below its rate, so a request
+// kept at a low rate is also kept for every signal with a higher one. The
+// outcome is in the request context under "heimdall_sampled".
+type ObservabilityConfig struct {
+	// Default applies to every tenant; unset rates use the defaults below
+	Default SampleRates `json:"default"`
+	// Tenants override rates by tenant (organization, or API key
+	// fingerprint without one); unset rates inherit from Default
+	Tenants map[string]SampleRates `json:"tenants"`
+}
+
+// SampleRates are the fractions of requests each signal is kept for
+type SampleRates struct {
+	// Logs are per-request routing log lines (default 1 with
+	// enable_observability, else 0)
+	Logs *float64 `json:"logs,omitempty"`
+	// Decisions are decision log records; check Sampled.Decisions before
+	// writing one with EncodeDecisionLog (default 1)
+	Decisions *float64 `json:"decisions,omitempty"`
+	// Traces are left to tracing plugins reading Sampled.Traces (default 1)
+	Traces *float64 `json:"traces,omitempty"`
+	// Debug captures log the full decision, features and scores included,
+	// as the decision log would record it (default 0)
+	Debug *float64 `json:"debug,omitempty"`
+}
+
+// Sampled reports which signals a request is kept for
+type Sampled struct {
+	Logs      bool `json:"logs"`
+	Decisions bool `json:"decisions"`
+	Traces    bool `json:"traces"`
+	Debug     bool `json:"debug"`
+}
+
+// SamplingStats counts requests kept per signal
+type SamplingStats struct {
+	Requests  int64 `json:"requests"`
+	Logs      int64 `json:"logs"`
+	Decisions int64 `json:"decisions"`
+	Traces    int64 `json:"traces"`
+	Debug     int64 `json:"debug"`
+}
+
+// resolvedRates are a tenant's rates with inheritance applied
+type resolvedRates struct {
+	logs, decisions, traces, debug float64
+}
+
+// ObservabilitySampler decides which signals each request is kept for
+type ObservabilitySampler struct {
+	defaults resolvedRates
+	tenants  map[string]resolvedRates
+	draw     func() float64
+
+	requests, logs, decisions, traces, debug atomic.Int64
+}
+
+// NewObservabilitySampler resolves the configured rates. Logs default to
+// kept only when enableLogs is set.
+func NewObservabilitySampler(config ObservabilityConfig, enableLogs bool) (*ObservabilitySampler, error) {
+	defaults := resolvedRates{decisions: 1, traces: 1}
+	if enableLogs {
+		defaults.logs = 1
+	}
+	defaults, err := defaults.with(config.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+
+	sp := &ObservabilitySampler{
+		defaults: defaults,
+		tenants:  make(map[string]resolvedRates, len(config.Tenants)),
+		draw:     rand.Float64,
+	}
+	for tenant, rates := range config.Tenants {
+		resolved, err := defaults.with(rates)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		sp.tenants[tenant] = resolved
+	}
+	return sp, nil
+}
+
+// with overrides the set rates, checking they are fractions
+func (r resolvedRates) with(rates SampleRates) (resolvedRates, error) {
+	for _, override := range []struct {
+		name  string
+		rate  *float64
+		field *float64
+	}{
+		{"logs", rates.Logs, &r.logs},
+		{"decisions", rates.Decisions, &r.decisions},
+		{"traces", rates.Traces, &r.traces},
+		{"debug", rates.Debug, &r.debug},
+	} {
+		if override.rate == nil {
+			continue
+		}
+		if *override.rate < 0 || *override.rate > 1 {
+			return r, fmt.Errorf("%s rate must be between 0 and 1", override.name)
+		}
+		*override.field = *override.rate
+	}
+	return r, nil
+}
+
+// Sample decides which signals a request from tenant is kept for
+func (sp *ObservabilitySampler) Sample(tenant string) Sampled {
+	rates, ok := sp.tenants[tenant]
+	if !ok {
+		rates = sp.defaults
+	}
+	u := sp.draw()
+	sampled := Sampled{
+		Logs:      u < rates.logs,
+		Decisions: u < rates.decisions,
+		Traces:    u < rates.traces,
+		Debug:     u < rates.debug,
+	}
+
+	sp.requests.Add(1)
+	for _, signal := range []struct {
+		kept  bool
+		count *atomic.Int64
+	}{
+		{sampled.Logs, &sp.logs},
+		{sampled.Decisions, &sp.decisions},
+		{sampled.Traces, &sp.traces},
+		{sampled.Debug, &sp.debug},
+	} {
+		if signal.kept {
+			signal.count.Add(1)
+		}
+	}
+	return sampled
+}
+
+// GetStats returns the counts of requests kept per signal
+func (sp *ObservabilitySampler) GetStats() SamplingStats {
+	return SamplingStats{
+		Requests:  sp.requests.Load(),
+		Logs:      sp.logs.Load(),
+		Decisions: sp.decisions.Load(),
+		Traces:    sp.traces.Load(),
+		Debug:     sp.debug.Load(),
+	}
+}
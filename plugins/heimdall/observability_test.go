@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rate(r float64) *float64 { return &r }
+
+func TestObservabilitySampler(t *testing.T) {
+	t.Run("should keep decisions and traces but not logs by default", func(t *testing.T) {
+		sampler, err := NewObservabilitySampler(ObservabilityConfig{}, false)
+		require.NoError(t, err)
+		assert.Equal(t, Sampled{Decisions: true, Traces: true}, sampler.Sample("acme"))
+
+		sampler, err = NewObservabilitySampler(ObservabilityConfig{}, true)
+		require.NoError(t, err)
+		assert.Equal(t, Sampled{Logs: true, Decisions: true, Traces: true}, sampler.Sample("acme"))
+	})
+
+	t.Run("should keep signals whose rate is above the request's draw", func(t *testing.T) {
+		sampler, err := NewObservabilitySampler(ObservabilityConfig{
+			Default: SampleRates{Logs: rate(0.5), Traces: rate(0.1), Debug: rate(0.01)},
+			Tenants: map[string]SampleRates{"acme": {Traces: rate(1)}},
+		}, false)
+		require.NoError(t, err)
+		sampler.draw = func() float64 { return 0.3 }
+
+		assert.Equal(t, Sampled{Logs: true, Decisions: true}, sampler.Sample("other"))
+		assert.Equal(t, Sampled{Logs: true, Decisions: true, Traces: true}, sampler.Sample("acme"), "unset tenant rates inherit")
+
+		stats := sampler.GetStats()
+		assert.Equal(t, int64(2), stats.Requests)
+		assert.Equal(t, int64(2), stats.Logs)
+		assert.Equal(t, int64(1), stats.Traces)
+		assert.Equal(t, int64(0), stats.Debug)
+	})
+
+	t.Run("should reject rates outside 0 to 1", func(t *testing.T) {
+		_, err := NewObservabilitySampler(ObservabilityConfig{Default: SampleRates{Logs: rate(2)}}, false)
+		assert.ErrorContains(t, err, "logs rate")
+		_, err = NewObservabilitySampler(ObservabilityConfig{Tenants: map[string]SampleRates{"acme": {Debug: rate(-0.5)}}}, false)
+		assert.ErrorContains(t, err, "tenant acme")
+	})
+}
+
+func TestObservabilitySampling(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Observability = ObservabilityConfig{
+		Default: SampleRates{Logs: rate(0), Debug: rate(0)},
+		Tenants: map[string]SampleRates{"acme": {Logs: rate(1), Debug: rate(1)}},
+	}
+	var logs bytes.Buffer
+	plugin, err := NewWithOptions(config, WithLogger(log.New(&logs, "", 0)))
+	require.NoError(t, err)
+	defer plugin.Cleanup()
+
+	route := func(org string) context.Context {
+		ctx := context.Background()
+		response := &RouterResponse{Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"}}
+		if org != "" {
+			response.AuthInfo = &AuthInfo{Provider: "openai", Org: org}
+		}
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+		require.NoError(t, err)
+		return ctx
+	}
+
+	t.Run("should sample signals per tenant", func(t *testing.T) {
+		ctx := route("")
+		sampled := ctx.Value("heimdall_sampled").(Sampled)
+		assert.False(t, sampled.Logs)
+		assert.NotContains(t, logs.String(), "Debug capture")
+
+		ctx = route("acme")
+		sampled = ctx.Value("heimdall_sampled").(Sampled)
+		assert.True(t, sampled.Logs)
+		assert.Contains(t, logs.String(), `Debug capture: {`)
+		assert.Contains(t, logs.String(), `"openai/gpt-4o"`)
+	})
+
+	t.Run("should log only sampled requests", func(t *testing.T) {
+		logs.Reset()
+		ctx := route("")
+		_, _, err := plugin.PostHook(&ctx, textResponse("ok", "stop"), nil)
+		require.NoError(t, err)
+		assert.NotContains(t, logs.String(), "Request routed to bucket")
+
+		ctx = route("acme")
+		ctx = context.WithValue(ctx, "heimdall_bucket", BucketMid)
+		_, _, err = plugin.PostHook(&ctx, textResponse("ok", "stop"), nil)
+		require.NoError(t, err)
+		assert.Contains(t, logs.String(), "Request routed to bucket: mid")
+	})
+
+	t.Run("should count kept requests in metrics", func(t *testing.T) {
+		stats := plugin.GetMetrics()["observability_sampling"].(SamplingStats)
+		assert.Equal(t, int64(4), stats.Requests)
+		assert.Equal(t, int64(2), stats.Debug)
+		assert.Equal(t, int64(4), stats.Decisions)
+	})
+
+	t.Run("should reject invalid rates", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Observability.Default.Traces = rate(1.5)
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid observability config")
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/router"
+)
+
+// Option customizes a plugin created with NewWithOptions
+type Option func(*options)
+
+// options holds the dependencies NewWithOptions injects
+type options struct {
+	logger     *log.Logger
+	httpClient *http.Client
+	embedder   features.EmbeddingProvider
+	now        func() time.Time
+	cache      DecisionCache
+	auditSink  io.Writer
+
+	featureExtractor router.FeatureExtractor
+	triageModel      router.TriageModel
+	scorer           router.Scorer
+
+	middleware []Middleware
+}
+
+// WithLogger routes the plugin's log output to logger
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithHTTPClient sets the client used to fetch routing artifacts. The
+// config's Timeout is not applied to a client supplied this way.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithEmbeddingProvider computes request embeddings with provider instead of
+// the built-in hash embedding. It has no effect with WithFeatureExtractor.
+func WithEmbeddingProvider(provider features.EmbeddingProvider) Option {
+	return func(o *options) {
+		o.embedder = provider
+	}
+}
+
+// WithClock sets the time source for decision cache expiry and artifact
+// reloads
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithCache stores routing decisions in cache instead of an in-process map
+func WithCache(cache DecisionCache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithFeatureExtractor replaces the built-in feature extraction
+func WithFeatureExtractor(extractor router.FeatureExtractor) Option {
+	return func(o *options) {
+		o.featureExtractor = extractor
+	}
+}
+
+// WithTriageModel replaces the built-in GBDT bucket triage
+func WithTriageModel(model router.TriageModel) Option {
+	return func(o *options) {
+		o.triageModel = model
+	}
+}
+
+// WithScorer replaces α-score selection within a bucket. Self-hosted cost
+// overrides, saturation penalties and online quality only shape the
+// built-in scorer.
+func WithScorer(scorer router.Scorer) Option {
+	return func(o *options) {
+		o.scorer = scorer
+	}
+}
+
+// WithMiddleware wraps routing decisions in middleware, outermost first.
+// Repeated uses append to the chain.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithAuditSink also writes each audit entry to sink as a JSON line, e.g. the
+// writer decision logs are shipped through. It has no effect unless the
+// audit trail is enabled.
+func WithAuditSink(sink io.Writer) Option {
+	return func(o *options) {
+		o.auditSink = sink
+	}
+}
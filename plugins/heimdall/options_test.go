@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc serves HTTP requests from a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// countingCache records writes to an in-memory decision cache
+type countingCache struct {
+	*memoryDecisionCache
+	sets int
+}
+
+func (c *countingCache) Set(key string, entry CacheEntry) {
+	c.sets++
+	c.memoryDecisionCache.Set(key, entry)
+}
+
+// fixedEmbedder returns the same embedding for every text, or an error
+type fixedEmbedder struct {
+	embedding []float64
+	err       error
+}
+
+func (e *fixedEmbedder) Embed(text string) ([]float64, error) {
+	return e.embedding, e.err
+}
+
+func TestNewWithOptions(t *testing.T) {
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}
+
+	t.Run("should default like New", func(t *testing.T) {
+		plugin, err := NewWithOptions(createRouterTestConfig())
+		require.NoError(t, err)
+		assert.NotNil(t, plugin.logger)
+		assert.NotNil(t, plugin.httpClient)
+		assert.IsType(t, &memoryDecisionCache{}, plugin.cache)
+	})
+
+	t.Run("should log through the injected logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := NewWithOptions(createRouterTestConfig(), WithLogger(log.New(&buf, "", 0)))
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Initialized native Heimdall plugin")
+	})
+
+	t.Run("should fetch artifacts with the injected client", func(t *testing.T) {
+		var fetched string
+		client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			fetched = r.URL.String()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"version":"injected-1"}`)),
+				Header:     make(http.Header),
+			}, nil
+		})}
+
+		plugin, err := NewWithOptions(createRouterTestConfig(), WithHTTPClient(client))
+		require.NoError(t, err)
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, plugin.config.Tuning.ArtifactURL, fetched)
+		assert.Equal(t, "injected-1", plugin.currentArtifact.Version)
+	})
+
+	t.Run("should expire cached decisions by the injected clock", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache := &countingCache{memoryDecisionCache: newMemoryDecisionCache()}
+		plugin, err := NewWithOptions(createRouterTestConfig(),
+			WithCache(cache),
+			WithClock(func() time.Time { return now }))
+		require.NoError(t, err)
+
+		plugin.cacheResponse(req, &RouterResponse{Decision: RouterDecision{Model: "openai/gpt-4o"}})
+		assert.Equal(t, 1, cache.sets)
+		require.NotNil(t, plugin.getCachedResponse(req))
+
+		now = now.Add(plugin.config.CacheTTL + time.Second)
+		assert.Nil(t, plugin.getCachedResponse(req))
+	})
+
+	t.Run("should embed with the injected provider", func(t *testing.T) {
+		embedding := []float64{0.1, 0.2, 0.3}
+		plugin, err := NewWithOptions(createRouterTestConfig(),
+			WithEmbeddingProvider(&fixedEmbedder{embedding: embedding}))
+		require.NoError(t, err)
+
+		features, err := plugin.featureExtractor.Extract(req, &AvengersArtifact{}, 25)
+		require.NoError(t, err)
+		assert.Equal(t, embedding, features.Embedding)
+	})
+
+	t.Run("should fall back to the hash embedding when the provider fails", func(t *testing.T) {
+		plugin, err := NewWithOptions(createRouterTestConfig(),
+			WithEmbeddingProvider(&fixedEmbedder{err: fmt.Errorf("unavailable")}))
+		require.NoError(t, err)
+
+		features, err := plugin.featureExtractor.Extract(req, &AvengersArtifact{}, 25)
+		require.NoError(t, err)
+		assert.Len(t, features.Embedding, 384)
+	})
+}
+
+// stubExtractor returns fixed features, or an error
+type stubExtractor struct {
+	features *RequestFeatures
+	err      error
+}
+
+func (e *stubExtractor) Extract(req *RouterRequest, artifact *AvengersArtifact, timeoutMs int) (*RequestFeatures, error) {
+	return e.features, e.err
+}
+
+// stubTriage returns fixed bucket probabilities
+type stubTriage struct {
+	probs *BucketProbabilities
+}
+
+func (m *stubTriage) Predict(features *RequestFeatures, artifact *AvengersArtifact) (*BucketProbabilities, error) {
+	return m.probs, nil
+}
+
+// recordingScorer picks the last candidate and records what it was offered
+type recordingScorer struct {
+	candidates []string
+}
+
+func (s *recordingScorer) SelectBest(candidates []string, features *RequestFeatures, artifact *AvengersArtifact) (string, error) {
+	s.candidates = candidates
+	return candidates[len(candidates)-1], nil
+}
+
+func TestInjectedStages(t *testing.T) {
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}
+	newPlugin := func(t *testing.T, opts ...Option) *Plugin {
+		plugin, err := NewWithOptions(createRouterTestConfig(), opts...)
+		require.NoError(t, err)
+		plugin.currentArtifact = &AvengersArtifact{Version: "stub"}
+		plugin.lastArtifactLoad = time.Now()
+		return plugin
+	}
+
+	t.Run("should route with injected triage and scorer", func(t *testing.T) {
+		scorer := &recordingScorer{}
+		plugin := newPlugin(t,
+			WithFeatureExtractor(&stubExtractor{features: &RequestFeatures{TokenCount: 10}}),
+			WithTriageModel(&stubTriage{probs: &BucketProbabilities{Hard: 0.9}}),
+			WithScorer(scorer))
+
+		response, err := plugin.decide(req, map[string][]string{})
+		require.NoError(t, err)
+		assert.Equal(t, BucketHard, response.Bucket)
+		assert.Equal(t, 10, response.Features.TokenCount)
+		require.NotEmpty(t, scorer.candidates)
+		assert.Subset(t, plugin.config.Router.HardCandidates, scorer.candidates)
+		assert.Equal(t, scorer.candidates[len(scorer.candidates)-1], response.Decision.Model)
+	})
+
+	t.Run("should surface extraction failures", func(t *testing.T) {
+		plugin := newPlugin(t, WithFeatureExtractor(&stubExtractor{err: fmt.Errorf("boom")}))
+
+		_, err := plugin.decide(req, map[string][]string{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "feature extraction failed")
+	})
+}
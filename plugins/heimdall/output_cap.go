@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// OutputCapConfig derives max_tokens from the context the selected models
+// have left after the prompt, so oversized generations are cut short rather
+// than failing provider-side with opaque context errors. Models need a
+// context_window (and optionally max_output) in the artifact's profiles.
+type OutputCapConfig struct {
+	Enabled bool `json:"enabled"`
+	// Margin is held back from the context window for error in the prompt
+	// token estimate (default 256)
+	Margin int `json:"margin"`
+}
+
+// capOutputTokens sets the decision's max_tokens param to the most output
+// every model in it can produce: min(max_output, context_window − prompt
+// tokens − margin) over models with a profile, counting prompt tokens as
+// each model's provider does (learned factors only when learned is set).
+// Bifrost retries fallbacks with the same parameters, so the cap must fit
+// all of them. Models the prompt alone overflows cannot be helped by a cap
+// and are skipped.
+func (p *Plugin) capOutputTokens(decision *RouterDecision, features *RequestFeatures, learned bool) {
+	config := p.config.OutputCap
+	if !config.Enabled || p.currentArtifact == nil {
+		return
+	}
+	margin := config.Margin
+	if margin == 0 {
+		margin = 256
+	}
+
+	limit := math.MaxInt
+	for _, model := range append([]string{decision.Model}, decision.Fallbacks...) {
+		profile, ok := p.currentArtifact.Models[model]
+		if !ok {
+			continue
+		}
+		if profile.MaxOutput > 0 {
+			limit = min(limit, profile.MaxOutput)
+		}
+		if profile.ContextWindow > 0 {
+			if room := profile.ContextWindow - p.calibratedTokens(model, features, learned) - margin; room > 0 {
+				limit = min(limit, room)
+			}
+		}
+	}
+	if limit == math.MaxInt {
+		return
+	}
+	if decision.Params == nil {
+		decision.Params = make(map[string]interface{})
+	}
+	decision.Params["max_tokens"] = limit
+}
+
+// applyOutputCap lowers the request's output limit to the decision's cap,
+// keeping a smaller value the caller asked for in either max_tokens or
+// max_completion_tokens. OpenAI reasoning models reject max_tokens, so
+// their limit is sent as max_completion_tokens, as is the limit of callers
+// that used it.
+func applyOutputCap(req *schemas.BifrostRequest, decision *RouterDecision) {
+	limit, ok := intParam(decision.Params["max_tokens"])
+	if !ok {
+		return
+	}
+
+	if req.Params == nil {
+		req.Params = &schemas.ModelParameters{}
+	}
+	params := req.Params
+	requested, completionTokens := intParam(params.ExtraParams["max_completion_tokens"])
+	if completionTokens || (decision.Kind == "openai" && openAIReasoningModel(decision.Model)) {
+		if completionTokens {
+			limit = min(limit, requested)
+		}
+		if params.MaxTokens != nil {
+			limit = min(limit, *params.MaxTokens)
+		}
+		params.ExtraParams = setParam(params.ExtraParams, "max_completion_tokens", limit)
+		params.MaxTokens = nil
+		return
+	}
+	if params.MaxTokens == nil || *params.MaxTokens > limit {
+		params.MaxTokens = &limit
+	}
+}
+
+// openAIReasoningModel reports whether an OpenAI model is an o-series or
+// GPT-5 reasoning model, which takes max_completion_tokens
+func openAIReasoningModel(model string) bool {
+	model = strings.TrimPrefix(model, "openai/")
+	if strings.HasPrefix(model, "gpt-5") {
+		return true
+	}
+	return len(model) > 1 && model[0] == 'o' && model[1] >= '1' && model[1] <= '9'
+}
+
+// setParam sets an extra parameter, allocating the map if needed
+func setParam(params map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	params[name] = value
+	return params
+}
+
+// intParam reads an integer extra parameter, which JSON decodes as float64
+func intParam(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), v == float64(int(v))
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputCap(t *testing.T) {
+	config := createRouterTestConfig()
+	config.OutputCap = OutputCapConfig{Enabled: true, Margin: 100}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	plugin.currentArtifact.Models = map[string]ModelProfile{
+		"openai/gpt-4o":         {ContextWindow: 128000, MaxOutput: 16384},
+		"google/gemini-1.5-pro": {ContextWindow: 10000},
+	}
+
+	t.Run("should cap at the smallest limit across the decision's models", func(t *testing.T) {
+		decision := &RouterDecision{Model: "openai/gpt-4o", Fallbacks: []string{"google/gemini-1.5-pro"}}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.Equal(t, 10000-4000-100, decision.Params["max_tokens"])
+
+		decision = &RouterDecision{Model: "openai/gpt-4o"}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.Equal(t, 16384, decision.Params["max_tokens"])
+	})
+
+	t.Run("should skip models without a profile or any room", func(t *testing.T) {
+		decision := &RouterDecision{Model: "openai/o1"}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.NotContains(t, decision.Params, "max_tokens")
+
+		decision = &RouterDecision{Model: "google/gemini-1.5-pro", Fallbacks: []string{"openai/gpt-4o"}}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 20000}, true)
+		assert.Equal(t, 16384, decision.Params["max_tokens"])
+	})
+
+	t.Run("should keep a smaller requested max_tokens", func(t *testing.T) {
+		decision := &RouterDecision{Params: map[string]interface{}{"max_tokens": 2000}}
+		req := &schemas.BifrostRequest{}
+		applyOutputCap(req, decision)
+		require.NotNil(t, req.Params.MaxTokens)
+		assert.Equal(t, 2000, *req.Params.MaxTokens)
+
+		requested := 500
+		req = &schemas.BifrostRequest{Params: &schemas.ModelParameters{MaxTokens: &requested}}
+		applyOutputCap(req, decision)
+		assert.Equal(t, 500, *req.Params.MaxTokens)
+
+		decoded := &RouterDecision{Params: map[string]interface{}{"max_tokens": float64(1000)}}
+		applyOutputCap(req, decoded)
+		assert.Equal(t, 500, *req.Params.MaxTokens)
+		requested = 5000
+		applyOutputCap(req, decoded)
+		assert.Equal(t, 1000, *req.Params.MaxTokens)
+	})
+
+	t.Run("should keep a smaller requested max_completion_tokens", func(t *testing.T) {
+		decision := &RouterDecision{Kind: "openai", Model: "openai/gpt-4o", Params: map[string]interface{}{"max_tokens": 2000}}
+		req := &schemas.BifrostRequest{Params: &schemas.ModelParameters{ExtraParams: map[string]interface{}{"max_completion_tokens": float64(300)}}}
+		applyOutputCap(req, decision)
+		assert.Nil(t, req.Params.MaxTokens, "max_tokens is not sent alongside max_completion_tokens")
+		assert.Equal(t, 300, req.Params.ExtraParams["max_completion_tokens"])
+
+		req.Params.ExtraParams["max_completion_tokens"] = float64(5000)
+		applyOutputCap(req, decision)
+		assert.Equal(t, 2000, req.Params.ExtraParams["max_completion_tokens"])
+	})
+
+	t.Run("should send max_completion_tokens to OpenAI reasoning models", func(t *testing.T) {
+		requested := 800
+		decision := &RouterDecision{Kind: "openai", Model: "openai/o1", Params: map[string]interface{}{"max_tokens": 2000}}
+		req := &schemas.BifrostRequest{Params: &schemas.ModelParameters{MaxTokens: &requested}}
+		applyOutputCap(req, decision)
+		assert.Nil(t, req.Params.MaxTokens)
+		assert.Equal(t, 800, req.Params.ExtraParams["max_completion_tokens"])
+
+		assert.True(t, openAIReasoningModel("o3-mini"))
+		assert.True(t, openAIReasoningModel("openai/gpt-5"))
+		assert.False(t, openAIReasoningModel("openai/gpt-4o"))
+		assert.False(t, openAIReasoningModel("openai/omni-moderation-latest"))
+	})
+
+	t.Run("should leave decisions alone when disabled", func(t *testing.T) {
+		disabled := createRouterTestPlugin(t)
+		disabled.currentArtifact.Models = plugin.currentArtifact.Models
+		decision := &RouterDecision{Model: "openai/gpt-4o"}
+		disabled.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.Nil(t, decision.Params)
+	})
+}
@@ -0,0 +1,25 @@
+package main
+
+import "github.com/nathanrice/heimdall-bifrost-plugin/core"
+
+// RoutingPolicy constrains routing for a class of callers (e.g. a JWT tier or
+// organization). Zero-valued fields leave routing unconstrained.
+type RoutingPolicy = core.RoutingPolicy
+
+// applyPolicy caps the decision's price budget
+func applyPolicy(rp *RoutingPolicy, decision *RouterDecision) {
+	if rp == nil || rp.MaxPrice <= 0 {
+		return
+	}
+	if decision.ProviderPrefs.MaxPrice == 0 || decision.ProviderPrefs.MaxPrice > rp.MaxPrice {
+		decision.ProviderPrefs.MaxPrice = rp.MaxPrice
+	}
+}
+
+// policyFor returns the routing policy attached to the request's auth, if any
+func policyFor(authInfo *AuthInfo) *RoutingPolicy {
+	if authInfo == nil {
+		return nil
+	}
+	return authInfo.Policy
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingPolicySelection(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 0, TokenCount: 500}
+
+	t.Run("should cap the bucket and price", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{MaxBucket: BucketCheap, MaxPrice: 3}}
+
+		decision, err := plugin.selectModel(BucketHard, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Contains(t, plugin.config.Router.CheapCandidates, decision.Model)
+		assert.Equal(t, 3, decision.ProviderPrefs.MaxPrice)
+	})
+
+	t.Run("should restrict candidates and fallbacks", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{Candidates: []string{"openai/o1"}}}
+
+		decision, err := plugin.selectModel(BucketHard, features, authInfo, false)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/o1", decision.Model)
+		assert.Empty(t, decision.Fallbacks)
+
+		_, err = plugin.selectModel(BucketCheap, features, authInfo, false)
+		assert.Error(t, err)
+	})
+}
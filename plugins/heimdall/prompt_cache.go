@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// PromptCacheConfig estimates how much of each prompt providers' prompt
+// caches already hold, from a hash index of recent prompt prefixes, and
+// discounts the cost of models that cache prompts accordingly
+type PromptCacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// TTL is how long providers keep a prefix cached (default 5m)
+	TTL time.Duration `json:"ttl"`
+	// MaxPrefixes bounds the prefix index (default 10000)
+	MaxPrefixes int `json:"max_prefixes"`
+	// MinTokens is the shortest prefix providers cache (default 1024)
+	MinTokens int `json:"min_tokens"`
+	// Discounts is the fraction of a model's cost saved when its whole
+	// prompt is cached, keyed by model or provider kind (model first).
+	// Weigh the provider's cached-input discount by the share of cost that
+	// is input, e.g. anthropic 0.6 for a 90% discount on 2/3 of cost.
+	Discounts map[string]float64 `json:"discounts"`
+}
+
+// validate checks the discounts and fills defaults
+func (c *PromptCacheConfig) validate() error {
+	if c.TTL == 0 {
+		c.TTL = 5 * time.Minute
+	}
+	if c.MaxPrefixes == 0 {
+		c.MaxPrefixes = 10000
+	}
+	if c.MinTokens == 0 {
+		c.MinTokens = 1024
+	}
+	if c.TTL < 0 || c.MaxPrefixes < 0 || c.MinTokens < 0 {
+		return fmt.Errorf("ttl, max_prefixes and min_tokens must be positive")
+	}
+	for key, discount := range c.Discounts {
+		if discount < 0 || discount > 1 {
+			return fmt.Errorf("discount for %s must be between 0 and 1", key)
+		}
+	}
+	return nil
+}
+
+// promptCacheDiscount is the fraction of model's cost a request saves from
+// the share of its prompt cached by model's provider
+func (p *Plugin) promptCacheDiscount(model string, features *RequestFeatures) float64 {
+	kind := p.inferProviderKind(model)
+	ratio := features.CacheablePrefixRatios[kind]
+	if ratio == 0 {
+		return 0
+	}
+	discount, ok := p.config.PromptCache.Discounts[model]
+	if !ok {
+		discount = p.config.PromptCache.Discounts[kind]
+	}
+	return ratio * discount
+}
+
+// promptCacheScope scopes prompt prefixes to a provider kind and tenant, as
+// providers cache prompts per account
+func promptCacheScope(kind, tenant string) string {
+	return kind + "\x00" + tenant
+}
+
+// estimatePromptCache sets the share of the prompt each candidate provider
+// likely holds cached for tenant
+func (p *Plugin) estimatePromptCache(features *RequestFeatures, tenant string) {
+	if p.prefixIndex == nil || features.PromptPrefixes == nil {
+		return
+	}
+	config := p.config.Router
+	matched := make(map[string]bool)
+	for _, candidates := range [][]string{config.CheapCandidates, config.MidCandidates, config.HardCandidates} {
+		for _, model := range candidates {
+			kind := p.inferProviderKind(model)
+			if matched[kind] {
+				continue
+			}
+			matched[kind] = true
+			if ratio := p.prefixIndex.Match(promptCacheScope(kind, tenant), features.PromptPrefixes); ratio > 0 {
+				if features.CacheablePrefixRatios == nil {
+					features.CacheablePrefixRatios = make(map[string]float64)
+				}
+				features.CacheablePrefixRatios[kind] = ratio
+			}
+		}
+	}
+}
+
+// recordPromptPrefixes notes the prompt as sent to model's provider for
+// tenant
+func (p *Plugin) recordPromptPrefixes(model string, features *RequestFeatures, tenant string) {
+	if p.prefixIndex == nil {
+		return
+	}
+	p.prefixIndex.Record(promptCacheScope(p.inferProviderKind(model), tenant), features.PromptPrefixes)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCacheDiscount(t *testing.T) {
+	config := createRouterTestConfig()
+	config.PromptCache = PromptCacheConfig{
+		Enabled:   true,
+		MinTokens: 256,
+		Discounts: map[string]float64{"anthropic": 0.6, "openai/gpt-4o": 0.5},
+	}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	t.Run("should discount by model, then provider kind", func(t *testing.T) {
+		features := &RequestFeatures{CacheablePrefixRatios: map[string]float64{"openai": 0.5, "anthropic": 0.5, "google": 0.5}}
+		assert.InDelta(t, 0.25, plugin.promptCacheDiscount("openai/gpt-4o", features), 1e-9)
+		assert.InDelta(t, 0.3, plugin.promptCacheDiscount("anthropic/claude-3-5-sonnet-20241022", features), 1e-9)
+		assert.Zero(t, plugin.promptCacheDiscount("google/gemini-1.5-pro", features))
+		assert.Zero(t, plugin.promptCacheDiscount("anthropic/claude-3-5-sonnet-20241022", &RequestFeatures{CacheablePrefixRatios: map[string]float64{"openai": 0.5}}))
+	})
+
+	t.Run("should estimate the cached prefix per provider and tenant", func(t *testing.T) {
+		system := strings.Repeat("Follow the style guide closely. ", 100)
+		req := func(question string) *RouterRequest {
+			return &RouterRequest{
+				Method: "POST",
+				Body: &RequestBody{Messages: []ChatMessage{
+					{Role: "system", Content: system},
+					{Role: "user", Content: question},
+				}},
+			}
+		}
+		headers := map[string][]string{"Authorization": {"Bearer sk-test-key"}}
+
+		first, err := plugin.decide(req("What changed?"), headers)
+		require.NoError(t, err)
+		assert.Empty(t, first.Features.CacheablePrefixRatios)
+
+		second, err := plugin.decide(req("Why did it change?"), headers)
+		require.NoError(t, err)
+		kind := plugin.inferProviderKind(first.Decision.Model)
+		assert.Greater(t, second.Features.CacheablePrefixRatios[kind], 0.8, "recorded against the selected model's provider")
+		assert.Len(t, second.Features.CacheablePrefixRatios, 1, "other providers have not seen the prompt")
+
+		other, err := plugin.decide(req("Why did it change?"), map[string][]string{"Authorization": {"Bearer sk-other-key"}})
+		require.NoError(t, err)
+		assert.Empty(t, other.Features.CacheablePrefixRatios, "tenants do not share provider caches")
+		assert.Equal(t, int64(1), plugin.GetMetrics()["prompt_cache"].(features.PrefixStats).Hits)
+	})
+
+	t.Run("should reject discounts outside [0, 1]", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.PromptCache = PromptCacheConfig{Enabled: true, Discounts: map[string]float64{"openai": 1.5}}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "discount")
+	})
+}
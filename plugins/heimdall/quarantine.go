@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// QuarantineConfig configures automatic quarantine of failing models
+type QuarantineConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Window is the number of recent outcomes a model's success rate covers
+	Window int `json:"window"`
+	// MinSamples is the outcomes needed before a model can be quarantined
+	MinSamples int `json:"min_samples"`
+	// MinSuccessRate below which a model is quarantined
+	MinSuccessRate float64 `json:"min_success_rate"`
+
+	// Duration is how long a quarantine lasts unless probes recover it sooner
+	Duration time.Duration `json:"duration"`
+	// ProbeRate is the fraction of selections that still consider a
+	// quarantined model, so recovery can be detected
+	ProbeRate float64 `json:"probe_rate"`
+	// RecoveryProbes is the number of consecutive successful probes that
+	// lift a quarantine early
+	RecoveryProbes int `json:"recovery_probes"`
+
+	// WebhookURL receives quarantine and release events (optional)
+	WebhookURL     string        `json:"webhook_url"`
+	WebhookTimeout time.Duration `json:"webhook_timeout"`
+}
+
+// QuarantineEvent is posted to the webhook on state changes
+type QuarantineEvent struct {
+	Event       string    `json:"event"` // "quarantined" or "released"
+	Model       string    `json:"model"`
+	SuccessRate float64   `json:"success_rate"`
+	Reason      string    `json:"reason"`
+	Until       time.Time `json:"until,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// QuarantineStatus reports a quarantined model
+type QuarantineStatus struct {
+	Model            string    `json:"model"`
+	SuccessRate      float64   `json:"success_rate"`
+	QuarantinedAt    time.Time `json:"quarantined_at"`
+	Until            time.Time `json:"until"`
+	Probes           int       `json:"probes"`
+	ConsecutiveProbe int       `json:"consecutive_probe_successes"`
+}
+
+// modelHealth is a ring of recent outcomes and any active quarantine
+type modelHealth struct {
+	outcomes []bool
+	next     int
+	filled   bool
+
+	quarantine *QuarantineStatus
+}
+
+func (h *modelHealth) successRate() (float64, int) {
+	n := h.next
+	if h.filled {
+		n = len(h.outcomes)
+	}
+	if n == 0 {
+		return 1, 0
+	}
+	successes := 0
+	for _, ok := range h.outcomes[:n] {
+		if ok {
+			successes++
+		}
+	}
+	return float64(successes) / float64(n), n
+}
+
+// QuarantineManager excludes models whose success rate collapses and
+// probes them with a trickle of traffic until they recover
+type QuarantineManager struct {
+	config     QuarantineConfig
+	httpClient *http.Client
+	// onEvent also receives events, under the manager's lock (optional)
+	onEvent func(QuarantineEvent)
+
+	models map[string]*modelHealth
+	mu     sync.Mutex
+}
+
+// NewQuarantineManager creates a manager, filling defaults
+func NewQuarantineManager(config QuarantineConfig) (*QuarantineManager, error) {
+	if config.Window == 0 {
+		config.Window = 50
+	}
+	if config.MinSamples == 0 {
+		config.MinSamples = 20
+	}
+	if config.MinSuccessRate == 0 {
+		config.MinSuccessRate = 0.5
+	}
+	if config.Duration == 0 {
+		config.Duration = 10 * time.Minute
+	}
+	if config.ProbeRate == 0 {
+		config.ProbeRate = 0.02
+	}
+	if config.RecoveryProbes == 0 {
+		config.RecoveryProbes = 3
+	}
+	if config.WebhookTimeout == 0 {
+		config.WebhookTimeout = 5 * time.Second
+	}
+	if config.MinSamples > config.Window {
+		return nil, fmt.Errorf("min_samples %d exceeds window %d", config.MinSamples, config.Window)
+	}
+	if config.ProbeRate < 0 || config.ProbeRate > 1 {
+		return nil, fmt.Errorf("probe_rate must be within [0, 1], got %v", config.ProbeRate)
+	}
+
+	return &QuarantineManager{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: config.WebhookTimeout,
+		},
+		models: make(map[string]*modelHealth),
+	}, nil
+}
+
+// Record adds a request outcome for a model. Outcomes of a quarantined
+// model are probes and count towards early recovery.
+func (qm *QuarantineManager) Record(model string, success bool) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	health := qm.health(model)
+	now := time.Now()
+	qm.expire(model, health, now)
+
+	if q := health.quarantine; q != nil {
+		q.Probes++
+		if !success {
+			q.ConsecutiveProbe = 0
+			return
+		}
+		q.ConsecutiveProbe++
+		if q.ConsecutiveProbe >= qm.config.RecoveryProbes {
+			qm.release(model, health, fmt.Sprintf("%d consecutive successful probes", q.ConsecutiveProbe))
+		}
+		return
+	}
+
+	health.outcomes[health.next] = success
+	health.next = (health.next + 1) % len(health.outcomes)
+	if health.next == 0 {
+		health.filled = true
+	}
+
+	rate, n := health.successRate()
+	if n >= qm.config.MinSamples && rate < qm.config.MinSuccessRate {
+		health.quarantine = &QuarantineStatus{
+			Model:         model,
+			SuccessRate:   rate,
+			QuarantinedAt: now,
+			Until:         now.Add(qm.config.Duration),
+		}
+		log.Printf("Quarantined model %s: success rate %.2f over %d requests", model, rate, n)
+		qm.notify(QuarantineEvent{
+			Event:       "quarantined",
+			Model:       model,
+			SuccessRate: rate,
+			Reason:      fmt.Sprintf("success rate %.2f below %.2f", rate, qm.config.MinSuccessRate),
+			Until:       health.quarantine.Until,
+			Timestamp:   now,
+		})
+	}
+}
+
+// IsQuarantined reports whether a model is currently excluded
+func (qm *QuarantineManager) IsQuarantined(model string) bool {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	health, ok := qm.models[model]
+	if !ok {
+		return false
+	}
+	qm.expire(model, health, time.Now())
+	return health.quarantine != nil
+}
+
+// ShouldProbe decides whether a quarantined model is offered to selection
+func (qm *QuarantineManager) ShouldProbe() bool {
+	return rand.Float64() < qm.config.ProbeRate
+}
+
+// Release lifts a model's quarantine manually
+func (qm *QuarantineManager) Release(model string) error {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	health, ok := qm.models[model]
+	if !ok || health.quarantine == nil {
+		return fmt.Errorf("model %s is not quarantined", model)
+	}
+	qm.release(model, health, "released manually")
+	return nil
+}
+
+// GetStatus returns the quarantined models
+func (qm *QuarantineManager) GetStatus() []QuarantineStatus {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	now := time.Now()
+	var statuses []QuarantineStatus
+	for model, health := range qm.models {
+		qm.expire(model, health, now)
+		if health.quarantine != nil {
+			statuses = append(statuses, *health.quarantine)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Model < statuses[j].Model })
+	return statuses
+}
+
+// Inherit copies a replaced manager's quarantines and, when the window is
+// unchanged, its recent outcomes
+func (qm *QuarantineManager) Inherit(old *QuarantineManager) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	for model, previous := range old.models {
+		health := qm.health(model)
+		if len(previous.outcomes) == len(health.outcomes) {
+			copy(health.outcomes, previous.outcomes)
+			health.next, health.filled = previous.next, previous.filled
+		}
+		if previous.quarantine != nil {
+			quarantine := *previous.quarantine
+			health.quarantine = &quarantine
+		}
+	}
+}
+
+// health returns a model's state (no lock - called from locked context)
+func (qm *QuarantineManager) health(model string) *modelHealth {
+	health, ok := qm.models[model]
+	if !ok {
+		health = &modelHealth{outcomes: make([]bool, qm.config.Window)}
+		qm.models[model] = health
+	}
+	return health
+}
+
+// expire ends a lapsed quarantine (no lock - called from locked context)
+func (qm *QuarantineManager) expire(model string, health *modelHealth, now time.Time) {
+	if health.quarantine != nil && now.After(health.quarantine.Until) {
+		qm.release(model, health, "quarantine period elapsed")
+	}
+}
+
+// release clears a quarantine and the outcomes that caused it, so the model
+// must fail afresh to be quarantined again (no lock - called from locked context)
+func (qm *QuarantineManager) release(model string, health *modelHealth, reason string) {
+	rate := health.quarantine.SuccessRate
+	health.quarantine = nil
+	health.next = 0
+	health.filled = false
+
+	log.Printf("Released model %s from quarantine: %s", model, reason)
+	qm.notify(QuarantineEvent{
+		Event:       "released",
+		Model:       model,
+		SuccessRate: rate,
+		Reason:      reason,
+		Timestamp:   time.Now(),
+	})
+}
+
+// notify posts an event to the webhook without blocking routing
+func (qm *QuarantineManager) notify(event QuarantineEvent) {
+	if qm.onEvent != nil {
+		qm.onEvent(event)
+	}
+	if qm.config.WebhookURL == "" {
+		return
+	}
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		resp, err := qm.httpClient.Post(qm.config.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Quarantine webhook failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Quarantine webhook returned %d", resp.StatusCode)
+		}
+	}()
+}
+
+// isProviderFailure reports whether an error reflects the model or provider
+// misbehaving rather than the caller's request or rate limits
+func isProviderFailure(err *schemas.BifrostError) bool {
+	if err == nil {
+		return false
+	}
+	if err.StatusCode != nil {
+		return *err.StatusCode >= 500
+	}
+	return !err.IsBifrostError
+}
+
+// filterQuarantinedCandidates removes quarantined models, except for the
+// occasional probe that tests whether they have recovered
+func (p *Plugin) filterQuarantinedCandidates(candidates []string) []string {
+	if p.quarantine == nil {
+		return candidates
+	}
+
+	filtered := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if !p.quarantine.IsQuarantined(c) || p.quarantine.ShouldProbe() {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
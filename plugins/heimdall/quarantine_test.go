@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQuarantineConfig() QuarantineConfig {
+	return QuarantineConfig{
+		Enabled:        true,
+		Window:         10,
+		MinSamples:     5,
+		MinSuccessRate: 0.5,
+		Duration:       time.Hour,
+		RecoveryProbes: 2,
+	}
+}
+
+func TestQuarantineManager(t *testing.T) {
+	t.Run("should quarantine a model whose success rate collapses", func(t *testing.T) {
+		qm, err := NewQuarantineManager(testQuarantineConfig())
+		require.NoError(t, err)
+
+		for i := 0; i < 4; i++ {
+			qm.Record("openai/gpt-4o", false)
+		}
+		assert.False(t, qm.IsQuarantined("openai/gpt-4o"), "below min samples")
+
+		qm.Record("openai/gpt-4o", false)
+		assert.True(t, qm.IsQuarantined("openai/gpt-4o"))
+
+		status := qm.GetStatus()
+		require.Len(t, status, 1)
+		assert.Equal(t, 0.0, status[0].SuccessRate)
+	})
+
+	t.Run("should tolerate occasional failures", func(t *testing.T) {
+		qm, err := NewQuarantineManager(testQuarantineConfig())
+		require.NoError(t, err)
+
+		for i := 0; i < 20; i++ {
+			qm.Record("openai/gpt-4o", i%3 != 0)
+		}
+		assert.False(t, qm.IsQuarantined("openai/gpt-4o"))
+	})
+
+	t.Run("should recover after consecutive successful probes", func(t *testing.T) {
+		qm, err := NewQuarantineManager(testQuarantineConfig())
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			qm.Record("openai/gpt-4o", false)
+		}
+
+		qm.Record("openai/gpt-4o", true)
+		qm.Record("openai/gpt-4o", false)
+		qm.Record("openai/gpt-4o", true)
+		assert.True(t, qm.IsQuarantined("openai/gpt-4o"), "a failed probe resets recovery")
+
+		qm.Record("openai/gpt-4o", true)
+		assert.False(t, qm.IsQuarantined("openai/gpt-4o"))
+
+		qm.Record("openai/gpt-4o", false)
+		assert.False(t, qm.IsQuarantined("openai/gpt-4o"), "history is reset on release")
+	})
+
+	t.Run("should release when the period elapses", func(t *testing.T) {
+		config := testQuarantineConfig()
+		config.Duration = time.Millisecond
+		qm, err := NewQuarantineManager(config)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			qm.Record("openai/gpt-4o", false)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		assert.False(t, qm.IsQuarantined("openai/gpt-4o"))
+		assert.Error(t, qm.Release("openai/gpt-4o"))
+	})
+
+	t.Run("should notify the webhook", func(t *testing.T) {
+		events := make(chan QuarantineEvent, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event QuarantineEvent
+			json.NewDecoder(r.Body).Decode(&event)
+			events <- event
+		}))
+		defer server.Close()
+
+		config := testQuarantineConfig()
+		config.WebhookURL = server.URL
+		qm, err := NewQuarantineManager(config)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			qm.Record("openai/gpt-4o", false)
+		}
+		require.NoError(t, qm.Release("openai/gpt-4o"))
+
+		received := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			select {
+			case event := <-events:
+				assert.Equal(t, "openai/gpt-4o", event.Model)
+				received[event.Event] = true
+			case <-time.After(time.Second):
+				t.Fatal("webhook not called")
+			}
+		}
+		assert.Equal(t, map[string]bool{"quarantined": true, "released": true}, received)
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		_, err := NewQuarantineManager(QuarantineConfig{Window: 5, MinSamples: 10})
+		assert.Error(t, err)
+	})
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	status := func(code int) *int { return &code }
+
+	assert.False(t, isProviderFailure(nil))
+	assert.True(t, isProviderFailure(&schemas.BifrostError{StatusCode: status(503)}))
+	assert.False(t, isProviderFailure(&schemas.BifrostError{StatusCode: status(429)}))
+	assert.False(t, isProviderFailure(&schemas.BifrostError{StatusCode: status(400)}))
+	assert.True(t, isProviderFailure(&schemas.BifrostError{}), "transport failures have no status")
+	assert.False(t, isProviderFailure(&schemas.BifrostError{IsBifrostError: true}))
+}
+
+func TestQuarantineRouting(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Quarantine = testQuarantineConfig()
+	plugin := createRouterTestPluginWithConfig(t, config)
+	plugin.quarantine.config.ProbeRate = 0 // no probes, so filtering is deterministic
+
+	// Provider errors observed in PostHook feed the quarantine
+	serverError := 502
+	for i := 0; i < 5; i++ {
+		ctx := context.Background()
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, &RouterResponse{
+			Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+		})
+		require.NoError(t, err)
+		_, _, err = plugin.PostHook(&ctx, nil, &schemas.BifrostError{StatusCode: &serverError})
+		require.NoError(t, err)
+	}
+	require.True(t, plugin.quarantine.IsQuarantined("openai/gpt-4o"))
+
+	candidates := plugin.availableCandidates(config.Router.MidCandidates, &RequestFeatures{})
+	assert.NotContains(t, candidates, "openai/gpt-4o")
+	assert.Len(t, candidates, len(config.Router.MidCandidates)-1)
+
+	plugin.quarantine.config.ProbeRate = 1
+	assert.Contains(t, plugin.availableCandidates(config.Router.MidCandidates, &RequestFeatures{}), "openai/gpt-4o",
+		"probes still offer quarantined models")
+	plugin.quarantine.config.ProbeRate = 0
+
+	health := plugin.GetHealth()
+	require.Len(t, health.Quarantined, 1)
+
+	t.Run("should release via the admin API", func(t *testing.T) {
+		server := httptest.NewServer(plugin.AdminHandler())
+		defer server.Close()
+
+		req, _ := http.NewRequest("DELETE", server.URL+"/admin/quarantine/openai/gpt-4o", nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.False(t, plugin.quarantine.IsQuarantined("openai/gpt-4o"))
+	})
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// Error types of rejected requests, as OpenAI-compatible SDKs expect them
+const (
+	ErrorTypeInvalidRequest    = "invalid_request_error"
+	ErrorTypeAuthentication    = "authentication_error"
+	ErrorTypePermission        = "permission_error"
+	ErrorTypeRateLimit         = "rate_limit_error"
+	ErrorTypeInsufficientQuota = "insufficient_quota"
+	ErrorTypeAPI               = "api_error"
+)
+
+// RejectionError short-circuits a request with a structured error instead
+// of routing it. Middleware return one (or wrap one) to reject requests,
+// e.g. over a rate limit or budget; Heimdall itself rejects failed
+// authentication, request loops, empty prompts when configured to, and
+// requests no candidate is permitted for by the caller's routing policy.
+// Other decision errors fall back to a default route as before.
+type RejectionError struct {
+	StatusCode int
+	// Type is one of the ErrorType constants
+	Type string
+	// Code is a stable machine-readable reason, e.g. "repeated_request"
+	Code    string
+	Message string
+	// Param names the request parameter at fault, if any, e.g. "model"
+	Param string
+	// RetryAfter is when a retry may succeed; 0 when retrying will not help
+	RetryAfter time.Duration
+	// DecisionID identifies the rejection in Heimdall's logs; it is
+	// assigned when the rejection is answered
+	DecisionID string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Type, e.Code, e.Message)
+}
+
+// NewInvalidRequestError rejects a request that cannot be routed as sent
+// with 400
+func NewInvalidRequestError(code, message string) *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusBadRequest,
+		Type:       ErrorTypeInvalidRequest,
+		Code:       code,
+		Message:    message,
+	}
+}
+
+// NewUnavailableError rejects a request Heimdall could not route with 503
+func NewUnavailableError(code, message string) *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusServiceUnavailable,
+		Type:       ErrorTypeAPI,
+		Code:       code,
+		Message:    message,
+	}
+}
+
+// NewRateLimitError rejects a request with 429 until retryAfter
+func NewRateLimitError(code, message string, retryAfter time.Duration) *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusTooManyRequests,
+		Type:       ErrorTypeRateLimit,
+		Code:       code,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewBudgetError rejects a request over budget with 429; retryAfter is
+// when the budget resets, or 0
+func NewBudgetError(code, message string, retryAfter time.Duration) *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusTooManyRequests,
+		Type:       ErrorTypeInsufficientQuota,
+		Code:       code,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewPolicyError rejects a request a routing policy does not permit with 403
+func NewPolicyError(code, message string) *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusForbidden,
+		Type:       ErrorTypePermission,
+		Code:       code,
+		Message:    message,
+	}
+}
+
+// rejection is the rejection for failed authentication
+func (e AuthenticationError) rejection() *RejectionError {
+	return &RejectionError{
+		StatusCode: http.StatusUnauthorized,
+		Type:       ErrorTypeAuthentication,
+		Code:       "invalid_credentials",
+		Message:    e.Message,
+	}
+}
+
+// ErrorBody is the OpenAI-compatible body answering a rejected request
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail is the error object of an ErrorBody
+type ErrorDetail struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Code    string  `json:"code"`
+	Param   *string `json:"param"`
+	// RetryAfterSeconds is set when a retry may succeed
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	DecisionID        string `json:"decision_id"`
+}
+
+// retryAfterSeconds rounds the retry hint up to whole seconds
+func (e *RejectionError) retryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Body renders the rejection as an OpenAI-compatible error body
+func (e *RejectionError) Body() ErrorBody {
+	body := ErrorBody{Error: ErrorDetail{
+		Message:           e.Message,
+		Type:              e.Type,
+		Code:              e.Code,
+		RetryAfterSeconds: e.retryAfterSeconds(),
+		DecisionID:        e.DecisionID,
+	}}
+	if e.Param != "" {
+		param := e.Param
+		body.Error.Param = &param
+	}
+	return body
+}
+
+// bifrostError renders the rejection for Bifrost, which answers with its
+// error field as the OpenAI error object. Bifrost has no place for retry
+// hints, so they are appended to the message; the decision ID is the
+// event ID.
+func (e *RejectionError) bifrostError() *schemas.BifrostError {
+	statusCode := e.StatusCode
+	allowFallbacks := false
+	errorType, code, decisionID := e.Type, e.Code, e.DecisionID
+	message := e.Message
+	if seconds := e.retryAfterSeconds(); seconds > 0 {
+		message = fmt.Sprintf("%s; retry after %s", message, time.Duration(seconds)*time.Second)
+	}
+	bifrostErr := &schemas.BifrostError{
+		EventID:        &decisionID,
+		Type:           &errorType,
+		StatusCode:     &statusCode,
+		AllowFallbacks: &allowFallbacks,
+		Error: schemas.ErrorField{
+			Type:    &errorType,
+			Code:    &code,
+			Message: message,
+			EventID: &decisionID,
+		},
+	}
+	if e.Param != "" {
+		bifrostErr.Error.Param = e.Param
+	}
+	return bifrostErr
+}
+
+// answered copies a rejection for answering, assigning its decision ID
+func (e *RejectionError) answered() *RejectionError {
+	copied := *e
+	if copied.DecisionID == "" {
+		copied.DecisionID = newLabelID()
+	}
+	return &copied
+}
+
+// reject short-circuits req with rejection. The rejection is in the request
+// context under "heimdall_rejection", for hosts that set Retry-After.
+func (p *Plugin) reject(ctx *context.Context, req *schemas.BifrostRequest, rejection *RejectionError) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
+	p.metricsMu.Lock()
+	p.errorCount++
+	p.metricsMu.Unlock()
+
+	rejection = rejection.answered()
+	p.logger.Printf("Heimdall rejected request %s: %v", rejection.DecisionID, rejection)
+	*ctx = context.WithValue(*ctx, "heimdall_rejection", rejection)
+
+	return req, &schemas.PluginShortCircuit{Error: rejection.bifrostError()}, nil
+}
+
+// writeRejection answers an HTTP request with a rejection's error body and
+// Retry-After hint
+func (p *Plugin) writeRejection(w http.ResponseWriter, rejection *RejectionError) {
+	rejection = rejection.answered()
+	p.logger.Printf("Heimdall rejected request %s: %v", rejection.DecisionID, rejection)
+	if seconds := rejection.retryAfterSeconds(); seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	writeJSON(w, rejection.StatusCode, rejection.Body())
+}
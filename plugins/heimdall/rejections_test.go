@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectionError(t *testing.T) {
+	t.Run("should render an OpenAI-compatible body", func(t *testing.T) {
+		rejection := NewRateLimitError("tenant_rate_limit", "too many requests", 1500*time.Millisecond).answered()
+		data, err := json.Marshal(rejection.Body())
+		require.NoError(t, err)
+
+		var body map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &body))
+		assert.Equal(t, "too many requests", body["error"]["message"])
+		assert.Equal(t, ErrorTypeRateLimit, body["error"]["type"])
+		assert.Equal(t, "tenant_rate_limit", body["error"]["code"])
+		assert.Nil(t, body["error"]["param"])
+		assert.Equal(t, 2.0, body["error"]["retry_after_seconds"], "rounded up")
+		assert.Len(t, body["error"]["decision_id"], 24)
+	})
+
+	t.Run("should carry the decision ID and retry hint to Bifrost", func(t *testing.T) {
+		rejection := NewBudgetError("monthly_budget", "monthly budget exhausted", time.Hour).answered()
+		bifrostErr := rejection.bifrostError()
+		assert.Equal(t, http.StatusTooManyRequests, *bifrostErr.StatusCode)
+		assert.Equal(t, ErrorTypeInsufficientQuota, *bifrostErr.Error.Type)
+		assert.Equal(t, "monthly_budget", *bifrostErr.Error.Code)
+		assert.Equal(t, rejection.DecisionID, *bifrostErr.EventID)
+		assert.Equal(t, "monthly budget exhausted; retry after 1h0m0s", bifrostErr.Error.Message)
+		assert.False(t, *bifrostErr.AllowFallbacks)
+
+		policyErr := NewPolicyError("no_permitted_candidates", "denied").bifrostError()
+		assert.Equal(t, "denied", policyErr.Error.Message, "no hint without a retry")
+	})
+}
+
+func TestRejections(t *testing.T) {
+	limiter := func(next DecideFunc) DecideFunc {
+		return func(ctx context.Context, req *RouterRequest, headers map[string][]string) (*RouterResponse, error) {
+			return nil, NewRateLimitError("tenant_rate_limit", "too many requests", 30*time.Second)
+		}
+	}
+
+	t.Run("should short-circuit requests middleware rejects", func(t *testing.T) {
+		plugin, err := NewWithOptions(createRouterTestConfig(), WithMiddleware(limiter))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{})
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit)
+		assert.Equal(t, http.StatusTooManyRequests, *shortCircuit.Error.StatusCode)
+		assert.Equal(t, "tenant_rate_limit", *shortCircuit.Error.Error.Code)
+
+		rejection, ok := ctx.Value("heimdall_rejection").(*RejectionError)
+		require.True(t, ok)
+		assert.Equal(t, 30*time.Second, rejection.RetryAfter)
+		assert.Equal(t, rejection.DecisionID, *shortCircuit.Error.EventID)
+		assert.Nil(t, ctx.Value("heimdall_decision"), "no default route")
+	})
+
+	t.Run("should reject requests no candidate is permitted for", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		authInfo := &AuthInfo{Type: "jwt", Policy: &RoutingPolicy{Candidates: []string{"openai/o1"}}}
+		_, err := plugin.selectModel(BucketCheap, &RequestFeatures{TokenCount: 500}, authInfo, false)
+
+		var rejection *RejectionError
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, http.StatusForbidden, rejection.StatusCode)
+		assert.Equal(t, ErrorTypePermission, rejection.Type)
+		assert.Equal(t, "no_permitted_candidates", rejection.Code)
+	})
+
+	t.Run("should answer advisory requests with the body and Retry-After", func(t *testing.T) {
+		plugin, err := NewWithOptions(createRouterTestConfig(), WithMiddleware(limiter))
+		require.NoError(t, err)
+		server := httptest.NewServer(plugin.RoutingHandler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/v1/route", "application/json",
+			strings.NewReader(`{"model": "auto", "messages": [{"role": "user", "content": "hi"}]}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+		var body ErrorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "tenant_rate_limit", body.Error.Code)
+		assert.Equal(t, 30, body.Error.RetryAfterSeconds)
+		assert.NotEmpty(t, body.Error.DecisionID)
+	})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// RequestShapingConfig sets the headers and query parameters requests to
+// each provider kind must carry, e.g. OpenRouter attribution, Anthropic's
+// API version or Azure's api-version. Bifrost has no per-request headers,
+// so shaping reaches upstreams through the provider's NetworkConfig
+// (ShapeProviderConfig, headers only), transports applying the request
+// context's "heimdall_outbound" (ApplyOutbound), the Envoy adapter (the
+// selected provider's headers) and the decision returned by the routing
+// service.
+type RequestShapingConfig struct {
+	// Providers shape requests by provider kind. Entries are merged over
+	// the built-in defaults; an empty value drops a default.
+	Providers map[string]OutboundShaping `json:"providers"`
+}
+
+// OutboundShaping is what a provider's requests carry
+type OutboundShaping struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Query   map[string]string `json:"query,omitempty"`
+}
+
+// defaultRequestShaping pins API versions upstreams reject requests without
+var defaultRequestShaping = map[string]OutboundShaping{
+	"anthropic": {Headers: map[string]string{"Anthropic-Version": "2023-06-01"}},
+}
+
+// reservedOutboundHeaders are set by the transport or the auth mode and
+// must not be shaped
+var reservedOutboundHeaders = map[string]bool{
+	"Authorization":  true,
+	"Host":           true,
+	"Content-Length": true,
+	"Content-Type":   true,
+}
+
+// RequestShaper resolves the outbound shaping of decisions
+type RequestShaper struct {
+	providers map[string]OutboundShaping
+}
+
+// NewRequestShaper merges the config over the defaults, validating header
+// names
+func NewRequestShaper(config RequestShapingConfig) (*RequestShaper, error) {
+	providers := make(map[string]OutboundShaping)
+	merge := func(kind string, shaping OutboundShaping) error {
+		merged := providers[kind]
+		for name, value := range shaping.Headers {
+			canonical := http.CanonicalHeaderKey(name)
+			if canonical == "" || strings.ContainsAny(canonical, " :\t\r\n") {
+				return fmt.Errorf("invalid header name %q for %s", name, kind)
+			}
+			if reservedOutboundHeaders[canonical] {
+				return fmt.Errorf("header %s for %s is set by the transport", canonical, kind)
+			}
+			merged.Headers = setOrDrop(merged.Headers, canonical, value)
+		}
+		for name, value := range shaping.Query {
+			if name == "" {
+				return fmt.Errorf("empty query parameter name for %s", kind)
+			}
+			merged.Query = setOrDrop(merged.Query, name, value)
+		}
+		providers[kind] = merged
+		return nil
+	}
+
+	for kind, shaping := range defaultRequestShaping {
+		if err := merge(kind, shaping); err != nil {
+			return nil, err
+		}
+	}
+	for kind, shaping := range config.Providers {
+		if err := merge(kind, shaping); err != nil {
+			return nil, err
+		}
+	}
+	return &RequestShaper{providers: providers}, nil
+}
+
+// setOrDrop sets name to value in values, or deletes it when value is empty
+func setOrDrop(values map[string]string, name, value string) map[string]string {
+	if value == "" {
+		delete(values, name)
+		return values
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+	values[name] = value
+	return values
+}
+
+// For returns the shaping of a provider kind's requests
+func (rs *RequestShaper) For(kind string) (OutboundShaping, bool) {
+	shaping, ok := rs.providers[kind]
+	if !ok || len(shaping.Headers)+len(shaping.Query) == 0 {
+		return OutboundShaping{}, false
+	}
+	return shaping, true
+}
+
+// Apply sets the shaping's headers and query parameters on an outgoing
+// request, replacing values the request already has
+func (s OutboundShaping) Apply(outgoing *http.Request) *http.Request {
+	for name, value := range s.Headers {
+		outgoing.Header.Set(name, value)
+	}
+	if len(s.Query) > 0 {
+		query := outgoing.URL.Query()
+		for name, value := range s.Query {
+			query.Set(name, value)
+		}
+		outgoing.URL.RawQuery = query.Encode()
+	}
+	return outgoing
+}
+
+// ApplyOutbound shapes a transport's outgoing request to a provider kind
+// with the shaping of the decision in ctx, which covers the decision's
+// model and fallbacks. Requests without a decision are returned as is.
+func (p *Plugin) ApplyOutbound(ctx context.Context, kind string, outgoing *http.Request) *http.Request {
+	outbound, _ := ctx.Value("heimdall_outbound").(map[string]OutboundShaping)
+	shaping, ok := outbound[kind]
+	if !ok {
+		return outgoing
+	}
+	return shaping.Apply(outgoing)
+}
+
+// ShapeProviderConfig adds a provider kind's shaping headers to its Bifrost
+// provider config, for Accounts to call from GetConfigForProvider. Bifrost
+// builds request URLs from the base URL, so query parameters cannot be set
+// this way and are an error; apply them with ApplyOutbound instead.
+func (p *Plugin) ShapeProviderConfig(kind string, config *schemas.ProviderConfig) error {
+	shaping, ok := p.shaper.For(kind)
+	if !ok {
+		return nil
+	}
+	if len(shaping.Query) > 0 {
+		return fmt.Errorf("query parameters %v for %s cannot be set in the provider config",
+			slices.Sorted(maps.Keys(shaping.Query)), kind)
+	}
+
+	headers := make(map[string]string, len(config.NetworkConfig.ExtraHeaders)+len(shaping.Headers))
+	maps.Copy(headers, config.NetworkConfig.ExtraHeaders)
+	maps.Copy(headers, shaping.Headers)
+	config.NetworkConfig.ExtraHeaders = headers
+	return nil
+}
+
+// shapeRequests sets a decision's outbound shaping for the provider kinds
+// of its model and fallbacks
+func (p *Plugin) shapeRequests(decision *RouterDecision) {
+	decision.Outbound = nil
+	for _, model := range append([]string{decision.Model}, decision.Fallbacks...) {
+		kind := p.inferProviderKind(model)
+		if model == decision.Model && decision.Kind != "" {
+			kind = decision.Kind
+		}
+		if shaping, ok := p.shaper.For(kind); ok {
+			if decision.Outbound == nil {
+				decision.Outbound = make(map[string]OutboundShaping)
+			}
+			decision.Outbound[kind] = shaping
+		}
+	}
+}
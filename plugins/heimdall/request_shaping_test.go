@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestShaper(t *testing.T) {
+	t.Run("should merge configured shaping over the defaults", func(t *testing.T) {
+		rs, err := NewRequestShaper(RequestShapingConfig{Providers: map[string]OutboundShaping{
+			"openrouter": {Headers: map[string]string{"http-referer": "https://example.com", "X-Title": "Example"}},
+			"azure":      {Query: map[string]string{"api-version": "2024-10-21"}},
+		}})
+		require.NoError(t, err)
+
+		shaping, ok := rs.For("openrouter")
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{"Http-Referer": "https://example.com", "X-Title": "Example"}, shaping.Headers)
+		shaping, _ = rs.For("azure")
+		assert.Equal(t, "2024-10-21", shaping.Query["api-version"])
+		shaping, _ = rs.For("anthropic")
+		assert.Equal(t, "2023-06-01", shaping.Headers["Anthropic-Version"])
+		_, ok = rs.For("openai")
+		assert.False(t, ok)
+	})
+
+	t.Run("should override and drop defaults", func(t *testing.T) {
+		rs, err := NewRequestShaper(RequestShapingConfig{Providers: map[string]OutboundShaping{
+			"anthropic": {Headers: map[string]string{"anthropic-version": ""}},
+		}})
+		require.NoError(t, err)
+		_, ok := rs.For("anthropic")
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject reserved and malformed headers", func(t *testing.T) {
+		_, err := NewRequestShaper(RequestShapingConfig{Providers: map[string]OutboundShaping{
+			"openai": {Headers: map[string]string{"authorization": "Bearer sk-test"}},
+		}})
+		assert.ErrorContains(t, err, "set by the transport")
+		_, err = NewRequestShaper(RequestShapingConfig{Providers: map[string]OutboundShaping{
+			"openai": {Headers: map[string]string{"X Title": "Example"}},
+		}})
+		assert.ErrorContains(t, err, "invalid header name")
+	})
+}
+
+func TestRequestShaping(t *testing.T) {
+	config := createRouterTestConfig()
+	probe := createRouterTestPluginWithConfig(t, config)
+	config.RequestShaping.Providers = map[string]OutboundShaping{}
+	for _, candidates := range [][]string{config.Router.CheapCandidates, config.Router.MidCandidates, config.Router.HardCandidates} {
+		for _, model := range candidates {
+			config.RequestShaping.Providers[probe.inferProviderKind(model)] = OutboundShaping{Headers: map[string]string{"X-Title": "Heimdall"}}
+		}
+	}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	t.Run("should shape the model and every fallback provider", func(t *testing.T) {
+		decision := &RouterDecision{Kind: "openai", Model: "openai/gpt-4o", Fallbacks: []string{"anthropic/claude-3.5-sonnet"}}
+		plugin.shapeRequests(decision)
+		assert.Equal(t, "Heimdall", decision.Outbound["openai"].Headers["X-Title"])
+		assert.Equal(t, "2023-06-01", decision.Outbound["anthropic"].Headers["Anthropic-Version"])
+	})
+
+	t.Run("should expose shaping to transports in the request context", func(t *testing.T) {
+		content := "What is 2+2?"
+		ctx := context.Background()
+		result, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+			ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}},
+		}})
+		require.NoError(t, err)
+
+		outbound, ok := ctx.Value("heimdall_outbound").(map[string]OutboundShaping)
+		require.True(t, ok)
+		assert.Equal(t, "Heimdall", outbound[plugin.inferProviderKind(result.Model)].Headers["X-Title"])
+	})
+
+	t.Run("should add the provider's headers upstream through Envoy", func(t *testing.T) {
+		server := httptest.NewServer(plugin.ExtAuthzHandler())
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/authz/v1/chat/completions", "application/json",
+			strings.NewReader(`{"model": "auto", "messages": [{"role": "user", "content": "What is 2+2?"}]}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.NotEmpty(t, resp.Header.Get("X-Heimdall-Model"))
+		assert.Equal(t, "Heimdall", resp.Header.Get("X-Title"))
+	})
+
+	t.Run("should apply the provider's shaping to the outgoing request", func(t *testing.T) {
+		var received *http.Request
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+		}))
+		defer upstream.Close()
+
+		config := createRouterTestConfig()
+		config.RequestShaping.Providers = map[string]OutboundShaping{
+			"azure": {Headers: map[string]string{"X-Title": "Heimdall"}, Query: map[string]string{"api-version": "2024-10-21"}},
+		}
+		plugin := createRouterTestPluginWithConfig(t, config)
+		decision := &RouterDecision{Kind: "azure", Model: "azure/gpt-4o"}
+		plugin.shapeRequests(decision)
+		ctx := context.WithValue(context.Background(), "heimdall_outbound", decision.Outbound)
+
+		outgoing, err := http.NewRequest("POST", upstream.URL+"/openai/deployments/gpt-4o/chat/completions?stream=false", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(plugin.ApplyOutbound(ctx, "azure", outgoing))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.NotNil(t, received)
+		assert.Equal(t, "Heimdall", received.Header.Get("X-Title"))
+		assert.Equal(t, "2024-10-21", received.URL.Query().Get("api-version"))
+		assert.Equal(t, "false", received.URL.Query().Get("stream"), "existing parameters are kept")
+
+		untouched, err := http.NewRequest("POST", upstream.URL, nil)
+		require.NoError(t, err)
+		assert.Empty(t, plugin.ApplyOutbound(ctx, "openai", untouched).Header)
+	})
+
+	t.Run("should add headers to Bifrost provider configs", func(t *testing.T) {
+		providerConfig := &schemas.ProviderConfig{NetworkConfig: schemas.NetworkConfig{ExtraHeaders: map[string]string{"X-Team": "search"}}}
+		require.NoError(t, plugin.ShapeProviderConfig("anthropic", providerConfig))
+		assert.Equal(t, map[string]string{"X-Team": "search", "X-Title": "Heimdall", "Anthropic-Version": "2023-06-01"},
+			providerConfig.NetworkConfig.ExtraHeaders)
+
+		config := createRouterTestConfig()
+		config.RequestShaping.Providers = map[string]OutboundShaping{"azure": {Query: map[string]string{"api-version": "2024-10-21"}}}
+		err := createRouterTestPluginWithConfig(t, config).ShapeProviderConfig("azure", &schemas.ProviderConfig{})
+		assert.ErrorContains(t, err, "cannot be set in the provider config")
+	})
+
+	t.Run("should reject invalid shaping", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.RequestShaping.Providers = map[string]OutboundShaping{"openai": {Headers: map[string]string{"Host": "example.com"}}}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid request shaping config")
+	})
+}
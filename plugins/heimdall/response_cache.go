@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+)
+
+// ResponseCacheConfig configures the exact-match response cache, which
+// answers repeated identical prompts (same messages, model and params) from
+// a cached completion without calling the provider. Only enable it for
+// idempotent traffic such as health-check prompts or template expansions.
+type ResponseCacheConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// TTL expires cached responses (default 5m)
+	TTL time.Duration `json:"ttl"`
+
+	// MaxEntries bounds the cache; the oldest entry is evicted (default 1000)
+	MaxEntries int `json:"max_entries"`
+
+	// MaxEntryBytes skips caching larger responses (default 64KiB)
+	MaxEntryBytes int `json:"max_entry_bytes"`
+
+	// SimilarityThreshold enables semantic matching: a prompt whose
+	// embedding has at least this cosine similarity to a cached prompt's,
+	// with the same caller and params, is answered from the cache. Keep it
+	// strict (e.g. 0.98); zero disables semantic matching.
+	SimilarityThreshold float64 `json:"similarity_threshold"`
+}
+
+// responseCacheEntry is one encoded, possibly sealed, response
+type responseCacheEntry struct {
+	data      []byte
+	storedAt  time.Time
+	expiresAt time.Time
+
+	// scope and embedding are set for entries eligible for semantic hits
+	scope     string
+	embedding []float64
+
+	// owner is the caller the response was served to, for data-subject
+	// requests
+	owner DataSubject
+}
+
+// CachedResponse is a cached response exported for a data-subject request
+type CachedResponse struct {
+	StoredAt  time.Time                `json:"stored_at"`
+	ExpiresAt time.Time                `json:"expires_at"`
+	Response  *schemas.BifrostResponse `json:"response"`
+}
+
+// ResponseCacheStats counts response cache activity
+type ResponseCacheStats struct {
+	Entries   int   `json:"entries"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+
+	// SemanticHits counts the hits served for similar, not identical, prompts
+	SemanticHits int64 `json:"semantic_hits"`
+}
+
+// ResponseCache stores provider responses by request key
+type ResponseCache struct {
+	config  ResponseCacheConfig
+	cipher  *EntryCipher // nil when cache encryption is disabled
+	now     func() time.Time
+	entries map[string]responseCacheEntry
+	stats   ResponseCacheStats
+	mu      sync.Mutex
+}
+
+// NewResponseCache creates a response cache, sealing entries with cipher
+// when it is set
+func NewResponseCache(config ResponseCacheConfig, cipher *EntryCipher, now func() time.Time) (*ResponseCache, error) {
+	if config.TTL < 0 || config.MaxEntries < 0 || config.MaxEntryBytes < 0 {
+		return nil, fmt.Errorf("ttl, max_entries and max_entry_bytes must not be negative")
+	}
+	if config.SimilarityThreshold < 0 || config.SimilarityThreshold > 1 {
+		return nil, fmt.Errorf("similarity_threshold must be between 0 and 1")
+	}
+	if config.TTL == 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.MaxEntries == 0 {
+		config.MaxEntries = 1000
+	}
+	if config.MaxEntryBytes == 0 {
+		config.MaxEntryBytes = 64 << 10
+	}
+	return &ResponseCache{
+		config:  config,
+		cipher:  cipher,
+		now:     now,
+		entries: make(map[string]responseCacheEntry),
+	}, nil
+}
+
+// Key identifies a request by caller credentials, model, messages and
+// params; it holds no request content in the clear
+func (rc *ResponseCache) Key(req *schemas.BifrostRequest, headers map[string][]string) string {
+	data, _ := json.Marshal(struct {
+		Model  string                   `json:"model"`
+		Input  schemas.RequestInput     `json:"input"`
+		Params *schemas.ModelParameters `json:"params,omitempty"`
+	}{req.Model, req.Input, req.Params})
+
+	// Cached answers must never cross tenants
+	requestHash := sha256.Sum256(data)
+	return fmt.Sprintf("%s:%x", auth.TokenFingerprint(credentialsOf(headers)), requestHash)
+}
+
+// Scope groups requests whose responses may answer each other's similar
+// prompts: the same caller, model and params
+func (rc *ResponseCache) Scope(req *schemas.BifrostRequest, headers map[string][]string) string {
+	data, _ := json.Marshal(struct {
+		Model  string                   `json:"model"`
+		Params *schemas.ModelParameters `json:"params,omitempty"`
+	}{req.Model, req.Params})
+
+	paramsHash := sha256.Sum256(data)
+	return fmt.Sprintf("%s:%x", auth.TokenFingerprint(credentialsOf(headers)), paramsHash)
+}
+
+// Semantic reports whether similar prompts may be answered from the cache
+func (rc *ResponseCache) Semantic() bool {
+	return rc.config.SimilarityThreshold > 0
+}
+
+// Get returns the cached response for key, if fresh
+func (rc *ResponseCache) Get(key string) (*schemas.BifrostResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	res, ok := rc.open(key)
+	if !ok {
+		rc.stats.Misses++
+		return nil, false
+	}
+	rc.stats.Hits++
+	return res, true
+}
+
+// GetSimilar returns the fresh response in scope whose prompt embedding is
+// most similar to embedding, if it reaches the similarity threshold
+func (rc *ResponseCache) GetSimilar(scope string, embedding []float64) (*schemas.BifrostResponse, float64, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := rc.now()
+	bestKey, bestSimilarity := "", 0.0
+	for key, entry := range rc.entries {
+		if entry.scope != scope || now.After(entry.expiresAt) {
+			continue
+		}
+		if similarity := cosineSimilarity(embedding, entry.embedding); similarity >= rc.config.SimilarityThreshold && similarity > bestSimilarity {
+			bestKey, bestSimilarity = key, similarity
+		}
+	}
+	if bestKey == "" {
+		return nil, 0, false
+	}
+
+	res, ok := rc.open(bestKey)
+	if !ok {
+		return nil, 0, false
+	}
+	rc.stats.Hits++
+	rc.stats.SemanticHits++
+	return res, bestSimilarity, true
+}
+
+// open decodes a fresh entry, dropping it when expired or unreadable;
+// callers hold mu
+func (rc *ResponseCache) open(key string) (*schemas.BifrostResponse, bool) {
+	entry, ok := rc.entries[key]
+	if !ok || rc.now().After(entry.expiresAt) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+
+	data := entry.data
+	if rc.cipher != nil {
+		var err error
+		if data, err = rc.cipher.Open(data, []byte(key)); err != nil {
+			delete(rc.entries, key)
+			return nil, false
+		}
+	}
+	var res schemas.BifrostResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return &res, true
+}
+
+// Set caches a complete response under key, evicting the oldest entry when
+// the cache is full. Responses stored with a scope and prompt embedding can
+// also answer similar prompts.
+func (rc *ResponseCache) Set(key string, scope string, owner DataSubject, embedding []float64, res *schemas.BifrostResponse) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	if len(data) > rc.config.MaxEntryBytes {
+		return nil
+	}
+	if rc.cipher != nil {
+		if data, err = rc.cipher.Seal(data, []byte(key)); err != nil {
+			return fmt.Errorf("failed to encrypt response: %w", err)
+		}
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := rc.now()
+	if _, exists := rc.entries[key]; !exists && len(rc.entries) >= rc.config.MaxEntries {
+		rc.evictOldest()
+	}
+	rc.entries[key] = responseCacheEntry{
+		data:      data,
+		storedAt:  now,
+		expiresAt: now.Add(rc.config.TTL),
+		scope:     scope,
+		embedding: embedding,
+		owner:     owner,
+	}
+	return nil
+}
+
+// SubjectEntries returns the fresh responses cached for an identity,
+// oldest first
+func (rc *ResponseCache) SubjectEntries(subject DataSubject) []CachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var cached []CachedResponse
+	for key, entry := range rc.entries {
+		if !subject.covers(entry.owner) {
+			continue
+		}
+		if res, ok := rc.open(key); ok {
+			cached = append(cached, CachedResponse{StoredAt: entry.storedAt, ExpiresAt: entry.expiresAt, Response: res})
+		}
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].StoredAt.Before(cached[j].StoredAt) })
+	return cached
+}
+
+// DeleteSubject removes every response cached for an identity, returning
+// how many were removed
+func (rc *ResponseCache) DeleteSubject(subject DataSubject) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	deleted := 0
+	for key, entry := range rc.entries {
+		if subject.covers(entry.owner) {
+			delete(rc.entries, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// evictOldest removes the least recently stored entry; callers hold mu
+func (rc *ResponseCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range rc.entries {
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	if oldestKey != "" {
+		delete(rc.entries, oldestKey)
+		rc.stats.Evictions++
+	}
+}
+
+// GetStats returns the cache's activity counters
+func (rc *ResponseCache) GetStats() ResponseCacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	stats := rc.stats
+	stats.Entries = len(rc.entries)
+	return stats
+}
+
+// similarResponse answers a request from the response cache when a similar
+// prompt was answered before and the caller's policy allows it; otherwise it
+// remembers the prompt embedding so the response can be cached
+func (p *Plugin) similarResponse(ctx *context.Context, response *RouterResponse) *schemas.PluginShortCircuit {
+	scope, ok := (*ctx).Value("heimdall_response_cache_scope").(string)
+	embedding := response.Features.Embedding
+	if !ok || len(embedding) == 0 || !policyFor(response.AuthInfo).AllowsSemanticCache() {
+		return nil
+	}
+
+	if cached, similarity, ok := p.responses.GetSimilar(scope, embedding); ok {
+		*ctx = context.WithValue(*ctx, "heimdall_response_cache_hit", true)
+		*ctx = context.WithValue(*ctx, "heimdall_response_cache_similarity", similarity)
+		return &schemas.PluginShortCircuit{Response: cached}
+	}
+	*ctx = context.WithValue(*ctx, "heimdall_response_cache_embedding", embedding)
+	return nil
+}
+
+// cosineSimilarity of two vectors; 0 when they differ in length or either
+// is zero
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	prompt := func(content string) *schemas.BifrostRequest {
+		return &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		}
+	}
+
+	t.Run("should key by prompt, params and caller", func(t *testing.T) {
+		cache, err := NewResponseCache(ResponseCacheConfig{}, nil, clock)
+		require.NoError(t, err)
+		alice := map[string][]string{"Authorization": {"Bearer alice"}}
+		bob := map[string][]string{"Authorization": {"Bearer bob"}}
+
+		assert.Equal(t, cache.Key(prompt("ping"), alice), cache.Key(prompt("ping"), alice))
+		assert.NotEqual(t, cache.Key(prompt("ping"), alice), cache.Key(prompt("pong"), alice))
+		assert.NotEqual(t, cache.Key(prompt("ping"), alice), cache.Key(prompt("ping"), bob))
+
+		temperature := 0.7
+		withParams := prompt("ping")
+		withParams.Params = &schemas.ModelParameters{Temperature: &temperature}
+		assert.NotEqual(t, cache.Key(prompt("ping"), alice), cache.Key(withParams, alice))
+		assert.NotContains(t, cache.Key(prompt("ping"), alice), "ping")
+	})
+
+	t.Run("should expire entries after the ttl", func(t *testing.T) {
+		cache, err := NewResponseCache(ResponseCacheConfig{TTL: time.Minute}, nil, clock)
+		require.NoError(t, err)
+		require.NoError(t, cache.Set("key", "", DataSubject{}, nil, textResponse("pong", "stop")))
+
+		res, ok := cache.Get("key")
+		require.True(t, ok)
+		assert.Equal(t, "pong", messageText(res.Choices[0].Message))
+
+		now = now.Add(2 * time.Minute)
+		_, ok = cache.Get("key")
+		assert.False(t, ok)
+	})
+
+	t.Run("should bound entries and entry size", func(t *testing.T) {
+		cache, err := NewResponseCache(ResponseCacheConfig{MaxEntries: 2, MaxEntryBytes: 512}, nil, clock)
+		require.NoError(t, err)
+		for _, key := range []string{"a", "b", "c"} {
+			now = now.Add(time.Second)
+			require.NoError(t, cache.Set(key, "", DataSubject{}, nil, textResponse("pong", "stop")))
+		}
+		_, ok := cache.Get("a")
+		assert.False(t, ok, "oldest entry should be evicted")
+		_, ok = cache.Get("c")
+		assert.True(t, ok)
+
+		require.NoError(t, cache.Set("large", "", DataSubject{}, nil, textResponse(string(make([]byte, 1024)), "stop")))
+		_, ok = cache.Get("large")
+		assert.False(t, ok)
+
+		stats := cache.GetStats()
+		assert.Equal(t, 2, stats.Entries)
+		assert.Equal(t, int64(1), stats.Evictions)
+	})
+
+	t.Run("should reject negative limits", func(t *testing.T) {
+		_, err := NewResponseCache(ResponseCacheConfig{MaxEntries: -1}, nil, clock)
+		assert.Error(t, err)
+	})
+}
+
+func TestResponseCacheShortCircuit(t *testing.T) {
+	config := createRouterTestConfig()
+	config.ResponseCache = ResponseCacheConfig{Enabled: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	content := "health check"
+	newRequest := func() *schemas.BifrostRequest {
+		return &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		}
+	}
+
+	ctx := context.Background()
+	_, shortCircuit, err := plugin.PreHook(&ctx, newRequest())
+	require.NoError(t, err)
+	assert.Nil(t, shortCircuit, "first request should reach the provider")
+	_, _, err = plugin.PostHook(&ctx, textResponse("ok", "stop"), nil)
+	require.NoError(t, err)
+
+	ctx = context.Background()
+	_, shortCircuit, err = plugin.PreHook(&ctx, newRequest())
+	require.NoError(t, err)
+	require.NotNil(t, shortCircuit)
+	require.NotNil(t, shortCircuit.Response)
+	assert.Equal(t, "ok", messageText(shortCircuit.Response.Choices[0].Message))
+	assert.Equal(t, true, ctx.Value("heimdall_response_cache_hit"))
+
+	stats := plugin.GetMetrics()["response_cache"].(ResponseCacheStats)
+	assert.Equal(t, int64(1), stats.Hits)
+
+	t.Run("should authenticate callers before serving cached responses", func(t *testing.T) {
+		plugin.config.Anonymous.Mode = AnonymousModeReject
+		defer func() { plugin.config.Anonymous.Mode = AnonymousModeAllow }()
+
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, newRequest())
+		require.NoError(t, err)
+		require.NotNil(t, shortCircuit)
+		require.NotNil(t, shortCircuit.Error)
+		assert.Equal(t, http.StatusUnauthorized, *shortCircuit.Error.StatusCode)
+		assert.Nil(t, ctx.Value("heimdall_response_cache_hit"))
+	})
+}
+
+func TestSemanticResponseCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("should match prompts above the similarity threshold within a scope", func(t *testing.T) {
+		cache, err := NewResponseCache(ResponseCacheConfig{SimilarityThreshold: 0.95}, nil, clock)
+		require.NoError(t, err)
+		require.True(t, cache.Semantic())
+		require.NoError(t, cache.Set("a", "alice", DataSubject{}, []float64{1, 0, 0}, textResponse("cached", "stop")))
+
+		res, similarity, ok := cache.GetSimilar("alice", []float64{1, 0.1, 0})
+		require.True(t, ok)
+		assert.InDelta(t, 0.995, similarity, 0.001)
+		assert.Equal(t, "cached", messageText(res.Choices[0].Message))
+
+		_, _, ok = cache.GetSimilar("alice", []float64{1, 1, 0})
+		assert.False(t, ok, "dissimilar prompts should miss")
+		_, _, ok = cache.GetSimilar("bob", []float64{1, 0, 0})
+		assert.False(t, ok, "other scopes should miss")
+
+		assert.Equal(t, int64(1), cache.GetStats().SemanticHits)
+	})
+
+	t.Run("should reject thresholds outside [0, 1]", func(t *testing.T) {
+		_, err := NewResponseCache(ResponseCacheConfig{SimilarityThreshold: 1.5}, nil, clock)
+		assert.Error(t, err)
+	})
+
+	config := createRouterTestConfig()
+	config.ResponseCache = ResponseCacheConfig{Enabled: true, SimilarityThreshold: 0.98}
+	plugin, err := NewWithOptions(config, WithEmbeddingProvider(&fixedEmbedder{embedding: []float64{0.6, 0.8}}))
+	require.NoError(t, err)
+	plugin.currentArtifact = createRouterTestPlugin(t).currentArtifact
+	plugin.lastArtifactLoad = time.Now()
+
+	ask := func(content string) (context.Context, *schemas.PluginShortCircuit) {
+		ctx := context.Background()
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		})
+		require.NoError(t, err)
+		return ctx, shortCircuit
+	}
+
+	t.Run("should answer similar prompts with an annotated cache hit", func(t *testing.T) {
+		ctx, shortCircuit := ask("What are your opening hours?")
+		require.Nil(t, shortCircuit)
+		_, _, err := plugin.PostHook(&ctx, textResponse("9 to 5", "stop"), nil)
+		require.NoError(t, err)
+
+		ctx, shortCircuit = ask("When are you open?")
+		require.NotNil(t, shortCircuit)
+		assert.Equal(t, "9 to 5", messageText(shortCircuit.Response.Choices[0].Message))
+		assert.Equal(t, true, ctx.Value("heimdall_response_cache_hit"))
+		assert.InDelta(t, 1.0, ctx.Value("heimdall_response_cache_similarity"), 1e-9)
+	})
+
+	t.Run("should not serve similar prompts when the caller's policy forbids it", func(t *testing.T) {
+		scope := plugin.responses.Scope(&schemas.BifrostRequest{Model: "gpt-4o"}, map[string][]string{})
+		ctx := context.WithValue(context.Background(), "heimdall_response_cache_scope", scope)
+		response := &RouterResponse{Features: RequestFeatures{Embedding: []float64{0.6, 0.8}}}
+		require.NotNil(t, plugin.similarResponse(&ctx, response))
+
+		response.AuthInfo = &AuthInfo{Policy: &RoutingPolicy{NoSemanticCache: true}}
+		assert.Nil(t, plugin.similarResponse(&ctx, response))
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// refusalPatterns match responses that decline or deflect the task
+var refusalPatterns = regexp.MustCompile(`(?i)^(i'?m sorry|i apologi[sz]e|i can'?t|i cannot|i am unable|i'?m unable|as an ai)`)
+
+// mathPattern matches numeric or symbolic working in a response
+var mathPattern = regexp.MustCompile(`[0-9]|\\[a-z]+|[=+\-*/^]`)
+
+// ResponseScorer estimates the quality of a model response with cheap
+// heuristics, returning a score in [0, 1]
+type ResponseScorer struct {
+	// MinLength is the response length below which answers are penalized
+	MinLength int
+}
+
+// NewResponseScorer creates a scorer with default heuristics
+func NewResponseScorer() *ResponseScorer {
+	return &ResponseScorer{
+		MinLength: 40,
+	}
+}
+
+// Score rates the first choice of a non-streaming response
+func (rs *ResponseScorer) Score(features *RequestFeatures, res *schemas.BifrostResponse) float64 {
+	if !scorable(res) {
+		return 0
+	}
+	choice := res.Choices[0]
+
+	content := strings.TrimSpace(messageText(choice.Message))
+	if content == "" {
+		return 0
+	}
+
+	score := 1.0
+
+	// Truncated answers are usually incomplete
+	if choice.FinishReason != nil && *choice.FinishReason == "length" {
+		score -= 0.4
+	}
+
+	if refusalPatterns.MatchString(content) {
+		score -= 0.6
+	}
+
+	// Short answers to substantial prompts are suspect
+	if len(content) < rs.MinLength && features != nil && features.TokenCount > 50 {
+		score -= 0.3
+	}
+
+	// Task-specific expectations
+	if features != nil {
+		if features.HasCode && !strings.Contains(content, "```") && !strings.Contains(content, "    ") {
+			score -= 0.3
+		}
+		if features.HasMath && !mathPattern.MatchString(content) {
+			score -= 0.3
+		}
+	}
+
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// scorable reports whether a response is a complete, non-streaming answer
+func scorable(res *schemas.BifrostResponse) bool {
+	return res != nil && len(res.Choices) > 0 && res.Choices[0].BifrostNonStreamResponseChoice != nil
+}
+
+// messageText returns the text content of a message
+func messageText(msg schemas.BifrostMessage) string {
+	if msg.Content.ContentStr != nil {
+		return *msg.Content.ContentStr
+	}
+	if msg.Content.ContentBlocks == nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range *msg.Content.ContentBlocks {
+		if block.Text != nil {
+			parts = append(parts, *block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func textResponse(content, finishReason string) *schemas.BifrostResponse {
+	choice := schemas.BifrostResponseChoice{
+		BifrostNonStreamResponseChoice: &schemas.BifrostNonStreamResponseChoice{
+			Message: schemas.BifrostMessage{
+				Role:    schemas.ModelChatMessageRoleAssistant,
+				Content: schemas.MessageContent{ContentStr: &content},
+			},
+		},
+	}
+	if finishReason != "" {
+		choice.FinishReason = &finishReason
+	}
+	return &schemas.BifrostResponse{Choices: []schemas.BifrostResponseChoice{choice}}
+}
+
+func TestResponseScorer(t *testing.T) {
+	scorer := NewResponseScorer()
+	features := &RequestFeatures{TokenCount: 200}
+	answer := "The capital of France is Paris, which has been the seat of government for centuries."
+
+	tests := []struct {
+		name     string
+		features *RequestFeatures
+		res      *schemas.BifrostResponse
+		expected float64
+	}{
+		{"complete answer", features, textResponse(answer, "stop"), 1.0},
+		{"empty answer", features, textResponse("  ", "stop"), 0},
+		{"refusal", features, textResponse("I'm sorry, but I can't help with that request today.", "stop"), 0.4},
+		{"truncated", features, textResponse(answer, "length"), 0.6},
+		{"short answer to long prompt", features, textResponse("Paris.", "stop"), 0.7},
+		{"code without a code block", &RequestFeatures{HasCode: true}, textResponse(answer, "stop"), 0.7},
+		{"code in a fence", &RequestFeatures{HasCode: true}, textResponse("```go\nfunc main() {}\n```", "stop"), 1.0},
+		{"math without working", &RequestFeatures{HasMath: true}, textResponse("It is the obvious answer.", "stop"), 0.7},
+		{"no response", features, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, scorer.Score(tt.features, tt.res), 1e-9)
+		})
+	}
+
+	t.Run("streamed chunks are not scorable", func(t *testing.T) {
+		chunk := &schemas.BifrostResponse{Choices: []schemas.BifrostResponseChoice{{
+			BifrostStreamResponseChoice: &schemas.BifrostStreamResponseChoice{},
+		}}}
+		assert.False(t, scorable(chunk))
+		assert.True(t, scorable(textResponse(answer, "")))
+	})
+}
@@ -0,0 +1,404 @@
+// Package router ties feature extraction, GBDT triage and α-score
+// selection into the Heimdall routing pipeline. It has no dependency on
+// Bifrost and can be embedded in other hosts.
+package router
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+)
+
+// Config configures the routing pipeline
+type Config struct {
+	// Thresholds are the GBDT probability cutoffs for the cheap and hard buckets
+	Thresholds core.BucketThresholds
+
+	// FeatureTimeout bounds feature extraction
+	FeatureTimeout time.Duration
+
+	// TopP is the number of candidates fully scored; larger candidate lists
+	// are shortlisted by cluster quality first. Zero scores every candidate.
+	TopP int
+
+	// MaxCandidates and MaxScoringTime bound the scoring of a request; past
+	// either the best candidate so far is selected. Zero is unbounded.
+	MaxCandidates  int
+	MaxScoringTime time.Duration
+}
+
+// FeatureExtractor turns a request into routing features
+type FeatureExtractor interface {
+	Extract(req *core.RouterRequest, artifact *core.AvengersArtifact, timeoutMs int) (*core.RequestFeatures, error)
+}
+
+// LightExtractor is a FeatureExtractor that can also extract lexical
+// features alone, without embeddings or cluster assignment
+type LightExtractor interface {
+	ExtractLight(req *core.RouterRequest) (*core.RequestFeatures, error)
+}
+
+// TriageModel predicts bucket probabilities from features
+type TriageModel interface {
+	Predict(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*core.BucketProbabilities, error)
+}
+
+// Scorer picks the best model among a bucket's candidates
+type Scorer interface {
+	SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error)
+}
+
+// DeadlineScorer is a Scorer that can stop at a deadline, selecting the best
+// candidate scored so far and reporting whether any were skipped. Scorers
+// that don't implement it always score every candidate.
+type DeadlineScorer interface {
+	SelectBestBefore(deadline time.Time, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, bool, error)
+}
+
+// The built-in stages
+var (
+	_ FeatureExtractor = (*features.FeatureExtractor)(nil)
+	_ LightExtractor   = (*features.FeatureExtractor)(nil)
+	_ TriageModel      = (*scoring.GBDTRuntime)(nil)
+	_ Scorer           = (*scoring.AlphaScorer)(nil)
+	_ Scorer           = (*scoring.WASMScorer)(nil)
+	_ DeadlineScorer   = (*scoring.AlphaScorer)(nil)
+	_ DeadlineScorer   = (*fallbackScorer)(nil)
+)
+
+// fallbackTriage predicts with primary, falling back to secondary on error
+type fallbackTriage struct {
+	primary   TriageModel
+	secondary TriageModel
+}
+
+// TriageWithFallback returns a TriageModel that predicts with primary and
+// uses secondary when primary fails, e.g. for a remote model backed by the
+// built-in GBDT runtime
+func TriageWithFallback(primary, secondary TriageModel) TriageModel {
+	return &fallbackTriage{primary: primary, secondary: secondary}
+}
+
+func (ft *fallbackTriage) Predict(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*core.BucketProbabilities, error) {
+	probs, err := ft.primary.Predict(features, artifact)
+	if err == nil {
+		return probs, nil
+	}
+	log.Printf("Triage failed, using fallback model: %v", err)
+	return ft.secondary.Predict(features, artifact)
+}
+
+// fallbackScorer selects with primary, falling back to secondary on error
+type fallbackScorer struct {
+	primary   Scorer
+	secondary Scorer
+}
+
+// ScorerWithFallback returns a Scorer that selects with primary and uses
+// secondary when primary fails
+func ScorerWithFallback(primary, secondary Scorer) Scorer {
+	return &fallbackScorer{primary: primary, secondary: secondary}
+}
+
+func (fs *fallbackScorer) SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	model, err := fs.primary.SelectBest(candidates, features, artifact)
+	if err == nil {
+		return model, nil
+	}
+	log.Printf("Scoring failed, using fallback scorer: %v", err)
+	return fs.secondary.SelectBest(candidates, features, artifact)
+}
+
+func (fs *fallbackScorer) SelectBestBefore(deadline time.Time, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, bool, error) {
+	model, truncated, err := selectBefore(fs.primary, deadline, candidates, features, artifact)
+	if err == nil {
+		return model, truncated, nil
+	}
+	log.Printf("Scoring failed, using fallback scorer: %v", err)
+	return selectBefore(fs.secondary, deadline, candidates, features, artifact)
+}
+
+// selectBefore selects with scorer, stopping at deadline if it supports one
+func selectBefore(scorer Scorer, deadline time.Time, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, bool, error) {
+	if ds, ok := scorer.(DeadlineScorer); ok && !deadline.IsZero() {
+		return ds.SelectBestBefore(deadline, candidates, features, artifact)
+	}
+	model, err := scorer.SelectBest(candidates, features, artifact)
+	return model, false, err
+}
+
+// Triage is the outcome of classifying a request into a bucket
+type Triage struct {
+	Features      *core.RequestFeatures
+	Probabilities *core.BucketProbabilities
+	Bucket        core.Bucket
+}
+
+// Router classifies requests into buckets and selects models within them
+type Router struct {
+	config      Config
+	extractor   FeatureExtractor
+	triage      TriageModel
+	scorer      Scorer
+	tokenFactor TokenFactor // nil counts prompts as estimated
+}
+
+// TokenFactor returns the ratio of the token counts of a bucket's
+// providers to the prompt token estimate
+type TokenFactor func(bucket core.Bucket) float64
+
+// SetTokenFactor scales the prompt token estimate by factor in the context
+// guardrails of bucket selection. Set it before the router is used.
+func (r *Router) SetTokenFactor(factor TokenFactor) {
+	r.tokenFactor = factor
+}
+
+// New creates a router from its pipeline stages
+func New(config Config, extractor FeatureExtractor, triage TriageModel, scorer Scorer) *Router {
+	return &Router{
+		config:    config,
+		extractor: extractor,
+		triage:    triage,
+		scorer:    scorer,
+	}
+}
+
+// Triage extracts features, predicts bucket probabilities and picks a bucket
+func (r *Router) Triage(req *core.RouterRequest, artifact *core.AvengersArtifact) (*Triage, error) {
+	// Feature extraction (≤25ms budget)
+	features, err := r.extractor.Extract(req, artifact, int(r.config.FeatureTimeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("feature extraction failed: %w", err)
+	}
+
+	return r.Classify(features, artifact)
+}
+
+// TriageLight classifies a request by heuristics on its lexical features,
+// skipping embeddings, cluster assignment and the triage model. It is the
+// degraded path taken to shed load; extractors that are not
+// LightExtractors take the full path.
+func (r *Router) TriageLight(req *core.RouterRequest, artifact *core.AvengersArtifact) (*Triage, error) {
+	light, ok := r.extractor.(LightExtractor)
+	if !ok {
+		return r.Triage(req, artifact)
+	}
+	features, err := light.ExtractLight(req)
+	if err != nil {
+		return nil, fmt.Errorf("feature extraction failed: %w", err)
+	}
+
+	probs := HeuristicProbabilities(features)
+	return &Triage{
+		Features:      features,
+		Probabilities: probs,
+		Bucket:        SelectBucket(probs, features, r.config.Thresholds, r.tokenFactor),
+	}, nil
+}
+
+// HeuristicProbabilities assigns a request to one bucket by rule: math or
+// long context is hard, code or a sizeable prompt is mid, anything else is
+// cheap
+func HeuristicProbabilities(features *core.RequestFeatures) *core.BucketProbabilities {
+	switch {
+	case features.HasMath || features.TokenCount > 50000:
+		return &core.BucketProbabilities{Hard: 1}
+	case features.HasCode || features.TokenCount >= 1000:
+		return &core.BucketProbabilities{Mid: 1}
+	default:
+		return &core.BucketProbabilities{Cheap: 1}
+	}
+}
+
+// Classify predicts bucket probabilities for features already extracted,
+// e.g. recorded with a decision being replayed, and picks a bucket
+func (r *Router) Classify(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*Triage, error) {
+	// GBDT triage
+	probs, err := r.triage.Predict(features, artifact)
+	if err != nil {
+		return nil, fmt.Errorf("GBDT prediction failed: %w", err)
+	}
+
+	return &Triage{
+		Features:      features,
+		Probabilities: probs,
+		Bucket:        SelectBucket(probs, features, r.config.Thresholds, r.tokenFactor),
+	}, nil
+}
+
+// Select picks the best candidate by α-score among the shortlist
+func (r *Router) Select(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	model, _, err := r.SelectWithBudget(candidates, features, artifact)
+	return model, err
+}
+
+// SelectWithBudget is Select within config.MaxCandidates and
+// config.MaxScoringTime, reporting whether the budget cut scoring short
+func (r *Router) SelectWithBudget(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, bool, error) {
+	var deadline time.Time
+	if r.config.MaxScoringTime > 0 {
+		deadline = time.Now().Add(r.config.MaxScoringTime)
+	}
+
+	// Capped by the same prior as the shortlist, keeping the likeliest winners
+	candidates = r.Shortlist(candidates, features, artifact)
+	capped := false
+	if r.config.MaxCandidates > 0 && len(candidates) > r.config.MaxCandidates {
+		candidates = topByPrior(candidates, features, artifact, r.config.MaxCandidates)
+		capped = true
+	}
+
+	model, truncated, err := selectBefore(r.scorer, deadline, candidates, features, artifact)
+	return model, capped || truncated, err
+}
+
+// Shortlist returns the config.TopP candidates with the highest artifact
+// quality on the request's cluster, a cheap prior to full scoring.
+// Candidates keep their relative order on ties and those without quality
+// data rank last.
+func (r *Router) Shortlist(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) []string {
+	if r.config.TopP <= 0 {
+		return candidates
+	}
+	return topByPrior(candidates, features, artifact, r.config.TopP)
+}
+
+// Constrain drops candidates that do not meet the artifact's constraint for
+// the request's cluster. Constraints are hard filters: when no candidate
+// qualifies the result is empty.
+func Constrain(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) []string {
+	if artifact == nil || features == nil {
+		return candidates
+	}
+	constraint, ok := artifact.Constraints[features.ClusterID]
+	if !ok {
+		return candidates
+	}
+
+	var permitted []string
+	for _, model := range candidates {
+		profile, ok := artifact.Models[model]
+		if ok && constraint.Permits(profile) {
+			permitted = append(permitted, model)
+		}
+	}
+	return permitted
+}
+
+// MeetQuality drops candidates whose artifact quality on the request's
+// cluster is below min. Models without quality data never qualify.
+func MeetQuality(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact, min float64) []string {
+	if min <= 0 || artifact == nil {
+		return candidates
+	}
+	clusterID := -1
+	if features != nil {
+		clusterID = features.ClusterID
+	}
+
+	var qualified []string
+	for _, model := range candidates {
+		if clusterQuality(artifact.Qhat[model], clusterID) >= min {
+			qualified = append(qualified, model)
+		}
+	}
+	return qualified
+}
+
+// topByPrior returns the n candidates with the highest cluster quality
+func topByPrior(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact, n int) []string {
+	if len(candidates) <= n {
+		return candidates
+	}
+	if artifact == nil {
+		return candidates[:n]
+	}
+
+	clusterID := -1
+	if features != nil {
+		clusterID = features.ClusterID
+	}
+	prior := make(map[string]float64, len(candidates))
+	for _, model := range candidates {
+		prior[model] = clusterQuality(artifact.Qhat[model], clusterID)
+	}
+	ranked := append([]string(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return prior[ranked[i]] > prior[ranked[j]]
+	})
+	return ranked[:n]
+}
+
+// clusterQuality is a model's Q̂ on a cluster, or its average over clusters
+// when the cluster has no finite value; -Inf without any quality data
+func clusterQuality(quality []float64, clusterID int) float64 {
+	if clusterID >= 0 && clusterID < len(quality) && !math.IsNaN(quality[clusterID]) && !math.IsInf(quality[clusterID], 0) {
+		return quality[clusterID]
+	}
+	sum, n := 0.0, 0
+	for _, q := range quality {
+		if !math.IsNaN(q) && !math.IsInf(q, 0) {
+			sum += q
+			n++
+		}
+	}
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	return sum / float64(n)
+}
+
+// SelectBucket implements bucket selection with guardrails (port of
+// RouterPreHook.selectBucket()). tokenFactor scales the prompt to how each
+// bucket's providers count it; nil counts the estimate.
+func SelectBucket(probs *core.BucketProbabilities, features *core.RequestFeatures, thresholds core.BucketThresholds, tokenFactor TokenFactor) core.Bucket {
+	factor := func(bucket core.Bucket) float64 {
+		if tokenFactor == nil {
+			return 1
+		}
+		return tokenFactor(bucket)
+	}
+
+	// Guardrails for context overflow
+	if ContextExceedsCapacity(features, core.BucketCheap, factor(core.BucketCheap)) {
+		if ContextExceedsCapacity(features, core.BucketMid, factor(core.BucketMid)) {
+			return core.BucketHard
+		}
+		return core.BucketMid
+	}
+
+	// Threshold-based bucket selection
+	if probs.Hard > thresholds.Hard {
+		return core.BucketHard
+	}
+
+	if probs.Cheap > thresholds.Cheap {
+		return core.BucketCheap
+	}
+
+	return core.BucketMid
+}
+
+// contextCapacities are rough context capacity estimates per bucket
+var contextCapacities = map[core.Bucket]int{
+	core.BucketCheap: 16000,   // DeepSeek R1, Qwen3-Coder
+	core.BucketMid:   128000,  // GPT-5 medium, Gemini medium
+	core.BucketHard:  1048576, // Gemini 2.5 Pro with high thinking
+}
+
+// ContextExceedsCapacity checks if context, its token estimate scaled by
+// factor, exceeds bucket capacity
+func ContextExceedsCapacity(features *core.RequestFeatures, bucket core.Bucket, factor float64) bool {
+	capacity, ok := contextCapacities[bucket]
+	if !ok {
+		return false
+	}
+
+	return float64(features.TokenCount)*factor > float64(capacity)*0.8 // 80% threshold
+}
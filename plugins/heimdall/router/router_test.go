@@ -0,0 +1,300 @@
+package router
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/features"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func routerTestArtifact() *core.AvengersArtifact {
+	return &core.AvengersArtifact{
+		Version: "test-router-v1.0",
+		Alpha:   0.7,
+		Thresholds: core.BucketThresholds{
+			Cheap: 0.3,
+			Hard:  0.7,
+		},
+		Qhat: map[string][]float64{
+			"openai/gpt-5":          {0.95, 0.92, 0.88, 0.94, 0.90},
+			"google/gemini-2.5-pro": {0.82, 0.85, 0.89, 0.91, 0.86},
+			"qwen/qwen3-coder":      {0.72, 0.75, 0.88, 0.74, 0.73},
+		},
+		Chat: map[string]float64{
+			"openai/gpt-5":          0.85,
+			"google/gemini-2.5-pro": 0.55,
+			"qwen/qwen3-coder":      0.25,
+		},
+	}
+}
+
+func newTestRouter() *Router {
+	return New(Config{
+		Thresholds:     core.BucketThresholds{Cheap: 0.3, Hard: 0.7},
+		FeatureTimeout: 25 * time.Millisecond,
+	}, features.NewFeatureExtractor(), scoring.NewGBDTRuntime(), scoring.NewAlphaScorer())
+}
+
+func TestRouterPipeline(t *testing.T) {
+	r := newTestRouter()
+	artifact := routerTestArtifact()
+
+	req := &core.RouterRequest{
+		URL:    "/v1/chat/completions",
+		Method: "POST",
+		Body: &core.RequestBody{
+			Messages: []core.ChatMessage{
+				{Role: "user", Content: "Please write a React component for a todo list with TypeScript"},
+			},
+		},
+	}
+
+	start := time.Now()
+
+	triage, err := r.Triage(req, artifact)
+	require.NoError(t, err)
+	require.NotNil(t, triage.Features)
+	require.NotNil(t, triage.Probabilities)
+	assert.Equal(t, SelectBucket(triage.Probabilities, triage.Features, core.BucketThresholds{Cheap: 0.3, Hard: 0.7}, nil), triage.Bucket)
+
+	candidates := []string{"openai/gpt-5", "qwen/qwen3-coder", "google/gemini-2.5-pro"}
+	bestModel, err := r.Select(candidates, triage.Features, artifact)
+
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Contains(t, candidates, bestModel)
+	assert.Less(t, elapsed, 30*time.Millisecond, "Full pipeline should complete under 30ms")
+}
+
+func TestSelectBucket(t *testing.T) {
+	thresholds := core.BucketThresholds{Cheap: 0.3, Hard: 0.7}
+	features := &core.RequestFeatures{TokenCount: 100}
+
+	t.Run("thresholds pick the bucket", func(t *testing.T) {
+		assert.Equal(t, core.BucketHard, SelectBucket(&core.BucketProbabilities{Hard: 0.8}, features, thresholds, nil))
+		assert.Equal(t, core.BucketCheap, SelectBucket(&core.BucketProbabilities{Cheap: 0.6}, features, thresholds, nil))
+		assert.Equal(t, core.BucketMid, SelectBucket(&core.BucketProbabilities{Cheap: 0.2, Mid: 0.6, Hard: 0.2}, features, thresholds, nil))
+	})
+
+	t.Run("context overflow overrides probabilities", func(t *testing.T) {
+		probs := &core.BucketProbabilities{Cheap: 0.9}
+		assert.Equal(t, core.BucketMid, SelectBucket(probs, &core.RequestFeatures{TokenCount: 20000}, thresholds, nil))
+		assert.Equal(t, core.BucketHard, SelectBucket(probs, &core.RequestFeatures{TokenCount: 200000}, thresholds, nil))
+	})
+
+	t.Run("unknown bucket has no capacity limit", func(t *testing.T) {
+		assert.False(t, ContextExceedsCapacity(&core.RequestFeatures{TokenCount: 1 << 30}, core.Bucket("unknown"), 1))
+	})
+}
+
+// fixedTriage returns fixed probabilities, or an error
+type fixedTriage struct {
+	probs *core.BucketProbabilities
+	err   error
+}
+
+func (ft *fixedTriage) Predict(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*core.BucketProbabilities, error) {
+	return ft.probs, ft.err
+}
+
+func TestTriageWithFallback(t *testing.T) {
+	secondary := &fixedTriage{probs: &core.BucketProbabilities{Mid: 1}}
+
+	t.Run("should use the primary model when it succeeds", func(t *testing.T) {
+		triage := TriageWithFallback(&fixedTriage{probs: &core.BucketProbabilities{Hard: 1}}, secondary)
+		probs, err := triage.Predict(&core.RequestFeatures{}, routerTestArtifact())
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, probs.Hard)
+	})
+
+	t.Run("should fall back when the primary model fails", func(t *testing.T) {
+		triage := TriageWithFallback(&fixedTriage{err: assert.AnError}, secondary)
+		probs, err := triage.Predict(&core.RequestFeatures{}, routerTestArtifact())
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, probs.Mid)
+	})
+}
+
+func TestTriageLight(t *testing.T) {
+	r := New(Config{Thresholds: core.BucketThresholds{Cheap: 0.3, Hard: 0.7}},
+		features.NewFeatureExtractor(), &fixedTriage{err: assert.AnError}, scoring.NewAlphaScorer())
+	request := func(content string) *core.RouterRequest {
+		return &core.RouterRequest{Body: &core.RequestBody{Messages: []core.ChatMessage{{Role: "user", Content: content}}}}
+	}
+
+	t.Run("should classify by heuristics without the triage model", func(t *testing.T) {
+		for content, bucket := range map[string]core.Bucket{
+			"What is the capital of France?":                     core.BucketCheap,
+			"Fix this: ```def add(a, b): return a - b```":        core.BucketMid,
+			"Compute the integral of x^2 from 0 to 1.":           core.BucketHard,
+			strings.Repeat("Summarize the meeting notes. ", 200): core.BucketMid,
+		} {
+			triage, err := r.TriageLight(request(content), routerTestArtifact())
+			require.NoError(t, err)
+			assert.Equal(t, bucket, triage.Bucket, content)
+			assert.Equal(t, core.NoCluster, triage.Features.ClusterID)
+			assert.Nil(t, triage.Features.Embedding)
+		}
+	})
+
+	t.Run("should select with quality averaged across clusters", func(t *testing.T) {
+		triage, err := r.TriageLight(request("What is the capital of France?"), routerTestArtifact())
+		require.NoError(t, err)
+		_, err = r.Select([]string{"openai/gpt-5", "qwen/qwen3-coder"}, triage.Features, routerTestArtifact())
+		require.NoError(t, err)
+	})
+}
+
+// failingScorer always fails
+type failingScorer struct{}
+
+func (failingScorer) SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	return "", assert.AnError
+}
+
+func TestScorerWithFallback(t *testing.T) {
+	candidates := []string{"openai/gpt-5", "qwen/qwen3-coder"}
+	features := &core.RequestFeatures{ClusterID: 2}
+
+	scorer := ScorerWithFallback(failingScorer{}, scoring.NewAlphaScorer())
+	model, err := scorer.SelectBest(candidates, features, routerTestArtifact())
+	require.NoError(t, err)
+	assert.Contains(t, candidates, model)
+}
+
+// recordingScorer selects the first candidate and records what it was given
+type recordingScorer struct {
+	candidates []string
+}
+
+func (rs *recordingScorer) SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	rs.candidates = candidates
+	return candidates[0], nil
+}
+
+func TestShortlist(t *testing.T) {
+	artifact := routerTestArtifact()
+	artifact.Qhat["unknown/model"] = nil
+	candidates := []string{"unknown/model", "qwen/qwen3-coder", "google/gemini-2.5-pro", "openai/gpt-5"}
+
+	newRouter := func(topP int, scorer Scorer) *Router {
+		return New(Config{TopP: topP}, features.NewFeatureExtractor(), scoring.NewGBDTRuntime(), scorer)
+	}
+
+	t.Run("should keep the top-P candidates by cluster quality", func(t *testing.T) {
+		r := newRouter(2, scoring.NewAlphaScorer())
+		assert.Equal(t, []string{"openai/gpt-5", "google/gemini-2.5-pro"}, r.Shortlist(candidates, &core.RequestFeatures{ClusterID: 0}, artifact))
+		// qwen and gpt-5 tie on cluster 2; ties keep their input order
+		assert.Equal(t, []string{"google/gemini-2.5-pro", "qwen/qwen3-coder"}, r.Shortlist(candidates, &core.RequestFeatures{ClusterID: 2}, artifact))
+	})
+
+	t.Run("should rank candidates without quality data last", func(t *testing.T) {
+		r := newRouter(3, scoring.NewAlphaScorer())
+		assert.NotContains(t, r.Shortlist(candidates, &core.RequestFeatures{ClusterID: 1}, artifact), "unknown/model")
+	})
+
+	t.Run("should keep every candidate when TopP is unset or not exceeded", func(t *testing.T) {
+		features := &core.RequestFeatures{ClusterID: 0}
+		assert.Equal(t, candidates, newRouter(0, scoring.NewAlphaScorer()).Shortlist(candidates, features, artifact))
+		assert.Equal(t, candidates, newRouter(4, scoring.NewAlphaScorer()).Shortlist(candidates, features, artifact))
+	})
+
+	t.Run("should only score the shortlist", func(t *testing.T) {
+		scorer := &recordingScorer{}
+		r := newRouter(1, scorer)
+		model, err := r.Select(candidates, &core.RequestFeatures{ClusterID: 3}, artifact)
+		require.NoError(t, err)
+		assert.Equal(t, "openai/gpt-5", model)
+		assert.Equal(t, []string{"openai/gpt-5"}, scorer.candidates)
+	})
+}
+
+func TestConstrain(t *testing.T) {
+	artifact := routerTestArtifact()
+	artifact.Models = map[string]core.ModelProfile{
+		"qwen/qwen3-coder":      {ContextWindow: 32000, QualityTier: 1},
+		"google/gemini-2.5-pro": {ContextWindow: 1000000, Capabilities: []string{"reasoning", "vision"}, QualityTier: 3},
+		"openai/gpt-5":          {ContextWindow: 400000, Capabilities: []string{"reasoning"}, QualityTier: 3},
+	}
+	artifact.Constraints = map[int]core.ClusterConstraint{
+		1: {MinContext: 100000, RequiredCapabilities: []string{"reasoning"}},
+		2: {MinQualityTier: 2, RequiredCapabilities: []string{"vision"}},
+	}
+	candidates := []string{"unknown/model", "qwen/qwen3-coder", "google/gemini-2.5-pro", "openai/gpt-5"}
+
+	t.Run("should keep only models meeting the cluster's constraint", func(t *testing.T) {
+		assert.Equal(t, []string{"google/gemini-2.5-pro", "openai/gpt-5"}, Constrain(candidates, &core.RequestFeatures{ClusterID: 1}, artifact))
+		assert.Equal(t, []string{"google/gemini-2.5-pro"}, Constrain(candidates, &core.RequestFeatures{ClusterID: 2}, artifact))
+	})
+
+	t.Run("should leave unconstrained clusters alone", func(t *testing.T) {
+		assert.Equal(t, candidates, Constrain(candidates, &core.RequestFeatures{ClusterID: 0}, artifact))
+	})
+
+	t.Run("should return nothing when no candidate qualifies", func(t *testing.T) {
+		assert.Empty(t, Constrain([]string{"qwen/qwen3-coder", "unknown/model"}, &core.RequestFeatures{ClusterID: 1}, artifact))
+	})
+}
+
+func TestMeetQuality(t *testing.T) {
+	artifact := routerTestArtifact()
+	candidates := []string{"unknown/model", "qwen/qwen3-coder", "google/gemini-2.5-pro", "openai/gpt-5"}
+
+	t.Run("should keep models at or above the floor on the request's cluster", func(t *testing.T) {
+		assert.Equal(t, []string{"openai/gpt-5"}, MeetQuality(candidates, &core.RequestFeatures{ClusterID: 0}, artifact, 0.9))
+		assert.Equal(t, []string{"qwen/qwen3-coder", "google/gemini-2.5-pro", "openai/gpt-5"},
+			MeetQuality(candidates, &core.RequestFeatures{ClusterID: 2}, artifact, 0.88))
+	})
+
+	t.Run("should leave candidates alone without a floor", func(t *testing.T) {
+		assert.Equal(t, candidates, MeetQuality(candidates, &core.RequestFeatures{}, artifact, 0))
+	})
+}
+
+func TestSelectWithBudget(t *testing.T) {
+	artifact := routerTestArtifact()
+	candidates := []string{"qwen/qwen3-coder", "google/gemini-2.5-pro", "openai/gpt-5"}
+	reqFeatures := &core.RequestFeatures{ClusterID: 0}
+
+	newRouter := func(config Config) *Router {
+		return New(config, features.NewFeatureExtractor(), scoring.NewGBDTRuntime(), scoring.NewAlphaScorer())
+	}
+
+	t.Run("should not truncate without a budget", func(t *testing.T) {
+		_, truncated, err := newRouter(Config{}).SelectWithBudget(candidates, reqFeatures, artifact)
+		require.NoError(t, err)
+		assert.False(t, truncated)
+	})
+
+	t.Run("should cap candidates by cluster quality", func(t *testing.T) {
+		scorer := &recordingScorer{}
+		r := New(Config{MaxCandidates: 1}, features.NewFeatureExtractor(), scoring.NewGBDTRuntime(), scorer)
+		model, truncated, err := r.SelectWithBudget(candidates, reqFeatures, artifact)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		assert.Equal(t, "openai/gpt-5", model)
+		assert.Equal(t, []string{"openai/gpt-5"}, scorer.candidates)
+	})
+
+	t.Run("should stop scoring at the time budget", func(t *testing.T) {
+		r := newRouter(Config{MaxScoringTime: time.Nanosecond})
+		model, truncated, err := r.SelectWithBudget(candidates, reqFeatures, artifact)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		assert.Equal(t, "qwen/qwen3-coder", model)
+	})
+
+	t.Run("should pass the time budget through the fallback scorer", func(t *testing.T) {
+		r := New(Config{MaxScoringTime: time.Nanosecond}, features.NewFeatureExtractor(), scoring.NewGBDTRuntime(),
+			ScorerWithFallback(failingScorer{}, scoring.NewAlphaScorer()))
+		_, truncated, err := r.SelectWithBudget(candidates, reqFeatures, artifact)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+	})
+}
@@ -12,6 +12,9 @@ import (
 	"time"
 
 	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -153,10 +156,10 @@ func TestRouterExecutorCore(t *testing.T) {
 			require.NotNil(t, response.AuthInfo)
 			assert.Equal(t, "anthropic", response.AuthInfo.Provider)
 			assert.Equal(t, "bearer", response.AuthInfo.Type)
-			assert.Equal(t, "anthropic_test123", response.AuthInfo.Token)
+			assert.Equal(t, "anthropic_test123", response.AuthInfo.Token.Reveal())
 		})
 		
-		t.Run("should fail gracefully without artifact", func(t *testing.T) {
+		t.Run("should degrade to the built-in artifact without one", func(t *testing.T) {
 			plugin := createTestPluginWithoutArtifact(t)
 			
 			req := &RouterRequest{
@@ -167,10 +170,36 @@ func TestRouterExecutorCore(t *testing.T) {
 				},
 			}
 			
-			_, err := plugin.decide(req, map[string][]string{})
+			response, err := plugin.decide(req, map[string][]string{})
 			
-			assert.Error(t, err)
-			assert.Contains(t, err.Error(), "artifact")
+			require.NoError(t, err)
+			assert.NotEmpty(t, response.Decision.Model)
+			assert.Equal(t, defaultArtifactVersion, plugin.currentArtifact.Version)
+		})
+		
+		t.Run("should not cache decisions made from the built-in artifact", func(t *testing.T) {
+			plugin := createTestPluginWithoutArtifact(t)
+			content := "Hello"
+			ctx := context.Background()
+			
+			_, _, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{Input: schemas.RequestInput{
+				ChatCompletionInput: &[]schemas.BifrostMessage{{
+					Role:    schemas.ModelChatMessageRoleUser,
+					Content: schemas.MessageContent{ContentStr: &content},
+				}},
+			}})
+			
+			require.NoError(t, err)
+			assert.Zero(t, plugin.cache.Len())
+		})
+		
+		t.Run("should fail without artifact under passthrough", func(t *testing.T) {
+			plugin := createTestPluginWithoutArtifact(t)
+			plugin.config.Startup.Policy = StartupPolicyPassThrough
+			
+			_, err := plugin.decide(&RouterRequest{}, map[string][]string{})
+			
+			assert.ErrorIs(t, err, ErrArtifactUnavailable)
 		})
 	})
 }
@@ -324,7 +353,7 @@ func TestModelSelection(t *testing.T) {
 			authInfo := &AuthInfo{
 				Provider: "anthropic",
 				Type:     "bearer",
-				Token:    "anthropic_test123",
+				Token:    auth.NewSecret("anthropic_test123"),
 			}
 			
 			decision, err := plugin.selectModel(BucketMid, features, authInfo, false)
@@ -339,7 +368,7 @@ func TestModelSelection(t *testing.T) {
 			authInfo := &AuthInfo{
 				Provider: "anthropic",
 				Type:     "bearer", 
-				Token:    "anthropic_test123",
+				Token:    auth.NewSecret("anthropic_test123"),
 			}
 			
 			decision, err := plugin.selectModel(BucketMid, features, authInfo, true)
@@ -734,6 +763,30 @@ func TestArtifactManagement(t *testing.T) {
 			assert.Equal(t, 0.7, plugin.currentArtifact.Alpha)
 		})
 		
+		t.Run("should convert pairwise preferences to quality at load", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"version": "pairwise-1", "pairwise": {"win_rates": [{"gpt-4o": {"claude-3-5": 0.65}}]}}`))
+			}))
+			defer server.Close()
+			
+			plugin := createTestPluginWithArtifactURL(t, server.URL)
+			require.NoError(t, plugin.ensureCurrentArtifact())
+			
+			qhat := plugin.currentArtifact.Qhat
+			require.Len(t, qhat, 2)
+			assert.Greater(t, qhat["gpt-4o"][0], qhat["claude-3-5"][0])
+		})
+		
+		t.Run("should reject invalid pairwise preferences", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"version": "pairwise-1", "pairwise": {"win_rates": [{"gpt-4o": {"claude-3-5": 1.65}}]}}`))
+			}))
+			defer server.Close()
+			
+			plugin := createTestPluginWithArtifactURL(t, server.URL)
+			assert.ErrorContains(t, plugin.ensureCurrentArtifact(), "invalid pairwise preferences")
+		})
+		
 		t.Run("should cache artifact and not reload frequently", func(t *testing.T) {
 			requestCount := 0
 			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -997,10 +1050,14 @@ func TestLoadBalancingAndFallbacks(t *testing.T) {
 		// Selected model should not be in fallbacks
 		assert.NotContains(t, decision.Fallbacks, decision.Model)
 		
-		// All fallbacks should be from the same bucket
-		for _, fallback := range decision.Fallbacks {
+		// All fallbacks but the final escalation should be from the same bucket
+		require.NotNil(t, decision.Escalation)
+		last := len(decision.Fallbacks) - 1
+		for _, fallback := range decision.Fallbacks[:last] {
 			assert.Contains(t, plugin.config.Router.MidCandidates, fallback)
 		}
+		assert.Equal(t, decision.Escalation.Model, decision.Fallbacks[last])
+		assert.Contains(t, plugin.config.Router.HardCandidates, decision.Escalation.Model)
 	})
 	
 	t.Run("provider preferences affect selection", func(t *testing.T) {
@@ -1085,8 +1142,12 @@ func TestErrorHandling(t *testing.T) {
 // ============================================================================
 
 // createTestPlugin creates a fully configured plugin for testing
-func createRouterTestPlugin(t *testing.T) *Plugin {
-	config := createRouterTestConfig()
+func createRouterTestPlugin(t testing.TB) *Plugin {
+	return createRouterTestPluginWithConfig(t, createRouterTestConfig())
+}
+
+// createRouterTestPluginWithConfig creates a plugin with the standard test artifact
+func createRouterTestPluginWithConfig(t testing.TB, config Config) *Plugin {
 	plugin, err := createPluginWithConfig(t, config)
 	require.NoError(t, err)
 	
@@ -1223,7 +1284,7 @@ func createRouterTestConfig() Config {
 }
 
 // createPluginWithConfig creates a plugin with the given configuration
-func createPluginWithConfig(t *testing.T, config Config) (*Plugin, error) {
+func createPluginWithConfig(t testing.TB, config Config) (*Plugin, error) {
 	return New(config)
 }
 
@@ -1236,4 +1297,184 @@ func containsGemini(candidates []string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+func TestFeatureOverridesContext(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	content := "Hello, world!"
+	bifrostReq := func() *schemas.BifrostRequest {
+		return &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{
+				ChatCompletionInput: &[]schemas.BifrostMessage{
+					{
+						Role:    schemas.ModelChatMessageRoleUser,
+						Content: schemas.MessageContent{ContentStr: &content},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("should route with upstream features", func(t *testing.T) {
+		// A token count beyond the mid bucket's context forces the hard bucket
+		tokenCount, hasCode := 200000, true
+		ctx := context.WithValue(context.Background(), FeatureOverridesContextKey,
+			&FeatureOverrides{TokenCount: &tokenCount, HasCode: &hasCode})
+
+		_, _, err := plugin.PreHook(&ctx, bifrostReq())
+		require.NoError(t, err)
+		assert.Equal(t, BucketHard, ctx.Value("heimdall_bucket"))
+		features := ctx.Value("heimdall_features").(RequestFeatures)
+		assert.Equal(t, 200000, features.TokenCount)
+		assert.True(t, features.HasCode)
+	})
+
+	t.Run("should not share cached decisions across overrides", func(t *testing.T) {
+		ctx := context.Background()
+		routerReq, _, err := plugin.convertToRouterRequest(&ctx, bifrostReq())
+		require.NoError(t, err)
+		tokenCount := 10
+		overridden := *routerReq
+		overridden.FeatureOverrides = &FeatureOverrides{TokenCount: &tokenCount}
+
+		assert.NotEqual(t, plugin.getCacheKey(routerReq), plugin.getCacheKey(&overridden))
+	})
+}
+
+func TestTemplateCacheKeys(t *testing.T) {
+	request := func(issue string) *RouterRequest {
+		return &RouterRequest{
+			Method: "POST",
+			Body: &RequestBody{Messages: []ChatMessage{
+				{Role: "system", Content: "You are a triage agent."},
+				{Role: "user", Content: "Label issue #" + issue},
+			}},
+		}
+	}
+
+	t.Run("should key decisions by exact content by default", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		assert.NotEqual(t, plugin.getCacheKey(request("12")), plugin.getCacheKey(request("3456")))
+	})
+
+	t.Run("should share decisions across template slot values", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.TemplateCacheKeys = true
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		assert.Equal(t, plugin.getCacheKey(request("12")), plugin.getCacheKey(request("3456")))
+
+		other := request("12")
+		other.Body.Messages[1].Content = "Close issue #12"
+		assert.NotEqual(t, plugin.getCacheKey(request("12")), plugin.getCacheKey(other))
+	})
+
+	t.Run("should separate prompts whose slots differ in size", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.TemplateCacheKeys = true
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		long := request("12")
+		long.Body.Messages[1].Content = `Label issue "` + strings.Repeat("stack trace line ", 500) + `"`
+		short := request("12")
+		short.Body.Messages[1].Content = `Label issue "crash"`
+		assert.NotEqual(t, plugin.getCacheKey(short), plugin.getCacheKey(long))
+	})
+
+	t.Run("should not answer template-keyed hits from the semantic cache", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.EnableCaching = true
+		config.TemplateCacheKeys = true
+		config.ResponseCache = ResponseCacheConfig{Enabled: true, SimilarityThreshold: 0.98}
+		plugin, err := NewWithOptions(config, WithEmbeddingProvider(&fixedEmbedder{embedding: []float64{0.6, 0.8}}))
+		require.NoError(t, err)
+		plugin.currentArtifact = createRouterTestPlugin(t).currentArtifact
+		plugin.lastArtifactLoad = time.Now()
+
+		ask := func(content string) (context.Context, *schemas.PluginShortCircuit) {
+			ctx := context.Background()
+			_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{
+				Model: "gpt-4o",
+				Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+					Role:    schemas.ModelChatMessageRoleUser,
+					Content: schemas.MessageContent{ContentStr: &content},
+				}}},
+			})
+			require.NoError(t, err)
+			return ctx, shortCircuit
+		}
+
+		ctx, shortCircuit := ask("Summarize ticket 12")
+		require.Nil(t, shortCircuit)
+		_, _, err = plugin.PostHook(&ctx, textResponse("Ticket 12 is a login bug", "stop"), nil)
+		require.NoError(t, err)
+
+		ctx, shortCircuit = ask("Summarize ticket 3456")
+		assert.Nil(t, shortCircuit, "the cached decision's embedding is ticket 12's")
+		assert.Nil(t, ctx.Value("heimdall_response_cache_embedding"))
+	})
+}
+
+func TestSidecarFallback(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Sidecar = sidecar.Config{
+		Address: "127.0.0.1:1", // nothing listens here
+		Timeout: 5 * time.Millisecond,
+		Embed:   true,
+		Cluster: true,
+		Triage:  true,
+	}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	require.NotNil(t, plugin.sidecar)
+
+	response, err := plugin.decide(&RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}, map[string][]string{})
+	require.NoError(t, err, "an unreachable sidecar falls back to the built-in stages")
+	assert.Len(t, response.Features.Embedding, 384)
+	assert.NoError(t, plugin.Cleanup())
+
+	config.Sidecar.Address = ""
+	_, err = NewWithOptions(config)
+	assert.ErrorContains(t, err, "invalid sidecar config")
+}
+
+func TestWASMScoring(t *testing.T) {
+	req := &RouterRequest{
+		Method: "POST",
+		Body:   &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "hello"}}},
+	}
+
+	t.Run("should select with the configured module", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Router.CheapCandidates = append(config.Router.CheapCandidates, "qwen/qwen3-coder")
+		config.Router.MidCandidates = append(config.Router.MidCandidates, "qwen/qwen3-coder")
+		config.Router.HardCandidates = append(config.Router.HardCandidates, "qwen/qwen3-coder")
+		config.WASMScoring = scoring.WASMConfig{ModulePath: "scoring/testdata/select.wasm"}
+		plugin := createRouterTestPluginWithConfig(t, config)
+		require.NotNil(t, plugin.wasmScorer)
+
+		response, err := plugin.decide(req, map[string][]string{})
+		require.NoError(t, err)
+		assert.Equal(t, "qwen/qwen3-coder", response.Decision.Model)
+		assert.NoError(t, plugin.Cleanup())
+	})
+
+	t.Run("should fall back to α-scoring when the module fails", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.WASMScoring = scoring.WASMConfig{ModulePath: "scoring/testdata/loop.wasm", Timeout: time.Millisecond}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		response, err := plugin.decide(req, map[string][]string{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.Decision.Model)
+	})
+
+	t.Run("should reject unloadable modules", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.WASMScoring = scoring.WASMConfig{ModulePath: "scoring/testdata/missing.wasm"}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid wasm scoring config")
+	})
+}
@@ -0,0 +1,172 @@
+// Package schema derives JSON Schemas from Heimdall's configuration and
+// artifact types and validates documents against them, so configuration
+// changes can be linted in CI before rollout.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect generated schemas declare
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema that generated schemas use
+type Schema struct {
+	Draft       string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Type is a JSON type name, or a list of them for nullable values
+	Type interface{} `json:"type,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is false for structs and the value schema for maps
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	marshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generate derives the schema encoding/json implies for a Go type. Structs
+// reject unknown fields, so misspelled keys are caught.
+func Generate(t reflect.Type, title string) *Schema {
+	s := generate(t, map[reflect.Type]bool{})
+	s.Draft = Draft
+	s.Title = title
+	return s
+}
+
+func generate(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == durationType {
+		return &Schema{Type: "integer", Description: "duration in nanoseconds"}
+	}
+	// Custom encodings (e.g. secrets) are serialized as strings
+	if t.Implements(marshalerType) || t.Implements(textMarshalType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := generate(t.Elem(), visiting)
+		if name, ok := s.Type.(string); ok {
+			s.Type = []string{name, "null"}
+		}
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Description: "base64"}
+		}
+		return &Schema{Type: []string{"array", "null"}, Items: generate(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: []string{"object", "null"}, AdditionalProperties: generate(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		addFields(s, t, visiting)
+		return s
+	default:
+		// interface{} and other dynamic values accept anything
+		return &Schema{}
+	}
+}
+
+// addFields adds a struct's JSON fields, flattening embedded structs as
+// encoding/json does
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(s, embedded, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = generate(field.Type, visiting)
+	}
+}
+
+// types returns the JSON types a schema allows; nil allows any
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, name := range t {
+			if str, ok := name.(string); ok {
+				names = append(names, str)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// additional returns the schema for properties not listed, and whether any
+// are allowed
+func (s *Schema) additional() (*Schema, bool) {
+	switch a := s.AdditionalProperties.(type) {
+	case bool:
+		return &Schema{}, a
+	case *Schema:
+		return a, true
+	case map[string]interface{}:
+		// Decoded from JSON: re-decode into a Schema
+		data, _ := json.Marshal(a)
+		var nested Schema
+		if err := json.Unmarshal(data, &nested); err != nil {
+			return &Schema{}, true
+		}
+		s.AdditionalProperties = &nested
+		return &nested, true
+	}
+	return &Schema{}, true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
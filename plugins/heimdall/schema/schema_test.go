@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "rewrite the committed schema files")
+
+func TestArtifactSchemaCurrent(t *testing.T) {
+	data, err := Encode(GenerateArtifact())
+	require.NoError(t, err)
+	if *updateSchema {
+		require.NoError(t, os.WriteFile("artifact.schema.json", data, 0o644))
+		return
+	}
+	assert.Equal(t, string(data), string(ArtifactJSON), "run go test ./schema -run SchemaCurrent -update-schema")
+}
+
+type secret struct{}
+
+func (secret) MarshalJSON() ([]byte, error) { return []byte(`""`), nil }
+
+type embedded struct {
+	Shared string `json:"shared"`
+}
+
+type example struct {
+	embedded
+	Name     string              `json:"name"`
+	Count    int                 `json:"count,omitempty"`
+	Ratio    float64             `json:"ratio"`
+	Timeout  time.Duration       `json:"timeout"`
+	Tags     []string            `json:"tags"`
+	Limits   map[string]int      `json:"limits"`
+	Child    *example            `json:"child,omitempty"`
+	Token    secret              `json:"token"`
+	Extra    interface{}         `json:"extra"`
+	Skipped  string              `json:"-"`
+	internal string              //nolint:unused
+	Nested   map[string]embedded `json:"nested"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(reflect.TypeOf(example{}), "Example")
+
+	assert.Equal(t, Draft, s.Draft)
+	assert.Equal(t, false, s.AdditionalProperties)
+	assert.Contains(t, s.Properties, "shared", "embedded fields are flattened")
+	assert.NotContains(t, s.Properties, "Skipped")
+	assert.NotContains(t, s.Properties, "internal")
+	assert.Equal(t, "integer", s.Properties["timeout"].Type)
+	assert.Equal(t, "string", s.Properties["token"].Type)
+	assert.Equal(t, []string{"array", "null"}, s.Properties["tags"].Type)
+	assert.Equal(t, []string{"object", "null"}, s.Properties["limits"].Type)
+	assert.Equal(t, &Schema{}, s.Properties["child"], "recursive types accept any value")
+}
+
+func TestValidate(t *testing.T) {
+	s := Generate(reflect.TypeOf(example{}), "Example")
+
+	t.Run("should accept a conforming document", func(t *testing.T) {
+		violations, err := Validate(s, []byte(`{
+			"name": "a", "count": 2, "ratio": 1, "timeout": 5000000, "tags": ["x"],
+			"limits": {"m": 1}, "child": {"name": "b"}, "token": "t", "extra": [1, "x"],
+			"nested": {"k": {"shared": "v"}}
+		}`))
+		require.NoError(t, err)
+		assert.Empty(t, violations)
+	})
+
+	t.Run("should report type mismatches and unknown fields by path", func(t *testing.T) {
+		violations, err := Validate(s, []byte(`{
+			"count": 1.5, "timeout": "5s", "tags": [1], "limits": {"m": "x"},
+			"nested": {"k": {"shared": 1, "sharde": ""}}
+		}`))
+		require.NoError(t, err)
+		var messages []string
+		for _, v := range violations {
+			messages = append(messages, v.String())
+		}
+		assert.Equal(t, []string{
+			"$.count: expected integer, got number",
+			"$.limits.m: expected integer, got string",
+			"$.nested.k.sharde: unknown field",
+			"$.nested.k.shared: expected string, got integer",
+			"$.tags[0]: expected string, got integer",
+			"$.timeout: expected integer, got string",
+		}, messages)
+	})
+
+	t.Run("should validate against a schema decoded from JSON", func(t *testing.T) {
+		data, err := Encode(s)
+		require.NoError(t, err)
+		decoded := mustParse(data)
+
+		violations, err := Validate(decoded, []byte(`{"limits": {"m": "x"}, "bogus": true}`))
+		require.NoError(t, err)
+		assert.Len(t, violations, 2)
+	})
+
+	t.Run("should reject documents that are not JSON", func(t *testing.T) {
+		_, err := Validate(s, []byte(`router:`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// ConfigJSON is the schema of the plugin configuration. It is generated from
+// the plugin's Config type, which this package cannot import; a test in the
+// plugin keeps it current.
+//
+//go:embed config.schema.json
+var ConfigJSON []byte
+
+// ArtifactJSON is the schema of routing artifacts, generated from
+// core.AvengersArtifact
+//
+//go:embed artifact.schema.json
+var ArtifactJSON []byte
+
+// DecisionJSON is the schema of encoded routing decisions, the stable format
+// decision logs and the advisory routing service use. Like the config
+// schema, it is generated and kept current by the plugin.
+//
+//go:embed decision.schema.json
+var DecisionJSON []byte
+
+var (
+	configSchema, artifactSchema, decisionSchema             *Schema
+	configSchemaOnce, artifactSchemaOnce, decisionSchemaOnce sync.Once
+)
+
+// Config returns the plugin configuration schema
+func Config() *Schema {
+	configSchemaOnce.Do(func() { configSchema = mustParse(ConfigJSON) })
+	return configSchema
+}
+
+// Artifact returns the routing artifact schema
+func Artifact() *Schema {
+	artifactSchemaOnce.Do(func() { artifactSchema = mustParse(ArtifactJSON) })
+	return artifactSchema
+}
+
+// Decision returns the routing decision schema
+func Decision() *Schema {
+	decisionSchemaOnce.Do(func() { decisionSchema = mustParse(DecisionJSON) })
+	return decisionSchema
+}
+
+// GenerateArtifact derives the artifact schema from core.AvengersArtifact
+func GenerateArtifact() *Schema {
+	return Generate(reflect.TypeOf(core.AvengersArtifact{}), "Heimdall routing artifact")
+}
+
+// Encode renders a schema as the committed schema files are written
+func Encode(s *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func mustParse(data []byte) *Schema {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		panic(fmt.Sprintf("embedded schema is invalid: %v", err))
+	}
+	return &s
+}
+
+// Violation is a document value that does not conform to a schema
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidateConfig checks an encoded plugin configuration
+func ValidateConfig(data []byte) ([]Violation, error) {
+	return Validate(Config(), data)
+}
+
+// Validate checks an encoded JSON document against a schema. It returns an
+// error only when the document is not JSON.
+func Validate(s *Schema, data []byte) ([]Violation, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var violations []Violation
+	validate(s, doc, "$", &violations)
+	return violations, nil
+}
+
+func validate(s *Schema, value interface{}, path string, violations *[]Violation) {
+	allowed := s.types()
+	if len(allowed) > 0 {
+		actual := jsonType(value)
+		if !slices.Contains(allowed, actual) && !(actual == "integer" && slices.Contains(allowed, "number")) {
+			*violations = append(*violations, Violation{
+				Path:    path,
+				Message: fmt.Sprintf("expected %s, got %s", describe(allowed), actual),
+			})
+			return
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			child := path + "." + key
+			if property, ok := s.Properties[key]; ok {
+				validate(property, v[key], child, violations)
+				continue
+			}
+			extra, ok := s.additional()
+			if !ok {
+				*violations = append(*violations, Violation{Path: child, Message: "unknown field"})
+				continue
+			}
+			validate(extra, v[key], child, violations)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func describe(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("one of %v", types)
+}
@@ -0,0 +1,805 @@
+package scoring
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// AlphaScorer implements α-score model selection with advanced features
+// Includes caching, batch optimization, and historical performance tracking
+type AlphaScorer struct {
+	mu              sync.RWMutex
+	scoreCache      sync.Map // string -> *ModelScore
+	performanceHist sync.Map // string -> *PerformanceHistory
+	costOverrides   sync.Map // string -> float64
+	penaltyHooks    []PenaltyHook
+	costDiscount    CostDiscount
+	costScale       CostScale
+	quality         *QualityStore
+	cacheTTL        time.Duration
+	lastCacheClean  time.Time
+
+	// Score inputs rejected as NaN or infinite
+	nonFiniteQuality atomic.Int64
+	nonFiniteCost    atomic.Int64
+}
+
+// PerformanceHistory tracks model performance over time for alpha tuning
+type PerformanceHistory struct {
+	ModelName     string    `json:"model_name"`
+	SuccessRate   float64   `json:"success_rate"`
+	AvgLatency    float64   `json:"avg_latency"`
+	TotalRequests int64     `json:"total_requests"`
+	LastUpdated   time.Time `json:"last_updated"`
+	AlphaOptimal  float64   `json:"alpha_optimal"` // Learned optimal alpha
+
+	// Completed requests by outcome; partial failures returned part of a
+	// response, e.g. streams aborted mid-response
+	Outcomes        int64 `json:"outcomes"`
+	Failures        int64 `json:"failures"`
+	PartialFailures int64 `json:"partial_failures"`
+}
+
+// PenaltyHook returns an additional, uncached penalty for a model based on
+// live state (e.g. endpoint saturation)
+type PenaltyHook func(model string, features *core.RequestFeatures) float64
+
+// CostDiscount returns the fraction of a model's cost a request is expected
+// to save, e.g. from provider prompt caching; applied uncached like penalty
+// hooks
+type CostDiscount func(model string, features *core.RequestFeatures) float64
+
+// CostScale returns a factor for a model's cost on a request, e.g. from
+// providers counting more tokens than estimated; applied before discounts
+type CostScale func(model string, features *core.RequestFeatures) float64
+
+// ScoreCacheEntry represents a cached score with expiration
+type ScoreCacheEntry struct {
+	Score     *core.ModelScore
+	ExpiresAt time.Time
+}
+
+func NewAlphaScorer() *AlphaScorer {
+	return &AlphaScorer{
+		cacheTTL:       5 * time.Minute,
+		lastCacheClean: time.Now(),
+	}
+}
+
+// NewAlphaScorerWithCache creates scorer with custom cache settings
+func NewAlphaScorerWithCache(cacheTTL time.Duration) *AlphaScorer {
+	return &AlphaScorer{
+		cacheTTL:       cacheTTL,
+		lastCacheClean: time.Now(),
+	}
+}
+
+func (as *AlphaScorer) SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no candidates provided")
+	}
+
+	// Clean expired cache entries periodically
+	if time.Since(as.lastCacheClean) > 10*time.Minute {
+		as.cleanExpiredCache()
+	}
+
+	scores, err := as.scoreModelsBatched(candidates, features, artifact)
+	if err != nil {
+		return "", err
+	}
+	return as.pickBest(scores, candidates, features), nil
+}
+
+// SelectBestBefore is SelectBest that stops scoring at deadline and selects
+// the best candidate scored so far, reporting whether any were skipped. At
+// least one candidate is always scored; a zero deadline scores them all.
+func (as *AlphaScorer) SelectBestBefore(deadline time.Time, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, bool, error) {
+	if len(candidates) == 0 {
+		return "", false, fmt.Errorf("no candidates provided")
+	}
+
+	scores, scored := as.scoreModelsUntil(deadline, candidates, features, artifact)
+	return as.pickBest(scores, candidates, features), scored < len(candidates), nil
+}
+
+// pickBest returns the highest α-score among scores, or the first candidate
+// when none could be scored
+func (as *AlphaScorer) pickBest(scores []core.ModelScore, candidates []string, features *core.RequestFeatures) string {
+	if len(scores) == 0 {
+		return candidates[0] // Fallback to first candidate
+	}
+
+	// Sort by α-score (descending) with tie-breaking
+	sort.SliceStable(scores, func(i, j int) bool {
+		if math.Abs(scores[i].AlphaScore-scores[j].AlphaScore) < 0.001 {
+			// Tie-breaking: prefer lower cost for equal quality, then the
+			// model name, so ties do not depend on candidate order
+			if scores[i].CostScore != scores[j].CostScore {
+				return scores[i].CostScore < scores[j].CostScore
+			}
+			return scores[i].Model < scores[j].Model
+		}
+		return scores[i].AlphaScore > scores[j].AlphaScore
+	})
+
+	best := scores[0]
+
+	// Update performance history (async)
+	go as.updatePerformanceHistory(best.Model, features)
+
+	log.Printf("Selected model: %s (α-score: %.3f, quality: %.3f, cost: %.3f, penalty: %.3f)",
+		best.Model, best.AlphaScore, best.QualityScore, best.CostScore, best.PenaltyScore)
+
+	return best.Model
+}
+
+// SetCostOverride pins the cost score for a model regardless of the artifact
+// (e.g. zero-cost self-hosted capacity)
+func (as *AlphaScorer) SetCostOverride(model string, cost float64) {
+	as.costOverrides.Store(model, cost)
+}
+
+// AddPenaltyHook registers a dynamic penalty applied on top of cached scores
+func (as *AlphaScorer) AddPenaltyHook(hook PenaltyHook) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.penaltyHooks = append(as.penaltyHooks, hook)
+}
+
+// SetQualityStore blends online quality estimates into Q̂ for future scores
+func (as *AlphaScorer) SetQualityStore(store *QualityStore) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.quality = store
+}
+
+// SetCostDiscount discounts model costs per request with discount
+func (as *AlphaScorer) SetCostDiscount(discount CostDiscount) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.costDiscount = discount
+}
+
+// SetCostScale scales model costs per request with scale
+func (as *AlphaScorer) SetCostScale(scale CostScale) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.costScale = scale
+}
+
+// applyCostDiscount scales a score's cost and lowers it by the request's
+// expected savings without touching the cache
+func (as *AlphaScorer) applyCostDiscount(score core.ModelScore, features *core.RequestFeatures, artifact *core.AvengersArtifact) core.ModelScore {
+	as.mu.RLock()
+	discount, scale := as.costDiscount, as.costScale
+	as.mu.RUnlock()
+
+	if scale != nil {
+		if factor := scale(score.Model, features); factor > 0 && factor != 1 {
+			extra := score.CostScore * (factor - 1)
+			score.CostScore += extra
+			score.AlphaScore -= (1 - artifact.Alpha) * extra
+		}
+	}
+	if discount == nil {
+		return score
+	}
+
+	if fraction := discount(score.Model, features); fraction > 0 {
+		saved := score.CostScore * math.Min(fraction, 1)
+		score.CostScore -= saved
+		score.AlphaScore += (1 - artifact.Alpha) * saved
+	}
+	return score
+}
+
+// applyPenaltyHooks adds dynamic penalties to a score without touching the cache
+func (as *AlphaScorer) applyPenaltyHooks(score core.ModelScore, features *core.RequestFeatures) core.ModelScore {
+	as.mu.RLock()
+	hooks := as.penaltyHooks
+	as.mu.RUnlock()
+
+	for _, hook := range hooks {
+		penalty := hook(score.Model, features)
+		if penalty != 0 {
+			score.PenaltyScore += penalty
+			score.AlphaScore -= penalty
+		}
+	}
+	return score
+}
+
+// SelectBestWithExplanation returns the best model with detailed scoring breakdown
+func (as *AlphaScorer) SelectBestWithExplanation(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, []core.ModelScore, error) {
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("no candidates provided")
+	}
+
+	scores, err := as.scoreModelsBatched(candidates, features, artifact)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(scores) == 0 {
+		return candidates[0], nil, nil
+	}
+
+	// Sort by α-score (descending)
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].AlphaScore > scores[j].AlphaScore
+	})
+
+	return scores[0].Model, scores, nil
+}
+
+// scoreModelsBatched implements optimized batch scoring with caching
+func (as *AlphaScorer) scoreModelsBatched(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) ([]core.ModelScore, error) {
+	scores, _ := as.scoreModelsUntil(time.Time{}, candidates, features, artifact)
+	return scores, nil
+}
+
+// scoreModelsUntil scores candidates in order until deadline passes, returning
+// the scores and how many candidates were considered
+func (as *AlphaScorer) scoreModelsUntil(deadline time.Time, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) ([]core.ModelScore, int) {
+	var scores []core.ModelScore
+
+	// Pre-allocate slice for efficiency
+	scores = make([]core.ModelScore, 0, len(candidates))
+
+	scored := 0
+	for _, model := range candidates {
+		if scored > 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		scored++
+
+		// Try cache first
+		if cachedScore := as.getCachedScore(model, features, artifact); cachedScore != nil {
+			scores = append(scores, as.applyPenaltyHooks(as.applyCostDiscount(*cachedScore, features, artifact), features))
+			continue
+		}
+
+		// Calculate fresh score
+		score := as.scoreModel(model, features, artifact)
+		if score != nil {
+			// Cache the result
+			as.cacheScore(model, features, artifact, score)
+			scores = append(scores, as.applyPenaltyHooks(as.applyCostDiscount(*score, features, artifact), features))
+		}
+	}
+
+	// Normalize after the cache so cached scores stay on the artifact's scale
+	normalizeScores(scores, artifact.Normalization, artifact.Alpha)
+
+	return scores, scored
+}
+
+// scoreAll scores candidates like scoreModelsBatched but bypassing the score
+// cache; candidates the artifact has no data for are omitted
+func (as *AlphaScorer) scoreAll(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) []core.ModelScore {
+	var scores []core.ModelScore
+	for _, model := range candidates {
+		if score := as.scoreModel(model, features, artifact); score != nil {
+			scores = append(scores, as.applyPenaltyHooks(as.applyCostDiscount(*score, features, artifact), features))
+		}
+	}
+	normalizeScores(scores, artifact.Normalization, artifact.Alpha)
+	return scores
+}
+
+// scoreModels maintains backward compatibility
+func (as *AlphaScorer) scoreModels(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) ([]core.ModelScore, error) {
+	return as.scoreModelsBatched(candidates, features, artifact)
+}
+
+func (as *AlphaScorer) scoreModel(model string, features *core.RequestFeatures, artifact *core.AvengersArtifact) *core.ModelScore {
+	// Get quality score for this model and cluster
+	qualityScore := as.QualityScore(model, features.ClusterID, artifact)
+	if qualityScore == nil {
+		return nil
+	}
+
+	as.mu.RLock()
+	quality := as.quality
+	as.mu.RUnlock()
+	if quality != nil {
+		blended := quality.Blend(model, features.ClusterID, *qualityScore)
+		if !isFinite(blended) {
+			as.nonFiniteQuality.Add(1)
+			return nil
+		}
+		qualityScore = &blended
+	}
+
+	// Get cost score for this model
+	costScore := as.getCostScore(model, artifact)
+	if costScore == nil {
+		return nil
+	}
+
+	// Calculate penalties
+	penaltyScore := as.calculatePenalties(model, features, artifact)
+
+	// Calculate α-score: α * Q̂[m,c] - (1-α) * Ĉ[m] - penalties
+	alpha := artifact.Alpha
+	alphaScore := (alpha * *qualityScore) - ((1 - alpha) * *costScore) - penaltyScore
+
+	return &core.ModelScore{
+		Model:        model,
+		QualityScore: *qualityScore,
+		CostScore:    *costScore,
+		PenaltyScore: penaltyScore,
+		AlphaScore:   alphaScore,
+	}
+}
+
+// QualityScore returns the artifact's Q̂ for a model on a cluster, falling
+// back to the model's average across clusters. NaN and infinite values are
+// rejected as missing.
+func (as *AlphaScorer) QualityScore(model string, clusterID int, artifact *core.AvengersArtifact) *float64 {
+	modelQuality, ok := artifact.Qhat[model]
+	if !ok || len(modelQuality) == 0 {
+		return nil
+	}
+
+	// Use cluster-specific quality score, fallback to average
+	if clusterID >= 0 && clusterID < len(modelQuality) {
+		score := modelQuality[clusterID]
+		if isFinite(score) {
+			return &score
+		}
+		as.nonFiniteQuality.Add(1)
+	}
+
+	// Fallback to average quality across all clusters
+	avg, n := 0.0, 0
+	for _, score := range modelQuality {
+		if isFinite(score) {
+			avg += score
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	avg /= float64(n)
+	return &avg
+}
+
+func (as *AlphaScorer) getCostScore(model string, artifact *core.AvengersArtifact) *float64 {
+	if override, ok := as.costOverrides.Load(model); ok {
+		cost := override.(float64)
+		return &cost
+	}
+	if cost, ok := artifact.Chat[model]; ok {
+		if !isFinite(cost) {
+			as.nonFiniteCost.Add(1)
+			return nil
+		}
+		return &cost
+	}
+	return nil
+}
+
+// SanityStats reports score inputs rejected as NaN or infinite
+func (as *AlphaScorer) SanityStats() ScoreSanityStats {
+	return ScoreSanityStats{
+		NonFiniteQuality: as.nonFiniteQuality.Load(),
+		NonFiniteCost:    as.nonFiniteCost.Load(),
+	}
+}
+
+func (as *AlphaScorer) calculatePenalties(model string, features *core.RequestFeatures, artifact *core.AvengersArtifact) float64 {
+	penalty := 0.0
+
+	// Context over-utilization penalty
+	if features.ContextRatio > 0.8 {
+		penalty += artifact.Penalties.CtxOver80Pct
+	}
+
+	// Latency variance penalty (simplified)
+	expectedLatency := as.estimateLatency(model, features)
+	if features.AvgLatency != nil {
+		latencyVariance := math.Abs(expectedLatency-*features.AvgLatency) / *features.AvgLatency
+		if latencyVariance > 0.2 {
+			penalty += artifact.Penalties.LatencySD * latencyVariance
+		}
+	}
+
+	// Model-specific penalties
+	penalty += as.getModelSpecificPenalties(model, features)
+
+	return penalty
+}
+
+func (as *AlphaScorer) estimateLatency(model string, features *core.RequestFeatures) float64 {
+	// Base latency estimates (in seconds)
+	baseLatencies := map[string]float64{
+		"deepseek/deepseek-r1":  3.0,
+		"qwen/qwen3-coder":      2.5,
+		"openai/gpt-5":          8.0,
+		"google/gemini-2.5-pro": 6.0,
+	}
+
+	latency := baseLatencies[model]
+	if latency == 0 {
+		latency = 5.0 // Default
+	}
+
+	// Scale with token count for large contexts
+	if features.TokenCount > 5000 {
+		tokenMultiplier := math.Min(float64(features.TokenCount)/10000, 3.0)
+		latency *= (1 + tokenMultiplier*0.5)
+	}
+
+	// Reasoning models take longer for complex tasks
+	if (strings.Contains(model, "gpt-5") || strings.Contains(model, "gemini")) &&
+		(features.HasCode || features.HasMath) {
+		latency *= 1.5
+	}
+
+	return latency
+}
+
+func (as *AlphaScorer) getModelSpecificPenalties(model string, features *core.RequestFeatures) float64 {
+	penalty := 0.0
+
+	// DeepSeek is good for code, give bonus
+	if features.HasCode && strings.Contains(model, "deepseek") {
+		penalty -= 0.05
+	}
+
+	// Math tasks benefit from reasoning models
+	if features.HasMath && !strings.Contains(model, "gpt-5") && !strings.Contains(model, "gemini") {
+		penalty += 0.1
+	}
+
+	// Very long context penalty for models without good long-context support
+	if features.TokenCount > 100000 && !strings.Contains(model, "gemini") {
+		penalty += 0.15
+	}
+
+	return penalty
+}
+
+// ============================================================================
+// ADVANCED ALPHA SCORING METHODS - Phase 3 Implementation
+// Caching, performance tracking, A/B testing, and optimization features
+// ============================================================================
+
+// getCachedScore retrieves a cached alpha score if available and not expired
+func (as *AlphaScorer) getCachedScore(model string, features *core.RequestFeatures, artifact *core.AvengersArtifact) *core.ModelScore {
+	cacheKey := as.generateCacheKey(model, features, artifact)
+
+	if cached, ok := as.scoreCache.Load(cacheKey); ok {
+		entry := cached.(*ScoreCacheEntry)
+		if time.Now().Before(entry.ExpiresAt) {
+			return entry.Score
+		}
+		// Expired - remove from cache
+		as.scoreCache.Delete(cacheKey)
+	}
+
+	return nil
+}
+
+// cacheScore stores a calculated score in the cache with expiration
+func (as *AlphaScorer) cacheScore(model string, features *core.RequestFeatures, artifact *core.AvengersArtifact, score *core.ModelScore) {
+	cacheKey := as.generateCacheKey(model, features, artifact)
+
+	entry := &ScoreCacheEntry{
+		Score:     score,
+		ExpiresAt: time.Now().Add(as.cacheTTL),
+	}
+
+	as.scoreCache.Store(cacheKey, entry)
+}
+
+// generateCacheKey creates a deterministic cache key from inputs
+func (as *AlphaScorer) generateCacheKey(model string, features *core.RequestFeatures, artifact *core.AvengersArtifact) string {
+	// Create deterministic key based on relevant inputs
+	keyData := fmt.Sprintf("%s:%d:%d:%.2f:%.2f:%t:%t",
+		model,
+		features.ClusterID,
+		features.TokenCount,
+		artifact.Alpha,
+		features.ContextRatio,
+		features.HasCode,
+		features.HasMath,
+	)
+
+	// Hash to fixed-length key
+	hash := sha256.Sum256([]byte(keyData))
+	return fmt.Sprintf("score:%x", hash[:8]) // Use first 8 bytes for efficiency
+}
+
+// cleanExpiredCache removes expired entries from the score cache
+func (as *AlphaScorer) cleanExpiredCache() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	now := time.Now()
+	as.lastCacheClean = now
+
+	// Iterate through cache and remove expired entries
+	as.scoreCache.Range(func(key, value interface{}) bool {
+		entry := value.(*ScoreCacheEntry)
+		if now.After(entry.ExpiresAt) {
+			as.scoreCache.Delete(key)
+		}
+		return true
+	})
+}
+
+// updatePerformanceHistory tracks model performance for alpha optimization
+func (as *AlphaScorer) updatePerformanceHistory(model string, features *core.RequestFeatures) {
+	histKey := fmt.Sprintf("perf:%s", model)
+
+	now := time.Now()
+
+	if existing, ok := as.performanceHist.Load(histKey); ok {
+		// Update existing history
+		hist := existing.(*PerformanceHistory)
+		as.mu.Lock()
+		hist.TotalRequests++
+		hist.LastUpdated = now
+		// Update average latency if available
+		if features.AvgLatency != nil {
+			hist.AvgLatency = (hist.AvgLatency + *features.AvgLatency) / 2.0
+		}
+		as.mu.Unlock()
+	} else {
+		// Create new history entry
+		hist := &PerformanceHistory{
+			ModelName:     model,
+			SuccessRate:   1.0, // Assume success initially
+			AvgLatency:    5.0, // Default latency
+			TotalRequests: 1,
+			LastUpdated:   now,
+			AlphaOptimal:  0.7, // Default alpha
+		}
+
+		if features.AvgLatency != nil {
+			hist.AvgLatency = *features.AvgLatency
+		}
+
+		as.performanceHist.Store(histKey, hist)
+	}
+}
+
+// RecordOutcome records how a request routed to model ended. A partial
+// failure delivered part of a response before failing; it counts against
+// the success rate like any other failure.
+func (as *AlphaScorer) RecordOutcome(model string, success, partial bool) {
+	histKey := fmt.Sprintf("perf:%s", model)
+	entry, _ := as.performanceHist.LoadOrStore(histKey, &PerformanceHistory{
+		ModelName:    model,
+		SuccessRate:  1.0,
+		AvgLatency:   5.0,
+		AlphaOptimal: 0.7,
+	})
+	hist := entry.(*PerformanceHistory)
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	hist.Outcomes++
+	if !success {
+		hist.Failures++
+		if partial {
+			hist.PartialFailures++
+		}
+	}
+	hist.SuccessRate = float64(hist.Outcomes-hist.Failures) / float64(hist.Outcomes)
+	hist.LastUpdated = time.Now()
+}
+
+// GetPerformanceMetrics returns performance history for observability
+func (as *AlphaScorer) GetPerformanceMetrics() map[string]*PerformanceHistory {
+	metrics := make(map[string]*PerformanceHistory)
+
+	as.performanceHist.Range(func(key, value interface{}) bool {
+		keyStr := key.(string)
+		hist := value.(*PerformanceHistory)
+		metrics[keyStr] = hist
+		return true
+	})
+
+	return metrics
+}
+
+// TuneAlphaParameter implements adaptive alpha tuning based on historical performance
+//
+// Deprecated: the plugin's AlphaController adjusts alpha in a closed loop
+// against configured targets.
+func (as *AlphaScorer) TuneAlphaParameter(currentAlpha float64, successRate float64, avgLatency float64) float64 {
+	// Simple adaptive tuning algorithm
+	// If success rate is low, favor quality (increase alpha)
+	// If latency is high, favor speed/cost (decrease alpha)
+
+	newAlpha := currentAlpha
+
+	if successRate < 0.8 {
+		// Low success rate - increase quality weight
+		newAlpha = math.Min(currentAlpha+0.05, 0.95)
+	} else if successRate > 0.95 && avgLatency > 10.0 {
+		// High success but slow - can reduce quality weight for speed
+		newAlpha = math.Max(currentAlpha-0.05, 0.1)
+	}
+
+	return newAlpha
+}
+
+// ScoreModelsWithAlphaTuning implements A/B testing for alpha parameter optimization
+func (as *AlphaScorer) ScoreModelsWithAlphaTuning(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact, explorationRate float64) ([]core.ModelScore, float64, error) {
+	// A/B test: Use different alpha values for exploration
+	originalAlpha := artifact.Alpha
+	testAlpha := originalAlpha
+
+	// With probability explorationRate, try a different alpha
+	if math.Mod(float64(time.Now().UnixNano()), 1.0) < explorationRate {
+		// Explore different alpha values
+		alphaVariants := []float64{0.3, 0.5, 0.7, 0.9}
+		variantIndex := int(time.Now().UnixNano()) % len(alphaVariants)
+		testAlpha = alphaVariants[variantIndex]
+
+		// Temporarily modify artifact
+		testArtifact := *artifact
+		testArtifact.Alpha = testAlpha
+		artifact = &testArtifact
+	}
+
+	scores, err := as.scoreModelsBatched(candidates, features, artifact)
+	if err != nil {
+		return nil, originalAlpha, err
+	}
+
+	return scores, testAlpha, nil
+}
+
+// ScoreCacheStats reports the score cache
+type ScoreCacheStats struct {
+	Size           int       `json:"cache_size"`
+	ExpiredEntries int       `json:"expired_entries"`
+	TTLMinutes     int       `json:"cache_ttl_minutes"`
+	LastCleanup    time.Time `json:"last_cleanup"`
+}
+
+// CacheStats returns cache performance metrics
+func (as *AlphaScorer) CacheStats() ScoreCacheStats {
+	stats := ScoreCacheStats{
+		TTLMinutes:  int(as.cacheTTL.Minutes()),
+		LastCleanup: as.lastCacheClean,
+	}
+	now := time.Now()
+
+	as.scoreCache.Range(func(key, value interface{}) bool {
+		stats.Size++
+		entry := value.(*ScoreCacheEntry)
+		if now.After(entry.ExpiresAt) {
+			stats.ExpiredEntries++
+		}
+		return true
+	})
+	return stats
+}
+
+// GetCacheMetrics returns CacheStats as a map, for existing callers
+func (as *AlphaScorer) GetCacheMetrics() map[string]interface{} {
+	stats := as.CacheStats()
+	return map[string]interface{}{
+		"cache_size":        stats.Size,
+		"expired_entries":   stats.ExpiredEntries,
+		"cache_ttl_minutes": stats.TTLMinutes,
+		"last_cleanup":      stats.LastCleanup.Format(time.RFC3339),
+	}
+}
+
+// InvalidateCache clears all cached scores (useful for testing or after artifact updates)
+func (as *AlphaScorer) InvalidateCache() {
+	as.scoreCache.Range(func(key, value interface{}) bool {
+		as.scoreCache.Delete(key)
+		return true
+	})
+}
+
+// ScoreModelsConcurrent implements concurrent scoring for improved performance
+func (as *AlphaScorer) ScoreModelsConcurrent(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact, maxWorkers int) ([]core.ModelScore, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// Limit workers to avoid over-subscription
+	workers := maxWorkers
+	if workers <= 0 || workers > len(candidates) {
+		workers = len(candidates)
+	}
+
+	type scoreJob struct {
+		model string
+		index int
+	}
+
+	type scoreResult struct {
+		score *core.ModelScore
+		index int
+	}
+
+	jobs := make(chan scoreJob, len(candidates))
+	results := make(chan scoreResult, len(candidates))
+
+	// Start workers
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				score := as.scoreModel(job.model, features, artifact)
+				results <- scoreResult{score: score, index: job.index}
+			}
+		}()
+	}
+
+	// Send jobs
+	for i, model := range candidates {
+		jobs <- scoreJob{model: model, index: i}
+	}
+	close(jobs)
+
+	// Collect results
+	scores := make([]*core.ModelScore, len(candidates))
+	for i := 0; i < len(candidates); i++ {
+		result := <-results
+		scores[result.index] = result.score
+	}
+
+	// Filter out nil scores and convert to slice
+	var validScores []core.ModelScore
+	for _, score := range scores {
+		if score != nil {
+			validScores = append(validScores, *score)
+		}
+	}
+	normalizeScores(validScores, artifact.Normalization, artifact.Alpha)
+
+	return validScores, nil
+}
+
+// EstimateOptimalAlpha suggests an optimal alpha value based on task characteristics
+func (as *AlphaScorer) EstimateOptimalAlpha(features *core.RequestFeatures) float64 {
+	baseAlpha := 0.7 // Default
+
+	// Adjust based on task characteristics
+	if features.HasCode {
+		// Code tasks benefit from specialized models (favor quality)
+		baseAlpha += 0.1
+	}
+
+	if features.HasMath {
+		// Math tasks need reasoning capabilities (strongly favor quality)
+		baseAlpha += 0.15
+	}
+
+	if features.TokenCount > 50000 {
+		// Long context tasks need capable models (favor quality)
+		baseAlpha += 0.05
+	} else if features.TokenCount < 1000 {
+		// Short tasks can use cheaper models (favor cost)
+		baseAlpha -= 0.1
+	}
+
+	if features.ContextRatio > 0.8 {
+		// High context utilization needs capable models
+		baseAlpha += 0.05
+	}
+
+	// Clamp to reasonable range
+	return math.Max(0.1, math.Min(0.95, baseAlpha))
+}
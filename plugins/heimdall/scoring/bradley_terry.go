@@ -0,0 +1,194 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+const (
+	bradleyTerryIterations = 200
+	bradleyTerryTolerance  = 1e-9
+
+	// bradleyTerryPrior is a pseudo-win credited each way for every compared
+	// pair, so a model that never wins keeps a positive strength
+	bradleyTerryPrior = 0.05
+)
+
+// ValidatePairwise checks that every win rate is within [0, 1]
+func ValidatePairwise(prefs *core.PairwisePreferences) error {
+	for cluster, rates := range prefs.WinRates {
+		for a, opponents := range rates {
+			for b, rate := range opponents {
+				if a == b {
+					return fmt.Errorf("cluster %d: %s is compared against itself", cluster, a)
+				}
+				if !isFinite(rate) || rate < 0 || rate > 1 {
+					return fmt.Errorf("cluster %d: win rate of %s over %s must be within [0, 1], got %v", cluster, a, b, rate)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyPairwise fills Qhat from the artifact's pairwise preferences,
+// replacing Q̂ for every model they rank
+func ApplyPairwise(artifact *core.AvengersArtifact) error {
+	if artifact.Pairwise == nil {
+		return nil
+	}
+	if err := ValidatePairwise(artifact.Pairwise); err != nil {
+		return err
+	}
+
+	if artifact.Qhat == nil {
+		artifact.Qhat = make(map[string][]float64)
+	}
+	for model, quality := range BradleyTerryQuality(artifact.Pairwise) {
+		artifact.Qhat[model] = quality
+	}
+	return nil
+}
+
+// BradleyTerryQuality fits a Bradley–Terry model to each cluster's win rates
+// and returns, per model and cluster, the expected win rate against the
+// cluster's other models. A model absent from a cluster gets its mean over
+// the clusters it was ranked in there.
+func BradleyTerryQuality(prefs *core.PairwisePreferences) map[string][]float64 {
+	quality := make(map[string][]float64)
+	ranked := make(map[string][]bool)
+	for cluster, rates := range prefs.WinRates {
+		for model, q := range fitBradleyTerry(rates) {
+			if _, ok := quality[model]; !ok {
+				quality[model] = make([]float64, len(prefs.WinRates))
+				ranked[model] = make([]bool, len(prefs.WinRates))
+			}
+			quality[model][cluster] = q
+			ranked[model][cluster] = true
+		}
+	}
+
+	for model, scores := range quality {
+		sum, n := 0.0, 0
+		for cluster, q := range scores {
+			if ranked[model][cluster] {
+				sum += q
+				n++
+			}
+		}
+		for cluster := range scores {
+			if !ranked[model][cluster] {
+				scores[cluster] = sum / float64(n)
+			}
+		}
+	}
+	return quality
+}
+
+// fitBradleyTerry estimates strengths for one cluster with the MM algorithm
+// (Hunter, 2004) and converts them to expected win rates
+func fitBradleyTerry(rates map[string]map[string]float64) map[string]float64 {
+	seen := make(map[string]bool)
+	for a, opponents := range rates {
+		seen[a] = true
+		for b := range opponents {
+			seen[b] = true
+		}
+	}
+	models := make([]string, 0, len(seen))
+	for m := range seen {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	index := make(map[string]int, len(models))
+	for i, m := range models {
+		index[m] = i
+	}
+	n := len(models)
+	if n == 0 {
+		return nil
+	}
+
+	// wins[i][j] is the (fractional) number of times i beat j
+	wins := make([][]float64, n)
+	compared := make([][]bool, n)
+	for i := range wins {
+		wins[i] = make([]float64, n)
+		compared[i] = make([]bool, n)
+	}
+	for a, opponents := range rates {
+		for b, rate := range opponents {
+			i, j := index[a], index[b]
+			wins[i][j] = rate
+			if _, ok := rates[b][a]; !ok {
+				wins[j][i] = 1 - rate
+			}
+			compared[i][j], compared[j][i] = true, true
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if compared[i][j] {
+				wins[i][j] += bradleyTerryPrior
+			}
+		}
+	}
+
+	strength := make([]float64, n)
+	for i := range strength {
+		strength[i] = 1
+	}
+	for iter := 0; iter < bradleyTerryIterations; iter++ {
+		next := make([]float64, n)
+		for i := 0; i < n; i++ {
+			won, denom := 0.0, 0.0
+			for j := 0; j < n; j++ {
+				if !compared[i][j] {
+					continue
+				}
+				won += wins[i][j]
+				denom += (wins[i][j] + wins[j][i]) / (strength[i] + strength[j])
+			}
+			next[i] = strength[i]
+			if denom > 0 {
+				next[i] = won / denom
+			}
+		}
+
+		// Strengths are only identified up to scale; pin the geometric mean
+		logMean := 0.0
+		for _, s := range next {
+			logMean += math.Log(s)
+		}
+		scale := math.Exp(logMean / float64(n))
+
+		change := 0.0
+		for i := range next {
+			next[i] /= scale
+			change = math.Max(change, math.Abs(next[i]-strength[i])/strength[i])
+		}
+		strength = next
+		if change < bradleyTerryTolerance {
+			break
+		}
+	}
+
+	quality := make(map[string]float64, n)
+	for i, m := range models {
+		if n == 1 {
+			quality[m] = 0.5
+			continue
+		}
+		expected := 0.0
+		for j := 0; j < n; j++ {
+			if j != i {
+				expected += strength[i] / (strength[i] + strength[j])
+			}
+		}
+		quality[m] = expected / float64(n-1)
+	}
+	return quality
+}
@@ -0,0 +1,81 @@
+package scoring
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBradleyTerry(t *testing.T) {
+	t.Run("should recover win rates consistent with the model", func(t *testing.T) {
+		// Strengths 4:2:1 imply these pairwise win rates
+		prefs := &core.PairwisePreferences{WinRates: []map[string]map[string]float64{{
+			"strong": {"medium": 4.0 / 6, "weak": 4.0 / 5},
+			"medium": {"weak": 2.0 / 3},
+		}}}
+
+		quality := BradleyTerryQuality(prefs)
+		require.Len(t, quality, 3)
+		assert.Greater(t, quality["strong"][0], quality["medium"][0])
+		assert.Greater(t, quality["medium"][0], quality["weak"][0])
+		// Expected win rate against the other two, softened slightly by the prior
+		assert.InDelta(t, (4.0/6+4.0/5)/2, quality["strong"][0], 0.03)
+		assert.InDelta(t, (1.0/3+1.0/5)/2, quality["weak"][0], 0.03)
+	})
+
+	t.Run("should keep models that never win finite", func(t *testing.T) {
+		prefs := &core.PairwisePreferences{WinRates: []map[string]map[string]float64{{
+			"a": {"b": 1},
+		}}}
+
+		quality := BradleyTerryQuality(prefs)
+		assert.Greater(t, quality["a"][0], quality["b"][0])
+		assert.Greater(t, quality["b"][0], 0.0)
+	})
+
+	t.Run("should fill clusters a model is absent from with its mean", func(t *testing.T) {
+		prefs := &core.PairwisePreferences{WinRates: []map[string]map[string]float64{
+			{"a": {"b": 0.7}},
+			{"b": {"c": 0.6}},
+			{"a": {"c": 0.9}},
+		}}
+
+		quality := BradleyTerryQuality(prefs)
+		assert.InDelta(t, (quality["a"][0]+quality["a"][2])/2, quality["a"][1], 1e-12)
+		assert.InDelta(t, (quality["c"][1]+quality["c"][2])/2, quality["c"][0], 1e-12)
+		assert.InDelta(t, (quality["b"][0]+quality["b"][1])/2, quality["b"][2], 1e-12)
+
+		_, err := json.Marshal(&core.AvengersArtifact{Qhat: quality})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should replace Qhat for ranked models at load", func(t *testing.T) {
+		artifact := &core.AvengersArtifact{
+			Qhat: map[string][]float64{"a": {0.1}, "unranked": {0.4}},
+			Pairwise: &core.PairwisePreferences{WinRates: []map[string]map[string]float64{
+				{"a": {"b": 0.9}},
+			}},
+		}
+		require.NoError(t, ApplyPairwise(artifact))
+		assert.Greater(t, artifact.Qhat["a"][0], 0.5)
+		assert.Less(t, artifact.Qhat["b"][0], 0.5)
+		assert.Equal(t, []float64{0.4}, artifact.Qhat["unranked"])
+	})
+
+	t.Run("should reject invalid win rates", func(t *testing.T) {
+		for _, rates := range []map[string]map[string]float64{
+			{"a": {"b": 1.5}},
+			{"a": {"b": math.NaN()}},
+			{"a": {"a": 0.5}},
+		} {
+			err := ApplyPairwise(&core.AvengersArtifact{Pairwise: &core.PairwisePreferences{
+				WinRates: []map[string]map[string]float64{rates},
+			}})
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// maxRecentMismatches bounds the mismatch samples kept for inspection
+const maxRecentMismatches = 20
+
+// DualRunConfig configures shadow comparison of a second scoring
+// implementation against the live α-scorer on a sample of traffic
+type DualRunConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// SampleRate is the fraction of selections compared
+	SampleRate float64 `json:"sample_rate"`
+
+	// Implementation names the shadow implementation (default "reference")
+	Implementation string `json:"implementation"`
+}
+
+// ScoringImplementation selects the best candidate for a request. Shadow
+// implementations must be free of side effects.
+type ScoringImplementation interface {
+	Select(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error)
+}
+
+// scoringImplementations are the shadow implementations available to dual-run
+var scoringImplementations = map[string]func(as *AlphaScorer) ScoringImplementation{
+	"reference": func(as *AlphaScorer) ScoringImplementation { return &referenceScorer{scorer: as} },
+}
+
+// referenceScorer is the straightforward, uncached α-score selection that
+// optimized scoring paths must agree with
+type referenceScorer struct {
+	scorer *AlphaScorer
+}
+
+func (rs *referenceScorer) Select(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no candidates provided")
+	}
+
+	scores := rs.scorer.scoreAll(candidates, features, artifact)
+	if len(scores) == 0 {
+		return candidates[0], nil
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if math.Abs(scores[i].AlphaScore-scores[j].AlphaScore) < 0.001 {
+			return scores[i].CostScore < scores[j].CostScore
+		}
+		return scores[i].AlphaScore > scores[j].AlphaScore
+	})
+	return scores[0].Model, nil
+}
+
+// DualRunMismatch records a selection the implementations disagreed on
+type DualRunMismatch struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ClusterID  int       `json:"cluster_id"`
+	Candidates []string  `json:"candidates"`
+	Primary    string    `json:"primary"`
+	Shadow     string    `json:"shadow"`
+}
+
+// DualRunStats reports comparison results
+type DualRunStats struct {
+	Implementation   string            `json:"implementation"`
+	Comparisons      int64             `json:"comparisons"`
+	Mismatches       int64             `json:"mismatches"`
+	Errors           int64             `json:"errors"`
+	MismatchRate     float64           `json:"mismatch_rate"`
+	PrimaryAvgMicros float64           `json:"primary_avg_micros"`
+	ShadowAvgMicros  float64           `json:"shadow_avg_micros"`
+	RecentMismatches []DualRunMismatch `json:"recent_mismatches,omitempty"`
+}
+
+// DualRunner compares the live scorer's selections with a shadow implementation
+type DualRunner struct {
+	config DualRunConfig
+	shadow ScoringImplementation
+
+	stats         DualRunStats
+	primaryMicros float64
+	shadowMicros  float64
+	mu            sync.Mutex
+}
+
+// NewDualRunner creates a runner for the configured shadow implementation
+func NewDualRunner(config DualRunConfig, scorer *AlphaScorer) (*DualRunner, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be within [0, 1], got %v", config.SampleRate)
+	}
+	if config.Implementation == "" {
+		config.Implementation = "reference"
+	}
+	newImpl, ok := scoringImplementations[config.Implementation]
+	if !ok {
+		return nil, fmt.Errorf("unknown scoring implementation %q", config.Implementation)
+	}
+
+	return &DualRunner{
+		config: config,
+		shadow: newImpl(scorer),
+		stats:  DualRunStats{Implementation: config.Implementation},
+	}, nil
+}
+
+// ShouldSample decides whether a selection is compared
+func (dr *DualRunner) ShouldSample() bool {
+	return rand.Float64() < dr.config.SampleRate
+}
+
+// Compare runs the shadow implementation on the same inputs as a live
+// selection and records whether it agreed
+func (dr *DualRunner) Compare(primary string, primaryLatency time.Duration, candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) {
+	start := time.Now()
+	shadow, err := dr.shadow.Select(candidates, features, artifact)
+	shadowLatency := time.Since(start)
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if err != nil {
+		dr.stats.Errors++
+		return
+	}
+
+	dr.stats.Comparisons++
+	dr.primaryMicros += float64(primaryLatency.Microseconds())
+	dr.shadowMicros += float64(shadowLatency.Microseconds())
+
+	if shadow != primary {
+		dr.stats.Mismatches++
+		dr.stats.RecentMismatches = append(dr.stats.RecentMismatches, DualRunMismatch{
+			Timestamp:  time.Now(),
+			ClusterID:  features.ClusterID,
+			Candidates: candidates,
+			Primary:    primary,
+			Shadow:     shadow,
+		})
+		if len(dr.stats.RecentMismatches) > maxRecentMismatches {
+			dr.stats.RecentMismatches = dr.stats.RecentMismatches[1:]
+		}
+	}
+}
+
+// GetStats returns comparison results
+func (dr *DualRunner) GetStats() DualRunStats {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	stats := dr.stats
+	stats.RecentMismatches = append([]DualRunMismatch(nil), dr.stats.RecentMismatches...)
+	if stats.Comparisons > 0 {
+		n := float64(stats.Comparisons)
+		stats.MismatchRate = float64(stats.Mismatches) / n
+		stats.PrimaryAvgMicros = dr.primaryMicros / n
+		stats.ShadowAvgMicros = dr.shadowMicros / n
+	}
+	return stats
+}
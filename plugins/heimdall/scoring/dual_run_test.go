@@ -0,0 +1,76 @@
+package scoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedScorer always selects the same model
+type fixedScorer struct {
+	model string
+}
+
+func (fs *fixedScorer) Select(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	return fs.model, nil
+}
+
+func dualRunTestArtifact() *core.AvengersArtifact {
+	return &core.AvengersArtifact{
+		Alpha: 0.7,
+		Qhat: map[string][]float64{
+			"model-a": {0.9, 0.5},
+			"model-b": {0.6, 0.8},
+		},
+		Chat: map[string]float64{"model-a": 0.3, "model-b": 0.2},
+	}
+}
+
+func TestReferenceScorerAgreesWithAlphaScorer(t *testing.T) {
+	scorer := NewAlphaScorer()
+	reference := &referenceScorer{scorer: scorer}
+	artifact := dualRunTestArtifact()
+	candidates := []string{"model-a", "model-b"}
+
+	for _, cluster := range []int{0, 1} {
+		features := &core.RequestFeatures{ClusterID: cluster}
+		expected, err := scorer.SelectBest(candidates, features, artifact)
+		require.NoError(t, err)
+		actual, err := reference.Select(candidates, features, artifact)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, "cluster %d", cluster)
+	}
+}
+
+func TestDualRunner(t *testing.T) {
+	scoringImplementations["fixed-b"] = func(as *AlphaScorer) ScoringImplementation {
+		return &fixedScorer{model: "model-b"}
+	}
+	defer delete(scoringImplementations, "fixed-b")
+
+	runner, err := NewDualRunner(DualRunConfig{Implementation: "fixed-b"}, NewAlphaScorer())
+	require.NoError(t, err)
+
+	artifact := dualRunTestArtifact()
+	candidates := []string{"model-a", "model-b"}
+	runner.Compare("model-b", time.Millisecond, candidates, &core.RequestFeatures{ClusterID: 1}, artifact)
+	runner.Compare("model-a", time.Millisecond, candidates, &core.RequestFeatures{ClusterID: 0}, artifact)
+
+	stats := runner.GetStats()
+	assert.Equal(t, "fixed-b", stats.Implementation)
+	assert.Equal(t, int64(2), stats.Comparisons)
+	assert.Equal(t, int64(1), stats.Mismatches)
+	assert.Equal(t, 0.5, stats.MismatchRate)
+	assert.Equal(t, 1000.0, stats.PrimaryAvgMicros)
+	require.Len(t, stats.RecentMismatches, 1)
+	assert.Equal(t, "model-a", stats.RecentMismatches[0].Primary)
+	assert.Equal(t, "model-b", stats.RecentMismatches[0].Shadow)
+
+	t.Run("should reject unknown implementations", func(t *testing.T) {
+		_, err := NewDualRunner(DualRunConfig{Implementation: "nope"}, NewAlphaScorer())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,68 @@
+// Package scoring implements GBDT bucket triage and α-score model selection.
+package scoring
+
+import (
+	"sync"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// GBDTRuntime implements GBDT prediction (port of gbdt_runtime.ts)
+type GBDTRuntime struct {
+	mu sync.RWMutex
+}
+
+func NewGBDTRuntime() *GBDTRuntime {
+	return &GBDTRuntime{}
+}
+
+func (gbdt *GBDTRuntime) Predict(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*core.BucketProbabilities, error) {
+	gbdt.mu.RLock()
+	defer gbdt.mu.RUnlock()
+
+	// Simplified GBDT prediction - in production would load actual model
+	// For now, use heuristics based on features
+
+	cheapProb := 0.33
+	midProb := 0.33
+	hardProb := 0.34
+
+	// Adjust probabilities based on features
+	if features.HasCode {
+		// Code tasks tend to be mid-tier
+		midProb += 0.2
+		cheapProb -= 0.1
+		hardProb -= 0.1
+	}
+
+	if features.HasMath {
+		// Math tasks tend to be hard
+		hardProb += 0.2
+		cheapProb -= 0.1
+		midProb -= 0.1
+	}
+
+	if features.TokenCount > 50000 {
+		// Long context tasks tend to be hard
+		hardProb += 0.15
+		cheapProb -= 0.075
+		midProb -= 0.075
+	} else if features.TokenCount < 1000 {
+		// Short tasks can be cheap
+		cheapProb += 0.15
+		midProb -= 0.075
+		hardProb -= 0.075
+	}
+
+	// Normalize probabilities
+	total := cheapProb + midProb + hardProb
+	cheapProb /= total
+	midProb /= total
+	hardProb /= total
+
+	return &core.BucketProbabilities{
+		Cheap: cheapProb,
+		Mid:   midProb,
+		Hard:  hardProb,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+func TestGBDTRuntime(t *testing.T) {
+	gbdt := NewGBDTRuntime()
+
+	features := &core.RequestFeatures{
+		TokenCount: 1000,
+		HasCode:    true,
+		HasMath:    false,
+	}
+
+	artifact := &core.AvengersArtifact{
+		Version: "test",
+		Alpha:   0.7,
+	}
+
+	probs, err := gbdt.Predict(features, artifact)
+	if err != nil {
+		t.Fatalf("GBDT prediction failed: %v", err)
+	}
+
+	total := probs.Cheap + probs.Mid + probs.Hard
+	if total < 0.99 || total > 1.01 {
+		t.Errorf("Expected probabilities to sum to ~1.0, got %.2f", total)
+	}
+}
@@ -0,0 +1,90 @@
+package scoring
+
+import (
+	"math"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+)
+
+// Score normalization modes, set per artifact in AvengersArtifact.Normalization
+const (
+	// NormalizationNone combines quality and cost on the artifact's scales
+	NormalizationNone = ""
+	// NormalizationMinMax rescales each request's candidates to [0, 1]
+	NormalizationMinMax = "minmax"
+	// NormalizationZScore standardizes each request's candidates to zero
+	// mean and unit variance
+	NormalizationZScore = "zscore"
+)
+
+// ScoreSanityStats counts score inputs rejected because they were NaN or
+// infinite
+type ScoreSanityStats struct {
+	NonFiniteQuality int64 `json:"non_finite_quality"`
+	NonFiniteCost    int64 `json:"non_finite_cost"`
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// normalizeScores rescales quality and cost across one request's candidates
+// and recomputes their α-scores. Unknown modes leave scores unchanged.
+func normalizeScores(scores []core.ModelScore, mode string, alpha float64) {
+	if mode != NormalizationMinMax && mode != NormalizationZScore {
+		return
+	}
+
+	quality := make([]float64, len(scores))
+	cost := make([]float64, len(scores))
+	for i, score := range scores {
+		quality[i], cost[i] = score.QualityScore, score.CostScore
+	}
+	quality, cost = rescale(quality, mode), rescale(cost, mode)
+
+	for i := range scores {
+		scores[i].QualityScore = quality[i]
+		scores[i].CostScore = cost[i]
+		scores[i].AlphaScore = alpha*quality[i] - (1-alpha)*cost[i] - scores[i].PenaltyScore
+	}
+}
+
+// rescale maps values with mode; values that are all equal map to 0
+func rescale(values []float64, mode string) []float64 {
+	out := make([]float64, len(values))
+	if len(values) == 0 {
+		return out
+	}
+
+	switch mode {
+	case NormalizationMinMax:
+		lo, hi := values[0], values[0]
+		for _, v := range values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		if hi == lo {
+			return out
+		}
+		for i, v := range values {
+			out[i] = (v - lo) / (hi - lo)
+		}
+	case NormalizationZScore:
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(len(values))
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		sd := math.Sqrt(variance / float64(len(values)))
+		if sd == 0 {
+			return out
+		}
+		for i, v := range values {
+			out[i] = (v - mean) / sd
+		}
+	}
+	return out
+}
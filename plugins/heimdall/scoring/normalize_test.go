@@ -0,0 +1,90 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreNormalization(t *testing.T) {
+	// Quality on a 0-100 scale swamps costs in [0, 1] unless normalized
+	artifact := func(normalization string) *core.AvengersArtifact {
+		return &core.AvengersArtifact{
+			Alpha:         0.4,
+			Normalization: normalization,
+			Qhat: map[string][]float64{
+				"premium/model": {82},
+				"budget/model":  {80},
+			},
+			Chat: map[string]float64{"premium/model": 1.0, "budget/model": 0.1},
+		}
+	}
+	candidates := []string{"premium/model", "budget/model"}
+	features := &core.RequestFeatures{ClusterID: 0, TokenCount: 100}
+
+	t.Run("should combine raw scales by default", func(t *testing.T) {
+		model, err := NewAlphaScorer().SelectBest(candidates, features, artifact(NormalizationNone))
+		require.NoError(t, err)
+		assert.Equal(t, "premium/model", model)
+	})
+
+	for _, mode := range []string{NormalizationMinMax, NormalizationZScore} {
+		t.Run("should rescale per request with "+mode, func(t *testing.T) {
+			scorer := NewAlphaScorer()
+			model, scores, err := scorer.SelectBestWithExplanation(candidates, features, artifact(mode))
+			require.NoError(t, err)
+			assert.Equal(t, "budget/model", model, "a 2%% quality gap no longer outweighs a 10x cost gap")
+			for _, score := range scores {
+				assert.LessOrEqual(t, math.Abs(score.QualityScore), 1.0)
+			}
+
+			// The reference implementation agrees
+			reference, err := (&referenceScorer{scorer: scorer}).Select(candidates, features, artifact(mode))
+			require.NoError(t, err)
+			assert.Equal(t, model, reference)
+		})
+	}
+
+	t.Run("should map equal values to zero", func(t *testing.T) {
+		assert.Equal(t, []float64{0, 0}, rescale([]float64{3, 3}, NormalizationMinMax))
+		assert.Equal(t, []float64{0, 0}, rescale([]float64{3, 3}, NormalizationZScore))
+		assert.Equal(t, []float64{0, 0.5, 1}, rescale([]float64{2, 4, 6}, NormalizationMinMax))
+	})
+}
+
+func TestNonFiniteScores(t *testing.T) {
+	artifact := &core.AvengersArtifact{
+		Alpha: 0.7,
+		Qhat: map[string][]float64{
+			"nan/model":  {math.NaN(), 0.4, 0.6},
+			"inf/model":  {math.Inf(1), math.NaN()},
+			"good/model": {0.7, 0.7},
+		},
+		Chat: map[string]float64{"nan/model": 0.2, "inf/model": 0.1, "good/model": math.Inf(-1)},
+	}
+
+	t.Run("should average finite clusters in place of a NaN", func(t *testing.T) {
+		scorer := NewAlphaScorer()
+		quality := scorer.QualityScore("nan/model", 0, artifact)
+		require.NotNil(t, quality)
+		assert.InDelta(t, 0.5, *quality, 1e-9)
+		assert.Equal(t, int64(1), scorer.SanityStats().NonFiniteQuality)
+	})
+
+	t.Run("should reject models without finite scores", func(t *testing.T) {
+		scorer := NewAlphaScorer()
+		_, scores, err := scorer.SelectBestWithExplanation([]string{"inf/model", "good/model", "nan/model"},
+			&core.RequestFeatures{ClusterID: 0}, artifact)
+		require.NoError(t, err)
+		require.Len(t, scores, 1)
+		assert.Equal(t, "nan/model", scores[0].Model)
+		assert.False(t, math.IsNaN(scores[0].AlphaScore))
+
+		stats := scorer.SanityStats()
+		assert.Equal(t, int64(2), stats.NonFiniteQuality)
+		assert.Equal(t, int64(1), stats.NonFiniteCost)
+	})
+}
@@ -0,0 +1,92 @@
+package scoring
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QualityEstimate is the online quality of one model on one cluster
+type QualityEstimate struct {
+	Model       string    `json:"model"`
+	ClusterID   int       `json:"cluster_id"`
+	Mean        float64   `json:"mean"`
+	Samples     int       `json:"samples"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// QualityStore holds online per-model×cluster quality estimates, blended
+// with the artifact's Q̂ during α-scoring
+type QualityStore struct {
+	// priorWeight is how many observations Q̂ is worth; online estimates
+	// dominate once they have clearly more samples than this
+	priorWeight float64
+
+	estimates map[string]*QualityEstimate
+	mu        sync.RWMutex
+}
+
+// NewQualityStore creates an empty store; priorWeight <= 0 uses 20
+func NewQualityStore(priorWeight float64) *QualityStore {
+	if priorWeight <= 0 {
+		priorWeight = 20
+	}
+	return &QualityStore{
+		priorWeight: priorWeight,
+		estimates:   make(map[string]*QualityEstimate),
+	}
+}
+
+func qualityKey(model string, clusterID int) string {
+	return fmt.Sprintf("%s|%d", model, clusterID)
+}
+
+// Observe folds one quality score in [0, 1] into the running estimate
+func (qs *QualityStore) Observe(model string, clusterID int, score float64) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	key := qualityKey(model, clusterID)
+	estimate, ok := qs.estimates[key]
+	if !ok {
+		estimate = &QualityEstimate{Model: model, ClusterID: clusterID}
+		qs.estimates[key] = estimate
+	}
+	estimate.Samples++
+	estimate.Mean += (score - estimate.Mean) / float64(estimate.Samples)
+	estimate.LastUpdated = time.Now()
+}
+
+// Estimate returns the online estimate for a model and cluster
+func (qs *QualityStore) Estimate(model string, clusterID int) (QualityEstimate, bool) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	estimate, ok := qs.estimates[qualityKey(model, clusterID)]
+	if !ok {
+		return QualityEstimate{}, false
+	}
+	return *estimate, true
+}
+
+// Blend combines Q̂ with the online estimate, weighting each by its evidence
+func (qs *QualityStore) Blend(model string, clusterID int, qhat float64) float64 {
+	estimate, ok := qs.Estimate(model, clusterID)
+	if !ok {
+		return qhat
+	}
+	n := float64(estimate.Samples)
+	return (qs.priorWeight*qhat + n*estimate.Mean) / (qs.priorWeight + n)
+}
+
+// Snapshot returns all online estimates
+func (qs *QualityStore) Snapshot() []QualityEstimate {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+
+	result := make([]QualityEstimate, 0, len(qs.estimates))
+	for _, estimate := range qs.estimates {
+		result = append(result, *estimate)
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQualityStore(t *testing.T) {
+	t.Run("should keep a running mean per model and cluster", func(t *testing.T) {
+		store := NewQualityStore(10)
+		store.Observe("openai/gpt-4o", 1, 0.8)
+		store.Observe("openai/gpt-4o", 1, 0.6)
+		store.Observe("openai/gpt-4o", 2, 0.1)
+
+		estimate, ok := store.Estimate("openai/gpt-4o", 1)
+		require.True(t, ok)
+		assert.InDelta(t, 0.7, estimate.Mean, 1e-9)
+		assert.Equal(t, 2, estimate.Samples)
+
+		_, ok = store.Estimate("openai/gpt-4o", 3)
+		assert.False(t, ok)
+		assert.Len(t, store.Snapshot(), 2)
+	})
+
+	t.Run("should weight Q̂ against online evidence", func(t *testing.T) {
+		store := NewQualityStore(10)
+		assert.Equal(t, 0.9, store.Blend("openai/gpt-4o", 1, 0.9), "no evidence keeps Q̂")
+
+		for i := 0; i < 10; i++ {
+			store.Observe("openai/gpt-4o", 1, 0.5)
+		}
+		assert.InDelta(t, 0.7, store.Blend("openai/gpt-4o", 1, 0.9), 1e-9)
+	})
+
+	t.Run("should shift alpha scoring", func(t *testing.T) {
+		artifact := &core.AvengersArtifact{
+			Alpha: 1.0,
+			Qhat: map[string][]float64{
+				"model-a": {0.9},
+				"model-b": {0.8},
+			},
+			Chat: map[string]float64{"model-a": 0.1, "model-b": 0.1},
+		}
+		features := &core.RequestFeatures{ClusterID: 0}
+
+		scorer := NewAlphaScorerWithCache(0)
+		best, err := scorer.SelectBest([]string{"model-a", "model-b"}, features, artifact)
+		require.NoError(t, err)
+		assert.Equal(t, "model-a", best)
+
+		store := NewQualityStore(1)
+		for i := 0; i < 20; i++ {
+			store.Observe("model-a", 0, 0.2)
+		}
+		scorer.SetQualityStore(store)
+		best, err = scorer.SelectBest([]string{"model-a", "model-b"}, features, artifact)
+		require.NoError(t, err)
+		assert.Equal(t, "model-b", best)
+	})
+}
@@ -0,0 +1,7 @@
+;; Asks for 32MiB of memory up front
+(module
+  (memory (export "memory") 512)
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  (func (export "score") (param i32 i32) (result i64)
+    i64.const 0))
@@ -0,0 +1,8 @@
+;; Never returns
+(module
+  (memory (export "memory") 1)
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  (func (export "score") (param i32 i32) (result i64)
+    (loop (br 0))
+    i64.const 0))
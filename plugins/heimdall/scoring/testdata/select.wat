@@ -0,0 +1,8 @@
+;; Always selects qwen/qwen3-coder
+(module
+  (memory (export "memory") 1)
+  (data (i32.const 0) "{\"model\":\"qwen/qwen3-coder\"}")
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  (func (export "score") (param i32 i32) (result i64)
+    i64.const 28))
@@ -0,0 +1,255 @@
+package scoring
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	defaultWASMTimeout     = 5 * time.Millisecond
+	defaultWASMMaxMemoryMB = 16
+
+	// maxIdleWASMInstances bounds the instances kept for reuse
+	maxIdleWASMInstances = 8
+)
+
+// WASMConfig configures a custom scoring function hosted as a WASM module.
+//
+// The module must export its memory and two functions:
+//
+//	alloc(size i32) -> ptr i32
+//	score(ptr i32, len i32) -> i64
+//
+// The host allocates len bytes with alloc, writes a JSON WASMScoreInput
+// there and calls score, which returns the location of a JSON
+// WASMScoreOutput packed as ptr<<32 | len. WASI is available without
+// filesystem, network or clock access beyond what wazero provides.
+type WASMConfig struct {
+	// ModulePath is the compiled module; empty disables WASM scoring
+	ModulePath string `json:"module_path"`
+
+	// Timeout bounds each call (default 5ms); a module still running is
+	// terminated
+	Timeout time.Duration `json:"timeout"`
+
+	// MaxMemoryMB caps the module's linear memory (default 16)
+	MaxMemoryMB int `json:"max_memory_mb"`
+}
+
+// Enabled reports whether a module is configured
+func (c WASMConfig) Enabled() bool {
+	return c.ModulePath != ""
+}
+
+// WASMScoreInput is what a scoring module receives
+type WASMScoreInput struct {
+	Features        core.RequestFeatures `json:"features"`
+	Alpha           float64              `json:"alpha"`
+	ArtifactVersion string               `json:"artifact_version"`
+
+	// Candidates carry the built-in α-score breakdown; candidates the
+	// artifact has no data for only carry their model
+	Candidates []core.ModelScore `json:"candidates"`
+}
+
+// WASMScoreOutput is what a scoring module returns
+type WASMScoreOutput struct {
+	// Model is the selected candidate
+	Model string `json:"model"`
+}
+
+// WASMScorer selects models with an operator-supplied WASM module, sandboxed
+// with per-call time and memory limits
+type WASMScorer struct {
+	base      *AlphaScorer
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	timeout   time.Duration
+	instances chan api.Module
+}
+
+// NewWASMScorer loads and compiles the module at config.ModulePath. base
+// provides the candidate metadata passed to the module.
+func NewWASMScorer(config WASMConfig, base *AlphaScorer) (*WASMScorer, error) {
+	if config.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	if config.MaxMemoryMB < 0 {
+		return nil, fmt.Errorf("max_memory_mb must not be negative")
+	}
+	wasm, err := os.ReadFile(config.ModulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultWASMTimeout
+	}
+	maxMemoryMB := config.MaxMemoryMB
+	if maxMemoryMB == 0 {
+		maxMemoryMB = defaultWASMMaxMemoryMB
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(uint32(maxMemoryMB)*16)) // 64KiB pages
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile module: %w", err)
+	}
+	if len(compiled.ExportedMemories()) == 0 {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("module does not export its memory")
+	}
+	for _, export := range []string{"alloc", "score"} {
+		if _, ok := compiled.ExportedFunctions()[export]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("module does not export %q", export)
+		}
+	}
+
+	return &WASMScorer{
+		base:      base,
+		runtime:   runtime,
+		compiled:  compiled,
+		timeout:   timeout,
+		instances: make(chan api.Module, maxIdleWASMInstances),
+	}, nil
+}
+
+// SelectBest asks the module to select among candidates
+func (ws *WASMScorer) SelectBest(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no candidates provided")
+	}
+
+	input := WASMScoreInput{
+		Features:        *features,
+		Alpha:           artifact.Alpha,
+		ArtifactVersion: artifact.Version,
+		Candidates:      ws.candidateScores(candidates, features, artifact),
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode module input: %w", err)
+	}
+
+	result, err := ws.invoke(data)
+	if err != nil {
+		return "", err
+	}
+	var output WASMScoreOutput
+	if err := json.Unmarshal(result, &output); err != nil {
+		return "", fmt.Errorf("failed to decode module output: %w", err)
+	}
+	if !slices.Contains(candidates, output.Model) {
+		return "", fmt.Errorf("module selected %q, which is not a candidate", output.Model)
+	}
+	return output.Model, nil
+}
+
+// candidateScores returns the built-in score breakdown for each candidate
+func (ws *WASMScorer) candidateScores(candidates []string, features *core.RequestFeatures, artifact *core.AvengersArtifact) []core.ModelScore {
+	scored := make(map[string]core.ModelScore, len(candidates))
+	for _, score := range ws.base.scoreAll(candidates, features, artifact) {
+		scored[score.Model] = score
+	}
+
+	scores := make([]core.ModelScore, 0, len(candidates))
+	for _, model := range candidates {
+		if score, ok := scored[model]; ok {
+			scores = append(scores, score)
+		} else {
+			scores = append(scores, core.ModelScore{Model: model})
+		}
+	}
+	return scores
+}
+
+// invoke runs the module's score function on input under the call timeout
+func (ws *WASMScorer) invoke(input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ws.timeout)
+	defer cancel()
+
+	mod, err := ws.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := call(ctx, mod, input)
+	if err != nil {
+		// The instance may be mid-execution or corrupted; never reuse it
+		mod.Close(context.Background())
+		return nil, err
+	}
+
+	select {
+	case ws.instances <- mod:
+	default:
+		mod.Close(context.Background())
+	}
+	return result, nil
+}
+
+// instance returns an idle instance, or instantiates a new one
+func (ws *WASMScorer) instance(ctx context.Context) (api.Module, error) {
+	select {
+	case mod := <-ws.instances:
+		return mod, nil
+	default:
+	}
+
+	// Anonymous instances so several can run concurrently; reactor modules
+	// (TinyGo, Rust cdylib) are initialized through _initialize
+	mod, err := ws.runtime.InstantiateModule(ctx, ws.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	return mod, nil
+}
+
+// call passes input to the module's score function and reads its output
+func call(ctx context.Context, mod api.Module, input []byte) ([]byte, error) {
+	results, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("module alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("module allocated out of range memory")
+	}
+
+	results, err = mod.ExportedFunction("score").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("module score failed: %w", err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	output, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("module returned out of range output")
+	}
+	// Read aliases module memory, which the next call may overwrite
+	return slices.Clone(output), nil
+}
+
+// Close releases the module and its runtime
+func (ws *WASMScorer) Close() error {
+	return ws.runtime.Close(context.Background())
+}
@@ -0,0 +1,87 @@
+package scoring
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWASMScorer(t *testing.T, config WASMConfig) *WASMScorer {
+	scorer, err := NewWASMScorer(config, NewAlphaScorer())
+	require.NoError(t, err)
+	t.Cleanup(func() { scorer.Close() })
+	return scorer
+}
+
+func TestWASMScorer(t *testing.T) {
+	artifact := &core.AvengersArtifact{
+		Version: "wasm-test",
+		Alpha:   0.7,
+		Qhat:    map[string][]float64{"qwen/qwen3-coder": {0.8}, "openai/gpt-4o": {0.9}},
+		Chat:    map[string]float64{"qwen/qwen3-coder": 0.1, "openai/gpt-4o": 0.6},
+	}
+	features := &core.RequestFeatures{TokenCount: 12}
+
+	t.Run("should select the module's choice", func(t *testing.T) {
+		scorer := newTestWASMScorer(t, WASMConfig{ModulePath: "testdata/select.wasm"})
+
+		for i := 0; i < 3; i++ { // reuses the pooled instance
+			model, err := scorer.SelectBest([]string{"openai/gpt-4o", "qwen/qwen3-coder"}, features, artifact)
+			require.NoError(t, err)
+			assert.Equal(t, "qwen/qwen3-coder", model)
+		}
+	})
+
+	t.Run("should reject a choice that is not a candidate", func(t *testing.T) {
+		scorer := newTestWASMScorer(t, WASMConfig{ModulePath: "testdata/select.wasm"})
+
+		_, err := scorer.SelectBest([]string{"openai/gpt-4o"}, features, artifact)
+		assert.ErrorContains(t, err, "not a candidate")
+	})
+
+	t.Run("should pass features and candidate metadata", func(t *testing.T) {
+		scorer := newTestWASMScorer(t, WASMConfig{ModulePath: "testdata/echo.wasm"})
+
+		data, err := json.Marshal(WASMScoreInput{
+			Features:   *features,
+			Candidates: scorer.candidateScores([]string{"openai/gpt-4o", "unknown/model"}, features, artifact),
+		})
+		require.NoError(t, err)
+		output, err := scorer.invoke(data)
+		require.NoError(t, err)
+
+		var input WASMScoreInput
+		require.NoError(t, json.Unmarshal(output, &input))
+		assert.Equal(t, 12, input.Features.TokenCount)
+		require.Len(t, input.Candidates, 2)
+		assert.Equal(t, 0.9, input.Candidates[0].QualityScore)
+		assert.Equal(t, 0.6, input.Candidates[0].CostScore)
+		assert.Equal(t, core.ModelScore{Model: "unknown/model"}, input.Candidates[1])
+	})
+
+	t.Run("should terminate modules that exceed the timeout", func(t *testing.T) {
+		scorer := newTestWASMScorer(t, WASMConfig{ModulePath: "testdata/loop.wasm", Timeout: 10 * time.Millisecond})
+
+		start := time.Now()
+		_, err := scorer.SelectBest([]string{"openai/gpt-4o"}, features, artifact)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should reject modules over the memory limit", func(t *testing.T) {
+		_, err := NewWASMScorer(WASMConfig{ModulePath: "testdata/bigmem.wasm", MaxMemoryMB: 16}, NewAlphaScorer())
+		assert.Error(t, err)
+
+		scorer := newTestWASMScorer(t, WASMConfig{ModulePath: "testdata/bigmem.wasm", MaxMemoryMB: 64})
+		assert.NotNil(t, scorer)
+	})
+
+	t.Run("should reject missing modules", func(t *testing.T) {
+		_, err := NewWASMScorer(WASMConfig{ModulePath: "testdata/missing.wasm"}, NewAlphaScorer())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderKindSelfHosted is the provider kind used for self-hosted
+// OpenAI-compatible endpoints (vLLM, Ollama, TGI, ...)
+const ProviderKindSelfHosted = "selfhosted"
+
+// SelfHostedConfig configures self-hosted model endpoints
+type SelfHostedConfig struct {
+	// Endpoints maps a model name (as used in candidate lists) to its endpoint
+	Endpoints map[string]SelfHostedEndpoint `json:"endpoints"`
+
+	// HealthCheckInterval controls how often endpoints are probed
+	HealthCheckInterval time.Duration `json:"health_check_interval"`
+
+	// HealthCheckTimeout bounds a single health probe
+	HealthCheckTimeout time.Duration `json:"health_check_timeout"`
+}
+
+// SelfHostedEndpoint describes a single self-hosted OpenAI-compatible endpoint
+type SelfHostedEndpoint struct {
+	BaseURL    string `json:"base_url"`
+	HealthPath string `json:"health_path"`
+
+	// BifrostProvider is the Bifrost provider used to reach the endpoint
+	// (e.g. "ollama" or "openai" for vLLM). Empty keeps the "selfhosted" kind.
+	BifrostProvider string `json:"bifrost_provider,omitempty"`
+
+	// ZeroCost scores the model with zero cost regardless of the artifact
+	ZeroCost bool `json:"zero_cost"`
+
+	// MinQuality is the minimum cluster quality (Q̂) required before the
+	// endpoint is considered; local capacity is only preferred when quality suffices
+	MinQuality float64 `json:"min_quality"`
+
+	// Capacity optionally probes the endpoint's metrics for saturation
+	Capacity *CapacityProbeConfig `json:"capacity,omitempty"`
+}
+
+// CapacityProbeConfig configures GPU-capacity probing of a self-hosted
+// endpoint via its Prometheus metrics (vLLM exposes these on /metrics)
+type CapacityProbeConfig struct {
+	MetricsPath      string `json:"metrics_path"`
+	QueueDepthMetric string `json:"queue_depth_metric"`
+	RunningMetric    string `json:"running_metric"`
+
+	// MaxQueueDepth and MaxConcurrent define saturation; an endpoint at or
+	// above either limit is excluded so traffic spills to cloud providers
+	MaxQueueDepth int `json:"max_queue_depth"`
+	MaxConcurrent int `json:"max_concurrent"`
+
+	// SaturationPenalty is scaled by load (0..1) and subtracted from the α-score
+	SaturationPenalty float64 `json:"saturation_penalty"`
+}
+
+// SelfHostedStatus represents the health of a self-hosted endpoint
+type SelfHostedStatus struct {
+	Model       string    `json:"model"`
+	BaseURL     string    `json:"base_url"`
+	Healthy     bool      `json:"healthy"`
+	LastChecked time.Time `json:"last_checked"`
+	LastError   string    `json:"last_error,omitempty"`
+
+	// Capacity probe results (only populated when probing is configured)
+	QueueDepth int     `json:"queue_depth,omitempty"`
+	Running    int     `json:"running,omitempty"`
+	Load       float64 `json:"load,omitempty"`
+	Saturated  bool    `json:"saturated,omitempty"`
+}
+
+// SelfHostedRegistry tracks self-hosted endpoints and their health
+type SelfHostedRegistry struct {
+	config     SelfHostedConfig
+	httpClient *http.Client
+
+	status map[string]*SelfHostedStatus
+	mu     sync.RWMutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSelfHostedRegistry creates a registry for the configured endpoints.
+// Endpoints are assumed healthy until the first probe says otherwise.
+func NewSelfHostedRegistry(config SelfHostedConfig) *SelfHostedRegistry {
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = 30 * time.Second
+	}
+	if config.HealthCheckTimeout == 0 {
+		config.HealthCheckTimeout = 2 * time.Second
+	}
+
+	registry := &SelfHostedRegistry{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: config.HealthCheckTimeout,
+		},
+		status: make(map[string]*SelfHostedStatus),
+		stopCh: make(chan struct{}),
+	}
+
+	for model, endpoint := range config.Endpoints {
+		registry.status[model] = &SelfHostedStatus{
+			Model:   model,
+			BaseURL: endpoint.BaseURL,
+			Healthy: true,
+		}
+	}
+
+	return registry
+}
+
+// IsSelfHosted reports whether the model is served by a self-hosted endpoint
+func (r *SelfHostedRegistry) IsSelfHosted(model string) bool {
+	_, ok := r.config.Endpoints[model]
+	return ok
+}
+
+// Endpoint returns the endpoint configuration for a model
+func (r *SelfHostedRegistry) Endpoint(model string) (SelfHostedEndpoint, bool) {
+	endpoint, ok := r.config.Endpoints[model]
+	return endpoint, ok
+}
+
+// IsHealthy reports whether the model's endpoint passed its last health check.
+// Models that are not self-hosted are always considered healthy.
+func (r *SelfHostedRegistry) IsHealthy(model string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status, ok := r.status[model]
+	if !ok {
+		return true
+	}
+	return status.Healthy
+}
+
+// ZeroCostModels returns the self-hosted models configured with zero cost
+func (r *SelfHostedRegistry) ZeroCostModels() []string {
+	var models []string
+	for model, endpoint := range r.config.Endpoints {
+		if endpoint.ZeroCost {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// Start begins periodic health checking in the background
+func (r *SelfHostedRegistry) Start() {
+	if len(r.config.Endpoints) == 0 {
+		return
+	}
+
+	go func() {
+		r.CheckAll(context.Background())
+
+		ticker := time.NewTicker(r.config.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.CheckAll(context.Background())
+			case <-r.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts background health checking
+func (r *SelfHostedRegistry) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// CheckAll probes every configured endpoint once
+func (r *SelfHostedRegistry) CheckAll(ctx context.Context) {
+	for model, endpoint := range r.config.Endpoints {
+		err := r.probe(ctx, endpoint)
+
+		var queueDepth, running int
+		var capacityErr error
+		if err == nil && endpoint.Capacity != nil {
+			queueDepth, running, capacityErr = r.probeCapacity(ctx, endpoint)
+			if capacityErr != nil {
+				log.Printf("Capacity probe for %s failed: %v", model, capacityErr)
+			}
+		}
+
+		r.mu.Lock()
+		status := r.status[model]
+		wasHealthy := status.Healthy
+		status.Healthy = err == nil
+		status.LastChecked = time.Now()
+		status.LastError = ""
+		if err != nil {
+			status.LastError = err.Error()
+		}
+		if endpoint.Capacity != nil && err == nil && capacityErr == nil {
+			status.QueueDepth = queueDepth
+			status.Running = running
+			status.Load, status.Saturated = endpoint.Capacity.load(queueDepth, running)
+		}
+		r.mu.Unlock()
+
+		if wasHealthy && err != nil {
+			log.Printf("Self-hosted endpoint for %s became unhealthy: %v", model, err)
+		} else if !wasHealthy && err == nil {
+			log.Printf("Self-hosted endpoint for %s recovered", model)
+		}
+	}
+}
+
+// probe performs a single health check against an endpoint
+func (r *SelfHostedRegistry) probe(ctx context.Context, endpoint SelfHostedEndpoint) error {
+	healthPath := endpoint.HealthPath
+	if healthPath == "" {
+		healthPath = "/health"
+	}
+	url := strings.TrimSuffix(endpoint.BaseURL, "/") + healthPath
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return nil
+}
+
+// probeCapacity reads queue depth and running request counts from the
+// endpoint's Prometheus metrics
+func (r *SelfHostedRegistry) probeCapacity(ctx context.Context, endpoint SelfHostedEndpoint) (int, int, error) {
+	probe := endpoint.Capacity
+	metricsPath := probe.MetricsPath
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+	url := strings.TrimSuffix(endpoint.BaseURL, "/") + metricsPath
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, 0, fmt.Errorf("metrics endpoint returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	queueMetric := probe.QueueDepthMetric
+	if queueMetric == "" {
+		queueMetric = "vllm:num_requests_waiting"
+	}
+	runningMetric := probe.RunningMetric
+	if runningMetric == "" {
+		runningMetric = "vllm:num_requests_running"
+	}
+
+	metrics := string(body)
+	return int(sumPrometheusMetric(metrics, queueMetric)), int(sumPrometheusMetric(metrics, runningMetric)), nil
+}
+
+// sumPrometheusMetric sums every sample of a metric in Prometheus text format
+// (label sets such as per-model series are added together)
+func sumPrometheusMetric(metrics string, name string) float64 {
+	total := 0.0
+	for _, line := range strings.Split(metrics, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || !strings.HasPrefix(line, name) {
+			continue
+		}
+
+		rest := line[len(name):]
+		if strings.HasPrefix(rest, "{") {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		} else if !strings.HasPrefix(rest, " ") {
+			continue // different metric sharing the prefix
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			total += value
+		}
+	}
+	return total
+}
+
+// load computes endpoint utilization (0..1) and whether it is saturated
+func (c *CapacityProbeConfig) load(queueDepth, running int) (float64, bool) {
+	load := 0.0
+	saturated := false
+
+	if c.MaxQueueDepth > 0 {
+		load = math.Max(load, float64(queueDepth)/float64(c.MaxQueueDepth))
+		saturated = saturated || queueDepth >= c.MaxQueueDepth
+	}
+	if c.MaxConcurrent > 0 {
+		load = math.Max(load, float64(running)/float64(c.MaxConcurrent))
+		saturated = saturated || running >= c.MaxConcurrent
+	}
+
+	return math.Min(load, 1.0), saturated
+}
+
+// IsSaturated reports whether the model's endpoint is at capacity
+func (r *SelfHostedRegistry) IsSaturated(model string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status, ok := r.status[model]
+	return ok && status.Saturated
+}
+
+// SaturationPenalty is a PenaltyHook that penalizes loaded self-hosted endpoints
+func (r *SelfHostedRegistry) SaturationPenalty(model string, features *RequestFeatures) float64 {
+	endpoint, ok := r.config.Endpoints[model]
+	if !ok || endpoint.Capacity == nil || endpoint.Capacity.SaturationPenalty == 0 {
+		return 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return endpoint.Capacity.SaturationPenalty * r.status[model].Load
+}
+
+// GetStatus returns the health status of all self-hosted endpoints
+func (r *SelfHostedRegistry) GetStatus() []SelfHostedStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]SelfHostedStatus, 0, len(r.status))
+	for _, status := range r.status {
+		statuses = append(statuses, *status)
+	}
+	return statuses
+}
+
+// filterSelfHostedCandidates drops self-hosted candidates that are unhealthy,
+// saturated, or whose predicted quality for the request's cluster is below their minimum
+func (p *Plugin) filterSelfHostedCandidates(candidates []string, features *RequestFeatures) []string {
+	if p.selfHosted == nil || len(p.config.SelfHosted.Endpoints) == 0 {
+		return candidates
+	}
+
+	filtered := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		endpoint, ok := p.selfHosted.Endpoint(c)
+		if !ok {
+			filtered = append(filtered, c)
+			continue
+		}
+
+		if !p.selfHosted.IsHealthy(c) || p.selfHosted.IsSaturated(c) {
+			continue
+		}
+
+		if endpoint.MinQuality > 0 {
+			quality := p.alphaScorer.QualityScore(c, features.ClusterID, p.currentArtifact)
+			if quality == nil || *quality < endpoint.MinQuality {
+				continue
+			}
+		}
+
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
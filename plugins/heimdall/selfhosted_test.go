@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfHostedRegistry(t *testing.T) {
+	t.Run("should mark endpoints healthy or unhealthy from probes", func(t *testing.T) {
+		healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/health", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer healthy.Close()
+
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer broken.Close()
+
+		registry := NewSelfHostedRegistry(SelfHostedConfig{
+			Endpoints: map[string]SelfHostedEndpoint{
+				"local/llama-3-70b": {BaseURL: healthy.URL},
+				"local/qwen-72b":    {BaseURL: broken.URL},
+			},
+		})
+
+		// Endpoints start healthy until probed
+		assert.True(t, registry.IsHealthy("local/qwen-72b"))
+
+		registry.CheckAll(context.Background())
+
+		assert.True(t, registry.IsHealthy("local/llama-3-70b"))
+		assert.False(t, registry.IsHealthy("local/qwen-72b"))
+		assert.True(t, registry.IsHealthy("openai/gpt-4o"), "non self-hosted models are always healthy")
+		assert.Len(t, registry.GetStatus(), 2)
+	})
+
+	t.Run("should use custom health path", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/tags" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		registry := NewSelfHostedRegistry(SelfHostedConfig{
+			Endpoints: map[string]SelfHostedEndpoint{
+				"ollama/llama3": {BaseURL: server.URL + "/", HealthPath: "/api/tags"},
+			},
+		})
+		registry.CheckAll(context.Background())
+
+		assert.True(t, registry.IsHealthy("ollama/llama3"))
+	})
+}
+
+func TestSelfHostedRouting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	newPlugin := func(t *testing.T, endpoint SelfHostedEndpoint) *Plugin {
+		endpoint.BaseURL = server.URL
+		config := createRouterTestConfig()
+		config.Router.CheapCandidates = append(config.Router.CheapCandidates, "local/llama-3-70b")
+		config.SelfHosted = SelfHostedConfig{
+			Endpoints: map[string]SelfHostedEndpoint{
+				"local/llama-3-70b": endpoint,
+			},
+		}
+
+		plugin := createRouterTestPluginWithConfig(t, config)
+		plugin.currentArtifact.Qhat["local/llama-3-70b"] = []float64{0.8, 0.8, 0.8}
+		return plugin
+	}
+
+	features := &RequestFeatures{ClusterID: 0, TokenCount: 500}
+
+	t.Run("should infer selfhosted provider kind", func(t *testing.T) {
+		plugin := newPlugin(t, SelfHostedEndpoint{})
+		defer plugin.Cleanup()
+
+		assert.Equal(t, ProviderKindSelfHosted, plugin.inferProviderKind("local/llama-3-70b"))
+		assert.Equal(t, "openai", plugin.inferProviderKind("openai/gpt-4o"))
+	})
+
+	t.Run("should prefer zero-cost local capacity when quality suffices", func(t *testing.T) {
+		plugin := newPlugin(t, SelfHostedEndpoint{ZeroCost: true, MinQuality: 0.6})
+		defer plugin.Cleanup()
+
+		decision, err := plugin.selectModelForBucket("cheap", features)
+		require.NoError(t, err)
+		assert.Equal(t, "local/llama-3-70b", decision.Model)
+		assert.Equal(t, ProviderKindSelfHosted, decision.Kind)
+	})
+
+	t.Run("should skip local capacity below minimum quality", func(t *testing.T) {
+		plugin := newPlugin(t, SelfHostedEndpoint{ZeroCost: true, MinQuality: 0.9})
+		defer plugin.Cleanup()
+
+		decision, err := plugin.selectModelForBucket("cheap", features)
+		require.NoError(t, err)
+		assert.NotEqual(t, "local/llama-3-70b", decision.Model)
+		assert.NotContains(t, decision.Fallbacks, "local/llama-3-70b")
+	})
+
+	t.Run("should map selfhosted kind to configured Bifrost provider", func(t *testing.T) {
+		plugin := newPlugin(t, SelfHostedEndpoint{BifrostProvider: "ollama"})
+		defer plugin.Cleanup()
+
+		assert.Equal(t, schemas.Ollama, plugin.bifrostProvider(ProviderKindSelfHosted, "local/llama-3-70b"))
+		assert.Equal(t, schemas.OpenAI, plugin.bifrostProvider("openai", "openai/gpt-4o"))
+	})
+
+	t.Run("should report endpoints in health output", func(t *testing.T) {
+		plugin := newPlugin(t, SelfHostedEndpoint{})
+		defer plugin.Cleanup()
+
+		health := plugin.GetHealth()
+		assert.Equal(t, "healthy", health.Status)
+		require.Len(t, health.SelfHosted, 1)
+		assert.Equal(t, "local/llama-3-70b", health.SelfHosted[0].Model)
+	})
+}
+
+func TestSelfHostedCapacity(t *testing.T) {
+	metrics := `# HELP vllm:num_requests_waiting Number of requests waiting
+# TYPE vllm:num_requests_waiting gauge
+vllm:num_requests_waiting{model_name="llama"} 6
+vllm:num_requests_waiting{model_name="qwen"} 2
+vllm:num_requests_waiting_total 99
+vllm:num_requests_running{model_name="llama"} 3
+`
+
+	t.Run("should sum metric samples across label sets", func(t *testing.T) {
+		assert.Equal(t, 8.0, sumPrometheusMetric(metrics, "vllm:num_requests_waiting"))
+		assert.Equal(t, 3.0, sumPrometheusMetric(metrics, "vllm:num_requests_running"))
+		assert.Equal(t, 0.0, sumPrometheusMetric(metrics, "missing_metric"))
+	})
+
+	newServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/metrics" {
+				w.Write([]byte(metrics))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	t.Run("should exclude saturated endpoints", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		registry := NewSelfHostedRegistry(SelfHostedConfig{
+			Endpoints: map[string]SelfHostedEndpoint{
+				"local/llama-3-70b": {
+					BaseURL:  server.URL,
+					Capacity: &CapacityProbeConfig{MaxQueueDepth: 8},
+				},
+			},
+		})
+		registry.CheckAll(context.Background())
+
+		assert.True(t, registry.IsHealthy("local/llama-3-70b"))
+		assert.True(t, registry.IsSaturated("local/llama-3-70b"))
+
+		status := registry.GetStatus()[0]
+		assert.Equal(t, 8, status.QueueDepth)
+		assert.Equal(t, 3, status.Running)
+	})
+
+	t.Run("should penalize endpoints proportionally to load", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		registry := NewSelfHostedRegistry(SelfHostedConfig{
+			Endpoints: map[string]SelfHostedEndpoint{
+				"local/llama-3-70b": {
+					BaseURL: server.URL,
+					Capacity: &CapacityProbeConfig{
+						MaxQueueDepth:     16,
+						MaxConcurrent:     4,
+						SaturationPenalty: 0.4,
+					},
+				},
+			},
+		})
+		registry.CheckAll(context.Background())
+
+		assert.False(t, registry.IsSaturated("local/llama-3-70b"))
+		// Load is max(8/16, 3/4) = 0.75
+		assert.InDelta(t, 0.3, registry.SaturationPenalty("local/llama-3-70b", nil), 1e-9)
+		assert.Equal(t, 0.0, registry.SaturationPenalty("openai/gpt-4o", nil))
+	})
+
+	t.Run("should apply penalty hooks on top of cached scores", func(t *testing.T) {
+		scorer := scoring.NewAlphaScorer()
+		artifact := &AvengersArtifact{
+			Alpha: 0.7,
+			Qhat:  map[string][]float64{"local/llama-3-70b": {0.8}},
+			Chat:  map[string]float64{"local/llama-3-70b": 0.0},
+		}
+		features := &RequestFeatures{ClusterID: 0, TokenCount: 100}
+
+		_, first, err := scorer.SelectBestWithExplanation([]string{"local/llama-3-70b"}, features, artifact)
+		require.NoError(t, err)
+
+		scorer.AddPenaltyHook(func(model string, features *RequestFeatures) float64 { return 0.25 })
+		_, second, err := scorer.SelectBestWithExplanation([]string{"local/llama-3-70b"}, features, artifact)
+		require.NoError(t, err)
+
+		assert.InDelta(t, first[0].AlphaScore-0.25, second[0].AlphaScore, 1e-9)
+		assert.InDelta(t, first[0].PenaltyScore+0.25, second[0].PenaltyScore, 1e-9)
+	})
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+)
+
+// SessionCostRule applies a routing policy once a session's spend reaches MinSpend
+type SessionCostRule struct {
+	MinSpend float64       `json:"min_spend"` // USD
+	Policy   RoutingPolicy `json:"policy"`
+}
+
+// SessionConfig configures per-session cost accumulation
+type SessionConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Header carrying the conversation/session identifier
+	Header string `json:"header"`
+	// IdleTTL expires sessions with no activity
+	IdleTTL time.Duration `json:"idle_ttl"`
+
+	// Pricing is per-model USD pricing used to account usage
+	Pricing map[string]catalog.ModelPricing `json:"pricing"`
+
+	// Rules are matched by the highest MinSpend the session has reached,
+	// e.g. {min_spend: 0.5, policy: {max_bucket: "cheap"}}
+	Rules []SessionCostRule `json:"rules"`
+
+	// Affinity keeps a session on the model its earlier turns were routed
+	// to, while that model remains a candidate of the turn's bucket
+	Affinity bool `json:"affinity"`
+	// RevalidateAfter is how long a decision stands within a streamed
+	// response (default 2m). Past it, a stream whose model is quarantined or
+	// drained, or that stalls for StallTimeout (default 30s) between chunks,
+	// marks the session to be routed afresh at its next turn.
+	RevalidateAfter time.Duration `json:"revalidate_after"`
+	StallTimeout    time.Duration `json:"stall_timeout"`
+}
+
+// sessionState is the accumulated usage of one session
+type sessionState struct {
+	owner    DataSubject
+	spend    float64
+	requests int
+	lastSeen time.Time
+	// model is the model the session sticks to under affinity; revalidate
+	// releases it at the next turn
+	model      string
+	revalidate bool
+}
+
+// SessionStatus reports a session's accumulated usage
+type SessionStatus struct {
+	SessionID string  `json:"session_id"`
+	Spend     float64 `json:"spend"`
+	Requests  int     `json:"requests"`
+	// Model is the model the session sticks to, if any
+	Model      string `json:"model,omitempty"`
+	Revalidate bool   `json:"revalidate,omitempty"`
+}
+
+// SessionStats counts live sessions and affinity revalidations
+type SessionStats struct {
+	Sessions int `json:"sessions"`
+	Pinned   int `json:"pinned"`
+	// Revalidations counts sessions released from a degraded model
+	Revalidations int64 `json:"revalidations"`
+}
+
+// SessionTracker accumulates spend per conversation/session
+type SessionTracker struct {
+	config        SessionConfig
+	sessions      map[string]*sessionState
+	revalidations int64
+	mu            sync.RWMutex
+}
+
+// NewSessionTracker creates a tracker, validating its rules
+func NewSessionTracker(config SessionConfig) (*SessionTracker, error) {
+	if config.Header == "" {
+		config.Header = "X-Session-ID"
+	}
+	if config.IdleTTL == 0 {
+		config.IdleTTL = time.Hour
+	}
+	if config.RevalidateAfter == 0 {
+		config.RevalidateAfter = 2 * time.Minute
+	}
+	if config.StallTimeout == 0 {
+		config.StallTimeout = 30 * time.Second
+	}
+	if config.RevalidateAfter < 0 || config.StallTimeout < 0 {
+		return nil, fmt.Errorf("revalidate_after and stall_timeout must be positive")
+	}
+
+	rules := append([]SessionCostRule(nil), config.Rules...)
+	for _, rule := range rules {
+		if err := rule.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("session rule at %.2f: %w", rule.MinSpend, err)
+		}
+	}
+	// Highest threshold first so the first match is the strictest reached rule
+	sort.Slice(rules, func(i, j int) bool { return rules[i].MinSpend > rules[j].MinSpend })
+	config.Rules = rules
+
+	return &SessionTracker{
+		config:   config,
+		sessions: make(map[string]*sessionState),
+	}, nil
+}
+
+// SessionID returns the request's session identifier, if any
+func (st *SessionTracker) SessionID(headers map[string][]string) string {
+	return auth.HeaderValue(headers, st.config.Header)
+}
+
+// Spend returns a session's accumulated spend in USD
+func (st *SessionTracker) Spend(sessionID string) float64 {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	state, ok := st.sessions[sessionID]
+	if !ok || time.Since(state.lastSeen) > st.config.IdleTTL {
+		return 0
+	}
+	return state.spend
+}
+
+// ruleIndex returns the index of the strictest rule the session has reached,
+// or -1 when none applies
+func (st *SessionTracker) ruleIndex(sessionID string) int {
+	if sessionID == "" {
+		return -1
+	}
+	spend := st.Spend(sessionID)
+	for i, rule := range st.config.Rules {
+		if spend >= rule.MinSpend {
+			return i
+		}
+	}
+	return -1
+}
+
+// PolicyFor returns the routing policy for a session's current spend
+func (st *SessionTracker) PolicyFor(sessionID string) *RoutingPolicy {
+	i := st.ruleIndex(sessionID)
+	if i < 0 {
+		return nil
+	}
+	return &st.config.Rules[i].Policy
+}
+
+// Pinned returns the model a session sticks to, or "" when affinity is off
+// or the session is new, idle or marked for revalidation
+func (st *SessionTracker) Pinned(sessionID string) string {
+	if !st.config.Affinity || sessionID == "" {
+		return ""
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	state, ok := st.sessions[sessionID]
+	if !ok || state.revalidate || time.Since(state.lastSeen) > st.config.IdleTTL {
+		return ""
+	}
+	return state.model
+}
+
+// Pin sticks a session to the model its turn was routed to, clearing any
+// revalidation mark
+func (st *SessionTracker) Pin(sessionID string, owner DataSubject, model string) {
+	if !st.config.Affinity || sessionID == "" {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	state := st.live(sessionID, owner, time.Now())
+	state.model = model
+	state.revalidate = false
+}
+
+// Revalidate marks a session stuck to model to be routed afresh at its
+// next turn. It reports whether the mark was new.
+func (st *SessionTracker) Revalidate(sessionID, model string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	state, ok := st.sessions[sessionID]
+	if !ok || state.model != model || state.revalidate {
+		return false
+	}
+	state.revalidate = true
+	st.revalidations++
+	return true
+}
+
+// Record accounts a response's usage against a session opened by owner
+func (st *SessionTracker) Record(sessionID string, owner DataSubject, model string, usage *schemas.LLMUsage) {
+	if sessionID == "" || usage == nil {
+		return
+	}
+
+	cost := usageCost(st.config.Pricing[model], usage)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	state := st.live(sessionID, owner, time.Now())
+	state.spend += cost
+	state.requests++
+}
+
+// live returns a session's state, starting it afresh when new or idle, and
+// marks it seen (no lock - called from locked context)
+func (st *SessionTracker) live(sessionID string, owner DataSubject, now time.Time) *sessionState {
+	state, ok := st.sessions[sessionID]
+	if !ok || now.Sub(state.lastSeen) > st.config.IdleTTL {
+		state = &sessionState{owner: owner}
+		st.sessions[sessionID] = state
+	}
+	state.lastSeen = now
+
+	if len(st.sessions) >= 10000 {
+		st.evictIdle(now)
+	}
+	return state
+}
+
+// Inherit copies a replaced tracker's live sessions, so spend and affinity
+// survive a config reload
+func (st *SessionTracker) Inherit(old *SessionTracker) {
+	old.mu.RLock()
+	sessions := make(map[string]sessionState, len(old.sessions))
+	for id, state := range old.sessions {
+		sessions[id] = *state
+	}
+	revalidations := old.revalidations
+	old.mu.RUnlock()
+
+	now := time.Now()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for id, state := range sessions {
+		if now.Sub(state.lastSeen) > st.config.IdleTTL {
+			continue
+		}
+		if _, ok := st.sessions[id]; !ok {
+			state := state
+			st.sessions[id] = &state
+		}
+	}
+	st.revalidations += revalidations
+}
+
+// GetStatus returns a session's accumulated usage
+func (st *SessionTracker) GetStatus(sessionID string) SessionStatus {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	status := SessionStatus{SessionID: sessionID}
+	if state, ok := st.sessions[sessionID]; ok && time.Since(state.lastSeen) <= st.config.IdleTTL {
+		status.Spend = state.spend
+		status.Requests = state.requests
+		status.Model = state.model
+		status.Revalidate = state.revalidate
+	}
+	return status
+}
+
+// GetStats counts live and pinned sessions
+func (st *SessionTracker) GetStats() SessionStats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	stats := SessionStats{Revalidations: st.revalidations}
+	for _, state := range st.sessions {
+		if time.Since(state.lastSeen) <= st.config.IdleTTL {
+			stats.Sessions++
+			if state.model != "" && !state.revalidate {
+				stats.Pinned++
+			}
+		}
+	}
+	return stats
+}
+
+// SubjectSessions returns the live sessions opened by an identity
+func (st *SessionTracker) SubjectSessions(subject DataSubject) []SessionStatus {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var sessions []SessionStatus
+	for id, state := range st.sessions {
+		if subject.covers(state.owner) && time.Since(state.lastSeen) <= st.config.IdleTTL {
+			sessions = append(sessions, SessionStatus{SessionID: id, Spend: state.spend, Requests: state.requests, Model: state.model, Revalidate: state.revalidate})
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	return sessions
+}
+
+// DeleteSubject drops every session opened by an identity, returning how
+// many were dropped
+func (st *SessionTracker) DeleteSubject(subject DataSubject) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	deleted := 0
+	for id, state := range st.sessions {
+		if subject.covers(state.owner) {
+			delete(st.sessions, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// evictIdle drops expired sessions (no lock - called from locked context)
+func (st *SessionTracker) evictIdle(now time.Time) {
+	for id, state := range st.sessions {
+		if now.Sub(state.lastSeen) > st.config.IdleTTL {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+// stickToSession keeps a session on its pinned model when the model is
+// among the decision's fallbacks from the same bucket, i.e. available and
+// permitted. The selected model then leads the fallbacks.
+func (p *Plugin) stickToSession(decision *RouterDecision, pinned string, bucket Bucket, features *RequestFeatures, authInfo *AuthInfo, policy *RoutingPolicy) *RouterDecision {
+	if pinned == "" || pinned == decision.Model || !slices.Contains(decision.Fallbacks, pinned) ||
+		(decision.Escalation != nil && decision.Escalation.Model == pinned) {
+		return decision
+	}
+	sticky, err := p.selectModelWithPolicy(bucket, features, authInfo, policy.Merge(&RoutingPolicy{Candidates: []string{pinned}}), false, nil)
+	if err != nil || sticky.Model != pinned {
+		return decision
+	}
+
+	fallbacks := []string{decision.Model}
+	for _, fallback := range decision.Fallbacks {
+		if fallback != pinned {
+			fallbacks = append(fallbacks, fallback)
+		}
+	}
+	sticky.Fallbacks = fallbacks
+	sticky.Escalation = decision.Escalation
+	return sticky
+}
+
+// revalidateSession marks the request's session, if stuck to model, to be
+// routed afresh at its next turn
+func (p *Plugin) revalidateSession(ctx context.Context, model, reason string) {
+	sessionID, ok := ctx.Value("heimdall_session_id").(string)
+	if !ok || p.sessions == nil {
+		return
+	}
+	if p.sessions.Revalidate(sessionID, model) {
+		p.logger.Printf("Session %s released from %s: %s", sessionID, model, reason)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSessionConfig() SessionConfig {
+	return SessionConfig{
+		Enabled: true,
+		Pricing: map[string]catalog.ModelPricing{
+			"openai/o1": {InPerMillion: 15, OutPerMillion: 60},
+		},
+		Rules: []SessionCostRule{
+			{MinSpend: 0.10, Policy: RoutingPolicy{MaxBucket: BucketMid}},
+			{MinSpend: 0.50, Policy: RoutingPolicy{MaxBucket: BucketCheap}},
+		},
+	}
+}
+
+func TestSessionTracker(t *testing.T) {
+	t.Run("should accumulate priced usage per session", func(t *testing.T) {
+		st, err := NewSessionTracker(testSessionConfig())
+		require.NoError(t, err)
+
+		usage := &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000}
+		st.Record("conv-1", DataSubject{}, "openai/o1", usage)
+		st.Record("conv-1", DataSubject{}, "openai/o1", usage)
+		st.Record("conv-2", DataSubject{}, "unpriced/model", usage)
+
+		assert.InDelta(t, 0.15, st.Spend("conv-1"), 1e-9)
+		assert.Equal(t, 2, st.GetStatus("conv-1").Requests)
+		assert.Zero(t, st.Spend("conv-2"))
+		assert.Zero(t, st.Spend("unknown"))
+	})
+
+	t.Run("should apply the strictest rule reached", func(t *testing.T) {
+		st, err := NewSessionTracker(testSessionConfig())
+		require.NoError(t, err)
+
+		assert.Nil(t, st.PolicyFor("conv-1"))
+
+		st.Record("conv-1", DataSubject{}, "openai/o1", &schemas.LLMUsage{PromptTokens: 10000})
+		assert.Equal(t, BucketMid, st.PolicyFor("conv-1").MaxBucket)
+
+		st.Record("conv-1", DataSubject{}, "openai/o1", &schemas.LLMUsage{CompletionTokens: 10000})
+		assert.Equal(t, BucketCheap, st.PolicyFor("conv-1").MaxBucket)
+
+		assert.Nil(t, st.PolicyFor(""))
+	})
+
+	t.Run("should reset idle sessions", func(t *testing.T) {
+		config := testSessionConfig()
+		config.IdleTTL = time.Millisecond
+		st, err := NewSessionTracker(config)
+		require.NoError(t, err)
+
+		st.Record("conv-1", DataSubject{}, "openai/o1", &schemas.LLMUsage{CompletionTokens: 100000})
+		time.Sleep(5 * time.Millisecond)
+		assert.Zero(t, st.Spend("conv-1"))
+	})
+
+	t.Run("should pin sessions until revalidated", func(t *testing.T) {
+		config := testSessionConfig()
+		config.Affinity = true
+		st, err := NewSessionTracker(config)
+		require.NoError(t, err)
+
+		st.Pin("conv-1", DataSubject{Tenant: "acme"}, "openai/gpt-4o")
+		assert.Equal(t, "openai/gpt-4o", st.Pinned("conv-1"))
+		assert.False(t, st.Revalidate("conv-1", "openai/o1"), "not the pinned model")
+		assert.True(t, st.Revalidate("conv-1", "openai/gpt-4o"))
+		assert.False(t, st.Revalidate("conv-1", "openai/gpt-4o"), "already marked")
+		assert.Empty(t, st.Pinned("conv-1"))
+		assert.True(t, st.GetStatus("conv-1").Revalidate)
+
+		st.Pin("conv-1", DataSubject{Tenant: "acme"}, "openai/gpt-4o-mini")
+		assert.Equal(t, "openai/gpt-4o-mini", st.Pinned("conv-1"))
+		assert.Equal(t, SessionStats{Sessions: 1, Pinned: 1, Revalidations: 1}, st.GetStats())
+	})
+
+	t.Run("should not pin without affinity", func(t *testing.T) {
+		st, err := NewSessionTracker(testSessionConfig())
+		require.NoError(t, err)
+		st.Pin("conv-1", DataSubject{Tenant: "acme"}, "openai/gpt-4o")
+		assert.Empty(t, st.Pinned("conv-1"))
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		_, err := NewSessionTracker(SessionConfig{Rules: []SessionCostRule{{Policy: RoutingPolicy{MaxBucket: "huge"}}}})
+		assert.Error(t, err)
+	})
+}
+
+func TestSessionCostRouting(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Sessions = testSessionConfig()
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	headers := map[string][]string{"X-Session-ID": {"conv-1"}}
+	req := &RouterRequest{
+		URL:     "/v1/chat/completions",
+		Method:  "POST",
+		Headers: headers,
+		Body: &RequestBody{
+			Messages: []ChatMessage{{Role: "user", Content: "Prove the Riemann hypothesis step by step with full rigor."}},
+		},
+	}
+
+	response, err := plugin.decide(req, headers)
+	require.NoError(t, err)
+	assert.Equal(t, "conv-1", response.SessionID)
+
+	// Account an expensive response through the plugin hooks
+	ctx := context.Background()
+	response.Decision = RouterDecision{Kind: "openai", Model: "openai/o1"}
+	_, _, err = plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+	require.NoError(t, err)
+	_, _, err = plugin.PostHook(&ctx, &schemas.BifrostResponse{
+		Usage: &schemas.LLMUsage{PromptTokens: 10000, CompletionTokens: 10000},
+	}, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, plugin.sessions.Spend("conv-1"), 1e-9)
+
+	response, err = plugin.decide(req, headers)
+	require.NoError(t, err)
+	assert.Equal(t, BucketCheap, response.Bucket)
+	assert.Contains(t, plugin.config.Router.CheapCandidates, response.Decision.Model)
+
+	t.Run("should never loosen the caller's policy", func(t *testing.T) {
+		config := config
+		config.Anonymous = AnonymousPolicyConfig{Mode: AnonymousModeRestricted, Policy: &RoutingPolicy{MaxBucket: BucketCheap}}
+		restricted := createRouterTestPluginWithConfig(t, config)
+		restricted.sessions.Record("conv-1", DataSubject{}, "openai/o1", &schemas.LLMUsage{CompletionTokens: 2000})
+		require.Equal(t, BucketMid, restricted.sessions.PolicyFor("conv-1").MaxBucket)
+
+		response, err := restricted.decide(req, headers)
+		require.NoError(t, err)
+		assert.Equal(t, BucketCheap, response.Bucket, "the session rule's mid cap does not lift the caller's cheap cap")
+	})
+
+	t.Run("should not reuse decisions cached before a rule was reached", func(t *testing.T) {
+		fresh := createRouterTestPluginWithConfig(t, config)
+		before := fresh.getCacheKey(req)
+		fresh.sessions.Record("conv-1", DataSubject{}, "openai/o1", &schemas.LLMUsage{CompletionTokens: 10000})
+		assert.NotEqual(t, before, fresh.getCacheKey(req))
+	})
+}
+
+func TestSessionAffinity(t *testing.T) {
+	now := time.Now()
+	config := createRouterTestConfig()
+	config.Sessions = SessionConfig{Enabled: true, Affinity: true}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	plugin.now = func() time.Time { return now }
+
+	headers := map[string][]string{"X-Session-ID": {"conv-1"}}
+	req := &RouterRequest{
+		URL:     "/v1/chat/completions",
+		Method:  "POST",
+		Headers: headers,
+		Body:    &RequestBody{Messages: []ChatMessage{{Role: "user", Content: "What is 2+2?"}}},
+	}
+
+	first, err := plugin.decide(req, headers)
+	require.NoError(t, err)
+	require.NotEmpty(t, first.Decision.Fallbacks)
+	assert.Equal(t, first.Decision.Model, plugin.sessions.Pinned("conv-1"))
+
+	// An earlier turn stuck the session to another candidate of the bucket
+	pinned := first.Decision.Fallbacks[0]
+	plugin.sessions.Pin("conv-1", DataSubject{}, pinned)
+
+	t.Run("should stick to the session's model", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		assert.Equal(t, pinned, response.Decision.Model)
+		assert.Equal(t, first.Decision.Model, response.Decision.Fallbacks[0])
+		assert.NotContains(t, response.Decision.Fallbacks, pinned)
+	})
+
+	t.Run("should route afresh after a long stream stalls", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		ctx := context.Background()
+		_, _, err = plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+		require.NoError(t, err)
+
+		stream := func(after time.Duration, finish string) {
+			now = now.Add(after)
+			_, _, err := plugin.PostHook(&ctx, streamChunk("...", finish), nil)
+			require.NoError(t, err)
+		}
+		stream(0, "")
+		stream(20*time.Second, "")
+		assert.Equal(t, pinned, plugin.sessions.Pinned("conv-1"), "within the revalidation TTL")
+		stream(2*time.Minute, "")
+		assert.Empty(t, plugin.sessions.Pinned("conv-1"))
+		stream(time.Second, "stop")
+
+		response, err = plugin.decide(req, headers)
+		require.NoError(t, err)
+		assert.Equal(t, first.Decision.Model, response.Decision.Model)
+		assert.Equal(t, first.Decision.Model, plugin.sessions.Pinned("conv-1"))
+		assert.Equal(t, int64(1), plugin.GetMetrics()["sessions"].(SessionStats).Revalidations)
+	})
+
+	t.Run("should route afresh after a stream fails", func(t *testing.T) {
+		response, err := plugin.decide(req, headers)
+		require.NoError(t, err)
+		ctx := context.Background()
+		_, _, err = plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+		require.NoError(t, err)
+
+		_, _, err = plugin.PostHook(&ctx, streamChunk("...", "content_filter"), nil)
+		require.NoError(t, err)
+		assert.Empty(t, plugin.sessions.Pinned("conv-1"))
+	})
+}
@@ -0,0 +1,191 @@
+// Package sidecar is a client for an external feature/embedding sidecar
+// speaking the protocol in sidecarpb, so heavy ML (embedding models,
+// cluster indexes, triage models) can run out of the gateway process.
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar/sidecarpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	defaultPoolSize = 4
+	defaultTimeout  = 10 * time.Millisecond
+)
+
+// Config configures the sidecar client. Each stage is only delegated to the
+// sidecar when enabled.
+type Config struct {
+	// Address is the sidecar's gRPC target, e.g. "localhost:50051"
+	Address string `json:"address"`
+
+	// PoolSize is the number of connections calls are spread over (default 4)
+	PoolSize int `json:"pool_size"`
+
+	// Timeout is the deadline for each call (default 10ms). Embedding and
+	// cluster calls are also held to what remains of feature_timeout.
+	Timeout time.Duration `json:"timeout"`
+
+	Embed   bool `json:"embed"`
+	Cluster bool `json:"cluster"`
+	Triage  bool `json:"triage"`
+}
+
+// Enabled reports whether any stage is delegated to the sidecar
+func (c Config) Enabled() bool {
+	return c.Embed || c.Cluster || c.Triage
+}
+
+// Validate checks the configuration
+func (c Config) Validate() error {
+	if c.Enabled() && c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if c.PoolSize < 0 {
+		return fmt.Errorf("pool_size must not be negative")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// Client calls the sidecar over a pool of connections, under a deadline
+// per call. It implements features.ContextEmbeddingProvider,
+// features.ContextClusterAssigner and router.TriageModel.
+type Client struct {
+	conns   []*grpc.ClientConn
+	clients []sidecarpb.SidecarClient
+	next    atomic.Uint64
+	timeout time.Duration
+}
+
+// NewClient creates a client for the sidecar at config.Address. Connections
+// are established lazily. Insecure transport credentials are used unless
+// opts supply others.
+func NewClient(config Config, opts ...grpc.DialOption) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+
+	poolSize := config.PoolSize
+	if poolSize == 0 {
+		poolSize = defaultPoolSize
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	c := &Client{timeout: timeout}
+	for i := 0; i < poolSize; i++ {
+		conn, err := grpc.NewClient(config.Address, dialOpts...)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to create sidecar connection: %w", err)
+		}
+		c.conns = append(c.conns, conn)
+		c.clients = append(c.clients, sidecarpb.NewSidecarClient(conn))
+	}
+	return c, nil
+}
+
+// client picks the next pooled connection round-robin
+func (c *Client) client() sidecarpb.SidecarClient {
+	return c.clients[c.next.Add(1)%uint64(len(c.clients))]
+}
+
+// Embed computes the embedding for text
+func (c *Client) Embed(text string) ([]float64, error) {
+	return c.EmbedContext(context.Background(), text)
+}
+
+// EmbedContext computes the embedding for text, under the sooner of ctx's
+// deadline and the call timeout
+func (c *Client) EmbedContext(ctx context.Context, text string) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client().Embed(ctx, &sidecarpb.EmbedRequest{Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("sidecar embed failed: %w", err)
+	}
+	return resp.GetEmbedding(), nil
+}
+
+// Cluster assigns embedding to its nearest cluster, with the distances to
+// the k nearest
+func (c *Client) Cluster(embedding []float64, k int) (int, []float64, error) {
+	return c.ClusterContext(context.Background(), embedding, k)
+}
+
+// ClusterContext assigns embedding to its nearest cluster, under the sooner
+// of ctx's deadline and the call timeout
+func (c *Client) ClusterContext(ctx context.Context, embedding []float64, k int) (int, []float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client().Cluster(ctx, &sidecarpb.ClusterRequest{Embedding: embedding, TopK: int32(k)})
+	if err != nil {
+		return 0, nil, fmt.Errorf("sidecar cluster failed: %w", err)
+	}
+	return int(resp.GetClusterId()), resp.GetDistances(), nil
+}
+
+// Predict predicts bucket probabilities for features
+func (c *Client) Predict(features *core.RequestFeatures, artifact *core.AvengersArtifact) (*core.BucketProbabilities, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	req := &sidecarpb.TriagePredictRequest{Features: toProto(features)}
+	if artifact != nil {
+		req.ArtifactVersion = artifact.Version
+	}
+	resp, err := c.client().TriagePredict(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar triage failed: %w", err)
+	}
+	return &core.BucketProbabilities{
+		Cheap: resp.GetCheap(),
+		Mid:   resp.GetMid(),
+		Hard:  resp.GetHard(),
+	}, nil
+}
+
+// Close closes the pooled connections
+func (c *Client) Close() error {
+	var firstErr error
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// toProto converts request features to their wire form
+func toProto(features *core.RequestFeatures) *sidecarpb.Features {
+	return &sidecarpb.Features{
+		Embedding:       features.Embedding,
+		ClusterId:       int32(features.ClusterID),
+		TopPDistances:   features.TopPDistances,
+		TokenCount:      int32(features.TokenCount),
+		HasCode:         features.HasCode,
+		HasMath:         features.HasMath,
+		NgramEntropy:    features.NgramEntropy,
+		ContextRatio:    features.ContextRatio,
+		UserSuccessRate: features.UserSuccessRate,
+		AvgLatency:      features.AvgLatency,
+	}
+}
@@ -0,0 +1,137 @@
+package sidecar
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/core"
+	"github.com/nathanrice/heimdall-bifrost-plugin/sidecar/sidecarpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeSidecar serves fixed responses, after an optional delay
+type fakeSidecar struct {
+	sidecarpb.UnimplementedSidecarServer
+	delay    time.Duration
+	features *sidecarpb.Features
+	version  string
+}
+
+func (f *fakeSidecar) wait(ctx context.Context) error {
+	select {
+	case <-time.After(f.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *fakeSidecar) Embed(ctx context.Context, req *sidecarpb.EmbedRequest) (*sidecarpb.EmbedResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &sidecarpb.EmbedResponse{Embedding: []float64{float64(len(req.Text)), 1}}, nil
+}
+
+func (f *fakeSidecar) Cluster(ctx context.Context, req *sidecarpb.ClusterRequest) (*sidecarpb.ClusterResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &sidecarpb.ClusterResponse{ClusterId: 3, Distances: make([]float64, req.TopK)}, nil
+}
+
+func (f *fakeSidecar) TriagePredict(ctx context.Context, req *sidecarpb.TriagePredictRequest) (*sidecarpb.TriagePredictResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+	f.features, f.version = req.Features, req.ArtifactVersion
+	return &sidecarpb.TriagePredictResponse{Cheap: 0.1, Mid: 0.2, Hard: 0.7}, nil
+}
+
+// newTestClient serves fake over an in-memory listener
+func newTestClient(t *testing.T, fake *fakeSidecar, config Config) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	sidecarpb.RegisterSidecarServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	config.Address = "passthrough:///bufnet"
+	client, err := NewClient(config, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient(t *testing.T) {
+	t.Run("should call each sidecar method", func(t *testing.T) {
+		fake := &fakeSidecar{}
+		client := newTestClient(t, fake, Config{Embed: true, Timeout: time.Second})
+
+		embedding, err := client.Embed("hello")
+		require.NoError(t, err)
+		assert.Equal(t, []float64{5, 1}, embedding)
+
+		clusterID, distances, err := client.Cluster(embedding, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 3, clusterID)
+		assert.Len(t, distances, 5)
+
+		successRate := 0.9
+		probs, err := client.Predict(&core.RequestFeatures{TokenCount: 42, HasCode: true, UserSuccessRate: &successRate},
+			&core.AvengersArtifact{Version: "v1"})
+		require.NoError(t, err)
+		assert.Equal(t, &core.BucketProbabilities{Cheap: 0.1, Mid: 0.2, Hard: 0.7}, probs)
+		assert.Equal(t, "v1", fake.version)
+		assert.Equal(t, int32(42), fake.features.TokenCount)
+		assert.True(t, fake.features.HasCode)
+		assert.Equal(t, 0.9, fake.features.GetUserSuccessRate())
+	})
+
+	t.Run("should spread calls over the pool", func(t *testing.T) {
+		client := newTestClient(t, &fakeSidecar{}, Config{Embed: true, PoolSize: 3})
+		assert.Len(t, client.conns, 3)
+
+		seen := make(map[sidecarpb.SidecarClient]bool)
+		for i := 0; i < 3; i++ {
+			seen[client.client()] = true
+		}
+		assert.Len(t, seen, 3)
+	})
+
+	t.Run("should fail calls that miss the deadline", func(t *testing.T) {
+		client := newTestClient(t, &fakeSidecar{delay: time.Second}, Config{Triage: true, Timeout: 20 * time.Millisecond})
+
+		start := time.Now()
+		_, err := client.Predict(&core.RequestFeatures{}, nil)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("should hold calls to the caller's deadline", func(t *testing.T) {
+		client := newTestClient(t, &fakeSidecar{delay: time.Second}, Config{Embed: true, Cluster: true, Timeout: time.Minute})
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.EmbedContext(ctx, "hello")
+		assert.Error(t, err)
+		_, _, err = client.ClusterContext(ctx, []float64{1}, 5)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.Error(t, Config{Embed: true}.Validate())
+	assert.Error(t, Config{Address: "localhost:50051", Triage: true, PoolSize: -1}.Validate())
+	assert.Error(t, Config{Address: "localhost:50051", Triage: true, Timeout: -time.Millisecond}.Validate())
+}
@@ -0,0 +1,594 @@
+// Protocol between Heimdall and an external feature/embedding sidecar.
+// Regenerate the Go bindings with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        (unknown)
+// source: sidecar/sidecarpb/sidecar.proto
+
+package sidecarpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EmbedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedRequest) Reset() {
+	*x = EmbedRequest{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedRequest) ProtoMessage() {}
+
+func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedRequest.ProtoReflect.Descriptor instead.
+func (*EmbedRequest) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EmbedRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type EmbedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Embedding     []float64              `protobuf:"fixed64,1,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedResponse) Reset() {
+	*x = EmbedResponse{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedResponse) ProtoMessage() {}
+
+func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedResponse.ProtoReflect.Descriptor instead.
+func (*EmbedResponse) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EmbedResponse) GetEmbedding() []float64 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+type ClusterRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Embedding []float64              `protobuf:"fixed64,1,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	// top_k is the number of nearest clusters to report distances for
+	TopK          int32 `protobuf:"varint,2,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClusterRequest) Reset() {
+	*x = ClusterRequest{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClusterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClusterRequest) ProtoMessage() {}
+
+func (x *ClusterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClusterRequest.ProtoReflect.Descriptor instead.
+func (*ClusterRequest) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ClusterRequest) GetEmbedding() []float64 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *ClusterRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+type ClusterResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ClusterId int32                  `protobuf:"varint,1,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	// distances to the top_k nearest clusters, nearest first
+	Distances     []float64 `protobuf:"fixed64,2,rep,packed,name=distances,proto3" json:"distances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClusterResponse) Reset() {
+	*x = ClusterResponse{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClusterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClusterResponse) ProtoMessage() {}
+
+func (x *ClusterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClusterResponse.ProtoReflect.Descriptor instead.
+func (*ClusterResponse) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ClusterResponse) GetClusterId() int32 {
+	if x != nil {
+		return x.ClusterId
+	}
+	return 0
+}
+
+func (x *ClusterResponse) GetDistances() []float64 {
+	if x != nil {
+		return x.Distances
+	}
+	return nil
+}
+
+type Features struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Embedding       []float64              `protobuf:"fixed64,1,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	ClusterId       int32                  `protobuf:"varint,2,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	TopPDistances   []float64              `protobuf:"fixed64,3,rep,packed,name=top_p_distances,json=topPDistances,proto3" json:"top_p_distances,omitempty"`
+	TokenCount      int32                  `protobuf:"varint,4,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
+	HasCode         bool                   `protobuf:"varint,5,opt,name=has_code,json=hasCode,proto3" json:"has_code,omitempty"`
+	HasMath         bool                   `protobuf:"varint,6,opt,name=has_math,json=hasMath,proto3" json:"has_math,omitempty"`
+	NgramEntropy    float64                `protobuf:"fixed64,7,opt,name=ngram_entropy,json=ngramEntropy,proto3" json:"ngram_entropy,omitempty"`
+	ContextRatio    float64                `protobuf:"fixed64,8,opt,name=context_ratio,json=contextRatio,proto3" json:"context_ratio,omitempty"`
+	UserSuccessRate *float64               `protobuf:"fixed64,9,opt,name=user_success_rate,json=userSuccessRate,proto3,oneof" json:"user_success_rate,omitempty"`
+	AvgLatency      *float64               `protobuf:"fixed64,10,opt,name=avg_latency,json=avgLatency,proto3,oneof" json:"avg_latency,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Features) Reset() {
+	*x = Features{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Features) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Features) ProtoMessage() {}
+
+func (x *Features) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Features.ProtoReflect.Descriptor instead.
+func (*Features) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Features) GetEmbedding() []float64 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *Features) GetClusterId() int32 {
+	if x != nil {
+		return x.ClusterId
+	}
+	return 0
+}
+
+func (x *Features) GetTopPDistances() []float64 {
+	if x != nil {
+		return x.TopPDistances
+	}
+	return nil
+}
+
+func (x *Features) GetTokenCount() int32 {
+	if x != nil {
+		return x.TokenCount
+	}
+	return 0
+}
+
+func (x *Features) GetHasCode() bool {
+	if x != nil {
+		return x.HasCode
+	}
+	return false
+}
+
+func (x *Features) GetHasMath() bool {
+	if x != nil {
+		return x.HasMath
+	}
+	return false
+}
+
+func (x *Features) GetNgramEntropy() float64 {
+	if x != nil {
+		return x.NgramEntropy
+	}
+	return 0
+}
+
+func (x *Features) GetContextRatio() float64 {
+	if x != nil {
+		return x.ContextRatio
+	}
+	return 0
+}
+
+func (x *Features) GetUserSuccessRate() float64 {
+	if x != nil && x.UserSuccessRate != nil {
+		return *x.UserSuccessRate
+	}
+	return 0
+}
+
+func (x *Features) GetAvgLatency() float64 {
+	if x != nil && x.AvgLatency != nil {
+		return *x.AvgLatency
+	}
+	return 0
+}
+
+type TriagePredictRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Features *Features              `protobuf:"bytes,1,opt,name=features,proto3" json:"features,omitempty"`
+	// artifact_version is the routing artifact Heimdall is serving
+	ArtifactVersion string `protobuf:"bytes,2,opt,name=artifact_version,json=artifactVersion,proto3" json:"artifact_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TriagePredictRequest) Reset() {
+	*x = TriagePredictRequest{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriagePredictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriagePredictRequest) ProtoMessage() {}
+
+func (x *TriagePredictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriagePredictRequest.ProtoReflect.Descriptor instead.
+func (*TriagePredictRequest) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TriagePredictRequest) GetFeatures() *Features {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *TriagePredictRequest) GetArtifactVersion() string {
+	if x != nil {
+		return x.ArtifactVersion
+	}
+	return ""
+}
+
+type TriagePredictResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cheap         float64                `protobuf:"fixed64,1,opt,name=cheap,proto3" json:"cheap,omitempty"`
+	Mid           float64                `protobuf:"fixed64,2,opt,name=mid,proto3" json:"mid,omitempty"`
+	Hard          float64                `protobuf:"fixed64,3,opt,name=hard,proto3" json:"hard,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriagePredictResponse) Reset() {
+	*x = TriagePredictResponse{}
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriagePredictResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriagePredictResponse) ProtoMessage() {}
+
+func (x *TriagePredictResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sidecar_sidecarpb_sidecar_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriagePredictResponse.ProtoReflect.Descriptor instead.
+func (*TriagePredictResponse) Descriptor() ([]byte, []int) {
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TriagePredictResponse) GetCheap() float64 {
+	if x != nil {
+		return x.Cheap
+	}
+	return 0
+}
+
+func (x *TriagePredictResponse) GetMid() float64 {
+	if x != nil {
+		return x.Mid
+	}
+	return 0
+}
+
+func (x *TriagePredictResponse) GetHard() float64 {
+	if x != nil {
+		return x.Hard
+	}
+	return 0
+}
+
+var File_sidecar_sidecarpb_sidecar_proto protoreflect.FileDescriptor
+
+var file_sidecar_sidecarpb_sidecar_proto_rawDesc = string([]byte{
+	0x0a, 0x1f, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2f, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61,
+	0x72, 0x70, 0x62, 0x2f, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x13, 0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65,
+	0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x22, 0x22, 0x0a, 0x0c, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x22, 0x2d, 0x0a, 0x0d, 0x45, 0x6d,
+	0x62, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x65,
+	0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x01, 0x20, 0x03, 0x28, 0x01, 0x52, 0x09,
+	0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x22, 0x43, 0x0a, 0x0e, 0x43, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x65,
+	0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x01, 0x20, 0x03, 0x28, 0x01, 0x52, 0x09,
+	0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x13, 0x0a, 0x05, 0x74, 0x6f, 0x70,
+	0x5f, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x74, 0x6f, 0x70, 0x4b, 0x22, 0x4e,
+	0x0a, 0x0f, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x01, 0x52, 0x09, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x22, 0x8d,
+	0x03, 0x0a, 0x08, 0x46, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x65,
+	0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x18, 0x01, 0x20, 0x03, 0x28, 0x01, 0x52, 0x09,
+	0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6c, 0x75,
+	0x73, 0x74, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x63,
+	0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x49, 0x64, 0x12, 0x26, 0x0a, 0x0f, 0x74, 0x6f, 0x70, 0x5f,
+	0x70, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x01, 0x52, 0x0d, 0x74, 0x6f, 0x70, 0x50, 0x44, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x73,
+	0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x19, 0x0a, 0x08, 0x68, 0x61, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x07, 0x68, 0x61, 0x73, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x19, 0x0a, 0x08,
+	0x68, 0x61, 0x73, 0x5f, 0x6d, 0x61, 0x74, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x68, 0x61, 0x73, 0x4d, 0x61, 0x74, 0x68, 0x12, 0x23, 0x0a, 0x0d, 0x6e, 0x67, 0x72, 0x61, 0x6d,
+	0x5f, 0x65, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0c,
+	0x6e, 0x67, 0x72, 0x61, 0x6d, 0x45, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x12, 0x23, 0x0a, 0x0d,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x5f, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x52, 0x61, 0x74, 0x69,
+	0x6f, 0x12, 0x2f, 0x0a, 0x11, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73,
+	0x73, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x0f,
+	0x75, 0x73, 0x65, 0x72, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x52, 0x61, 0x74, 0x65, 0x88,
+	0x01, 0x01, 0x12, 0x24, 0x0a, 0x0b, 0x61, 0x76, 0x67, 0x5f, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63,
+	0x79, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x0a, 0x61, 0x76, 0x67, 0x4c, 0x61,
+	0x74, 0x65, 0x6e, 0x63, 0x79, 0x88, 0x01, 0x01, 0x42, 0x14, 0x0a, 0x12, 0x5f, 0x75, 0x73, 0x65,
+	0x72, 0x5f, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x42, 0x0e,
+	0x0a, 0x0c, 0x5f, 0x61, 0x76, 0x67, 0x5f, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x22, 0x7c,
+	0x0a, 0x14, 0x54, 0x72, 0x69, 0x61, 0x67, 0x65, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x39, 0x0a, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x68, 0x65, 0x69, 0x6d, 0x64,
+	0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x46,
+	0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x52, 0x08, 0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x73, 0x12, 0x29, 0x0a, 0x10, 0x61, 0x72, 0x74, 0x69, 0x66, 0x61, 0x63, 0x74, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x61, 0x72, 0x74,
+	0x69, 0x66, 0x61, 0x63, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x53, 0x0a, 0x15,
+	0x54, 0x72, 0x69, 0x61, 0x67, 0x65, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x65, 0x61, 0x70, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x63, 0x68, 0x65, 0x61, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x6d,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6d, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x68, 0x61, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x68, 0x61, 0x72,
+	0x64, 0x32, 0x97, 0x02, 0x0a, 0x07, 0x53, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x12, 0x4e, 0x0a,
+	0x05, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x12, 0x21, 0x2e, 0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c,
+	0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6d, 0x62,
+	0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x68, 0x65, 0x69, 0x6d,
+	0x64, 0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e,
+	0x45, 0x6d, 0x62, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x54, 0x0a,
+	0x07, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x12, 0x23, 0x2e, 0x68, 0x65, 0x69, 0x6d, 0x64,
+	0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x43,
+	0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e,
+	0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x66, 0x0a, 0x0d, 0x54, 0x72, 0x69, 0x61, 0x67, 0x65, 0x50, 0x72, 0x65,
+	0x64, 0x69, 0x63, 0x74, 0x12, 0x29, 0x2e, 0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c, 0x6c, 0x2e,
+	0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x61, 0x67,
+	0x65, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x2a, 0x2e, 0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c, 0x6c, 0x2e, 0x73, 0x69, 0x64, 0x65, 0x63,
+	0x61, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x69, 0x61, 0x67, 0x65, 0x50, 0x72, 0x65, 0x64,
+	0x69, 0x63, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x41, 0x5a, 0x3f, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x61, 0x74, 0x68, 0x61, 0x6e,
+	0x72, 0x69, 0x63, 0x65, 0x2f, 0x68, 0x65, 0x69, 0x6d, 0x64, 0x61, 0x6c, 0x6c, 0x2d, 0x62, 0x69,
+	0x66, 0x72, 0x6f, 0x73, 0x74, 0x2d, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2f, 0x73, 0x69, 0x64,
+	0x65, 0x63, 0x61, 0x72, 0x2f, 0x73, 0x69, 0x64, 0x65, 0x63, 0x61, 0x72, 0x70, 0x62, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
+
+var (
+	file_sidecar_sidecarpb_sidecar_proto_rawDescOnce sync.Once
+	file_sidecar_sidecarpb_sidecar_proto_rawDescData []byte
+)
+
+func file_sidecar_sidecarpb_sidecar_proto_rawDescGZIP() []byte {
+	file_sidecar_sidecarpb_sidecar_proto_rawDescOnce.Do(func() {
+		file_sidecar_sidecarpb_sidecar_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sidecar_sidecarpb_sidecar_proto_rawDesc), len(file_sidecar_sidecarpb_sidecar_proto_rawDesc)))
+	})
+	return file_sidecar_sidecarpb_sidecar_proto_rawDescData
+}
+
+var file_sidecar_sidecarpb_sidecar_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_sidecar_sidecarpb_sidecar_proto_goTypes = []any{
+	(*EmbedRequest)(nil),          // 0: heimdall.sidecar.v1.EmbedRequest
+	(*EmbedResponse)(nil),         // 1: heimdall.sidecar.v1.EmbedResponse
+	(*ClusterRequest)(nil),        // 2: heimdall.sidecar.v1.ClusterRequest
+	(*ClusterResponse)(nil),       // 3: heimdall.sidecar.v1.ClusterResponse
+	(*Features)(nil),              // 4: heimdall.sidecar.v1.Features
+	(*TriagePredictRequest)(nil),  // 5: heimdall.sidecar.v1.TriagePredictRequest
+	(*TriagePredictResponse)(nil), // 6: heimdall.sidecar.v1.TriagePredictResponse
+}
+var file_sidecar_sidecarpb_sidecar_proto_depIdxs = []int32{
+	4, // 0: heimdall.sidecar.v1.TriagePredictRequest.features:type_name -> heimdall.sidecar.v1.Features
+	0, // 1: heimdall.sidecar.v1.Sidecar.Embed:input_type -> heimdall.sidecar.v1.EmbedRequest
+	2, // 2: heimdall.sidecar.v1.Sidecar.Cluster:input_type -> heimdall.sidecar.v1.ClusterRequest
+	5, // 3: heimdall.sidecar.v1.Sidecar.TriagePredict:input_type -> heimdall.sidecar.v1.TriagePredictRequest
+	1, // 4: heimdall.sidecar.v1.Sidecar.Embed:output_type -> heimdall.sidecar.v1.EmbedResponse
+	3, // 5: heimdall.sidecar.v1.Sidecar.Cluster:output_type -> heimdall.sidecar.v1.ClusterResponse
+	6, // 6: heimdall.sidecar.v1.Sidecar.TriagePredict:output_type -> heimdall.sidecar.v1.TriagePredictResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_sidecar_sidecarpb_sidecar_proto_init() }
+func file_sidecar_sidecarpb_sidecar_proto_init() {
+	if File_sidecar_sidecarpb_sidecar_proto != nil {
+		return
+	}
+	file_sidecar_sidecarpb_sidecar_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sidecar_sidecarpb_sidecar_proto_rawDesc), len(file_sidecar_sidecarpb_sidecar_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sidecar_sidecarpb_sidecar_proto_goTypes,
+		DependencyIndexes: file_sidecar_sidecarpb_sidecar_proto_depIdxs,
+		MessageInfos:      file_sidecar_sidecarpb_sidecar_proto_msgTypes,
+	}.Build()
+	File_sidecar_sidecarpb_sidecar_proto = out.File
+	file_sidecar_sidecarpb_sidecar_proto_goTypes = nil
+	file_sidecar_sidecarpb_sidecar_proto_depIdxs = nil
+}
@@ -0,0 +1,214 @@
+// Protocol between Heimdall and an external feature/embedding sidecar.
+// Regenerate the Go bindings with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: sidecar/sidecarpb/sidecar.proto
+
+package sidecarpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Sidecar_Embed_FullMethodName         = "/heimdall.sidecar.v1.Sidecar/Embed"
+	Sidecar_Cluster_FullMethodName       = "/heimdall.sidecar.v1.Sidecar/Cluster"
+	Sidecar_TriagePredict_FullMethodName = "/heimdall.sidecar.v1.Sidecar/TriagePredict"
+)
+
+// SidecarClient is the client API for Sidecar service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Sidecar runs heavy ML out of the gateway process. Every call is made
+// under a deadline that fits the 25ms routing budget; Heimdall falls back
+// to its built-in implementation when a call fails or times out.
+type SidecarClient interface {
+	// Embed computes the embedding used for cluster assignment
+	Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error)
+	// Cluster assigns an embedding to its nearest routing clusters
+	Cluster(ctx context.Context, in *ClusterRequest, opts ...grpc.CallOption) (*ClusterResponse, error)
+	// TriagePredict predicts bucket probabilities from request features
+	TriagePredict(ctx context.Context, in *TriagePredictRequest, opts ...grpc.CallOption) (*TriagePredictResponse, error)
+}
+
+type sidecarClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSidecarClient(cc grpc.ClientConnInterface) SidecarClient {
+	return &sidecarClient{cc}
+}
+
+func (c *sidecarClient) Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmbedResponse)
+	err := c.cc.Invoke(ctx, Sidecar_Embed_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sidecarClient) Cluster(ctx context.Context, in *ClusterRequest, opts ...grpc.CallOption) (*ClusterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClusterResponse)
+	err := c.cc.Invoke(ctx, Sidecar_Cluster_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sidecarClient) TriagePredict(ctx context.Context, in *TriagePredictRequest, opts ...grpc.CallOption) (*TriagePredictResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TriagePredictResponse)
+	err := c.cc.Invoke(ctx, Sidecar_TriagePredict_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SidecarServer is the server API for Sidecar service.
+// All implementations must embed UnimplementedSidecarServer
+// for forward compatibility.
+//
+// Sidecar runs heavy ML out of the gateway process. Every call is made
+// under a deadline that fits the 25ms routing budget; Heimdall falls back
+// to its built-in implementation when a call fails or times out.
+type SidecarServer interface {
+	// Embed computes the embedding used for cluster assignment
+	Embed(context.Context, *EmbedRequest) (*EmbedResponse, error)
+	// Cluster assigns an embedding to its nearest routing clusters
+	Cluster(context.Context, *ClusterRequest) (*ClusterResponse, error)
+	// TriagePredict predicts bucket probabilities from request features
+	TriagePredict(context.Context, *TriagePredictRequest) (*TriagePredictResponse, error)
+	mustEmbedUnimplementedSidecarServer()
+}
+
+// UnimplementedSidecarServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSidecarServer struct{}
+
+func (UnimplementedSidecarServer) Embed(context.Context, *EmbedRequest) (*EmbedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Embed not implemented")
+}
+func (UnimplementedSidecarServer) Cluster(context.Context, *ClusterRequest) (*ClusterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cluster not implemented")
+}
+func (UnimplementedSidecarServer) TriagePredict(context.Context, *TriagePredictRequest) (*TriagePredictResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriagePredict not implemented")
+}
+func (UnimplementedSidecarServer) mustEmbedUnimplementedSidecarServer() {}
+func (UnimplementedSidecarServer) testEmbeddedByValue()                 {}
+
+// UnsafeSidecarServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SidecarServer will
+// result in compilation errors.
+type UnsafeSidecarServer interface {
+	mustEmbedUnimplementedSidecarServer()
+}
+
+func RegisterSidecarServer(s grpc.ServiceRegistrar, srv SidecarServer) {
+	// If the following call pancis, it indicates UnimplementedSidecarServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Sidecar_ServiceDesc, srv)
+}
+
+func _Sidecar_Embed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmbedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).Embed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sidecar_Embed_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).Embed(ctx, req.(*EmbedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sidecar_Cluster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClusterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).Cluster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sidecar_Cluster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).Cluster(ctx, req.(*ClusterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sidecar_TriagePredict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriagePredictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).TriagePredict(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sidecar_TriagePredict_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).TriagePredict(ctx, req.(*TriagePredictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Sidecar_ServiceDesc is the grpc.ServiceDesc for Sidecar service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sidecar_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "heimdall.sidecar.v1.Sidecar",
+	HandlerType: (*SidecarServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Embed",
+			Handler:    _Sidecar_Embed_Handler,
+		},
+		{
+			MethodName: "Cluster",
+			Handler:    _Sidecar_Cluster_Handler,
+		},
+		{
+			MethodName: "TriagePredict",
+			Handler:    _Sidecar_TriagePredict_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sidecar/sidecarpb/sidecar.proto",
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SnapshotConfig configures signed routing policy snapshots: the effective
+// config, artifact, provider health, circuit breakers and experiments at
+// one moment, captured so incidents can be reconstructed later
+type SnapshotConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// KeyID names the HMAC-SHA256 signing key; Key holds it base64-encoded
+	// (at least 32 bytes) and KeyEnv names an environment variable holding
+	// it instead
+	KeyID  string `json:"key_id"`
+	Key    string `json:"key,omitempty"`
+	KeyEnv string `json:"key_env,omitempty"`
+
+	// MaxSnapshots is how many captured snapshots are kept for the admin
+	// API (default 20)
+	MaxSnapshots int `json:"max_snapshots"`
+	// Dir also writes each captured snapshot to <dir>/<id>.json (optional)
+	Dir string `json:"dir"`
+
+	// OnAlert captures a snapshot when a model is quarantined or a cost
+	// anomaly is detected
+	OnAlert bool `json:"on_alert"`
+}
+
+// PolicySnapshot is the routing state at one moment
+type PolicySnapshot struct {
+	ID         string    `json:"id"`
+	Reason     string    `json:"reason"`
+	CapturedAt time.Time `json:"captured_at"`
+
+	// Config is the effective config with secrets redacted
+	Config       map[string]interface{} `json:"config"`
+	ConfigSHA256 string                 `json:"config_sha256"`
+
+	Artifact        SnapshotArtifact  `json:"artifact"`
+	Health          HealthStatus      `json:"health"`
+	CircuitBreakers map[string]string `json:"circuit_breakers"`
+	// Experiments holds the stats of the enabled experiments, e.g.
+	// "dual_run", "compare", "cascade" and "alpha_controller"
+	Experiments map[string]interface{} `json:"experiments"`
+}
+
+// SnapshotArtifact identifies the artifact in use
+type SnapshotArtifact struct {
+	Version  string    `json:"version"`
+	SHA256   string    `json:"sha256"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// SignedSnapshot is a snapshot and its signature over the snapshot's
+// compact JSON encoding
+type SignedSnapshot struct {
+	Snapshot  json.RawMessage   `json:"snapshot"`
+	Signature SnapshotSignature `json:"signature"`
+}
+
+// SnapshotSignature signs a snapshot
+type SnapshotSignature struct {
+	Algorithm string `json:"algorithm"` // always "HMAC-SHA256"
+	KeyID     string `json:"key_id"`
+	Value     string `json:"value"` // hex
+}
+
+// SnapshotSummary lists a captured snapshot
+type SnapshotSummary struct {
+	ID         string    `json:"id"`
+	Reason     string    `json:"reason"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// SnapshotStore signs and keeps captured snapshots
+type SnapshotStore struct {
+	config SnapshotConfig
+	key    []byte
+
+	snapshots []*SignedSnapshot
+	summaries []SnapshotSummary
+	mu        sync.Mutex
+}
+
+// NewSnapshotStore creates a store, filling defaults and decoding the
+// signing key
+func NewSnapshotStore(config SnapshotConfig) (*SnapshotStore, error) {
+	if config.MaxSnapshots == 0 {
+		config.MaxSnapshots = 20
+	}
+	if config.MaxSnapshots < 0 {
+		return nil, fmt.Errorf("max_snapshots must not be negative")
+	}
+	if config.KeyID == "" {
+		return nil, fmt.Errorf("key_id is required")
+	}
+	encoded := config.Key
+	if config.KeyEnv != "" {
+		encoded = os.Getenv(config.KeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key %s is not valid base64: %w", config.KeyID, err)
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("key %s must be at least 32 bytes, got %d", config.KeyID, len(key))
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("snapshot dir: %w", err)
+		}
+	}
+
+	return &SnapshotStore{config: config, key: key}, nil
+}
+
+// Sign encodes and signs a snapshot
+func (ss *SnapshotStore) Sign(snapshot *PolicySnapshot) (*SignedSnapshot, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return &SignedSnapshot{
+		Snapshot: data,
+		Signature: SnapshotSignature{
+			Algorithm: "HMAC-SHA256",
+			KeyID:     ss.config.KeyID,
+			Value:     hex.EncodeToString(signSnapshot(ss.key, data)),
+		},
+	}, nil
+}
+
+// Keep stores a signed snapshot, evicting the oldest past MaxSnapshots,
+// and writes it to Dir when configured
+func (ss *SnapshotStore) Keep(summary SnapshotSummary, signed *SignedSnapshot) error {
+	ss.mu.Lock()
+	ss.snapshots = append(ss.snapshots, signed)
+	ss.summaries = append(ss.summaries, summary)
+	if over := len(ss.snapshots) - ss.config.MaxSnapshots; over > 0 {
+		ss.snapshots = ss.snapshots[over:]
+		ss.summaries = ss.summaries[over:]
+	}
+	ss.mu.Unlock()
+
+	if ss.config.Dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(ss.config.Dir, summary.ID+".json"), data, 0o600)
+}
+
+// List returns the kept snapshots, oldest first
+func (ss *SnapshotStore) List() []SnapshotSummary {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return append([]SnapshotSummary(nil), ss.summaries...)
+}
+
+// Get returns a kept snapshot
+func (ss *SnapshotStore) Get(id string) (*SignedSnapshot, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for i, summary := range ss.summaries {
+		if summary.ID == id {
+			return ss.snapshots[i], true
+		}
+	}
+	return nil, false
+}
+
+// signSnapshot computes the HMAC of a snapshot's compact encoding
+func signSnapshot(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// VerifySnapshot checks a signed snapshot document against the signing key
+// and returns the snapshot. Whitespace added to the document, e.g. by
+// pretty-printing, does not affect verification.
+func VerifySnapshot(document []byte, key []byte) (*PolicySnapshot, error) {
+	var signed SignedSnapshot
+	if err := json.Unmarshal(document, &signed); err != nil {
+		return nil, fmt.Errorf("invalid snapshot document: %w", err)
+	}
+	if signed.Signature.Algorithm != "HMAC-SHA256" {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", signed.Signature.Algorithm)
+	}
+	signature, err := hex.DecodeString(signed.Signature.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, signed.Snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if !hmac.Equal(signature, signSnapshot(key, compact.Bytes())) {
+		return nil, fmt.Errorf("snapshot signature does not match key %s", signed.Signature.KeyID)
+	}
+
+	var snapshot PolicySnapshot
+	if err := json.Unmarshal(compact.Bytes(), &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// CaptureSnapshot captures and signs the current routing state. Snapshots
+// captured for a reason are kept for the admin API; an empty reason
+// captures on demand without keeping the snapshot.
+func (p *Plugin) CaptureSnapshot(reason string) (*SignedSnapshot, error) {
+	if p.snapshots == nil {
+		return nil, fmt.Errorf("snapshots are disabled")
+	}
+
+	config, err := redactConfig(p.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture config: %w", err)
+	}
+	configData, _ := json.Marshal(config)
+	configSum := sha256.Sum256(configData)
+
+	snapshot := &PolicySnapshot{
+		ID:              newLabelID(),
+		Reason:          reason,
+		CapturedAt:      p.now().UTC(),
+		Config:          config,
+		ConfigSHA256:    hex.EncodeToString(configSum[:]),
+		Health:          p.GetHealth(),
+		CircuitBreakers: GetCircuitBreakerStates(),
+		Experiments:     p.experiments(),
+	}
+
+	p.artifactMu.RLock()
+	if p.currentArtifact != nil {
+		artifactData, err := json.Marshal(p.currentArtifact)
+		if err != nil {
+			p.artifactMu.RUnlock()
+			return nil, fmt.Errorf("failed to capture artifact: %w", err)
+		}
+		artifactSum := sha256.Sum256(artifactData)
+		snapshot.Artifact = SnapshotArtifact{
+			Version:  p.currentArtifact.Version,
+			SHA256:   hex.EncodeToString(artifactSum[:]),
+			LoadedAt: p.lastArtifactLoad.UTC(),
+		}
+	}
+	p.artifactMu.RUnlock()
+
+	signed, err := p.snapshots.Sign(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		summary := SnapshotSummary{ID: snapshot.ID, Reason: reason, CapturedAt: snapshot.CapturedAt}
+		if err := p.snapshots.Keep(summary, signed); err != nil {
+			p.logger.Printf("Failed to write snapshot %s: %v", snapshot.ID, err)
+		}
+		p.logger.Printf("Captured routing policy snapshot %s: %s", snapshot.ID, reason)
+	}
+	return signed, nil
+}
+
+// experiments returns the stats of the enabled experiments
+func (p *Plugin) experiments() map[string]interface{} {
+	experiments := make(map[string]interface{})
+	if p.config.Cascade.Enabled {
+		experiments["cascade"] = p.cascade.snapshot()
+	}
+	if p.comparer != nil {
+		experiments["compare"] = p.comparer.GetStats()
+	}
+	if p.dualRun != nil {
+		experiments["dual_run"] = p.dualRun.GetStats()
+	}
+	if p.alphaController != nil {
+		experiments["alpha_controller"] = p.alphaController.GetStatus()
+	}
+	return experiments
+}
+
+// snapshotOnAlert captures a snapshot in the background; alerts are raised
+// under their detectors' locks, which capturing takes again
+func (p *Plugin) snapshotOnAlert(reason string) {
+	go func() {
+		if _, err := p.CaptureSnapshot(reason); err != nil {
+			p.logger.Printf("Failed to capture snapshot on alert: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// Startup policies, applied while no routing artifact has loaded
+const (
+	// StartupPolicyLazy loads the artifact on the first request, routing
+	// with the embedded default artifact if it cannot
+	StartupPolicyLazy = ""
+	// StartupPolicyBlock loads the artifact during construction, failing it
+	// when no artifact loads within the timeout
+	StartupPolicyBlock = "block"
+	// StartupPolicyDefault routes with the embedded default artifact from
+	// construction until the real one loads
+	StartupPolicyDefault = "default"
+	// StartupPolicyPassThrough forwards requests unrouted until the
+	// artifact loads
+	StartupPolicyPassThrough = "passthrough"
+)
+
+const (
+	defaultStartupTimeout = 30 * time.Second
+	startupRetryInterval  = time.Second
+)
+
+// ErrArtifactUnavailable is returned when no routing artifact has loaded
+var ErrArtifactUnavailable = errors.New("no routing artifact available")
+
+// StartupConfig controls behavior until the first artifact loads
+type StartupConfig struct {
+	Policy string `json:"policy"`
+
+	// Timeout bounds the initial load under the "block" policy (default 30s)
+	Timeout time.Duration `json:"timeout"`
+}
+
+// validate checks the policy and timeout
+func (c StartupConfig) validate() error {
+	switch c.Policy {
+	case StartupPolicyLazy, StartupPolicyBlock, StartupPolicyDefault, StartupPolicyPassThrough:
+	default:
+		return fmt.Errorf("unknown startup policy %q", c.Policy)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// awaitArtifact retries loading the artifact until it loads or timeout
+// elapses
+func (p *Plugin) awaitArtifact(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := p.loadArtifact(true)
+		if err == nil {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("artifact did not load within %s: %w", timeout, err)
+		}
+		p.logger.Printf("Waiting for routing artifact: %v", err)
+		time.Sleep(min(startupRetryInterval, remaining))
+	}
+}
+
+// installDefaultArtifact routes with the embedded default artifact until a
+// real one loads, unless one already has
+func (p *Plugin) installDefaultArtifact(cause error) {
+	p.artifactMu.Lock()
+	defer p.artifactMu.Unlock()
+	if p.currentArtifact == nil {
+		p.logger.Printf("Routing with built-in default artifact: %v", cause)
+		p.currentArtifact = defaultArtifact(p.config)
+	}
+}
+
+// passThrough forwards a request unrouted when no artifact is available
+// under the "passthrough" policy
+func (p *Plugin) passThrough(ctx *context.Context, req *schemas.BifrostRequest, err error) (*schemas.BifrostRequest, *schemas.PluginShortCircuit, error) {
+	p.logger.Printf("Passing request through unrouted: %v", err)
+	p.recordFallback(FallbackArtifactMissing)
+	*ctx = context.WithValue(*ctx, "heimdall_fallback_reason", string(FallbackArtifactMissing))
+	*ctx = context.WithValue(*ctx, "heimdall_passthrough", true)
+	*ctx = context.WithValue(*ctx, "heimdall_error", err.Error())
+	return req, nil, nil
+}
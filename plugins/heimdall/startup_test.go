@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupConfigValidation(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Startup.Policy = "eager"
+	_, err := New(config)
+	assert.ErrorContains(t, err, "unknown startup policy")
+
+	config.Startup = StartupConfig{Policy: StartupPolicyBlock, Timeout: -time.Second}
+	_, err = New(config)
+	assert.ErrorContains(t, err, "timeout must not be negative")
+}
+
+func TestStartupPolicies(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&AvengersArtifact{Version: "v1.0"})
+	}))
+	defer available.Close()
+
+	t.Run("should load the artifact before returning under block", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = available.URL
+		config.Startup.Policy = StartupPolicyBlock
+		plugin, err := New(config)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.0", plugin.currentArtifact.Version)
+	})
+
+	t.Run("should fail construction when block times out", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = unavailable.URL
+		config.Startup = StartupConfig{Policy: StartupPolicyBlock, Timeout: 10 * time.Millisecond}
+		_, err := New(config)
+		assert.ErrorContains(t, err, "artifact did not load within 10ms")
+	})
+
+	t.Run("should route with the built-in artifact under default", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = unavailable.URL
+		config.Startup.Policy = StartupPolicyDefault
+		plugin, err := New(config)
+		require.NoError(t, err)
+
+		response, err := plugin.decide(&RouterRequest{}, map[string][]string{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, response.Decision.Model)
+		assert.Equal(t, "degraded", plugin.GetHealth().Status)
+
+		plugin.config.Tuning.ArtifactURL = available.URL
+		plugin.artifactSources = newArtifactSources([]string{available.URL}, plugin.now)
+		require.NoError(t, plugin.ensureCurrentArtifact())
+		assert.Equal(t, "v1.0", plugin.currentArtifact.Version)
+		assert.Equal(t, "healthy", plugin.GetHealth().Status)
+	})
+
+	t.Run("should forward requests unrouted under passthrough", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = unavailable.URL
+		config.Startup.Policy = StartupPolicyPassThrough
+		plugin, err := New(config)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		req := &schemas.BifrostRequest{Provider: schemas.OpenAI, Model: "gpt-4o"}
+		result, shortCircuit, err := plugin.PreHook(&ctx, req)
+		require.NoError(t, err)
+		assert.Nil(t, shortCircuit)
+		assert.Equal(t, "gpt-4o", result.Model)
+		assert.Equal(t, schemas.OpenAI, result.Provider)
+		assert.Equal(t, true, ctx.Value("heimdall_passthrough"))
+	})
+
+	t.Run("should degrade to the built-in artifact when lazy loading fails", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Tuning.ArtifactURL = unavailable.URL
+		plugin, err := New(config)
+		require.NoError(t, err)
+		assert.Nil(t, plugin.currentArtifact)
+
+		ctx := context.Background()
+		req := &schemas.BifrostRequest{Provider: schemas.OpenAI, Model: "gpt-4o"}
+		_, _, err = plugin.PreHook(&ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, defaultArtifactVersion, plugin.currentArtifact.Version)
+		assert.Nil(t, ctx.Value("heimdall_passthrough"))
+	})
+}
+
+func TestDefaultArtifact(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Router.CheapCandidates = []string{"qwen/qwen3-coder", "local/unpriced"}
+	artifact := defaultArtifact(config)
+
+	t.Run("should assign uniform quality to every candidate", func(t *testing.T) {
+		for _, model := range []string{"qwen/qwen3-coder", "local/unpriced", "openai/gpt-4o"} {
+			assert.Equal(t, []float64{builtinDefault.Quality}, artifact.Qhat[model], model)
+		}
+	})
+
+	t.Run("should derive normalized costs from catalog pricing", func(t *testing.T) {
+		assert.Equal(t, 1.0, artifact.Chat["anthropic/claude-opus-4"])
+		assert.Less(t, artifact.Chat["qwen/qwen3-coder"], artifact.Chat["openai/gpt-4o"])
+		assert.Equal(t, unpricedModelCost, artifact.Chat["local/unpriced"])
+	})
+
+	t.Run("should prefer the cheapest candidate", func(t *testing.T) {
+		plugin, err := New(config)
+		require.NoError(t, err)
+		plugin.currentArtifact = artifact
+
+		decision, err := plugin.selectModel(BucketCheap, &RequestFeatures{}, nil, false)
+		require.NoError(t, err)
+		assert.Equal(t, "qwen/qwen3-coder", decision.Model)
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// StreamAccountingConfig configures how streamed responses are accounted.
+// Bifrost runs PostHook once per streamed chunk; a stream's outcome is
+// recorded once, when it ends, so a stream that fails after its first
+// chunks no longer counts as a success.
+type StreamAccountingConfig struct {
+	// ErrorFinishReasons end a stream as a partial failure
+	// (default length, content_filter and error)
+	ErrorFinishReasons []string `json:"error_finish_reasons"`
+}
+
+// StreamOutcome is how a streamed response ended
+type StreamOutcome string
+
+const (
+	StreamCompleted StreamOutcome = "completed"
+	StreamPartial   StreamOutcome = "partial" // finished with an error finish reason
+	StreamAborted   StreamOutcome = "aborted" // failed or was cancelled after its first chunk
+)
+
+// StreamStats counts a model's streamed responses by how they ended
+type StreamStats struct {
+	Streams     int64   `json:"streams"`
+	Completed   int64   `json:"completed"`
+	Partial     int64   `json:"partial"`
+	Aborted     int64   `json:"aborted"`
+	AbortRate   float64 `json:"abort_rate"`
+	PartialRate float64 `json:"partial_rate"`
+}
+
+// streamState follows one request's stream across PostHook calls
+type streamState struct {
+	mu     sync.Mutex
+	chunks int
+	ended  bool
+	stop   func() bool // cancels the abort watch
+	// started and last are when the first and latest chunks arrived
+	started time.Time
+	last    time.Time
+	// degraded is set once the stream marked its session for revalidation
+	degraded bool
+}
+
+// StreamTracker counts stream outcomes by model
+type StreamTracker struct {
+	errorReasons map[string]bool
+	stats        map[string]*StreamStats
+	mu           sync.Mutex
+}
+
+// NewStreamTracker creates a tracker, filling defaults
+func NewStreamTracker(config StreamAccountingConfig) *StreamTracker {
+	reasons := config.ErrorFinishReasons
+	if len(reasons) == 0 {
+		reasons = []string{"length", "content_filter", "error"}
+	}
+	st := &StreamTracker{
+		errorReasons: make(map[string]bool, len(reasons)),
+		stats:        make(map[string]*StreamStats),
+	}
+	for _, reason := range reasons {
+		st.errorReasons[reason] = true
+	}
+	return st
+}
+
+// record counts a stream's outcome
+func (st *StreamTracker) record(model string, outcome StreamOutcome) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	stats, ok := st.stats[model]
+	if !ok {
+		stats = &StreamStats{}
+		st.stats[model] = stats
+	}
+	stats.Streams++
+	switch outcome {
+	case StreamCompleted:
+		stats.Completed++
+	case StreamPartial:
+		stats.Partial++
+	case StreamAborted:
+		stats.Aborted++
+	}
+}
+
+// GetStats returns the counters by model
+func (st *StreamTracker) GetStats() map[string]StreamStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	snapshot := make(map[string]StreamStats, len(st.stats))
+	for model, stats := range st.stats {
+		copied := *stats
+		copied.AbortRate = float64(stats.Aborted) / float64(stats.Streams)
+		copied.PartialRate = float64(stats.Partial) / float64(stats.Streams)
+		snapshot[model] = copied
+	}
+	return snapshot
+}
+
+// observeStream follows a streamed response through PostHook. It reports
+// ongoing for chunks before the stream's end, which are not accounted, and
+// the outcome when the stream ends. Responses that are not streamed, and
+// errors before the first chunk, return no outcome and are accounted as
+// usual. The first chunk starts a watch that ends the stream as aborted,
+// via PostHook, if the request is cancelled before it finishes.
+func (p *Plugin) observeStream(ctx *context.Context, model string, res *schemas.BifrostResponse, err *schemas.BifrostError) (outcome StreamOutcome, ongoing bool) {
+	state, ok := (*ctx).Value("heimdall_stream").(*streamState)
+	if !ok {
+		return "", false
+	}
+	chunk := res != nil && len(res.Choices) > 0 && res.Choices[0].BifrostStreamResponseChoice != nil
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.ended {
+		// e.g. the abort watch firing after the request completed
+		return "", state.chunks > 0
+	}
+
+	switch {
+	case chunk:
+		now := p.now()
+		if state.chunks == 0 {
+			watched := *ctx
+			state.stop = context.AfterFunc(watched, func() {
+				p.PostHook(&watched, nil, streamCancelled())
+			})
+			state.started = now
+		} else if reason := p.streamDegradation(model, state, now); reason != "" && !state.degraded {
+			state.degraded = true
+			p.revalidateSession(*ctx, model, reason)
+		}
+		state.chunks++
+		state.last = now
+		reason := res.Choices[0].FinishReason
+		if reason == nil || *reason == "" {
+			return "", true
+		}
+		outcome = StreamCompleted
+		if p.streams.errorReasons[*reason] {
+			outcome = StreamPartial
+		}
+	case err != nil && state.chunks > 0:
+		outcome = StreamAborted
+	default:
+		return "", false
+	}
+
+	state.ended = true
+	if state.stop != nil {
+		state.stop()
+	}
+	p.streams.record(model, outcome)
+	return outcome, false
+}
+
+// streamUsage reports whether a response is a stream's usage-only chunk
+// (no choices, usage set), which providers send after the finish chunk when
+// asked to include usage
+func streamUsage(ctx context.Context, res *schemas.BifrostResponse) bool {
+	_, streaming := ctx.Value("heimdall_stream").(*streamState)
+	return streaming && res != nil && len(res.Choices) == 0 && res.Usage != nil
+}
+
+// streamDegradation reports why a stream that outlived its decision's
+// revalidation TTL is degraded, or "" while it is healthy or within the TTL
+func (p *Plugin) streamDegradation(model string, state *streamState, now time.Time) string {
+	if p.sessions == nil || now.Sub(state.started) < p.sessions.config.RevalidateAfter {
+		return ""
+	}
+	switch {
+	case p.quarantine != nil && p.quarantine.IsQuarantined(model):
+		return "model quarantined"
+	case p.drains.IsDrained(p.inferProviderKind(model), model):
+		return "model drained"
+	case now.Sub(state.last) >= p.sessions.config.StallTimeout:
+		return fmt.Sprintf("stalled %s between chunks", now.Sub(state.last).Round(time.Second))
+	}
+	return ""
+}
+
+// streamCancelled is the error a stream abandoned by its caller ends with
+func streamCancelled() *schemas.BifrostError {
+	errorType := schemas.RequestCancelled
+	return &schemas.BifrostError{
+		IsBifrostError: true,
+		Error: schemas.ErrorField{
+			Type:    &errorType,
+			Message: "stream cancelled before it finished",
+		},
+	}
+}
+
+// streamFailed reports whether a stream's end counts against its model's
+// health: error finish reasons and aborts, except for the caller going away
+func streamFailed(outcome StreamOutcome, err *schemas.BifrostError) bool {
+	switch outcome {
+	case StreamPartial:
+		return true
+	case StreamAborted:
+		return err.Error.Type == nil || *err.Error.Type != schemas.RequestCancelled
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/nathanrice/heimdall-bifrost-plugin/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamChunk(content, finishReason string) *schemas.BifrostResponse {
+	choice := schemas.BifrostResponseChoice{
+		BifrostStreamResponseChoice: &schemas.BifrostStreamResponseChoice{
+			Delta: schemas.BifrostStreamDelta{Content: &content},
+		},
+	}
+	if finishReason != "" {
+		choice.FinishReason = &finishReason
+	}
+	return &schemas.BifrostResponse{Choices: []schemas.BifrostResponseChoice{choice}}
+}
+
+func TestStreamAccounting(t *testing.T) {
+	plugin := createRouterTestPlugin(t)
+	require.NoError(t, plugin.Drain("openai", ""))
+
+	start := func(ctx context.Context, model string) context.Context {
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, &RouterResponse{
+			Decision: RouterDecision{Kind: "openai", Model: model},
+		})
+		require.NoError(t, err)
+		return ctx
+	}
+	inFlight := func() int64 { return plugin.GetHealth().Drained[0].InFlight }
+	history := func(model string) scoring.PerformanceHistory {
+		hist := plugin.alphaScorer.GetPerformanceMetrics()["perf:"+model]
+		require.NotNil(t, hist)
+		return *hist
+	}
+
+	t.Run("should account a stream once, when it finishes", func(t *testing.T) {
+		ctx := start(context.Background(), "openai/gpt-4o")
+		for _, chunk := range []*schemas.BifrostResponse{streamChunk("Hello", ""), streamChunk(" world", "")} {
+			_, _, err := plugin.PostHook(&ctx, chunk, nil)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), inFlight(), "chunks do not release the request")
+		}
+		_, _, err := plugin.PostHook(&ctx, streamChunk("", "stop"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), inFlight())
+
+		stats := plugin.streams.GetStats()["openai/gpt-4o"]
+		assert.Equal(t, int64(1), stats.Streams)
+		assert.Equal(t, int64(1), stats.Completed)
+		assert.Equal(t, int64(0), history("openai/gpt-4o").Failures)
+	})
+
+	t.Run("should count error finish reasons as partial failures", func(t *testing.T) {
+		ctx := start(context.Background(), "openai/gpt-4o-mini")
+		_, _, err := plugin.PostHook(&ctx, streamChunk("Once upon", ""), nil)
+		require.NoError(t, err)
+		_, _, err = plugin.PostHook(&ctx, streamChunk(" a time", "content_filter"), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(1), plugin.streams.GetStats()["openai/gpt-4o-mini"].Partial)
+		assert.Equal(t, int64(1), history("openai/gpt-4o-mini").PartialFailures)
+		assert.Equal(t, 0.0, history("openai/gpt-4o-mini").SuccessRate)
+	})
+
+	t.Run("should count errors after the first chunk as aborts", func(t *testing.T) {
+		ctx := start(context.Background(), "openai/o1")
+		_, _, err := plugin.PostHook(&ctx, streamChunk("Step one", ""), nil)
+		require.NoError(t, err)
+		_, _, err = plugin.PostHook(&ctx, nil, &schemas.BifrostError{IsBifrostError: true, Error: schemas.ErrorField{Message: "Error reading stream: EOF"}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), inFlight())
+
+		_, _, err = plugin.PostHook(&ctx, nil, &schemas.BifrostError{IsBifrostError: true})
+		require.NoError(t, err)
+		stats := plugin.streams.GetStats()["openai/o1"]
+		assert.Equal(t, int64(1), stats.Aborted, "calls after the end are ignored")
+		assert.Equal(t, 1.0, stats.AbortRate)
+		assert.Equal(t, int64(1), history("openai/o1").PartialFailures)
+	})
+
+	t.Run("should count streams the caller abandons as aborts", func(t *testing.T) {
+		parent, cancel := context.WithCancel(context.Background())
+		ctx := start(parent, "openai/gpt-4.1")
+		_, _, err := plugin.PostHook(&ctx, streamChunk("Partial", ""), nil)
+		require.NoError(t, err)
+
+		cancel()
+		assert.Eventually(t, func() bool {
+			return plugin.streams.GetStats()["openai/gpt-4.1"].Aborted == 1 && inFlight() == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("should leave non-streamed responses alone", func(t *testing.T) {
+		ctx := start(context.Background(), "openai/gpt-4o-nano")
+		_, _, err := plugin.PostHook(&ctx, textResponse("Done.", "length"), nil)
+		require.NoError(t, err)
+		assert.NotContains(t, plugin.streams.GetStats(), "openai/gpt-4o-nano")
+		assert.Equal(t, int64(0), inFlight())
+	})
+}
+
+func TestStreamUsageChunk(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Sessions = testSessionConfig()
+	plugin := createRouterTestPluginWithConfig(t, config)
+	require.NoError(t, plugin.Drain("openai", ""))
+
+	ctx := context.WithValue(context.Background(), "heimdall_session_id", "s1")
+	_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, &RouterResponse{
+		Decision: RouterDecision{Kind: "openai", Model: "openai/o1"},
+	})
+	require.NoError(t, err)
+
+	t.Run("should account usage sent after the finish chunk without ending the stream again", func(t *testing.T) {
+		for _, chunk := range []*schemas.BifrostResponse{streamChunk("Hello", ""), streamChunk("", "stop")} {
+			_, _, err := plugin.PostHook(&ctx, chunk, nil)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 0.0, plugin.sessions.Spend("s1"))
+
+		usage := &schemas.BifrostResponse{Usage: &schemas.LLMUsage{PromptTokens: 1000, CompletionTokens: 1000}}
+		_, _, err := plugin.PostHook(&ctx, usage, nil)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 0.075, plugin.sessions.Spend("s1"), 1e-9)
+		assert.Equal(t, int64(0), plugin.GetHealth().Drained[0].InFlight, "the stream is released once")
+		assert.Equal(t, int64(1), plugin.streams.GetStats()["openai/o1"].Streams)
+	})
+}
+
+func TestStreamFailed(t *testing.T) {
+	cancelled := streamCancelled()
+	assert.True(t, streamFailed(StreamPartial, nil))
+	assert.True(t, streamFailed(StreamAborted, &schemas.BifrostError{IsBifrostError: true}))
+	assert.False(t, streamFailed(StreamAborted, cancelled), "callers going away is not the model's failure")
+	assert.False(t, streamFailed(StreamCompleted, nil))
+	assert.False(t, streamFailed("", nil))
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathanrice/heimdall-bifrost-plugin/auth"
+)
+
+// maxSyntheticLabels bounds the labels counted separately; header values
+// beyond it are counted under "other"
+const maxSyntheticLabels = 32
+
+// SyntheticSignature marks requests whose first user message starts with
+// Prefix as synthetic traffic of kind Label
+type SyntheticSignature struct {
+	Label  string `json:"label"`
+	Prefix string `json:"prefix"`
+}
+
+// SyntheticConfig configures tagging of health-check and canary traffic.
+// Tagged requests are routed normally but excluded from learning (latency
+// estimates, the α controller, judging, labeling and calibration), and are
+// counted separately in metrics. Any caller can tag its requests, so only
+// probes presenting the shared secret are exempt from loop detection.
+type SyntheticConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Header marks a request as synthetic; its value labels the traffic,
+	// e.g. "health_check" (default X-Heimdall-Synthetic)
+	Header string `json:"header"`
+	// Signatures mark requests by prompt, for probes that cannot set headers
+	Signatures []SyntheticSignature `json:"signatures"`
+
+	// Secret is shared with probes, which send it in TokenHeader (default
+	// X-Heimdall-Synthetic-Token); SecretEnv names an environment variable
+	// holding it instead. When set, header tags without it are ignored.
+	Secret      string `json:"secret,omitempty"`
+	SecretEnv   string `json:"secret_env,omitempty"`
+	TokenHeader string `json:"token_header"`
+}
+
+// SyntheticStats counts one kind of synthetic traffic
+type SyntheticStats struct {
+	Requests     int64   `json:"requests"`
+	Failures     int64   `json:"failures"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+
+	latencyTotal time.Duration
+	completed    int64
+}
+
+// SyntheticDetector tags synthetic requests and counts their outcomes
+type SyntheticDetector struct {
+	config SyntheticConfig
+	secret []byte                     // nil when probes are not authenticated
+	stats  map[string]*SyntheticStats // by label
+	mu     sync.Mutex
+}
+
+// NewSyntheticDetector creates a detector, filling defaults and resolving
+// the probe secret
+func NewSyntheticDetector(config SyntheticConfig) (*SyntheticDetector, error) {
+	if config.Header == "" {
+		config.Header = "X-Heimdall-Synthetic"
+	}
+	if config.TokenHeader == "" {
+		config.TokenHeader = "X-Heimdall-Synthetic-Token"
+	}
+	for i, signature := range config.Signatures {
+		if signature.Label == "" || signature.Prefix == "" {
+			return nil, fmt.Errorf("signature %d needs a label and a prefix", i)
+		}
+	}
+
+	secret := config.Secret
+	if config.SecretEnv != "" {
+		if secret = os.Getenv(config.SecretEnv); secret == "" {
+			return nil, fmt.Errorf("secret_env %s is not set", config.SecretEnv)
+		}
+	}
+	sd := &SyntheticDetector{
+		config: config,
+		stats:  make(map[string]*SyntheticStats),
+	}
+	if secret != "" {
+		sd.secret = []byte(secret)
+	}
+	return sd, nil
+}
+
+// Detect returns the request's synthetic traffic label, or "" for real
+// traffic, and counts it. verified reports a header tag sent with the
+// probe secret.
+func (sd *SyntheticDetector) Detect(req *RouterRequest) (label string, verified bool) {
+	label = strings.TrimSpace(auth.HeaderValue(req.Headers, sd.config.Header))
+	if label != "" && sd.secret != nil {
+		token := auth.HeaderValue(req.Headers, sd.config.TokenHeader)
+		if verified = subtle.ConstantTimeCompare([]byte(token), sd.secret) == 1; !verified {
+			label = ""
+		}
+	}
+	if label == "" && req.Body != nil {
+		label = sd.matchSignature(req.Body.Messages)
+	}
+	if label == "" {
+		return "", false
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	stats, ok := sd.stats[label]
+	if !ok {
+		if len(sd.stats) >= maxSyntheticLabels {
+			label = "other"
+			stats = sd.stats[label]
+		}
+		if stats == nil {
+			stats = &SyntheticStats{}
+			sd.stats[label] = stats
+		}
+	}
+	stats.Requests++
+	return label, verified
+}
+
+// matchSignature labels messages by the first user message's prefix
+func (sd *SyntheticDetector) matchSignature(messages []ChatMessage) string {
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		for _, signature := range sd.config.Signatures {
+			if strings.HasPrefix(msg.Content, signature.Prefix) {
+				return signature.Label
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// Record counts a synthetic request's outcome; latency counts only for
+// successful requests
+func (sd *SyntheticDetector) Record(label string, success bool, latency time.Duration) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	stats, ok := sd.stats[label]
+	if !ok {
+		return
+	}
+	if !success {
+		stats.Failures++
+		return
+	}
+	stats.completed++
+	stats.latencyTotal += latency
+}
+
+// GetStats returns the counters by label
+func (sd *SyntheticDetector) GetStats() map[string]SyntheticStats {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	snapshot := make(map[string]SyntheticStats, len(sd.stats))
+	for label, stats := range sd.stats {
+		copied := SyntheticStats{Requests: stats.Requests, Failures: stats.Failures}
+		if stats.completed > 0 {
+			copied.AvgLatencyMs = float64(stats.latencyTotal.Microseconds()) / 1000 / float64(stats.completed)
+		}
+		snapshot[label] = copied
+	}
+	return snapshot
+}
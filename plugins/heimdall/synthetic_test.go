@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyntheticDetector(t *testing.T) {
+	request := func(headers map[string][]string, prompt string) *RouterRequest {
+		return &RouterRequest{
+			Headers: headers,
+			Body:    &RequestBody{Messages: []ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: prompt}}},
+		}
+	}
+	newDetector := func(t *testing.T) *SyntheticDetector {
+		sd, err := NewSyntheticDetector(SyntheticConfig{
+			Signatures: []SyntheticSignature{{Label: "canary", Prefix: "[canary]"}},
+		})
+		require.NoError(t, err)
+		return sd
+	}
+
+	t.Run("should tag by header and by prompt signature", func(t *testing.T) {
+		sd := newDetector(t)
+		label, verified := sd.Detect(request(map[string][]string{"X-Heimdall-Synthetic": {"health_check"}}, "ping"))
+		assert.Equal(t, "health_check", label)
+		assert.False(t, verified, "no secret is configured")
+		label, _ = sd.Detect(request(nil, "[canary] what is 2+2?"))
+		assert.Equal(t, "canary", label)
+		label, _ = sd.Detect(request(nil, "what is 2+2? [canary]"))
+		assert.Empty(t, label)
+		label, _ = sd.Detect(request(nil, "hello"))
+		assert.Empty(t, label)
+	})
+
+	t.Run("should only honour header tags sent with the secret once one is set", func(t *testing.T) {
+		sd, err := NewSyntheticDetector(SyntheticConfig{Secret: "probe-secret"})
+		require.NoError(t, err)
+
+		label, verified := sd.Detect(request(map[string][]string{
+			"X-Heimdall-Synthetic":       {"health_check"},
+			"X-Heimdall-Synthetic-Token": {"probe-secret"},
+		}, "ping"))
+		assert.Equal(t, "health_check", label)
+		assert.True(t, verified)
+
+		label, verified = sd.Detect(request(map[string][]string{
+			"X-Heimdall-Synthetic":       {"health_check"},
+			"X-Heimdall-Synthetic-Token": {"guess"},
+		}, "ping"))
+		assert.Empty(t, label)
+		assert.False(t, verified)
+	})
+
+	t.Run("should count outcomes by label", func(t *testing.T) {
+		sd := newDetector(t)
+		sd.Detect(request(nil, "[canary] a"))
+		sd.Detect(request(nil, "[canary] b"))
+		sd.Record("canary", true, 20*time.Millisecond)
+		sd.Record("canary", false, 0)
+		sd.Record("unknown", true, time.Second)
+
+		stats := sd.GetStats()
+		assert.Equal(t, SyntheticStats{Requests: 2, Failures: 1, AvgLatencyMs: 20}, stats["canary"])
+		assert.NotContains(t, stats, "unknown")
+	})
+
+	t.Run("should bound the labels counted", func(t *testing.T) {
+		sd := newDetector(t)
+		for i := 0; i <= maxSyntheticLabels; i++ {
+			sd.Detect(request(map[string][]string{"X-Heimdall-Synthetic": {fmt.Sprintf("probe-%d", i)}}, "ping"))
+		}
+		assert.Equal(t, int64(1), sd.GetStats()["other"].Requests)
+	})
+
+	t.Run("should reject incomplete signatures and unset secrets", func(t *testing.T) {
+		_, err := NewSyntheticDetector(SyntheticConfig{Signatures: []SyntheticSignature{{Label: "canary"}}})
+		assert.Error(t, err)
+		_, err = NewSyntheticDetector(SyntheticConfig{SecretEnv: "HEIMDALL_TEST_UNSET_SYNTHETIC_SECRET"})
+		assert.ErrorContains(t, err, "is not set")
+	})
+}
+
+func TestSyntheticTrafficExclusion(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Synthetic = SyntheticConfig{Enabled: true}
+	config.AlphaController = AlphaControllerConfig{Enabled: true, TargetSuccessRate: 0.9}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	defer plugin.Cleanup()
+
+	post := func(synthetic string) {
+		ctx := context.WithValue(context.Background(), "heimdall_inflight", RouterDecision{Kind: "openai", Model: "openai/gpt-4o"})
+		ctx = context.WithValue(ctx, "heimdall_start_time", time.Now())
+		if synthetic != "" {
+			ctx = context.WithValue(ctx, "heimdall_synthetic", synthetic)
+		}
+		_, _, err := plugin.PostHook(&ctx, &schemas.BifrostResponse{}, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run("should keep synthetic outcomes out of learning", func(t *testing.T) {
+		plugin.synthetic.Detect(&RouterRequest{Headers: map[string][]string{"X-Heimdall-Synthetic": {"health_check"}}})
+		post("health_check")
+		assert.Zero(t, plugin.alphaController.GetStatus().Window.Requests)
+
+		metrics := plugin.GetMetrics()["synthetic"].(map[string]SyntheticStats)
+		assert.Equal(t, int64(1), metrics["health_check"].Requests)
+	})
+
+	t.Run("should learn from real traffic", func(t *testing.T) {
+		post("")
+		assert.Equal(t, 1, plugin.alphaController.GetStatus().Window.Requests)
+	})
+}
+
+func TestSyntheticLoopDetection(t *testing.T) {
+	config := createRouterTestConfig()
+	config.Synthetic = SyntheticConfig{Enabled: true, Secret: "probe-secret"}
+	config.LoopDetection = LoopConfig{Enabled: true, Threshold: 2}
+	plugin := createRouterTestPluginWithConfig(t, config)
+
+	content := "ping"
+	ask := func(headers map[string][]string) *schemas.PluginShortCircuit {
+		ctx := context.WithValue(context.Background(), "http_headers", headers)
+		_, shortCircuit, err := plugin.PreHook(&ctx, &schemas.BifrostRequest{
+			Model: "gpt-4o",
+			Input: schemas.RequestInput{ChatCompletionInput: &[]schemas.BifrostMessage{{
+				Role:    schemas.ModelChatMessageRoleUser,
+				Content: schemas.MessageContent{ContentStr: &content},
+			}}},
+		})
+		require.NoError(t, err)
+		return shortCircuit
+	}
+
+	t.Run("should exempt probes with the secret from loop detection", func(t *testing.T) {
+		headers := map[string][]string{
+			"Authorization":              {"Bearer sk-probe"},
+			"X-Heimdall-Synthetic":       {"health_check"},
+			"X-Heimdall-Synthetic-Token": {"probe-secret"},
+		}
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, ask(headers))
+		}
+	})
+
+	t.Run("should keep detecting loops for tags without the secret", func(t *testing.T) {
+		headers := map[string][]string{
+			"Authorization":        {"Bearer sk-agent"},
+			"X-Heimdall-Synthetic": {"health_check"},
+		}
+		assert.Nil(t, ask(headers))
+		shortCircuit := ask(headers)
+		require.NotNil(t, shortCircuit)
+		require.NotNil(t, shortCircuit.Error)
+		assert.Equal(t, http.StatusTooManyRequests, *shortCircuit.Error.StatusCode)
+	})
+}
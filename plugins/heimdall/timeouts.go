@@ -0,0 +1,178 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// BucketTimeoutConfig sets outbound timeout and retry hints for a bucket
+type BucketTimeoutConfig struct {
+	// Base is the timeout used until enough latency samples are observed
+	Base time.Duration `json:"base"`
+	// Min and Max clamp the latency-derived timeout
+	Min time.Duration `json:"min"`
+	Max time.Duration `json:"max"`
+	// LatencyMultiplier scales the observed latency estimate
+	LatencyMultiplier float64 `json:"latency_multiplier"`
+
+	MaxRetries   int           `json:"max_retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+}
+
+// TimeoutPolicyConfig configures per-bucket timeout and retry hints
+type TimeoutPolicyConfig struct {
+	Cheap BucketTimeoutConfig `json:"cheap"`
+	Mid   BucketTimeoutConfig `json:"mid"`
+	Hard  BucketTimeoutConfig `json:"hard"`
+
+	// MinSamples is how many observations a provider needs before its
+	// tracked latency replaces the base timeout
+	MinSamples int `json:"min_samples"`
+}
+
+// defaultBucketTimeouts fail cheap requests fast so they fall back quickly,
+// while hard-bucket reasoning requests get long timeouts and retries
+var defaultBucketTimeouts = map[Bucket]BucketTimeoutConfig{
+	BucketCheap: {Base: 15 * time.Second, Min: 5 * time.Second, Max: 30 * time.Second, LatencyMultiplier: 2},
+	BucketMid:   {Base: 60 * time.Second, Min: 15 * time.Second, Max: 120 * time.Second, LatencyMultiplier: 3, MaxRetries: 1, RetryBackoff: 500 * time.Millisecond},
+	BucketHard:  {Base: 300 * time.Second, Min: 60 * time.Second, Max: 600 * time.Second, LatencyMultiplier: 4, MaxRetries: 2, RetryBackoff: 2 * time.Second},
+}
+
+// ProviderHint is an outbound timeout/retry hint for one provider
+type ProviderHint struct {
+	TimeoutMs      int64 `json:"timeout_ms"`
+	MaxRetries     int   `json:"max_retries"`
+	RetryBackoffMs int64 `json:"retry_backoff_ms"`
+}
+
+// forBucket returns the bucket's settings. Fields left zero, including
+// those of partially configured buckets, fall back to the defaults.
+func (c TimeoutPolicyConfig) forBucket(bucket Bucket) BucketTimeoutConfig {
+	var config BucketTimeoutConfig
+	switch bucket {
+	case BucketCheap:
+		config = c.Cheap
+	case BucketMid:
+		config = c.Mid
+	case BucketHard:
+		config = c.Hard
+	}
+
+	defaults := defaultBucketTimeouts[bucket]
+	if config.Base == 0 {
+		config.Base = defaults.Base
+	}
+	if config.Min == 0 {
+		config.Min = defaults.Min
+	}
+	if config.Max == 0 {
+		config.Max = defaults.Max
+	}
+	if config.LatencyMultiplier == 0 {
+		config.LatencyMultiplier = defaults.LatencyMultiplier
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = defaults.RetryBackoff
+	}
+	return config
+}
+
+// latencyStats is a smoothed latency estimate in the style of TCP RTO
+type latencyStats struct {
+	mean      float64 // seconds
+	deviation float64 // seconds
+	samples   int
+}
+
+// LatencyTracker tracks observed per-provider request latency
+type LatencyTracker struct {
+	stats map[string]*latencyStats
+	mu    sync.RWMutex
+}
+
+// NewLatencyTracker creates an empty latency tracker
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		stats: make(map[string]*latencyStats),
+	}
+}
+
+// Record adds a latency observation for a provider
+func (lt *LatencyTracker) Record(provider string, latency time.Duration) {
+	seconds := latency.Seconds()
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	stats, ok := lt.stats[provider]
+	if !ok {
+		lt.stats[provider] = &latencyStats{mean: seconds, deviation: seconds / 2, samples: 1}
+		return
+	}
+
+	// RFC 6298 smoothing: deviation gain 1/4, mean gain 1/8
+	diff := seconds - stats.mean
+	if diff < 0 {
+		diff = -diff
+	}
+	stats.deviation = 0.75*stats.deviation + 0.25*diff
+	stats.mean = 0.875*stats.mean + 0.125*seconds
+	stats.samples++
+}
+
+// Estimate returns a conservative latency bound (mean + 4 deviations) and
+// the number of samples behind it
+func (lt *LatencyTracker) Estimate(provider string) (time.Duration, int) {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+
+	stats, ok := lt.stats[provider]
+	if !ok {
+		return 0, 0
+	}
+	bound := stats.mean + 4*stats.deviation
+	return time.Duration(bound * float64(time.Second)), stats.samples
+}
+
+// providerHints builds timeout/retry hints for the decision's primary
+// provider and each fallback provider
+func (p *Plugin) providerHints(bucket Bucket, decision *RouterDecision) map[string]ProviderHint {
+	config := p.config.Timeouts.forBucket(bucket)
+	minSamples := p.config.Timeouts.MinSamples
+	if minSamples == 0 {
+		minSamples = 5
+	}
+
+	providers := []string{decision.Kind}
+	for _, fallback := range decision.Fallbacks {
+		providers = append(providers, p.inferProviderKind(fallback))
+	}
+
+	hints := make(map[string]ProviderHint, len(providers))
+	for _, provider := range providers {
+		if _, done := hints[provider]; done {
+			continue
+		}
+
+		timeout := config.Base
+		if estimate, samples := p.latency.Estimate(provider); samples >= minSamples {
+			timeout = time.Duration(float64(estimate) * config.LatencyMultiplier)
+			if timeout < config.Min {
+				timeout = config.Min
+			}
+			if config.Max > 0 && timeout > config.Max {
+				timeout = config.Max
+			}
+		}
+
+		hints[provider] = ProviderHint{
+			TimeoutMs:      timeout.Milliseconds(),
+			MaxRetries:     config.MaxRetries,
+			RetryBackoffMs: config.RetryBackoff.Milliseconds(),
+		}
+	}
+	return hints
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	t.Run("should converge towards observed latency", func(t *testing.T) {
+		lt := NewLatencyTracker()
+		for i := 0; i < 50; i++ {
+			lt.Record("openai", 2*time.Second)
+		}
+
+		estimate, samples := lt.Estimate("openai")
+		assert.Equal(t, 50, samples)
+		assert.InDelta(t, 2.0, estimate.Seconds(), 0.2)
+	})
+
+	t.Run("should report no samples for unknown providers", func(t *testing.T) {
+		_, samples := NewLatencyTracker().Estimate("openai")
+		assert.Zero(t, samples)
+	})
+}
+
+func TestProviderHints(t *testing.T) {
+	features := &RequestFeatures{ClusterID: 0, TokenCount: 500}
+
+	t.Run("should fail fast in cheap and wait longer in hard", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		cheap, err := plugin.selectModel(BucketCheap, features, nil, false)
+		require.NoError(t, err)
+		hard, err := plugin.selectModel(BucketHard, features, nil, false)
+		require.NoError(t, err)
+
+		cheapHint := cheap.ProviderHints[cheap.Kind]
+		hardHint := hard.ProviderHints[hard.Kind]
+		assert.Zero(t, cheapHint.MaxRetries)
+		assert.Less(t, cheapHint.TimeoutMs, hardHint.TimeoutMs)
+		assert.Greater(t, hardHint.MaxRetries, 0)
+	})
+
+	t.Run("should cover every fallback provider", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		decision, err := plugin.selectModel(BucketHard, features, nil, false)
+		require.NoError(t, err)
+		for _, fallback := range decision.Fallbacks {
+			assert.Contains(t, decision.ProviderHints, plugin.inferProviderKind(fallback))
+		}
+	})
+
+	t.Run("should derive timeouts from tracked latency within bucket bounds", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+		for i := 0; i < 10; i++ {
+			plugin.latency.Record("openai", 200*time.Second)
+			plugin.latency.Record("anthropic", 100*time.Millisecond)
+		}
+
+		decision := &RouterDecision{Kind: "openai", Fallbacks: []string{"anthropic/claude-3-opus"}}
+		hints := plugin.providerHints(BucketMid, decision)
+
+		mid := defaultBucketTimeouts[BucketMid]
+		assert.Equal(t, mid.Max.Milliseconds(), hints["openai"].TimeoutMs)
+		assert.Equal(t, mid.Min.Milliseconds(), hints["anthropic"].TimeoutMs)
+	})
+
+	t.Run("should honor configured bucket settings", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Timeouts.Cheap = BucketTimeoutConfig{Base: 3 * time.Second, MaxRetries: 0}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		hints := plugin.providerHints(BucketCheap, &RouterDecision{Kind: "openai"})
+		assert.Equal(t, int64(3000), hints["openai"].TimeoutMs)
+	})
+
+	t.Run("should keep configured fields of buckets without a base", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.Timeouts.Hard = BucketTimeoutConfig{MaxRetries: 5, RetryBackoff: time.Second}
+		plugin := createRouterTestPluginWithConfig(t, config)
+
+		hint := plugin.providerHints(BucketHard, &RouterDecision{Kind: "openai"})["openai"]
+		assert.Equal(t, defaultBucketTimeouts[BucketHard].Base.Milliseconds(), hint.TimeoutMs)
+		assert.Equal(t, 5, hint.MaxRetries)
+		assert.Equal(t, int64(1000), hint.RetryBackoffMs)
+	})
+
+	t.Run("should record latency for successful requests in PostHook", func(t *testing.T) {
+		plugin := createRouterTestPlugin(t)
+
+		ctx := context.Background()
+		response := &RouterResponse{Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"}}
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, response)
+		require.NoError(t, err)
+
+		_, _, err = plugin.PostHook(&ctx, &schemas.BifrostResponse{}, nil)
+		require.NoError(t, err)
+
+		_, samples := plugin.latency.Estimate("openai")
+		assert.Equal(t, 1, samples)
+	})
+}
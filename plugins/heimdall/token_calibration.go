@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/maximhq/bifrost/core/schemas"
+)
+
+// TokenCalibrationConfig corrects the prompt token estimate for how each
+// provider counts tokens, e.g. system prompt overhead and tool schemas.
+// A factor is the ratio of the provider's prompt token count to the
+// estimate; it scales model costs in scoring and the prompt's share of the
+// context window in output caps.
+type TokenCalibrationConfig struct {
+	Enabled bool `json:"enabled"`
+	// Factors are static ratios keyed by model or provider kind. A model's
+	// own factor wins over a learned one, which wins over its provider's.
+	Factors map[string]float64 `json:"factors"`
+	// Learn estimates a factor per provider kind from usage responses
+	Learn bool `json:"learn"`
+	// MinSamples is how many responses a learned factor needs before it
+	// applies (default 20)
+	MinSamples int `json:"min_samples"`
+	// Weight of each response in the learned moving average (default 0.05)
+	Weight float64 `json:"weight"`
+}
+
+// Ratios outside these bounds, e.g. from images or prompts too short to
+// estimate, are not learned from
+const (
+	minTokenRatio = 0.25
+	maxTokenRatio = 4.0
+)
+
+// TokenFactorStats is a learned factor and the responses behind it
+type TokenFactorStats struct {
+	Factor  float64 `json:"factor"`
+	Samples int64   `json:"samples"`
+	// Active once MinSamples responses are seen
+	Active bool `json:"active"`
+}
+
+// TokenCalibrator resolves token factors and learns them from usage
+type TokenCalibrator struct {
+	config  TokenCalibrationConfig
+	kind    func(model string) string
+	learned map[string]*TokenFactorStats
+	mu      sync.RWMutex
+}
+
+// NewTokenCalibrator validates the config and fills defaults; kind maps a
+// model to its provider kind
+func NewTokenCalibrator(config TokenCalibrationConfig, kind func(model string) string) (*TokenCalibrator, error) {
+	if config.MinSamples == 0 {
+		config.MinSamples = 20
+	}
+	if config.Weight == 0 {
+		config.Weight = 0.05
+	}
+	if config.MinSamples < 0 || config.Weight < 0 || config.Weight > 1 {
+		return nil, fmt.Errorf("min_samples must be positive and weight between 0 and 1")
+	}
+	for key, factor := range config.Factors {
+		if factor < minTokenRatio || factor > maxTokenRatio {
+			return nil, fmt.Errorf("factor for %s must be between %g and %g", key, minTokenRatio, maxTokenRatio)
+		}
+	}
+	return &TokenCalibrator{
+		config:  config,
+		kind:    kind,
+		learned: make(map[string]*TokenFactorStats),
+	}, nil
+}
+
+// Factor returns model's token factor, 1 when none is known. Learned
+// factors are skipped unless learned is set.
+func (tc *TokenCalibrator) Factor(model string, learned bool) float64 {
+	if factor, ok := tc.config.Factors[model]; ok {
+		return factor
+	}
+	kind := tc.kind(model)
+	if learned {
+		tc.mu.RLock()
+		stats, ok := tc.learned[kind]
+		if ok && stats.Samples >= int64(tc.config.MinSamples) {
+			factor := stats.Factor
+			tc.mu.RUnlock()
+			return factor
+		}
+		tc.mu.RUnlock()
+	}
+	if factor, ok := tc.config.Factors[kind]; ok {
+		return factor
+	}
+	return 1
+}
+
+// Observe learns from the prompt tokens a provider reported for a request
+// estimated at estimate tokens
+func (tc *TokenCalibrator) Observe(model string, estimate int, usage *schemas.LLMUsage) {
+	if !tc.config.Learn || estimate <= 0 || usage == nil || usage.PromptTokens <= 0 {
+		return
+	}
+	ratio := float64(usage.PromptTokens) / float64(estimate)
+	if ratio < minTokenRatio || ratio > maxTokenRatio {
+		return
+	}
+
+	kind := tc.kind(model)
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	stats, ok := tc.learned[kind]
+	if !ok {
+		stats = &TokenFactorStats{Factor: ratio}
+		tc.learned[kind] = stats
+	} else {
+		stats.Factor += tc.config.Weight * (ratio - stats.Factor)
+	}
+	stats.Samples++
+	stats.Active = stats.Samples >= int64(tc.config.MinSamples)
+}
+
+// GetStats returns the learned factors by provider kind
+func (tc *TokenCalibrator) GetStats() map[string]TokenFactorStats {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	snapshot := make(map[string]TokenFactorStats, len(tc.learned))
+	for kind, stats := range tc.learned {
+		snapshot[kind] = *stats
+	}
+	return snapshot
+}
+
+// calibratedTokens is the prompt's token count as model's provider counts
+// it; deterministic decisions pass learned false to stay replayable
+func (p *Plugin) calibratedTokens(model string, features *RequestFeatures, learned bool) int {
+	if p.tokens == nil {
+		return features.TokenCount
+	}
+	return int(math.Ceil(float64(features.TokenCount) * p.tokens.Factor(model, learned)))
+}
+
+// bucketTokenFactor is the largest token factor among a bucket's
+// candidates, so context guardrails hold for the provider counting the
+// prompt as longest. Learned factors are skipped unless learned is set.
+func (p *Plugin) bucketTokenFactor(bucket Bucket, learned bool) float64 {
+	factor := 1.0
+	if p.tokens == nil {
+		return factor
+	}
+	candidates, _ := p.bucketCandidates(string(bucket))
+	for i, model := range candidates {
+		if f := p.tokens.Factor(model, learned); i == 0 || f > factor {
+			factor = f
+		}
+	}
+	return factor
+}
+
+// learnedBucketTokenFactor is bucketTokenFactor with learned factors
+func (p *Plugin) learnedBucketTokenFactor(bucket Bucket) float64 {
+	return p.bucketTokenFactor(bucket, true)
+}
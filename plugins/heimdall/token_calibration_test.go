@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maximhq/bifrost/core/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCalibrator(t *testing.T) {
+	kind := func(model string) string { return model[:len("openai")] }
+	usage := func(prompt int) *schemas.LLMUsage { return &schemas.LLMUsage{PromptTokens: prompt} }
+
+	t.Run("should prefer model factors, then learned, then provider factors", func(t *testing.T) {
+		tc, err := NewTokenCalibrator(TokenCalibrationConfig{
+			Factors:    map[string]float64{"openai/o1": 1.1, "openai": 1.3},
+			Learn:      true,
+			MinSamples: 2,
+		}, kind)
+		require.NoError(t, err)
+		assert.Equal(t, 1.3, tc.Factor("openai/gpt-4o", true))
+		assert.Equal(t, 1.0, tc.Factor("google/gemini", true))
+
+		tc.Observe("openai/gpt-4o", 1000, usage(1500))
+		assert.Equal(t, 1.3, tc.Factor("openai/gpt-4o", true), "too few samples")
+		tc.Observe("openai/gpt-4o", 1000, usage(1500))
+		assert.Equal(t, 1.5, tc.Factor("openai/gpt-4o", true))
+		assert.Equal(t, 1.3, tc.Factor("openai/gpt-4o", false))
+		assert.Equal(t, 1.1, tc.Factor("openai/o1", true))
+	})
+
+	t.Run("should move learned factors by the configured weight", func(t *testing.T) {
+		tc, err := NewTokenCalibrator(TokenCalibrationConfig{Learn: true, MinSamples: 1, Weight: 0.5}, kind)
+		require.NoError(t, err)
+		tc.Observe("openai/gpt-4o", 1000, usage(1200))
+		tc.Observe("openai/gpt-4o", 1000, usage(1400))
+		assert.InDelta(t, 1.3, tc.Factor("openai/gpt-4o", true), 1e-9)
+
+		tc.Observe("openai/gpt-4o", 10, usage(500))
+		tc.Observe("openai/gpt-4o", 0, usage(500))
+		tc.Observe("openai/gpt-4o", 1000, nil)
+		stats := tc.GetStats()["openai"]
+		assert.Equal(t, int64(2), stats.Samples, "outliers and missing usage are skipped")
+		assert.True(t, stats.Active)
+	})
+
+	t.Run("should not learn unless asked", func(t *testing.T) {
+		tc, err := NewTokenCalibrator(TokenCalibrationConfig{MinSamples: 1}, kind)
+		require.NoError(t, err)
+		tc.Observe("openai/gpt-4o", 1000, usage(2000))
+		assert.Empty(t, tc.GetStats())
+	})
+
+	t.Run("should reject invalid config", func(t *testing.T) {
+		_, err := NewTokenCalibrator(TokenCalibrationConfig{Factors: map[string]float64{"openai": 10}}, kind)
+		assert.ErrorContains(t, err, "factor for openai")
+		_, err = NewTokenCalibrator(TokenCalibrationConfig{Weight: 2}, kind)
+		assert.Error(t, err)
+	})
+}
+
+func TestTokenCalibration(t *testing.T) {
+	config := createRouterTestConfig()
+	config.OutputCap = OutputCapConfig{Enabled: true, Margin: 100}
+	config.TokenCalibration = TokenCalibrationConfig{
+		Enabled:    true,
+		Factors:    map[string]float64{"google": 1.5},
+		Learn:      true,
+		MinSamples: 1,
+	}
+	plugin := createRouterTestPluginWithConfig(t, config)
+	plugin.currentArtifact.Models = map[string]ModelProfile{
+		"openai/gpt-4o":         {ContextWindow: 20000},
+		"google/gemini-1.5-pro": {ContextWindow: 20000},
+	}
+
+	t.Run("should count prompt tokens as the provider does in output caps", func(t *testing.T) {
+		decision := &RouterDecision{Model: "google/gemini-1.5-pro"}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.Equal(t, 20000-6000-100, decision.Params["max_tokens"])
+	})
+
+	t.Run("should learn factors from usage responses", func(t *testing.T) {
+		ctx := context.Background()
+		_, _, err := plugin.applyRoutingDecision(&ctx, &schemas.BifrostRequest{}, &RouterResponse{
+			Decision: RouterDecision{Kind: "openai", Model: "openai/gpt-4o"},
+			Features: RequestFeatures{TokenCount: 4000},
+		})
+		require.NoError(t, err)
+		res := textResponse("ok", "stop")
+		res.Usage = &schemas.LLMUsage{PromptTokens: 5000, CompletionTokens: 10}
+		_, _, err = plugin.PostHook(&ctx, res, nil)
+		require.NoError(t, err)
+
+		stats := plugin.GetMetrics()["token_calibration"].(map[string]TokenFactorStats)
+		assert.Equal(t, 1.25, stats["openai"].Factor)
+
+		decision := &RouterDecision{Model: "openai/gpt-4o"}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, true)
+		assert.Equal(t, 20000-5000-100, decision.Params["max_tokens"])
+
+		decision = &RouterDecision{Model: "openai/gpt-4o"}
+		plugin.capOutputTokens(decision, &RequestFeatures{TokenCount: 4000}, false)
+		assert.Equal(t, 20000-4000-100, decision.Params["max_tokens"], "deterministic decisions ignore learned factors")
+	})
+
+	t.Run("should hold context guardrails to the provider's count", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.TokenCalibration = TokenCalibrationConfig{Enabled: true, Factors: map[string]float64{"deepseek/deepseek-r1": 2}}
+		plugin := createRouterTestPluginWithConfig(t, config)
+		features := &RequestFeatures{TokenCount: 7000}
+
+		assert.True(t, plugin.contextExceedsCapacity(features, BucketCheap), "7000 tokens count as 14000 for deepseek-r1")
+		assert.False(t, plugin.contextExceedsCapacity(features, BucketMid))
+		assert.Equal(t, BucketMid, plugin.selectBucket(&BucketProbabilities{Cheap: 0.9, Mid: 0.05, Hard: 0.05}, features))
+		assert.Equal(t, 2.0, plugin.bucketTokenFactor(BucketCheap, false))
+	})
+
+	t.Run("should reject invalid factors", func(t *testing.T) {
+		config := createRouterTestConfig()
+		config.TokenCalibration = TokenCalibrationConfig{Enabled: true, Factors: map[string]float64{"openai": 0}}
+		_, err := NewWithOptions(config)
+		assert.ErrorContains(t, err, "invalid token calibration config")
+	})
+}